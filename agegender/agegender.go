@@ -0,0 +1,196 @@
+// This example estimates age and gender of faces, chaining three DNN models in one program:
+// the OpenCV face detector finds faces, then the age and gender networks classify each face crop
+//
+// Call: gocv-examples agegender [-input 0] [-conf-thr 0.5] [-padding 20]
+// Input can be a camera ID (default 0), a video file, a stream URL or images
+// Each face is labeled with the estimated gender and age range, e.g. "Female, 25-32", and with -conf
+// the confidence of both models. Time spent by each model is shown in the metrics overlay
+// Parameters can also be set with AGEGENDER_* environment variables or a config file, see internal/config
+//
+// Face detector files are downloaded on first run and cached, see the landmarks example.
+// The age and gender Caffe models by G. Levi and T. Hassner, Age and Gender Classification using
+// Convolutional Neural Networks, 2015, are downloaded as well: https://talhassner.github.io/home/publication/2015_CVPR
+// They predict one of eight age ranges, so an estimate is a range rather than an age, and work
+// best on frontal faces in good light
+
+package agegender
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/models"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"github.com/marchevska/gocv-examples/landmarks"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID             = "0" // Default input
+	ageConfigPath     = "age_deploy.prototxt"
+	ageWeightsPath    = "age_net.caffemodel"
+	genderConfigPath  = "gender_deploy.prototxt"
+	genderWeightsPath = "gender_net.caffemodel"
+	inputSize         = 227 // Input size of the age and gender models
+	facePadding       = 20
+	faceConfThr       = 0.5
+	faceConfThrStep   = 0.05 // Change of the threshold by +/- keys
+)
+
+// Mean of the training images of the age and gender models, in BGR order
+var modelMean = gocv.NewScalar(78.4263377603, 87.7689143744, 114.895847746, 0)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Run estimates age and gender of the faces on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples agegender", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	confThr := fs.Float64("conf-thr", faceConfThr, "Face detection confidence threshold")
+	padding := fs.Int("padding", facePadding, "Pixels added around the face before classification")
+	showConf := fs.Bool("conf", false, "Show confidence of the age and gender models")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "AGEGENDER"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("agegender")
+
+	if *padding < 0 {
+		return errors.New("Padding should not be negative")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{DNN: true, Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	faces, err := landmarks.LoadFaceDetector(sd, *confThr)
+	if err != nil {
+		return err
+	}
+	ageNet, err := loadNet(sd, "age", ageConfigPath, ageWeightsPath)
+	if err != nil {
+		return err
+	}
+	genderNet, err := loadNet(sd, "gender", genderConfigPath, genderWeightsPath)
+	if err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Age and gender")
+	if err != nil {
+		return err
+	}
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			faces.ConfThr += float32(step) * faceConfThrStep
+			if faces.ConfThr < faceConfThrStep {
+				faces.ConfThr = faceConfThrStep
+			}
+			return fmt.Sprintf("face confidence %.2f", faces.ConfThr)
+		}
+		window.Controls.Keys = append(window.Controls.Keys,
+			videoio.KeyAction{Key: 'c', Help: "toggle model confidence", Do: func() { *showConf = !*showConf }})
+	}
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("faces")
+		dets, err := faces.Detect(*img)
+		stop()
+		if err != nil {
+			logging.Errorf("Error detecting faces: %v", err)
+		}
+
+		bounds := image.Rect(0, 0, img.Cols(), img.Rows())
+		for _, d := range dets {
+			box := padBox(d.BBox, *padding, bounds)
+			if box.Empty() {
+				continue
+			}
+			crop := img.Region(box)
+			blob := gocv.BlobFromImage(crop, 1, image.Pt(inputSize, inputSize), modelMean, false, false)
+			crop.Close()
+
+			stop = stats.Start("gender")
+			genderProbs := forward(genderNet, blob)
+			stop()
+			stop = stats.Start("age")
+			ageProbs := forward(ageNet, blob)
+			stop()
+			blob.Close()
+
+			p := newPrediction(ageProbs, genderProbs)
+			label := p.String()
+			if *showConf {
+				label = fmt.Sprintf("%s %.0f%%, %s %.0f%%", genders[p.Gender], p.GenderConf*100,
+					ageRanges[p.Age], p.AgeConf*100)
+			}
+			draw.LabelBox(img, d.BBox, label, draw.DefaultStyle)
+		}
+		stats.Frame()
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err != nil && err != videoio.ErrStopped {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	return nil
+}
+
+// Resolves the model files and loads the network, which is closed by sd
+func loadNet(sd *shutdown.Handler, name, cfg, weights string) (*gocv.Net, error) {
+	var paths []string
+	for _, file := range []string{cfg, weights} {
+		path, err := models.Resolve(file)
+		if err != nil {
+			return nil, fmt.Errorf("Error loading %s model: %v", name, err)
+		}
+		paths = append(paths, path)
+	}
+	net := gocv.ReadNet(paths[1], paths[0])
+	if net.Empty() {
+		return nil, fmt.Errorf("Error loading %s model", name)
+	}
+	sd.OnClose(name+" model", net.Close)
+	return &net, nil
+}
+
+// Runs the network on the blob and returns the class probabilities
+func forward(net *gocv.Net, blob gocv.Mat) []float32 {
+	net.SetInput(blob, "")
+	out := net.Forward("")
+	defer out.Close()
+	probs, err := out.DataPtrFloat32()
+	if err != nil {
+		return nil
+	}
+	return append([]float32(nil), probs...)
+}
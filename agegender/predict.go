@@ -0,0 +1,50 @@
+package agegender
+
+import (
+	"fmt"
+	"image"
+)
+
+// Classes of the models in the order of their outputs
+var (
+	ageRanges = []string{"0-2", "4-6", "8-12", "15-20", "25-32", "38-43", "48-53", "60-100"}
+	genders   = []string{"Male", "Female"}
+)
+
+// prediction is the most probable class of each model for a face
+type prediction struct {
+	Age, Gender         int // Indexes in ageRanges and genders
+	AgeConf, GenderConf float32
+}
+
+// newPrediction picks the most probable classes of the model outputs
+func newPrediction(ageProbs, genderProbs []float32) prediction {
+	var p prediction
+	p.Age, p.AgeConf = argmax(ageProbs)
+	p.Gender, p.GenderConf = argmax(genderProbs)
+	return p
+}
+
+// String returns the label drawn above the face, e.g. "Female, 25-32"
+func (p prediction) String() string {
+	return fmt.Sprintf("%s, %s", genders[p.Gender], ageRanges[p.Age])
+}
+
+func argmax(probs []float32) (int, float32) {
+	if len(probs) == 0 {
+		return 0, 0
+	}
+	b := 0
+	for i, p := range probs {
+		if p > probs[b] {
+			b = i
+		}
+	}
+	return b, probs[b]
+}
+
+// padBox enlarges the face box by pad pixels on each side, limited to bounds. The models
+// were trained on faces with some background around them
+func padBox(face image.Rectangle, pad int, bounds image.Rectangle) image.Rectangle {
+	return face.Inset(-pad).Intersect(bounds)
+}
@@ -0,0 +1,29 @@
+package agegender
+
+import (
+	"image"
+	"testing"
+)
+
+func TestPrediction(t *testing.T) {
+	p := newPrediction([]float32{0.01, 0.02, 0.05, 0.1, 0.6, 0.2, 0.01, 0.01}, []float32{0.3, 0.7})
+	if p.Age != 4 || p.Gender != 1 || p.AgeConf != 0.6 || p.GenderConf != 0.7 {
+		t.Errorf("Prediction %+v", p)
+	}
+	if s := p.String(); s != "Female, 25-32" {
+		t.Errorf("Label %q", s)
+	}
+	if i, conf := argmax(nil); i != 0 || conf != 0 {
+		t.Errorf("argmax(nil) = %d, %v", i, conf)
+	}
+}
+
+func TestPadBox(t *testing.T) {
+	bounds := image.Rect(0, 0, 640, 480)
+	if got := padBox(image.Rect(100, 100, 200, 220), 20, bounds); got != image.Rect(80, 80, 220, 240) {
+		t.Errorf("padBox = %v", got)
+	}
+	if got := padBox(image.Rect(5, 400, 100, 475), 20, bounds); got != image.Rect(0, 380, 120, 480) {
+		t.Errorf("padBox at the edge = %v", got)
+	}
+}
@@ -0,0 +1,296 @@
+// This example tracks a ball, fits its recent trajectory with a parabola and draws the predicted
+// path with the points where the ball will bounce off the floor
+//
+// Call: gocv-examples ball [-input 0] [-detect color|hough] [-hsv hmin,smin,vmin,hmax,smax,vmax] [-floor 0]
+// Input can be a camera ID (default 0), a video file or a stream URL. Sports clips filmed from the side work best
+// With -detect color the ball is found by its color as in the color-track example: draw a rectangle inside
+// the ball with the mouse and press Enter or Space to sample it, or give the range with -hsv. With -detect hough
+// it is the circle of the Hough transform closest to the predicted position. I samples the color again,
+// F puts the floor at the lowest point of the trajectory, X forgets the trajectory and M shows the color mask.
+// +/- change the number of predicted frames
+// Parameters can also be set with BALL_* environment variables or a config file, see internal/config
+//
+// The camera should be fixed and look from the side, so that gravity pulls the ball down the image.
+// Time is counted in frames, and speeds are in pixels per frame. The trajectory starts anew when the
+// ball bounces or is lost for a few frames. Each predicted bounce reverses the vertical speed scaled
+// by -restitution, which is about 0.75 for a basketball and 0.5 for a tennis ball on clay
+
+package ball
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/marchevska/gocv-examples/colortrack"
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID        = "0" // Default input
+	windowTitle  = "Ball tracking"
+	minArea      = 30 // Smallest ball blob in pixels
+	minRadius    = 5
+	maxRadius    = 80
+	houghParam1  = 100 // Upper Canny threshold
+	houghParam2  = 30  // Accumulator threshold, lower finds more circles
+	predictFrame = 30
+	maxPredict   = 300
+	predictStep  = 10 // Change of the predicted frames by +/- keys
+	restitution  = 0.7
+	minBounce    = 1.0 // Pixels per frame
+	maxPoints    = 30
+	maxGap       = 5   // Frames
+	minStep      = 1.5 // Pixels
+	hueTol       = 10  // Tolerances of the sampled range
+	satTol       = 60
+	valTol       = 60
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Run tracks the ball on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples ball", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file or stream URL")
+	detect := fs.String("detect", "color", "Ball detection: color or hough")
+	rangeStr := fs.String("hsv", "", "HSV range of the ball hmin,smin,vmin,hmax,smax,vmax; sampled from the ball if not given")
+	area := fs.Float64("min-area", minArea, "Smallest ball blob in pixels for color detection")
+	minR := fs.Int("min-radius", minRadius, "Minimal ball radius in pixels for Hough detection")
+	maxR := fs.Int("max-radius", maxRadius, "Maximal ball radius in pixels for Hough detection")
+	floor := fs.Int("floor", 0, "Row of the floor in pixels, the bottom of the image if 0")
+	bounce := fs.Float64("restitution", restitution, "Part of the vertical speed kept by a bounce, from 0 to 1")
+	frames := fs.Int("predict", predictFrame, fmt.Sprintf("Number of predicted frames, up to %d", maxPredict))
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "BALL"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("ball")
+
+	var r colortrack.HSVRange
+	switch *detect {
+	case "color":
+		if *rangeStr != "" {
+			var err error
+			if r, err = colortrack.ParseRange(*rangeStr); err != nil {
+				return err
+			}
+		} else if outputs.Enabled() {
+			return errors.New("Without a display the ball color should be given with -hsv")
+		}
+	case "hough":
+		if *minR < 1 || *maxR <= *minR {
+			return errors.New("Radius range should be positive and not empty")
+		}
+	default:
+		return fmt.Errorf("Unknown detection %q, should be color or hough", *detect)
+	}
+	if *bounce < 0 || *bounce > 1 {
+		return errors.New("Restitution should be from 0 to 1")
+	}
+	if *frames < 1 || *frames > maxPredict {
+		return fmt.Errorf("Predicted frames should be from 1 to %d", maxPredict)
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, windowTitle)
+	if err != nil {
+		return err
+	}
+	tr := trajectory{MaxPoints: maxPoints, MaxGap: maxGap, MinStep: minStep}
+	reselect := *detect == "color" && *rangeStr == ""
+	showMask, setFloor := false, false
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			*frames += step * predictStep
+			if *frames < predictStep {
+				*frames = predictStep
+			}
+			if *frames > maxPredict {
+				*frames = maxPredict
+			}
+			return fmt.Sprintf("%d predicted frames", *frames)
+		}
+		window.Controls.Keys = append(window.Controls.Keys,
+			videoio.KeyAction{Key: 'f', Help: "floor at the lowest point", Do: func() { setFloor = true }},
+			videoio.KeyAction{Key: 'x', Help: "forget the trajectory", Do: tr.Reset},
+		)
+		if *detect == "color" {
+			window.Controls.Keys = append(window.Controls.Keys,
+				videoio.KeyAction{Key: 'i', Help: "sample the ball color", Do: func() { reselect = true }},
+				videoio.KeyAction{Key: 'm', Help: "show mask", Do: func() { showMask = !showMask }},
+			)
+		}
+	}
+
+	hsv, mask, gray, circles := gocv.NewMat(), gocv.NewMat(), gocv.NewMat(), gocv.NewMat()
+	defer hsv.Close()
+	defer mask.Close()
+	defer gray.Close()
+	defer circles.Close()
+	kernel := gocv.GetStructuringElement(gocv.MorphEllipse, image.Pt(5, 5))
+	defer kernel.Close()
+
+	frame := 0
+	var predicted []image.Point // Predicted positions from the previous frame, for Hough detection
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		frame++
+		if *floor <= 0 || *floor >= img.Rows() {
+			*floor = img.Rows() - 1
+		}
+
+		stop := stats.Start("detect")
+		var center image.Point
+		found := false
+		if *detect == "color" {
+			gocv.CvtColor(*img, &hsv, gocv.ColorBGRToHSV)
+			if reselect {
+				stop()
+				reselect = false
+				rect := gocv.SelectROI(windowTitle, *img)
+				if rect.Empty() {
+					logging.Infof("No ball selected, press I to sample its color")
+					return
+				}
+				r = colortrack.RangeFromSamples(colortrack.SamplePixels(hsv, rect), hueTol, satTol, valTol)
+				tr.Reset()
+				logging.Infof("Ball HSV range %v", r)
+				return
+			}
+			colortrack.Threshold(hsv, r, &mask)
+			gocv.MorphologyEx(mask, &mask, gocv.MorphOpen, kernel)
+			gocv.MorphologyEx(mask, &mask, gocv.MorphClose, kernel)
+			if blob := colortrack.LargestBlob(mask, *area); blob != nil {
+				center, found = colortrack.Centroid(blob), true
+			}
+		} else {
+			gocv.CvtColor(*img, &gray, gocv.ColorBGRToGray)
+			gocv.MedianBlur(gray, &gray, 5)
+			gocv.HoughCirclesWithParams(gray, &circles, gocv.HoughGradient, 1, float64(2**minR),
+				houghParam1, houghParam2, *minR, *maxR)
+			center, found = closestCircle(circles, predicted)
+		}
+		stop()
+
+		stop = stats.Start("predict")
+		if found {
+			tr.Add(frame, center)
+		}
+		var pts, bounces [][2]float64
+		if p, ok := tr.Fit(); ok && frame-p.T0 <= maxGap {
+			pts, bounces = p.Predict(*frames+frame-p.T0, float64(*floor), *bounce, minBounce)
+			// Prediction starts on the last frame with the ball
+			if skip := frame - p.T0; skip < len(pts) {
+				pts = pts[skip:]
+			} else {
+				pts = nil
+			}
+		}
+		predicted = predicted[:0]
+		for _, pt := range pts {
+			predicted = append(predicted, round(pt))
+		}
+		stop()
+		stats.Frame()
+
+		if setFloor {
+			setFloor = false
+			lowest := 0
+			for _, pt := range tr.Points() {
+				if pt.Y > lowest {
+					lowest = pt.Y
+				}
+			}
+			if lowest > 0 {
+				*floor = lowest
+				logging.Infof("Floor at row %d", *floor)
+			} else {
+				logging.Warnf("No trajectory to put the floor at")
+			}
+		}
+
+		if showMask {
+			gocv.CvtColor(mask, img, gocv.ColorGrayToBGR)
+		}
+		st := draw.DefaultStyle
+		gocv.Line(img, image.Pt(0, *floor), image.Pt(img.Cols()-1, *floor), draw.DarkBlue, 1)
+		trail := tr.Points()
+		for i := 1; i < len(trail); i++ {
+			gocv.Line(img, trail[i-1], trail[i], draw.Red, 2)
+		}
+		for i, pt := range predicted {
+			if i%2 == 0 {
+				gocv.Circle(img, pt, 2, st.LineColor, -1)
+			}
+		}
+		for _, b := range bounces {
+			draw.Crosshair(img, round(b), 12, st.WithColor(draw.White))
+		}
+		if found {
+			draw.Crosshair(img, center, 10, st.WithColor(draw.Red))
+		}
+		if len(bounces) > 0 {
+			text := fmt.Sprintf("bounce at x %d", int(math.Round(bounces[0][0])))
+			draw.TextWithBackground(img, text, image.Pt(0, st.TextSize(text).Y+2*st.Padding), st)
+		}
+		metrics.Overlay(img, stats, st)
+	})
+	if err != nil && err != videoio.ErrStopped {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	return nil
+}
+
+// Returns the center of the circle of the HoughCircles output closest to the first predicted position,
+// or the largest circle without a prediction
+func closestCircle(m gocv.Mat, predicted []image.Point) (image.Point, bool) {
+	best, found := image.Point{}, false
+	bestScore := math.Inf(1)
+	for i := 0; i < m.Cols(); i++ {
+		v := m.GetVecfAt(0, i)
+		center := image.Pt(int(v[0]), int(v[1]))
+		score := -float64(v[2])
+		if len(predicted) > 0 {
+			d := center.Sub(predicted[0])
+			score = math.Hypot(float64(d.X), float64(d.Y))
+		}
+		if score < bestScore {
+			best, bestScore, found = center, score, true
+		}
+	}
+	return best, found
+}
@@ -0,0 +1,200 @@
+package ball
+
+import (
+	"image"
+	"math"
+)
+
+const minFitPoints = 4 // Fewest points for a parabola that is not just their noise
+
+// sample is a ball position on a frame
+type sample struct {
+	Frame int
+	Pos   [2]float64
+}
+
+// trajectory keeps the recent positions of the ball since it was last thrown or bounced.
+// With the camera looking from the side, gravity pulls the ball down the image, so y of a
+// flying ball has no local maximum: one means a bounce off the floor, and a reversal of x
+// a bounce off a wall. Either starts the trajectory anew from the bounce point
+type trajectory struct {
+	MaxPoints int
+	MaxGap    int     // Frames without the ball before the trajectory starts anew
+	MinStep   float64 // Pixels a coordinate should change by to count as a reversal, against jitter
+
+	points []sample
+}
+
+// Add adds the position of the ball on the frame
+func (tr *trajectory) Add(frame int, p image.Point) {
+	cur := sample{frame, [2]float64{float64(p.X), float64(p.Y)}}
+	n := len(tr.points)
+	if n > 0 && frame-tr.points[n-1].Frame > tr.MaxGap {
+		tr.points, n = tr.points[:0], 0
+	}
+	if n >= 2 {
+		a, b := tr.points[n-2].Pos, tr.points[n-1].Pos
+		falling, rising := b[1]-a[1] > tr.MinStep, cur.Pos[1]-b[1] < -tr.MinStep
+		dx1, dx2 := b[0]-a[0], cur.Pos[0]-b[0]
+		turned := dx1 > tr.MinStep && dx2 < -tr.MinStep || dx1 < -tr.MinStep && dx2 > tr.MinStep
+		if falling && rising || turned {
+			tr.points = append(tr.points[:0], tr.points[n-1])
+		}
+	}
+	tr.points = append(tr.points, cur)
+	if len(tr.points) > tr.MaxPoints {
+		tr.points = append(tr.points[:0], tr.points[len(tr.points)-tr.MaxPoints:]...)
+	}
+}
+
+// Reset forgets the positions
+func (tr *trajectory) Reset() {
+	tr.points = tr.points[:0]
+}
+
+// Points returns the positions since the trajectory started
+func (tr *trajectory) Points() []image.Point {
+	pts := make([]image.Point, len(tr.points))
+	for i, s := range tr.points {
+		pts[i] = round(s.Pos)
+	}
+	return pts
+}
+
+// path is the motion of the ball with a constant horizontal speed and a constant vertical
+// acceleration G, from the position (X0, Y0) at frame T0. Speeds are in pixels per frame
+type path struct {
+	T0             int
+	X0, Y0, VX, VY float64
+	G              float64
+}
+
+// At returns the position the given number of frames after T0
+func (p path) At(t float64) [2]float64 {
+	return [2]float64{p.X0 + p.VX*t, p.Y0 + p.VY*t + p.G*t*t/2}
+}
+
+// Fit returns the path through the positions by least squares, starting at the last one.
+// It is false until there are enough positions
+func (tr *trajectory) Fit() (path, bool) {
+	n := len(tr.points)
+	if n < minFitPoints {
+		return path{}, false
+	}
+	last := tr.points[n-1].Frame
+	// Sums of powers of t and of the coordinates times them, for the normal equations
+	var st [5]float64
+	var sx, sy [3]float64
+	for _, s := range tr.points {
+		t := float64(s.Frame - last)
+		pow := 1.0
+		for k := 0; k < 5; k++ {
+			st[k] += pow
+			if k < 3 {
+				sx[k] += s.Pos[0] * pow
+				sy[k] += s.Pos[1] * pow
+			}
+			pow *= t
+		}
+	}
+
+	// x = x0 + vx*t
+	det := st[0]*st[2] - st[1]*st[1]
+	if math.Abs(det) < 1e-9 {
+		return path{}, false
+	}
+	x0 := (sx[0]*st[2] - sx[1]*st[1]) / det
+	vx := (st[0]*sx[1] - st[1]*sx[0]) / det
+
+	// y = y0 + vy*t + c*t^2
+	y, ok := solve3([3][3]float64{
+		{st[0], st[1], st[2]},
+		{st[1], st[2], st[3]},
+		{st[2], st[3], st[4]},
+	}, sy)
+	if !ok {
+		return path{}, false
+	}
+	return path{T0: last, X0: x0, Y0: y[0], VX: vx, VY: y[1], G: 2 * y[2]}, true
+}
+
+// solve3 solves a 3x3 linear system by Cramer's rule, false if it is singular
+func solve3(a [3][3]float64, b [3]float64) ([3]float64, bool) {
+	d := det3(a)
+	if math.Abs(d) < 1e-9 {
+		return [3]float64{}, false
+	}
+	var x [3]float64
+	for i := range x {
+		m := a
+		for r := 0; r < 3; r++ {
+			m[r][i] = b[r]
+		}
+		x[i] = det3(m) / d
+	}
+	return x, true
+}
+
+func det3(m [3][3]float64) float64 {
+	return m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+}
+
+// Predict returns the positions on the given number of frames after T0 and the points where the
+// ball hits the floor at y. Each bounce keeps the horizontal speed and reverses the vertical one,
+// scaled by the restitution coefficient. Prediction ends when the ball bounces lower than minBounce
+// pixels per frame and would roll rather than fly
+func (p path) Predict(frames int, floor, restitution, minBounce float64) (pts, bounces [][2]float64) {
+	seg, start := p, 0.0 // Current flight and its start time
+	for i := 1; i <= frames; i++ {
+		t := float64(i)
+		pos := seg.At(t - start)
+		for pos[1] > floor {
+			tau, ok := seg.hits(floor)
+			if !ok {
+				break
+			}
+			hit := seg.At(tau)
+			hit[1] = floor
+			bounces = append(bounces, hit)
+			vy := -restitution * (seg.VY + seg.G*tau)
+			if math.Abs(vy) < minBounce {
+				return pts, bounces
+			}
+			start += tau
+			seg = path{T0: seg.T0, X0: hit[0], Y0: floor, VX: seg.VX, VY: vy, G: seg.G}
+			pos = seg.At(t - start)
+		}
+		pts = append(pts, pos)
+	}
+	return pts, bounces
+}
+
+// hits returns the time after the start of the path when the ball falls through y, if it does
+func (p path) hits(y float64) (float64, bool) {
+	// Y0 + VY*t + G*t^2/2 = y, the ball moving down
+	a, b, c := p.G/2, p.VY, p.Y0-y
+	if math.Abs(a) < 1e-9 {
+		if b <= 0 {
+			return 0, false
+		}
+		return -c / b, -c/b > 0
+	}
+	d := b*b - 4*a*c
+	if d < 0 {
+		return 0, false
+	}
+	sq := math.Sqrt(d)
+	best, ok := 0.0, false
+	for _, t := range []float64{(-b - sq) / (2 * a), (-b + sq) / (2 * a)} {
+		if t > 1e-6 && b+2*a*t > 0 && (!ok || t < best) {
+			best, ok = t, true
+		}
+	}
+	return best, ok
+}
+
+func round(p [2]float64) image.Point {
+	return image.Pt(int(math.Round(p[0])), int(math.Round(p[1])))
+}
@@ -0,0 +1,74 @@
+package ball
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestFit(t *testing.T) {
+	tr := trajectory{MaxPoints: 20, MaxGap: 3, MinStep: 1}
+	if _, ok := tr.Fit(); ok {
+		t.Error("Expected no fit without points")
+	}
+	// Thrown up and to the right: x = 100+5t, y = 300-20t+t^2
+	for f := 0; f < 10; f++ {
+		tr.Add(f, image.Pt(100+5*f, 300-20*f+f*f))
+	}
+	p, ok := tr.Fit()
+	if !ok {
+		t.Fatal("Expected a fit")
+	}
+	// At frame 9: x 145, y 201, vy -20+2*9
+	want := path{T0: 9, X0: 145, Y0: 201, VX: 5, VY: -2, G: 2}
+	if p.T0 != want.T0 || !near(p.X0, want.X0) || !near(p.Y0, want.Y0) || !near(p.VX, want.VX) ||
+		!near(p.VY, want.VY) || !near(p.G, want.G) {
+		t.Errorf("Expected %+v, got %+v", want, p)
+	}
+}
+
+func TestBounceRestarts(t *testing.T) {
+	tr := trajectory{MaxPoints: 20, MaxGap: 3, MinStep: 1}
+	for _, y := range []int{100, 150, 200, 150, 110} {
+		tr.Add(len(tr.points), image.Pt(0, y))
+	}
+	if pts := tr.Points(); len(pts) != 3 || pts[0] != image.Pt(0, 200) {
+		t.Errorf("Expected the trajectory to start at the bounce, got %v", pts)
+	}
+
+	tr.Reset()
+	tr.Add(0, image.Pt(0, 0))
+	tr.Add(10, image.Pt(0, 0))
+	if n := len(tr.Points()); n != 1 {
+		t.Errorf("Expected a gap to restart the trajectory, got %d points", n)
+	}
+}
+
+func TestPredict(t *testing.T) {
+	// Falling from 10 px above the floor at 2 px/frame with no gravity hits it after 5 frames
+	p := path{Y0: 90, VY: 2}
+	pts, bounces := p.Predict(8, 100, 0.5, 0.5)
+	if len(bounces) != 1 || !near(bounces[0][1], 100) {
+		t.Fatalf("Expected one bounce on the floor, got %v", bounces)
+	}
+	if len(pts) != 8 || !near(pts[4][1], 100) || !near(pts[7][1], 97) {
+		t.Errorf("Expected the ball to come back up at half speed, got %v", pts)
+	}
+
+	// Too slow to bounce
+	pts, bounces = p.Predict(8, 100, 0.1, 0.5)
+	if len(bounces) != 1 || len(pts) != 5 {
+		t.Errorf("Expected prediction to end on the floor, got %v %v", pts, bounces)
+	}
+
+	// Gravity: 100 px above the floor at rest with g = 2 hits it after 10 frames
+	p = path{X0: 0, Y0: 0, VX: 1, G: 2}
+	_, bounces = p.Predict(12, 100, 0.5, 0.5)
+	if len(bounces) != 1 || !near(bounces[0][0], 10) {
+		t.Errorf("Expected a bounce at x 10, got %v", bounces)
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
@@ -0,0 +1,247 @@
+// This example finds and decodes 1D barcodes on camera frames: EAN-13, UPC-A, EAN-8 and Code 128
+//
+// Call: gocv-examples barcode [-input 0] [-min-area 2000]
+// Input can be a camera ID (default 0), a video file, a stream URL or images
+// Decoded barcodes are outlined and labeled, and each value is logged when it is first seen,
+// or seen again after -repeat. M shows the mask of barcode-like regions
+// Parameters can also be set with BARCODE_* environment variables or a config file, see internal/config
+//
+// GoCV has no binding of the OpenCV barcode module at the moment of writing, so barcodes are decoded
+// here. Regions with strong gradients in one direction and weak ones across it are found first,
+// then lines across each region are sampled, binarized and the widths of bars and spaces are matched
+// with the patterns of the symbologies, in both directions. Check digits reject misreadings.
+// Bars should be at least 2-3 pixels wide, so hold the barcode close to the camera
+
+package barcode
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"math"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID       = "0"  // Default input
+	minArea     = 2000 // Smallest barcode region in pixels
+	repeatAfter = 5 * time.Second
+	blurSize    = 9
+	closeSize   = 21 // Closing joins the bars of a barcode into one region
+	openSize    = 9  // Opening removes thin regions like text lines
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Barcode is a decoded barcode
+type Barcode struct {
+	Format  string // EAN13, EAN8 or Code128
+	Text    string
+	Corners []image.Point // Corners of the barcode region
+}
+
+// Finder finds barcode regions and decodes them
+type Finder struct {
+	MinArea float64
+
+	gx, gy, ax, ay, mask gocv.Mat
+	closeKernel          gocv.Mat
+	openKernel           gocv.Mat
+}
+
+// NewFinder creates a finder, which should be closed after use
+func NewFinder(minArea float64) *Finder {
+	return &Finder{MinArea: minArea,
+		gx: gocv.NewMat(), gy: gocv.NewMat(), ax: gocv.NewMat(), ay: gocv.NewMat(), mask: gocv.NewMat(),
+		closeKernel: gocv.GetStructuringElement(gocv.MorphRect, image.Pt(closeSize, closeSize)),
+		openKernel:  gocv.GetStructuringElement(gocv.MorphRect, image.Pt(openSize, openSize)),
+	}
+}
+
+// Mask returns the mask of barcode-like regions found by the last call of Find
+func (f *Finder) Mask() gocv.Mat {
+	return f.mask
+}
+
+// regions returns rotated rectangles around areas of parallel edges: the gradient is strong
+// along one axis and weak along the other
+func (f *Finder) regions(gray gocv.Mat) [][4]image.Point {
+	gocv.Scharr(gray, &f.gx, gocv.MatTypeCV32F, 1, 0, 1, 0, gocv.BorderDefault)
+	gocv.Scharr(gray, &f.gy, gocv.MatTypeCV32F, 0, 1, 1, 0, gocv.BorderDefault)
+	gocv.ConvertScaleAbs(f.gx, &f.ax, 1, 0)
+	gocv.ConvertScaleAbs(f.gy, &f.ay, 1, 0)
+	gocv.AbsDiff(f.ax, f.ay, &f.mask)
+	gocv.Blur(f.mask, &f.mask, image.Pt(blurSize, blurSize))
+	gocv.Threshold(f.mask, &f.mask, 0, 255, gocv.ThresholdBinary|gocv.ThresholdOtsu)
+	gocv.MorphologyEx(f.mask, &f.mask, gocv.MorphClose, f.closeKernel)
+	gocv.MorphologyEx(f.mask, &f.mask, gocv.MorphOpen, f.openKernel)
+
+	contours := gocv.FindContours(f.mask, gocv.RetrievalExternal, gocv.ChainApproxSimple)
+	defer contours.Close()
+	var rects [][4]image.Point
+	for i := 0; i < contours.Size(); i++ {
+		c := contours.At(i)
+		if gocv.ContourArea(c) < f.MinArea {
+			continue
+		}
+		rr := gocv.MinAreaRect(c)
+		if len(rr.Points) == 4 {
+			rects = append(rects, [4]image.Point{rr.Points[0], rr.Points[1], rr.Points[2], rr.Points[3]})
+		}
+	}
+	return rects
+}
+
+// Find returns the barcodes decoded on the grayscale image
+func (f *Finder) Find(gray gocv.Mat) []Barcode {
+	w, h := gray.Cols(), gray.Rows()
+	pix := gray.ToBytes()
+	var found []Barcode
+	for _, rect := range f.regions(gray) {
+		var corners [4][2]float64
+		for i, p := range rect {
+			corners[i] = [2]float64{float64(p.X), float64(p.Y)}
+		}
+		for _, line := range scanLines(corners) {
+			length := int(distance(line[0], line[1]))
+			if length < 2 {
+				continue
+			}
+			// Two samples per pixel keep narrow bars apart
+			r := runs(sampleLine(pix, w, h, line[0], line[1], 2*length))
+			if format, text, ok := decodeLine(r); ok {
+				found = append(found, Barcode{Format: format, Text: text, Corners: rect[:]})
+				break
+			}
+		}
+	}
+	return found
+}
+
+// Close releases the buffers
+func (f *Finder) Close() error {
+	f.gx.Close()
+	f.gy.Close()
+	f.ax.Close()
+	f.ay.Close()
+	f.mask.Close()
+	f.closeKernel.Close()
+	return f.openKernel.Close()
+}
+
+func distance(a, b [2]float64) float64 {
+	dx, dy := a[0]-b[0], a[1]-b[1]
+	return math.Hypot(dx, dy)
+}
+
+// Run decodes barcodes on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples barcode", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	area := fs.Float64("min-area", minArea, "Smallest barcode region in pixels")
+	repeat := fs.Duration("repeat", repeatAfter, "Log a barcode again when it is seen after this time")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "BARCODE"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("barcode")
+
+	if *area <= 0 {
+		return errors.New("Minimal area should be positive")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Barcode scanner")
+	if err != nil {
+		return err
+	}
+	showMask := false
+	if window != nil {
+		window.Controls.Keys = append(window.Controls.Keys,
+			videoio.KeyAction{Key: 'm', Help: "show mask", Do: func() { showMask = !showMask }})
+	}
+
+	finder := NewFinder(*area)
+	sd.OnClose("finder", finder.Close)
+	gray := gocv.NewMat()
+	defer gray.Close()
+	lastSeen := map[string]time.Time{}
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("decode")
+		gocv.CvtColor(*img, &gray, gocv.ColorBGRToGray)
+		barcodes := finder.Find(gray)
+		stop()
+		stats.Frame()
+
+		now := time.Now()
+		for _, b := range barcodes {
+			key := b.Format + ":" + b.Text
+			if now.Sub(lastSeen[key]) >= *repeat {
+				logging.Infof("%s %s", b.Format, b.Text)
+			}
+			lastSeen[key] = now
+		}
+
+		if showMask {
+			gocv.CvtColor(finder.Mask(), img, gocv.ColorGrayToBGR)
+		}
+		st := draw.DefaultStyle.WithColor(draw.Green)
+		for _, b := range barcodes {
+			draw.Outline(img, b.Corners, st)
+			pv := gocv.NewPointVectorFromPoints(b.Corners)
+			rect := gocv.BoundingRect(pv)
+			pv.Close()
+			text := fmt.Sprintf("%s %s", b.Format, b.Text)
+			draw.TextWithBackground(img, text, rect.Min, st)
+		}
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package barcode
+
+import "strings"
+
+// Widths of the Code 128 symbols by value, bar first, 11 modules each
+var code128Patterns = [106]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232",
+}
+
+// Stop pattern, with the final bar
+const code128Stop = "2331112"
+
+// Special symbol values
+const (
+	code128Shift  = 98
+	code128CodeC  = 99
+	code128CodeB  = 100 // FNC4 in code set B
+	code128CodeA  = 101 // FNC4 in code set A
+	code128FNC1   = 102
+	code128StartA = 103
+	code128StartB = 104
+	code128StartC = 105
+)
+
+// Maximal pattern error of a symbol, in modules
+const maxSymbolError = 2.0
+
+func widths(s string) []int {
+	w := make([]int, len(s))
+	for i, c := range s {
+		w[i] = int(c - '0')
+	}
+	return w
+}
+
+var code128Widths, code128StopWidths = func() ([106][]int, []int) {
+	var w [106][]int
+	for i, p := range code128Patterns {
+		w[i] = widths(p)
+	}
+	return w, widths(code128Stop)
+}()
+
+// Returns the value of the symbol best matching the 6 runs, or -1
+func code128Symbol(r []int) int {
+	best, value := maxSymbolError, -1
+	for v, p := range code128Widths {
+		if e := patternError(r, p); e < best {
+			best, value = e, v
+		}
+	}
+	return value
+}
+
+// decodeCode128 decodes a Code 128 barcode from the runs of a scan line
+func decodeCode128(r []int) (string, bool) {
+	for start := 0; start+6 <= len(r); start += 2 {
+		first := code128Symbol(r[start : start+6])
+		if first < code128StartA {
+			continue
+		}
+		values := []int{first}
+		stopped := false
+		for pos := start + 6; pos+6 <= len(r); pos += 6 {
+			if pos+7 <= len(r) && patternError(r[pos:pos+7], code128StopWidths) < maxSymbolError {
+				stopped = true
+				break
+			}
+			v := code128Symbol(r[pos : pos+6])
+			if v < 0 {
+				break
+			}
+			values = append(values, v)
+		}
+		if !stopped || len(values) < 3 {
+			continue
+		}
+		if text, ok := code128Text(values); ok {
+			return text, true
+		}
+	}
+	return "", false
+}
+
+// code128Text validates the check symbol of the values from the start symbol to the check symbol
+// and returns the encoded text
+func code128Text(values []int) (string, bool) {
+	check := values[len(values)-1]
+	data := values[1 : len(values)-1]
+	sum := values[0]
+	for i, v := range data {
+		sum += (i + 1) * v
+	}
+	if sum%103 != check {
+		return "", false
+	}
+
+	set := byte("ABC"[values[0]-code128StartA])
+	var text strings.Builder
+	shift := false
+	for _, v := range data {
+		current := set
+		if shift {
+			current = "BA"[strings.IndexByte("AB", set)]
+			shift = false
+		}
+		switch {
+		case current == 'C' && v < 100:
+			text.WriteByte(byte('0' + v/10))
+			text.WriteByte(byte('0' + v%10))
+		case current != 'C' && v < 96:
+			if current == 'A' && v >= 64 {
+				text.WriteByte(byte(v - 64)) // Control characters
+			} else {
+				text.WriteByte(byte(32 + v))
+			}
+		case current != 'C' && v == code128Shift:
+			shift = true
+		case v == code128CodeC && current != 'C':
+			set = 'C'
+		case v == code128CodeB && current != 'B':
+			set = 'B'
+		case v == code128CodeA && current != 'A':
+			set = 'A'
+		case v >= 96 && v <= code128FNC1:
+			// FNC1 to FNC4 carry no text
+		default:
+			return "", false
+		}
+	}
+	return text.String(), true
+}
@@ -0,0 +1,193 @@
+package barcode
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Returns the runs of an EAN-13 barcode of the 13 digits
+func encodeEAN13(code string) []int {
+	r := append([]int{}, guard...)
+	parity := eanFirstDigit[code[0]-'0']
+	for k := 0; k < 6; k++ {
+		p := eanL[code[1+k]-'0']
+		if parity[k] == 'G' {
+			p = []int{p[3], p[2], p[1], p[0]}
+		}
+		r = append(r, p...)
+	}
+	r = append(r, middleGuard...)
+	for k := 0; k < 6; k++ {
+		r = append(r, eanL[code[7+k]-'0']...)
+	}
+	return append(r, guard...)
+}
+
+// Returns the runs of a Code 128 barcode of the values, from the start symbol to the data,
+// adding the check symbol and the stop pattern
+func encodeCode128(values ...int) []int {
+	var r []int
+	sum := values[0]
+	for i, v := range values {
+		r = append(r, code128Widths[v]...)
+		if i > 0 {
+			sum += i * v
+		}
+	}
+	r = append(r, code128Widths[sum%103]...)
+	return append(r, code128StopWidths...)
+}
+
+// Scales the runs, adds +-1 pixel of noise and quiet zones
+func render(r []int, scale int, rnd *rand.Rand) []uint8 {
+	line := make([]uint8, 20)
+	for i := range line {
+		line[i] = 230
+	}
+	for i, w := range r {
+		v := uint8(30)
+		if i%2 == 1 {
+			v = 220
+		}
+		n := w*scale + rnd.Intn(3) - 1
+		for j := 0; j < n; j++ {
+			line = append(line, v)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		line = append(line, 230)
+	}
+	return line
+}
+
+func TestRuns(t *testing.T) {
+	got := runs([]uint8{200, 200, 10, 10, 10, 200, 10, 200, 200})
+	if len(got) != 3 || got[0] != 3 || got[1] != 1 || got[2] != 1 {
+		t.Errorf("Unexpected runs %v", got)
+	}
+	if runs([]uint8{100, 110, 120}) != nil {
+		t.Error("A line without contrast should have no runs")
+	}
+}
+
+func TestSampleLine(t *testing.T) {
+	gray := []uint8{0, 100, 200, 0, 100, 200}
+	got := sampleLine(gray, 3, 2, [2]float64{0, 0}, [2]float64{2, 0}, 5)
+	want := []uint8{0, 50, 100, 150, 200}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sampleLine = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestEANChecksum(t *testing.T) {
+	for _, code := range []string{"4006381333931", "5901234123457", "96385074", "036000291452"} {
+		if !eanChecksumOK(code) {
+			t.Errorf("Checksum of %s should be valid", code)
+		}
+	}
+	if eanChecksumOK("4006381333932") {
+		t.Error("Invalid checksum accepted")
+	}
+}
+
+func TestDecodeEAN13(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, code := range []string{"4006381333931", "5901234123457", "0036000291452"} {
+		r := runs(render(encodeEAN13(code), 3, rnd))
+		if got, ok := decodeEAN13(r); !ok || got != code {
+			t.Errorf("Decoded %q, %v, want %s", got, ok, code)
+		}
+		if _, ok := decodeEAN13(reversed(r)); ok {
+			t.Errorf("Reversed %s should not decode", code)
+		}
+	}
+}
+
+func TestDecodeEAN8(t *testing.T) {
+	code := "96385074"
+	r := append([]int{}, guard...)
+	for k := 0; k < 4; k++ {
+		r = append(r, eanL[code[k]-'0']...)
+	}
+	r = append(r, middleGuard...)
+	for k := 4; k < 8; k++ {
+		r = append(r, eanL[code[k]-'0']...)
+	}
+	r = append(r, guard...)
+	got, ok := decodeEAN8(runs(render(r, 4, rand.New(rand.NewSource(2)))))
+	if !ok || got != code {
+		t.Errorf("Decoded %q, %v, want %s", got, ok, code)
+	}
+}
+
+func TestCode128Patterns(t *testing.T) {
+	for v, w := range code128Widths {
+		sum := 0
+		for _, x := range w {
+			sum += x
+		}
+		if sum != 11 {
+			t.Errorf("Pattern %d has %d modules", v, sum)
+		}
+	}
+}
+
+func TestDecodeCode128(t *testing.T) {
+	rnd := rand.New(rand.NewSource(3))
+	for _, c := range []struct {
+		values []int
+		want   string
+	}{
+		// "Hi-5" in code set B
+		{[]int{code128StartB, 'H' - 32, 'i' - 32, '-' - 32, '5' - 32}, "Hi-5"},
+		// "123456" in code set C, then "A" after switching to code set B
+		{[]int{code128StartC, 12, 34, 56, code128CodeB, 'A' - 32}, "123456A"},
+		// Shift from code set A to B for a lowercase letter
+		{[]int{code128StartA, 'X' - 32, code128Shift, 'y' - 32, 'Z' - 32}, "XyZ"},
+	} {
+		r := runs(render(encodeCode128(c.values...), 3, rnd))
+		if got, ok := decodeCode128(r); !ok || got != c.want {
+			t.Errorf("Decoded %q, %v, want %q", got, ok, c.want)
+		}
+	}
+
+	// A wrong check symbol is rejected
+	r := encodeCode128(code128StartB, 'A'-32)
+	copy(r[12:18], code128Widths[0])
+	if _, ok := decodeCode128(r); ok {
+		t.Error("Barcode with a wrong check symbol decoded")
+	}
+}
+
+func TestScanLines(t *testing.T) {
+	lines := scanLines([4][2]float64{{0, 0}, {100, 0}, {100, 40}, {0, 40}})
+	if len(lines) != 2*len(scanFractions) {
+		t.Fatalf("Got %d lines", len(lines))
+	}
+	// The first line crosses the middle horizontally with quiet zones, the first of the other
+	// direction vertically
+	near := func(a, b [2][2]float64) bool {
+		for i := range a {
+			if math.Abs(a[i][0]-b[i][0]) > 1e-9 || math.Abs(a[i][1]-b[i][1]) > 1e-9 {
+				return false
+			}
+		}
+		return true
+	}
+	if !near(lines[0], [2][2]float64{{-10, 20}, {110, 20}}) {
+		t.Errorf("Unexpected horizontal line %v", lines[0])
+	}
+	if !near(lines[len(scanFractions)], [2][2]float64{{50, -4}, {50, 44}}) {
+		t.Errorf("Unexpected vertical line %v", lines[len(scanFractions)])
+	}
+}
+
+func TestDecodeLine(t *testing.T) {
+	r := runs(render(encodeEAN13("5901234123457"), 3, rand.New(rand.NewSource(4))))
+	if format, text, ok := decodeLine(reversed(r)); !ok || format != EAN13 || text != "5901234123457" {
+		t.Errorf("Decoded %s %q, %v", format, text, ok)
+	}
+}
@@ -0,0 +1,127 @@
+package barcode
+
+import "strings"
+
+// Widths of the EAN digit patterns of the left half with odd parity (L code), starting with
+// a space. Right half digits (R code) have the same widths starting with a bar, and left half
+// digits with even parity (G code) have them reversed
+var eanL = [10][]int{
+	{3, 2, 1, 1}, {2, 2, 2, 1}, {2, 1, 2, 2}, {1, 4, 1, 1}, {1, 1, 3, 2},
+	{1, 2, 3, 1}, {1, 1, 1, 4}, {1, 3, 1, 2}, {1, 2, 1, 3}, {3, 1, 1, 2},
+}
+
+// Parities of the left half digits of EAN-13 by the first digit, which is not coded by bars
+var eanFirstDigit = [10]string{
+	"LLLLLL", "LLGLGG", "LLGGLG", "LLGGGL", "LGLLGG", "LGGLLG", "LGGGLL", "LGLGLG", "LGLGGL", "LGGLGL",
+}
+
+// Maximal pattern error of a digit, in modules
+const maxDigitError = 1.5
+
+var guard, middleGuard = []int{1, 1, 1}, []int{1, 1, 1, 1, 1}
+
+// Returns the digit best matching the 4 runs and whether it has even parity
+func eanDigit(r []int, allowG bool) (digit int, even bool, ok bool) {
+	best := maxDigitError
+	digit = -1
+	for d, p := range eanL {
+		if e := patternError(r, p); e < best {
+			best, digit, even = e, d, false
+		}
+		if allowG {
+			g := []int{p[3], p[2], p[1], p[0]}
+			if e := patternError(r, g); e < best {
+				best, digit, even = e, d, true
+			}
+		}
+	}
+	return digit, even, digit >= 0
+}
+
+// eanChecksumOK validates the last digit of an EAN code: digits are weighted 3 and 1 alternately
+// from the right, starting with 3 next to the check digit
+func eanChecksumOK(code string) bool {
+	sum := 0
+	for i := len(code) - 2; i >= 0; i-- {
+		d := int(code[i] - '0')
+		if (len(code)-2-i)%2 == 0 {
+			d *= 3
+		}
+		sum += d
+	}
+	return (10-sum%10)%10 == int(code[len(code)-1]-'0')
+}
+
+// decodeEAN13 decodes an EAN-13 or UPC-A barcode, which is EAN-13 starting with 0, from the runs
+// of a scan line
+func decodeEAN13(r []int) (string, bool) {
+	const numRuns = 59
+	for start := 0; start+numRuns <= len(r); start += 2 {
+		s := r[start : start+numRuns]
+		if patternError(s[0:3], guard) > 1 || patternError(s[27:32], middleGuard) > 1.5 || patternError(s[56:59], guard) > 1 {
+			continue
+		}
+		var code, parity strings.Builder
+		ok := true
+		for k := 0; k < 6 && ok; k++ {
+			var d int
+			var even bool
+			if d, even, ok = eanDigit(s[3+4*k:7+4*k], true); ok {
+				code.WriteByte(byte('0' + d))
+				parity.WriteByte("LG"[boolIndex(even)])
+			}
+		}
+		for k := 0; k < 6 && ok; k++ {
+			var d int
+			if d, _, ok = eanDigit(s[32+4*k:36+4*k], false); ok {
+				code.WriteByte(byte('0' + d))
+			}
+		}
+		if !ok {
+			continue
+		}
+		for first, p := range eanFirstDigit {
+			if p == parity.String() {
+				result := string(rune('0'+first)) + code.String()
+				if eanChecksumOK(result) {
+					return result, true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// decodeEAN8 decodes an EAN-8 barcode from the runs of a scan line
+func decodeEAN8(r []int) (string, bool) {
+	const numRuns = 43
+	for start := 0; start+numRuns <= len(r); start += 2 {
+		s := r[start : start+numRuns]
+		if patternError(s[0:3], guard) > 1 || patternError(s[19:24], middleGuard) > 1.5 || patternError(s[40:43], guard) > 1 {
+			continue
+		}
+		var code strings.Builder
+		ok := true
+		for k := 0; k < 8 && ok; k++ {
+			i := 3 + 4*k
+			if k >= 4 {
+				i = 24 + 4*(k-4)
+			}
+			var d int
+			if d, _, ok = eanDigit(s[i:i+4], false); ok {
+				code.WriteByte(byte('0' + d))
+			}
+		}
+		if ok && eanChecksumOK(code.String()) {
+			return code.String(), true
+		}
+	}
+	return "", false
+}
+
+func boolIndex(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,156 @@
+package barcode
+
+import "math"
+
+// Minimal difference of the darkest and the brightest pixel of a scan line with a barcode
+const minContrast = 40
+
+// sampleLine returns n gray values along the segment from p0 to p1 of a w x h image,
+// interpolated bilinearly. Points outside the image take the nearest edge pixel
+func sampleLine(gray []uint8, w, h int, p0, p1 [2]float64, n int) []uint8 {
+	at := func(x, y int) float64 {
+		x = clampInt(x, 0, w-1)
+		y = clampInt(y, 0, h-1)
+		return float64(gray[y*w+x])
+	}
+	line := make([]uint8, n)
+	for i := range line {
+		t := 0.0
+		if n > 1 {
+			t = float64(i) / float64(n-1)
+		}
+		x := p0[0] + t*(p1[0]-p0[0])
+		y := p0[1] + t*(p1[1]-p0[1])
+		x0, y0 := math.Floor(x), math.Floor(y)
+		fx, fy := x-x0, y-y0
+		ix, iy := int(x0), int(y0)
+		v := at(ix, iy)*(1-fx)*(1-fy) + at(ix+1, iy)*fx*(1-fy) + at(ix, iy+1)*(1-fx)*fy + at(ix+1, iy+1)*fx*fy
+		line[i] = uint8(math.Round(v))
+	}
+	return line
+}
+
+func clampInt(v, min, max int) int {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// runs binarizes the scan line halfway between its darkest and brightest values and returns
+// the widths of alternating dark and light runs, from the first dark run to the last one.
+// A line without enough contrast gives nil
+func runs(line []uint8) []int {
+	if len(line) == 0 {
+		return nil
+	}
+	min, max := line[0], line[0]
+	for _, v := range line {
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	if int(max)-int(min) < minContrast {
+		return nil
+	}
+	thr := (int(min) + int(max)) / 2
+	var out []int
+	dark, width := false, 0
+	for _, v := range line {
+		d := int(v) < thr
+		if d == dark {
+			width++
+			continue
+		}
+		if width > 0 && (dark || len(out) > 0) {
+			out = append(out, width)
+		}
+		dark, width = d, 1
+	}
+	if dark {
+		out = append(out, width)
+	}
+	return out
+}
+
+// reversed returns the runs in the opposite order, for barcodes seen upside down
+func reversed(r []int) []int {
+	out := make([]int, len(r))
+	for i, v := range r {
+		out[len(r)-1-i] = v
+	}
+	return out
+}
+
+// patternError returns how far the runs are from the pattern of module widths, after scaling
+// the runs to the same total width
+func patternError(r []int, pattern []int) float64 {
+	var sum, modules int
+	for i := range pattern {
+		sum += r[i]
+		modules += pattern[i]
+	}
+	if sum == 0 {
+		return math.Inf(1)
+	}
+	scale := float64(modules) / float64(sum)
+	var e float64
+	for i, p := range pattern {
+		e += math.Abs(float64(r[i])*scale - float64(p))
+	}
+	return e
+}
+
+// Symbologies
+const (
+	EAN13   = "EAN-13"
+	EAN8    = "EAN-8"
+	Code128 = "Code 128"
+)
+
+// decodeLine decodes the runs of a scan line read in either direction, and returns
+// the symbology and the text
+func decodeLine(r []int) (format, text string, ok bool) {
+	for _, rr := range [][]int{r, reversed(r)} {
+		if text, ok = decodeEAN13(rr); ok {
+			return EAN13, text, true
+		}
+		if text, ok = decodeEAN8(rr); ok {
+			return EAN8, text, true
+		}
+		if text, ok = decodeCode128(rr); ok {
+			return Code128, text, true
+		}
+	}
+	return "", "", false
+}
+
+// Positions of the scan lines across a region, as fractions of its height
+var scanFractions = []float64{0.5, 0.35, 0.65, 0.2, 0.8}
+
+// Extension of the scan lines beyond the region on each side, as a fraction of its width,
+// so that the quiet zones are included
+const quietZone = 0.1
+
+// scanLines returns segments crossing the quadrilateral of consecutive corners in both directions,
+// parallel to its sides, the middle ones first
+func scanLines(c [4][2]float64) [][2][2]float64 {
+	var lines [][2][2]float64
+	lerp := func(a, b [2]float64, t float64) [2]float64 {
+		return [2]float64{a[0] + t*(b[0]-a[0]), a[1] + t*(b[1]-a[1])}
+	}
+	// Sides c0-c1 and c3-c2 are scanned from one to the other, then sides c1-c2 and c0-c3
+	for _, sides := range [2][4][2]float64{{c[0], c[3], c[1], c[2]}, {c[0], c[1], c[3], c[2]}} {
+		for _, f := range scanFractions {
+			start, end := lerp(sides[0], sides[1], f), lerp(sides[2], sides[3], f)
+			lines = append(lines, [2][2]float64{lerp(start, end, -quietZone), lerp(start, end, 1+quietZone)})
+		}
+	}
+	return lines
+}
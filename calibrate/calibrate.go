@@ -0,0 +1,246 @@
+// This example calibrates a camera with a printed chessboard and shows the undistorted video
+//
+// Call: gocv-examples calibrate [-input 0] [-pattern 9x6] [-square 25] [-views 20] [-out camera.yaml]
+// Show the chessboard to the camera at different positions, distances and angles. Views are captured
+// automatically every -interval while the board is found, or with C when -auto=false. When -views
+// views are collected the camera is calibrated, intrinsics are saved to -out and the video is shown
+// undistorted, U toggles undistortion. With -load camera.yaml saved intrinsics are only applied
+// Parameters can also be set with CALIBRATE_* environment variables or a config file, see internal/config
+//
+// The pattern is the number of inner corners per row and column, e.g. 9x6 for a board of 10x7
+// squares: https://github.com/opencv/opencv/blob/4.x/doc/pattern.png
+// The saved file, see internal/calib, is read by the examples working with image geometry.
+// A reprojection error below 0.5 pixels is good, a larger one means blurred or too similar views
+
+package calibrate
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/calib"
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID       = "0" // Default input
+	pattern     = "9x6"
+	squareSize  = 25 // Millimeters
+	numViews    = 20
+	outPath     = "camera.yaml"
+	minInterval = time.Second
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// parsePattern parses the number of inner corners given as "COLSxROWS"
+func parsePattern(s string) (image.Point, error) {
+	parts := strings.Split(strings.ToLower(s), "x")
+	if len(parts) == 2 {
+		cols, errC := strconv.Atoi(parts[0])
+		rows, errR := strconv.Atoi(parts[1])
+		if errC == nil && errR == nil && cols >= 2 && rows >= 2 {
+			return image.Pt(cols, rows), nil
+		}
+	}
+	return image.Point{}, fmt.Errorf("Pattern should be COLSxROWS of inner corners, at least 2x2: %q", s)
+}
+
+// Finds the inner corners of the chessboard with subpixel accuracy, corners are also written to
+// the matrix for drawing
+func findCorners(gray gocv.Mat, pattern image.Point, corners *gocv.Mat) ([]gocv.Point2f, bool) {
+	flags := gocv.CalibCBAdaptiveThresh | gocv.CalibCBNormalizeImage | gocv.CalibCBFastCheck
+	if !gocv.FindChessboardCorners(gray, pattern, corners, flags) {
+		return nil, false
+	}
+	gocv.CornerSubPix(gray, corners, image.Pt(11, 11), image.Pt(-1, -1),
+		gocv.NewTermCriteria(gocv.Count|gocv.EPS, 30, 0.001))
+	pts := make([]gocv.Point2f, corners.Rows())
+	for i := range pts {
+		v := corners.GetVecfAt(i, 0)
+		pts[i] = gocv.Point2f{X: v[0], Y: v[1]}
+	}
+	return pts, true
+}
+
+// Calibrates the camera from the views of the chessboard
+func calibrateViews(views [][]gocv.Point2f, pattern image.Point, square float64, size image.Point) calib.Intrinsics {
+	board := calib.ChessboardPoints(pattern, square)
+	objectPts := make([][]gocv.Point3f, len(views))
+	for i := range views {
+		objectPts[i] = make([]gocv.Point3f, len(board))
+		for j, p := range board {
+			objectPts[i][j] = gocv.Point3f{X: float32(p[0]), Y: float32(p[1]), Z: float32(p[2])}
+		}
+	}
+	objectVec := gocv.NewPoints3fVectorFromPoints(objectPts)
+	defer objectVec.Close()
+	imageVec := gocv.NewPoints2fVectorFromPoints(views)
+	defer imageVec.Close()
+
+	camera, dist, rvecs, tvecs := gocv.NewMat(), gocv.NewMat(), gocv.NewMat(), gocv.NewMat()
+	defer camera.Close()
+	defer dist.Close()
+	defer rvecs.Close()
+	defer tvecs.Close()
+	rms := gocv.CalibrateCamera(objectVec, imageVec, size, &camera, &dist, &rvecs, &tvecs, 0)
+	return calib.FromMats(camera, dist, size, rms)
+}
+
+// Run calibrates the camera given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples calibrate", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	patternStr := fs.String("pattern", pattern, "Inner corners of the chessboard per row and column, COLSxROWS")
+	square := fs.Float64("square", squareSize, "Size of a chessboard square, e.g. in millimeters")
+	views := fs.Int("views", numViews, "Number of views to calibrate from")
+	auto := fs.Bool("auto", true, "Capture views automatically when the board is found")
+	interval := fs.Duration("interval", minInterval, "Minimal time between automatically captured views")
+	out := fs.String("out", outPath, "File to save intrinsics to")
+	load := fs.String("load", "", "Intrinsics file to apply instead of calibrating")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "CALIBRATE"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("calibrate")
+
+	patternSize, err := parsePattern(*patternStr)
+	if err != nil {
+		return err
+	}
+	if *square <= 0 || *views < 3 {
+		return errors.New("Square size should be positive and at least 3 views are needed")
+	}
+	var intrinsics *calib.Intrinsics
+	if *load != "" {
+		in, err := calib.Load(*load)
+		if err != nil {
+			return fmt.Errorf("Error loading intrinsics: %v", err)
+		}
+		intrinsics = &in
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Camera calibration")
+	if err != nil {
+		return err
+	}
+	capture, undistort := false, true
+	if window != nil {
+		window.Controls.Keys = append(window.Controls.Keys,
+			videoio.KeyAction{Key: 'c', Help: "capture a view", Do: func() { capture = true }},
+			videoio.KeyAction{Key: 'u', Help: "toggle undistortion", Do: func() { undistort = !undistort }},
+		)
+	}
+
+	var undistorter *calib.Undistorter
+	if intrinsics != nil {
+		undistorter = calib.NewUndistorter(*intrinsics)
+		sd.OnClose("undistorter", undistorter.Close)
+	}
+
+	gray, cornersMat := gocv.NewMat(), gocv.NewMat()
+	defer gray.Close()
+	defer cornersMat.Close()
+	var collected [][]gocv.Point2f
+	var lastCapture time.Time
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		if undistorter != nil {
+			if undistort {
+				stop := stats.Start("undistort")
+				undistorter.Undistort(*img, img)
+				stop()
+			}
+			stats.Frame()
+			metrics.Overlay(img, stats, draw.DefaultStyle)
+			return
+		}
+
+		stop := stats.Start("corners")
+		gocv.CvtColor(*img, &gray, gocv.ColorBGRToGray)
+		corners, found := findCorners(gray, patternSize, &cornersMat)
+		stop()
+		stats.Frame()
+
+		if found && (capture || *auto && time.Since(lastCapture) >= *interval) {
+			collected = append(collected, corners)
+			lastCapture = time.Now()
+			logging.Infof("Captured view %d of %d", len(collected), *views)
+			gocv.BitwiseNot(*img, img)
+		}
+		capture = false
+		if found {
+			gocv.DrawChessboardCorners(img, patternSize, cornersMat, true)
+		}
+
+		if len(collected) >= *views {
+			size := image.Pt(img.Cols(), img.Rows())
+			in := calibrateViews(collected, patternSize, *square, size)
+			logging.Infof("Calibrated with reprojection error %.3f px, fx %.1f, fy %.1f, cx %.1f, cy %.1f",
+				in.RMS, in.CameraMatrix[0], in.CameraMatrix[4], in.CameraMatrix[2], in.CameraMatrix[5])
+			if err := in.Save(*out); err != nil {
+				logging.Errorf("Error saving intrinsics: %v", err)
+			} else {
+				logging.Infof("Intrinsics saved to %s", *out)
+			}
+			undistorter = calib.NewUndistorter(in)
+			sd.OnClose("undistorter", undistorter.Close)
+			return
+		}
+
+		text := fmt.Sprintf("Views: %d of %d", len(collected), *views)
+		if !found {
+			text += " - chessboard not found"
+		}
+		st := draw.DefaultStyle
+		draw.TextWithBackground(img, text, image.Pt(0, st.TextSize(text).Y+2*st.Padding), st)
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	if undistorter == nil {
+		return fmt.Errorf("Input ended after %d of %d views, calibration needs more views", len(collected), *views)
+	}
+	return nil
+}
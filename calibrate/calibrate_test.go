@@ -0,0 +1,18 @@
+package calibrate
+
+import (
+	"image"
+	"testing"
+)
+
+func TestParsePattern(t *testing.T) {
+	got, err := parsePattern("9x6")
+	if err != nil || got != image.Pt(9, 6) {
+		t.Errorf("parsePattern(9x6) = %v, %v", got, err)
+	}
+	for _, s := range []string{"9", "1x6", "ax6", "9x6x1"} {
+		if _, err := parsePattern(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
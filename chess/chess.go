@@ -0,0 +1,186 @@
+// This example recognizes the position on a physical chessboard and prints it in FEN notation
+//
+// Call: gocv-examples chess [-input 0] [-templates chess-templates] [-black-bottom]
+// Input can be a camera ID (default 0), a video file, a stream URL or images
+// The board is the largest quadrilateral of the image, so its edge should contrast with the table.
+// It is rectified to a top-down view of 8x8 squares, and each square is classified by template
+// matching. Templates are learned from the start position: set up the pieces and press L, the
+// templates are then saved to -templates and loaded from there on the next run. Each new position
+// is logged as the piece placement field of FEN, e.g. rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR,
+// and the recognized pieces are drawn on the rectified board next to the video
+// Parameters can also be set with CHESS_* environment variables or a config file, see internal/config
+//
+// White is expected at the bottom of the image, use -black-bottom otherwise. The camera should look
+// down at the board from above and stay in place after learning, since pieces look different from
+// other angles. Side to move, castling and en passant can not be seen on the board and are not output
+
+package chess
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"os"
+
+	"github.com/marchevska/gocv-examples/docscan"
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID        = "0" // Default input
+	templatesDir = "chess-templates"
+	minBoardArea = 0.2 // Smallest board, as part of the image area
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Run recognizes chess positions on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples chess", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	dir := fs.String("templates", templatesDir, "Directory to save piece templates to and load them from")
+	blackBottom := fs.Bool("black-bottom", false, "Black pieces are at the bottom of the image")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "CHESS"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("chess")
+
+	var classifier Classifier
+	defer classifier.Close()
+	if _, err := os.Stat(*dir); err == nil {
+		if err := classifier.Load(*dir); err != nil {
+			return fmt.Errorf("Error loading templates: %v", err)
+		}
+		logging.Infof("Templates loaded from %s", *dir)
+	} else {
+		logging.Infof("No templates yet, set up the start position and press L")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Chess position")
+	if err != nil {
+		return err
+	}
+	learn := false
+	if window != nil {
+		window.Controls.Keys = append(window.Controls.Keys,
+			videoio.KeyAction{Key: 'l', Help: "learn templates from the start position", Do: func() { learn = true }})
+	}
+
+	side := 8 * squareSize
+	dst := gocv.NewPointVectorFromPoints([]image.Point{{0, 0}, {side, 0}, {side, side}, {0, side}})
+	defer dst.Close()
+	gray, board, view := gocv.NewMat(), gocv.NewMat(), gocv.NewMat()
+	defer gray.Close()
+	defer board.Close()
+	defer view.Close()
+	last := ""
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		st := draw.DefaultStyle
+		stop := stats.Start("board")
+		corners, found := docscan.FindQuad(*img, minBoardArea)
+		stop()
+		stats.Frame()
+		if !found {
+			text := "No board found"
+			draw.TextWithBackground(img, text, image.Pt(0, st.TextSize(text).Y+2*st.Padding), st)
+			metrics.Overlay(img, stats, st)
+			return
+		}
+
+		stop = stats.Start("squares")
+		quad := gocv.NewPointVectorFromPoints(corners[:])
+		m := gocv.GetPerspectiveTransform(quad, dst)
+		gocv.WarpPerspective(*img, &board, m, image.Pt(side, side))
+		m.Close()
+		quad.Close()
+		if *blackBottom {
+			gocv.Rotate(board, &board, gocv.Rotate180Clockwise)
+		}
+		gocv.CvtColor(board, &gray, gocv.ColorBGRToGray)
+
+		if learn {
+			learn = false
+			classifier.Learn(gray, startPosition())
+			if err := classifier.Save(*dir); err != nil {
+				logging.Errorf("Error saving templates: %v", err)
+			} else {
+				logging.Infof("Templates learned and saved to %s", *dir)
+			}
+		}
+		var p position
+		if classifier.Ready() {
+			p = classifier.Classify(gray)
+		}
+		stop()
+
+		draw.Outline(img, corners[:], st)
+		text := "Press L with the start position to learn pieces"
+		if classifier.Ready() {
+			text = p.Placement()
+			if text != last {
+				logging.Infof("Position %s", text)
+				last = text
+			}
+			drawPieces(&board, p)
+		}
+		draw.TextWithBackground(img, text, image.Pt(0, st.TextSize(text).Y+2*st.Padding), st)
+		metrics.Overlay(img, stats, st)
+
+		// The rectified board is shown next to the frame, scaled to its height
+		gocv.Resize(board, &view, image.Pt(img.Rows(), img.Rows()), 0, 0, gocv.InterpolationArea)
+		gocv.Hconcat(*img, view, &board)
+		board.CopyTo(img)
+	})
+	if err != nil && err != videoio.ErrStopped {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	return nil
+}
+
+// Draws the letters of the recognized pieces on the rectified board
+func drawPieces(board *gocv.Mat, p position) {
+	st := draw.DefaultStyle
+	for i, piece := range p {
+		if piece == empty {
+			continue
+		}
+		r := squareRect(i)
+		text := string(piece)
+		size := st.TextSize(text)
+		draw.TextWithBackground(board, text, image.Pt(r.Min.X+(r.Dx()-size.X)/2, r.Min.Y+(r.Dy()+size.Y)/2), st)
+	}
+}
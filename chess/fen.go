@@ -0,0 +1,91 @@
+package chess
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pieces are written as in FEN: KQRBNP for white and kqrbnp for black
+const empty = byte(0)
+
+// position holds the piece of each square from a8 to h8, then a7 and so on down to h1,
+// as FEN lists them
+type position [64]byte
+
+// startPosition returns the initial position of a game
+func startPosition() position {
+	var p position
+	back := "rnbqkbnr"
+	for i := 0; i < 8; i++ {
+		p[i] = back[i]
+		p[8+i] = 'p'
+		p[48+i] = 'P'
+		p[56+i] = back[i] - 'a' + 'A'
+	}
+	return p
+}
+
+// Placement returns the piece placement field of FEN, e.g. "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR"
+func (p position) Placement() string {
+	var b strings.Builder
+	for rank := 0; rank < 8; rank++ {
+		if rank > 0 {
+			b.WriteByte('/')
+		}
+		run := 0
+		for file := 0; file < 8; file++ {
+			piece := p[rank*8+file]
+			if piece == empty {
+				run++
+				continue
+			}
+			if run > 0 {
+				fmt.Fprint(&b, run)
+				run = 0
+			}
+			b.WriteByte(piece)
+		}
+		if run > 0 {
+			fmt.Fprint(&b, run)
+		}
+	}
+	return b.String()
+}
+
+// rotate returns the position seen from the other side of the board
+func (p position) rotate() position {
+	var r position
+	for i, piece := range p {
+		r[63-i] = piece
+	}
+	return r
+}
+
+// pieceName returns the file name part of the piece templates: "wk" for the white king,
+// "bp" for a black pawn, "empty" for an empty square
+func pieceName(piece byte) string {
+	switch {
+	case piece == empty:
+		return "empty"
+	case piece >= 'A' && piece <= 'Z':
+		return "w" + string(piece-'A'+'a')
+	}
+	return "b" + string(piece)
+}
+
+// parsePieceName is the inverse of pieceName
+func parsePieceName(name string) (byte, bool) {
+	if name == "empty" {
+		return empty, true
+	}
+	if len(name) != 2 || !strings.ContainsRune("kqrbnp", rune(name[1])) {
+		return 0, false
+	}
+	switch name[0] {
+	case 'w':
+		return name[1] - 'a' + 'A', true
+	case 'b':
+		return name[1], true
+	}
+	return 0, false
+}
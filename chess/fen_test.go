@@ -0,0 +1,48 @@
+package chess
+
+import "testing"
+
+func TestPlacement(t *testing.T) {
+	p := startPosition()
+	if got := p.Placement(); got != "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR" {
+		t.Errorf("Start position %s", got)
+	}
+
+	// 1. e4 c5
+	p[52], p[36] = empty, 'P'
+	p[10], p[26] = empty, 'p'
+	if got := p.Placement(); got != "rnbqkbnr/pp1ppppp/8/2p5/4P3/8/PPPP1PPP/RNBQKBNR" {
+		t.Errorf("After 1. e4 c5: %s", got)
+	}
+	if got := (position{}).Placement(); got != "8/8/8/8/8/8/8/8" {
+		t.Errorf("Empty board %s", got)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	p := startPosition()
+	r := p.rotate()
+	if r[0] != 'R' || r[63] != 'r' || r[3] != 'K' {
+		t.Errorf("Rotated position %s", r.Placement())
+	}
+	if r.rotate() != p {
+		t.Error("Rotating twice should give the same position")
+	}
+}
+
+func TestPieceName(t *testing.T) {
+	for _, piece := range []byte("KQRBNPkqrbnp\x00") {
+		name := pieceName(piece)
+		if got, ok := parsePieceName(name); !ok || got != piece {
+			t.Errorf("parsePieceName(%q) = %q, %v; want %q", name, got, ok, piece)
+		}
+	}
+	if pieceName('K') != "wk" || pieceName('p') != "bp" {
+		t.Errorf("Names %s, %s", pieceName('K'), pieceName('p'))
+	}
+	for _, name := range []string{"", "wx", "xk", "wkk"} {
+		if _, ok := parsePieceName(name); ok {
+			t.Errorf("parsePieceName(%q) should fail", name)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package chess
+
+import (
+	"fmt"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gocv.io/x/gocv"
+)
+
+const (
+	squareSize   = 64 // Side of a square of the rectified board in pixels
+	squareMargin = 4  // Pixels ignored at each edge of a square
+)
+
+// squareRect returns the square i of the rectified board, without the margin
+func squareRect(i int) image.Rectangle {
+	return image.Rect(i%8*squareSize, i/8*squareSize, (i%8+1)*squareSize, (i/8+1)*squareSize).Inset(squareMargin)
+}
+
+// template is a grayscale image of a square with a known piece
+type template struct {
+	Piece byte
+	Img   gocv.Mat
+}
+
+// Classifier recognizes the pieces by matching squares to templates taken from a known position
+type Classifier struct {
+	templates []template
+}
+
+// Learn takes a template of every square of the rectified grayscale board in the known position,
+// replacing the previous templates
+func (c *Classifier) Learn(board gocv.Mat, p position) {
+	c.Close()
+	for i, piece := range p {
+		region := board.Region(squareRect(i))
+		c.templates = append(c.templates, template{Piece: piece, Img: region.Clone()})
+		region.Close()
+	}
+}
+
+// Ready reports whether there are templates to classify with
+func (c *Classifier) Ready() bool {
+	return len(c.templates) > 0
+}
+
+// Classify returns the position of the rectified grayscale board: the piece of each square
+// is that of the most similar template
+func (c *Classifier) Classify(board gocv.Mat) position {
+	var p position
+	result, mask := gocv.NewMat(), gocv.NewMat()
+	defer result.Close()
+	defer mask.Close()
+	for i := range p {
+		square := board.Region(squareRect(i))
+		best := float32(-2)
+		for _, t := range c.templates {
+			gocv.MatchTemplate(square, t.Img, &result, gocv.TmCcoeffNormed, mask)
+			if _, score, _, _ := gocv.MinMaxLoc(result); score > best {
+				best, p[i] = score, t.Piece
+			}
+		}
+		square.Close()
+	}
+	return p
+}
+
+// Save writes the templates to the directory as <piece>_<n>.png, see pieceName
+func (c *Classifier) Save(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for i, t := range c.templates {
+		path := filepath.Join(dir, fmt.Sprintf("%s_%02d.png", pieceName(t.Piece), i))
+		if !gocv.IMWrite(path, t.Img) {
+			return fmt.Errorf("Cannot write template %s", path)
+		}
+	}
+	return nil
+}
+
+// Load reads templates saved with Save
+func (c *Classifier) Load(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	c.Close()
+	for _, f := range files {
+		name := f.Name()
+		if filepath.Ext(name) != ".png" {
+			continue
+		}
+		piece, ok := parsePieceName(strings.SplitN(name, "_", 2)[0])
+		if !ok {
+			continue
+		}
+		img := gocv.IMRead(filepath.Join(dir, name), gocv.IMReadGrayScale)
+		if img.Empty() || img.Cols() != squareSize-2*squareMargin || img.Rows() != squareSize-2*squareMargin {
+			img.Close()
+			return fmt.Errorf("Template %s should be a %dx%d image", name, squareSize-2*squareMargin,
+				squareSize-2*squareMargin)
+		}
+		c.templates = append(c.templates, template{Piece: piece, Img: img})
+	}
+	if len(c.templates) == 0 {
+		return fmt.Errorf("No templates in %s", dir)
+	}
+	return nil
+}
+
+// Close releases the templates
+func (c *Classifier) Close() error {
+	for _, t := range c.templates {
+		t.Img.Close()
+	}
+	c.templates = nil
+	return nil
+}
@@ -0,0 +1,335 @@
+// This example replaces a green or blue backdrop behind the subject with an image or a video
+//
+// Call: gocv-examples chroma-key [-input 0] [-background beach.jpg] [-key green] [-spill 0.5]
+// Input can be a camera ID (default 0), a video file, a stream URL or images. The background can be
+// an image, a video file, which is looped, a camera ID or a stream URL; without it a solid -color is used
+// Trackbars set the hue tolerance, the minimal saturation of the backdrop and the spill suppression,
+// +/- keys also change the spill suppression, M shows the mask of the subject
+// Parameters can also be set with CHROMAKEY_* environment variables or a config file, see internal/config
+//
+// Backdrop pixels are those with the hue of the key color within the tolerance and saturated and
+// bright enough, so that shadows and gray clothes are kept. The mask edge is feathered to blend hair.
+// Light reflected from the backdrop gives the subject a green or blue fringe: spill suppression lowers
+// the key channel where it exceeds both other channels, 0 keeps colors and 1 removes the excess fully
+
+package chromakey
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID       = "0" // Default input
+	keyName     = "green"
+	hueTol      = 20
+	minSat      = 80
+	minVal      = 50
+	spillAmount = 0.5
+	featherSize = 5
+	fillColor   = "0,0,0"
+	spillStep   = 0.1 // Change of the spill suppression by +/- keys
+	maxHueTol   = 90
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Keyer separates the subject from the backdrop and composites it over the background
+type Keyer struct {
+	Backdrop       Backdrop
+	HueTol         int
+	MinSat, MinVal int
+	Spill          float64 // Spill suppression from 0 to 1
+	Feather        int     // Size of the mask edge blur in pixels, 0 for a hard edge
+
+	hsv, mask, alpha, diff gocv.Mat
+	kernel                 gocv.Mat
+}
+
+// NewKeyer creates a keyer for the backdrop, which should be closed after use
+func NewKeyer(b Backdrop) *Keyer {
+	return &Keyer{Backdrop: b, HueTol: hueTol, MinSat: minSat, MinVal: minVal, Spill: spillAmount, Feather: featherSize,
+		hsv: gocv.NewMat(), mask: gocv.NewMat(), alpha: gocv.NewMat(), diff: gocv.NewMat(),
+		kernel: gocv.GetStructuringElement(gocv.MorphEllipse, image.Pt(3, 3))}
+}
+
+// Mask returns the mask of the subject, 255 where the subject is. It is valid until the next call
+func (k *Keyer) Mask(img gocv.Mat) gocv.Mat {
+	gocv.CvtColor(img, &k.hsv, gocv.ColorBGRToHSV)
+	low, high := keyRange(k.Backdrop.Hue, k.HueTol, k.MinSat, k.MinVal)
+	gocv.InRangeWithScalar(k.hsv, gocv.NewScalar(low[0], low[1], low[2], 0), gocv.NewScalar(high[0], high[1], high[2], 0), &k.mask)
+	gocv.BitwiseNot(k.mask, &k.mask)
+	// Remove speckles of the backdrop noise and fill small holes in the subject
+	gocv.MorphologyEx(k.mask, &k.mask, gocv.MorphOpen, k.kernel)
+	gocv.MorphologyEx(k.mask, &k.mask, gocv.MorphClose, k.kernel)
+	return k.mask
+}
+
+// Suppress lowers the key channel of the image where it exceeds both other channels
+func (k *Keyer) Suppress(img *gocv.Mat) {
+	if k.Spill <= 0 {
+		return
+	}
+	channels := gocv.Split(*img)
+	defer func() {
+		for _, c := range channels {
+			c.Close()
+		}
+	}()
+	key := k.Backdrop.Channel
+	others := make([]gocv.Mat, 0, 2)
+	for i, c := range channels {
+		if i != key {
+			others = append(others, c)
+		}
+	}
+	limit := gocv.NewMat()
+	defer limit.Close()
+	gocv.Max(others[0], others[1], &limit)
+	// Subtraction of 8-bit values saturates, so the excess is 0 where the key channel is not dominant
+	excess := gocv.NewMat()
+	defer excess.Close()
+	gocv.Subtract(channels[key], limit, &excess)
+	excess.ConvertToWithParams(&excess, gocv.MatTypeCV8U, float32(k.Spill), 0)
+	gocv.Subtract(channels[key], excess, &channels[key])
+	gocv.Merge(channels, img)
+}
+
+// Composite replaces the backdrop on the image with the background of the same size
+func (k *Keyer) Composite(img *gocv.Mat, background gocv.Mat) {
+	mask := k.Mask(*img)
+	k.Suppress(img)
+
+	// img = background + (img - background) * alpha, in floating point
+	mask.ConvertToWithParams(&k.alpha, gocv.MatTypeCV32F, 1.0/255, 0)
+	if k.Feather > 0 {
+		size := k.Feather | 1
+		gocv.GaussianBlur(k.alpha, &k.alpha, image.Pt(size, size), 0, 0, gocv.BorderReflect101)
+	}
+	gocv.Merge([]gocv.Mat{k.alpha, k.alpha, k.alpha}, &k.alpha)
+	fg, bg := gocv.NewMat(), gocv.NewMat()
+	defer fg.Close()
+	defer bg.Close()
+	img.ConvertTo(&fg, gocv.MatTypeCV32FC3)
+	background.ConvertTo(&bg, gocv.MatTypeCV32FC3)
+	gocv.Subtract(fg, bg, &k.diff)
+	gocv.Multiply(k.diff, k.alpha, &k.diff)
+	gocv.Add(bg, k.diff, &fg)
+	fg.ConvertTo(img, gocv.MatTypeCV8UC3)
+}
+
+// Close releases the buffers
+func (k *Keyer) Close() error {
+	k.hsv.Close()
+	k.mask.Close()
+	k.alpha.Close()
+	k.diff.Close()
+	return k.kernel.Close()
+}
+
+// Background provides frames of the background: a still image or color, or frames of a video,
+// which is reopened at the end
+type Background struct {
+	input string
+	src   videoio.FrameSource // Nil for a still background
+	still gocv.Mat
+	fill  color.RGBA
+	frame gocv.Mat
+}
+
+// OpenBackground opens the background given by the input: an image, a video file, a camera ID
+// or a stream URL, or a solid color if the input is empty. It should be closed after use
+func OpenBackground(input string, fill color.RGBA) (*Background, error) {
+	b := &Background{input: input, fill: fill, still: gocv.NewMat(), frame: gocv.NewMat()}
+	switch ext := strings.ToLower(filepath.Ext(input)); {
+	case input == "":
+	case ext == ".jpg" || ext == ".jpeg" || ext == ".png" || ext == ".bmp":
+		b.still.Close()
+		if b.still = gocv.IMRead(input, gocv.IMReadColor); b.still.Empty() {
+			return nil, fmt.Errorf("Cannot read image %s", input)
+		}
+	default:
+		src, err := videoio.OpenSource(input, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		b.src = src
+	}
+	return b, nil
+}
+
+// Next returns the background of given size, valid until the next call
+func (b *Background) Next(size image.Point) gocv.Mat {
+	if b.src != nil {
+		img, err := b.src.Next()
+		if err == io.EOF {
+			// Loop the video
+			b.src.Close()
+			if b.src, err = videoio.OpenSource(b.input, 0, 0); err == nil {
+				img, err = b.src.Next()
+			}
+		}
+		if err != nil {
+			logging.Errorf("Error reading background, keeping the last frame: %v", err)
+		} else {
+			b.still.Close()
+			b.still = img
+		}
+	}
+	if b.still.Empty() {
+		b.still.Close()
+		b.still = gocv.NewMatWithSizeFromScalar(gocv.NewScalar(float64(b.fill.B), float64(b.fill.G), float64(b.fill.R), 0),
+			size.Y, size.X, gocv.MatTypeCV8UC3)
+	}
+	gocv.Resize(b.still, &b.frame, size, 0, 0, gocv.InterpolationLinear)
+	return b.frame
+}
+
+// Close closes the background source and releases the frames
+func (b *Background) Close() error {
+	if b.src != nil {
+		b.src.Close()
+	}
+	b.still.Close()
+	return b.frame.Close()
+}
+
+// Run replaces the backdrop on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples chroma-key", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	bgInput := fs.String("background", "", "Background image, video file, camera ID or stream URL")
+	colorStr := fs.String("color", fillColor, "Background color B,G,R when no background is given")
+	key := fs.String("key", keyName, "Backdrop color: green or blue")
+	tol := fs.Int("hue-tol", hueTol, "Hue tolerance of the backdrop, from 0 to 90")
+	sat := fs.Int("min-sat", minSat, "Minimal saturation of the backdrop, from 0 to 255")
+	val := fs.Int("min-val", minVal, "Minimal brightness of the backdrop, from 0 to 255")
+	spill := fs.Float64("spill", spillAmount, "Spill suppression, from 0 to 1")
+	feather := fs.Int("feather", featherSize, "Mask edge blur in pixels, 0 for a hard edge")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "CHROMAKEY"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("chroma-key")
+
+	b, ok := backdrops[strings.ToLower(*key)]
+	if !ok {
+		return fmt.Errorf("Key should be green or blue: %q", *key)
+	}
+	fill, err := ParseColor(*colorStr)
+	if err != nil {
+		return err
+	}
+	if *tol < 0 || *tol > maxHueTol || *sat < 0 || *sat > maxSV || *val < 0 || *val > maxSV {
+		return errors.New("Hue tolerance should be from 0 to 90, saturation and brightness from 0 to 255")
+	}
+	if *spill < 0 || *spill > 1 || *feather < 0 {
+		return errors.New("Spill suppression should be from 0 to 1 and feather not negative")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+	bg, err := OpenBackground(*bgInput, fill)
+	if err != nil {
+		return fmt.Errorf("Error opening background: %v", err)
+	}
+	sd.OnClose("background", bg.Close)
+
+	keyer := NewKeyer(b)
+	keyer.HueTol, keyer.MinSat, keyer.MinVal, keyer.Spill, keyer.Feather = *tol, *sat, *val, *spill, *feather
+	sd.OnClose("keyer", keyer.Close)
+
+	window, sink, err := outputs.Open(sd, "Chroma key")
+	if err != nil {
+		return err
+	}
+	showMask := false
+	var tolBar, satBar, spillBar videoio.Slider
+	if window != nil {
+		tolBar = window.CreateTrackbar("Hue tolerance", maxHueTol)
+		tolBar.SetPos(keyer.HueTol)
+		satBar = window.CreateTrackbar("Min saturation", maxSV)
+		satBar.SetPos(keyer.MinSat)
+		spillBar = window.CreateTrackbar("Spill %", 100)
+		spillBar.SetPos(int(keyer.Spill*100 + 0.5))
+		window.Controls.Threshold = func(step int) string {
+			pos := spillBar.GetPos() + int(float64(step)*spillStep*100)
+			if pos < 0 {
+				pos = 0
+			}
+			if pos > 100 {
+				pos = 100
+			}
+			spillBar.SetPos(pos)
+			return fmt.Sprintf("spill suppression %.1f", float64(pos)/100)
+		}
+		window.Controls.Keys = append(window.Controls.Keys,
+			videoio.KeyAction{Key: 'm', Help: "show mask", Do: func() { showMask = !showMask }})
+	}
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		if window != nil {
+			keyer.HueTol, keyer.MinSat, keyer.Spill = tolBar.GetPos(), satBar.GetPos(), float64(spillBar.GetPos())/100
+		}
+		stop := stats.Start("key")
+		if showMask {
+			mask := keyer.Mask(*img)
+			gocv.CvtColor(mask, img, gocv.ColorGrayToBGR)
+		} else {
+			keyer.Composite(img, bg.Next(image.Pt(img.Cols(), img.Rows())))
+		}
+		stop()
+		stats.Frame()
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package chromakey
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// Maximal values of the OpenCV 8-bit HSV channels, hue is in degrees divided by 2
+const (
+	maxHue = 179
+	maxSV  = 255
+)
+
+// Backdrop is a key color: its hue on the OpenCV scale and the BGR channel which spills
+// from the backdrop onto the subject
+type Backdrop struct {
+	Hue     int
+	Channel int
+}
+
+// Supported backdrops by name
+var backdrops = map[string]Backdrop{
+	"green": {Hue: 60, Channel: 1},
+	"blue":  {Hue: 120, Channel: 0},
+}
+
+// keyRange returns the HSV bounds of backdrop pixels: hue within tol of the key hue, saturation
+// and value at least minSat and minVal. Bounds are clamped to the channel ranges
+func keyRange(hue, tol, minSat, minVal int) (low, high [3]float64) {
+	lowHue, highHue := hue-tol, hue+tol
+	if lowHue < 0 {
+		lowHue = 0
+	}
+	if highHue > maxHue {
+		highHue = maxHue
+	}
+	return [3]float64{float64(lowHue), float64(minSat), float64(minVal)},
+		[3]float64{float64(highHue), maxSV, maxSV}
+}
+
+// ParseColor parses a color given as "B,G,R" with values from 0 to 255
+func ParseColor(s string) (color.RGBA, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return color.RGBA{}, fmt.Errorf("Color should be B,G,R from 0 to 255: %q", s)
+	}
+	var bgr [3]uint8
+	for i, p := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || v < 0 || v > 255 {
+			return color.RGBA{}, fmt.Errorf("Color should be B,G,R from 0 to 255: %q", s)
+		}
+		bgr[i] = uint8(v)
+	}
+	return color.RGBA{R: bgr[2], G: bgr[1], B: bgr[0], A: 255}, nil
+}
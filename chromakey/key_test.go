@@ -0,0 +1,32 @@
+package chromakey
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestKeyRange(t *testing.T) {
+	low, high := keyRange(backdrops["green"].Hue, 20, 80, 50)
+	if low != [3]float64{40, 80, 50} || high != [3]float64{80, 255, 255} {
+		t.Errorf("Unexpected green range %v-%v", low, high)
+	}
+	low, high = keyRange(170, 20, 0, 0)
+	if low[0] != 150 || high[0] != maxHue {
+		t.Errorf("Hue range %v-%v should be clamped", low[0], high[0])
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	c, err := ParseColor("255, 128, 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != (color.RGBA{R: 0, G: 128, B: 255, A: 255}) {
+		t.Errorf("Unexpected color %v", c)
+	}
+	for _, s := range []string{"1,2", "0,0,256", "a,0,0", "-1,0,0"} {
+		if _, err := ParseColor(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+// Command bench runs the benchmarks of the examples and prints a table of the results,
+// optionally compared with a previous run, so that performance regressions are visible
+// across refactors. Benchmarks need OpenCV installed, same as the tests.
+//
+// Usage, from the repository root:
+//
+//	go run ./cmd/bench -save bench-old.txt       # record results before a change
+//	go run ./cmd/bench -compare bench-old.txt    # after the change
+//
+// Raw `go test -bench` output saved to a file can be compared as well.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// result holds averaged measurements of a benchmark
+type result struct {
+	nsPerOp     float64
+	bytesPerOp  float64
+	allocsPerOp float64
+	runs        int
+}
+
+func main() {
+	bench := flag.String("bench", ".", "Regular expression selecting benchmarks")
+	count := flag.Int("count", 1, "Number of runs of each benchmark, results are averaged")
+	benchtime := flag.String("benchtime", "", "Run time of each benchmark, e.g. 2s or 100x")
+	pkgs := flag.String("pkg", "./...", "Packages to benchmark, space separated")
+	save := flag.String("save", "", "File to save the raw benchmark output to")
+	compare := flag.String("compare", "", "File with previous benchmark output to compare with")
+	flag.Parse()
+
+	var old map[string]result
+	if *compare != "" {
+		data, err := ioutil.ReadFile(*compare)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error reading previous results:", err)
+			os.Exit(1)
+		}
+		old = parse(bytes.NewReader(data))
+	}
+
+	args := []string{"test", "-run", "^$", "-bench", *bench, "-benchmem", "-count", strconv.Itoa(*count)}
+	if *benchtime != "" {
+		args = append(args, "-benchtime", *benchtime)
+	}
+	args = append(args, strings.Fields(*pkgs)...)
+
+	// Progress is shown while benchmarks run, the output is parsed afterwards
+	var out bytes.Buffer
+	cmd := exec.Command("go", args...)
+	cmd.Stdout = io.MultiWriter(&out, os.Stderr)
+	cmd.Stderr = os.Stderr
+	runErr := cmd.Run()
+
+	if *save != "" {
+		if err := ioutil.WriteFile(*save, out.Bytes(), 0644); err != nil {
+			fmt.Fprintln(os.Stderr, "Error saving results:", err)
+		}
+	}
+
+	printTable(os.Stdout, parse(&out), old)
+	if runErr != nil {
+		fmt.Fprintln(os.Stderr, "Benchmarks failed:", runErr)
+		os.Exit(1)
+	}
+}
+
+// Parses `go test -bench` output into results keyed by package and benchmark name
+func parse(r io.Reader) map[string]result {
+	results := map[string]result{}
+	pkg := ""
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "pkg: ") {
+			pkg = strings.TrimPrefix(line, "pkg: ")
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+
+		// Drop the GOMAXPROCS suffix, e.g. BenchmarkNMS-8
+		name := fields[0]
+		if i := strings.LastIndex(name, "-"); i > 0 {
+			if _, err := strconv.Atoi(name[i+1:]); err == nil {
+				name = name[:i]
+			}
+		}
+		key := pkg + "." + name
+
+		res := results[key]
+		for i := 2; i+1 < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				continue
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				res.nsPerOp += v
+			case "B/op":
+				res.bytesPerOp += v
+			case "allocs/op":
+				res.allocsPerOp += v
+			}
+		}
+		res.runs++
+		results[key] = res
+	}
+
+	for key, res := range results {
+		n := float64(res.runs)
+		results[key] = result{res.nsPerOp / n, res.bytesPerOp / n, res.allocsPerOp / n, res.runs}
+	}
+	return results
+}
+
+func printTable(w io.Writer, results, old map[string]result) {
+	keys := make([]string, 0, len(results))
+	for key := range results {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if old == nil {
+		fmt.Fprintln(tw, "Benchmark\ttime/op\tB/op\tallocs/op\t")
+	} else {
+		fmt.Fprintln(tw, "Benchmark\told time/op\tnew time/op\tdelta\tB/op\tallocs/op\t")
+	}
+	for _, key := range keys {
+		res := results[key]
+		if old == nil {
+			fmt.Fprintf(tw, "%s\t%s\t%.0f\t%.0f\t\n", key, formatNs(res.nsPerOp), res.bytesPerOp, res.allocsPerOp)
+			continue
+		}
+		prev, ok := old[key]
+		oldTime, delta := "-", "new"
+		if ok && prev.nsPerOp > 0 {
+			oldTime = formatNs(prev.nsPerOp)
+			delta = fmt.Sprintf("%+.1f%%", (res.nsPerOp/prev.nsPerOp-1)*100)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%.0f\t%.0f\t\n", key, oldTime, formatNs(res.nsPerOp), delta,
+			res.bytesPerOp, res.allocsPerOp)
+	}
+	tw.Flush()
+}
+
+func formatNs(ns float64) string {
+	switch {
+	case ns >= 1e9:
+		return fmt.Sprintf("%.2fs", ns/1e9)
+	case ns >= 1e6:
+		return fmt.Sprintf("%.2fms", ns/1e6)
+	case ns >= 1e3:
+		return fmt.Sprintf("%.2fµs", ns/1e3)
+	}
+	return fmt.Sprintf("%.0fns", ns)
+}
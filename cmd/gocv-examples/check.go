@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/probe"
+)
+
+// runCheck prints results of all environment checks, including those not required by any example
+func runCheck(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples check", flag.ExitOnError)
+	camera := fs.Int("camera", -1, "ID of a camera to check, negative to skip")
+	var codecs config.StringList
+	fs.Var(&codecs, "codec", "FourCC code of a video codec to check, repeatable (default MJPG)")
+	cuda := fs.Bool("cuda", false, "Require a CUDA device")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if len(codecs) == 0 {
+		codecs = append(codecs, "MJPG")
+	}
+
+	report := probe.Run(probe.Requirements{DNN: true, CUDA: *cuda, Codecs: codecs, Camera: *camera})
+	if err := report.Print(os.Stdout); err != nil {
+		return err
+	}
+	return report.Err()
+}
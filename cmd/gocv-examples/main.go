@@ -0,0 +1,179 @@
+// Command gocv-examples runs the examples of this repository as subcommands of a single binary.
+// Each subcommand accepts its own flags, environment variables and config file as described
+// in the example's package comment, e.g.
+//
+//	go run ./cmd/gocv-examples yolo -input yolo4/img/person.jpg
+//	go run ./cmd/gocv-examples orb -all
+//	go run ./cmd/gocv-examples edit -input video1.avi -output video_edited.avi
+//	go run ./cmd/gocv-examples yolo -h    # flags of a subcommand
+//	go run ./cmd/gocv-examples check -camera 0
+//
+// Examples check the environment at startup and report what is missing with a hint to fix it,
+// the check command prints the results of all checks.
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/marchevska/gocv-examples/agegender"
+	"github.com/marchevska/gocv-examples/ball"
+	"github.com/marchevska/gocv-examples/barcode"
+	"github.com/marchevska/gocv-examples/calibrate"
+	"github.com/marchevska/gocv-examples/chess"
+	"github.com/marchevska/gocv-examples/chromakey"
+	"github.com/marchevska/gocv-examples/coins"
+	"github.com/marchevska/gocv-examples/colorize"
+	"github.com/marchevska/gocv-examples/colortrack"
+	"github.com/marchevska/gocv-examples/dedup"
+	"github.com/marchevska/gocv-examples/denoise"
+	"github.com/marchevska/gocv-examples/dice"
+	"github.com/marchevska/gocv-examples/docscan"
+	"github.com/marchevska/gocv-examples/drowsiness"
+	"github.com/marchevska/gocv-examples/emotion"
+	"github.com/marchevska/gocv-examples/fisheye"
+	"github.com/marchevska/gocv-examples/flow"
+	"github.com/marchevska/gocv-examples/footfall"
+	"github.com/marchevska/gocv-examples/gaze"
+	"github.com/marchevska/gocv-examples/haar"
+	"github.com/marchevska/gocv-examples/hdr"
+	"github.com/marchevska/gocv-examples/hog"
+	"github.com/marchevska/gocv-examples/inpaint"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/matleak"
+	"github.com/marchevska/gocv-examples/keyframes"
+	"github.com/marchevska/gocv-examples/landmarks"
+	"github.com/marchevska/gocv-examples/lanes"
+	"github.com/marchevska/gocv-examples/maskrcnn"
+	"github.com/marchevska/gocv-examples/midas"
+	"github.com/marchevska/gocv-examples/morph"
+	"github.com/marchevska/gocv-examples/motion"
+	"github.com/marchevska/gocv-examples/ocr"
+	editvideo "github.com/marchevska/gocv-examples/orb/edit-video"
+	goorb "github.com/marchevska/gocv-examples/orb/go-orb"
+	"github.com/marchevska/gocv-examples/panorama"
+	"github.com/marchevska/gocv-examples/portrait"
+	"github.com/marchevska/gocv-examples/qrcode"
+	"github.com/marchevska/gocv-examples/review"
+	"github.com/marchevska/gocv-examples/security"
+	"github.com/marchevska/gocv-examples/segmentation"
+	"github.com/marchevska/gocv-examples/shapes"
+	"github.com/marchevska/gocv-examples/sparseflow"
+	"github.com/marchevska/gocv-examples/stereo"
+	"github.com/marchevska/gocv-examples/sudoku"
+	"github.com/marchevska/gocv-examples/superres"
+	"github.com/marchevska/gocv-examples/templatematch"
+	"github.com/marchevska/gocv-examples/textdetect"
+	"github.com/marchevska/gocv-examples/tflite"
+	"github.com/marchevska/gocv-examples/timelapse"
+	"github.com/marchevska/gocv-examples/tracking"
+	"github.com/marchevska/gocv-examples/whiteboard"
+	"github.com/marchevska/gocv-examples/yolo4"
+)
+
+// command is an example exposed as a subcommand
+type command struct {
+	name  string
+	short string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"yolo", "Detect objects with Yolo 4 on images, video, camera or stream", yolo4.Run},
+	{"orb", "Identify playing cards with the ORB algorithm", goorb.Run},
+	{"edit", "Edit a video recorded with the ORB example, adding intro screens and transitions", editvideo.Run},
+	{"landmarks", "Detect faces and fit facial landmarks", landmarks.Run},
+	{"track", "Track an object selected with the mouse (CSRT, KCF or MIL)", tracking.Run},
+	{"motion", "Detect motion with background subtraction and record motion events", motion.Run},
+	{"flow", "Visualize dense optical flow as colors or arrows", flow.Run},
+	{"sparse-flow", "Track corners with Lucas-Kanade optical flow, drawing trails", sparseflow.Run},
+	{"qr", "Find and decode QR codes", qrcode.Run},
+	{"panorama", "Stitch overlapping photos or video frames into a panorama", panorama.Run},
+	{"ocr", "Recognize text with Tesseract OCR", ocr.Run},
+	{"text", "Find text in natural images with the EAST detector", textdetect.Run},
+	{"segment", "Color classes of each pixel with a segmentation network", segmentation.Run},
+	{"mask-rcnn", "Draw instance masks found by Mask R-CNN", maskrcnn.Run},
+	{"superres", "Upscale images with a super-resolution network", superres.Run},
+	{"colorize", "Colorize grayscale photos with a DNN", colorize.Run},
+	{"haar", "Detect faces and eyes with Haar cascades", haar.Run},
+	{"hog", "Detect pedestrians with HOG and compare with Yolo", hog.Run},
+	{"lanes", "Find lane boundaries on dashcam video", lanes.Run},
+	{"scan", "Scan documents from photos with perspective correction", docscan.Run},
+	{"color-track", "Track an object by its HSV color", colortrack.Run},
+	{"ball", "Track a ball and predict its trajectory and bounces", ball.Run},
+	{"template", "Find a template at unknown scale with MatchTemplate", templatematch.Run},
+	{"shapes", "Find and name geometric shapes via contours", shapes.Run},
+	{"calibrate", "Calibrate a camera with a chessboard", calibrate.Run},
+	{"stereo", "Compute a disparity map of a stereo pair", stereo.Run},
+	{"fisheye", "Undistort fisheye video to a rectilinear view", fisheye.Run},
+	{"chroma-key", "Replace a green or blue backdrop with another background", chromakey.Run},
+	{"inpaint", "Remove marked regions from a photo by inpainting", inpaint.Run},
+	{"denoise", "Compare denoising filters in a labeled grid", denoise.Run},
+	{"hdr", "Merge bracketed exposures with Mertens fusion and Debevec", hdr.Run},
+	{"depth", "Estimate depth from a single camera with MiDaS", midas.Run},
+	{"barcode", "Decode EAN and Code 128 barcodes", barcode.Run},
+	{"timelapse", "Capture a timelapse video at regular intervals", timelapse.Run},
+	{"security", "Record motion clips with pre-roll and a disk cap", security.Run},
+	{"footfall", "Count blobs crossing a line with background subtraction", footfall.Run},
+	{"drowsiness", "Raise an alarm when eyes stay closed", drowsiness.Run},
+	{"emotion", "Classify facial emotions with FER+", emotion.Run},
+	{"agegender", "Estimate age and gender of faces", agegender.Run},
+	{"dedup", "Find near-duplicate images with perceptual hashing", dedup.Run},
+	{"keyframes", "Extract keyframes and assemble video highlights", keyframes.Run},
+	{"sudoku", "Find, read and solve Sudoku puzzles", sudoku.Run},
+	{"dice", "Count dice pips and show the total", dice.Run},
+	{"chess", "Recognize a chess position and print it as FEN", chess.Run},
+	{"coins", "Detect coins and sum their value", coins.Run},
+	{"gaze", "Estimate gaze direction from head pose and pupils", gaze.Run},
+	{"portrait", "Blur or replace the background behind a person", portrait.Run},
+	{"morph", "Morph one face photo into another", morph.Run},
+	{"whiteboard", "Draw on the webcam image with a colored pen", whiteboard.Run},
+	{"tflite", "Detect objects with a quantized TensorFlow Lite model on edge devices", tflite.Run},
+	{"review", "Step through exported detections and mark false positives", review.Run},
+	{"check", "Check OpenCV version, DNN backends, CUDA, codecs and cameras", runCheck},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: gocv-examples <command> [flags]")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	tw := tabwriter.NewWriter(os.Stderr, 0, 8, 2, ' ', 0)
+	for _, c := range commands {
+		fmt.Fprintf(tw, "  %s\t%s\n", c.name, c.short)
+	}
+	tw.Flush()
+	fmt.Fprintln(os.Stderr, "Run 'gocv-examples <command> -h' for the flags of a command.")
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run is separated from main so that deferred calls are done before exit
+func run(args []string) int {
+	// Reports unclosed Mats when built with -tags matprofile
+	defer matleak.Report()
+
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage()
+		return 0
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		if err := c.run(args[1:]); err != nil {
+			logging.Errorf("%v", err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n", args[0])
+	usage()
+	return 2
+}
@@ -0,0 +1,233 @@
+// This example detects coins on a flat surface, recognizes them by size and color and sums their value
+//
+// Call: gocv-examples coins [-input 0] [-coins coins.yaml] [-ref 1EUR] [-px-per-mm 0]
+// Input can be a camera ID (default 0), a video file, a stream URL or images
+// Coins are found as circles with the Hough transform. Their diameter in millimeters needs the
+// scale of the image: place the -ref coin alone under the camera and press C. The scale is saved
+// with the coin set to -coins and loaded from there on the next run, or it can be given with -px-per-mm.
+// Each circle is labeled with the coin of the closest diameter within -tol millimeters, preferring
+// coins of its color, and the total value is shown at the top. +/- change the circle detection threshold
+// Parameters can also be set with COINS_* environment variables or a config file, see internal/config
+//
+// Euro coins are built in, other currencies can be described in the YAML file, see CoinSet.
+// The camera should look straight down at the coins from a fixed height, and the light should be
+// diffuse: reflections change the colors, and shadows make circles larger
+
+package coins
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID         = "0" // Default input
+	coinsPath     = "coins.yaml"
+	refCoin       = "1EUR"
+	diameterTol   = 0.8 // Millimeters
+	minRadius     = 10
+	maxRadius     = 150
+	houghParam1   = 100 // Upper Canny threshold
+	houghParam2   = 40  // Accumulator threshold, lower finds more circles
+	houghStep     = 5   // Change of the accumulator threshold by +/- keys
+	centerPart    = 0.4 // Radius of the sampled center, as part of the coin radius
+	ringInnerPart = 0.75
+	ringOuterPart = 0.95
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// circle found by the Hough transform
+type circle struct {
+	Center image.Point
+	Radius int
+}
+
+// Run counts coins on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples coins", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	path := fs.String("coins", coinsPath, "YAML file of the coin set and scale, Euro coins are used if it does not exist")
+	ref := fs.String("ref", refCoin, "Coin used for calibration")
+	pxPerMM := fs.Float64("px-per-mm", 0, "Scale of the image in pixels per millimeter, instead of calibration")
+	tol := fs.Float64("tol", diameterTol, "Maximal difference of the diameter from the coin's one in millimeters")
+	minR := fs.Int("min-radius", minRadius, "Minimal coin radius in pixels")
+	maxR := fs.Int("max-radius", maxRadius, "Maximal coin radius in pixels")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "COINS"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("coins")
+
+	set := Euro
+	if _, err := os.Stat(*path); err == nil {
+		if set, err = LoadCoinSet(*path); err != nil {
+			return fmt.Errorf("Error loading coin set: %v", err)
+		}
+	}
+	if *pxPerMM > 0 {
+		set.PxPerMM = *pxPerMM
+	}
+	refCoin, ok := set.Find(*ref)
+	if !ok {
+		return fmt.Errorf("Reference coin %q is not in the coin set", *ref)
+	}
+	if *minR < 1 || *maxR <= *minR || *tol <= 0 {
+		return errors.New("Radius range should be positive and not empty, tolerance positive")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Coin counter")
+	if err != nil {
+		return err
+	}
+	param2 := houghParam2
+	calibrate := false
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			param2 += step * houghStep
+			if param2 < houghStep {
+				param2 = houghStep
+			}
+			return fmt.Sprintf("circle threshold %d", param2)
+		}
+		window.Controls.Keys = append(window.Controls.Keys,
+			videoio.KeyAction{Key: 'c', Help: "calibrate with the reference coin", Do: func() { calibrate = true }})
+	}
+
+	gray, hsv, circles := gocv.NewMat(), gocv.NewMat(), gocv.NewMat()
+	defer gray.Close()
+	defer hsv.Close()
+	defer circles.Close()
+	last := ""
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("circles")
+		gocv.CvtColor(*img, &gray, gocv.ColorBGRToGray)
+		gocv.MedianBlur(gray, &gray, 5)
+		gocv.HoughCirclesWithParams(gray, &circles, gocv.HoughGradient, 1, float64(2**minR),
+			houghParam1, float64(param2), *minR, *maxR)
+		found := readCircles(circles)
+		stop()
+
+		stop = stats.Start("classify")
+		gocv.CvtColor(*img, &hsv, gocv.ColorBGRToHSV)
+		st := draw.DefaultStyle
+		total, unknown := 0, 0
+		var names []string
+		for _, c := range found {
+			label := fmt.Sprintf("%d px", c.Radius)
+			if set.PxPerMM > 0 {
+				diameter := 2 * float64(c.Radius) / set.PxPerMM
+				color := colorClass(meanHSV(hsv, c, 0, centerPart), meanHSV(hsv, c, ringInnerPart, ringOuterPart))
+				if coin, ok := set.Classify(diameter, color, *tol); ok {
+					label = coin.Name
+					total += coin.Value
+					names = append(names, coin.Name)
+				} else {
+					label = fmt.Sprintf("? %.1f mm %s", diameter, color)
+					unknown++
+				}
+			}
+			gocv.Circle(img, c.Center, c.Radius, st.LineColor, 2)
+			size := st.TextSize(label)
+			draw.TextWithBackground(img, label, c.Center.Add(image.Pt(-size.X/2, size.Y/2)), st)
+		}
+		stop()
+		stats.Frame()
+
+		if calibrate {
+			calibrate = false
+			if len(found) != 1 {
+				logging.Warnf("Calibration needs exactly one coin, found %d", len(found))
+			} else {
+				set.PxPerMM = 2 * float64(found[0].Radius) / refCoin.Diameter
+				logging.Infof("Calibrated with %s: %.2f px/mm", refCoin.Name, set.PxPerMM)
+				if err := set.Save(*path); err != nil {
+					logging.Errorf("Error saving coin set: %v", err)
+				} else {
+					logging.Infof("Coin set and scale saved to %s", *path)
+				}
+			}
+		}
+
+		text := fmt.Sprintf("Place %s alone and press C to calibrate", refCoin.Name)
+		if set.PxPerMM > 0 {
+			text = fmt.Sprintf("%d coins, total %s", len(found), set.Format(total))
+			if unknown > 0 {
+				text += fmt.Sprintf(", %d unknown", unknown)
+			}
+			sort.Strings(names)
+			if summary := text + ": " + strings.Join(names, " "); summary != last {
+				logging.Debugf("%s", summary)
+				last = summary
+			}
+		}
+		draw.TextWithBackground(img, text, image.Pt(0, st.TextSize(text).Y+2*st.Padding), st)
+		metrics.Overlay(img, stats, st)
+	})
+	if err != nil && err != videoio.ErrStopped {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	return nil
+}
+
+// Reads circles of the HoughCircles output
+func readCircles(m gocv.Mat) []circle {
+	var found []circle
+	for i := 0; i < m.Cols(); i++ {
+		v := m.GetVecfAt(0, i)
+		found = append(found, circle{image.Pt(int(v[0]), int(v[1])), int(v[2])})
+	}
+	return found
+}
+
+// Returns the mean HSV of the ring of the circle between the inner and outer parts of its radius
+func meanHSV(hsv gocv.Mat, c circle, inner, outer float64) [3]float64 {
+	mask := gocv.NewMatWithSize(hsv.Rows(), hsv.Cols(), gocv.MatTypeCV8U)
+	defer mask.Close()
+	gocv.Circle(&mask, c.Center, int(outer*float64(c.Radius)), draw.White, -1)
+	if inner > 0 {
+		gocv.Circle(&mask, c.Center, int(inner*float64(c.Radius)), draw.Black, -1)
+	}
+	m := hsv.MeanWithMask(mask)
+	return [3]float64{m.Val1, m.Val2, m.Val3}
+}
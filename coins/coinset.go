@@ -0,0 +1,147 @@
+package coins
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Coin colors
+const (
+	copper  = "copper"
+	gold    = "gold"
+	silver  = "silver"
+	bicolor = "bicolor" // Center and ring of different colors
+)
+
+// Coin is a denomination of a coin set
+type Coin struct {
+	Name     string  `yaml:"name"`
+	Value    int     `yaml:"value"`    // In the smallest unit, e.g. cents
+	Diameter float64 `yaml:"diameter"` // Millimeters
+	Color    string  `yaml:"color"`    // copper, gold, silver or bicolor
+}
+
+// CoinSet describes the coins of a currency and the scale found by calibration. It is stored
+// in a YAML file, so that other currencies can be added without changing the code
+type CoinSet struct {
+	Currency string  `yaml:"currency"`
+	Unit     int     `yaml:"unit"`                // Value of the main unit, e.g. 100 cents per euro
+	PxPerMM  float64 `yaml:"px_per_mm,omitempty"` // Scale of the image found by calibration
+	Coins    []Coin  `yaml:"coins"`
+}
+
+// Euro is the built-in coin set
+var Euro = CoinSet{
+	Currency: "EUR",
+	Unit:     100,
+	Coins: []Coin{
+		{"1c", 1, 16.25, copper},
+		{"2c", 2, 18.75, copper},
+		{"5c", 5, 21.25, copper},
+		{"10c", 10, 19.75, gold},
+		{"20c", 20, 22.25, gold},
+		{"50c", 50, 24.25, gold},
+		{"1EUR", 100, 23.25, bicolor},
+		{"2EUR", 200, 25.75, bicolor},
+	},
+}
+
+// LoadCoinSet reads a coin set from a YAML file
+func LoadCoinSet(path string) (CoinSet, error) {
+	var cs CoinSet
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cs, err
+	}
+	if err := yaml.UnmarshalStrict(data, &cs); err != nil {
+		return cs, fmt.Errorf("Error parsing %s: %v", path, err)
+	}
+	if len(cs.Coins) == 0 || cs.Unit <= 0 {
+		return cs, errors.New("Coin set should have coins and a positive unit")
+	}
+	for _, c := range cs.Coins {
+		switch c.Color {
+		case copper, gold, silver, bicolor:
+		default:
+			return cs, fmt.Errorf("Coin %s has unknown color %q, should be copper, gold, silver or bicolor", c.Name, c.Color)
+		}
+		if c.Diameter <= 0 {
+			return cs, fmt.Errorf("Coin %s should have a positive diameter", c.Name)
+		}
+	}
+	return cs, nil
+}
+
+// Save writes the coin set to a YAML file
+func (cs CoinSet) Save(path string) error {
+	data, err := yaml.Marshal(cs)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// Find returns the coin by its name, ignoring case
+func (cs CoinSet) Find(name string) (Coin, bool) {
+	for _, c := range cs.Coins {
+		if strings.EqualFold(c.Name, name) {
+			return c, true
+		}
+	}
+	return Coin{}, false
+}
+
+// Classify returns the coin with the closest diameter, in millimeters, within tol. Coins of the
+// color are preferred; if none of them is close enough, the color is ignored, since it is less
+// reliable than the size under colored light
+func (cs CoinSet) Classify(diameter float64, color string, tol float64) (Coin, bool) {
+	closest := func(sameColor bool) (Coin, bool) {
+		var best Coin
+		bestDiff := tol
+		found := false
+		for _, c := range cs.Coins {
+			if sameColor && c.Color != color {
+				continue
+			}
+			if d := math.Abs(c.Diameter - diameter); d <= bestDiff {
+				best, bestDiff, found = c, d, true
+			}
+		}
+		return best, found
+	}
+	if c, ok := closest(true); ok {
+		return c, true
+	}
+	return closest(false)
+}
+
+// Format returns the value in the main unit, e.g. "3.45 EUR"
+func (cs CoinSet) Format(value int) string {
+	decimals := int(math.Round(math.Log10(float64(cs.Unit))))
+	return fmt.Sprintf("%.*f %s", decimals, float64(value)/float64(cs.Unit), cs.Currency)
+}
+
+// colorClass names the color of a coin from the mean HSV of its center and its ring, with hue
+// from 0 to 180 as in OpenCV: silver is unsaturated, copper is red-orange and gold is yellow
+func colorClass(center, ring [3]float64) string {
+	c, r := hueClass(center), hueClass(ring)
+	if c != r && (c == silver || r == silver) {
+		return bicolor
+	}
+	return c
+}
+
+func hueClass(hsv [3]float64) string {
+	switch h, s := hsv[0], hsv[1]; {
+	case s < 60:
+		return silver
+	case h < 15 || h > 165:
+		return copper
+	}
+	return gold
+}
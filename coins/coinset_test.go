@@ -0,0 +1,89 @@
+package coins
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		diameter float64
+		color    string
+		want     string
+		ok       bool
+	}{
+		{16.3, copper, "1c", true},
+		{23.0, bicolor, "1EUR", true},
+		{22.0, gold, "20c", true},
+		{23.4, gold, "1EUR", true}, // No gold coin close enough, the size decides
+		{19.9, copper, "10c", true},
+		{30, gold, "", false},
+	}
+	for _, tt := range tests {
+		c, ok := Euro.Classify(tt.diameter, tt.color, 0.8)
+		if ok != tt.ok || c.Name != tt.want {
+			t.Errorf("Classify(%v, %s) = %s, %v; want %s, %v", tt.diameter, tt.color, c.Name, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if s := Euro.Format(345); s != "3.45 EUR" {
+		t.Errorf("Format = %q", s)
+	}
+	yen := CoinSet{Currency: "JPY", Unit: 1}
+	if s := yen.Format(1500); s != "1500 JPY" {
+		t.Errorf("Format = %q", s)
+	}
+}
+
+func TestColorClass(t *testing.T) {
+	tests := []struct {
+		center, ring [3]float64
+		want         string
+	}{
+		{[3]float64{10, 150, 120}, [3]float64{12, 140, 110}, copper},
+		{[3]float64{25, 120, 150}, [3]float64{24, 130, 150}, gold},
+		{[3]float64{90, 20, 180}, [3]float64{25, 120, 150}, bicolor},
+		{[3]float64{25, 120, 150}, [3]float64{90, 20, 180}, bicolor},
+		{[3]float64{90, 20, 180}, [3]float64{100, 30, 170}, silver},
+	}
+	for _, tt := range tests {
+		if got := colorClass(tt.center, tt.ring); got != tt.want {
+			t.Errorf("colorClass(%v, %v) = %s, want %s", tt.center, tt.ring, got, tt.want)
+		}
+	}
+}
+
+func TestCoinSetFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "coins.yaml")
+
+	cs := Euro
+	cs.PxPerMM = 4.2
+	if err := cs.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadCoinSet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, cs) {
+		t.Errorf("Loaded %+v, want %+v", got, cs)
+	}
+
+	ioutil.WriteFile(path, []byte("currency: X\nunit: 1\ncoins:\n- {name: a, value: 1, diameter: 10, color: blue}\n"), 0644)
+	if _, err := LoadCoinSet(path); err == nil {
+		t.Error("Unknown color should fail")
+	}
+	if c, ok := Euro.Find("2eur"); !ok || c.Value != 200 {
+		t.Errorf("Find = %+v, %v", c, ok)
+	}
+}
@@ -0,0 +1,222 @@
+// This example colorizes grayscale photographs with the network of Zhang et al.
+//
+// Call: gocv-examples colorize -input old_photo.jpg [-model colorization_release_v2.caffemodel]
+// Input can be images, a video file, a camera ID or a stream URL. Color images are colorized
+// from their lightness, which shows how well the network guesses the original colors
+// Parameters can also be set with COLORIZE_* environment variables or a config file, see internal/config
+//
+// The Caffe model, its config and the color bins pts_in_hull.npy are downloaded on first run.
+// The image is converted to Lab, the network predicts a distribution over 313 ab color bins
+// from the lightness, and the colors are the annealed mean of the bins, computed in Go because
+// GoCV cannot set the weights of the last layers as the OpenCV sample does. The predicted ab
+// channels are upscaled to the image size and merged with its original lightness
+//
+// Colorful Image Colorization, https://arxiv.org/abs/1603.08511
+
+package colorize
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/models"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	configPath  = "colorization_deploy_v2.prototxt"
+	weightsPath = "colorization_release_v2.caffemodel"
+	pointsPath  = "pts_in_hull.npy"
+	scoresLayer = "conv8_313" // Scores of the color bins, before the layers computing the mean
+	netSize     = 224
+	lightMean   = 50   // Subtracted from L, which is from 0 to 100
+	temperature = 0.38 // Annealed mean temperature, lower gives more saturated colors
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Colorizer predicts the colors of an image from its lightness
+type Colorizer struct {
+	Net    *gocv.Net
+	Points [][2]float32 // ab values of the color bins
+}
+
+// Colorize returns a BGR image with the lightness of img and predicted colors
+func (c *Colorizer) Colorize(img gocv.Mat) (gocv.Mat, error) {
+	lab := gocv.NewMat()
+	defer lab.Close()
+	img.ConvertToWithParams(&lab, gocv.MatTypeCV32FC3, 1.0/255, 0)
+	gocv.CvtColor(lab, &lab, gocv.ColorBGRToLab)
+	planes := gocv.Split(lab)
+	defer func() {
+		for _, p := range planes {
+			p.Close()
+		}
+	}()
+
+	blob := gocv.BlobFromImage(planes[0], 1.0, image.Pt(netSize, netSize), gocv.NewScalar(lightMean, 0, 0, 0), false, false)
+	defer blob.Close()
+	c.Net.SetInput(blob, "")
+	out := c.Net.Forward(scoresLayer)
+	defer out.Close()
+
+	dims := out.Size()
+	if len(dims) != 4 || dims[1] != len(c.Points) {
+		return gocv.Mat{}, fmt.Errorf("Unexpected output shape %v for %d color bins", dims, len(c.Points))
+	}
+	scores, err := out.DataPtrFloat32()
+	if err != nil {
+		return gocv.Mat{}, err
+	}
+	ab := gocv.NewMatWithSize(dims[2], dims[3], gocv.MatTypeCV32FC2)
+	defer ab.Close()
+	abData, err := ab.DataPtrFloat32()
+	if err != nil {
+		return gocv.Mat{}, err
+	}
+	annealedMean(scores, c.Points, dims[2]*dims[3], 1/temperature, abData)
+
+	gocv.Resize(ab, &ab, image.Pt(img.Cols(), img.Rows()), 0, 0, gocv.InterpolationLinear)
+	abPlanes := gocv.Split(ab)
+	planes[1].Close()
+	planes[2].Close()
+	planes[1], planes[2] = abPlanes[0], abPlanes[1]
+	gocv.Merge(planes, &lab)
+	gocv.CvtColor(lab, &lab, gocv.ColorLabToBGR)
+	result := gocv.NewMat()
+	lab.ConvertToWithParams(&result, gocv.MatTypeCV8UC3, 255, 0)
+	return result, nil
+}
+
+// annealedMean writes the interleaved ab values of each pixel to ab, as the mean of the color bins
+// weighted by softmax of their scores multiplied by scale. Scores are planar, one plane per bin
+func annealedMean(scores []float32, points [][2]float32, plane int, scale float32, ab []float32) {
+	for i := 0; i < plane; i++ {
+		max := scores[i]
+		for q := 1; q < len(points); q++ {
+			if s := scores[q*plane+i]; s > max {
+				max = s
+			}
+		}
+		var sum, a, b float32
+		for q, p := range points {
+			w := float32(math.Exp(float64(scale * (scores[q*plane+i] - max))))
+			sum += w
+			a += w * p[0]
+			b += w * p[1]
+		}
+		ab[2*i], ab[2*i+1] = a/sum, b/sum
+	}
+}
+
+// Run colorizes the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples colorize", flag.ExitOnError)
+	input := fs.String("input", "", "Image, directory or glob pattern of images, video file, camera ID or stream URL")
+	model := fs.String("model", weightsPath, "Colorization model weights")
+	modelConfig := fs.String("model-config", configPath, "Colorization model config")
+	pointsFile := fs.String("points", pointsPath, "ab values of the color bins, NumPy array")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "COLORIZE"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("colorize")
+
+	if *input == "" {
+		return errors.New("Input is required, see 'gocv-examples colorize -h'")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{DNN: true, Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	var paths []string
+	for _, name := range []string{*modelConfig, *model, *pointsFile} {
+		path, err := models.Resolve(name)
+		if err != nil {
+			return fmt.Errorf("Error loading model, see 'gocv-examples colorize -h': %v", err)
+		}
+		paths = append(paths, path)
+	}
+	points, err := ReadPoints(paths[2])
+	if err != nil {
+		return fmt.Errorf("Error loading color bins: %v", err)
+	}
+	net := gocv.ReadNet(paths[1], paths[0])
+	if net.Empty() {
+		return errors.New("Error loading model")
+	}
+	sd.OnClose("model", net.Close)
+	colorizer := &Colorizer{Net: &net, Points: points}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Colorization")
+	if err != nil {
+		return err
+	}
+	showOriginal := false
+	if window != nil {
+		window.Controls.Keys = append(window.Controls.Keys, videoio.KeyAction{
+			Key: 'o', Help: "show original / colorized", Do: func() { showOriginal = !showOriginal },
+		})
+	}
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("inference")
+		colored, err := colorizer.Colorize(*img)
+		stop()
+		stats.Frame()
+		if err != nil {
+			logging.Errorf("Error colorizing: %v", err)
+			return
+		}
+		if !showOriginal {
+			colored.CopyTo(img)
+		}
+		colored.Close()
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}
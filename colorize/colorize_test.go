@@ -0,0 +1,64 @@
+package colorize
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// Builds a version 1 NumPy file with the values
+func npyFile(descr string, values interface{}, rows int) []byte {
+	header := []byte("{'descr': '" + descr + "', 'fortran_order': False, 'shape': (" + string(rune('0'+rows)) + ", 2), }\n")
+	var b bytes.Buffer
+	b.WriteString("\x93NUMPY\x01\x00")
+	binary.Write(&b, binary.LittleEndian, uint16(len(header)))
+	b.Write(header)
+	binary.Write(&b, binary.LittleEndian, values)
+	return b.Bytes()
+}
+
+func TestParsePoints(t *testing.T) {
+	for _, tt := range []struct {
+		descr  string
+		values interface{}
+	}{
+		{"<i8", []int64{-90, 50, 0, -10}},
+		{"<f4", []float32{-90, 50, 0, -10}},
+		{"<f8", []float64{-90, 50, 0, -10}},
+	} {
+		points, err := parsePoints(bytes.NewReader(npyFile(tt.descr, tt.values, 2)))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.descr, err)
+		}
+		if len(points) != 2 || points[0] != [2]float32{-90, 50} || points[1] != [2]float32{0, -10} {
+			t.Errorf("%s: got %v", tt.descr, points)
+		}
+	}
+
+	if _, err := parsePoints(bytes.NewReader([]byte("not numpy"))); err == nil {
+		t.Error("Expected error for non-NumPy data")
+	}
+	if _, err := parsePoints(bytes.NewReader(npyFile("<i8", []int64{1, 2}, 2))); err == nil {
+		t.Error("Expected error for truncated data")
+	}
+}
+
+func TestAnnealedMean(t *testing.T) {
+	points := [][2]float32{{-10, 20}, {30, -40}}
+	// Two pixels: equal scores, and a much higher score of the second bin
+	scores := []float32{
+		1, 0, // bin 0
+		1, 100, // bin 1
+	}
+	ab := make([]float32, 4)
+	annealedMean(scores, points, 2, 2.6, ab)
+
+	near := func(a, b float32) bool { return math.Abs(float64(a-b)) < 1e-3 }
+	if !near(ab[0], 10) || !near(ab[1], -10) {
+		t.Errorf("Equal scores should give the mean of the bins, got %v, %v", ab[0], ab[1])
+	}
+	if !near(ab[2], 30) || !near(ab[3], -40) {
+		t.Errorf("Dominant score should give its bin, got %v, %v", ab[2], ab[3])
+	}
+}
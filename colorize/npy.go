@@ -0,0 +1,106 @@
+package colorize
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+var (
+	npyMagic = []byte("\x93NUMPY")
+	descrRe  = regexp.MustCompile(`'descr':\s*'([<>|]?[a-z]\d)'`)
+	shapeRe  = regexp.MustCompile(`'shape':\s*\((\d+),\s*(\d+)\)`)
+)
+
+// ReadPoints reads the ab values of the color bins, an Nx2 NumPy array like pts_in_hull.npy
+func ReadPoints(path string) ([][2]float32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parsePoints(bufio.NewReader(f))
+}
+
+// parsePoints parses a little-endian NumPy array of shape Nx2 with integer or float values
+func parsePoints(r io.Reader) ([][2]float32, error) {
+	var pre [8]byte
+	if _, err := io.ReadFull(r, pre[:]); err != nil {
+		return nil, err
+	}
+	if string(pre[:6]) != string(npyMagic) {
+		return nil, errors.New("Not a NumPy file")
+	}
+	var headerLen int
+	if pre[6] == 1 {
+		var n uint16
+		if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return nil, err
+		}
+		headerLen = int(n)
+	} else {
+		var n uint32
+		if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return nil, err
+		}
+		headerLen = int(n)
+	}
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	descr := descrRe.FindSubmatch(header)
+	shape := shapeRe.FindSubmatch(header)
+	if descr == nil || shape == nil {
+		return nil, fmt.Errorf("Unsupported NumPy header: %s", header)
+	}
+	if cols, _ := strconv.Atoi(string(shape[2])); cols != 2 {
+		return nil, fmt.Errorf("Expected Nx2 array, got shape %sx%s", shape[1], shape[2])
+	}
+	rows, _ := strconv.Atoi(string(shape[1]))
+
+	dtype := string(descr[1])
+	if dtype[0] == '<' || dtype[0] == '|' {
+		dtype = dtype[1:]
+	} else if dtype[0] == '>' {
+		return nil, errors.New("Big-endian NumPy arrays are not supported")
+	}
+	size, _ := strconv.Atoi(dtype[1:])
+	buf := make([]byte, size)
+	points := make([][2]float32, rows)
+	for i := range points {
+		for j := 0; j < 2; j++ {
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return nil, fmt.Errorf("Truncated NumPy data: %v", err)
+			}
+			v, err := decodeValue(dtype, buf)
+			if err != nil {
+				return nil, err
+			}
+			points[i][j] = v
+		}
+	}
+	return points, nil
+}
+
+// Decodes a little-endian value of the NumPy type
+func decodeValue(dtype string, b []byte) (float32, error) {
+	switch dtype {
+	case "i4":
+		return float32(int32(binary.LittleEndian.Uint32(b))), nil
+	case "i8":
+		return float32(int64(binary.LittleEndian.Uint64(b))), nil
+	case "f4":
+		return math.Float32frombits(binary.LittleEndian.Uint32(b)), nil
+	case "f8":
+		return float32(math.Float64frombits(binary.LittleEndian.Uint64(b))), nil
+	}
+	return 0, fmt.Errorf("Unsupported NumPy type %s", dtype)
+}
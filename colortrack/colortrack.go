@@ -0,0 +1,236 @@
+// This example follows an object by its color: the color is sampled from the object, pixels in its
+// HSV range are found on each frame and the largest blob of them is tracked by its centroid
+//
+// Call: gocv-examples color-track [-input 0] [-hsv hmin,smin,vmin,hmax,smax,vmax] [-min-area 300]
+// Draw a rectangle inside the object with the mouse and press Enter or Space to sample its color,
+// press I to sample again. The range can be tuned with the trackbars while tracking, M shows
+// the mask of matching pixels. Without a display the range should be given with -hsv
+// Parameters can also be set with COLORTRACK_* environment variables or a config file, see internal/config
+//
+// Unlike the feature based trackers of the track example, this needs no initial box and finds the
+// object again as soon as it reappears, but any other object of the same color is confused with it.
+// Hue in OpenCV is from 0 to 179, red wraps around 0 and is given like 170,...,10,...
+// GoCV has no mouse callbacks at the moment of writing, so the color is sampled from a selected rectangle
+
+package colortrack
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID       = "0" // Default input
+	windowTitle = "Color tracking"
+	minArea     = 300 // Smallest blob in pixels
+	trailLength = 32
+	hueTol      = 10 // Tolerances of the sampled range
+	satTol      = 60
+	valTol      = 60
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Names of the trackbars, in the order of the range values
+var trackbarNames = []string{"H min", "S min", "V min", "H max", "S max", "V max"}
+
+// SamplePixels returns HSV pixels of the rectangle of an HSV image
+func SamplePixels(hsv gocv.Mat, rect image.Rectangle) [][3]uint8 {
+	rect = rect.Intersect(image.Rect(0, 0, hsv.Cols(), hsv.Rows()))
+	var pixels [][3]uint8
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			v := hsv.GetVecbAt(y, x)
+			pixels = append(pixels, [3]uint8{v[0], v[1], v[2]})
+		}
+	}
+	return pixels
+}
+
+// Threshold finds pixels of the HSV image in the range, handling hue wrapping around 0
+func Threshold(hsv gocv.Mat, r HSVRange, mask *gocv.Mat) {
+	scalar := func(v [3]int) gocv.Scalar {
+		return gocv.NewScalar(float64(v[0]), float64(v[1]), float64(v[2]), 0)
+	}
+	if !r.wraps() {
+		gocv.InRangeWithScalar(hsv, scalar(r.low), scalar(r.high), mask)
+		return
+	}
+	upper := gocv.NewMat()
+	defer upper.Close()
+	gocv.InRangeWithScalar(hsv, scalar(r.low), scalar([3]int{maxHue, r.high[1], r.high[2]}), &upper)
+	gocv.InRangeWithScalar(hsv, scalar([3]int{0, r.low[1], r.low[2]}), scalar(r.high), mask)
+	gocv.BitwiseOr(*mask, upper, mask)
+}
+
+// LargestBlob returns the outline of the largest blob of the mask of at least minArea pixels, or nil
+func LargestBlob(mask gocv.Mat, minArea float64) []image.Point {
+	contours := gocv.FindContours(mask, gocv.RetrievalExternal, gocv.ChainApproxSimple)
+	defer contours.Close()
+	best, bestArea := -1, minArea
+	for i := 0; i < contours.Size(); i++ {
+		if area := gocv.ContourArea(contours.At(i)); area >= bestArea {
+			best, bestArea = i, area
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+	return contours.At(best).ToPoints()
+}
+
+// Run tracks an object by its color on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples color-track", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	rangeStr := fs.String("hsv", "", "HSV range hmin,smin,vmin,hmax,smax,vmax; sampled from the object if not given")
+	area := fs.Float64("min-area", minArea, "Smallest blob in pixels")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "COLORTRACK"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("color-track")
+
+	var r HSVRange
+	if *rangeStr != "" {
+		var err error
+		if r, err = ParseRange(*rangeStr); err != nil {
+			return err
+		}
+	} else if outputs.Enabled() {
+		return errors.New("Without a display the color should be given with -hsv")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, windowTitle)
+	if err != nil {
+		return err
+	}
+	reselect, showMask := *rangeStr == "", false
+	var trackbars []videoio.Slider
+	if window != nil {
+		window.Controls.Keys = append(window.Controls.Keys,
+			videoio.KeyAction{Key: 'i', Help: "sample the color", Do: func() { reselect = true }},
+			videoio.KeyAction{Key: 'm', Help: "show mask", Do: func() { showMask = !showMask }},
+		)
+		for i, name := range trackbarNames {
+			max := maxSV
+			if i%3 == 0 {
+				max = maxHue
+			}
+			trackbars = append(trackbars, window.CreateTrackbar(name, max))
+		}
+	}
+	// Shows the range on the trackbars
+	setTrackbars := func() {
+		for i, tb := range trackbars {
+			if i < 3 {
+				tb.SetPos(r.low[i])
+			} else {
+				tb.SetPos(r.high[i-3])
+			}
+		}
+	}
+	setTrackbars()
+
+	hsv, mask := gocv.NewMat(), gocv.NewMat()
+	defer hsv.Close()
+	defer mask.Close()
+	kernel := gocv.GetStructuringElement(gocv.MorphEllipse, image.Pt(5, 5))
+	defer kernel.Close()
+	var trail []image.Point
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		gocv.CvtColor(*img, &hsv, gocv.ColorBGRToHSV)
+		if reselect {
+			reselect = false
+			rect := gocv.SelectROI(windowTitle, *img)
+			if rect.Empty() {
+				logging.Infof("No object selected, press I to sample the color")
+				return
+			}
+			r = RangeFromSamples(SamplePixels(hsv, rect), hueTol, satTol, valTol)
+			setTrackbars()
+			trail = nil
+			logging.Infof("Tracking HSV range %v", r)
+			return
+		}
+		for i, tb := range trackbars {
+			if i < 3 {
+				r.low[i] = tb.GetPos()
+			} else {
+				r.high[i-3] = tb.GetPos()
+			}
+		}
+
+		stop := stats.Start("threshold")
+		Threshold(hsv, r, &mask)
+		gocv.MorphologyEx(mask, &mask, gocv.MorphOpen, kernel)
+		gocv.MorphologyEx(mask, &mask, gocv.MorphClose, kernel)
+		blob := LargestBlob(mask, *area)
+		stop()
+		stats.Frame()
+
+		if showMask {
+			gocv.CvtColor(mask, img, gocv.ColorGrayToBGR)
+		}
+		if blob != nil {
+			center := Centroid(blob)
+			trail = append(trail, center)
+			if len(trail) > trailLength {
+				trail = trail[len(trail)-trailLength:]
+			}
+			logging.Debugf("Object at %v", center)
+			pv := gocv.NewPointVectorFromPoints(blob)
+			draw.LabelBox(img, gocv.BoundingRect(pv), fmt.Sprintf("%v", center), draw.DefaultStyle)
+			pv.Close()
+			draw.Crosshair(img, center, 10, draw.DefaultStyle.WithColor(draw.Red))
+		} else {
+			trail = nil
+		}
+		for i := 1; i < len(trail); i++ {
+			gocv.Line(img, trail[i-1], trail[i], draw.Red, 2)
+		}
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err != nil && err != videoio.ErrStopped {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package colortrack
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Maximal values of the OpenCV 8-bit HSV channels, hue is in degrees divided by 2
+const (
+	maxHue = 179
+	maxSV  = 255
+)
+
+// HSVRange is the range of HSV values of the tracked color. The hue range wraps around when
+// its low end is greater than the high one, e.g. from 170 to 10 for red
+type HSVRange struct {
+	low, high [3]int
+}
+
+func (r HSVRange) String() string {
+	return fmt.Sprintf("%d,%d,%d,%d,%d,%d", r.low[0], r.low[1], r.low[2], r.high[0], r.high[1], r.high[2])
+}
+
+// wraps tells whether the hue range goes across 0
+func (r HSVRange) wraps() bool {
+	return r.low[0] > r.high[0]
+}
+
+// ParseRange parses the range given as "hmin,smin,vmin,hmax,smax,vmax"
+func ParseRange(s string) (HSVRange, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 6 {
+		return HSVRange{}, fmt.Errorf("HSV range should be hmin,smin,vmin,hmax,smax,vmax: %q", s)
+	}
+	var r HSVRange
+	for i, p := range parts {
+		v, err := strconv.Atoi(strings.TrimSpace(p))
+		max := maxSV
+		if i%3 == 0 {
+			max = maxHue
+		}
+		if err != nil || v < 0 || v > max {
+			return HSVRange{}, fmt.Errorf("HSV range should be hmin,smin,vmin,hmax,smax,vmax with hue up to %d and others up to %d: %q",
+				maxHue, maxSV, s)
+		}
+		if i < 3 {
+			r.low[i] = v
+		} else {
+			r.high[i-3] = v
+		}
+	}
+	return r, nil
+}
+
+// RangeFromSamples returns the range around the mean color of HSV pixels, widened by the tolerances.
+// Hue is averaged on the circle, so that red pixels on both sides of 0 give red
+func RangeFromSamples(pixels [][3]uint8, tolH, tolS, tolV int) HSVRange {
+	if len(pixels) == 0 {
+		return HSVRange{}
+	}
+	var sin, cos, s, v float64
+	for _, p := range pixels {
+		angle := float64(p[0]) * 2 * math.Pi / (maxHue + 1)
+		sin += math.Sin(angle)
+		cos += math.Cos(angle)
+		s += float64(p[1])
+		v += float64(p[2])
+	}
+	n := float64(len(pixels))
+	angle := math.Atan2(sin, cos)
+	if angle < 0 {
+		angle += 2 * math.Pi
+	}
+	hue := int(math.Round(angle*(maxHue+1)/(2*math.Pi))) % (maxHue + 1)
+	sat, val := int(math.Round(s/n)), int(math.Round(v/n))
+
+	clamp := func(x int) int {
+		if x < 0 {
+			return 0
+		}
+		if x > maxSV {
+			return maxSV
+		}
+		return x
+	}
+	return HSVRange{
+		low:  [3]int{(hue - tolH + maxHue + 1) % (maxHue + 1), clamp(sat - tolS), clamp(val - tolV)},
+		high: [3]int{(hue + tolH) % (maxHue + 1), clamp(sat + tolS), clamp(val + tolV)},
+	}
+}
+
+// Centroid returns the center of mass of a polygon, or the mean of its points if its area is zero
+func Centroid(pts []image.Point) image.Point {
+	if len(pts) == 0 {
+		return image.Point{}
+	}
+	var area, cx, cy float64
+	for i, p := range pts {
+		q := pts[(i+1)%len(pts)]
+		cross := float64(p.X*q.Y - q.X*p.Y)
+		area += cross
+		cx += float64(p.X+q.X) * cross
+		cy += float64(p.Y+q.Y) * cross
+	}
+	if area == 0 {
+		var sx, sy int
+		for _, p := range pts {
+			sx += p.X
+			sy += p.Y
+		}
+		return image.Pt(sx/len(pts), sy/len(pts))
+	}
+	return image.Pt(int(math.Round(cx/(3*area))), int(math.Round(cy/(3*area))))
+}
@@ -0,0 +1,49 @@
+package colortrack
+
+import (
+	"image"
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	r, err := ParseRange("170, 100, 50, 10, 255, 255")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.low != [3]int{170, 100, 50} || r.high != [3]int{10, 255, 255} || !r.wraps() {
+		t.Errorf("Unexpected range %v", r)
+	}
+	if r.String() != "170,100,50,10,255,255" {
+		t.Errorf("Unexpected string %q", r.String())
+	}
+	for _, s := range []string{"1,2,3", "180,0,0,10,255,255", "0,0,0,10,256,255", "a,0,0,10,255,255"} {
+		if _, err := ParseRange(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestRangeFromSamples(t *testing.T) {
+	// Green, hue 60
+	r := RangeFromSamples([][3]uint8{{58, 200, 100}, {62, 220, 120}}, 10, 50, 50)
+	if r.low != [3]int{50, 160, 60} || r.high != [3]int{70, 255, 160} || r.wraps() {
+		t.Errorf("Unexpected green range %v", r)
+	}
+
+	// Red on both sides of 0 averages to 0, not to 90
+	r = RangeFromSamples([][3]uint8{{176, 200, 200}, {4, 200, 200}}, 10, 50, 50)
+	if r.low[0] != 170 || r.high[0] != 10 || !r.wraps() {
+		t.Errorf("Unexpected red range %v", r)
+	}
+}
+
+func TestCentroid(t *testing.T) {
+	square := []image.Point{{10, 10}, {30, 10}, {30, 30}, {10, 30}}
+	if c := Centroid(square); c != image.Pt(20, 20) {
+		t.Errorf("Expected square centroid (20,20), got %v", c)
+	}
+	line := []image.Point{{0, 0}, {10, 0}}
+	if c := Centroid(line); c != image.Pt(5, 0) {
+		t.Errorf("Expected degenerate centroid (5,0), got %v", c)
+	}
+}
@@ -0,0 +1,73 @@
+package dedup
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"sort"
+)
+
+// hashValue packs the 8-byte hash computed by img_hash into an integer
+func hashValue(b []byte) uint64 {
+	if len(b) < 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+// hamming returns the number of differing bits of the hashes
+func hamming(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// cluster groups images whose hashes differ by at most maxDist bits, directly or through other
+// images of the group, and returns the groups with more than one image. Indexes in each group
+// and the groups by their first index are sorted
+func cluster(hashes []uint64, maxDist int) [][]int {
+	parent := make([]int, len(hashes))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := range hashes {
+		for j := i + 1; j < len(hashes); j++ {
+			if hamming(hashes[i], hashes[j]) <= maxDist {
+				if ri, rj := find(i), find(j); ri != rj {
+					parent[rj] = ri
+				}
+			}
+		}
+	}
+
+	groups := map[int][]int{}
+	for i := range hashes {
+		r := find(i)
+		groups[r] = append(groups[r], i)
+	}
+	var dups [][]int
+	for _, g := range groups {
+		if len(g) > 1 {
+			sort.Ints(g)
+			dups = append(dups, g)
+		}
+	}
+	sort.Slice(dups, func(i, j int) bool { return dups[i][0] < dups[j][0] })
+	return dups
+}
+
+// keeper returns the index of the group's image to keep: the one with the most pixels,
+// the first one on a tie
+func keeper(group []int, pixels []int) int {
+	best := group[0]
+	for _, i := range group[1:] {
+		if pixels[i] > pixels[best] {
+			best = i
+		}
+	}
+	return best
+}
@@ -0,0 +1,41 @@
+package dedup
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashValue(t *testing.T) {
+	if v := hashValue([]byte{1, 0, 0, 0, 0, 0, 0, 0x80}); v != 1<<56|0x80 {
+		t.Errorf("hashValue = %x", v)
+	}
+	if v := hashValue([]byte{1}); v != 0 {
+		t.Errorf("hashValue of a short hash = %x", v)
+	}
+}
+
+func TestCluster(t *testing.T) {
+	hashes := []uint64{
+		0x0000000000000000,
+		0xffff000000000000, // Far from everything
+		0x0000000000000003, // 2 bits from the first
+		0x000000000000000f, // 2 bits from the third, 4 from the first
+		0xffff000000000001, // 1 bit from the second
+		0x00ff00ff00ff00ff,
+	}
+	got := cluster(hashes, 2)
+	want := [][]int{{0, 2, 3}, {1, 4}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("cluster = %v, want %v", got, want)
+	}
+	if got := cluster(hashes, 0); got != nil {
+		t.Errorf("cluster with distance 0 = %v, want none", got)
+	}
+}
+
+func TestKeeper(t *testing.T) {
+	pixels := []int{100, 400, 400, 50}
+	if k := keeper([]int{0, 1, 2, 3}, pixels); k != 1 {
+		t.Errorf("keeper = %d, want 1", k)
+	}
+}
@@ -0,0 +1,170 @@
+// This example finds near-duplicate images with perceptual hashing
+//
+// Call: gocv-examples dedup -input photos [-hash phash|average] [-max-dist 6] [-move-dir duplicates] [-report dups.csv]
+// A 64-bit hash is computed for every image of the directory or glob pattern, and images whose hashes
+// differ by at most -max-dist bits are grouped, also through other images of the group. In each group
+// the image with the most pixels is kept, and the others are reported and, with -move-dir, moved there.
+// -report saves the groups as CSV: group, file, kept, distance in bits to the kept image
+// Parameters can also be set with DEDUP_* environment variables or a config file, see internal/config
+//
+// The hashes come from the img_hash module of opencv_contrib. pHash compares low frequencies of
+// the DCT, so it finds resized, recompressed and slightly edited copies; average hash is faster
+// and stricter. Neither finds crops or rotated copies. Up to about 8 bits are near-duplicates for pHash
+
+package dedup
+
+import (
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+	"gocv.io/x/gocv/contrib"
+)
+
+const maxDist = 6 // Bits
+
+// Hash algorithms by name
+var hashes = map[string]contrib.ImgHashBase{
+	"phash":   contrib.PHash{},
+	"average": contrib.AverageHash{},
+}
+
+// Run finds duplicates of the images given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples dedup", flag.ExitOnError)
+	input := fs.String("input", "", "Directory or glob pattern of images")
+	hashName := fs.String("hash", "phash", "Hash algorithm: phash or average")
+	dist := fs.Int("max-dist", maxDist, "Maximal number of differing hash bits of near-duplicates")
+	moveDir := fs.String("move-dir", "", "Directory to move duplicates to, they are only reported if empty")
+	report := fs.String("report", "", "CSV file to save the groups of duplicates to")
+	logging.RegisterFlags(fs)
+	if err := config.Load(fs, args, "DEDUP"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("dedup")
+
+	if *input == "" {
+		return errors.New("Input is required, see 'gocv-examples dedup -h'")
+	}
+	hash, ok := hashes[strings.ToLower(*hashName)]
+	if !ok {
+		return fmt.Errorf("Unknown hash %q, available: phash, average", *hashName)
+	}
+	if *dist < 0 || *dist > 64 {
+		return errors.New("Maximal distance should be from 0 to 64 bits")
+	}
+	files, err := videoio.ImageFiles(*input)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	rep := probe.Run(probe.Requirements{Camera: -1})
+	rep.Log()
+	if err := rep.Err(); err != nil {
+		return err
+	}
+
+	// Hashes and sizes of the readable images
+	var paths []string
+	var values []uint64
+	var pixels []int
+	out := gocv.NewMat()
+	defer out.Close()
+	for _, file := range files {
+		if sd.Context().Err() != nil {
+			return nil
+		}
+		img := gocv.IMRead(file, gocv.IMReadColor)
+		if img.Empty() {
+			logging.Warnf("%s: cannot read image", file)
+			img.Close()
+			continue
+		}
+		hash.Compute(img, &out)
+		paths = append(paths, file)
+		values = append(values, hashValue(out.ToBytes()))
+		pixels = append(pixels, img.Rows()*img.Cols())
+		img.Close()
+	}
+	logging.Infof("Hashed %d of %d files", len(paths), len(files))
+
+	groups := cluster(values, *dist)
+	if *moveDir != "" && len(groups) > 0 {
+		if err := os.MkdirAll(*moveDir, 0755); err != nil {
+			return fmt.Errorf("Error creating directory for duplicates: %v", err)
+		}
+	}
+	var rows [][]string
+	dups := 0
+	for g, group := range groups {
+		keep := keeper(group, pixels)
+		logging.Infof("Group %d: keeping %s", g+1, paths[keep])
+		rows = append(rows, []string{strconv.Itoa(g + 1), paths[keep], "true", "0"})
+		for _, i := range group {
+			if i == keep {
+				continue
+			}
+			d := hamming(values[i], values[keep])
+			logging.Infof("Group %d: duplicate %s, %d bits", g+1, paths[i], d)
+			rows = append(rows, []string{strconv.Itoa(g + 1), paths[i], "false", strconv.Itoa(d)})
+			dups++
+			if *moveDir != "" {
+				if err := move(paths[i], *moveDir); err != nil {
+					logging.Errorf("Error moving %s: %v", paths[i], err)
+				}
+			}
+		}
+	}
+	logging.Infof("Found %d duplicates in %d groups", dups, len(groups))
+
+	if *report != "" {
+		if err := writeReport(*report, rows); err != nil {
+			return fmt.Errorf("Error writing report: %v", err)
+		}
+		logging.Infof("Report saved to %s", *report)
+	}
+	return nil
+}
+
+// Moves the file to the directory, adding a number to its name if the directory has such a file
+func move(path, dir string) error {
+	name := filepath.Base(path)
+	ext := filepath.Ext(name)
+	dst := filepath.Join(dir, name)
+	for n := 1; ; n++ {
+		if _, err := os.Stat(dst); os.IsNotExist(err) {
+			break
+		}
+		dst = filepath.Join(dir, fmt.Sprintf("%s_%d%s", strings.TrimSuffix(name, ext), n, ext))
+	}
+	return os.Rename(path, dst)
+}
+
+func writeReport(path string, rows [][]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	w.Write([]string{"group", "file", "kept", "distance"})
+	w.WriteAll(rows)
+	if err := w.Error(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,270 @@
+// This example compares denoising filters side by side, to help choosing one for an input
+//
+// Call: gocv-examples denoise [-input 0] [-noise 20] [-methods nlmeans,bilateral,gaussian,median]
+// Input can be a camera ID (default 0), a video file, a stream URL or images
+// Each frame is shown in a grid with the noisy input first and the result of each method next,
+// labeled with the average time of the method. Gaussian noise with the standard deviation -noise
+// is added to the input first, then the peak signal to noise ratio against the clean input is shown
+// as well, higher is better; with -noise 0 the input is filtered as it is, e.g. a dark video.
+// +/- keys change the added noise
+// With -opencl, scaling and the Gaussian blur run with OpenCL when OpenCV has a device, see internal/ocl
+// Parameters can also be set with DENOISE_* environment variables or a config file, see internal/config
+//
+// Methods:
+//   nlmeans    non-local means (fastNlMeansDenoisingColored), best quality, slowest
+//   bilateral  bilateral filter, keeps edges, fast with a small diameter
+//   gaussian   Gaussian blur, fastest, blurs edges as much as noise
+//   median     median blur, good for salt and pepper noise
+
+package denoise
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/ocl"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID      = "0" // Default input
+	noiseSigma = 20
+	noiseStep  = 5 // Change of the noise by +/- keys
+	maxNoise   = 100
+	nlmH       = 10 // Filter strength of non-local means
+	bilateralD = 9
+	sigmaColor = 75
+	sigmaSpace = 75
+	kernelSize = 5
+	cellScale  = 1.0
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Labels of the methods in the grid
+var methodLabels = map[string]string{
+	"nlmeans":   "NL means",
+	"bilateral": "Bilateral",
+	"gaussian":  "Gaussian",
+	"median":    "Median",
+}
+
+// Filters holds the parameters of the denoising methods
+type Filters struct {
+	H                      float32 // Strength of non-local means, for luminance and color
+	Diameter               int     // Pixel neighborhood of the bilateral filter
+	SigmaColor, SigmaSpace float64
+	KernelSize             int // Odd kernel size of the Gaussian and median blur
+}
+
+// Apply writes the image filtered by the method to dst
+func (f Filters) Apply(method string, img gocv.Mat, dst *gocv.Mat) {
+	switch method {
+	case "nlmeans":
+		gocv.FastNlMeansDenoisingColoredWithParams(img, dst, f.H, f.H, 7, 21)
+	case "bilateral":
+		gocv.BilateralFilter(img, dst, f.Diameter, f.SigmaColor, f.SigmaSpace)
+	case "gaussian":
+		ocl.GaussianBlur(img, dst, image.Pt(f.KernelSize, f.KernelSize), 0, 0, gocv.BorderDefault)
+	case "median":
+		gocv.MedianBlur(img, dst, f.KernelSize)
+	}
+}
+
+// Adds Gaussian noise with the standard deviation to the image
+func addNoise(img *gocv.Mat, sigma float64) {
+	noise := gocv.NewMatWithSize(img.Rows(), img.Cols(), gocv.MatTypeCV32FC3)
+	defer noise.Close()
+	gocv.RandN(&noise, gocv.NewScalar(0, 0, 0, 0), gocv.NewScalar(sigma, sigma, sigma, 0))
+	f := gocv.NewMat()
+	defer f.Close()
+	img.ConvertTo(&f, gocv.MatTypeCV32FC3)
+	gocv.Add(f, noise, &f)
+	f.ConvertTo(img, gocv.MatTypeCV8UC3)
+}
+
+// Returns the mean squared error between 8-bit images of the same size
+func meanSquaredError(a, b gocv.Mat) float64 {
+	diff := gocv.NewMat()
+	defer diff.Close()
+	gocv.AbsDiff(a, b, &diff)
+	diff.ConvertTo(&diff, gocv.MatTypeCV32F)
+	gocv.Multiply(diff, diff, &diff)
+	m := diff.Mean()
+	return (m.Val1 + m.Val2 + m.Val3) / 3
+}
+
+// Returns the panels laid out in a grid, empty cells are black
+func grid(panels []gocv.Mat) gocv.Mat {
+	cols, rows := gridSize(len(panels))
+	blank := gocv.NewMatWithSize(panels[0].Rows(), panels[0].Cols(), panels[0].Type())
+	defer blank.Close()
+	result := gocv.NewMat()
+	for r := 0; r < rows; r++ {
+		row := gocv.NewMat()
+		for c := 0; c < cols; c++ {
+			cell := blank
+			if i := r*cols + c; i < len(panels) {
+				cell = panels[i]
+			}
+			if c == 0 {
+				cell.CopyTo(&row)
+			} else {
+				gocv.Hconcat(row, cell, &row)
+			}
+		}
+		if r == 0 {
+			row.CopyTo(&result)
+		} else {
+			gocv.Vconcat(result, row, &result)
+		}
+		row.Close()
+	}
+	return result
+}
+
+// Run compares the denoising methods on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples denoise", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	noise := fs.Float64("noise", noiseSigma, "Standard deviation of the Gaussian noise added to the input, 0 for none")
+	methodsStr := fs.String("methods", strings.Join(methodNames, ","), "Methods to compare, separated by commas")
+	h := fs.Float64("h", nlmH, "Filter strength of non-local means, larger removes more noise and detail")
+	d := fs.Int("diameter", bilateralD, "Pixel neighborhood diameter of the bilateral filter")
+	sColor := fs.Float64("sigma-color", sigmaColor, "Color sigma of the bilateral filter")
+	sSpace := fs.Float64("sigma-space", sigmaSpace, "Space sigma of the bilateral filter")
+	ksize := fs.Int("ksize", kernelSize, "Odd kernel size of the Gaussian and median blur")
+	scale := fs.Float64("scale", cellScale, "Scale of the grid cells relative to the input")
+	logging.RegisterFlags(fs)
+	ocl.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "DENOISE"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("denoise")
+
+	methods, err := parseMethods(*methodsStr)
+	if err != nil {
+		return err
+	}
+	if *noise < 0 || *noise > maxNoise {
+		return fmt.Errorf("Noise should be from 0 to %d", maxNoise)
+	}
+	if *h <= 0 || *d <= 0 || *ksize < 1 || *ksize%2 == 0 || *scale <= 0 {
+		return errors.New("Filter strength, diameter and scale should be positive, kernel size odd")
+	}
+	filters := Filters{H: float32(*h), Diameter: *d, SigmaColor: *sColor, SigmaSpace: *sSpace, KernelSize: *ksize}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+	ocl.Setup()
+	defer ocl.Report()
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Denoising comparison")
+	if err != nil {
+		return err
+	}
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			*noise = math.Max(0, math.Min(maxNoise, *noise+float64(step)*noiseStep))
+			return fmt.Sprintf("noise %.0f", *noise)
+		}
+	}
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		if *scale != 1 {
+			ocl.Resize(*img, img, image.Pt(int(float64(img.Cols())**scale), int(float64(img.Rows())**scale)),
+				0, 0, gocv.InterpolationArea)
+		}
+		clean := img.Clone()
+		defer clean.Close()
+		synthetic := *noise > 0
+		inputLabel := "Input"
+		if synthetic {
+			addNoise(img, *noise)
+			inputLabel = fmt.Sprintf("Noise %.0f, %.1f dB", *noise, psnr(meanSquaredError(*img, clean)))
+		}
+
+		panels := []gocv.Mat{img.Clone()}
+		labels := []string{inputLabel}
+		for _, m := range methods {
+			out := gocv.NewMat()
+			start := time.Now()
+			filters.Apply(m, *img, &out)
+			stats.Observe(m, time.Since(start))
+			panels = append(panels, out)
+			labels = append(labels, methodLabels[m])
+		}
+		stats.Frame()
+
+		avg := map[string]time.Duration{}
+		for _, st := range stats.Stages() {
+			avg[st.Name] = st.Avg
+		}
+		st := draw.DefaultStyle
+		for i, p := range panels {
+			text := labels[i]
+			if i > 0 {
+				text = fmt.Sprintf("%s, %.1f ms", text, float64(avg[methods[i-1]])/float64(time.Millisecond))
+				if synthetic {
+					text += fmt.Sprintf(", %.1f dB", psnr(meanSquaredError(p, clean)))
+				}
+			}
+			draw.TextWithBackground(&panels[i], text, image.Pt(0, st.TextSize(text).Y+2*st.Padding), st)
+		}
+		logging.Debugf("%v", stats)
+
+		result := grid(panels)
+		for _, p := range panels {
+			p.Close()
+		}
+		result.CopyTo(img)
+		result.Close()
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}
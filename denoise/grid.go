@@ -0,0 +1,52 @@
+package denoise
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Denoising methods by name, in the default order of the grid
+var methodNames = []string{"nlmeans", "bilateral", "gaussian", "median"}
+
+// parseMethods parses a comma separated list of method names, keeping their order
+func parseMethods(s string) ([]string, error) {
+	var methods []string
+	seen := map[string]bool{}
+	for _, p := range strings.Split(s, ",") {
+		name := strings.ToLower(strings.TrimSpace(p))
+		if name == "" || seen[name] {
+			continue
+		}
+		found := false
+		for _, m := range methodNames {
+			found = found || m == name
+		}
+		if !found {
+			return nil, fmt.Errorf("Unknown method %q, should be some of %s", name, strings.Join(methodNames, ", "))
+		}
+		seen[name] = true
+		methods = append(methods, name)
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("At least one method is needed, some of %s", strings.Join(methodNames, ", "))
+	}
+	return methods, nil
+}
+
+// gridSize returns the columns and rows of the most square grid with at least n cells,
+// preferring wider grids for the landscape frames
+func gridSize(n int) (cols, rows int) {
+	cols = int(math.Ceil(math.Sqrt(float64(n))))
+	rows = (n + cols - 1) / cols
+	return cols, rows
+}
+
+// psnr returns the peak signal to noise ratio in decibels of 8-bit images with the mean squared error,
+// +Inf for identical images
+func psnr(mse float64) float64 {
+	if mse <= 0 {
+		return math.Inf(1)
+	}
+	return 10 * math.Log10(255*255/mse)
+}
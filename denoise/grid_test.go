@@ -0,0 +1,39 @@
+package denoise
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseMethods(t *testing.T) {
+	got, err := parseMethods("Gaussian, nlmeans,,gaussian")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"gaussian", "nlmeans"}) {
+		t.Errorf("Unexpected methods %v", got)
+	}
+	for _, s := range []string{"", " , ", "wiener"} {
+		if _, err := parseMethods(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestGridSize(t *testing.T) {
+	for n, want := range map[int][2]int{1: {1, 1}, 2: {2, 1}, 3: {2, 2}, 4: {2, 2}, 5: {3, 2}, 7: {3, 3}} {
+		if cols, rows := gridSize(n); cols != want[0] || rows != want[1] {
+			t.Errorf("gridSize(%d) = %d, %d, want %v", n, cols, rows, want)
+		}
+	}
+}
+
+func TestPSNR(t *testing.T) {
+	if got := psnr(65.025); math.Abs(got-30) > 1e-9 {
+		t.Errorf("psnr(65.025) = %v, want 30", got)
+	}
+	if !math.IsInf(psnr(0), 1) {
+		t.Error("PSNR of identical images should be infinite")
+	}
+}
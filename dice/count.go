@@ -0,0 +1,44 @@
+package dice
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// squareEnough reports whether a rectangle of the size is close enough to a square for a die
+// seen from above: the longer side is at most tol times the shorter one
+func squareEnough(w, h int, tol float64) bool {
+	if w <= 0 || h <= 0 {
+		return false
+	}
+	if w < h {
+		w, h = h, w
+	}
+	return float64(w) <= tol*float64(h)
+}
+
+// summary describes the dice values, e.g. "3 dice, total 12 (2 + 4 + 6)". Values outside 1-6,
+// e.g. of a die lying on an edge, are not counted and shown as ?
+func summary(values []int) string {
+	if len(values) == 0 {
+		return "No dice"
+	}
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	total := 0
+	parts := make([]string, len(sorted))
+	for i, v := range sorted {
+		if v < 1 || v > 6 {
+			parts[i] = "?"
+			continue
+		}
+		parts[i] = fmt.Sprint(v)
+		total += v
+	}
+	noun := "dice"
+	if len(values) == 1 {
+		noun = "die"
+	}
+	return fmt.Sprintf("%d %s, total %d (%s)", len(values), noun, total, strings.Join(parts, " + "))
+}
@@ -0,0 +1,38 @@
+package dice
+
+import "testing"
+
+func TestSquareEnough(t *testing.T) {
+	tests := []struct {
+		w, h int
+		want bool
+	}{
+		{50, 50, true},
+		{50, 60, true},
+		{60, 50, true},
+		{50, 80, false},
+		{0, 50, false},
+	}
+	for _, tt := range tests {
+		if got := squareEnough(tt.w, tt.h, 1.3); got != tt.want {
+			t.Errorf("squareEnough(%d, %d) = %v, want %v", tt.w, tt.h, got, tt.want)
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		values []int
+		want   string
+	}{
+		{nil, "No dice"},
+		{[]int{5}, "1 die, total 5 (5)"},
+		{[]int{6, 2, 4}, "3 dice, total 12 (2 + 4 + 6)"},
+		{[]int{3, 0, 7}, "3 dice, total 3 (? + 3 + ?)"},
+	}
+	for _, tt := range tests {
+		if got := summary(tt.values); got != tt.want {
+			t.Errorf("summary(%v) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// This example counts the pips of dice on a table and shows the total, complementing the playing
+// cards theme of the ORB example
+//
+// Call: gocv-examples dice [-input 0] [-dice light|dark] [-min-area 400] [-max-area 40000]
+// Input can be a camera ID (default 0), a video file, a stream URL or images
+// Dice are found by thresholding with Otsu's method, so they should contrast with the table:
+// light dice on a dark table by default, or dark dice on a light table with -dice dark. Pips are
+// filled by morphological closing, and each roughly square blob from -min-area to -max-area pixels
+// is a die. Its crop is resized to a fixed size, where pips are found with a blob detector
+// Each die is labeled with its value and the total is shown at the top, +/- change the minimal die area
+// Parameters can also be set with DICE_* environment variables or a config file, see internal/config
+//
+// A camera looking down from above works best; dice touching each other merge into one blob
+
+package dice
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID       = "0" // Default input
+	minDieArea  = 400
+	maxDieArea  = 40000
+	dieAreaStep = 100 // Change of the minimal area by +/- keys
+	squareTol   = 1.3 // Maximal ratio of the sides of a die
+	dieSize     = 100 // Side of the resized die crop, pip sizes below are for this size
+	minPipArea  = 20
+	maxPipArea  = 500
+	kernelSize  = 7
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Run counts dice pips on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples dice", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	diceColor := fs.String("dice", "light", "Dice color: light dice on a dark table or dark dice on a light table")
+	minArea := fs.Int("min-area", minDieArea, "Minimal area of a die in pixels")
+	maxArea := fs.Int("max-area", maxDieArea, "Maximal area of a die in pixels")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "DICE"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("dice")
+
+	var darkDice bool
+	switch *diceColor {
+	case "light":
+	case "dark":
+		darkDice = true
+	default:
+		return fmt.Errorf("Dice should be light or dark, got %q", *diceColor)
+	}
+	if *minArea < 1 || *maxArea <= *minArea {
+		return errors.New("Minimal die area should be positive and less than the maximal one")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Dice")
+	if err != nil {
+		return err
+	}
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			*minArea += step * dieAreaStep
+			if *minArea < dieAreaStep {
+				*minArea = dieAreaStep
+			}
+			return fmt.Sprintf("min die area %d px", *minArea)
+		}
+	}
+
+	// Pips are dark on light dice and the other way round
+	params := gocv.NewSimpleBlobDetectorParams()
+	params.SetFilterByColor(true)
+	if darkDice {
+		params.SetBlobColor(255)
+	} else {
+		params.SetBlobColor(0)
+	}
+	params.SetFilterByArea(true)
+	params.SetMinArea(minPipArea)
+	params.SetMaxArea(maxPipArea)
+	params.SetFilterByCircularity(true)
+	params.SetMinCircularity(0.6)
+	pips := gocv.NewSimpleBlobDetectorWithParams(params)
+	defer pips.Close()
+
+	gray, bin, die := gocv.NewMat(), gocv.NewMat(), gocv.NewMat()
+	defer gray.Close()
+	defer bin.Close()
+	defer die.Close()
+	kernel := gocv.GetStructuringElement(gocv.MorphEllipse, image.Pt(kernelSize, kernelSize))
+	defer kernel.Close()
+	last := ""
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("dice")
+		gocv.CvtColor(*img, &gray, gocv.ColorBGRToGray)
+		gocv.GaussianBlur(gray, &gray, image.Pt(5, 5), 0, 0, gocv.BorderDefault)
+		typ := gocv.ThresholdBinary
+		if darkDice {
+			typ = gocv.ThresholdBinaryInv
+		}
+		gocv.Threshold(gray, &bin, 0, 255, typ|gocv.ThresholdOtsu)
+		gocv.MorphologyEx(bin, &bin, gocv.MorphClose, kernel)
+		contours := gocv.FindContours(bin, gocv.RetrievalExternal, gocv.ChainApproxSimple)
+		stop()
+
+		stop = stats.Start("pips")
+		bounds := image.Rect(0, 0, img.Cols(), img.Rows())
+		var values []int
+		var boxes []image.Rectangle
+		for i := 0; i < contours.Size(); i++ {
+			c := contours.At(i)
+			area := gocv.ContourArea(c)
+			if area < float64(*minArea) || area > float64(*maxArea) {
+				continue
+			}
+			rect := gocv.MinAreaRect(c)
+			if !squareEnough(rect.Width, rect.Height, squareTol) {
+				continue
+			}
+			box := rect.BoundingRect.Intersect(bounds)
+			crop := gray.Region(box)
+			gocv.Resize(crop, &die, image.Pt(dieSize, dieSize), 0, 0, gocv.InterpolationArea)
+			crop.Close()
+			values = append(values, len(pips.Detect(die)))
+			boxes = append(boxes, box)
+		}
+		contours.Close()
+		stop()
+		stats.Frame()
+
+		st := draw.DefaultStyle
+		for i, box := range boxes {
+			label := fmt.Sprint(values[i])
+			if values[i] < 1 || values[i] > 6 {
+				label = "?"
+			}
+			draw.LabelBox(img, box, label, st)
+		}
+		text := summary(values)
+		if text != last {
+			logging.Infof("%s", text)
+			last = text
+		}
+		draw.TextWithBackground(img, text, image.Pt(0, st.TextSize(text).Y+2*st.Padding), st)
+		metrics.Overlay(img, stats, st)
+	})
+	if err != nil && err != videoio.ErrStopped {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	return nil
+}
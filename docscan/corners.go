@@ -0,0 +1,40 @@
+package docscan
+
+import (
+	"image"
+	"math"
+)
+
+// OrderCorners returns the corners of a quadrilateral as top-left, top-right, bottom-right and
+// bottom-left: top-left has the smallest x+y, bottom-right the largest, top-right the largest x-y
+// and bottom-left the smallest
+func OrderCorners(pts []image.Point) [4]image.Point {
+	var c [4]image.Point
+	c[0], c[1], c[2], c[3] = pts[0], pts[0], pts[0], pts[0]
+	for _, p := range pts[1:] {
+		if p.X+p.Y < c[0].X+c[0].Y {
+			c[0] = p
+		}
+		if p.X-p.Y > c[1].X-c[1].Y {
+			c[1] = p
+		}
+		if p.X+p.Y > c[2].X+c[2].Y {
+			c[2] = p
+		}
+		if p.X-p.Y < c[3].X-c[3].Y {
+			c[3] = p
+		}
+	}
+	return c
+}
+
+// pageSize returns the size of the top-down view of the ordered corners: the longer of the
+// opposite edges in each direction, so that no detail is lost
+func pageSize(c [4]image.Point) image.Point {
+	dist := func(a, b image.Point) float64 {
+		return math.Hypot(float64(a.X-b.X), float64(a.Y-b.Y))
+	}
+	w := math.Max(dist(c[0], c[1]), dist(c[3], c[2]))
+	h := math.Max(dist(c[0], c[3]), dist(c[1], c[2]))
+	return image.Pt(int(math.Round(w)), int(math.Round(h)))
+}
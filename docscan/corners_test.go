@@ -0,0 +1,22 @@
+package docscan
+
+import (
+	"image"
+	"testing"
+)
+
+func TestOrderCorners(t *testing.T) {
+	want := [4]image.Point{{20, 10}, {310, 30}, {300, 420}, {10, 400}}
+	shuffled := []image.Point{want[2], want[0], want[3], want[1]}
+	if got := OrderCorners(shuffled); got != want {
+		t.Errorf("OrderCorners(%v) = %v, want %v", shuffled, got, want)
+	}
+}
+
+func TestPageSize(t *testing.T) {
+	// Page seen in perspective, the bottom edge is wider than the top one
+	c := [4]image.Point{{100, 0}, {200, 0}, {300, 400}, {0, 400}}
+	if got := pageSize(c); got != image.Pt(300, 412) {
+		t.Errorf("Unexpected page size %v", got)
+	}
+}
@@ -0,0 +1,197 @@
+// This example turns photos of documents into clean top-down scans
+//
+// Call: gocv-examples scan -input "photos/*.jpg" [-out-dir scans] [-color] [-no-gui]
+// All images matching the input, or all images of a directory, are processed and the scans are
+// saved to -out-dir as <name>_scan.png. Each photo is shown with the document outline next to
+// its scan, press any key to go to the next one
+// Parameters can also be set with SCAN_* environment variables or a config file, see internal/config
+//
+// Edges of a downscaled photo are found with Canny, and the largest contour which simplifies
+// to four corners is taken as the page. It is warped to a rectangle of the size of its longer
+// edges, then adaptive thresholding removes shadows and uneven lighting, unless -color is given.
+// The page should contrast with the background and all its corners should be visible
+
+package docscan
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	outDir       = "scans"
+	detectHeight = 500  // Height of the image used to find the page
+	minPageArea  = 0.2  // Smallest page, as part of the image area
+	approxEps    = 0.02 // Contour simplification tolerance, as part of its perimeter
+	blockSize    = 21   // Neighbourhood of adaptive thresholding
+	threshC      = 10   // Subtracted from the neighbourhood mean, higher gives whiter paper
+	outlineWidth = 3
+)
+
+// FindQuad returns the corners of the largest quadrilateral contour of the image covering at least
+// minArea of its area, ordered clockwise from top-left, and false if there is none
+func FindQuad(img gocv.Mat, minArea float64) ([4]image.Point, bool) {
+	scale := float64(img.Rows()) / detectHeight
+	small := gocv.NewMat()
+	defer small.Close()
+	gocv.Resize(img, &small, image.Pt(int(float64(img.Cols())/scale), detectHeight), 0, 0, gocv.InterpolationArea)
+	gocv.CvtColor(small, &small, gocv.ColorBGRToGray)
+	gocv.GaussianBlur(small, &small, image.Pt(5, 5), 0, 0, gocv.BorderDefault)
+	gocv.Canny(small, &small, 75, 200)
+	kernel := gocv.GetStructuringElement(gocv.MorphRect, image.Pt(3, 3))
+	gocv.Dilate(small, &small, kernel)
+	kernel.Close()
+
+	contours := gocv.FindContours(small, gocv.RetrievalList, gocv.ChainApproxSimple)
+	defer contours.Close()
+	best := minArea * float64(small.Rows()*small.Cols())
+	var corners []image.Point
+	for i := 0; i < contours.Size(); i++ {
+		c := contours.At(i)
+		approx := gocv.ApproxPolyDP(c, approxEps*gocv.ArcLength(c, true), true)
+		if approx.Size() == 4 {
+			if area := gocv.ContourArea(approx); area > best {
+				best, corners = area, approx.ToPoints()
+			}
+		}
+		approx.Close()
+	}
+	if corners == nil {
+		return [4]image.Point{}, false
+	}
+	for i, p := range corners {
+		corners[i] = image.Pt(int(float64(p.X)*scale), int(float64(p.Y)*scale))
+	}
+	return OrderCorners(corners), true
+}
+
+// scan warps the page to a top-down view and cleans it up with adaptive thresholding unless
+// color is set
+func scan(img gocv.Mat, corners [4]image.Point, color bool) gocv.Mat {
+	size := pageSize(corners)
+	src := gocv.NewPointVectorFromPoints(corners[:])
+	defer src.Close()
+	dst := gocv.NewPointVectorFromPoints([]image.Point{
+		{0, 0}, {size.X - 1, 0}, {size.X - 1, size.Y - 1}, {0, size.Y - 1},
+	})
+	defer dst.Close()
+	m := gocv.GetPerspectiveTransform(src, dst)
+	defer m.Close()
+
+	page := gocv.NewMat()
+	gocv.WarpPerspective(img, &page, m, size)
+	if color {
+		return page
+	}
+	gocv.CvtColor(page, &page, gocv.ColorBGRToGray)
+	gocv.AdaptiveThreshold(page, &page, 255, gocv.AdaptiveThresholdGaussian, gocv.ThresholdBinary, blockSize, threshC)
+	gocv.CvtColor(page, &page, gocv.ColorGrayToBGR)
+	return page
+}
+
+// Run scans the photos given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples scan", flag.ExitOnError)
+	input := fs.String("input", "", "Image file, directory or glob pattern of images")
+	dir := fs.String("out-dir", outDir, "Output directory")
+	color := fs.Bool("color", false, "Keep colors instead of thresholding the page")
+	var headless videoio.Headless
+	headless.RegisterFlags(fs)
+	logging.RegisterFlags(fs)
+	if err := config.Load(fs, args, "SCAN"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("scan")
+
+	if *input == "" {
+		return errors.New("Input is required, see 'gocv-examples scan -h'")
+	}
+	files, err := videoio.ImageFiles(*input)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return fmt.Errorf("Error creating output directory: %v", err)
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Camera: -1})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	var window *gocv.Window
+	if !headless.Enabled() {
+		window = gocv.NewWindow("Document scanner - press any key for the next photo")
+		defer window.Close()
+	}
+
+	done := 0
+	for _, file := range files {
+		if sd.Context().Err() != nil {
+			break
+		}
+		img := gocv.IMRead(file, gocv.IMReadColor)
+		if img.Empty() {
+			img.Close()
+			continue
+		}
+		corners, ok := FindQuad(img, minPageArea)
+		if !ok {
+			logging.Warnf("%s: no page found", filepath.Base(file))
+			img.Close()
+			continue
+		}
+		page := scan(img, corners, *color)
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		out := filepath.Join(*dir, name+"_scan.png")
+		if gocv.IMWrite(out, page) {
+			logging.Infof("%s: page %v saved to %s", filepath.Base(file), corners, out)
+			done++
+		} else {
+			logging.Errorf("Cannot write %s", out)
+		}
+		if window != nil {
+			show(window, img, corners, page)
+			videoio.WaitForKey(sd.Context(), window)
+		}
+		page.Close()
+		img.Close()
+	}
+	logging.Infof("Scanned %d of %d images to %s", done, len(files), *dir)
+	return nil
+}
+
+// Shows the photo with the page outline next to the scan of the same height
+func show(window *gocv.Window, img gocv.Mat, corners [4]image.Point, page gocv.Mat) {
+	photo := img.Clone()
+	defer photo.Close()
+	st := draw.DefaultStyle
+	st.LineThickness = outlineWidth
+	draw.Outline(&photo, corners[:], st)
+
+	resized := gocv.NewMat()
+	defer resized.Close()
+	w := page.Cols() * photo.Rows() / page.Rows()
+	gocv.Resize(page, &resized, image.Pt(w, photo.Rows()), 0, 0, gocv.InterpolationArea)
+	side := gocv.NewMat()
+	defer side.Close()
+	gocv.Hconcat(photo, resized, &side)
+	window.IMShow(side)
+}
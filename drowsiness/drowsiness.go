@@ -0,0 +1,237 @@
+// This example detects drowsiness: it follows the eye aspect ratio (EAR) of the driver's face
+// and raises a visual and audible alarm when the eyes stay closed for too long
+//
+// Call: gocv-examples drowsiness [-input 0] [-landmarks model.onnx] [-ear-thr 0.21] [-closed 1.5s]
+// The largest face is taken as the driver. EAR is the mean ratio of the height of both eyes to their
+// width, computed from the facial landmarks. When it stays below -ear-thr for -closed, the frame is
+// framed in red with an alert and, unless -beep=false, the terminal bell rings every second
+// The EAR of the last frames is plotted with the threshold, which can be changed with +/- keys
+// Parameters can also be set with DROWSINESS_* environment variables or a config file, see internal/config
+//
+// Faces are detected and landmarks fitted as in the landmarks example, see its package comment
+// for the face detector and the landmark model, which should give the 68-point iBUG 300-W layout.
+// EAR of open eyes differs between people, around 0.25-0.35: look at the plot to choose the threshold.
+//
+// Reference: T. Soukupová and J. Čech, Real-Time Eye Blink Detection using Facial Landmarks, 2016
+
+package drowsiness
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"github.com/marchevska/gocv-examples/landmarks"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID         = "0"                   // Default input
+	landmarksPath = "face_landmarks.onnx" // Default landmark model
+	landmarkSize  = 112                   // Input size of the landmark model
+	faceConfThr   = 0.5
+	earThr        = 0.21
+	earThrStep    = 0.01 // Change of the threshold by +/- keys
+	closedTime    = 1500 * time.Millisecond
+	beepInterval  = time.Second
+	plotLength    = 150 // Frames in the EAR plot
+	plotHeight    = 80
+	plotMaxEAR    = 0.5 // EAR at the top of the plot
+	alarmBorder   = 12
+	alarmText     = "DROWSINESS ALERT"
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Run detects drowsiness on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples drowsiness", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file or stream URL")
+	lmPath := fs.String("landmarks", landmarksPath, "ONNX landmark model with 68 points, see 'gocv-examples landmarks -h'")
+	lmSize := fs.Int("landmark-size", landmarkSize, "Input size of the landmark model")
+	confThr := fs.Float64("conf-thr", faceConfThr, "Face detection confidence threshold")
+	thr := fs.Float64("ear-thr", earThr, "Eye aspect ratio below which the eyes are closed")
+	closed := fs.Duration("closed", closedTime, "Time with closed eyes that raises the alarm")
+	beep := fs.Bool("beep", true, "Ring the terminal bell during the alarm")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "DROWSINESS"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("drowsiness")
+
+	if *thr <= 0 || *closed <= 0 {
+		return errors.New("EAR threshold and closed time should be positive")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{DNN: true, Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	faces, err := landmarks.LoadFaceDetector(sd, *confThr)
+	if err != nil {
+		return err
+	}
+	lmNet, err := landmarks.LoadModel(sd, *lmPath)
+	if err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Drowsiness detection")
+	if err != nil {
+		return err
+	}
+	eyes := closedEyes{Threshold: *thr, Duration: *closed}
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			eyes.Threshold += float64(step) * earThrStep
+			if eyes.Threshold < earThrStep {
+				eyes.Threshold = earThrStep
+			}
+			return fmt.Sprintf("EAR threshold %.2f", eyes.Threshold)
+		}
+	}
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	var history []float64
+	var alarmOn, warned bool
+	var lastBeep time.Time
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("faces")
+		dets, err := faces.Detect(*img)
+		stop()
+		if err != nil {
+			logging.Errorf("Error detecting faces: %v", err)
+		}
+		driver := -1
+		for i, d := range dets {
+			if driver < 0 || area(d.BBox) > area(dets[driver].BBox) {
+				driver = i
+			}
+		}
+
+		st := draw.DefaultStyle
+		now := time.Now()
+		var closedFor time.Duration
+		alarm := alarmOn
+		if driver >= 0 {
+			stop = stats.Start("landmarks")
+			pts := landmarks.Landmarks(lmNet, *img, dets[driver].BBox, *lmSize)
+			stop()
+			if ear, ok := meanEAR(pts); ok {
+				closedFor, alarm = eyes.Update(ear, now)
+				history = append(history, ear)
+				if len(history) > plotLength {
+					history = history[1:]
+				}
+				for _, e := range eyePoints(pts) {
+					gocv.Polylines(img, e, true, st.LineColor, st.LineThickness)
+					e.Close()
+				}
+			} else if !warned {
+				logging.Errorf("Landmark model gives %d points, 68 are needed to find the eyes", len(pts))
+				warned = true
+			}
+			draw.LabelBox(img, dets[driver].BBox, "driver", st)
+		}
+		stats.Frame()
+
+		if alarm != alarmOn {
+			if alarm {
+				logging.Infof("Eyes closed for %v, alarm", closedFor.Round(100*time.Millisecond))
+			} else {
+				logging.Infof("Eyes open, alarm off")
+			}
+			alarmOn = alarm
+		}
+		text := fmt.Sprintf("EAR threshold %.2f", eyes.Threshold)
+		if closedFor > 0 {
+			text += fmt.Sprintf(", eyes closed %.1fs", closedFor.Seconds())
+		}
+		if alarmOn {
+			gocv.Rectangle(img, image.Rect(0, 0, img.Cols(), img.Rows()), draw.Red, alarmBorder)
+			text = alarmText
+			st = st.WithColor(draw.Red)
+			if *beep && now.Sub(lastBeep) >= beepInterval {
+				fmt.Fprint(os.Stdout, "\a")
+				lastBeep = now
+			}
+		}
+		draw.TextWithBackground(img, text, image.Pt(0, st.TextSize(text).Y+2*st.Padding), st)
+		plotEAR(img, history, eyes.Threshold)
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err != nil && err != videoio.ErrStopped {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	return nil
+}
+
+func area(r image.Rectangle) int {
+	return r.Dx() * r.Dy()
+}
+
+// Returns outlines of both eyes of the 68 landmarks, to be closed after drawing
+func eyePoints(pts []image.Point) []gocv.PointsVector {
+	var pvs []gocv.PointsVector
+	for _, e := range eyes {
+		pvs = append(pvs, gocv.NewPointsVectorFromPoints([][]image.Point{pts[e[0]:e[1]]}))
+	}
+	return pvs
+}
+
+// Plots EAR of the last frames in the bottom right corner, with the threshold as a red line
+func plotEAR(img *gocv.Mat, history []float64, thr float64) {
+	if img.Cols() < plotLength || img.Rows() < plotHeight {
+		return
+	}
+	orig := image.Pt(img.Cols()-plotLength, img.Rows())
+	y := func(ear float64) int {
+		if ear > plotMaxEAR {
+			ear = plotMaxEAR
+		}
+		return orig.Y - int(ear/plotMaxEAR*plotHeight)
+	}
+	gocv.Rectangle(img, image.Rect(orig.X, orig.Y-plotHeight, img.Cols(), orig.Y), draw.Black, -1)
+	gocv.Line(img, image.Pt(orig.X, y(thr)), image.Pt(img.Cols(), y(thr)), draw.Red, 1)
+	if len(history) < 2 {
+		return
+	}
+	pts := make([]image.Point, len(history))
+	for i, ear := range history {
+		pts[i] = image.Pt(orig.X+i, y(ear))
+	}
+	pv := gocv.NewPointsVectorFromPoints([][]image.Point{pts})
+	defer pv.Close()
+	gocv.Polylines(img, pv, false, draw.Green, 1)
+}
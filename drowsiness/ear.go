@@ -0,0 +1,59 @@
+package drowsiness
+
+import (
+	"image"
+	"math"
+	"time"
+)
+
+// Eyes in the 68-point iBUG 300-W layout, six points each starting from the outer corner
+var eyes = [2][2]int{{36, 42}, {42, 48}}
+
+// eyeAspectRatio returns the ratio of the eye height to its width for the six eye points p1..p6:
+// (|p2-p6| + |p3-p5|) / (2 |p1-p4|). It is about 0.3 for an open eye and drops towards 0 when it closes
+func eyeAspectRatio(pts []image.Point) float64 {
+	width := dist(pts[0], pts[3])
+	if width == 0 {
+		return 0
+	}
+	return (dist(pts[1], pts[5]) + dist(pts[2], pts[4])) / (2 * width)
+}
+
+// meanEAR returns the mean eye aspect ratio of both eyes of the 68 face landmarks,
+// false for another layout
+func meanEAR(landmarks []image.Point) (float64, bool) {
+	if len(landmarks) != 68 {
+		return 0, false
+	}
+	var sum float64
+	for _, e := range eyes {
+		sum += eyeAspectRatio(landmarks[e[0]:e[1]])
+	}
+	return sum / 2, true
+}
+
+func dist(a, b image.Point) float64 {
+	return math.Hypot(float64(a.X-b.X), float64(a.Y-b.Y))
+}
+
+// closedEyes raises an alarm when the eye aspect ratio stays below Threshold for Duration.
+// Frames without a face keep the state, so that looking down does not reset the timer
+type closedEyes struct {
+	Threshold float64
+	Duration  time.Duration
+	since     time.Time // Start of closed eyes, zero when they are open
+}
+
+// Update takes the eye aspect ratio at time t and returns how long the eyes are closed
+// and whether the alarm is on
+func (c *closedEyes) Update(ear float64, t time.Time) (time.Duration, bool) {
+	if ear >= c.Threshold {
+		c.since = time.Time{}
+		return 0, false
+	}
+	if c.since.IsZero() {
+		c.since = t
+	}
+	closed := t.Sub(c.since)
+	return closed, closed >= c.Duration
+}
@@ -0,0 +1,58 @@
+package drowsiness
+
+import (
+	"image"
+	"math"
+	"testing"
+	"time"
+)
+
+// Six points of an eye 60 px wide and h px high
+func eye(x, h int) []image.Point {
+	return []image.Point{{x, 50}, {x + 20, 50 - h/2}, {x + 40, 50 - h/2}, {x + 60, 50}, {x + 40, 50 + h/2}, {x + 20, 50 + h/2}}
+}
+
+func TestEyeAspectRatio(t *testing.T) {
+	if got := eyeAspectRatio(eye(0, 18)); math.Abs(got-0.3) > 1e-9 {
+		t.Errorf("Open eye EAR = %v, want 0.3", got)
+	}
+	if got := eyeAspectRatio(eye(0, 0)); got != 0 {
+		t.Errorf("Closed eye EAR = %v, want 0", got)
+	}
+}
+
+func TestMeanEAR(t *testing.T) {
+	pts := make([]image.Point, 68)
+	copy(pts[36:], eye(0, 18))
+	copy(pts[42:], eye(100, 6))
+	if got, ok := meanEAR(pts); !ok || math.Abs(got-0.2) > 1e-9 {
+		t.Errorf("meanEAR = %v, %v; want 0.2", got, ok)
+	}
+	if _, ok := meanEAR(pts[:5]); ok {
+		t.Error("meanEAR should need 68 points")
+	}
+}
+
+func TestClosedEyes(t *testing.T) {
+	c := closedEyes{Threshold: 0.2, Duration: time.Second}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	steps := []struct {
+		ear    float64
+		at     time.Duration
+		closed time.Duration
+		alarm  bool
+	}{
+		{0.3, 0, 0, false},
+		{0.1, 100 * time.Millisecond, 0, false},
+		{0.1, 600 * time.Millisecond, 500 * time.Millisecond, false},
+		{0.1, 1100 * time.Millisecond, time.Second, true},
+		{0.3, 1200 * time.Millisecond, 0, false},
+		{0.1, 1300 * time.Millisecond, 0, false},
+	}
+	for i, s := range steps {
+		closed, alarm := c.Update(s.ear, start.Add(s.at))
+		if closed != s.closed || alarm != s.alarm {
+			t.Errorf("Step %d: %v, %v; want %v, %v", i, closed, alarm, s.closed, s.alarm)
+		}
+	}
+}
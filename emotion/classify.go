@@ -0,0 +1,37 @@
+package emotion
+
+import (
+	"image"
+
+	"gocv.io/x/gocv"
+)
+
+// Emotions in the order of the FER+ model output
+var emotions = []string{"neutral", "happiness", "surprise", "sadness", "anger", "disgust", "fear", "contempt"}
+
+// FER+ takes a 64x64 grayscale face with pixel values from 0 to 255
+const inputSize = 64
+
+// classify runs the emotion model on the face and returns the probabilities of the emotions
+func classify(net *gocv.Net, img gocv.Mat, face image.Rectangle) []float64 {
+	face = face.Intersect(image.Rect(0, 0, img.Cols(), img.Rows()))
+	if face.Empty() {
+		return nil
+	}
+	crop := img.Region(face)
+	defer crop.Close()
+	gray := gocv.NewMat()
+	defer gray.Close()
+	gocv.CvtColor(crop, &gray, gocv.ColorBGRToGray)
+	blob := gocv.BlobFromImage(gray, 1, image.Pt(inputSize, inputSize), gocv.NewScalar(0, 0, 0, 0), false, false)
+	defer blob.Close()
+	net.SetInput(blob, "")
+	out := net.Forward("")
+	defer out.Close()
+
+	scores, err := out.DataPtrFloat32()
+	if err != nil || len(scores) != len(emotions) {
+		return nil
+	}
+	return softmax(scores)
+}
@@ -0,0 +1,178 @@
+// This example classifies facial emotions: faces are detected with the OpenCV DNN face detector,
+// and each face is classified with the FER+ model
+//
+// Call: gocv-examples emotion [-input 0] [-model emotion-ferplus-8.onnx] [-conf-thr 0.5]
+// Input can be a camera ID (default 0), a video file, a stream URL or images
+// Each face is labeled with its most probable emotion, and the probabilities of all eight emotions
+// (neutral, happiness, surprise, sadness, anger, disgust, fear, contempt) are shown as bars next to it
+// Parameters can also be set with EMOTION_* environment variables or a config file, see internal/config
+//
+// Face detector files are downloaded on first run and cached, see the landmarks example.
+// The FER+ model is downloaded from the ONNX model zoo:
+// https://github.com/onnx/models/tree/main/vision/body_analysis/emotion_ferplus
+
+package emotion
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/models"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"github.com/marchevska/gocv-examples/landmarks"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID           = "0"                      // Default input
+	modelPath       = "emotion-ferplus-8.onnx" // Default emotion model
+	faceConfThr     = 0.5
+	faceConfThrStep = 0.05 // Change of the threshold by +/- keys
+	barWidth        = 80   // Width of the bar of probability 1
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Run classifies emotions of the faces on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples emotion", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	model := fs.String("model", modelPath, "FER+ ONNX emotion model")
+	confThr := fs.Float64("conf-thr", faceConfThr, "Face detection confidence threshold")
+	bars := fs.Bool("bars", true, "Show probabilities of all emotions next to each face")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "EMOTION"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("emotion")
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{DNN: true, Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	faces, err := landmarks.LoadFaceDetector(sd, *confThr)
+	if err != nil {
+		return err
+	}
+	path, err := models.Resolve(*model)
+	if err != nil {
+		return fmt.Errorf("Error loading emotion model: %v", err)
+	}
+	net := gocv.ReadNetFromONNX(path)
+	if net.Empty() {
+		return errors.New("Error loading emotion model")
+	}
+	sd.OnClose("emotion model", net.Close)
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Emotion classification")
+	if err != nil {
+		return err
+	}
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			faces.ConfThr += float32(step) * faceConfThrStep
+			if faces.ConfThr < faceConfThrStep {
+				faces.ConfThr = faceConfThrStep
+			}
+			return fmt.Sprintf("face confidence %.2f", faces.ConfThr)
+		}
+		window.Controls.Keys = append(window.Controls.Keys,
+			videoio.KeyAction{Key: 'b', Help: "toggle probability bars", Do: func() { *bars = !*bars }})
+	}
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("faces")
+		dets, err := faces.Detect(*img)
+		stop()
+		if err != nil {
+			logging.Errorf("Error detecting faces: %v", err)
+		}
+
+		stop = stats.Start("emotions")
+		probs := make([][]float64, len(dets))
+		for i, d := range dets {
+			probs[i] = classify(&net, *img, d.BBox)
+		}
+		stop()
+		stats.Frame()
+
+		for i, d := range dets {
+			if probs[i] == nil {
+				draw.LabelBox(img, d.BBox, "face", draw.DefaultStyle)
+				continue
+			}
+			b := best(probs[i])
+			draw.LabelBox(img, d.BBox, fmt.Sprintf("%s %.0f%%", emotions[b], probs[i][b]*100), draw.DefaultStyle)
+			if *bars {
+				drawBars(img, d.BBox, probs[i], draw.DefaultStyle)
+			}
+		}
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err != nil && err != videoio.ErrStopped {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	return nil
+}
+
+// Draws a bar with the probability of each emotion to the right of the face, or to the left
+// when there is no room
+func drawBars(img *gocv.Mat, face image.Rectangle, probs []float64, st draw.Style) {
+	textWidth := 0
+	for _, e := range emotions {
+		if w := st.TextSize(e).X; w > textWidth {
+			textWidth = w
+		}
+	}
+	lineHeight := st.TextSize("Ag").Y + 2*st.Padding
+	width := textWidth + barWidth + 3*st.Padding
+	x := face.Max.X + st.Padding
+	if x+width > img.Cols() {
+		x = face.Min.X - st.Padding - width
+	}
+	panel := image.Rect(x, face.Min.Y, x+width, face.Min.Y+lineHeight*len(probs))
+	gocv.Rectangle(img, panel, st.BgColor, -1)
+
+	b := best(probs)
+	for i, p := range probs {
+		top := panel.Min.Y + i*lineHeight
+		gocv.PutText(img, emotions[i], image.Pt(x+st.Padding, top+lineHeight-st.Padding), st.Font, st.FontScale,
+			st.TextColor, st.TextThickness)
+		color := st.LineColor
+		if i == b {
+			color = draw.Red
+		}
+		barX := x + textWidth + 2*st.Padding
+		gocv.Rectangle(img, image.Rect(barX, top+st.Padding, barX+int(p*barWidth)+1, top+lineHeight-st.Padding), color, -1)
+	}
+}
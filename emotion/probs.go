@@ -0,0 +1,32 @@
+package emotion
+
+import "math"
+
+// softmax turns the scores of the model into probabilities
+func softmax(scores []float32) []float64 {
+	max := math.Inf(-1)
+	for _, s := range scores {
+		max = math.Max(max, float64(s))
+	}
+	probs := make([]float64, len(scores))
+	var sum float64
+	for i, s := range scores {
+		probs[i] = math.Exp(float64(s) - max)
+		sum += probs[i]
+	}
+	for i := range probs {
+		probs[i] /= sum
+	}
+	return probs
+}
+
+// best returns the index of the most probable emotion
+func best(probs []float64) int {
+	b := 0
+	for i, p := range probs {
+		if p > probs[b] {
+			b = i
+		}
+	}
+	return b
+}
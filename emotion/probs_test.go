@@ -0,0 +1,31 @@
+package emotion
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSoftmax(t *testing.T) {
+	probs := softmax([]float32{1, 2, 3, 1000})
+	var sum float64
+	for _, p := range probs {
+		sum += p
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("Sum of probabilities %v, want 1", sum)
+	}
+	if best(probs) != 3 || probs[3] < 0.999 {
+		t.Errorf("Probabilities %v, the last one should dominate", probs)
+	}
+
+	probs = softmax([]float32{0, math.Float32frombits(0x3f317218)}) // ln 2
+	if math.Abs(probs[1]/probs[0]-2) > 1e-6 {
+		t.Errorf("Ratio of probabilities %v, want 2", probs[1]/probs[0])
+	}
+}
+
+func TestBest(t *testing.T) {
+	if b := best([]float64{0.1, 0.5, 0.4}); b != 1 {
+		t.Errorf("best = %d, want 1", b)
+	}
+}
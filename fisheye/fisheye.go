@@ -0,0 +1,151 @@
+// This example remaps video of a fisheye camera, e.g. an action camera, to a rectilinear view
+// where straight lines stay straight
+//
+// Call: gocv-examples fisheye -calib fisheye.yaml [-input 0] [-scale 0.7]
+// Input can be a camera ID (default 0), a video file, a stream URL or images
+// +/- keys change the field of view of the output, U toggles undistortion
+// Parameters can also be set with FISHEYE_* environment variables or a config file, see internal/config
+//
+// Intrinsics are read from a file in the format of internal/calib with "model: fisheye" and
+// the 4 coefficients k1 to k4 of the OpenCV fisheye model. Fisheye calibration is not exposed by
+// GoCV at the moment of writing, so the parameters should be found with another tool, e.g.
+// cv2.fisheye.calibrate in Python, and copied to the file. Intrinsics are scaled to the frame size
+//
+// The output view has the focal length of the camera multiplied by -scale: 1 keeps the center
+// sharp but crops much of the image, smaller values show a wider field of view with stretched corners
+
+package fisheye
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/marchevska/gocv-examples/internal/calib"
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID     = "0" // Default input
+	viewScale = 0.7
+	minScale  = 0.2
+	maxScale  = 2.0
+	scaleStep = 0.05 // Change of the scale by +/- keys
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// fieldOfView returns the horizontal field of view in degrees of a rectilinear view of given width
+// and focal length in pixels
+func fieldOfView(width int, focal float64) float64 {
+	return 2 * math.Atan(float64(width)/2/focal) * 180 / math.Pi
+}
+
+// Run undistorts the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples fisheye", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	calibPath := fs.String("calib", "", "Fisheye intrinsics file, see internal/calib")
+	scale := fs.Float64("scale", viewScale, fmt.Sprintf("Focal length multiplier of the output view, from %.1f to %.1f, "+
+		"smaller values show a wider field of view", minScale, maxScale))
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "FISHEYE"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("fisheye")
+
+	if *calibPath == "" {
+		return errors.New("Intrinsics file is required, see 'gocv-examples fisheye -h'")
+	}
+	if *scale < minScale || *scale > maxScale {
+		return fmt.Errorf("Scale should be from %.1f to %.1f", minScale, maxScale)
+	}
+	in, err := calib.Load(*calibPath)
+	if err != nil {
+		return fmt.Errorf("Error loading intrinsics: %v", err)
+	}
+	if in.Model != calib.Fisheye {
+		logging.Warnf("Intrinsics in %s are not of the fisheye model, they are applied with the pinhole model", *calibPath)
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	undistorter := calib.NewUndistorter(in)
+	undistorter.Scale = *scale
+	sd.OnClose("undistorter", undistorter.Close)
+
+	window, sink, err := outputs.Open(sd, "Fisheye undistortion")
+	if err != nil {
+		return err
+	}
+	undistort := true
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			undistorter.Scale = math.Max(minScale, math.Min(maxScale, undistorter.Scale-float64(step)*scaleStep))
+			return fmt.Sprintf("scale %.2f", undistorter.Scale)
+		}
+		window.Controls.Keys = append(window.Controls.Keys,
+			videoio.KeyAction{Key: 'u', Help: "toggle undistortion", Do: func() { undistort = !undistort }})
+	}
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		text := "Original"
+		if undistort {
+			stop := stats.Start("undistort")
+			undistorter.Undistort(*img, img)
+			stop()
+			focal := in.Scaled(image.Pt(img.Cols(), img.Rows())).CameraMatrix[0] * undistorter.Scale
+			text = fmt.Sprintf("Field of view %.0f deg", fieldOfView(img.Cols(), focal))
+		}
+		stats.Frame()
+
+		st := draw.DefaultStyle
+		draw.TextWithBackground(img, text, image.Pt(0, st.TextSize(text).Y+2*st.Padding), st)
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package fisheye
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFieldOfView(t *testing.T) {
+	for _, c := range []struct {
+		width int
+		focal float64
+		want  float64
+	}{
+		{640, 320, 90},
+		{1000, 500 / math.Tan(math.Pi/3), 120},
+		{640, 320 / math.Tan(math.Pi/12), 30},
+	} {
+		if got := fieldOfView(c.width, c.focal); math.Abs(got-c.want) > 1e-6 {
+			t.Errorf("fieldOfView(%d, %v) = %v, want %v", c.width, c.focal, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+// This example computes dense optical flow between consecutive frames and visualizes it
+//
+// Call: gocv-examples flow [-input 0] [-view hsv|arrows] [-scale 0.5] [-step 16] [-opencl]
+// With -view hsv the direction of motion is shown as hue and its speed as brightness,
+// with -view arrows motion vectors are drawn on a grid over the frame
+// Frames are downscaled by -scale before the flow is computed, which is much faster
+// With -opencl, downscaling and the flow run with OpenCL when OpenCV has a device, see internal/ocl
+// Parameters can also be set with FLOW_* environment variables or a config file, see internal/config
+//
+// Gunnar Farneback's algorithm is used. DIS optical flow is not exposed by GoCV at the moment of writing
+// https://docs.opencv.org/4.x/d4/dee/tutorial_optical_flow.html
+
+package flow
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"math"
+	"strings"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/ocl"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID        = "0" // Default input
+	defaultScale = 0.5
+	arrowStep    = 16  // Grid step of arrows in pixels of the frame
+	minArrow     = 1.0 // Shorter motion vectors are not drawn, in pixels of the frame
+)
+
+// Parameters of Farneback's algorithm, as in the OpenCV tutorial
+const (
+	pyrScale   = 0.5
+	levels     = 3
+	winSize    = 15
+	iterations = 3
+	polyN      = 5
+	polySigma  = 1.2
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Views of the flow
+const (
+	viewHSV    = "hsv"
+	viewArrows = "arrows"
+)
+
+// Run computes optical flow on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples flow", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	method := fs.String("method", "farneback", "Optical flow algorithm, only farneback is available")
+	view := fs.String("view", viewHSV, "Visualization: hsv or arrows")
+	scale := fs.Float64("scale", defaultScale, "Downscale factor of frames before computing the flow, (0, 1]")
+	step := fs.Int("step", arrowStep, "Grid step of arrows in pixels")
+	logging.RegisterFlags(fs)
+	ocl.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "FLOW"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("flow")
+
+	switch strings.ToLower(*method) {
+	case "farneback":
+	case "dis":
+		return errors.New("DIS optical flow is not available in GoCV, use -method farneback")
+	default:
+		return fmt.Errorf("Unknown optical flow method %q", *method)
+	}
+	*view = strings.ToLower(*view)
+	if *view != viewHSV && *view != viewArrows {
+		return fmt.Errorf("Unknown view %q, available: hsv, arrows", *view)
+	}
+	if *scale <= 0 || *scale > 1 {
+		return errors.New("Scale should be in (0, 1]")
+	}
+	if *step < 2 {
+		return errors.New("Arrow step should be at least 2 pixels")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+	ocl.Setup()
+	defer ocl.Report()
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	_, sink, err := outputs.Open(sd, "Optical flow")
+	if err != nil {
+		return err
+	}
+
+	prev, gray, flow := gocv.NewMat(), gocv.NewMat(), gocv.NewMat()
+	defer prev.Close()
+	defer gray.Close()
+	defer flow.Close()
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("flow")
+		gocv.CvtColor(*img, &gray, gocv.ColorBGRToGray)
+		if *scale < 1 {
+			ocl.Resize(gray, &gray, image.Point{}, *scale, *scale, gocv.InterpolationArea)
+		}
+		if prev.Empty() || prev.Cols() != gray.Cols() || prev.Rows() != gray.Rows() {
+			gray.CopyTo(&prev)
+			stop()
+			return
+		}
+		ocl.CalcOpticalFlowFarneback(prev, gray, &flow, pyrScale, levels, winSize, iterations, polyN, polySigma, 0)
+		gray.CopyTo(&prev)
+		stop()
+
+		stop = stats.Start("render")
+		if *view == viewHSV {
+			hsv := FlowToBGR(flow)
+			ocl.Resize(hsv, img, image.Pt(img.Cols(), img.Rows()), 0, 0, gocv.InterpolationLinear)
+			hsv.Close()
+		} else {
+			drawArrows(img, flow, 1 / *scale, *step)
+		}
+		stop()
+		stats.Frame()
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err != nil && err != videoio.ErrStopped {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	return nil
+}
+
+// FlowToBGR renders a 2-channel float flow field as a color image: hue is the direction
+// of motion and brightness its speed, normalized to the fastest motion of the field
+func FlowToBGR(flow gocv.Mat) gocv.Mat {
+	xy := gocv.Split(flow)
+	defer xy[0].Close()
+	defer xy[1].Close()
+	mag, angle := gocv.NewMat(), gocv.NewMat()
+	defer mag.Close()
+	defer angle.Close()
+	gocv.CartToPolar(xy[0], xy[1], &mag, &angle, true)
+
+	// 8-bit hue covers 0-180
+	hue, val := gocv.NewMat(), gocv.NewMat()
+	defer hue.Close()
+	defer val.Close()
+	angle.ConvertToWithParams(&hue, gocv.MatTypeCV8U, 0.5, 0)
+	gocv.Normalize(mag, &mag, 0, 255, gocv.NormMinMax)
+	mag.ConvertTo(&val, gocv.MatTypeCV8U)
+	sat := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(255, 0, 0, 0), flow.Rows(), flow.Cols(), gocv.MatTypeCV8U)
+	defer sat.Close()
+
+	hsv := gocv.NewMat()
+	defer hsv.Close()
+	gocv.Merge([]gocv.Mat{hue, sat, val}, &hsv)
+	bgr := gocv.NewMat()
+	gocv.CvtColor(hsv, &bgr, gocv.ColorHSVToBGR)
+	return bgr
+}
+
+// Draws motion vectors of the flow on a grid with given step. Flow coordinates are multiplied by scale
+func drawArrows(img *gocv.Mat, flow gocv.Mat, scale float64, step int) {
+	for y := step / 2; y < img.Rows(); y += step {
+		for x := step / 2; x < img.Cols(); x += step {
+			fy, fx := int(float64(y)/scale), int(float64(x)/scale)
+			if fy >= flow.Rows() || fx >= flow.Cols() {
+				continue
+			}
+			v := flow.GetVecfAt(fy, fx)
+			dx, dy := float64(v[0])*scale, float64(v[1])*scale
+			if math.Hypot(dx, dy) < minArrow {
+				continue
+			}
+			gocv.ArrowedLine(img, image.Pt(x, y), image.Pt(x+int(dx), y+int(dy)), draw.DefaultStyle.LineColor, 1)
+		}
+	}
+}
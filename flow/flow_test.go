@@ -0,0 +1,25 @@
+package flow
+
+import (
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+func TestFlowToBGR(t *testing.T) {
+	// Still pixel and a pixel moving right
+	flow := gocv.NewMatWithSize(1, 2, gocv.MatTypeCV32FC2)
+	defer flow.Close()
+	flow.SetTo(gocv.NewScalar(0, 0, 0, 0))
+	flow.SetFloatAt(0, 2, 3)
+
+	bgr := FlowToBGR(flow)
+	defer bgr.Close()
+	if got := bgr.GetVecbAt(0, 0); got[0] != 0 || got[1] != 0 || got[2] != 0 {
+		t.Errorf("Expected black for no motion, got %v", got)
+	}
+	// Zero angle is red hue, the fastest motion is full brightness
+	if got := bgr.GetVecbAt(0, 1); got[0] != 0 || got[1] != 0 || got[2] != 255 {
+		t.Errorf("Expected red for motion to the right, got %v", got)
+	}
+}
@@ -0,0 +1,211 @@
+// This example counts people or vehicles crossing a virtual line without a DNN, using background
+// subtraction and simple blob tracking, and saves hourly counts to CSV
+//
+// Call: gocv-examples footfall [-input 0] [-line 0,0.5,1,0.5] [-min-area 1500] [-csv footfall.csv]
+// Moving blobs larger than -min-area pixels are followed from frame to frame, and a blob crossing
+// the line given as x1,y1,x2,y2 in fractions of the frame size is counted once: "in" when it moves
+// to the right side of the line looking from its first point to the second one, e.g. downwards across
+// the default horizontal line, "out" otherwise. Counts are appended to -csv for each hour with crossings
+// With -kafka host:port or -redis host:port, each crossing is also published as an event, and Redis keeps
+// the latest annotated frame for dashboards, see internal/events
+// Parameters can also be set with FOOTFALL_* environment variables or a config file, see internal/config
+//
+// It is a lightweight alternative to DNN detection for Raspberry Pi class hardware: a camera looking
+// down at a passage works best, since blobs of people walking side by side merge in a side view.
+// -max-dist should exceed the move of a blob between frames, and -min-area the size of noise like
+// leaves or reflections. Hours are taken from the clock, so counts of a video file have the time of processing
+
+package footfall
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/events"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"github.com/marchevska/gocv-examples/motion"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID       = "0" // Default input
+	defaultLine = "0,0.5,1,0.5"
+	minArea     = 1500
+	minAreaStep = 250 // Change of the minimal area by +/- keys
+	maxDist     = 60
+	maxMissed   = 5
+	csvPath     = "footfall.csv"
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Run counts blobs crossing the line on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples footfall", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file or stream URL")
+	method := fs.String("method", "mog2", "Background subtraction method: mog2 or knn")
+	lineStr := fs.String("line", defaultLine, "Counting line x1,y1,x2,y2 in fractions of the frame size")
+	area := fs.Int("min-area", minArea, "Minimal area of a blob in pixels")
+	dist := fs.Int("max-dist", maxDist, "Maximal move of a blob between frames in pixels")
+	missed := fs.Int("max-missed", maxMissed, "Frames a blob may be lost before its track is dropped")
+	out := fs.String("csv", csvPath, "CSV file the hourly counts are appended to")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	var eventOpts events.Options
+	eventOpts.RegisterFlags(fs)
+	if err := config.Load(fs, args, "FOOTFALL"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("footfall")
+
+	line, err := parseLine(*lineStr)
+	if err != nil {
+		return err
+	}
+	if *dist < 1 || *missed < 0 {
+		return errors.New("Maximal distance should be positive and missed frames not negative")
+	}
+
+	_, statErr := os.Stat(*out)
+	f, err := os.OpenFile(*out, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("Error opening CSV file: %v", err)
+	}
+	counts, err := newHourlyCounts(f, os.IsNotExist(statErr))
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("Error writing CSV file: %v", err)
+	}
+
+	detector, err := motion.NewDetector(*method, float64(*area))
+	if err != nil {
+		f.Close()
+		return err
+	}
+	defer detector.Close()
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+	sd.OnClose("csv", func() error {
+		if err := counts.Flush(); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	})
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Footfall counter")
+	if err != nil {
+		return err
+	}
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			detector.MinArea += float64(step * minAreaStep)
+			if detector.MinArea < minAreaStep {
+				detector.MinArea = minAreaStep
+			}
+			return fmt.Sprintf("min area %.0f px", detector.MinArea)
+		}
+	}
+
+	publisher, err := eventOpts.Open(sd, *input)
+	if err != nil {
+		return err
+	}
+
+	tracker := blobTracker{MaxDist: *dist, MaxMissed: *missed}
+	var in, outCount, frame int
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("subtraction")
+		regions := detector.Detect(*img)
+		stop()
+		stats.Frame()
+
+		centroids := make([]image.Point, len(regions))
+		for i, r := range regions {
+			centroids[i] = r.Min.Add(r.Max).Div(2)
+		}
+		a, b := line.Points(image.Pt(img.Cols(), img.Rows()))
+		now := time.Now()
+		st := draw.DefaultStyle
+		for _, t := range tracker.Update(centroids) {
+			if !t.Counted {
+				if dir := crossing(a, b, t.Prev, t.Pos); dir != 0 {
+					t.Counted = true
+					if dir > 0 {
+						in++
+					} else {
+						outCount++
+					}
+					logging.Debugf("Blob %d crossed the line, in %d, out %d", t.ID, in, outCount)
+					if err := counts.Add(now, dir); err != nil {
+						logging.Errorf("Error writing CSV file: %v", err)
+					}
+					if publisher != nil {
+						direction := "in"
+						if dir < 0 {
+							direction = "out"
+						}
+						publisher.Publish(events.Event{Type: events.TypeCrossing, Time: now, Frame: frame,
+							Track: t.ID, Direction: direction})
+					}
+				}
+			}
+			color := draw.Green
+			if t.Counted {
+				color = draw.Red
+			}
+			gocv.Circle(img, t.Pos, 4, color, -1)
+			draw.TextWithBackground(img, fmt.Sprint(t.ID), t.Pos, st.WithColor(color))
+		}
+		for _, r := range regions {
+			gocv.Rectangle(img, r, draw.Green, st.LineThickness)
+		}
+		gocv.Line(img, a, b, draw.Red, 2*st.LineThickness)
+
+		text := fmt.Sprintf("In: %d  Out: %d", in, outCount)
+		draw.TextWithBackground(img, text, image.Pt(0, st.TextSize(text).Y+2*st.Padding), st)
+		metrics.Overlay(img, stats, st)
+		if publisher != nil {
+			publisher.Frame(*img)
+		}
+		frame++
+	})
+	logging.Infof("Counted %d in, %d out", in, outCount)
+	if err != nil && err != videoio.ErrStopped {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	return nil
+}
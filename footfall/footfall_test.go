@@ -0,0 +1,93 @@
+package footfall
+
+import (
+	"bytes"
+	"image"
+	"testing"
+	"time"
+)
+
+func TestParseLine(t *testing.T) {
+	l, err := parseLine("0, 0.5, 1, 0.5")
+	if err != nil || l != (countLine{0, 0.5, 1, 0.5}) {
+		t.Errorf("parseLine = %v, %v", l, err)
+	}
+	a, b := l.Points(image.Pt(640, 480))
+	if a != image.Pt(0, 240) || b != image.Pt(640, 240) {
+		t.Errorf("Points = %v, %v", a, b)
+	}
+	for _, s := range []string{"", "0,0,1", "0,0,2,1", "0.5,0.5,0.5,0.5", "a,0,1,1"} {
+		if _, err := parseLine(s); err == nil {
+			t.Errorf("parseLine(%q) should fail", s)
+		}
+	}
+}
+
+func TestCrossing(t *testing.T) {
+	a, b := image.Pt(0, 100), image.Pt(200, 100)
+	tests := []struct {
+		p, q image.Point
+		want int
+	}{
+		{image.Pt(50, 90), image.Pt(50, 110), 1},
+		{image.Pt(50, 110), image.Pt(50, 90), -1},
+		{image.Pt(50, 80), image.Pt(50, 90), 0},
+		{image.Pt(250, 90), image.Pt(250, 110), 0}, // Beyond the end of the segment
+		{image.Pt(50, 90), image.Pt(50, 100), 0},   // Stops on the line
+		{image.Pt(50, 100), image.Pt(50, 110), 1},
+	}
+	for _, tt := range tests {
+		if got := crossing(a, b, tt.p, tt.q); got != tt.want {
+			t.Errorf("crossing(%v, %v) = %d, want %d", tt.p, tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestBlobTracker(t *testing.T) {
+	bt := blobTracker{MaxDist: 20, MaxMissed: 1}
+	seen := bt.Update([]image.Point{{10, 10}, {100, 100}})
+	if len(seen) != 2 || seen[0].ID != 1 || seen[1].ID != 2 {
+		t.Fatalf("First frame tracks %+v", seen)
+	}
+
+	seen = bt.Update([]image.Point{{105, 108}, {15, 12}})
+	ids := map[image.Point]int{}
+	for _, tr := range seen {
+		ids[tr.Pos] = tr.ID
+	}
+	if ids[image.Pt(15, 12)] != 1 || ids[image.Pt(105, 108)] != 2 {
+		t.Errorf("Second frame tracks %v", ids)
+	}
+	if seen[0].Prev == seen[0].Pos {
+		t.Errorf("Previous position not kept: %+v", seen[0])
+	}
+
+	// The first blob disappears for one frame and comes back, the second one is gone
+	bt.Update([]image.Point{{200, 200}})
+	seen = bt.Update([]image.Point{{20, 15}})
+	if len(seen) != 1 || seen[0].ID != 1 {
+		t.Errorf("Track after a missed frame %+v", seen)
+	}
+	if len(bt.tracks) != 2 {
+		t.Errorf("%d tracks kept, want 2", len(bt.tracks))
+	}
+}
+
+func TestHourlyCounts(t *testing.T) {
+	var buf bytes.Buffer
+	hc, err := newHourlyCounts(&buf, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)
+	hc.Add(start, 1)
+	hc.Add(start.Add(10*time.Minute), 1)
+	hc.Add(start.Add(20*time.Minute), -1)
+	hc.Add(start.Add(3*time.Hour), -1)
+	hc.Flush()
+
+	want := "hour,in,out\n2024-01-01 09:00,2,1\n2024-01-01 12:00,0,1\n"
+	if buf.String() != want {
+		t.Errorf("CSV:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,69 @@
+package footfall
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+// hourlyCounts writes counts of crossings per hour as CSV rows: hour, in, out.
+// A row is written when the hour changes and on Flush
+type hourlyCounts struct {
+	w       *csv.Writer
+	hour    time.Time
+	in, out int
+}
+
+// newHourlyCounts writes the header and returns counts writing to w
+func newHourlyCounts(w io.Writer, header bool) (*hourlyCounts, error) {
+	hc := &hourlyCounts{w: csv.NewWriter(w)}
+	if header {
+		if err := hc.w.Write([]string{"hour", "in", "out"}); err != nil {
+			return nil, err
+		}
+		hc.w.Flush()
+	}
+	return hc, hc.w.Error()
+}
+
+// Add counts a crossing at time t, dir is 1 for in and -1 for out
+func (hc *hourlyCounts) Add(t time.Time, dir int) error {
+	if err := hc.advance(t); err != nil {
+		return err
+	}
+	if dir > 0 {
+		hc.in++
+	} else if dir < 0 {
+		hc.out++
+	}
+	return nil
+}
+
+// advance writes the row of the finished hour if t is in the next one. Hours without crossings are skipped
+func (hc *hourlyCounts) advance(t time.Time) error {
+	hour := t.Truncate(time.Hour)
+	if hc.hour.IsZero() {
+		hc.hour = hour
+	}
+	if hour.Equal(hc.hour) {
+		return nil
+	}
+	err := hc.Flush()
+	hc.hour = hour
+	return err
+}
+
+// Flush writes the row of the current hour, if there were crossings, and resets the counts
+func (hc *hourlyCounts) Flush() error {
+	if hc.in == 0 && hc.out == 0 {
+		return nil
+	}
+	row := []string{hc.hour.Format("2006-01-02 15:04"), strconv.Itoa(hc.in), strconv.Itoa(hc.out)}
+	hc.in, hc.out = 0, 0
+	if err := hc.w.Write(row); err != nil {
+		return err
+	}
+	hc.w.Flush()
+	return hc.w.Error()
+}
@@ -0,0 +1,75 @@
+package footfall
+
+import (
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+)
+
+// countLine is the virtual line blobs are counted on, given in fractions of the frame size
+// so that it does not depend on the resolution
+type countLine struct {
+	X1, Y1, X2, Y2 float64
+}
+
+// parseLine parses a line given as "x1,y1,x2,y2" in fractions of the frame width and height
+func parseLine(s string) (countLine, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) == 4 {
+		var v [4]float64
+		ok := true
+		for i, p := range parts {
+			f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+			if err != nil || f < 0 || f > 1 {
+				ok = false
+				break
+			}
+			v[i] = f
+		}
+		if ok && (v[0] != v[2] || v[1] != v[3]) {
+			return countLine{v[0], v[1], v[2], v[3]}, nil
+		}
+	}
+	return countLine{}, fmt.Errorf("Line should be x1,y1,x2,y2 in fractions of the frame size from 0 to 1: %q", s)
+}
+
+// Points returns the ends of the line on a frame of the size
+func (l countLine) Points(size image.Point) (image.Point, image.Point) {
+	return image.Pt(int(l.X1*float64(size.X)), int(l.Y1*float64(size.Y))),
+		image.Pt(int(l.X2*float64(size.X)), int(l.Y2*float64(size.Y)))
+}
+
+// side returns the sign of the side of the line a→b the point p is on: positive on the right
+// in image coordinates, e.g. below a line drawn from left to right
+func side(a, b, p image.Point) int {
+	c := (b.X-a.X)*(p.Y-a.Y) - (b.Y-a.Y)*(p.X-a.X)
+	switch {
+	case c > 0:
+		return 1
+	case c < 0:
+		return -1
+	}
+	return 0
+}
+
+// crossing reports whether the move from p to q crosses the segment a→b: 1 when moving to
+// its right side, -1 to its left side, and 0 when it does not cross. Touching the line
+// counts as being on the left side, so a blob stopping on the line is counted once
+func crossing(a, b, p, q image.Point) int {
+	sp, sq := side(a, b, p), side(a, b, q)
+	if sp == 0 {
+		sp = -1
+	}
+	if sq == 0 {
+		sq = -1
+	}
+	if sp == sq {
+		return 0
+	}
+	// The move should also cross the segment, not its extension
+	if side(p, q, a)*side(p, q, b) > 0 {
+		return 0
+	}
+	return sq
+}
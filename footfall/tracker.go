@@ -0,0 +1,81 @@
+package footfall
+
+import (
+	"image"
+	"sort"
+)
+
+// track is a blob followed over frames
+type track struct {
+	ID      int
+	Pos     image.Point // Centroid on the last frame it was seen
+	Prev    image.Point // Centroid on the frame before
+	Missed  int         // Consecutive frames the blob was not found
+	Counted bool        // Crossed the counting line already, so that jitter on the line is counted once
+}
+
+// blobTracker follows blob centroids over frames by matching each one to the nearest track.
+// It is enough for separate blobs moving steadily, e.g. people seen from above; blobs that merge
+// or split get new tracks
+type blobTracker struct {
+	MaxDist   int // Maximal move of a blob between frames in pixels
+	MaxMissed int // Frames a track is kept without its blob
+	nextID    int
+	tracks    []*track
+}
+
+// Update matches the centroids of the frame to the tracks, closest pairs first, starts new tracks
+// for unmatched centroids and drops tracks missed for too long. It returns the tracks seen on the frame
+func (bt *blobTracker) Update(centroids []image.Point) []*track {
+	type pair struct {
+		t, c int
+		dist int
+	}
+	var pairs []pair
+	for i, t := range bt.tracks {
+		for j, c := range centroids {
+			if d := sqDist(t.Pos, c); d <= bt.MaxDist*bt.MaxDist {
+				pairs = append(pairs, pair{i, j, d})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].dist < pairs[j].dist })
+
+	matchedT := make([]bool, len(bt.tracks))
+	matchedC := make([]bool, len(centroids))
+	var seen []*track
+	for _, p := range pairs {
+		if matchedT[p.t] || matchedC[p.c] {
+			continue
+		}
+		matchedT[p.t], matchedC[p.c] = true, true
+		t := bt.tracks[p.t]
+		t.Prev, t.Pos, t.Missed = t.Pos, centroids[p.c], 0
+		seen = append(seen, t)
+	}
+
+	kept := bt.tracks[:0]
+	for i, t := range bt.tracks {
+		if !matchedT[i] {
+			t.Missed++
+		}
+		if t.Missed <= bt.MaxMissed {
+			kept = append(kept, t)
+		}
+	}
+	bt.tracks = kept
+	for j, c := range centroids {
+		if !matchedC[j] {
+			bt.nextID++
+			t := &track{ID: bt.nextID, Pos: c, Prev: c}
+			bt.tracks = append(bt.tracks, t)
+			seen = append(seen, t)
+		}
+	}
+	return seen
+}
+
+func sqDist(a, b image.Point) int {
+	d := a.Sub(b)
+	return d.X*d.X + d.Y*d.Y
+}
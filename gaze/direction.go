@@ -0,0 +1,68 @@
+package gaze
+
+import (
+	"image"
+	"math"
+)
+
+// eyeOffset returns the position of the pupil in the eye given by its six landmarks, from the
+// outer corner: -1 to 1 from the left to the right corner of the image and from about the upper
+// to the lower lid, 0 in the middle. The eye height is taken as a third of its width, since
+// the lids follow the pupil when looking up or down
+func eyeOffset(pupil image.Point, eye []image.Point) (float64, float64) {
+	left, right := eye[0], eye[3]
+	if left.X > right.X {
+		left, right = right, left
+	}
+	cx, cy := float64(left.X+right.X)/2, float64(left.Y+right.Y)/2
+	width := math.Hypot(float64(right.X-left.X), float64(right.Y-left.Y))
+	if width == 0 {
+		return 0, 0
+	}
+	clamp := func(v float64) float64 { return math.Max(-1, math.Min(1, v)) }
+	return clamp((float64(pupil.X) - cx) / (width / 2)), clamp((float64(pupil.Y) - cy) / (width / 6))
+}
+
+// gazeDirection returns the unit gaze vector in camera coordinates: the head rotation applied
+// to the eye rotation, which turns the eye by maxYaw and maxPitch radians at offsets of 1
+func gazeDirection(head [3]float64, offX, offY, maxYaw, maxPitch float64) [3]float64 {
+	yaw, pitch := offX*maxYaw, offY*maxPitch
+	// Looking straight ahead is towards the camera, along -z
+	eye := [3]float64{math.Sin(yaw) * math.Cos(pitch), math.Sin(pitch), -math.Cos(yaw) * math.Cos(pitch)}
+	return rotate(rodrigues(head), eye)
+}
+
+// angles returns the horizontal and vertical angles of the gaze in degrees, positive to the right
+// and down in the image, 0 when looking into the camera
+func angles(dir [3]float64) (float64, float64) {
+	return math.Atan2(dir[0], -dir[2]) * 180 / math.Pi, math.Atan2(dir[1], -dir[2]) * 180 / math.Pi
+}
+
+// describe names the gaze direction from the angles, with thr degrees counted as looking
+// into the camera. Directions are as seen in the image, so "left" is the person's right
+func describe(h, v, thr float64) string {
+	var s string
+	switch {
+	case v < -thr:
+		s = "up"
+	case v > thr:
+		s = "down"
+	}
+	switch {
+	case h < -thr:
+		s = join(s, "left")
+	case h > thr:
+		s = join(s, "right")
+	}
+	if s == "" {
+		return "center"
+	}
+	return s
+}
+
+func join(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "-" + b
+}
@@ -0,0 +1,246 @@
+// This example estimates where a person is looking: the head pose is fitted to facial landmarks,
+// the pupils are located within the eye outlines, and both are combined into a gaze vector
+//
+// Call: gocv-examples gaze [-input 0] [-landmarks model.onnx] [-calib camera.yaml] [-center-thr 10]
+// Input can be a camera ID (default 0), a video file, a stream URL or images
+// For each face the head axes are drawn from the nose tip (x red, y green, z blue towards the camera),
+// an arrow shows the gaze from each pupil, and the label gives the gaze angles and direction as seen
+// in the image. Gaze within -center-thr degrees is into the camera, +/- keys change the threshold
+// Parameters can also be set with GAZE_* environment variables or a config file, see internal/config
+//
+// Faces are detected and landmarks fitted as in the landmarks example, see its package comment
+// for the face detector and the landmark model, which should give the 68-point iBUG 300-W layout.
+// The head pose is fitted to a generic face model, with the camera intrinsics from -calib (see the
+// calibrate example) or, without it, a focal length equal to the image width. The eyes turn by
+// -max-yaw and -max-pitch degrees when the pupil reaches the corner or the lid: the pupil position
+// is rough at webcam resolutions, so the gaze is an estimate good for directions, not for points
+
+package gaze
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/marchevska/gocv-examples/internal/calib"
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"github.com/marchevska/gocv-examples/landmarks"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID         = "0"                   // Default input
+	landmarksPath = "face_landmarks.onnx" // Default landmark model
+	landmarkSize  = 112                   // Input size of the landmark model
+	faceConfThr   = 0.5
+	maxEyeYaw     = 30.0 // Degrees
+	maxEyePitch   = 20.0
+	centerThr     = 10.0
+	centerThrStep = 1.0   // Change of the threshold by +/- keys
+	maxPoseError  = 10.0  // Reprojection error in pixels above which the pose is not drawn
+	axisLength    = 100.0 // Length of the head axes in millimeters
+	gazeLength    = 3.0   // Length of the gaze arrows relative to the eye width
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Outlines of both eyes in the 68 landmarks
+var eyeRanges = [2][2]int{{36, 42}, {42, 48}}
+
+// Run estimates gaze direction on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples gaze", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	lmPath := fs.String("landmarks", landmarksPath, "ONNX landmark model with 68 points, see 'gocv-examples landmarks -h'")
+	lmSize := fs.Int("landmark-size", landmarkSize, "Input size of the landmark model")
+	confThr := fs.Float64("conf-thr", faceConfThr, "Face detection confidence threshold")
+	calibPath := fs.String("calib", "", "Camera intrinsics file, see internal/calib; the focal length is guessed without it")
+	maxYaw := fs.Float64("max-yaw", maxEyeYaw, "Eye rotation in degrees with the pupil in the eye corner")
+	maxPitch := fs.Float64("max-pitch", maxEyePitch, "Eye rotation in degrees with the pupil at the lid")
+	thr := fs.Float64("center-thr", centerThr, "Gaze angle in degrees counted as looking into the camera")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "GAZE"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("gaze")
+
+	if *maxYaw <= 0 || *maxPitch <= 0 || *thr < 0 {
+		return errors.New("Eye rotations should be positive and the center threshold not negative")
+	}
+	var intrinsics *calib.Intrinsics
+	if *calibPath != "" {
+		in, err := calib.Load(*calibPath)
+		if err != nil {
+			return fmt.Errorf("Error loading intrinsics: %v", err)
+		}
+		intrinsics = &in
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{DNN: true, Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	faces, err := landmarks.LoadFaceDetector(sd, *confThr)
+	if err != nil {
+		return err
+	}
+	lmNet, err := landmarks.LoadModel(sd, *lmPath)
+	if err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Gaze direction")
+	if err != nil {
+		return err
+	}
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			*thr = math.Max(0, *thr+float64(step)*centerThrStep)
+			return fmt.Sprintf("center threshold %.0f°", *thr)
+		}
+	}
+
+	deg := math.Pi / 180
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	gray := gocv.NewMat()
+	defer gray.Close()
+	warned := false
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("faces")
+		dets, err := faces.Detect(*img)
+		stop()
+		if err != nil {
+			logging.Errorf("Error detecting faces: %v", err)
+		}
+		cam := frameCamera(intrinsics, image.Pt(img.Cols(), img.Rows()))
+		gocv.CvtColor(*img, &gray, gocv.ColorBGRToGray)
+
+		st := draw.DefaultStyle
+		for _, d := range dets {
+			stop = stats.Start("landmarks")
+			pts := landmarks.Landmarks(lmNet, *img, d.BBox, *lmSize)
+			stop()
+			if len(pts) != 68 {
+				if !warned {
+					logging.Errorf("Landmark model gives %d points, 68 are needed for the head pose and the eyes", len(pts))
+					warned = true
+				}
+				continue
+			}
+
+			stop = stats.Start("pose")
+			imgPts := make([][2]float64, len(modelLandmarks))
+			for i, l := range modelLandmarks {
+				imgPts[i] = [2]float64{float64(pts[l].X), float64(pts[l].Y)}
+			}
+			head, rms := cam.solvePose(faceModel, imgPts, cam.initialPose(imgPts))
+			stop()
+			if rms > maxPoseError {
+				draw.LabelBox(img, d.BBox, "no pose", st)
+				continue
+			}
+			drawAxes(img, cam, head)
+
+			stop = stats.Start("pupils")
+			var offX, offY float64
+			found := 0
+			for _, r := range eyeRanges {
+				eye := pts[r[0]:r[1]]
+				pupil, ok := findPupil(gray, eye)
+				if !ok {
+					continue
+				}
+				x, y := eyeOffset(pupil, eye)
+				offX, offY = offX+x, offY+y
+				found++
+				gocv.Circle(img, pupil, 2, draw.Red, -1)
+			}
+			stop()
+			if found == 0 {
+				draw.LabelBox(img, d.BBox, "eyes closed", st)
+				continue
+			}
+			dir := gazeDirection(head.R, offX/float64(found), offY/float64(found), *maxYaw*deg, *maxPitch*deg)
+			for _, r := range eyeRanges {
+				drawGaze(img, pts[r[0]:r[1]], dir)
+			}
+			h, v := angles(dir)
+			draw.LabelBox(img, d.BBox, fmt.Sprintf("%s %.0f° %.0f°", describe(h, v, *thr), h, v), st)
+		}
+		stats.Frame()
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err != nil && err != videoio.ErrStopped {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	return nil
+}
+
+// frameCamera returns the camera for frames of the size, from the intrinsics if given, otherwise
+// with the focal length equal to the width, which is close for most webcams, and a centered
+// principal point. Distortion is ignored
+func frameCamera(in *calib.Intrinsics, size image.Point) camera {
+	if in == nil {
+		return camera{F: float64(size.X), Cx: float64(size.X) / 2, Cy: float64(size.Y) / 2}
+	}
+	m := in.Scaled(size).CameraMatrix
+	return camera{F: (m[0] + m[4]) / 2, Cx: m[2], Cy: m[5]}
+}
+
+// Draws the head axes from the nose tip, with z towards the camera
+func drawAxes(img *gocv.Mat, cam camera, head pose) {
+	pt := func(p [3]float64) image.Point {
+		q := cam.project(head, p)
+		return image.Pt(int(q[0]), int(q[1]))
+	}
+	nose := pt(faceModel[0])
+	axes := []struct {
+		end [3]float64
+		c   color.RGBA
+	}{
+		{[3]float64{axisLength, 0, 0}, draw.Red},
+		{[3]float64{0, axisLength, 0}, draw.Green},
+		{[3]float64{0, 0, -axisLength}, color.RGBA{0, 0, 255, 0}},
+	}
+	for _, a := range axes {
+		gocv.Line(img, nose, pt(a.end), a.c, 2)
+	}
+}
+
+// Draws the gaze as an arrow from the center of the eye, the image projection of the direction
+func drawGaze(img *gocv.Mat, eye []image.Point, dir [3]float64) {
+	center := eye[0].Add(eye[3]).Div(2)
+	length := gazeLength * math.Hypot(float64(eye[3].X-eye[0].X), float64(eye[3].Y-eye[0].Y))
+	end := center.Add(image.Pt(int(dir[0]*length), int(dir[1]*length)))
+	gocv.ArrowedLine(img, center, end, draw.Red, 2)
+}
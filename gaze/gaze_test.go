@@ -0,0 +1,82 @@
+package gaze
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestRodrigues(t *testing.T) {
+	// Quarter turn around y takes x to -z
+	m := rodrigues([3]float64{0, math.Pi / 2, 0})
+	if p := rotate(m, [3]float64{1, 0, 0}); math.Abs(p[0]) > 1e-9 || math.Abs(p[2]+1) > 1e-9 {
+		t.Errorf("Rotated x = %v, want -z", p)
+	}
+	if m := rodrigues([3]float64{}); m != [9]float64{1, 0, 0, 0, 1, 0, 0, 0, 1} {
+		t.Errorf("Zero rotation %v", m)
+	}
+}
+
+func TestSolvePose(t *testing.T) {
+	cam := camera{F: 640, Cx: 320, Cy: 240}
+	for _, want := range []pose{
+		{R: [3]float64{0, 0, 0}, T: [3]float64{0, 0, 1500}},
+		{R: [3]float64{0.1, -0.35, 0.05}, T: [3]float64{-120, 60, 2000}},
+		{R: [3]float64{-0.25, 0.2, -0.1}, T: [3]float64{200, -80, 1200}},
+	} {
+		img := make([][2]float64, len(faceModel))
+		for i, p := range faceModel {
+			img[i] = cam.project(want, p)
+		}
+		got, rms := cam.solvePose(faceModel, img, cam.initialPose(img))
+		if rms > 1e-3 {
+			t.Errorf("Pose %v: reprojection error %v", want, rms)
+		}
+		for k := 0; k < 3; k++ {
+			if math.Abs(got.R[k]-want.R[k]) > 1e-3 || math.Abs(got.T[k]-want.T[k]) > 1 {
+				t.Errorf("Pose %+v, want %+v", got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestEyeOffset(t *testing.T) {
+	eye := []image.Point{{100, 50}, {110, 45}, {120, 45}, {130, 50}, {120, 55}, {110, 55}}
+	tests := []struct {
+		pupil image.Point
+		x, y  float64
+	}{
+		{image.Pt(115, 50), 0, 0},
+		{image.Pt(100, 50), -1, 0},
+		{image.Pt(130, 55), 1, 1},
+		{image.Pt(115, 40), 0, -1}, // Clamped
+	}
+	for _, tt := range tests {
+		if x, y := eyeOffset(tt.pupil, eye); math.Abs(x-tt.x) > 1e-9 || math.Abs(y-tt.y) > 1e-9 {
+			t.Errorf("eyeOffset(%v) = %v, %v; want %v, %v", tt.pupil, x, y, tt.x, tt.y)
+		}
+	}
+}
+
+func TestGazeDirection(t *testing.T) {
+	deg := math.Pi / 180
+	tests := []struct {
+		head       [3]float64
+		offX, offY float64
+		want       string
+	}{
+		{[3]float64{}, 0, 0, "center"},
+		{[3]float64{}, 1, 0, "right"},
+		{[3]float64{}, -1, -1, "up-left"},
+		{[3]float64{0, -30 * deg, 0}, 0, 0, "right"},   // Head turned, eyes straight
+		{[3]float64{0, -30 * deg, 0}, -1, 0, "center"}, // Eyes compensate the head
+	}
+	for _, tt := range tests {
+		dir := gazeDirection(tt.head, tt.offX, tt.offY, 30*deg, 20*deg)
+		h, v := angles(dir)
+		if got := describe(h, v, 10); got != tt.want {
+			t.Errorf("Head %v, eyes %v, %v: %s (%.1f, %.1f), want %s", tt.head, tt.offX, tt.offY, got, h, v, tt.want)
+		}
+	}
+}
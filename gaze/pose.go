@@ -0,0 +1,201 @@
+package gaze
+
+import (
+	"math"
+)
+
+// GoCV does not wrap solvePnP at the moment of writing, so the head pose is fitted here with
+// Levenberg-Marquardt, which is what solvePnP does with SOLVEPNP_ITERATIVE
+
+// Generic face model in millimeters for the landmarks used by pose estimation. Axes follow
+// the camera: x to the right of the image, y down and z away from the camera, so that a face
+// looking straight into the camera has no rotation
+var faceModel = [][3]float64{
+	{0, 0, 0},         // Nose tip, landmark 30
+	{0, 330, 65},      // Chin, 8
+	{-225, -170, 135}, // Outer corner of the eye on the left of the image, 36
+	{225, -170, 135},  // Outer corner of the other eye, 45
+	{-150, 150, 125},  // Mouth corner on the left of the image, 48
+	{150, 150, 125},   // Other mouth corner, 54
+}
+
+// Limits of the pose fitting: iterations, and attempts to decrease the error with a larger damping
+const (
+	maxIterations = 100
+	maxTries      = 10
+)
+
+// Landmarks of the 68-point layout matching faceModel
+var modelLandmarks = []int{30, 8, 36, 45, 48, 54}
+
+// camera holds pinhole intrinsics without distortion
+type camera struct {
+	F      float64 // Focal length in pixels
+	Cx, Cy float64
+}
+
+// pose is the rotation, as a Rodrigues vector, and the translation of the model in camera coordinates
+type pose struct {
+	R, T [3]float64
+}
+
+// rodrigues converts a rotation vector to a row-major rotation matrix
+func rodrigues(r [3]float64) [9]float64 {
+	theta := math.Sqrt(r[0]*r[0] + r[1]*r[1] + r[2]*r[2])
+	if theta < 1e-12 {
+		return [9]float64{1, 0, 0, 0, 1, 0, 0, 0, 1}
+	}
+	x, y, z := r[0]/theta, r[1]/theta, r[2]/theta
+	c, s := math.Cos(theta), math.Sin(theta)
+	v := 1 - c
+	return [9]float64{
+		c + x*x*v, x*y*v - z*s, x*z*v + y*s,
+		y*x*v + z*s, c + y*y*v, y*z*v - x*s,
+		z*x*v - y*s, z*y*v + x*s, c + z*z*v,
+	}
+}
+
+// rotate applies the row-major rotation matrix to the point
+func rotate(m [9]float64, p [3]float64) [3]float64 {
+	return [3]float64{
+		m[0]*p[0] + m[1]*p[1] + m[2]*p[2],
+		m[3]*p[0] + m[4]*p[1] + m[5]*p[2],
+		m[6]*p[0] + m[7]*p[1] + m[8]*p[2],
+	}
+}
+
+// project returns the image point of the model point seen with the pose
+func (c camera) project(p pose, pt [3]float64) [2]float64 {
+	q := rotate(rodrigues(p.R), pt)
+	x, y, z := q[0]+p.T[0], q[1]+p.T[1], q[2]+p.T[2]
+	return [2]float64{c.F*x/z + c.Cx, c.F*y/z + c.Cy}
+}
+
+// initialPose guesses the pose of a face looking into the camera from the image points of faceModel:
+// the distance follows from the eye distance in pixels, and the nose tip lies on its ray
+func (c camera) initialPose(img [][2]float64) pose {
+	eyes := math.Hypot(img[3][0]-img[2][0], img[3][1]-img[2][1])
+	z := 1000.0
+	if eyes > 0 {
+		z = c.F * (faceModel[3][0] - faceModel[2][0]) / eyes
+	}
+	return pose{T: [3]float64{(img[0][0] - c.Cx) * z / c.F, (img[0][1] - c.Cy) * z / c.F, z}}
+}
+
+// residuals returns the differences of the projected model points from the image points
+func (c camera) residuals(p pose, model [][3]float64, img [][2]float64) []float64 {
+	res := make([]float64, 2*len(model))
+	for i, pt := range model {
+		q := c.project(p, pt)
+		res[2*i], res[2*i+1] = q[0]-img[i][0], q[1]-img[i][1]
+	}
+	return res
+}
+
+// solvePose fits the pose of the model to the image points starting from init and returns it
+// with the RMS reprojection error in pixels
+func (c camera) solvePose(model [][3]float64, img [][2]float64, init pose) (pose, float64) {
+	params := func(p pose) [6]float64 {
+		return [6]float64{p.R[0], p.R[1], p.R[2], p.T[0], p.T[1], p.T[2]}
+	}
+	fromParams := func(v [6]float64) pose {
+		return pose{R: [3]float64{v[0], v[1], v[2]}, T: [3]float64{v[3], v[4], v[5]}}
+	}
+	cost := func(res []float64) float64 {
+		var s float64
+		for _, r := range res {
+			s += r * r
+		}
+		return s
+	}
+
+	p := params(init)
+	res := c.residuals(init, model, img)
+	lambda := 1e-3
+	for iter, done := 0, false; iter < maxIterations && !done; iter++ {
+		// Numeric Jacobian by central differences
+		jac := make([][6]float64, len(res))
+		for k := 0; k < 6; k++ {
+			eps := 1e-6 * math.Max(1, math.Abs(p[k]))
+			plus, minus := p, p
+			plus[k] += eps
+			minus[k] -= eps
+			rp := c.residuals(fromParams(plus), model, img)
+			rm := c.residuals(fromParams(minus), model, img)
+			for i := range res {
+				jac[i][k] = (rp[i] - rm[i]) / (2 * eps)
+			}
+		}
+		var jtj [6][6]float64
+		var jtr [6]float64
+		for i, row := range jac {
+			for a := 0; a < 6; a++ {
+				jtr[a] += row[a] * res[i]
+				for b := 0; b < 6; b++ {
+					jtj[a][b] += row[a] * row[b]
+				}
+			}
+		}
+
+		improved := false
+		for try := 0; try < maxTries && !improved; try++ {
+			a := jtj
+			for k := 0; k < 6; k++ {
+				a[k][k] *= 1 + lambda
+			}
+			step, ok := solve6(a, jtr)
+			if !ok {
+				lambda *= 10
+				continue
+			}
+			next := p
+			for k := range next {
+				next[k] -= step[k]
+			}
+			nextRes := c.residuals(fromParams(next), model, img)
+			if cost(nextRes) < cost(res) {
+				done = cost(res)-cost(nextRes) < 1e-10*cost(res)
+				p, res, improved = next, nextRes, true
+				lambda = math.Max(lambda/10, 1e-12)
+			} else {
+				lambda *= 10
+			}
+		}
+		done = done || !improved
+	}
+	return fromParams(p), math.Sqrt(cost(res) / float64(len(model)))
+}
+
+// solve6 solves the linear system a x = b by Gaussian elimination with partial pivoting
+func solve6(a [6][6]float64, b [6]float64) ([6]float64, bool) {
+	const n = 6
+	for col := 0; col < n; col++ {
+		pivot := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[pivot][col]) {
+				pivot = r
+			}
+		}
+		if math.Abs(a[pivot][col]) < 1e-12 {
+			return b, false
+		}
+		a[col], a[pivot] = a[pivot], a[col]
+		b[col], b[pivot] = b[pivot], b[col]
+		for r := col + 1; r < n; r++ {
+			f := a[r][col] / a[col][col]
+			for k := col; k < n; k++ {
+				a[r][k] -= f * a[col][k]
+			}
+			b[r] -= f * b[col]
+		}
+	}
+	var x [6]float64
+	for r := n - 1; r >= 0; r-- {
+		s := b[r]
+		for k := r + 1; k < n; k++ {
+			s -= a[r][k] * x[k]
+		}
+		x[r] = s / a[r][r]
+	}
+	return x, true
+}
@@ -0,0 +1,61 @@
+package gaze
+
+import (
+	"image"
+
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"gocv.io/x/gocv"
+)
+
+const (
+	pupilBlur = 5   // Gaussian blur of the eye before looking for the pupil, odd
+	pupilDark = 0.4 // Pixels darker than this fraction from the darkest to the mean of the eye form the pupil
+)
+
+// findPupil returns the center of the pupil within the eye outlined by its six landmarks: the
+// centroid of the darkest pixels inside the outline. ok is false if the eye is closed or too small
+func findPupil(gray gocv.Mat, eye []image.Point) (image.Point, bool) {
+	box := image.Rectangle{Min: eye[0], Max: eye[0]}
+	for _, p := range eye[1:] {
+		box = box.Union(image.Rectangle{Min: p, Max: p.Add(image.Pt(1, 1))})
+	}
+	box = box.Intersect(image.Rect(0, 0, gray.Cols(), gray.Rows()))
+	if box.Dx() < 2*pupilBlur || box.Dy() < 2 {
+		return image.Point{}, false
+	}
+
+	crop := gray.Region(box)
+	defer crop.Close()
+	blurred := gocv.NewMat()
+	defer blurred.Close()
+	gocv.GaussianBlur(crop, &blurred, image.Pt(pupilBlur, pupilBlur), 0, 0, gocv.BorderReplicate)
+
+	// Outline of the eye in the crop, and the eye with the rest made white
+	mask := gocv.NewMatWithSize(box.Dy(), box.Dx(), gocv.MatTypeCV8U)
+	defer mask.Close()
+	local := make([]image.Point, len(eye))
+	for i, p := range eye {
+		local[i] = p.Sub(box.Min)
+	}
+	pv := gocv.NewPointsVectorFromPoints([][]image.Point{local})
+	defer pv.Close()
+	gocv.FillPoly(&mask, pv, draw.White)
+	if gocv.CountNonZero(mask) == 0 {
+		return image.Point{}, false
+	}
+	outside := gocv.NewMat()
+	defer outside.Close()
+	gocv.BitwiseNot(mask, &outside)
+	gocv.BitwiseOr(blurred, outside, &blurred)
+
+	minVal, _, minLoc, _ := gocv.MinMaxLoc(blurred)
+	mean := blurred.MeanWithMask(mask).Val1
+	dark := gocv.NewMat()
+	defer dark.Close()
+	gocv.Threshold(blurred, &dark, minVal+pupilDark*(float32(mean)-minVal), 255, gocv.ThresholdBinaryInv)
+	m := gocv.Moments(dark, true)
+	if m["m00"] == 0 {
+		return box.Min.Add(minLoc), true
+	}
+	return box.Min.Add(image.Pt(int(m["m10"]/m["m00"]), int(m["m01"]/m["m00"]))), true
+}
@@ -0,0 +1,178 @@
+// This example detects faces and eyes inside them with Haar cascade classifiers. It needs no DNN
+// module and runs in real time on machines where the DNN examples are too heavy, and serves as
+// a baseline for them
+//
+// Call: gocv-examples haar [-input 0] [-min-neighbors 3] [-min-size 30] [-eyes=false]
+// Input can be a camera ID (default 0), a video file, a stream URL or images
+// Parameters can also be set with HAAR_* environment variables or a config file, see internal/config
+//
+// Cascades shipped with OpenCV are downloaded on first run and cached:
+// https://github.com/opencv/opencv/tree/4.x/data/haarcascades
+// Any other cascade file, e.g. haarcascade_profileface.xml or an LBP cascade, can be given with -face-cascade.
+// Eyes are searched in the upper part of each face only, which is faster and avoids false
+// detections on nostrils and mouth corners
+
+package haar
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/models"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID           = "0" // Default input
+	faceCascadePath = "haarcascade_frontalface_default.xml"
+	eyeCascadePath  = "haarcascade_eye_tree_eyeglasses.xml"
+	minFaceSize     = 30
+)
+
+// Part of the face where eyes are searched, in percent of its size
+var eyeBounds = image.Rect(0, 0, 100, 60)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Loads a cascade file, resolving it in the model cache. The classifier is closed by sd
+func loadCascade(sd *shutdown.Handler, name string) (*gocv.CascadeClassifier, error) {
+	path, err := models.Resolve(name)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading cascade, see 'gocv-examples haar -h': %v", err)
+	}
+	classifier := gocv.NewCascadeClassifier()
+	if !classifier.Load(path) {
+		classifier.Close()
+		return nil, fmt.Errorf("Error loading cascade %s", path)
+	}
+	sd.OnClose(name, classifier.Close)
+	return &classifier, nil
+}
+
+// Run detects faces and eyes on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples haar", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	faceCascade := fs.String("face-cascade", faceCascadePath, "Face cascade file")
+	eyeCascade := fs.String("eye-cascade", eyeCascadePath, "Eye cascade file")
+	eyes := fs.Bool("eyes", true, "Detect eyes inside faces")
+	scale := fs.Float64("scale-factor", detection.DefaultCascadeScale, "Scale step of the image pyramid, greater than 1")
+	minNeighbors := fs.Int("min-neighbors", detection.DefaultCascadeMinNeighbors, "Overlapping candidates needed to keep a face")
+	minSize := fs.Int("min-size", minFaceSize, "Smallest face size in pixels")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "HAAR"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("haar")
+
+	if *scale <= 1 {
+		return errors.New("Scale factor should be greater than 1")
+	}
+	if *minNeighbors < 0 || *minSize < 0 {
+		return errors.New("Min neighbors and min size should not be negative")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	classifier, err := loadCascade(sd, *faceCascade)
+	if err != nil {
+		return err
+	}
+	faces := detection.NewCascadeDetector(classifier, "face")
+	faces.ScaleFactor, faces.MinNeighbors = *scale, *minNeighbors
+	faces.MinSize = image.Pt(*minSize, *minSize)
+	var eyeDetector *detection.CascadeDetector
+	if *eyes {
+		classifier, err := loadCascade(sd, *eyeCascade)
+		if err != nil {
+			return err
+		}
+		eyeDetector = detection.NewCascadeDetector(classifier, "eye")
+		eyeDetector.ScaleFactor = *scale
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Haar cascades")
+	if err != nil {
+		return err
+	}
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			faces.MinNeighbors += step
+			if faces.MinNeighbors < 0 {
+				faces.MinNeighbors = 0
+			}
+			return fmt.Sprintf("min neighbors %d", faces.MinNeighbors)
+		}
+	}
+
+	eyeStyle := draw.DefaultStyle.WithColor(draw.LabelColor("eye"))
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("faces")
+		dets, _ := faces.Detect(*img)
+		stop()
+		var nested [][]detection.Detection
+		if eyeDetector != nil {
+			stop = stats.Start("eyes")
+			nested = eyeDetector.DetectNested(*img, dets, eyeBounds)
+			stop()
+		}
+		stats.Frame()
+		logging.Debugf("Faces: %d", len(dets))
+
+		for i, d := range dets {
+			draw.LabelBox(img, d.BBox, d.Label, draw.DefaultStyle)
+			if i < len(nested) {
+				for _, e := range nested[i] {
+					gocv.Rectangle(img, e.BBox, eyeStyle.LineColor, eyeStyle.LineThickness)
+				}
+			}
+		}
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package hdr
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// parseExposures parses exposure times in seconds separated by commas, as decimals or fractions
+// like 1/30
+func parseExposures(s string) ([]float64, error) {
+	var times []float64
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		var t float64
+		var err error
+		if parts := strings.Split(p, "/"); len(parts) == 2 {
+			var num, den float64
+			if num, err = strconv.ParseFloat(parts[0], 64); err == nil {
+				if den, err = strconv.ParseFloat(parts[1], 64); err == nil && den != 0 {
+					t = num / den
+				}
+			}
+		} else {
+			t, err = strconv.ParseFloat(p, 64)
+		}
+		if err != nil || t <= 0 {
+			return nil, fmt.Errorf("Exposures should be positive times in seconds like 1/30,0.5,2: %q", s)
+		}
+		times = append(times, t)
+	}
+	return times, nil
+}
+
+// weight is the hat function of Debevec and Malik: mid-tones are trusted more than values near
+// 0 and 255, which are clipped by the sensor
+func weight(z uint8) float64 {
+	if z < 128 {
+		return float64(z) + 1
+	}
+	return float64(256 - int(z))
+}
+
+// samplePoints returns indices of about n pixels of a w x h image on a regular grid
+func samplePoints(w, h, n int) []int {
+	step := int(math.Sqrt(float64(w*h) / float64(n)))
+	if step < 1 {
+		step = 1
+	}
+	var pts []int
+	for y := step / 2; y < h; y += step {
+		for x := step / 2; x < w; x += step {
+			pts = append(pts, y*w+x)
+		}
+	}
+	return pts
+}
+
+// responseCurve recovers the log inverse camera response g, so that g(z) = ln(E) + ln(t) for a pixel
+// value z of irradiance E exposed for t, by the method of P. Debevec and J. Malik, "Recovering
+// high dynamic range radiance maps from photographs" (1997). samples[i][j] is the value of pixel i
+// on image j, logTimes[j] is the log exposure time of image j and lambda weighs the smoothness
+// of the curve. The curve is fixed by g(128) = 0
+func responseCurve(samples [][]uint8, logTimes []float64, lambda float64) [256]float64 {
+	// Least squares over unknowns g(0..255) and ln(E) of each sample, solved with normal equations
+	n := 256 + len(samples)
+	ata := make([][]float64, n)
+	for i := range ata {
+		ata[i] = make([]float64, n)
+	}
+	atb := make([]float64, n)
+	addRow := func(cols []int, vals []float64, b float64) {
+		for i, ci := range cols {
+			for j, cj := range cols {
+				ata[ci][cj] += vals[i] * vals[j]
+			}
+			atb[ci] += vals[i] * b
+		}
+	}
+
+	for i, pixel := range samples {
+		for j, z := range pixel {
+			w := weight(z)
+			addRow([]int{int(z), 256 + i}, []float64{w, -w}, w*logTimes[j])
+		}
+	}
+	addRow([]int{128}, []float64{1}, 0)
+	for z := 1; z < 255; z++ {
+		w := lambda * weight(uint8(z))
+		addRow([]int{z - 1, z, z + 1}, []float64{w, -2 * w, w}, 0)
+	}
+
+	x := solve(ata, atb)
+	var g [256]float64
+	copy(g[:], x[:256])
+	return g
+}
+
+// Solves the linear system in place by Gaussian elimination with partial pivoting
+func solve(a [][]float64, b []float64) []float64 {
+	n := len(b)
+	for c := 0; c < n; c++ {
+		pivot := c
+		for r := c + 1; r < n; r++ {
+			if math.Abs(a[r][c]) > math.Abs(a[pivot][c]) {
+				pivot = r
+			}
+		}
+		a[c], a[pivot] = a[pivot], a[c]
+		b[c], b[pivot] = b[pivot], b[c]
+		if a[c][c] == 0 {
+			continue
+		}
+		for r := c + 1; r < n; r++ {
+			f := a[r][c] / a[c][c]
+			if f == 0 {
+				continue
+			}
+			for k := c; k < n; k++ {
+				a[r][k] -= f * a[c][k]
+			}
+			b[r] -= f * b[c]
+		}
+	}
+	x := make([]float64, n)
+	for r := n - 1; r >= 0; r-- {
+		if a[r][r] == 0 {
+			continue
+		}
+		s := b[r]
+		for k := r + 1; k < n; k++ {
+			s -= a[r][k] * x[k]
+		}
+		x[r] = s / a[r][r]
+	}
+	return x
+}
+
+// mergeRadiance returns the relative irradiance of each pixel value of images with interleaved
+// channels, as the weighted mean over the exposures of exp(g(z) - ln(t)). curves are the response
+// curves of the channels
+func mergeRadiance(images [][]uint8, ch int, curves [][256]float64, logTimes []float64) []float32 {
+	rad := make([]float32, len(images[0]))
+	for i := range rad {
+		c := i % ch
+		var sum, total float64
+		for j, img := range images {
+			w := weight(img[i])
+			sum += w * (curves[c][img[i]] - logTimes[j])
+			total += w
+		}
+		rad[i] = float32(math.Exp(sum / total))
+	}
+	return rad
+}
+
+// tonemap maps radiance of BGR pixels to 8-bit values with the global operator of E. Reinhard et al.,
+// "Photographic tone reproduction for digital images" (2002): luminance is scaled so that its log
+// average becomes key and compressed by L / (1 + L), colors keep their ratios, then gamma is applied
+func tonemap(rad []float32, key, gamma float64) []uint8 {
+	const eps = 1e-6
+	n := len(rad) / 3
+	lum := make([]float64, n)
+	var logSum float64
+	for i := range lum {
+		b, g, r := float64(rad[3*i]), float64(rad[3*i+1]), float64(rad[3*i+2])
+		lum[i] = 0.0722*b + 0.7152*g + 0.2126*r
+		logSum += math.Log(lum[i] + eps)
+	}
+	scale := key / math.Exp(logSum/float64(n))
+
+	out := make([]uint8, len(rad))
+	for i, l := range lum {
+		lm := l * scale
+		ratio := (lm / (1 + lm)) / (l + eps)
+		for c := 0; c < 3; c++ {
+			v := math.Pow(math.Min(1, float64(rad[3*i+c])*ratio), 1/gamma)
+			out[3*i+c] = uint8(math.Round(v * 255))
+		}
+	}
+	return out
+}
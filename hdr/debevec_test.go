@@ -0,0 +1,90 @@
+package hdr
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseExposures(t *testing.T) {
+	got, err := parseExposures("1/4, 0.5,2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []float64{0.25, 0.5, 2}) {
+		t.Errorf("Unexpected exposures %v", got)
+	}
+	for _, s := range []string{"", "1/0", "-1", "a,1", "1/2/3"} {
+		if _, err := parseExposures(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestSamplePoints(t *testing.T) {
+	pts := samplePoints(100, 50, 50)
+	if len(pts) != 50 || pts[0] != 5*100+5 {
+		t.Errorf("Unexpected %d points starting with %v", len(pts), pts[0])
+	}
+}
+
+// Pixel value of a camera with gamma 2.2 response for irradiance e exposed for time t
+func gammaCamera(e, t float64) uint8 {
+	return uint8(math.Round(255 * math.Min(1, math.Pow(e*t, 1/2.2))))
+}
+
+func TestResponseCurveAndMerge(t *testing.T) {
+	times := []float64{1.0 / 16, 1.0 / 4, 1, 4}
+	logTimes := make([]float64, len(times))
+	for j, tm := range times {
+		logTimes[j] = math.Log(tm)
+	}
+	var irradiance []float64
+	for e := 0.002; e < 8; e *= 1.15 {
+		irradiance = append(irradiance, e)
+	}
+	samples := make([][]uint8, len(irradiance))
+	for i, e := range irradiance {
+		for _, tm := range times {
+			samples[i] = append(samples[i], gammaCamera(e, tm))
+		}
+	}
+	g := responseCurve(samples, logTimes, 10)
+
+	// g(z) = 2.2 ln(z / 255) + const, so differences do not depend on the constant
+	for _, z := range [][2]int{{50, 100}, {100, 200}, {30, 220}} {
+		want := 2.2 * math.Log(float64(z[1])/float64(z[0]))
+		if got := g[z[1]] - g[z[0]]; math.Abs(got-want) > 0.15 {
+			t.Errorf("g(%d) - g(%d) = %.3f, want %.3f", z[1], z[0], got, want)
+		}
+	}
+
+	// Irradiance ratios of merged pixels match the scene, for one gray channel
+	images := make([][]uint8, len(times))
+	scene := []float64{0.01, 0.1, 1}
+	for j, tm := range times {
+		for _, e := range scene {
+			images[j] = append(images[j], gammaCamera(e, tm))
+		}
+	}
+	rad := mergeRadiance(images, 1, [][256]float64{g}, logTimes)
+	for i := 1; i < len(scene); i++ {
+		if ratio := float64(rad[i] / rad[i-1]); math.Abs(ratio-10)/10 > 0.2 {
+			t.Errorf("Radiance ratio %.2f, want 10", ratio)
+		}
+	}
+}
+
+func TestTonemap(t *testing.T) {
+	rad := []float32{0.01, 0.01, 0.01, 1, 1, 1, 100, 100, 100, 5, 0, 0}
+	out := tonemap(rad, 0.18, 2.2)
+	if !(out[0] < out[3] && out[3] < out[6]) {
+		t.Errorf("Tone mapping should keep the order of luminance: %v", out)
+	}
+	if out[6] == 255 && out[3] == 255 {
+		t.Errorf("Highlights should be compressed: %v", out)
+	}
+	if out[9] == 0 || out[10] != 0 || out[11] != 0 {
+		t.Errorf("Colors should keep their ratios: %v", out[9:])
+	}
+}
@@ -0,0 +1,240 @@
+// This example merges a bracket of photos taken with different exposures into one image showing
+// detail in both shadows and highlights
+//
+// Call: gocv-examples hdr -input "bracket/*.jpg" [-exposures 1/30,1/8,1/2] [-out-dir hdr] [-no-gui]
+// The images should show the same scene from a tripod or a steady hand, they are aligned first
+// unless -align=false. Two methods are used:
+//   Mertens exposure fusion blends the best exposed parts of the photos directly, no exposure times
+//   are needed; the result is saved as fusion.png
+//   Debevec merge recovers the camera response curve and the scene radiance from photos with known
+//   exposure times, given with -exposures in the order of the sorted file names. The radiance map
+//   is saved as radiance.hdr, which can be opened by HDR editors, and tone mapped to tonemapped.png
+// Both results are shown side by side, press any key to close the window
+// Parameters can also be set with HDR_* environment variables or a config file, see internal/config
+//
+// GoCV has no Debevec calibration, merge and tone mapping at the moment of writing, so they are
+// implemented here on a subset of pixels for the response curve and with the global Reinhard operator
+// for tone mapping: -key sets the brightness of the average tone and -gamma the display gamma
+
+package hdr
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	outDir     = "hdr"
+	numSamples = 100 // Pixels used to recover the response curve
+	smoothness = 10  // Weight of the response curve smoothness
+	keyValue   = 0.18
+	gammaValue = 2.2
+)
+
+// Debevec recovers the response curve of each channel of 8-bit BGR images with the exposure times
+// and merges them into a 32-bit float radiance map
+func Debevec(images []gocv.Mat, times []float64, samples int, lambda float64) gocv.Mat {
+	w, h := images[0].Cols(), images[0].Rows()
+	logTimes := make([]float64, len(times))
+	for j, t := range times {
+		logTimes[j] = math.Log(t)
+	}
+	pix := make([][]uint8, len(images))
+	for j, img := range images {
+		pix[j] = img.ToBytes()
+	}
+
+	points := samplePoints(w, h, samples)
+	curves := make([][256]float64, 3)
+	for c := range curves {
+		values := make([][]uint8, len(points))
+		for i, p := range points {
+			for j := range pix {
+				values[i] = append(values[i], pix[j][3*p+c])
+			}
+		}
+		curves[c] = responseCurve(values, logTimes, lambda)
+	}
+
+	rad := mergeRadiance(pix, 3, curves, logTimes)
+	result := gocv.NewMatWithSize(h, w, gocv.MatTypeCV32FC3)
+	data, _ := result.DataPtrFloat32()
+	copy(data, rad)
+	return result
+}
+
+// Tonemap returns the radiance map tone mapped to an 8-bit image, see tonemap
+func Tonemap(radiance gocv.Mat, key, gamma float64) gocv.Mat {
+	data, _ := radiance.DataPtrFloat32()
+	result, _ := gocv.NewMatFromBytes(radiance.Rows(), radiance.Cols(), gocv.MatTypeCV8UC3, tonemap(data, key, gamma))
+	return result
+}
+
+// Run merges the images given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples hdr", flag.ExitOnError)
+	input := fs.String("input", "", "Directory or glob pattern of the bracketed images")
+	exposuresStr := fs.String("exposures", "", "Exposure times in seconds of the sorted images, e.g. 1/30,1/8,1/2")
+	align := fs.Bool("align", true, "Align the images before merging")
+	dir := fs.String("out-dir", outDir, "Output directory")
+	samples := fs.Int("samples", numSamples, "Number of pixels used to recover the response curve")
+	lambda := fs.Float64("lambda", smoothness, "Smoothness of the response curve")
+	key := fs.Float64("key", keyValue, "Brightness of the average tone after tone mapping, from 0 to 1")
+	gamma := fs.Float64("gamma", gammaValue, "Display gamma of the tone mapped image")
+	var headless videoio.Headless
+	headless.RegisterFlags(fs)
+	logging.RegisterFlags(fs)
+	if err := config.Load(fs, args, "HDR"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("hdr")
+
+	if *input == "" {
+		return errors.New("Input is required, see 'gocv-examples hdr -h'")
+	}
+	files, err := videoio.ImageFiles(*input)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	if len(files) < 2 {
+		return errors.New("At least 2 images with different exposures are needed")
+	}
+	var times []float64
+	if *exposuresStr != "" {
+		if times, err = parseExposures(*exposuresStr); err != nil {
+			return err
+		}
+		if len(times) != len(files) {
+			return fmt.Errorf("%d exposure times are given for %d images", len(times), len(files))
+		}
+	}
+	if *samples < 10 || *lambda <= 0 || *key <= 0 || *key > 1 || *gamma <= 0 {
+		return errors.New("At least 10 samples are needed, smoothness and gamma should be positive, key from 0 to 1")
+	}
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return fmt.Errorf("Error creating output directory: %v", err)
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Camera: -1})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	var images []gocv.Mat
+	defer func() {
+		for _, img := range images {
+			img.Close()
+		}
+	}()
+	for _, file := range files {
+		img := gocv.IMRead(file, gocv.IMReadColor)
+		if img.Empty() {
+			img.Close()
+			return fmt.Errorf("Cannot read image %s", file)
+		}
+		if len(images) > 0 && (img.Cols() != images[0].Cols() || img.Rows() != images[0].Rows()) {
+			img.Close()
+			return fmt.Errorf("Images should have the same size: %s", file)
+		}
+		images = append(images, img)
+	}
+
+	if *align {
+		start := time.Now()
+		mtb := gocv.NewAlignMTB()
+		var aligned []gocv.Mat
+		mtb.Process(images, &aligned)
+		mtb.Close()
+		for _, img := range images {
+			img.Close()
+		}
+		images = aligned
+		logging.Infof("Aligned %d images in %v", len(images), time.Since(start).Round(time.Millisecond))
+	}
+
+	start := time.Now()
+	mertens := gocv.NewMergeMertens()
+	fusion := gocv.NewMat()
+	defer fusion.Close()
+	mertens.Process(images, &fusion)
+	mertens.Close()
+	logging.Infof("Exposure fusion in %v", time.Since(start).Round(time.Millisecond))
+	if err := save(filepath.Join(*dir, "fusion.png"), fusion); err != nil {
+		return err
+	}
+	panels := []gocv.Mat{fusion}
+	labels := []string{"Mertens fusion"}
+
+	if times != nil {
+		start = time.Now()
+		radiance := Debevec(images, times, *samples, *lambda)
+		defer radiance.Close()
+		ldr := Tonemap(radiance, *key, *gamma)
+		defer ldr.Close()
+		logging.Infof("Debevec merge and tone mapping in %v", time.Since(start).Round(time.Millisecond))
+		if err := save(filepath.Join(*dir, "radiance.hdr"), radiance); err != nil {
+			return err
+		}
+		if err := save(filepath.Join(*dir, "tonemapped.png"), ldr); err != nil {
+			return err
+		}
+		panels = append(panels, ldr)
+		labels = append(labels, "Debevec + Reinhard")
+	} else {
+		logging.Infof("No exposure times given, Debevec merge is skipped")
+	}
+
+	if headless.Enabled() {
+		return nil
+	}
+	window := gocv.NewWindow("HDR - press any key to close")
+	defer window.Close()
+	show(window, panels, labels)
+	videoio.WaitForKey(sd.Context(), window)
+	return nil
+}
+
+// Saves the image, IMWrite chooses the format by the extension
+func save(path string, img gocv.Mat) error {
+	if !gocv.IMWrite(path, img) {
+		return fmt.Errorf("Cannot write %s", path)
+	}
+	logging.Infof("Saved %s", path)
+	return nil
+}
+
+// Shows the labeled results side by side
+func show(window *gocv.Window, panels []gocv.Mat, labels []string) {
+	side := gocv.NewMat()
+	defer side.Close()
+	st := draw.DefaultStyle
+	for i, p := range panels {
+		labeled := p.Clone()
+		draw.TextWithBackground(&labeled, labels[i], image.Pt(0, st.TextSize(labels[i]).Y+2*st.Padding), st)
+		if i == 0 {
+			labeled.CopyTo(&side)
+		} else {
+			gocv.Hconcat(side, labeled, &side)
+		}
+		labeled.Close()
+	}
+	window.IMShow(side)
+}
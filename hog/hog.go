@@ -0,0 +1,199 @@
+// This example detects pedestrians with a HOG descriptor and the default people SVM of OpenCV.
+// It needs no DNN module and shows the classic approach which DNN detectors replaced
+//
+// Call: gocv-examples hog [-input 0] [-scale 1.05] [-stride 8] [-hit-thr 0] [-compare]
+// Input can be a camera ID (default 0), a video file, a stream URL or images
+// Parameters can also be set with HOG_* environment variables or a config file, see internal/config
+//
+// The detector is trained on upright people about 64x128 pixels and larger. A smaller -scale and
+// -stride find more people at the cost of speed, -hit-thr and -final-thr trade recall for fewer
+// false positives
+// With -compare, Yolo 4 runs on the same frames and people found by it are drawn too, with the timing
+// of both detectors and the number of HOG detections matching Yolo ones. Yolo model files are
+// downloaded on first run, see the yolo4 example
+
+package hog
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/nms"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"github.com/marchevska/gocv-examples/yolo4"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID      = "0" // Default input
+	hitThrStep = 0.1 // Change of the threshold by +/- keys
+	matchIoU   = 0.5 // Overlap of HOG and Yolo boxes of the same person
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Colors of the detectors in comparison mode
+var (
+	hogColor  = draw.Green
+	yoloColor = draw.Red
+)
+
+// matched returns the number of detections of a which overlap a detection of b by at least iouThr.
+// Each detection of b is matched once
+func matched(a, b []detection.Detection, iouThr float64) int {
+	used := make([]bool, len(b))
+	count := 0
+	for _, da := range a {
+		best, bestIoU := -1, iouThr
+		for j, db := range b {
+			if iou := nms.IoU(da.BBox, db.BBox); !used[j] && iou >= bestIoU {
+				best, bestIoU = j, iou
+			}
+		}
+		if best >= 0 {
+			used[best] = true
+			count++
+		}
+	}
+	return count
+}
+
+// Run detects pedestrians on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples hog", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	scale := fs.Float64("scale", detection.DefaultHOGScale, "Scale step of the image pyramid, greater than 1")
+	stride := fs.Int("stride", detection.DefaultHOGStride.X, "Step of the detection window in pixels")
+	padding := fs.Int("padding", detection.DefaultHOGPadding.X, "Padding around the detection window in pixels")
+	hitThr := fs.Float64("hit-thr", 0, "Distance from the SVM plane to keep a window")
+	finalThr := fs.Float64("final-thr", detection.DefaultHOGFinalThr, "Overlapping windows needed to keep a detection")
+	compare := fs.Bool("compare", false, "Also run Yolo 4 and compare its people with HOG detections")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "HOG"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("hog")
+
+	if *scale <= 1 {
+		return errors.New("Scale should be greater than 1")
+	}
+	if *stride <= 0 || *padding < 0 {
+		return errors.New("Stride should be positive and padding should not be negative")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	req := probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)}
+	if *compare {
+		req.MinOpenCV, req.DNN = yolo4.MinOpenCV, true
+	}
+	report := probe.Run(req)
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	hog := detection.NewHOGPeopleDetector()
+	sd.OnClose("HOG descriptor", hog.Close)
+	hog.Scale, hog.HitThr, hog.FinalThr = *scale, *hitThr, *finalThr
+	hog.WinStride, hog.Padding = image.Pt(*stride, *stride), image.Pt(*padding, *padding)
+	var yolo *detection.YoloDetector
+	if *compare {
+		var err error
+		if yolo, err = yolo4.LoadYolo(sd); err != nil {
+			return err
+		}
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "HOG pedestrian detection")
+	if err != nil {
+		return err
+	}
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			hog.HitThr += float64(step) * hitThrStep
+			if hog.HitThr < 0 {
+				hog.HitThr = 0
+			}
+			return fmt.Sprintf("hit threshold %.1f", hog.HitThr)
+		}
+	}
+
+	hogStyle := draw.DefaultStyle.WithColor(hogColor)
+	yoloStyle := draw.DefaultStyle.WithColor(yoloColor)
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("hog")
+		people, _ := hog.Detect(*img)
+		stop()
+		var yoloPeople []detection.Detection
+		if yolo != nil {
+			stop = stats.Start("yolo")
+			dets, _ := yolo.Detect(*img)
+			stop()
+			for _, d := range dets {
+				if d.Label == "person" {
+					yoloPeople = append(yoloPeople, d)
+				}
+			}
+		}
+		stats.Frame()
+
+		for _, d := range yoloPeople {
+			draw.LabelBox(img, d.BBox, fmt.Sprintf("Yolo %.0f%%", d.Confidence*100), yoloStyle)
+		}
+		for _, d := range people {
+			draw.LabelBox(img, d.BBox, "HOG", hogStyle)
+		}
+		if yolo != nil {
+			both := matched(people, yoloPeople, matchIoU)
+			logging.Infof("People: HOG %d, Yolo %d, both %d", len(people), len(yoloPeople), both)
+			draw.Legend(img, []draw.LegendEntry{
+				{Color: hogColor, Text: fmt.Sprintf("HOG: %d", len(people))},
+				{Color: yoloColor, Text: fmt.Sprintf("Yolo: %d", len(yoloPeople))},
+				{Color: draw.White, Text: fmt.Sprintf("Both: %d", both)},
+			}, image.Pt(0, 0), draw.DefaultStyle)
+		} else {
+			logging.Infof("People: %d", len(people))
+		}
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package hog
+
+import (
+	"image"
+	"testing"
+
+	"github.com/marchevska/gocv-examples/internal/detection"
+)
+
+func TestMatched(t *testing.T) {
+	person := func(r image.Rectangle) detection.Detection {
+		return detection.Detection{Label: "person", BBox: r}
+	}
+	hog := []detection.Detection{
+		person(image.Rect(0, 0, 64, 128)),
+		person(image.Rect(4, 4, 68, 132)),    // Duplicate of the same person
+		person(image.Rect(300, 0, 364, 128)), // Not found by Yolo
+	}
+	yolo := []detection.Detection{
+		person(image.Rect(2, 0, 66, 128)),
+		person(image.Rect(500, 0, 564, 128)),
+	}
+	if got := matched(hog, yolo, 0.5); got != 1 {
+		t.Errorf("Expected 1 match, got %d", got)
+	}
+	if got := matched(nil, yolo, 0.5); got != 0 {
+		t.Errorf("Expected no matches without detections, got %d", got)
+	}
+}
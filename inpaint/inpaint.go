@@ -0,0 +1,223 @@
+// This example removes unwanted objects, scratches or text from a photo by inpainting: the marked
+// regions are filled from their surroundings
+//
+// Call: gocv-examples inpaint -input photo.jpg [-mask mask.png] [-method telea] [-out photo_inpainted.png]
+// Mark the regions to remove by drawing rectangles with the mouse, press Enter or Space after each
+// one and Esc when done. The repaired image is saved and shown: press R to mark more regions,
+// C to clear the marks and start over, any other key to quit.
+// With -mask the regions are read from an image, where non-zero pixels are removed, and with
+// -no-gui, or when there is no display, the image is repaired with the mask only
+// Parameters can also be set with INPAINT_* environment variables or a config file, see internal/config
+//
+// Methods:
+//   telea     fast marching method of A. Telea, good for thin scratches and small objects
+//   fsr-fast  frequency selective reconstruction of opencv_contrib xphoto, better for larger regions
+//   fsr-best  the same with higher quality, slow
+//   shiftmap  fills regions with shifted patches of the image, keeps texture, xphoto as well
+// GoCV has no binding of OpenCV photo inpainting at the moment of writing, so the Telea method is
+// implemented here; Navier-Stokes inpainting is not available. GoCV has no mouse callbacks either,
+// so regions are marked with rectangles instead of painted with a brush
+
+package inpaint
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+	"gocv.io/x/gocv/contrib"
+)
+
+const (
+	method      = "telea"
+	radius      = 5   // Neighborhood of the Telea method
+	maskOpacity = 0.5 // Opacity of the marked regions in the window
+	windowTitle = "Inpainting"
+)
+
+// Methods of opencv_contrib xphoto by name
+var xphotoMethods = map[string]contrib.InpaintTypes{
+	"fsr-fast": contrib.FsrFast,
+	"fsr-best": contrib.FsrBest,
+	"shiftmap": contrib.ShitMap,
+}
+
+// Inpaint returns the image with the pixels where the mask is non-zero filled by the method
+func Inpaint(img, mask gocv.Mat, method string, radius int) gocv.Mat {
+	if m, ok := xphotoMethods[method]; ok {
+		return inpaintXPhoto(img, mask, m)
+	}
+
+	w, h, ch := img.Cols(), img.Rows(), img.Channels()
+	pix := img.ToBytes()
+	maskBytes := mask.ToBytes()
+	marked := make([]bool, len(maskBytes))
+	for i, v := range maskBytes {
+		marked[i] = v != 0
+	}
+	telea(pix, w, h, ch, marked, radius)
+	result, _ := gocv.NewMatFromBytes(h, w, img.Type(), pix)
+	return result
+}
+
+// xphoto takes the mask of valid pixels rather than of the pixels to fill, and the shift map
+// method works in the Lab color space
+func inpaintXPhoto(img, mask gocv.Mat, m contrib.InpaintTypes) gocv.Mat {
+	valid := gocv.NewMat()
+	defer valid.Close()
+	gocv.BitwiseNot(mask, &valid)
+	src := img.Clone()
+	defer src.Close()
+	if m == contrib.ShitMap {
+		gocv.CvtColor(img, &src, gocv.ColorBGRToLab)
+	}
+	result := gocv.NewMat()
+	contrib.Inpaint(&src, &valid, &result, m)
+	if m == contrib.ShitMap {
+		gocv.CvtColor(result, &result, gocv.ColorLabToBGR)
+	}
+	return result
+}
+
+// Run repairs the image given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples inpaint", flag.ExitOnError)
+	input := fs.String("input", "", "Image file to repair")
+	maskPath := fs.String("mask", "", "Mask image, non-zero pixels are removed")
+	methodName := fs.String("method", method, "Inpainting method: telea, fsr-fast, fsr-best or shiftmap")
+	r := fs.Int("radius", radius, "Neighborhood radius of the Telea method in pixels")
+	out := fs.String("out", "", "Output file, <input name>_inpainted.png by default")
+	var headless videoio.Headless
+	headless.RegisterFlags(fs)
+	logging.RegisterFlags(fs)
+	if err := config.Load(fs, args, "INPAINT"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("inpaint")
+
+	if *input == "" {
+		return errors.New("Input is required, see 'gocv-examples inpaint -h'")
+	}
+	if _, ok := xphotoMethods[*methodName]; !ok && *methodName != "telea" {
+		return fmt.Errorf("Method should be telea, fsr-fast, fsr-best or shiftmap: %q", *methodName)
+	}
+	if *r < 1 {
+		return errors.New("Radius should be positive")
+	}
+	if *maskPath == "" && headless.Enabled() {
+		return errors.New("Without a display the regions to remove should be given with -mask")
+	}
+	if *out == "" {
+		*out = strings.TrimSuffix(filepath.Base(*input), filepath.Ext(*input)) + "_inpainted.png"
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Camera: -1})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	img := gocv.IMRead(*input, gocv.IMReadColor)
+	defer img.Close()
+	if img.Empty() {
+		return fmt.Errorf("Cannot read image %s", *input)
+	}
+	mask := gocv.NewMatWithSize(img.Rows(), img.Cols(), gocv.MatTypeCV8U)
+	defer mask.Close()
+	if *maskPath != "" {
+		loaded := gocv.IMRead(*maskPath, gocv.IMReadGrayScale)
+		defer loaded.Close()
+		if loaded.Empty() {
+			return fmt.Errorf("Cannot read mask %s", *maskPath)
+		}
+		gocv.Resize(loaded, &loaded, image.Pt(img.Cols(), img.Rows()), 0, 0, gocv.InterpolationNearestNeighbor)
+		gocv.Threshold(loaded, &mask, 0, 255, gocv.ThresholdBinary)
+	}
+
+	if headless.Enabled() {
+		result := repair(img, mask, *methodName, *r, *out)
+		result.Close()
+		return nil
+	}
+
+	window := gocv.NewWindow(windowTitle)
+	defer window.Close()
+	for sd.Context().Err() == nil {
+		// Mark more regions over the marks so far
+		preview := markedPreview(img, mask)
+		for _, rect := range window.SelectROIs(preview) {
+			gocv.Rectangle(&mask, rect, draw.White, -1)
+		}
+		preview.Close()
+		if gocv.CountNonZero(mask) == 0 {
+			logging.Infof("No regions marked")
+			return nil
+		}
+
+		result := repair(img, mask, *methodName, *r, *out)
+		st := draw.DefaultStyle
+		text := "R - mark more, C - clear marks, other keys - quit"
+		draw.TextWithBackground(&result, text, image.Pt(0, st.TextSize(text).Y+2*st.Padding), st)
+		window.IMShow(result)
+		result.Close()
+		switch waitKey(sd.Context(), window) {
+		case 'r', 'R':
+		case 'c', 'C':
+			mask.SetTo(gocv.NewScalar(0, 0, 0, 0))
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// Inpaints the image and saves the result, which should be closed after use
+func repair(img, mask gocv.Mat, method string, radius int, out string) gocv.Mat {
+	start := time.Now()
+	result := Inpaint(img, mask, method, radius)
+	logging.Infof("Filled %d pixels with %s in %v", gocv.CountNonZero(mask), method,
+		time.Since(start).Round(time.Millisecond))
+	if gocv.IMWrite(out, result) {
+		logging.Infof("Saved %s", out)
+	} else {
+		logging.Errorf("Cannot write %s", out)
+	}
+	return result
+}
+
+// Returns the image with the marked regions tinted red
+func markedPreview(img, mask gocv.Mat) gocv.Mat {
+	preview := img.Clone()
+	tinted, red := img.Clone(), img.Clone()
+	defer tinted.Close()
+	defer red.Close()
+	red.SetTo(gocv.NewScalar(float64(draw.Red.B), float64(draw.Red.G), float64(draw.Red.R), 0))
+	red.CopyToWithMask(&tinted, mask)
+	gocv.AddWeighted(img, 1-maskOpacity, tinted, maskOpacity, 0, &preview)
+	return preview
+}
+
+// Waits for a key press until the context is cancelled, which gives -1
+func waitKey(ctx context.Context, window *gocv.Window) int {
+	for ctx.Err() == nil {
+		if key := window.WaitKey(50); key >= 0 {
+			return key
+		}
+	}
+	return -1
+}
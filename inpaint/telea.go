@@ -0,0 +1,174 @@
+package inpaint
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Pixel states of the fast marching method
+const (
+	known  = iota // Original or already filled
+	band          // On the front between the known and the unknown pixels
+	inside        // Still to be filled
+)
+
+const unknownDist = 1e6
+
+// Pixel of the front ordered by its distance from the original boundary
+type bandPixel struct {
+	dist float64
+	i    int
+}
+
+type bandHeap []bandPixel
+
+func (h bandHeap) Len() int            { return len(h) }
+func (h bandHeap) Less(i, j int) bool  { return h[i].dist < h[j].dist }
+func (h bandHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *bandHeap) Push(x interface{}) { *h = append(*h, x.(bandPixel)) }
+func (h *bandHeap) Pop() interface{} {
+	old := *h
+	p := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return p
+}
+
+// teleaFiller fills the masked region of an image from its boundary inwards, see telea
+type teleaFiller struct {
+	pix      []uint8
+	w, h, ch int
+	radius   int
+	state    []uint8
+	dist     []float64
+	front    bandHeap
+}
+
+// telea fills the pixels where mask is true with the method of A. Telea, "An image inpainting
+// technique based on the fast marching method" (2004). The image has ch interleaved channels and
+// is changed in place. Pixels are filled in the order of their distance from the region boundary,
+// each one with the weighted mean of the known pixels within radius: closer pixels, pixels
+// at a similar distance from the boundary and pixels in the direction of the boundary normal
+// weigh more
+func telea(pix []uint8, w, h, ch int, mask []bool, radius int) {
+	f := &teleaFiller{pix: pix, w: w, h: h, ch: ch, radius: radius,
+		state: make([]uint8, w*h), dist: make([]float64, w*h)}
+	for i, m := range mask {
+		if m {
+			f.state[i], f.dist[i] = inside, unknownDist
+		}
+	}
+	// Known pixels next to the region form the initial front
+	for i, m := range mask {
+		if !m && f.nextToInside(i) {
+			f.state[i] = band
+			f.front = append(f.front, bandPixel{0, i})
+		}
+	}
+	heap.Init(&f.front)
+
+	for f.front.Len() > 0 {
+		p := heap.Pop(&f.front).(bandPixel).i
+		f.state[p] = known
+		x, y := p%w, p/w
+		for _, d := range [4][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+			nx, ny := x+d[0], y+d[1]
+			if nx < 0 || ny < 0 || nx >= w || ny >= h || f.state[ny*w+nx] != inside {
+				continue
+			}
+			q := ny*w + nx
+			f.state[q] = band
+			f.dist[q] = math.Min(
+				math.Min(f.solve(nx-1, ny, nx, ny-1), f.solve(nx+1, ny, nx, ny-1)),
+				math.Min(f.solve(nx-1, ny, nx, ny+1), f.solve(nx+1, ny, nx, ny+1)))
+			f.fill(nx, ny)
+			heap.Push(&f.front, bandPixel{f.dist[q], q})
+		}
+	}
+}
+
+// Tells whether a 4-neighbor of the pixel is inside the region
+func (f *teleaFiller) nextToInside(i int) bool {
+	x, y := i%f.w, i/f.w
+	return x > 0 && f.state[i-1] == inside || x < f.w-1 && f.state[i+1] == inside ||
+		y > 0 && f.state[i-f.w] == inside || y < f.h-1 && f.state[i+f.w] == inside
+}
+
+// Returns the distance of a pixel from the boundary, or unknownDist if it is not reached yet
+func (f *teleaFiller) at(x, y int) float64 {
+	if x < 0 || y < 0 || x >= f.w || y >= f.h || f.state[y*f.w+x] == inside {
+		return unknownDist
+	}
+	return f.dist[y*f.w+x]
+}
+
+// Solves the eikonal equation |grad T| = 1 from a horizontal and a vertical neighbor
+func (f *teleaFiller) solve(x1, y1, x2, y2 int) float64 {
+	t1, t2 := f.at(x1, y1), f.at(x2, y2)
+	if t1 < unknownDist && t2 < unknownDist {
+		if d := 2 - (t1-t2)*(t1-t2); d > 0 {
+			r := math.Sqrt(d)
+			if s := (t1 + t2 - r) / 2; s >= t1 && s >= t2 {
+				return s
+			}
+			if s := (t1 + t2 + r) / 2; s >= t1 && s >= t2 {
+				return s
+			}
+		}
+		return unknownDist
+	}
+	return 1 + math.Min(t1, t2)
+}
+
+// Returns the derivative of the distance along one axis, one-sided at the region edge
+func (f *teleaFiller) derivative(x, y, dx, dy int) float64 {
+	prev, next := f.at(x-dx, y-dy), f.at(x+dx, y+dy)
+	switch {
+	case prev < unknownDist && next < unknownDist:
+		return (next - prev) / 2
+	case next < unknownDist:
+		return next - f.dist[y*f.w+x]
+	case prev < unknownDist:
+		return f.dist[y*f.w+x] - prev
+	}
+	return 0
+}
+
+// Fills the pixel with the weighted mean of the known pixels around it
+func (f *teleaFiller) fill(x, y int) {
+	q := y*f.w + x
+	gx, gy := f.derivative(x, y, 1, 0), f.derivative(x, y, 0, 1)
+	gradLen := math.Hypot(gx, gy)
+	sum := make([]float64, f.ch)
+	var total float64
+	for ky := y - f.radius; ky <= y+f.radius; ky++ {
+		for kx := x - f.radius; kx <= x+f.radius; kx++ {
+			if kx < 0 || ky < 0 || kx >= f.w || ky >= f.h {
+				continue
+			}
+			k := ky*f.w + kx
+			rx, ry := float64(x-kx), float64(y-ky)
+			d2 := rx*rx + ry*ry
+			if k == q || f.state[k] == inside || d2 > float64(f.radius*f.radius) {
+				continue
+			}
+			dir := 1.0
+			if gradLen > 0 {
+				dir = math.Abs(rx*gx+ry*gy) / (math.Sqrt(d2) * gradLen)
+			}
+			if dir < 0.01 {
+				dir = 0.01
+			}
+			weight := dir / d2 / (1 + math.Abs(f.dist[k]-f.dist[q]))
+			for c := 0; c < f.ch; c++ {
+				sum[c] += weight * float64(f.pix[k*f.ch+c])
+			}
+			total += weight
+		}
+	}
+	if total == 0 {
+		return
+	}
+	for c := 0; c < f.ch; c++ {
+		f.pix[q*f.ch+c] = uint8(math.Min(255, math.Round(sum[c]/total)))
+	}
+}
@@ -0,0 +1,73 @@
+package inpaint
+
+import "testing"
+
+// Returns a mask of the rectangle from (x0, y0) to (x1, y1) exclusive
+func rectMask(w, h, x0, y0, x1, y1 int) []bool {
+	mask := make([]bool, w*h)
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			mask[y*w+x] = true
+		}
+	}
+	return mask
+}
+
+func TestTeleaUniform(t *testing.T) {
+	w, h := 20, 15
+	pix := make([]uint8, w*h*3)
+	for i := 0; i < len(pix); i += 3 {
+		pix[i], pix[i+1], pix[i+2] = 10, 120, 200
+	}
+	mask := rectMask(w, h, 5, 4, 12, 10)
+	for i, m := range mask {
+		if m {
+			pix[i*3], pix[i*3+1], pix[i*3+2] = 255, 255, 255
+		}
+	}
+	telea(pix, w, h, 3, mask, 3)
+	for i := 0; i < w*h; i++ {
+		if pix[i*3] != 10 || pix[i*3+1] != 120 || pix[i*3+2] != 200 {
+			t.Fatalf("Pixel %d,%d is %v, want the surrounding color", i%w, i/w, pix[i*3:i*3+3])
+		}
+	}
+}
+
+func TestTeleaGradient(t *testing.T) {
+	// Horizontal ramp with a vertical stripe removed: filled values should stay within the values
+	// of the known pixels in reach and grow from left to right
+	w, h := 30, 10
+	pix := make([]uint8, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			pix[y*w+x] = uint8(x * 8)
+		}
+	}
+	mask := rectMask(w, h, 12, 0, 18, h)
+	for i, m := range mask {
+		if m {
+			pix[i] = 0
+		}
+	}
+	telea(pix, w, h, 1, mask, 4)
+	for y := 0; y < h; y++ {
+		for x := 12; x < 18; x++ {
+			v := pix[y*w+x]
+			if v < 8*8 || v > 21*8 {
+				t.Errorf("Pixel %d,%d is %d, want between %d and %d", x, y, v, 8*8, 21*8)
+			}
+		}
+		if pix[y*w+12] >= pix[y*w+17] {
+			t.Errorf("Row %d should grow from left to right: %v", y, pix[y*w+11:y*w+19])
+		}
+	}
+}
+
+func TestTeleaWholeImage(t *testing.T) {
+	// Without known pixels nothing can be filled
+	pix := []uint8{1, 2, 3, 4}
+	telea(pix, 2, 2, 1, rectMask(2, 2, 0, 0, 2, 2), 3)
+	if pix[0] != 1 || pix[3] != 4 {
+		t.Errorf("Pixels should not change: %v", pix)
+	}
+}
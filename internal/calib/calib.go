@@ -0,0 +1,109 @@
+// Package calib stores camera intrinsics found by the calibrate example in a YAML file, so that
+// examples working with image geometry can undistort frames or estimate poses.
+package calib
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Intrinsics are the parameters of a camera found by calibration
+type Intrinsics struct {
+	ImageWidth   int        `yaml:"image_width"`
+	ImageHeight  int        `yaml:"image_height"`
+	CameraMatrix [9]float64 `yaml:"camera_matrix"`   // fx, 0, cx, 0, fy, cy, 0, 0, 1, row by row
+	DistCoeffs   []float64  `yaml:"dist_coeffs"`     // k1, k2, p1, p2, k3 for the pinhole model, k1 to k4 for fisheye
+	RMS          float64    `yaml:"rms"`             // Reprojection error of the calibration in pixels
+	Model        string     `yaml:"model,omitempty"` // Pinhole if empty, or Fisheye
+}
+
+// Camera models
+const (
+	Pinhole = ""
+	Fisheye = "fisheye"
+)
+
+// Size returns the image size the camera was calibrated at
+func (in Intrinsics) Size() image.Point {
+	return image.Pt(in.ImageWidth, in.ImageHeight)
+}
+
+// Scaled returns the intrinsics for frames of another size with the same aspect ratio, e.g. when
+// the camera was calibrated at full resolution and frames are captured at a lower one
+func (in Intrinsics) Scaled(size image.Point) Intrinsics {
+	if size == in.Size() || in.ImageWidth == 0 || in.ImageHeight == 0 {
+		return in
+	}
+	sx := float64(size.X) / float64(in.ImageWidth)
+	sy := float64(size.Y) / float64(in.ImageHeight)
+	out := in
+	out.ImageWidth, out.ImageHeight = size.X, size.Y
+	out.CameraMatrix[0] *= sx // fx
+	out.CameraMatrix[2] *= sx // cx
+	out.CameraMatrix[4] *= sy // fy
+	out.CameraMatrix[5] *= sy // cy
+	out.DistCoeffs = append([]float64(nil), in.DistCoeffs...)
+	return out
+}
+
+// Load reads intrinsics from a YAML file
+func Load(path string) (Intrinsics, error) {
+	var in Intrinsics
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return in, err
+	}
+	if err := yaml.UnmarshalStrict(data, &in); err != nil {
+		return in, fmt.Errorf("Error parsing %s: %v", path, err)
+	}
+	if in.ImageWidth <= 0 || in.ImageHeight <= 0 || in.CameraMatrix[0] == 0 || in.CameraMatrix[4] == 0 {
+		return in, errors.New("Intrinsics should have image size and focal lengths")
+	}
+	switch in.Model {
+	case Pinhole:
+	case Fisheye:
+		if len(in.DistCoeffs) != 4 {
+			return in, errors.New("Fisheye intrinsics should have 4 distortion coefficients")
+		}
+	default:
+		return in, fmt.Errorf("Unknown camera model %q, should be empty or %q", in.Model, Fisheye)
+	}
+	return in, nil
+}
+
+// Save writes intrinsics to a YAML file
+func (in Intrinsics) Save(path string) error {
+	data, err := yaml.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// ChessboardPoints returns the inner corners of a chessboard with pattern corners per row and
+// column in the plane z = 0, row by row, in the units of the square size
+func ChessboardPoints(pattern image.Point, square float64) [][3]float64 {
+	pts := make([][3]float64, 0, pattern.X*pattern.Y)
+	for y := 0; y < pattern.Y; y++ {
+		for x := 0; x < pattern.X; x++ {
+			pts = append(pts, [3]float64{float64(x) * square, float64(y) * square, 0})
+		}
+	}
+	return pts
+}
+
+// ViewMatrix returns the camera matrix of an undistorted view of given size, with the focal lengths
+// multiplied by scale and the principal point in the center. Scale below 1 widens the field of view,
+// showing more of a wide angle image at the cost of stretched corners
+func (in Intrinsics) ViewMatrix(size image.Point, scale float64) [9]float64 {
+	m := in.CameraMatrix
+	m[0] *= scale
+	m[4] *= scale
+	m[2] = float64(size.X) / 2
+	m[5] = float64(size.Y) / 2
+	return m
+}
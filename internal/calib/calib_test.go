@@ -0,0 +1,90 @@
+package calib
+
+import (
+	"image"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	in := Intrinsics{
+		ImageWidth: 640, ImageHeight: 480,
+		CameraMatrix: [9]float64{600, 0, 320, 0, 610, 240, 0, 0, 1},
+		DistCoeffs:   []float64{-0.1, 0.05, 0, 0, 0.01},
+		RMS:          0.3,
+	}
+	path := filepath.Join(t.TempDir(), "camera.yaml")
+	if err := in.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, in) {
+		t.Errorf("Loaded %+v, saved %+v", got, in)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+}
+
+func TestScaled(t *testing.T) {
+	in := Intrinsics{ImageWidth: 1280, ImageHeight: 720, CameraMatrix: [9]float64{1000, 0, 640, 0, 1000, 360, 0, 0, 1}}
+	got := in.Scaled(image.Pt(640, 360))
+	want := [9]float64{500, 0, 320, 0, 500, 180, 0, 0, 1}
+	if got.CameraMatrix != want || got.Size() != image.Pt(640, 360) {
+		t.Errorf("Unexpected scaled intrinsics %+v", got)
+	}
+	if in.CameraMatrix[0] != 1000 {
+		t.Error("Original intrinsics should not change")
+	}
+}
+
+func TestChessboardPoints(t *testing.T) {
+	pts := ChessboardPoints(image.Pt(3, 2), 25)
+	if len(pts) != 6 || pts[1] != [3]float64{25, 0, 0} || pts[5] != [3]float64{50, 25, 0} {
+		t.Errorf("Unexpected points %v", pts)
+	}
+}
+
+func TestLoadFisheye(t *testing.T) {
+	in := Intrinsics{
+		ImageWidth: 1920, ImageHeight: 1080,
+		CameraMatrix: [9]float64{800, 0, 960, 0, 800, 540, 0, 0, 1},
+		DistCoeffs:   []float64{0.05, -0.01, 0.002, 0},
+		Model:        Fisheye,
+	}
+	path := filepath.Join(t.TempDir(), "fisheye.yaml")
+	if err := in.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Load(path); err != nil || got.Model != Fisheye {
+		t.Errorf("Loaded %+v, error %v", got, err)
+	}
+
+	in.DistCoeffs = append(in.DistCoeffs, 0)
+	if err := in.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected error for 5 fisheye coefficients")
+	}
+	in.Model = "omni"
+	if err := in.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Error("Expected error for an unknown model")
+	}
+}
+
+func TestViewMatrix(t *testing.T) {
+	in := Intrinsics{ImageWidth: 640, ImageHeight: 480, CameraMatrix: [9]float64{400, 0, 322, 0, 410, 236, 0, 0, 1}}
+	got := in.ViewMatrix(image.Pt(800, 600), 0.5)
+	want := [9]float64{200, 0, 400, 0, 205, 300, 0, 0, 1}
+	if got != want {
+		t.Errorf("View matrix %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,92 @@
+package calib
+
+import (
+	"image"
+
+	"gocv.io/x/gocv"
+)
+
+// Mats returns the camera matrix and the distortion coefficients as OpenCV matrices, which
+// should be closed after use
+func (in Intrinsics) Mats() (camera, dist gocv.Mat) {
+	camera = cameraMat(in.CameraMatrix)
+	dist = gocv.NewMatWithSize(1, len(in.DistCoeffs), gocv.MatTypeCV64F)
+	for i, v := range in.DistCoeffs {
+		dist.SetDoubleAt(0, i, v)
+	}
+	return camera, dist
+}
+
+// Returns a 3x3 camera matrix given by rows
+func cameraMat(m [9]float64) gocv.Mat {
+	mat := gocv.NewMatWithSize(3, 3, gocv.MatTypeCV64F)
+	for i, v := range m {
+		mat.SetDoubleAt(i/3, i%3, v)
+	}
+	return mat
+}
+
+// FromMats creates intrinsics from the matrices found by CalibrateCamera
+func FromMats(camera, dist gocv.Mat, size image.Point, rms float64) Intrinsics {
+	in := Intrinsics{ImageWidth: size.X, ImageHeight: size.Y, RMS: rms}
+	for i := range in.CameraMatrix {
+		in.CameraMatrix[i] = camera.GetDoubleAt(i/3, i%3)
+	}
+	flat := dist.Reshape(1, 1)
+	defer flat.Close()
+	for i := 0; i < flat.Cols(); i++ {
+		in.DistCoeffs = append(in.DistCoeffs, flat.GetDoubleAt(0, i))
+	}
+	return in
+}
+
+// Undistorter removes lens distortion from frames of the calibrated camera, with the pinhole or
+// the fisheye model
+type Undistorter struct {
+	// Scale multiplies the focal length of the undistorted view, see Intrinsics.ViewMatrix.
+	// With 1 the view of a pinhole camera keeps the original camera matrix
+	Scale float64
+
+	in                 Intrinsics
+	camera, dist, view gocv.Mat
+	size               image.Point
+	scale              float64
+}
+
+// NewUndistorter creates an undistorter with scale 1, which should be closed after use
+func NewUndistorter(in Intrinsics) *Undistorter {
+	return &Undistorter{Scale: 1, in: in, camera: gocv.NewMat(), dist: gocv.NewMat(), view: gocv.NewMat()}
+}
+
+// Undistort writes the undistorted image to dst. Intrinsics are scaled to the image size
+func (u *Undistorter) Undistort(src gocv.Mat, dst *gocv.Mat) {
+	size := image.Pt(src.Cols(), src.Rows())
+	if size != u.size || u.Scale != u.scale {
+		u.camera.Close()
+		u.dist.Close()
+		u.view.Close()
+		scaled := u.in.Scaled(size)
+		u.camera, u.dist = scaled.Mats()
+		if u.in.Model == Pinhole && u.Scale == 1 {
+			u.view = u.camera.Clone()
+		} else {
+			u.view = cameraMat(scaled.ViewMatrix(size, u.Scale))
+		}
+		u.size, u.scale = size, u.Scale
+	}
+	if u.in.Model == Fisheye {
+		out := gocv.NewMat()
+		gocv.FisheyeUndistortImageWithParams(src, &out, u.camera, u.dist, u.view, size)
+		out.CopyTo(dst)
+		out.Close()
+		return
+	}
+	gocv.Undistort(src, dst, u.camera, u.dist, u.view)
+}
+
+// Close releases the matrices
+func (u *Undistorter) Close() error {
+	u.camera.Close()
+	u.dist.Close()
+	return u.view.Close()
+}
@@ -0,0 +1,159 @@
+// Package config loads example parameters from command line flags, environment variables
+// and an optional YAML file.
+//
+// Every parameter is defined as a flag of a flag.FlagSet. Values are taken with the following
+// precedence, from highest to lowest:
+//
+//  1. command line flags, e.g. -conf-thr 0.6
+//  2. environment variables: the prefix, an underscore and the flag name in upper case
+//     with dashes replaced by underscores, e.g. YOLO4_CONF_THR=0.6
+//  3. the YAML file given by -config (or the PREFIX_CONFIG variable), with flag names as keys,
+//     e.g. "conf-thr: 0.6"; lists set repeatable flags once per item
+//  4. flag defaults
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ConfigFlag is the name of the flag holding the config file path
+const ConfigFlag = "config"
+
+// Load parses args into fs, then sets flags that were not given on the command line from
+// environment variables with envPrefix and from the config file.
+// A -config flag is added to fs if it is not defined yet
+func Load(fs *flag.FlagSet, args []string, envPrefix string) error {
+	if fs.Lookup(ConfigFlag) == nil {
+		fs.String(ConfigFlag, "", "YAML file with parameters, keys are flag names")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	// Config file path may itself come from the environment
+	if !set[ConfigFlag] {
+		if v, ok := os.LookupEnv(EnvName(envPrefix, ConfigFlag)); ok {
+			if err := fs.Set(ConfigFlag, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Environment overrides the file, so it is applied last
+	fileValues, err := readFile(fs.Lookup(ConfigFlag).Value.String())
+	if err != nil {
+		return err
+	}
+	for name, value := range fileValues {
+		if set[name] {
+			continue
+		}
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("Unknown parameter in config file: %s", name)
+		}
+		if err := setValue(fs, name, value); err != nil {
+			return err
+		}
+	}
+
+	var envErr error
+	fs.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] || f.Name == ConfigFlag || envErr != nil {
+			return
+		}
+		if v, ok := os.LookupEnv(EnvName(envPrefix, f.Name)); ok {
+			resetValue(f)
+			for _, item := range splitEnvValue(f, v) {
+				if err := fs.Set(f.Name, item); err != nil {
+					envErr = fmt.Errorf("Invalid value %q for %s: %v", v, EnvName(envPrefix, f.Name), err)
+					return
+				}
+			}
+		}
+	})
+	return envErr
+}
+
+// EnvName returns the name of the environment variable for the flag
+func EnvName(prefix, name string) string {
+	name = strings.ToUpper(strings.Replace(name, "-", "_", -1))
+	if prefix == "" {
+		return name
+	}
+	return strings.ToUpper(prefix) + "_" + name
+}
+
+// Reads flat key-value YAML file; empty path means no file
+func readFile(path string) (map[string]interface{}, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("Cannot parse config file %s: %v", path, err)
+	}
+	return values, nil
+}
+
+// Sets a flag from a config file value. Lists set the flag once per item
+func setValue(fs *flag.FlagSet, name string, value interface{}) error {
+	items, ok := value.([]interface{})
+	if !ok {
+		items = []interface{}{value}
+	}
+	for _, item := range items {
+		if err := fs.Set(name, fmt.Sprint(item)); err != nil {
+			return fmt.Errorf("Invalid value %v for %s in config file: %v", item, name, err)
+		}
+	}
+	return nil
+}
+
+// Repeatable flags collect values, so the ones from the file are dropped before applying the environment
+func resetValue(f *flag.Flag) {
+	if r, ok := f.Value.(interface{ Reset() }); ok {
+		r.Reset()
+	}
+}
+
+// Environment values of repeatable flags are comma separated
+func splitEnvValue(f *flag.Flag, v string) []string {
+	if _, ok := f.Value.(*StringList); ok {
+		return strings.Split(v, ",")
+	}
+	return []string{v}
+}
+
+// StringList is a repeatable string flag
+type StringList []string
+
+func (sl *StringList) String() string {
+	if sl == nil {
+		return ""
+	}
+	return strings.Join(*sl, ",")
+}
+
+// Set appends the value to the list
+func (sl *StringList) Set(v string) error {
+	*sl = append(*sl, v)
+	return nil
+}
+
+// Reset empties the list
+func (sl *StringList) Reset() {
+	*sl = nil
+}
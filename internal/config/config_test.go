@@ -0,0 +1,103 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		file    string // YAML content, no config file if empty
+		want    string // Values of -name, -count and -list
+		wantErr bool
+	}{
+		{name: "defaults", want: "default 1 "},
+		{name: "file", file: "name: file\ncount: 2", want: "file 2 "},
+		{name: "env over file", file: "name: file\ncount: 2", env: map[string]string{"TEST_NAME": "env"},
+			want: "env 2 "},
+		{name: "flag over env and file", args: []string{"-name", "flag"}, file: "name: file",
+			env: map[string]string{"TEST_NAME": "env"}, want: "flag 1 "},
+		{name: "config path from env", env: map[string]string{"TEST_CONFIG": "FILE", "TEST_COUNT": "3"},
+			file: "name: file", want: "file 3 "},
+		{name: "list in file", file: "list: [a, b]", want: "default 1 a,b"},
+		{name: "list in env replaces file", file: "list: [a, b]", env: map[string]string{"TEST_LIST": "c,d"},
+			want: "default 1 c,d"},
+		{name: "list flags replace env and file", args: []string{"-list", "e", "-list", "f"}, file: "list: [a, b]",
+			env: map[string]string{"TEST_LIST": "c,d"}, want: "default 1 e,f"},
+		{name: "invalid YAML", file: "name: [unclosed", wantErr: true},
+		{name: "unknown key in file", file: "size: 3", wantErr: true},
+		{name: "invalid file value", file: "count: many", wantErr: true},
+		{name: "invalid env value", env: map[string]string{"TEST_COUNT": "many"}, wantErr: true},
+		{name: "invalid flag value", args: []string{"-count", "many"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The config file is given by -config unless TEST_CONFIG is FILE
+			args, path := tt.args, ""
+			if tt.file != "" {
+				path = filepath.Join(t.TempDir(), "config.yaml")
+				if err := ioutil.WriteFile(path, []byte(tt.file), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if tt.env["TEST_CONFIG"] != "FILE" {
+					args = append([]string{"-config", path}, args...)
+				}
+			}
+			for k, v := range tt.env {
+				if v == "FILE" {
+					v = path
+				}
+				setEnv(t, k, v)
+			}
+
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			fs.SetOutput(ioutil.Discard)
+			name := fs.String("name", "default", "")
+			count := fs.Int("count", 1, "")
+			var list StringList
+			fs.Var(&list, "list", "")
+			err := Load(fs, args, "TEST")
+			if tt.wantErr {
+				if err == nil {
+					t.Error("No error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprintf("%s %d %s", *name, *count, list.String()); got != tt.want {
+				t.Errorf("Got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Sets the environment variable for the test, restoring it afterwards
+func setEnv(t *testing.T, key, value string) {
+	old, had := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if had {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestEnvName(t *testing.T) {
+	if got := EnvName("yolo4", "conf-thr"); got != "YOLO4_CONF_THR" {
+		t.Errorf("Got %s", got)
+	}
+	if got := EnvName("", "input"); got != "INPUT" {
+		t.Errorf("Got %s without a prefix", got)
+	}
+}
@@ -0,0 +1,505 @@
+// Package control lets clients steer a running example over HTTP or a WebSocket without restarting
+// it: pause and resume processing, change detection thresholds, choose the classes to report and
+// take snapshots of the annotated frames. The API serves JSON on the -control address:
+//
+//	GET  /state     {"paused":false,"thresholds":{"confidence":0.5,"nms":0.4},"classes":[],"frames":120}
+//	POST /state     {"paused":true} or {"thresholds":{"confidence":0.6}} or {"classes":["person","car"]}
+//	POST /snapshot  saves the next annotated frame, replies {"file":"snapshot_20240131_120000.png"}
+//	GET  /ws        WebSocket taking the same commands as POST /state, plus {"snapshot":true}
+//
+// POST /state replies with the new state. An empty class list reports all classes. WebSocket clients
+// get a {"type":"state",...} message on connect and after every change, {"type":"snapshot","file":...}
+// after their snapshots and {"type":"error","error":...} for rejected commands, e.g. with websocat:
+//
+//	echo '{"paused":true}' | websocat ws://localhost:8090/ws
+//
+// Changes are applied by the processing loop between frames, through the source returned by
+// Controller.Source, so that detectors need no locking
+package control
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+// DefaultSnapshotPattern is the file name of snapshots, %s is replaced with the time
+const DefaultSnapshotPattern = "snapshot_%s.png"
+
+// Time a snapshot request waits for a frame
+const snapshotTimeout = 5 * time.Second
+
+// Options holds the control endpoint parameters given by flags
+type Options struct {
+	Addr            string
+	SnapshotPattern string
+}
+
+// RegisterFlags adds -control and -control-snapshots flags to fs
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Addr, "control", "", `Address like ":8090" serving the runtime control API over HTTP and WebSocket; none if empty`)
+	fs.StringVar(&o.SnapshotPattern, "control-snapshots", DefaultSnapshotPattern, "File name of snapshots taken through the control API, %s is replaced with the time")
+}
+
+// Open starts serving the control API, closed by sd, whose context stops waiting in pause.
+// The controller is nil without an address
+func (o *Options) Open(sd *shutdown.Handler) (*Controller, error) {
+	if o.Addr == "" {
+		return nil, nil
+	}
+	ln, err := net.Listen("tcp", o.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot serve control API: %v", err)
+	}
+	c := NewController(sd.Context(), o.SnapshotPattern)
+	c.server = &http.Server{Handler: c}
+	go c.server.Serve(ln)
+	logging.Infof("Serving control API on %s", ln.Addr())
+	sd.OnClose("control", c.Close)
+	return c, nil
+}
+
+// Command changes the state of a controller. Fields left out are not changed
+type Command struct {
+	Paused     *bool              `json:"paused,omitempty"`
+	Thresholds map[string]float64 `json:"thresholds,omitempty"`
+	Classes    *[]string          `json:"classes,omitempty"`
+	Snapshot   bool               `json:"snapshot,omitempty"` // WebSocket only, POST /snapshot over HTTP
+}
+
+// State is the current state of a controller
+type State struct {
+	Paused     bool               `json:"paused"`
+	Thresholds map[string]float64 `json:"thresholds"`
+	Classes    []string           `json:"classes"`
+	Frames     int                `json:"frames"`
+}
+
+// Message is sent to WebSocket clients
+type Message struct {
+	Type string `json:"type"` // "state", "snapshot" or "error"
+	*State
+	File  string `json:"file,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// threshold is a value of the processing loop changed through the API
+type threshold struct {
+	get      func() float64
+	set      func(float64)
+	min, max float64
+	wanted   float64 // Value to apply, as requested by clients or changed by the loop
+	applied  float64 // Value seen by the loop after the last frame
+	dirty    bool    // Requested by clients and not applied yet
+}
+
+type snapshotResult struct {
+	file string
+	err  error
+}
+
+// Controller holds the state changed by clients. It is an http.Handler serving the API and
+// a videoio.FrameSink taking snapshots of written frames
+type Controller struct {
+	ctx        context.Context
+	pattern    string
+	mux        *http.ServeMux
+	server     *http.Server
+	mu         sync.Mutex
+	paused     bool
+	thresholds map[string]*threshold
+	classes    map[string]bool // Nil for all classes
+	frames     int
+	changed    chan struct{} // Closed and replaced on every change
+	snapshots  []chan snapshotResult
+	last       gocv.Mat // Copy of the last frame while paused, to take snapshots of
+	hasLast    bool
+	clients    map[*wsConn]bool
+}
+
+// NewController creates a controller saving snapshots with the file name pattern.
+// Pause is interrupted when ctx is cancelled
+func NewController(ctx context.Context, pattern string) *Controller {
+	c := &Controller{
+		ctx:        ctx,
+		pattern:    pattern,
+		mux:        http.NewServeMux(),
+		thresholds: make(map[string]*threshold),
+		changed:    make(chan struct{}),
+		clients:    make(map[*wsConn]bool),
+	}
+	c.mux.HandleFunc("/state", c.handleState)
+	c.mux.HandleFunc("/snapshot", c.handleSnapshot)
+	c.mux.HandleFunc("/ws", c.handleWebSocket)
+	return c
+}
+
+// AddThreshold lets clients set a value of the processing loop between min and max. The loop calls
+// get and set between frames, and changes made by the loop itself, e.g. with keys in the window,
+// are reported to clients
+func (c *Controller) AddThreshold(name string, min, max float64, get func() float64, set func(float64)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v := get()
+	c.thresholds[name] = &threshold{get: get, set: set, min: min, max: max, wanted: v, applied: v}
+}
+
+// AddThreshold32 is AddThreshold for a float32 variable of the processing loop
+func (c *Controller) AddThreshold32(name string, value *float32, min, max float64) {
+	c.AddThreshold(name, min, max, func() float64 { return float64(*value) }, func(v float64) { *value = float32(v) })
+}
+
+// Source wraps src to apply changes before reading each frame and to block while paused.
+// Next returns videoio.ErrStopped if the context is cancelled during pause
+func (c *Controller) Source(src videoio.FrameSource) videoio.FrameSource {
+	return &source{FrameSource: src, c: c}
+}
+
+type source struct {
+	videoio.FrameSource
+	c *Controller
+}
+
+func (s *source) Next() (gocv.Mat, error) {
+	if err := s.c.wait(); err != nil {
+		return gocv.Mat{}, err
+	}
+	return s.FrameSource.Next()
+}
+
+// Applies pending changes, then blocks while paused
+func (c *Controller) wait() error {
+	for {
+		c.mu.Lock()
+		if c.apply() {
+			c.broadcastLocked()
+		}
+		if !c.paused {
+			if c.hasLast {
+				c.last.Close()
+				c.hasLast = false
+			}
+			c.mu.Unlock()
+			return nil
+		}
+		changed := c.changed
+		c.mu.Unlock()
+
+		select {
+		case <-c.ctx.Done():
+			return videoio.ErrStopped
+		case <-changed:
+		}
+	}
+}
+
+// Sets requested thresholds and takes changes made by the loop. Returns whether the loop made any
+func (c *Controller) apply() bool {
+	local := false
+	for _, t := range c.thresholds {
+		if t.dirty {
+			t.set(t.wanted)
+			t.dirty = false
+		} else if v := t.get(); v != t.applied {
+			t.wanted, local = v, true
+		}
+		t.applied = t.get()
+	}
+	return local
+}
+
+// Paused reports whether processing is paused
+func (c *Controller) Paused() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+// Filter returns the detections of the classes chosen by clients, reusing dets
+func (c *Controller) Filter(dets []detection.Detection) []detection.Detection {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.classes == nil {
+		return dets
+	}
+	kept := dets[:0]
+	for _, d := range dets {
+		if c.classes[d.Label] {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+// State returns the current state
+func (c *Controller) State() State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stateLocked()
+}
+
+func (c *Controller) stateLocked() State {
+	st := State{Paused: c.paused, Thresholds: make(map[string]float64), Classes: []string{}, Frames: c.frames}
+	for name, t := range c.thresholds {
+		st.Thresholds[name] = t.wanted
+	}
+	for class := range c.classes {
+		st.Classes = append(st.Classes, class)
+	}
+	sort.Strings(st.Classes)
+	return st
+}
+
+// Execute validates and applies the command, except the snapshot, and returns the new state.
+// Nothing is changed if the command is invalid
+func (c *Controller) Execute(cmd Command) (State, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, v := range cmd.Thresholds {
+		t, ok := c.thresholds[name]
+		if !ok {
+			return c.stateLocked(), fmt.Errorf("Unknown threshold %q", name)
+		}
+		if v < t.min || v > t.max {
+			return c.stateLocked(), fmt.Errorf("Threshold %s should be from %g to %g", name, t.min, t.max)
+		}
+	}
+
+	for name, v := range cmd.Thresholds {
+		t := c.thresholds[name]
+		t.wanted, t.dirty = v, true
+	}
+	if cmd.Paused != nil && *cmd.Paused != c.paused {
+		c.paused = *cmd.Paused
+		logging.Infof("Processing %s through control API", map[bool]string{true: "paused", false: "resumed"}[c.paused])
+	}
+	if cmd.Classes != nil {
+		c.classes = nil
+		if len(*cmd.Classes) > 0 {
+			c.classes = make(map[string]bool)
+			for _, class := range *cmd.Classes {
+				c.classes[class] = true
+			}
+		}
+	}
+	close(c.changed)
+	c.changed = make(chan struct{})
+	c.broadcastLocked()
+	return c.stateLocked(), nil
+}
+
+// Snapshot saves the next written frame, or the last one while paused, and returns the file name
+func (c *Controller) Snapshot() (string, error) {
+	c.mu.Lock()
+	if c.hasLast {
+		defer c.mu.Unlock()
+		return c.save(c.last)
+	}
+	result := make(chan snapshotResult, 1)
+	c.snapshots = append(c.snapshots, result)
+	c.mu.Unlock()
+
+	select {
+	case r := <-result:
+		return r.file, r.err
+	case <-time.After(snapshotTimeout):
+		c.mu.Lock()
+		for i, ch := range c.snapshots {
+			if ch == result {
+				c.snapshots = append(c.snapshots[:i], c.snapshots[i+1:]...)
+				break
+			}
+		}
+		c.mu.Unlock()
+		return "", fmt.Errorf("No frame processed in %v", snapshotTimeout)
+	}
+}
+
+func (c *Controller) save(img gocv.Mat) (string, error) {
+	filename := fmt.Sprintf(c.pattern, time.Now().Format("20060102_150405"))
+	if !gocv.IMWrite(filename, img) {
+		return "", fmt.Errorf("Cannot save snapshot %s", filename)
+	}
+	logging.Infof("Saved snapshot %s", filename)
+	return filename, nil
+}
+
+// Write counts the frame, saves it for pending snapshots and keeps a copy of it when paused
+func (c *Controller) Write(img gocv.Mat) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.frames++
+	if len(c.snapshots) > 0 {
+		file, err := c.save(img)
+		for _, ch := range c.snapshots {
+			ch <- snapshotResult{file, err}
+		}
+		c.snapshots = nil
+	}
+	if c.paused {
+		if c.hasLast {
+			c.last.Close()
+		}
+		c.last, c.hasLast = img.Clone(), true
+	}
+	return nil
+}
+
+// Close stops the server and disconnects clients
+func (c *Controller) Close() error {
+	var err error
+	if c.server != nil {
+		err = c.server.Close()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for client := range c.clients {
+		client.Close()
+	}
+	if c.hasLast {
+		c.last.Close()
+		c.hasLast = false
+	}
+	return err
+}
+
+// ServeHTTP serves the API
+func (c *Controller) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mux.ServeHTTP(w, r)
+}
+
+func (c *Controller) handleState(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, c.State())
+	case http.MethodPost:
+		var cmd Command
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, wsMaxMessage)).Decode(&cmd); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("Invalid command: %v", err))
+			return
+		}
+		if cmd.Snapshot {
+			writeError(w, http.StatusBadRequest, errors.New("Snapshots are taken with POST /snapshot"))
+			return
+		}
+		st, err := c.Execute(cmd)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, st)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Method not allowed"))
+	}
+}
+
+func (c *Controller) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Method not allowed"))
+		return
+	}
+	file, err := c.Snapshot()
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		File string `json:"file"`
+	}{file})
+}
+
+func (c *Controller) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := wsUpgrade(w, r)
+	if err != nil {
+		logging.Debugf("Control WebSocket: %v", err)
+		return
+	}
+	defer conn.Close()
+	c.mu.Lock()
+	c.clients[conn] = true
+	st := c.stateLocked()
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.clients, conn)
+		c.mu.Unlock()
+	}()
+	if sendMessage(conn, Message{Type: "state", State: &st}) != nil {
+		return
+	}
+
+	for {
+		msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var cmd Command
+		if err := json.Unmarshal(msg, &cmd); err != nil {
+			sendMessage(conn, Message{Type: "error", Error: fmt.Sprintf("Invalid command: %v", err)})
+			continue
+		}
+		if cmd.Paused != nil || cmd.Thresholds != nil || cmd.Classes != nil {
+			if _, err := c.Execute(cmd); err != nil {
+				sendMessage(conn, Message{Type: "error", Error: err.Error()})
+				continue
+			}
+		}
+		if cmd.Snapshot {
+			// Frames keep being read from the connection, e.g. to resume, while waiting
+			go func() {
+				file, err := c.Snapshot()
+				if err != nil {
+					sendMessage(conn, Message{Type: "error", Error: err.Error()})
+					return
+				}
+				sendMessage(conn, Message{Type: "snapshot", File: file})
+			}()
+		}
+	}
+}
+
+// Sends the state to all WebSocket clients
+func (c *Controller) broadcastLocked() {
+	if len(c.clients) == 0 {
+		return
+	}
+	st := c.stateLocked()
+	for client := range c.clients {
+		sendMessage(client, Message{Type: "state", State: &st})
+	}
+}
+
+func sendMessage(conn *wsConn, m Message) error {
+	buf, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return conn.WriteText(buf)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
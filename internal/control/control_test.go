@@ -0,0 +1,249 @@
+package control
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+// frameSource returns empty frames and counts them
+type frameSource struct {
+	frames int
+}
+
+func (s *frameSource) Next() (gocv.Mat, error) {
+	s.frames++
+	return gocv.NewMat(), nil
+}
+
+func (s *frameSource) Close() error {
+	return nil
+}
+
+func postState(t *testing.T, url, body string) (int, State) {
+	t.Helper()
+	resp, err := http.Post(url+"/state", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var st State
+	json.NewDecoder(resp.Body).Decode(&st)
+	return resp.StatusCode, st
+}
+
+func TestStateAPI(t *testing.T) {
+	c := NewController(context.Background(), DefaultSnapshotPattern)
+	conf := float32(0.5)
+	c.AddThreshold32("confidence", &conf, 0.05, 0.95)
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	status, st := postState(t, server.URL, `{"thresholds":{"confidence":0.7},"classes":["person","car"]}`)
+	if status != http.StatusOK || st.Thresholds["confidence"] != 0.7 || strings.Join(st.Classes, ",") != "car,person" {
+		t.Errorf("Unexpected state %d %+v", status, st)
+	}
+	if conf != 0.5 {
+		t.Errorf("Threshold changed before the next frame: %v", conf)
+	}
+	for _, body := range []string{`{"thresholds":{"confidence":2}}`, `{"thresholds":{"iou":0.5}}`, `{"paused":`} {
+		if status, _ := postState(t, server.URL, body); status != http.StatusBadRequest {
+			t.Errorf("POST %s = %d, want %d", body, status, http.StatusBadRequest)
+		}
+	}
+
+	src := c.Source(&frameSource{})
+	img, err := src.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	img.Close()
+	if conf != 0.7 {
+		t.Errorf("Threshold not applied on the next frame: %v", conf)
+	}
+
+	// Changes of the loop are reported
+	conf = 0.3
+	img, _ = src.Next()
+	img.Close()
+	resp, err := http.Get(server.URL + "/state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	json.NewDecoder(resp.Body).Decode(&st)
+	if st.Thresholds["confidence"] != float64(float32(0.3)) {
+		t.Errorf("Expected the threshold set by the loop, got %+v", st)
+	}
+
+	dets := []detection.Detection{{Label: "person"}, {Label: "dog"}, {Label: "car"}}
+	if got := c.Filter(dets); len(got) != 2 || got[0].Label != "person" || got[1].Label != "car" {
+		t.Errorf("Unexpected filtered detections %v", got)
+	}
+	postState(t, server.URL, `{"classes":[]}`)
+	if got := c.Filter([]detection.Detection{{Label: "dog"}}); len(got) != 1 {
+		t.Errorf("Expected all classes to be kept, got %v", got)
+	}
+}
+
+func TestPause(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := NewController(ctx, DefaultSnapshotPattern)
+	fs := &frameSource{}
+	src := c.Source(fs)
+
+	paused := true
+	c.Execute(Command{Paused: &paused})
+	errc := make(chan error, 1)
+	go func() {
+		img, err := src.Next()
+		img.Close()
+		errc <- err
+	}()
+	select {
+	case err := <-errc:
+		t.Fatalf("Frame read while paused: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	paused = false
+	c.Execute(Command{Paused: &paused})
+	if err := <-errc; err != nil || fs.frames != 1 {
+		t.Errorf("Expected a frame after resuming, got %v after %d frames", err, fs.frames)
+	}
+
+	paused = true
+	c.Execute(Command{Paused: &paused})
+	go func() {
+		_, err := src.Next()
+		errc <- err
+	}()
+	cancel()
+	if err := <-errc; err != videoio.ErrStopped {
+		t.Errorf("Expected ErrStopped on cancel, got %v", err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	c := NewController(context.Background(), filepath.Join(dir, "snap_%s.png"))
+	img := gocv.NewMatWithSize(8, 8, gocv.MatTypeCV8UC3)
+	defer img.Close()
+
+	files := make(chan string, 1)
+	go func() {
+		file, err := c.Snapshot()
+		if err != nil {
+			t.Error(err)
+		}
+		files <- file
+	}()
+	for len(files) == 0 {
+		c.Write(img)
+		time.Sleep(10 * time.Millisecond)
+	}
+	if file := <-files; !strings.HasPrefix(file, filepath.Join(dir, "snap_")) {
+		t.Errorf("Unexpected snapshot %q", file)
+	} else if _, err := os.Stat(file); err != nil {
+		t.Error(err)
+	}
+}
+
+// wsDial connects to the WebSocket of the server
+func wsDial(t *testing.T, url string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Value from RFC 6455
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Unexpected handshake %s %v", resp.Status, resp.Header)
+	}
+	return conn, r
+}
+
+// wsSend writes a masked text frame as clients do
+func wsSend(conn net.Conn, msg string) {
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x80 | wsText, 0x80 | byte(len(msg))}
+	frame = append(frame, mask...)
+	for i := 0; i < len(msg); i++ {
+		frame = append(frame, msg[i]^mask[i%4])
+	}
+	conn.Write(frame)
+}
+
+// wsReceive reads an unmasked server message
+func wsReceive(t *testing.T, r *bufio.Reader) Message {
+	t.Helper()
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(r, head); err != nil {
+		t.Fatal(err)
+	}
+	n := int(head[1] & 0x7f)
+	if n == 126 {
+		ext := make([]byte, 2)
+		io.ReadFull(r, ext)
+		n = int(binary.BigEndian.Uint16(ext))
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatal(err)
+	}
+	var m Message
+	if err := json.Unmarshal(payload, &m); err != nil {
+		t.Fatalf("Invalid message %q: %v", payload, err)
+	}
+	return m
+}
+
+func TestWebSocket(t *testing.T) {
+	c := NewController(context.Background(), DefaultSnapshotPattern)
+	nms := 0.4
+	c.AddThreshold("nms", 0.1, 0.9, func() float64 { return nms }, func(v float64) { nms = v })
+	server := httptest.NewServer(c)
+	defer server.Close()
+	conn, r := wsDial(t, server.URL)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if m := wsReceive(t, r); m.Type != "state" || m.Paused || m.Thresholds["nms"] != 0.4 {
+		t.Errorf("Unexpected initial message %+v", m)
+	}
+	wsSend(conn, `{"paused":true,"thresholds":{"nms":0.5}}`)
+	if m := wsReceive(t, r); m.Type != "state" || !m.Paused || m.Thresholds["nms"] != 0.5 {
+		t.Errorf("Unexpected state %+v", m)
+	}
+	wsSend(conn, `{"classes":"person"}`)
+	if m := wsReceive(t, r); m.Type != "error" || m.Error == "" {
+		t.Errorf("Expected an error, got %+v", m)
+	}
+
+	// Changes over HTTP are pushed too
+	postState(t, server.URL, `{"paused":false}`)
+	if m := wsReceive(t, r); m.Type != "state" || m.Paused {
+		t.Errorf("Unexpected state %+v", m)
+	}
+}
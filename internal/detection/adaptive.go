@@ -0,0 +1,147 @@
+package detection
+
+import (
+	"errors"
+	"image"
+	"math"
+	"sync"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// Default and tuning parameters of adaptive scaling
+const (
+	DefaultAdaptiveMinScale = 0.5
+	adaptiveStep            = 0.125 // Change of the scale in one adjustment
+	adaptiveSmoothing       = 0.2   // Weight of the latest frame in the average processing time
+	adaptiveHeadroom        = 0.8   // Fraction of the budget the estimated time at a larger scale must fit in
+	adaptiveSettle          = 10    // Frames measured after an adjustment before the next one
+)
+
+// InputScaler is a detector which can reduce the size of its network input itself, which costs less
+// than resizing frames and keeps its results in frame coordinates
+type InputScaler interface {
+	// DetectScaled is Detect with the network input scaled by s
+	DetectScaled(img gocv.Mat, s float64) ([]Detection, error)
+}
+
+// Adaptive wraps a detector to keep up with real time under CPU pressure: when the average time of
+// a detection exceeds the budget of a frame, the inference input is downscaled in steps down to a
+// minimum scale, and it is restored when the time estimated at the larger scale fits in the budget
+// again. Detectors implementing InputScaler scale their input, frames are resized for the others and
+// the results scaled back to frame coordinates. Detect is safe for concurrent use if the wrapped
+// detector's is
+type Adaptive struct {
+	Detector Detector
+	Budget   time.Duration // Processing time available for a frame
+	MinScale float64
+
+	mu     sync.Mutex
+	scale  float64
+	avg    float64 // Average processing time at the current scale, in seconds
+	frames int     // Frames measured since the last adjustment
+}
+
+// NewAdaptive wraps the detector to process frames within the budget, scaling the input down to minScale
+func NewAdaptive(d Detector, budget time.Duration, minScale float64) (*Adaptive, error) {
+	if budget <= 0 {
+		return nil, errors.New("Adaptive scaling needs a positive frame budget")
+	}
+	if minScale <= 0 || minScale > 1 {
+		return nil, errors.New("Minimum scale must be above 0 and at most 1")
+	}
+	return &Adaptive{Detector: d, Budget: budget, MinScale: minScale, scale: 1}, nil
+}
+
+// Scale returns the current scale of the inference input, 1 for full resolution
+func (a *Adaptive) Scale() float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.scale
+}
+
+// Detect runs the detector at the current scale and adjusts the scale to the time it took
+func (a *Adaptive) Detect(img gocv.Mat) ([]Detection, error) {
+	scale := a.Scale()
+	start := time.Now()
+	var dets []Detection
+	var err error
+	if is, ok := a.Detector.(InputScaler); ok {
+		dets, err = is.DetectScaled(img, scale)
+	} else {
+		dets, err = a.detectResized(img, scale)
+	}
+	a.update(scale, time.Since(start))
+	return dets, err
+}
+
+// Runs the detector on the frame resized by the scale and scales the results back
+func (a *Adaptive) detectResized(img gocv.Mat, scale float64) ([]Detection, error) {
+	if scale == 1 {
+		return a.Detector.Detect(img)
+	}
+	small := gocv.NewMat()
+	defer small.Close()
+	size := image.Pt(int(math.Round(float64(img.Cols())*scale)), int(math.Round(float64(img.Rows())*scale)))
+	gocv.Resize(img, &small, size, 0, 0, gocv.InterpolationArea)
+	dets, err := a.Detector.Detect(small)
+	bounds := image.Rect(0, 0, img.Cols(), img.Rows())
+	for i := range dets {
+		dets[i] = scaleDetection(dets[i], 1/scale, bounds)
+	}
+	return dets, err
+}
+
+// Records the processing time of a frame at the scale and adjusts the scale after enough frames.
+// Times of frames started at another scale are ignored
+func (a *Adaptive) update(scale float64, d time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if scale != a.scale {
+		return
+	}
+	if a.frames == 0 {
+		a.avg = d.Seconds()
+	} else {
+		a.avg += adaptiveSmoothing * (d.Seconds() - a.avg)
+	}
+	a.frames++
+	if a.frames < adaptiveSettle {
+		return
+	}
+	budget := a.Budget.Seconds()
+	next := a.scale
+	if a.avg > budget {
+		next = math.Max(a.scale-adaptiveStep, a.MinScale)
+	} else if a.scale < 1 {
+		// Processing time grows with the number of pixels
+		up := math.Min(a.scale+adaptiveStep, 1)
+		if a.avg*(up*up)/(a.scale*a.scale) < adaptiveHeadroom*budget {
+			next = up
+		}
+	}
+	if next != a.scale {
+		a.scale, a.frames = next, 0
+	}
+}
+
+// Returns the detection with coordinates multiplied by f, limited to the bounds
+func scaleDetection(d Detection, f float64, bounds image.Rectangle) Detection {
+	pt := func(p image.Point) image.Point {
+		return image.Pt(int(math.Round(float64(p.X)*f)), int(math.Round(float64(p.Y)*f)))
+	}
+	pts := func(ps []image.Point) []image.Point {
+		if ps == nil {
+			return nil
+		}
+		res := make([]image.Point, len(ps))
+		for i, p := range ps {
+			res[i] = pt(p)
+		}
+		return res
+	}
+	d.BBox = image.Rectangle{Min: pt(d.BBox.Min), Max: pt(d.BBox.Max)}.Intersect(bounds)
+	d.Quad, d.Points, d.Mask = pts(d.Quad), pts(d.Points), pts(d.Mask)
+	return d
+}
@@ -0,0 +1,91 @@
+package detection
+
+import (
+	"image"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveScale(t *testing.T) {
+	a, err := NewAdaptive(workerDetector{}, 40*time.Millisecond, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Frames taking time proportional to the number of pixels
+	run := func(full time.Duration, frames int) {
+		for i := 0; i < frames; i++ {
+			s := a.Scale()
+			a.update(s, time.Duration(float64(full)*s*s))
+		}
+	}
+
+	// Falling behind steps down to the minimum scale
+	run(200*time.Millisecond, adaptiveSettle)
+	if s := a.Scale(); s != 1-adaptiveStep {
+		t.Fatalf("Scale %v after one adjustment", s)
+	}
+	run(200*time.Millisecond, 10*adaptiveSettle)
+	if s := a.Scale(); s != 0.5 {
+		t.Fatalf("Scale %v when behind, want the minimum", s)
+	}
+
+	// Times of frames started at another scale are ignored
+	a.update(1, time.Second)
+	if s := a.Scale(); s != 0.5 {
+		t.Fatalf("Scale %v after a stale frame", s)
+	}
+
+	// The scale is kept while a larger one would not fit in the budget
+	run(100*time.Millisecond, 10*adaptiveSettle)
+	if s := a.Scale(); s != 0.5 {
+		t.Fatalf("Scale %v without headroom", s)
+	}
+
+	// Full resolution is restored when there is headroom
+	run(20*time.Millisecond, 10*adaptiveSettle)
+	if s := a.Scale(); s != 1 {
+		t.Fatalf("Scale %v with headroom, want 1", s)
+	}
+
+	if _, err := NewAdaptive(workerDetector{}, 0, 0.5); err == nil {
+		t.Error("Expected an error for no budget")
+	}
+	if _, err := NewAdaptive(workerDetector{}, time.Second, 1.5); err == nil {
+		t.Error("Expected an error for a minimum scale above 1")
+	}
+}
+
+func TestScaleDetection(t *testing.T) {
+	d := Detection{
+		Label:  "car",
+		BBox:   image.Rect(10, 20, 60, 50),
+		Points: []image.Point{{15, 25}},
+		Mask:   []image.Point{{10, 20}, {60, 20}, {60, 50}},
+	}
+	got := scaleDetection(d, 2, image.Rect(0, 0, 100, 90))
+	want := Detection{
+		Label:  "car",
+		BBox:   image.Rect(20, 40, 100, 90),
+		Points: []image.Point{{30, 50}},
+		Mask:   []image.Point{{20, 40}, {120, 40}, {120, 100}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Scaled to %+v, want %+v", got, want)
+	}
+	if d.Points[0] != image.Pt(15, 25) {
+		t.Error("Points of the original detection changed")
+	}
+}
+
+func TestYoloBlobSize(t *testing.T) {
+	yd := &YoloDetector{BlobSize: 416}
+	for _, tt := range []struct {
+		scale float64
+		want  int
+	}{{0, 416}, {1, 416}, {0.75, 320}, {0.5, 224}, {0.01, 32}} {
+		if got := yd.blobSize(tt.scale); got != tt.want {
+			t.Errorf("Blob size %d at scale %v, want %d", got, tt.scale, tt.want)
+		}
+	}
+}
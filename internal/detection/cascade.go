@@ -0,0 +1,94 @@
+package detection
+
+import (
+	"image"
+
+	"gocv.io/x/gocv"
+)
+
+// Default parameters of CascadeDetector
+const (
+	DefaultCascadeScale        = 1.1
+	DefaultCascadeMinNeighbors = 3
+)
+
+// CascadeDetector finds objects with a Haar or LBP cascade classifier and implements Detector.
+// Cascades give no confidence, all detections have confidence 1
+type CascadeDetector struct {
+	Classifier   *gocv.CascadeClassifier
+	Label        string
+	ScaleFactor  float64     // Scale step of the image pyramid, greater than 1
+	MinNeighbors int         // Overlapping candidates needed to keep a detection, higher gives fewer false positives
+	MinSize      image.Point // Smallest object size, zero for no limit
+}
+
+// NewCascadeDetector creates a detector with default parameters
+func NewCascadeDetector(classifier *gocv.CascadeClassifier, label string) *CascadeDetector {
+	return &CascadeDetector{
+		Classifier:   classifier,
+		Label:        label,
+		ScaleFactor:  DefaultCascadeScale,
+		MinNeighbors: DefaultCascadeMinNeighbors,
+	}
+}
+
+// Detect finds objects on a BGR or grayscale image
+func (cd *CascadeDetector) Detect(img gocv.Mat) ([]Detection, error) {
+	gray := gocv.NewMat()
+	defer gray.Close()
+	if img.Channels() == 1 {
+		img.CopyTo(&gray)
+	} else {
+		gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+	}
+	gocv.EqualizeHist(gray, &gray)
+	return cd.detectGray(gray, image.Point{}), nil
+}
+
+// DetectNested finds objects inside each region of the image, e.g. eyes inside faces. Each region
+// is searched in the part given by relative bounds: image.Rect(0, 0, 100, 60) is the upper 60%.
+// Detections are in image coordinates and grouped by region
+func (cd *CascadeDetector) DetectNested(img gocv.Mat, regions []Detection, bounds image.Rectangle) [][]Detection {
+	gray := gocv.NewMat()
+	defer gray.Close()
+	if img.Channels() == 1 {
+		img.CopyTo(&gray)
+	} else {
+		gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+	}
+	frame := image.Rect(0, 0, img.Cols(), img.Rows())
+
+	nested := make([][]Detection, len(regions))
+	for i, r := range regions {
+		area := SubRegion(r.BBox, bounds).Intersect(frame)
+		if area.Empty() {
+			continue
+		}
+		roi := gray.Region(area)
+		part := roi.Clone()
+		roi.Close()
+		gocv.EqualizeHist(part, &part)
+		nested[i] = cd.detectGray(part, area.Min)
+		part.Close()
+	}
+	return nested
+}
+
+// Runs the classifier on an equalized grayscale image and shifts detections by offset
+func (cd *CascadeDetector) detectGray(gray gocv.Mat, offset image.Point) []Detection {
+	rects := cd.Classifier.DetectMultiScaleWithParams(gray, cd.ScaleFactor, cd.MinNeighbors, 0, cd.MinSize, image.Point{})
+	dets := make([]Detection, len(rects))
+	for i, r := range rects {
+		dets[i] = Detection{Label: cd.Label, Confidence: 1, BBox: r.Add(offset)}
+	}
+	return dets
+}
+
+// SubRegion returns the part of rect given by bounds relative to its size in percent
+func SubRegion(rect image.Rectangle, bounds image.Rectangle) image.Rectangle {
+	w, h := rect.Dx(), rect.Dy()
+	return image.Rect(
+		rect.Min.X+w*bounds.Min.X/100, rect.Min.Y+h*bounds.Min.Y/100,
+		rect.Min.X+w*bounds.Max.X/100, rect.Min.Y+h*bounds.Max.Y/100,
+	)
+}
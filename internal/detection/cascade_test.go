@@ -0,0 +1,22 @@
+package detection
+
+import (
+	"image"
+	"testing"
+)
+
+func TestSubRegion(t *testing.T) {
+	face := image.Rect(100, 50, 300, 250)
+	tests := []struct {
+		bounds, want image.Rectangle
+	}{
+		{image.Rect(0, 0, 100, 100), face},
+		{image.Rect(0, 0, 100, 60), image.Rect(100, 50, 300, 170)},
+		{image.Rect(50, 50, 100, 100), image.Rect(200, 150, 300, 250)},
+	}
+	for _, tt := range tests {
+		if got := SubRegion(face, tt.bounds); got != tt.want {
+			t.Errorf("SubRegion(%v, %v) = %v, want %v", face, tt.bounds, got, tt.want)
+		}
+	}
+}
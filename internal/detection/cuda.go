@@ -0,0 +1,20 @@
+//go:build cuda
+// +build cuda
+
+package detection
+
+import (
+	"fmt"
+
+	"gocv.io/x/gocv/cuda"
+)
+
+// SetCUDADevice makes the device current for the calling OS thread, so that networks set up
+// on the thread with the CUDA backend run on it
+func SetCUDADevice(id int) error {
+	if n := cuda.GetCudaEnabledDeviceCount(); id < 0 || id >= n {
+		return fmt.Errorf("CUDA device %d not found, %d devices available", id, n)
+	}
+	cuda.SetDevice(id)
+	return nil
+}
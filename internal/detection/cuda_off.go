@@ -0,0 +1,11 @@
+//go:build !cuda
+// +build !cuda
+
+package detection
+
+import "errors"
+
+// SetCUDADevice cannot select devices without the cuda tag
+func SetCUDADevice(id int) error {
+	return errors.New("Built without CUDA, build with -tags cuda")
+}
@@ -0,0 +1,28 @@
+package detection
+
+import (
+	"fmt"
+	"image"
+
+	"gocv.io/x/gocv"
+)
+
+// Detection is an object found on a frame, independent of the detector which found it
+type Detection struct {
+	Label      string          `json:"label"`
+	Confidence float32         `json:"confidence"`       // From 0 to 1
+	BBox       image.Rectangle `json:"bbox"`             // Bounding box in frame coordinates
+	Quad       []image.Point   `json:"quad,omitempty"`   // Outline of a pattern seen in perspective, nil for detectors giving boxes only
+	Points     []image.Point   `json:"points,omitempty"` // Key points of the object, e.g. face landmarks
+	Mask       []image.Point   `json:"mask,omitempty"`   // Outline of the object mask, for instance segmentation
+}
+
+func (d Detection) String() string {
+	return fmt.Sprintf("%s, Confidence: %.2f%%, Bbox: %v", d.Label, d.Confidence*100, d.BBox)
+}
+
+// Detector finds objects on a frame. Drawing, tracking, counting and output stages work with
+// the results of any detector
+type Detector interface {
+	Detect(img gocv.Mat) ([]Detection, error)
+}
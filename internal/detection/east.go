@@ -0,0 +1,161 @@
+package detection
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"sort"
+
+	"github.com/marchevska/gocv-examples/internal/models"
+	"github.com/marchevska/gocv-examples/internal/nms"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"gocv.io/x/gocv"
+)
+
+// Output layers of the EAST text detector: score map and geometry map
+var eastOutputLayers = []string{"feature_fusion/Conv_7/Sigmoid", "feature_fusion/concat_3"}
+
+// Default EAST parameters
+const (
+	DefaultEASTSize    = 320 // Input width and height, multiples of 32
+	DefaultEASTConfThr = 0.5
+	DefaultEASTOvrThr  = 0.4
+	eastCellSize       = 4 // Maps are 4 times smaller than the input
+)
+
+// EASTDetector finds text in natural images with the EAST network and implements Detector.
+// Detections are labelled "text", Quad holds the corners of the rotated text box
+// (top-left, top-right, bottom-right, bottom-left) and BBox its bounding box
+type EASTDetector struct {
+	Net     *gocv.Net
+	Size    image.Point // Input size of the network, multiples of 32
+	ConfThr float32
+	OvrThr  float64 // Overlapping threshold for NMS of bounding boxes
+}
+
+// NewEASTDetector creates a detector with default parameters
+func NewEASTDetector(net *gocv.Net) *EASTDetector {
+	return &EASTDetector{Net: net, Size: image.Pt(DefaultEASTSize, DefaultEASTSize),
+		ConfThr: DefaultEASTConfThr, OvrThr: DefaultEASTOvrThr}
+}
+
+// LoadEAST resolves the EAST model file and creates a detector with the input size. The network is
+// closed by sd
+func LoadEAST(sd *shutdown.Handler, path string, size int) (*EASTDetector, error) {
+	if size <= 0 || size%32 != 0 {
+		return nil, errors.New("EAST input size should be a positive multiple of 32")
+	}
+	resolved, err := models.Resolve(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading EAST model, see 'gocv-examples text -h': %v", err)
+	}
+	net := gocv.ReadNet(resolved, "")
+	if net.Empty() {
+		return nil, errors.New("Error loading EAST model")
+	}
+	sd.OnClose("EAST model", net.Close)
+	ed := NewEASTDetector(&net)
+	ed.Size = image.Pt(size, size)
+	return ed, nil
+}
+
+// Detect runs the network and returns text boxes, most confident first
+func (ed *EASTDetector) Detect(img gocv.Mat) ([]Detection, error) {
+	if ed.Size.X%32 != 0 || ed.Size.Y%32 != 0 {
+		return nil, errors.New("EAST input size should be a multiple of 32")
+	}
+	blob := gocv.BlobFromImage(img, 1.0, ed.Size, gocv.NewScalar(123.68, 116.78, 103.94, 0), true, false)
+	defer blob.Close()
+	ed.Net.SetInput(blob, "")
+	outs := ed.Net.ForwardLayers(eastOutputLayers)
+	defer func() {
+		for _, out := range outs {
+			out.Close()
+		}
+	}()
+	scores, err := outs[0].DataPtrFloat32()
+	if err != nil {
+		return nil, err
+	}
+	geometry, err := outs[1].DataPtrFloat32()
+	if err != nil {
+		return nil, err
+	}
+
+	dets := DecodeEAST(scores, geometry, ed.Size.Y/eastCellSize, ed.Size.X/eastCellSize, ed.ConfThr)
+	sx := float64(img.Cols()) / float64(ed.Size.X)
+	sy := float64(img.Rows()) / float64(ed.Size.Y)
+	bounds := image.Rect(0, 0, img.Cols(), img.Rows())
+	boxes := make([]nms.Box, len(dets))
+	for i := range dets {
+		for j, p := range dets[i].Quad {
+			dets[i].Quad[j] = image.Pt(int(float64(p.X)*sx), int(float64(p.Y)*sy))
+		}
+		dets[i].BBox = pointBounds(dets[i].Quad).Intersect(bounds)
+		boxes[i] = nms.Box{Rect: dets[i].BBox, Score: dets[i].Confidence}
+	}
+	var kept []Detection
+	for _, i := range nms.Hard(boxes, ed.OvrThr) {
+		kept = append(kept, dets[i])
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].Confidence > kept[j].Confidence })
+	return kept, nil
+}
+
+// DecodeEAST converts the EAST score map (rows x cols) and geometry map (5 x rows x cols: distances
+// to the top, right, bottom and left edges of the box and its angle) to text boxes with confidence
+// above confThr. Coordinates are in pixels of the network input
+func DecodeEAST(scores, geometry []float32, rows, cols int, confThr float32) []Detection {
+	plane := rows * cols
+	var dets []Detection
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			i := y*cols + x
+			score := scores[i]
+			if score < confThr {
+				continue
+			}
+			top, right, bottom, left := float64(geometry[i]), float64(geometry[plane+i]),
+				float64(geometry[2*plane+i]), float64(geometry[3*plane+i])
+			angle := float64(geometry[4*plane+i])
+			cos, sin := math.Cos(angle), math.Sin(angle)
+			h, w := top+bottom, right+left
+
+			// Bottom-right corner, from the cell moved to the right and bottom edges
+			brX := float64(x*eastCellSize) + cos*right + sin*bottom
+			brY := float64(y*eastCellSize) - sin*right + cos*bottom
+			br := [2]float64{brX, brY}
+			bl := [2]float64{brX - cos*w, brY + sin*w}
+			tr := [2]float64{brX - sin*h, brY - cos*h}
+			tl := [2]float64{bl[0] - sin*h, bl[1] - cos*h}
+
+			quad := make([]image.Point, 4)
+			for j, c := range [][2]float64{tl, tr, br, bl} {
+				quad[j] = image.Pt(int(math.Round(c[0])), int(math.Round(c[1])))
+			}
+			dets = append(dets, Detection{Label: "text", Confidence: score, Quad: quad, BBox: pointBounds(quad)})
+		}
+	}
+	return dets
+}
+
+// Returns the bounding box of the points
+func pointBounds(pts []image.Point) image.Rectangle {
+	r := image.Rectangle{Min: pts[0], Max: pts[0]}
+	for _, p := range pts[1:] {
+		if p.X < r.Min.X {
+			r.Min.X = p.X
+		}
+		if p.Y < r.Min.Y {
+			r.Min.Y = p.Y
+		}
+		if p.X > r.Max.X {
+			r.Max.X = p.X
+		}
+		if p.Y > r.Max.Y {
+			r.Max.Y = p.Y
+		}
+	}
+	return r
+}
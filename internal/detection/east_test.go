@@ -0,0 +1,43 @@
+package detection
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestDecodeEAST(t *testing.T) {
+	// 2x2 maps, text found at the cell x = 1, y = 1
+	scores := []float32{0.1, 0.2, 0.3, 0.9}
+	geometry := make([]float32, 5*4)
+	set := func(ch int, v float32) { geometry[ch*4+3] = v }
+	set(0, 2)  // Top
+	set(1, 10) // Right
+	set(2, 3)  // Bottom
+	set(3, 6)  // Left
+
+	dets := DecodeEAST(scores, geometry, 2, 2, 0.5)
+	if len(dets) != 1 {
+		t.Fatalf("Expected 1 detection, got %v", dets)
+	}
+	// Cell at (4, 4), the box spans 6 px left, 10 px right, 2 px up and 3 px down
+	want := []image.Point{{-2, 2}, {14, 2}, {14, 7}, {-2, 7}}
+	for i, p := range dets[0].Quad {
+		if p != want[i] {
+			t.Fatalf("Expected quad %v, got %v", want, dets[0].Quad)
+		}
+	}
+	if dets[0].BBox != image.Rect(-2, 2, 14, 7) || dets[0].Confidence != 0.9 {
+		t.Errorf("Unexpected detection %v", dets[0])
+	}
+
+	// Rotated by 90 degrees counterclockwise the width goes up
+	set(4, math.Pi/2)
+	dets = DecodeEAST(scores, geometry, 2, 2, 0.5)
+	want = []image.Point{{2, 10}, {2, -6}, {7, -6}, {7, 10}}
+	for i, p := range dets[0].Quad {
+		if p != want[i] {
+			t.Fatalf("Expected rotated quad %v, got %v", want, dets[0].Quad)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package detection
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// FrameDetections holds detections on one frame of the input. Detections are exported as JSON lines,
+// one FrameDetections object per line, e.g.
+//
+//	{"frame":0,"detections":[{"label":"person","confidence":0.93,"bbox":{"Min":{"X":12,"Y":40},"Max":{"X":96,"Y":230}}}]}
+//
+// Frames are numbered from 0 in the order they are read from the input
+type FrameDetections struct {
+	Frame      int         `json:"frame"`
+	Detections []Detection `json:"detections"`
+}
+
+// JSONWriter exports detections to a file
+type JSONWriter struct {
+	file *os.File
+	buf  *bufio.Writer
+	enc  *json.Encoder
+}
+
+// NewJSONWriter creates the file, truncating an existing one
+func NewJSONWriter(path string) (*JSONWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	buf := bufio.NewWriter(file)
+	return &JSONWriter{file: file, buf: buf, enc: json.NewEncoder(buf)}, nil
+}
+
+// Write appends detections of a frame
+func (jw *JSONWriter) Write(fd FrameDetections) error {
+	if fd.Detections == nil {
+		fd.Detections = []Detection{}
+	}
+	return jw.enc.Encode(fd)
+}
+
+// Close flushes and closes the file
+func (jw *JSONWriter) Close() error {
+	if err := jw.buf.Flush(); err != nil {
+		jw.file.Close()
+		return err
+	}
+	return jw.file.Close()
+}
+
+// ReadJSON reads detections exported by JSONWriter
+func ReadJSON(path string) ([]FrameDetections, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var frames []FrameDetections
+	dec := json.NewDecoder(file)
+	for dec.More() {
+		var fd FrameDetections
+		if err := dec.Decode(&fd); err != nil {
+			return nil, fmt.Errorf("Cannot parse %s: %v", path, err)
+		}
+		frames = append(frames, fd)
+	}
+	return frames, nil
+}
@@ -0,0 +1,38 @@
+package detection
+
+import (
+	"image"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "detections.jsonl")
+	jw, err := NewJSONWriter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FrameDetections{
+		{Frame: 0, Detections: []Detection{{Label: "person", Confidence: 0.9, BBox: image.Rect(1, 2, 30, 40)}}},
+		{Frame: 1, Detections: []Detection{}},
+		{Frame: 2, Detections: []Detection{{Label: "card", Confidence: 0.5, BBox: image.Rect(0, 0, 4, 4),
+			Quad: []image.Point{{0, 0}, {4, 0}, {4, 4}, {0, 4}}}}},
+	}
+	for _, fd := range want {
+		if err := jw.Write(fd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := jw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadJSON(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
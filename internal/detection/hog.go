@@ -0,0 +1,61 @@
+package detection
+
+import (
+	"image"
+
+	"gocv.io/x/gocv"
+)
+
+// Default parameters of HOGDetector, as in the OpenCV people detection sample
+const (
+	DefaultHOGScale    = 1.05
+	DefaultHOGFinalThr = 2
+)
+
+// Default window stride and padding of HOGDetector
+var (
+	DefaultHOGStride  = image.Pt(8, 8)
+	DefaultHOGPadding = image.Pt(8, 8)
+)
+
+// HOGDetector finds people with a HOG descriptor and a linear SVM and implements Detector.
+// Detections give no confidence, all of them have confidence 1
+type HOGDetector struct {
+	Descriptor gocv.HOGDescriptor
+	HitThr     float64     // Distance from the SVM plane to keep a window, higher gives fewer false positives
+	WinStride  image.Point // Step of the detection window, larger is faster but misses more people
+	Padding    image.Point
+	Scale      float64 // Scale step of the image pyramid, greater than 1
+	FinalThr   float64 // Overlapping windows needed to keep a detection
+}
+
+// NewHOGPeopleDetector creates a detector with the default people SVM of OpenCV, trained on
+// 64x128 windows, and default parameters. It should be closed after use
+func NewHOGPeopleDetector() *HOGDetector {
+	hd := &HOGDetector{
+		Descriptor: gocv.NewHOGDescriptor(),
+		WinStride:  DefaultHOGStride,
+		Padding:    DefaultHOGPadding,
+		Scale:      DefaultHOGScale,
+		FinalThr:   DefaultHOGFinalThr,
+	}
+	svm := gocv.HOGDefaultPeopleDetector()
+	defer svm.Close()
+	hd.Descriptor.SetSVMDetector(svm)
+	return hd
+}
+
+// Detect finds people on the image
+func (hd *HOGDetector) Detect(img gocv.Mat) ([]Detection, error) {
+	rects := hd.Descriptor.DetectMultiScaleWithParams(img, hd.HitThr, hd.WinStride, hd.Padding, hd.Scale, hd.FinalThr, false)
+	dets := make([]Detection, len(rects))
+	for i, r := range rects {
+		dets[i] = Detection{Label: "person", Confidence: 1, BBox: r}
+	}
+	return dets, nil
+}
+
+// Close releases the descriptor
+func (hd *HOGDetector) Close() error {
+	return hd.Descriptor.Close()
+}
@@ -0,0 +1,51 @@
+package detection
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ReadClassLabels reads class labels, one per line, like coco.names. The line number is the class ID
+func ReadClassLabels(filename string) (cl []string, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		cl = append(cl, scanner.Text())
+	}
+	return cl, scanner.Err()
+}
+
+// ReadIndexedLabels reads class labels with their IDs, one "ID label" pair per line, like the label
+// files of TensorFlow Lite detection models. Lines without an ID get the next one, and IDs that are
+// skipped have empty labels
+func ReadIndexedLabels(filename string) (cl []string, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		id, label := len(cl), line
+		if fields := strings.SplitN(line, " ", 2); len(fields) == 2 {
+			if n, err := strconv.Atoi(fields[0]); err == nil && n >= len(cl) {
+				id, label = n, strings.TrimSpace(fields[1])
+			}
+		}
+		for len(cl) < id {
+			cl = append(cl, "")
+		}
+		cl = append(cl, label)
+	}
+	return cl, scanner.Err()
+}
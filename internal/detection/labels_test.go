@@ -0,0 +1,45 @@
+package detection
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadClassLabels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labels.names")
+	if err := ioutil.WriteFile(path, []byte("person\nbicycle\ncar\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cl, err := ReadClassLabels(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cl) != 3 || cl[0] != "person" || cl[2] != "car" {
+		t.Errorf("Unexpected labels %v", cl)
+	}
+
+	if _, err := ReadClassLabels(filepath.Join(t.TempDir(), "missing.names")); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+}
+
+func TestReadIndexedLabels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labels.txt")
+	if err := ioutil.WriteFile(path, []byte("0  person\n1  bicycle\n\n3  motorcycle\ntraffic light\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cl, err := ReadIndexedLabels(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"person", "bicycle", "", "motorcycle", "traffic light"}
+	if len(cl) != len(want) {
+		t.Fatalf("Labels %q, want %q", cl, want)
+	}
+	for i := range want {
+		if cl[i] != want[i] {
+			t.Errorf("Label %d is %q, want %q", i, cl[i], want[i])
+		}
+	}
+}
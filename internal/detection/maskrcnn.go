@@ -0,0 +1,100 @@
+package detection
+
+import (
+	"fmt"
+	"image"
+
+	"gocv.io/x/gocv"
+)
+
+// Output layers of Mask R-CNN: boxes in the SSD format and masks
+var maskRCNNOutputLayers = []string{"detection_out_final", "detection_masks"}
+
+// Default Mask R-CNN parameters
+const (
+	DefaultMaskRCNNConfThr = 0.5
+	DefaultMaskThr         = 0.3 // Minimal mask probability of an object pixel
+)
+
+// MaskRCNNDetector runs the TensorFlow Mask R-CNN network and implements Detector. Each detection
+// has the outline of the object mask in Mask
+type MaskRCNNDetector struct {
+	Net         *gocv.Net
+	ClassLabels []string // Labels by class ID; class IDs are used as labels if there are none
+	ConfThr     float32
+	MaskThr     float32
+}
+
+// NewMaskRCNNDetector creates a detector with default thresholds
+func NewMaskRCNNDetector(net *gocv.Net, classLabels []string) *MaskRCNNDetector {
+	return &MaskRCNNDetector{Net: net, ClassLabels: classLabels, ConfThr: DefaultMaskRCNNConfThr, MaskThr: DefaultMaskThr}
+}
+
+// Detect runs the network and returns detections with masks, in the order of the network output,
+// which is by confidence
+func (md *MaskRCNNDetector) Detect(img gocv.Mat) ([]Detection, error) {
+	blob := gocv.BlobFromImage(img, 1.0, image.Pt(img.Cols(), img.Rows()), gocv.NewScalar(0, 0, 0, 0), true, false)
+	defer blob.Close()
+	md.Net.SetInput(blob, "")
+	outs := md.Net.ForwardLayers(maskRCNNOutputLayers)
+	defer func() {
+		for _, out := range outs {
+			out.Close()
+		}
+	}()
+
+	dets, rowIDs, classIDs := extractSSD(outs[0], image.Pt(img.Cols(), img.Rows()), md.ClassLabels, md.ConfThr)
+	masks := outs[1]
+	dims := masks.Size()
+	if len(dims) != 4 {
+		return dets, fmt.Errorf("Unexpected mask output shape %v", dims)
+	}
+	data, err := masks.DataPtrFloat32()
+	if err != nil {
+		return dets, err
+	}
+	classes, mh, mw := dims[1], dims[2], dims[3]
+	for i := range dets {
+		if rowIDs[i] >= dims[0] || classIDs[i] < 0 || classIDs[i] >= classes {
+			continue
+		}
+		offset := (rowIDs[i]*classes + classIDs[i]) * mh * mw
+		dets[i].Mask = MaskOutline(data[offset:offset+mh*mw], image.Pt(mw, mh), dets[i].BBox, md.MaskThr)
+	}
+	return dets, nil
+}
+
+// MaskOutline scales the mask of probabilities, of given size, to the box and returns the outline
+// of the largest region with probability above thr, in image coordinates
+func MaskOutline(probs []float32, size image.Point, box image.Rectangle, thr float32) []image.Point {
+	if box.Empty() {
+		return nil
+	}
+	mask := gocv.NewMatWithSize(size.Y, size.X, gocv.MatTypeCV32F)
+	defer mask.Close()
+	for i, p := range probs {
+		mask.SetFloatAt(i/size.X, i%size.X, p)
+	}
+	gocv.Resize(mask, &mask, box.Size(), 0, 0, gocv.InterpolationLinear)
+	binary := gocv.NewMat()
+	defer binary.Close()
+	gocv.Threshold(mask, &mask, thr, 255, gocv.ThresholdBinary)
+	mask.ConvertTo(&binary, gocv.MatTypeCV8U)
+
+	contours := gocv.FindContours(binary, gocv.RetrievalExternal, gocv.ChainApproxSimple)
+	defer contours.Close()
+	best, bestArea := -1, 0.0
+	for i := 0; i < contours.Size(); i++ {
+		if a := gocv.ContourArea(contours.At(i)); best < 0 || a > bestArea {
+			best, bestArea = i, a
+		}
+	}
+	if best < 0 {
+		return nil
+	}
+	pts := contours.At(best).ToPoints()
+	for i := range pts {
+		pts[i] = pts[i].Add(box.Min)
+	}
+	return pts
+}
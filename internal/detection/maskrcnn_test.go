@@ -0,0 +1,34 @@
+package detection
+
+import (
+	"image"
+	"testing"
+)
+
+func TestMaskOutline(t *testing.T) {
+	// 4x4 mask with the object in the right half
+	probs := []float32{
+		0, 0, 0.9, 0.9,
+		0, 0, 0.9, 0.9,
+		0, 0, 0.9, 0.9,
+		0, 0, 0.9, 0.9,
+	}
+	box := image.Rect(100, 50, 140, 90)
+	outline := MaskOutline(probs, image.Pt(4, 4), box, 0.5)
+	if len(outline) < 4 {
+		t.Fatalf("Expected a region outline, got %v", outline)
+	}
+	r := pointBounds(outline)
+	for _, p := range outline {
+		if !p.In(box) {
+			t.Fatalf("Outline point %v is outside of the box %v", p, box)
+		}
+	}
+	// Linear interpolation blurs the edge by about a mask cell
+	if r.Min.X < 115 || r.Min.X > 125 || r.Max.X != 139 {
+		t.Errorf("Expected the right half of the box, got %v", r)
+	}
+	if MaskOutline(make([]float32, 16), image.Pt(4, 4), box, 0.5) != nil {
+		t.Error("Expected no outline for an empty mask")
+	}
+}
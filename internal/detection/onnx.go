@@ -0,0 +1,143 @@
+//go:build onnxruntime
+// +build onnxruntime
+
+package detection
+
+import (
+	"errors"
+	"fmt"
+	"image"
+
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	ort "github.com/yalue/onnxruntime_go"
+	"gocv.io/x/gocv"
+)
+
+// OnnxAvailable reports whether the binary is built with ONNX Runtime
+const OnnxAvailable = true
+
+// OnnxYoloDetector runs a YOLOv5 or YOLOv8 ONNX model with ONNX Runtime through
+// github.com/yalue/onnxruntime_go and implements Detector. It can load models that the DNN module
+// of OpenCV cannot import, and uses the execution providers of the installed ONNX Runtime
+type OnnxYoloDetector struct {
+	ClassLabels []string
+	ConfThr     float32
+	OvrThr      float64
+	Stats       *metrics.Collector // Optional, records preprocess, inference and postprocess timing
+
+	size    int
+	session *ort.AdvancedSession
+	input   *ort.Tensor[float32]
+	output  *ort.Tensor[float32]
+}
+
+// NewOnnxYoloDetector loads the model with the ONNX Runtime library at libPath, or the one found
+// by the system loader if it is empty. The model should have one image input and one output
+func NewOnnxYoloDetector(modelPath string, classLabels []string, libPath string) (*OnnxYoloDetector, error) {
+	if !ort.IsInitialized() {
+		if libPath != "" {
+			ort.SetSharedLibraryPath(libPath)
+		}
+		if err := ort.InitializeEnvironment(); err != nil {
+			return nil, fmt.Errorf("Cannot load ONNX Runtime: %v", err)
+		}
+	}
+	inputs, outputs, err := ort.GetInputOutputInfo(modelPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(inputs) != 1 || len(outputs) != 1 {
+		return nil, fmt.Errorf("Model should have 1 input and 1 output, has %d and %d", len(inputs), len(outputs))
+	}
+
+	// Dynamic dimensions are -1
+	size := DefaultOnnxYoloSize
+	if dims := inputs[0].Dimensions; len(dims) == 4 && dims[2] > 0 {
+		size = int(dims[2])
+	}
+	outShape := outputs[0].Dimensions
+	if len(outShape) != 3 {
+		return nil, fmt.Errorf("Model output should have 3 dimensions, has %v", outShape)
+	}
+	outShape[0] = 1
+	for _, d := range outShape {
+		if d <= 0 {
+			return nil, fmt.Errorf("Model output should have a fixed shape, has %v", outShape)
+		}
+	}
+
+	od := &OnnxYoloDetector{ClassLabels: classLabels, ConfThr: DefaultYoloConfThr, OvrThr: DefaultYoloOvrThr, size: size}
+	if od.input, err = ort.NewEmptyTensor[float32](ort.NewShape(1, 3, int64(size), int64(size))); err != nil {
+		return nil, err
+	}
+	if od.output, err = ort.NewEmptyTensor[float32](outShape); err != nil {
+		od.Close()
+		return nil, err
+	}
+	od.session, err = ort.NewAdvancedSession(modelPath, []string{inputs[0].Name}, []string{outputs[0].Name},
+		[]ort.Value{od.input}, []ort.Value{od.output}, nil)
+	if err != nil {
+		od.Close()
+		return nil, fmt.Errorf("Cannot load ONNX model %s: %v", modelPath, err)
+	}
+	return od, nil
+}
+
+// Detect runs the model on the image and returns detections after NMS, most confident first
+func (od *OnnxYoloDetector) Detect(img gocv.Mat) ([]Detection, error) {
+	stop := od.stage("preprocess")
+	img32 := gocv.NewMat()
+	defer img32.Close()
+	img.ConvertTo(&img32, gocv.MatTypeCV32F)
+	blob := gocv.BlobFromImage(img32, 1.0/255, image.Pt(od.size, od.size), gocv.NewScalar(0, 0, 0, 0), true, false)
+	defer blob.Close()
+	data, err := blob.DataPtrFloat32()
+	if err != nil {
+		return nil, err
+	}
+	if copy(od.input.GetData(), data) != len(data) {
+		return nil, errors.New("Input blob does not match the model input")
+	}
+	stop()
+
+	stop = od.stage("inference")
+	err = od.session.Run()
+	stop()
+	if err != nil {
+		return nil, err
+	}
+
+	defer od.stage("postprocess")()
+	yd, err := decodeYoloOutput(od.output.GetData(), od.output.GetShape(), od.ClassLabels, od.ConfThr, od.size,
+		image.Pt(img.Cols(), img.Rows()))
+	if err != nil {
+		return nil, err
+	}
+	var dets []Detection
+	for _, d := range NMS(yd, od.OvrThr) {
+		dets = append(dets, d.Detection())
+	}
+	return dets, nil
+}
+
+// Close releases the session and its tensors
+func (od *OnnxYoloDetector) Close() error {
+	if od.session != nil {
+		od.session.Destroy()
+	}
+	if od.output != nil {
+		od.output.Destroy()
+	}
+	if od.input != nil {
+		od.input.Destroy()
+	}
+	return nil
+}
+
+// Starts timing of a stage if Stats is set
+func (od *OnnxYoloDetector) stage(name string) func() {
+	if od.Stats == nil {
+		return func() {}
+	}
+	return od.Stats.Start(name)
+}
@@ -0,0 +1,39 @@
+//go:build !onnxruntime
+// +build !onnxruntime
+
+package detection
+
+import (
+	"errors"
+
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"gocv.io/x/gocv"
+)
+
+// OnnxAvailable reports whether the binary is built with ONNX Runtime
+const OnnxAvailable = false
+
+var errNoOnnx = errors.New("Built without ONNX Runtime, build with -tags onnxruntime or use the OpenCV backend")
+
+// OnnxYoloDetector needs ONNX Runtime, which is only linked with the onnxruntime tag
+type OnnxYoloDetector struct {
+	ClassLabels []string
+	ConfThr     float32
+	OvrThr      float64
+	Stats       *metrics.Collector
+}
+
+// NewOnnxYoloDetector cannot load models without the onnxruntime tag
+func NewOnnxYoloDetector(modelPath string, classLabels []string, libPath string) (*OnnxYoloDetector, error) {
+	return nil, errNoOnnx
+}
+
+// Detect returns an error, see NewOnnxYoloDetector
+func (od *OnnxYoloDetector) Detect(img gocv.Mat) ([]Detection, error) {
+	return nil, errNoOnnx
+}
+
+// Close does nothing
+func (od *OnnxYoloDetector) Close() error {
+	return nil
+}
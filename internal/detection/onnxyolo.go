@@ -0,0 +1,69 @@
+package detection
+
+import (
+	"fmt"
+	"image"
+)
+
+// Default input size of YOLOv5 and YOLOv8 ONNX exports, used when the model has a dynamic input
+const DefaultOnnxYoloSize = 640
+
+// decodeYoloOutput returns the detections with confidence above confThr in the output of a YOLOv5
+// or YOLOv8 ONNX export, before NMS. The layout is told by the shape and the number of classes:
+//   - YOLOv5: [1, N, 5+C] rows of center x, center y, width, height, objectness and class scores
+//   - YOLOv8: [1, 4+C, N] columns of center x, center y, width, height and class scores, without
+//     objectness; [1, N, 4+C] rows are also accepted
+//
+// Boxes are in pixels of the square network input of the given size and are scaled to the frame size
+func decodeYoloOutput(out []float32, shape []int64, labels []string, confThr float32, inputSize int, frame image.Point) (YoloDSlice, error) {
+	if len(shape) != 3 || shape[0] != 1 {
+		return nil, fmt.Errorf("YOLO output should have shape [1, N, A] or [1, A, N], got %v", shape)
+	}
+	classes := len(labels)
+	rows, attrs := int(shape[1]), int(shape[2])
+	transposed := false
+	if rows == 4+classes && attrs != 4+classes && attrs != 5+classes {
+		rows, attrs, transposed = attrs, rows, true
+	}
+	objectness := attrs == 5+classes
+	if !objectness && attrs != 4+classes {
+		return nil, fmt.Errorf("YOLO output %v does not match %d classes", shape, classes)
+	}
+	if len(out) != rows*attrs {
+		return nil, fmt.Errorf("YOLO output has %d values, shape %v", len(out), shape)
+	}
+
+	at := func(row, attr int) float32 {
+		if transposed {
+			return out[attr*rows+row]
+		}
+		return out[row*attrs+attr]
+	}
+	first := 4 // First class score
+	if objectness {
+		first = 5
+	}
+	sx, sy := float32(frame.X)/float32(inputSize), float32(frame.Y)/float32(inputSize)
+	var yd YoloDSlice
+	for r := 0; r < rows; r++ {
+		obj := float32(1)
+		if objectness {
+			if obj = at(r, 4); obj <= confThr {
+				continue
+			}
+		}
+		class, score := 0, float32(0)
+		for c := 0; c < classes; c++ {
+			if s := at(r, first+c); s > score {
+				class, score = c, s
+			}
+		}
+		if conf := obj * score; conf > confThr {
+			cx, cy, w, h := at(r, 0)*sx, at(r, 1)*sy, at(r, 2)*sx, at(r, 3)*sy
+			left, top := int(cx-w/2), int(cy-h/2)
+			yd = append(yd, YoloDetection{class, labels[class], conf,
+				image.Rect(left, top, left+int(w), top+int(h))})
+		}
+	}
+	return yd, nil
+}
@@ -0,0 +1,50 @@
+package detection
+
+import (
+	"image"
+	"testing"
+)
+
+func TestDecodeYoloOutput(t *testing.T) {
+	labels := []string{"person", "dog"}
+	frame := image.Pt(1280, 640) // Twice the width of the 640 input, same height
+
+	// YOLOv5 rows: cx, cy, w, h, objectness, scores
+	v5 := []float32{
+		320, 320, 100, 200, 0.9, 0.2, 0.8, // Dog, 0.72
+		100, 100, 50, 50, 0.4, 0.9, 0.0, // Objectness below the threshold
+	}
+	yd, err := decodeYoloOutput(v5, []int64{1, 2, 7}, labels, 0.5, 640, frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(yd) != 1 || yd[0].DetName != "dog" || yd[0].DetBBox != image.Rect(540, 220, 740, 420) {
+		t.Errorf("YOLOv5 detections %v", yd)
+	}
+	if conf := yd[0].DetConf; conf < 0.71 || conf > 0.73 {
+		t.Errorf("Confidence %.3f, want 0.72", conf)
+	}
+
+	// YOLOv8 columns: 4+C attributes of 3 boxes
+	v8 := []float32{
+		320, 100, 600, // cx
+		320, 100, 600, // cy
+		100, 50, 20, // w
+		200, 50, 20, // h
+		0.9, 0.1, 0.3, // person
+		0.0, 0.6, 0.2, // dog
+	}
+	yd, err = decodeYoloOutput(v8, []int64{1, 6, 3}, labels, 0.5, 640, frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(yd) != 2 || yd[0].DetName != "person" || yd[1].DetName != "dog" || yd[1].DetBBox != image.Rect(150, 75, 250, 125) {
+		t.Errorf("YOLOv8 detections %v", yd)
+	}
+
+	for _, shape := range [][]int64{{1, 3, 5}, {2, 6, 3}, {6, 3}} {
+		if _, err := decodeYoloOutput(v8, shape, labels, 0.5, 640, frame); err == nil {
+			t.Errorf("Expected an error for shape %v", shape)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package detection
+
+import (
+	"image"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gocv.io/x/gocv"
+)
+
+// Default ORB matching parameters
+const (
+	DefaultMinMatches = 15   // Minimum number of feature matches to detect a pattern
+	DefaultDistFactor = 0.75 // Magic factor of the ratio test
+)
+
+type (
+	// ORBPattern stores single pattern image
+	ORBPattern struct {
+		Name      string
+		Img       gocv.Mat        // Image
+		KeyPoints []gocv.KeyPoint // ORB key points
+		Descr     gocv.Mat        // ORB descriptors
+	}
+	// ORBPatternDetector stores a set of patterns and has an associated method
+	// to match an image versus this set
+	ORBPatternDetector struct {
+		Pats       []ORBPattern
+		MinMatches int
+		DistFactor float64
+		orb        gocv.ORB
+	}
+)
+
+// NewORBPatternDetector creates a new instance of ORBPatternDetector with assigned ORB
+// and loads image patterns from dir. Only files accepted by filter are loaded; nil filter accepts all files
+func NewORBPatternDetector(orb gocv.ORB, dir string, filter func(filename string) bool) ORBPatternDetector {
+	pats := []ORBPattern{}
+
+	// Read patterns
+	items, _ := ioutil.ReadDir(dir)
+	for _, item := range items {
+		filename := item.Name()
+		if filter != nil && !filter(filename) {
+			continue
+		}
+		patImg := gocv.IMRead(filepath.Join(dir, filename), gocv.IMReadGrayScale)
+		if !patImg.Empty() {
+			mask := gocv.NewMat()
+			kp, descr := orb.DetectAndCompute(patImg, mask)
+			mask.Close()
+			pats = append(pats, ORBPattern{Name: strings.Split(filename, ".")[0], Img: patImg, KeyPoints: kp, Descr: descr})
+		}
+	}
+
+	return ORBPatternDetector{orb: orb, Pats: pats, MinMatches: DefaultMinMatches, DistFactor: DefaultDistFactor}
+}
+
+// Match finds and returns a single pattern with the best match to the image, and the number of matches,
+// using bruteforce matcher. Number of matches should be greater than MinMatches
+// Returns an empty struct and 0 in the case of no matches detected
+func (opd *ORBPatternDetector) Match(img gocv.Mat) (best ORBPattern, numMatches int) {
+	bestID, _, matches := opd.match(img)
+	if bestID >= 0 {
+		best = opd.Pats[bestID]
+	}
+	return best, len(matches)
+}
+
+// Detect implements Detector. The best matching pattern is located on the image with a homography,
+// its outline is returned as Quad. Confidence is the share of pattern key points which are matched
+func (opd *ORBPatternDetector) Detect(img gocv.Mat) ([]Detection, error) {
+	bestID, kp, matches := opd.match(img)
+	if bestID < 0 {
+		return nil, nil
+	}
+	pat := opd.Pats[bestID]
+	det := Detection{Label: pat.Name, Confidence: float32(len(matches)) / float32(len(pat.KeyPoints))}
+	if det.Confidence > 1 {
+		det.Confidence = 1
+	}
+
+	h := MatchHomography(pat.KeyPoints, kp, matches)
+	defer h.Close()
+	if h.Empty() {
+		// No consistent geometry, box the matched key points
+		var pts []image.Point
+		for _, m := range matches {
+			pts = append(pts, image.Pt(int(kp[m.QueryIdx].X), int(kp[m.QueryIdx].Y)))
+		}
+		pv := gocv.NewPointVectorFromPoints(pts)
+		defer pv.Close()
+		det.BBox = gocv.BoundingRect(pv)
+		return []Detection{det}, nil
+	}
+
+	w, ht := float64(pat.Img.Cols()), float64(pat.Img.Rows())
+	for _, c := range [][2]float64{{0, 0}, {w, 0}, {w, ht}, {0, ht}} {
+		det.Quad = append(det.Quad, transformPoint(h, c[0], c[1]))
+	}
+	pv := gocv.NewPointVectorFromPoints(det.Quad)
+	defer pv.Close()
+	det.BBox = gocv.BoundingRect(pv).Intersect(image.Rect(0, 0, img.Cols(), img.Rows()))
+	return []Detection{det}, nil
+}
+
+// Finds the pattern with the most good matches to the image, which should be more than MinMatches.
+// Returns its index or -1, key points of the image and the good matches
+func (opd *ORBPatternDetector) match(img gocv.Mat) (bestID int, kp []gocv.KeyPoint, best []gocv.DMatch) {
+	bestID = -1
+	if img.Empty() {
+		return
+	}
+
+	// BF comparison to all patterns
+	mask := gocv.NewMat()
+	defer mask.Close()
+	kp, descr := opd.orb.DetectAndCompute(img, mask)
+	defer descr.Close()
+	bf := gocv.NewBFMatcher()
+	defer bf.Close()
+	for i, pat := range opd.Pats {
+		matches := opd.goodMatches(bf, descr, pat.Descr)
+		if len(matches) > len(best) && len(matches) > opd.MinMatches {
+			best = matches
+			bestID = i
+		}
+	}
+	return
+}
+
+// Close releases pattern images and descriptors
+func (opd *ORBPatternDetector) Close() error {
+	for _, pat := range opd.Pats {
+		pat.Img.Close()
+		pat.Descr.Close()
+	}
+	opd.Pats = nil
+	return nil
+}
+
+// Compares feature descriptions of 2 images and returns matches between them passing the ratio test
+func (opd *ORBPatternDetector) goodMatches(bf gocv.BFMatcher, descr1, descr2 gocv.Mat) []gocv.DMatch {
+	return RatioTest(bf.KnnMatch(descr1, descr2, 2), opd.DistFactor)
+}
+
+// RatioTest keeps the best match of each pair of nearest neighbours returned by KnnMatch with k = 2,
+// if it is clearly better than the second one: its distance is less than distFactor of the second's
+func RatioTest(knn [][]gocv.DMatch, distFactor float64) (good []gocv.DMatch) {
+	for _, mtcPair := range knn {
+		if len(mtcPair) == 2 && mtcPair[0].Distance < distFactor*mtcPair[1].Distance {
+			good = append(good, mtcPair[0])
+		}
+	}
+	return
+}
+
+// MatchHomography estimates with RANSAC the perspective transform from the train image to the query
+// image of the matches. Returns a 3x3 CV_64F Mat, which is empty if there is no consistent transform
+func MatchHomography(trainKP, queryKP []gocv.KeyPoint, matches []gocv.DMatch) gocv.Mat {
+	src := gocv.NewMatWithSize(len(matches), 2, gocv.MatTypeCV32F)
+	defer src.Close()
+	dst := gocv.NewMatWithSize(len(matches), 2, gocv.MatTypeCV32F)
+	defer dst.Close()
+	for i, m := range matches {
+		p, q := trainKP[m.TrainIdx], queryKP[m.QueryIdx]
+		src.SetFloatAt(i, 0, float32(p.X))
+		src.SetFloatAt(i, 1, float32(p.Y))
+		dst.SetFloatAt(i, 0, float32(q.X))
+		dst.SetFloatAt(i, 1, float32(q.Y))
+	}
+	mask := gocv.NewMat()
+	defer mask.Close()
+	return gocv.FindHomography(src, &dst, gocv.HomograpyMethodRANSAC, 3, &mask, 2000, 0.995)
+}
+
+// Applies the 3x3 perspective transform h to the point
+func transformPoint(h gocv.Mat, x, y float64) image.Point {
+	w := h.GetDoubleAt(2, 0)*x + h.GetDoubleAt(2, 1)*y + h.GetDoubleAt(2, 2)
+	px := (h.GetDoubleAt(0, 0)*x + h.GetDoubleAt(0, 1)*y + h.GetDoubleAt(0, 2)) / w
+	py := (h.GetDoubleAt(1, 0)*x + h.GetDoubleAt(1, 1)*y + h.GetDoubleAt(1, 2)) / w
+	return image.Pt(int(px), int(py))
+}
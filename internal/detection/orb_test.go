@@ -0,0 +1,88 @@
+package detection
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/marchevska/gocv-examples/internal/testutil"
+	"gocv.io/x/gocv"
+)
+
+func loadCardDetector(t *testing.T) ORBPatternDetector {
+	orb := gocv.NewORB()
+	t.Cleanup(func() { orb.Close() })
+	opd := NewORBPatternDetector(orb, testutil.RepoPath("orb", "real_cards", "train_img"), func(filename string) bool {
+		return strings.HasPrefix(filename, "King") || strings.HasPrefix(filename, "Queen")
+	})
+	t.Cleanup(func() { opd.Close() })
+	if len(opd.Pats) != 8 {
+		t.Fatalf("Expected 8 patterns, got %d", len(opd.Pats))
+	}
+	return opd
+}
+
+func TestORBPatternDetectorMatch(t *testing.T) {
+	opd := loadCardDetector(t)
+
+	// Fixture is the King of Hearts pattern at half size
+	best, n := opd.Match(testutil.LoadGray(t, "card.png"))
+	if best.Name != "King of Hearts" {
+		t.Errorf("Expected King of Hearts, got %q with %d matches", best.Name, n)
+	}
+}
+
+func TestORBPatternDetectorNoMatch(t *testing.T) {
+	opd := loadCardDetector(t)
+
+	best, n := opd.Match(testutil.LoadGray(t, "checkerboard.png"))
+	if n != 0 || best.Name != "" {
+		t.Errorf("Expected no match, got %q with %d matches", best.Name, n)
+	}
+}
+
+func TestORBPatternDetectorDetect(t *testing.T) {
+	opd := loadCardDetector(t)
+	var detector Detector = &opd
+
+	img := testutil.LoadGray(t, "card.png")
+	dets, err := detector.Detect(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dets) != 1 || dets[0].Label != "King of Hearts" {
+		t.Fatalf("Expected King of Hearts, got %v", dets)
+	}
+	// Fixture is the whole card, so the box covers most of the image
+	d := dets[0]
+	if d.BBox.Dx() < img.Cols()/2 || d.BBox.Dy() < img.Rows()/2 || len(d.Quad) != 4 {
+		t.Errorf("Unexpected location: box %v, outline %v", d.BBox, d.Quad)
+	}
+	if d.Confidence <= 0 || d.Confidence > 1 {
+		t.Errorf("Confidence out of range: %v", d.Confidence)
+	}
+}
+
+func BenchmarkORBPatternDetectorMatch(b *testing.B) {
+	orb := gocv.NewORB()
+	defer orb.Close()
+	opd := NewORBPatternDetector(orb, testutil.RepoPath("orb", "real_cards", "train_img"), nil)
+	defer opd.Close()
+	img := testutil.LoadGray(b, "card.png")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		opd.Match(img)
+	}
+}
+
+func TestRatioTest(t *testing.T) {
+	knn := [][]gocv.DMatch{
+		{{QueryIdx: 0, TrainIdx: 1, Distance: 10}, {QueryIdx: 0, TrainIdx: 2, Distance: 50}}, // Distinct
+		{{QueryIdx: 1, TrainIdx: 3, Distance: 40}, {QueryIdx: 1, TrainIdx: 4, Distance: 45}}, // Ambiguous
+		{{QueryIdx: 2, TrainIdx: 5, Distance: 5}},                                            // Single neighbour
+	}
+	good := RatioTest(knn, DefaultDistFactor)
+	if len(good) != 1 || good[0].TrainIdx != 1 {
+		t.Errorf("Expected only the distinct match, got %v", good)
+	}
+}
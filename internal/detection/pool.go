@@ -0,0 +1,131 @@
+package detection
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+
+	"gocv.io/x/gocv"
+)
+
+// WorkerFunc creates the detector of worker i and returns it with a function releasing it.
+// It runs on the OS thread of the worker, so thread state like the current CUDA device applies
+// to all detections of the worker
+type WorkerFunc func(i int) (Detector, func() error, error)
+
+// Pool spreads detections across workers, e.g. one network per GPU, and implements Detector.
+// Detect is safe for concurrent use: each call goes to the worker with the fewest pending frames,
+// the one that has processed fewer frames on a tie. Each worker runs its detections one at a time
+// on a locked OS thread
+type Pool struct {
+	mu      sync.Mutex
+	workers []*poolWorker
+	wg      sync.WaitGroup
+}
+
+type poolWorker struct {
+	jobs    chan poolJob
+	pending int // Frames sent and not finished yet
+	frames  int // Frames finished
+}
+
+type poolJob struct {
+	img gocv.Mat
+	res chan poolResult
+}
+
+type poolResult struct {
+	dets []Detection
+	err  error
+}
+
+// NewPool starts n workers and returns when all of them have created their detectors.
+// If any fails, the others are closed
+func NewPool(n int, create WorkerFunc) (*Pool, error) {
+	if n < 1 {
+		return nil, errors.New("Pool needs at least one worker")
+	}
+	p := &Pool{}
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		w := &poolWorker{jobs: make(chan poolJob)}
+		p.workers = append(p.workers, w)
+		p.wg.Add(1)
+		go p.run(i, w, create, errs)
+	}
+	var err error
+	for i := 0; i < n; i++ {
+		if e := <-errs; e != nil && err == nil {
+			err = e
+		}
+	}
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// Creates the detector of the worker on a locked thread and runs its jobs until the pool is closed
+func (p *Pool) run(i int, w *poolWorker, create WorkerFunc, errs chan<- error) {
+	defer p.wg.Done()
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	det, release, err := create(i)
+	errs <- err
+	if err != nil {
+		for range w.jobs {
+		}
+		return
+	}
+	defer release()
+	for job := range w.jobs {
+		dets, err := det.Detect(job.img)
+		job.res <- poolResult{dets, err}
+	}
+}
+
+// Detect runs the detector of the least busy worker on the image
+func (p *Pool) Detect(img gocv.Mat) ([]Detection, error) {
+	p.mu.Lock()
+	var w *poolWorker
+	for _, c := range p.workers {
+		if w == nil || c.pending < w.pending || (c.pending == w.pending && c.frames < w.frames) {
+			w = c
+		}
+	}
+	w.pending++
+	p.mu.Unlock()
+
+	res := make(chan poolResult, 1)
+	w.jobs <- poolJob{img, res}
+	r := <-res
+
+	p.mu.Lock()
+	w.pending--
+	w.frames++
+	p.mu.Unlock()
+	return r.dets, r.err
+}
+
+// Frames returns the number of frames processed by each worker
+func (p *Pool) Frames() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	frames := make([]int, len(p.workers))
+	for i, w := range p.workers {
+		frames[i] = w.frames
+	}
+	return frames
+}
+
+// Close stops the workers after their current detections and releases their detectors.
+// Detect should not be called after Close
+func (p *Pool) Close() error {
+	for _, w := range p.workers {
+		close(w.jobs)
+	}
+	p.wg.Wait()
+	return nil
+}
@@ -0,0 +1,95 @@
+package detection
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// Detector returning the ID of its worker as label
+type workerDetector struct {
+	id    string
+	delay time.Duration
+}
+
+func (d workerDetector) Detect(img gocv.Mat) ([]Detection, error) {
+	time.Sleep(d.delay)
+	return []Detection{{Label: d.id}}, nil
+}
+
+func TestPoolSpreadsLoad(t *testing.T) {
+	var mu sync.Mutex
+	released := 0
+	pool, err := NewPool(3, func(i int) (Detector, func() error, error) {
+		return workerDetector{id: string(rune('a' + i)), delay: 20 * time.Millisecond}, func() error {
+			mu.Lock()
+			released++
+			mu.Unlock()
+			return nil
+		}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Concurrent frames go to idle workers
+	img := gocv.NewMat()
+	defer img.Close()
+	var wg sync.WaitGroup
+	labels := make(chan string, 6)
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dets, err := pool.Detect(img)
+			if err != nil || len(dets) != 1 {
+				t.Errorf("Unexpected result %v, %v", dets, err)
+				return
+			}
+			labels <- dets[0].Label
+		}()
+	}
+	wg.Wait()
+	close(labels)
+	count := map[string]int{}
+	for l := range labels {
+		count[l]++
+	}
+	if count["a"] != 2 || count["b"] != 2 || count["c"] != 2 {
+		t.Errorf("Frames by worker %v, want 2 each", count)
+	}
+
+	// Sequential frames alternate, as the worker with fewer frames is picked
+	for i := 0; i < 3; i++ {
+		if _, err := pool.Detect(img); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if frames := pool.Frames(); frames[0] != 3 || frames[1] != 3 || frames[2] != 3 {
+		t.Errorf("Frames %v, want 3 each", frames)
+	}
+
+	pool.Close()
+	if released != 3 {
+		t.Errorf("%d detectors released, want 3", released)
+	}
+}
+
+func TestPoolCreateError(t *testing.T) {
+	released := make(chan int, 2)
+	_, err := NewPool(2, func(i int) (Detector, func() error, error) {
+		if i == 1 {
+			return nil, nil, errors.New("No device")
+		}
+		return workerDetector{}, func() error { released <- i; return nil }, nil
+	})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if len(released) != 1 {
+		t.Errorf("Created detectors should be released")
+	}
+}
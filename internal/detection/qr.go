@@ -0,0 +1,54 @@
+package detection
+
+import (
+	"image"
+
+	"gocv.io/x/gocv"
+)
+
+// QRDetector finds and decodes QR codes and implements Detector. Each detection is labelled
+// with the decoded payload, which is empty if the code was found but could not be decoded
+type QRDetector struct {
+	Detector *gocv.QRCodeDetector
+}
+
+// Detect finds all QR codes on the image
+func (qd *QRDetector) Detect(img gocv.Mat) ([]Detection, error) {
+	var decoded []string
+	points := gocv.NewMat()
+	defer points.Close()
+	var codes []gocv.Mat
+	found := qd.Detector.DetectAndDecodeMulti(img, &decoded, &points, &codes)
+	for _, c := range codes {
+		c.Close()
+	}
+	if !found || points.Empty() {
+		return nil, nil
+	}
+
+	// Corners of all codes as a single column of points
+	flat := points.Reshape(2, points.Total())
+	defer flat.Close()
+	corners := make([]image.Point, flat.Rows())
+	for i := range corners {
+		v := flat.GetVecfAt(i, 0)
+		corners[i] = image.Pt(int(v[0]), int(v[1]))
+	}
+	return QRDetections(decoded, corners), nil
+}
+
+// QRDetections groups corners of the codes by four and labels them with the decoded payloads
+func QRDetections(decoded []string, corners []image.Point) []Detection {
+	var dets []Detection
+	for i := 0; (i+1)*4 <= len(corners); i++ {
+		quad := corners[i*4 : (i+1)*4]
+		pv := gocv.NewPointVectorFromPoints(quad)
+		d := Detection{Confidence: 1, BBox: gocv.BoundingRect(pv), Quad: quad}
+		pv.Close()
+		if i < len(decoded) {
+			d.Label = decoded[i]
+		}
+		dets = append(dets, d)
+	}
+	return dets
+}
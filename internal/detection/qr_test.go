@@ -0,0 +1,24 @@
+package detection
+
+import (
+	"image"
+	"testing"
+)
+
+func TestQRDetections(t *testing.T) {
+	corners := []image.Point{
+		{10, 10}, {50, 12}, {48, 50}, {8, 48}, // Decoded code
+		{100, 100}, {120, 100}, {120, 120}, {100, 120}, // Found, not decoded
+		{200, 200}, // Incomplete quad is ignored
+	}
+	dets := QRDetections([]string{"https://gocv.io", ""}, corners)
+	if len(dets) != 2 {
+		t.Fatalf("Expected 2 detections, got %v", dets)
+	}
+	if dets[0].Label != "https://gocv.io" || dets[0].BBox != image.Rect(8, 10, 51, 51) || len(dets[0].Quad) != 4 {
+		t.Errorf("Unexpected first detection %v", dets[0])
+	}
+	if dets[1].Label != "" || dets[1].Quad[2] != image.Pt(120, 120) {
+		t.Errorf("Unexpected second detection %v", dets[1])
+	}
+}
@@ -0,0 +1,26 @@
+package detection
+
+import (
+	"sync"
+
+	"gocv.io/x/gocv"
+)
+
+// Serialized makes a detector safe for concurrent use by running one detection at a time, e.g. to
+// share one network between several streams
+type Serialized struct {
+	mu       sync.Mutex
+	detector Detector
+}
+
+// NewSerialized wraps the detector
+func NewSerialized(d Detector) *Serialized {
+	return &Serialized{detector: d}
+}
+
+// Detect waits for running detections to finish and runs the detector on the image
+func (s *Serialized) Detect(img gocv.Mat) ([]Detection, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.detector.Detect(img)
+}
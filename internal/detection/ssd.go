@@ -0,0 +1,70 @@
+package detection
+
+import (
+	"image"
+	"sort"
+	"strconv"
+
+	"gocv.io/x/gocv"
+)
+
+// SSDDetector runs a Single Shot Detector network, like the OpenCV face detector or MobileNet SSD,
+// and implements Detector
+type SSDDetector struct {
+	Net         *gocv.Net
+	Size        image.Point // Input size of the network
+	Scale       float64
+	Mean        gocv.Scalar
+	SwapRB      bool
+	ClassLabels []string // Labels by class ID; class IDs are used as labels if there are none
+	ConfThr     float32
+}
+
+// Detect runs the network on the image and returns detections, most confident first
+func (sd *SSDDetector) Detect(img gocv.Mat) ([]Detection, error) {
+	blob := gocv.BlobFromImage(img, sd.Scale, sd.Size, sd.Mean, sd.SwapRB, false)
+	defer blob.Close()
+	sd.Net.SetInput(blob, "")
+	out := sd.Net.Forward("")
+	defer out.Close()
+	return ExtractSSDPredictions(out, image.Pt(img.Cols(), img.Rows()), sd.ClassLabels, sd.ConfThr), nil
+}
+
+// ExtractSSDPredictions extracts detections with confidence above confThr from SSD output.
+// Output has a shape 1x1xNx7, where each of N rows is
+// [image ID, class ID, confidence, left, top, right, bottom], coordinates relative to the image size
+func ExtractSSDPredictions(out gocv.Mat, imgSize image.Point, classLabels []string, confThr float32) []Detection {
+	dets, _, _ := extractSSD(out, imgSize, classLabels, confThr)
+	sort.SliceStable(dets, func(i, j int) bool { return dets[i].Confidence > dets[j].Confidence })
+	return dets
+}
+
+// Extracts detections in the order of output rows. Returns also the row and the class ID of each detection
+func extractSSD(out gocv.Mat, imgSize image.Point, classLabels []string, confThr float32) (dets []Detection, rowIDs, classIDs []int) {
+	rows := out.Reshape(1, out.Total()/7)
+	defer rows.Close()
+	bounds := image.Rectangle{Max: imgSize}
+
+	for i := 0; i < rows.Rows(); i++ {
+		conf := rows.GetFloatAt(i, 2)
+		if conf <= confThr {
+			continue
+		}
+		class := int(rows.GetFloatAt(i, 1))
+		label := strconv.Itoa(class)
+		if class >= 0 && class < len(classLabels) {
+			label = classLabels[class]
+		}
+		rect := image.Rect(
+			int(rows.GetFloatAt(i, 3)*float32(imgSize.X)), int(rows.GetFloatAt(i, 4)*float32(imgSize.Y)),
+			int(rows.GetFloatAt(i, 5)*float32(imgSize.X)), int(rows.GetFloatAt(i, 6)*float32(imgSize.Y)),
+		).Intersect(bounds)
+		if rect.Empty() {
+			continue
+		}
+		dets = append(dets, Detection{Label: label, Confidence: conf, BBox: rect})
+		rowIDs = append(rowIDs, i)
+		classIDs = append(classIDs, class)
+	}
+	return dets, rowIDs, classIDs
+}
@@ -0,0 +1,26 @@
+package detection
+
+import (
+	"image"
+	"testing"
+)
+
+func TestExtractSSDPredictions(t *testing.T) {
+	out := floatRows([][]float32{
+		{0, 1, 0.6, 0.125, 0.125, 0.25, 0.5}, // Face
+		{0, 1, 0.95, 0.5, 0.25, 0.75, 1.25},  // Face clipped by the bottom edge
+		{0, 1, 0.2, 0, 0, 0.5, 0.5},          // Below the threshold
+	})
+	defer out.Close()
+
+	dets := ExtractSSDPredictions(out, image.Pt(200, 100), []string{"background", "face"}, 0.5)
+	if len(dets) != 2 {
+		t.Fatalf("Expected 2 detections, got %v", dets)
+	}
+	if dets[0].Label != "face" || dets[0].BBox != image.Rect(100, 25, 150, 100) {
+		t.Errorf("Unexpected first detection %v", dets[0])
+	}
+	if dets[1].BBox != image.Rect(25, 12, 50, 50) {
+		t.Errorf("Unexpected second detection %v", dets[1])
+	}
+}
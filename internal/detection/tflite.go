@@ -0,0 +1,127 @@
+//go:build tflite
+// +build tflite
+
+package detection
+
+import (
+	"errors"
+	"fmt"
+	"image"
+
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/mattn/go-tflite"
+	"gocv.io/x/gocv"
+)
+
+// TFLiteAvailable reports whether the binary is built with TensorFlow Lite
+const TFLiteAvailable = true
+
+// TFLiteDetector runs a TensorFlow Lite SSD detection model ending with the detection postprocess op,
+// like the quantized SSD MobileNet COCO models, through github.com/mattn/go-tflite, and implements Detector.
+// Its outputs are boxes, classes, scores and the number of detections, in this order.
+// Quantized models take 8-bit RGB input, float ones RGB scaled to [-1, 1]
+type TFLiteDetector struct {
+	ClassLabels []string // Labels by class ID; class IDs are used as labels if there are none
+	ConfThr     float32
+	Stats       *metrics.Collector // Optional, records preprocess, inference and postprocess timing
+
+	size        image.Point
+	model       *tflite.Model
+	options     *tflite.InterpreterOptions
+	interpreter *tflite.Interpreter
+}
+
+// NewTFLiteDetector loads the model, running inference with the given number of threads
+func NewTFLiteDetector(modelPath string, classLabels []string, threads int) (*TFLiteDetector, error) {
+	td := &TFLiteDetector{ClassLabels: classLabels, ConfThr: DefaultYoloConfThr}
+	if td.model = tflite.NewModelFromFile(modelPath); td.model == nil {
+		return nil, fmt.Errorf("Cannot load TFLite model %s", modelPath)
+	}
+	td.options = tflite.NewInterpreterOptions()
+	td.options.SetNumThread(threads)
+	if td.interpreter = tflite.NewInterpreter(td.model, td.options); td.interpreter == nil {
+		td.Close()
+		return nil, errors.New("Cannot create TFLite interpreter")
+	}
+	if status := td.interpreter.AllocateTensors(); status != tflite.OK {
+		td.Close()
+		return nil, fmt.Errorf("Cannot allocate TFLite tensors: %v", status)
+	}
+
+	input := td.interpreter.GetInputTensor(0)
+	if input.NumDims() != 4 || input.Dim(3) != 3 || (input.Type() != tflite.UInt8 && input.Type() != tflite.Float32) {
+		td.Close()
+		return nil, errors.New("Model input should be a batch of RGB images of 8-bit or float values")
+	}
+	if n := td.interpreter.GetOutputTensorCount(); n != 4 {
+		td.Close()
+		return nil, fmt.Errorf("Model should have the 4 outputs of the detection postprocess op, has %d", n)
+	}
+	td.size = image.Pt(input.Dim(2), input.Dim(1))
+	return td, nil
+}
+
+// Detect runs the model on the image and returns detections, most confident first
+func (td *TFLiteDetector) Detect(img gocv.Mat) ([]Detection, error) {
+	stop := td.stage("preprocess")
+	rgb := gocv.NewMat()
+	defer rgb.Close()
+	gocv.Resize(img, &rgb, td.size, 0, 0, gocv.InterpolationLinear)
+	gocv.CvtColor(rgb, &rgb, gocv.ColorBGRToRGB)
+	input := td.interpreter.GetInputTensor(0)
+	if input.Type() == tflite.UInt8 {
+		data, err := rgb.DataPtrUint8()
+		if err != nil {
+			return nil, err
+		}
+		copy(input.UInt8s(), data)
+	} else {
+		rgb32 := gocv.NewMat()
+		defer rgb32.Close()
+		rgb.ConvertToWithParams(&rgb32, gocv.MatTypeCV32FC3, 1/127.5, -1)
+		data, err := rgb32.DataPtrFloat32()
+		if err != nil {
+			return nil, err
+		}
+		copy(input.Float32s(), data)
+	}
+	stop()
+
+	stop = td.stage("inference")
+	status := td.interpreter.Invoke()
+	stop()
+	if status != tflite.OK {
+		return nil, fmt.Errorf("TFLite inference failed: %v", status)
+	}
+
+	defer td.stage("postprocess")()
+	out := func(i int) []float32 { return td.interpreter.GetOutputTensor(i).Float32s() }
+	count := 0
+	if n := out(3); len(n) > 0 {
+		count = int(n[0])
+	}
+	return decodeSSDOutputs(out(0), out(1), out(2), count, td.ClassLabels, td.ConfThr,
+		image.Pt(img.Cols(), img.Rows())), nil
+}
+
+// Close releases the interpreter and the model
+func (td *TFLiteDetector) Close() error {
+	if td.interpreter != nil {
+		td.interpreter.Delete()
+	}
+	if td.options != nil {
+		td.options.Delete()
+	}
+	if td.model != nil {
+		td.model.Delete()
+	}
+	return nil
+}
+
+// Starts timing of a stage if Stats is set
+func (td *TFLiteDetector) stage(name string) func() {
+	if td.Stats == nil {
+		return func() {}
+	}
+	return td.Stats.Start(name)
+}
@@ -0,0 +1,38 @@
+//go:build !tflite
+// +build !tflite
+
+package detection
+
+import (
+	"errors"
+
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"gocv.io/x/gocv"
+)
+
+// TFLiteAvailable reports whether the binary is built with TensorFlow Lite
+const TFLiteAvailable = false
+
+var errNoTFLite = errors.New("Built without TensorFlow Lite, build with -tags tflite")
+
+// TFLiteDetector needs TensorFlow Lite, which is only linked with the tflite tag
+type TFLiteDetector struct {
+	ClassLabels []string
+	ConfThr     float32
+	Stats       *metrics.Collector
+}
+
+// NewTFLiteDetector cannot load models without the tflite tag
+func NewTFLiteDetector(modelPath string, classLabels []string, threads int) (*TFLiteDetector, error) {
+	return nil, errNoTFLite
+}
+
+// Detect returns an error, see NewTFLiteDetector
+func (td *TFLiteDetector) Detect(img gocv.Mat) ([]Detection, error) {
+	return nil, errNoTFLite
+}
+
+// Close does nothing
+func (td *TFLiteDetector) Close() error {
+	return nil
+}
@@ -0,0 +1,43 @@
+package detection
+
+import (
+	"image"
+	"sort"
+	"strconv"
+)
+
+// decodeSSDOutputs returns the detections with confidence above confThr from the outputs of the
+// TensorFlow Lite detection postprocess op, most confident first: boxes of [top, left, bottom, right]
+// relative to the image size, class IDs and scores of count detections. Boxes are scaled to the frame size
+func decodeSSDOutputs(boxes, classes, scores []float32, count int, labels []string, confThr float32, frame image.Point) []Detection {
+	if count > len(scores) || count > len(classes) || 4*count > len(boxes) {
+		count = len(scores)
+		if len(classes) < count {
+			count = len(classes)
+		}
+		if len(boxes)/4 < count {
+			count = len(boxes) / 4
+		}
+	}
+	bounds := image.Rectangle{Max: frame}
+	var dets []Detection
+	for i := 0; i < count; i++ {
+		if scores[i] <= confThr {
+			continue
+		}
+		class := int(classes[i])
+		label := strconv.Itoa(class)
+		if class >= 0 && class < len(labels) && labels[class] != "" {
+			label = labels[class]
+		}
+		b := boxes[4*i : 4*i+4]
+		rect := image.Rect(int(b[1]*float32(frame.X)), int(b[0]*float32(frame.Y)),
+			int(b[3]*float32(frame.X)), int(b[2]*float32(frame.Y))).Intersect(bounds)
+		if rect.Empty() {
+			continue
+		}
+		dets = append(dets, Detection{Label: label, Confidence: scores[i], BBox: rect})
+	}
+	sort.SliceStable(dets, func(i, j int) bool { return dets[i].Confidence > dets[j].Confidence })
+	return dets
+}
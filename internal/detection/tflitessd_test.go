@@ -0,0 +1,33 @@
+package detection
+
+import (
+	"image"
+	"testing"
+)
+
+func TestDecodeSSDOutputs(t *testing.T) {
+	labels := []string{"person", "bicycle", "", "motorcycle"}
+	boxes := []float32{
+		0.1, 0.2, 0.5, 0.6, // top, left, bottom, right
+		0.0, 0.0, 1.0, 1.2, // Clipped to the frame
+		0.3, 0.3, 0.4, 0.4,
+		0.5, 0.5, 0.6, 0.6, // Past the count
+	}
+	classes := []float32{0, 2, 3, 1}
+	scores := []float32{0.6, 0.9, 0.3, 0.95}
+	dets := decodeSSDOutputs(boxes, classes, scores, 3, labels, 0.5, image.Pt(200, 100))
+	if len(dets) != 2 {
+		t.Fatalf("Detections %v, want 2", dets)
+	}
+	if dets[0].Label != "2" || dets[0].BBox != image.Rect(0, 0, 200, 100) {
+		t.Errorf("Unexpected first detection %v", dets[0])
+	}
+	if dets[1].Label != "person" || dets[1].BBox != image.Rect(40, 10, 120, 50) {
+		t.Errorf("Unexpected second detection %v", dets[1])
+	}
+
+	// A count larger than the outputs is limited to them
+	if dets := decodeSSDOutputs(boxes[:8], classes, scores, 10, labels, 0.5, image.Pt(200, 100)); len(dets) != 2 {
+		t.Errorf("Detections %v, want 2", dets)
+	}
+}
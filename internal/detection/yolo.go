@@ -0,0 +1,202 @@
+// Package detection contains detectors and postprocessing shared by the examples.
+package detection
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/nms"
+	"gocv.io/x/gocv"
+)
+
+// YoloDetection struct stores single detection information
+type YoloDetection struct {
+	DetClass int
+	DetName  string
+	DetConf  float32
+	DetBBox  image.Rectangle
+}
+
+func (d YoloDetection) String() string {
+	return fmt.Sprintf("Detected %d: %s, Confidence: %.2f%%, Bbox: %v", d.DetClass, d.DetName, d.DetConf*100, d.DetBBox)
+}
+
+// YoloDSlice stores a sortable slice of detections
+type YoloDSlice []YoloDetection
+
+func (yd YoloDSlice) Len() int           { return len(yd) }
+func (yd YoloDSlice) Less(i, j int) bool { return yd[i].DetConf < yd[j].DetConf }
+func (yd YoloDSlice) Swap(i, j int)      { yd[i], yd[j] = yd[j], yd[i] }
+
+// YoloOutputLayers finds names of the layers with type "Region" which are output layers
+// GetLayer argument (layer number) is starting from 1 since layer 0 is "_input"
+// In Yolo 4 configuration, these should be [yolo_139 yolo_150 yolo_161]
+func YoloOutputLayers(net *gocv.Net) (names []string) {
+	layers := net.GetLayerNames()
+	for i := 0; i < len(layers); i++ {
+		l := net.GetLayer(i + 1)
+		if l.GetType() == "Region" {
+			names = append(names, l.GetName())
+		}
+	}
+	return
+}
+
+// ExtractYoloPredictions extracts predictions from Yolo output layers, keeping detections with
+// confidence above confThr, and applies NMS with overlapping threshold ovrThr
+func ExtractYoloPredictions(detLayers []gocv.Mat, imgSize []int, classLabels []string,
+	confThr float32, ovrThr float64) YoloDSlice {
+	var yd YoloDSlice
+	frameWidth, frameHeight := imgSize[1], imgSize[0]
+
+	// Modified quote from:
+	// https://github.com/opencv/opencv/blob/8c25a8eb7b10fb50cda323ee6bec68aa1a9ce43c/samples/dnn/object_detection.py#L130
+	// Network produces output blob with a shape NxC where N is a number of
+	// detected objects (regions) and C is a number of classes + 5 where the first 4
+	// numbers are [center_x, center_y, width, height],
+	// and starting from the column 5 you get scores for each class
+	for _, prob := range detLayers {
+		for j := 0; j < prob.Rows(); j++ {
+			row := prob.RowRange(j, j+1)           // gocv.Mat
+			scores := row.ColRange(5, prob.Cols()) // gocv.Mat
+			_, confidence, _, maxLoc := gocv.MinMaxLoc(scores)
+			if confidence > confThr {
+				classID := maxLoc.X
+				className := classLabels[classID]
+				centerX := int(row.GetFloatAt(0, 0) * float32(frameWidth))
+				centerY := int(row.GetFloatAt(0, 1) * float32(frameHeight))
+				width := int(row.GetFloatAt(0, 2) * float32(frameWidth))
+				height := int(row.GetFloatAt(0, 3) * float32(frameHeight))
+				left := int(centerX - width/2)
+				top := int(centerY - height/2)
+				yd = append(yd, YoloDetection{classID, className, confidence,
+					image.Rect(left, top, left+width, top+height)})
+			}
+			scores.Close()
+			row.Close()
+		}
+	}
+
+	return NMS(yd, ovrThr)
+}
+
+// NMS applies hard non-maximum suppression to the detections, dropping those overlapping by more than
+// ovrThr of their area with a more confident detection, see package nms for other variants.
+// Returns detections ordered by descending confidence
+func NMS(yd YoloDSlice, ovrThr float64) YoloDSlice {
+	boxes := make([]nms.Box, len(yd))
+	for i, d := range yd {
+		boxes[i] = nms.Box{Rect: d.DetBBox, Score: d.DetConf, Class: d.DetClass}
+	}
+	var ydFiltered YoloDSlice
+	for _, i := range nms.HardOverlap(boxes, ovrThr) {
+		ydFiltered = append(ydFiltered, yd[i])
+	}
+	return ydFiltered
+}
+
+// Detection converts the Yolo detection to the detector independent form
+func (d YoloDetection) Detection() Detection {
+	return Detection{Label: d.DetName, Confidence: d.DetConf, BBox: d.DetBBox}
+}
+
+// Default Yolo 4 parameters
+const (
+	DefaultYoloBlobSize = 416
+	DefaultYoloConfThr  = 0.5 // Detection confidence threshold
+	DefaultYoloOvrThr   = 0.4 // Overlapping threshold for NMS
+)
+
+// YoloDetector runs a Yolo network on frames and implements Detector
+type YoloDetector struct {
+	Net          *gocv.Net
+	OutputLayers []string
+	ClassLabels  []string
+	BlobSize     int
+	ConfThr      float32
+	OvrThr       float64
+	Stats        *metrics.Collector // Optional, records preprocess, inference and postprocess timing
+}
+
+// NewYoloDetector creates a detector for the loaded network with default parameters
+func NewYoloDetector(net *gocv.Net, classLabels []string) *YoloDetector {
+	return &YoloDetector{
+		Net:          net,
+		OutputLayers: YoloOutputLayers(net),
+		ClassLabels:  classLabels,
+		BlobSize:     DefaultYoloBlobSize,
+		ConfThr:      DefaultYoloConfThr,
+		OvrThr:       DefaultYoloOvrThr,
+	}
+}
+
+// Detect runs the network on the image and returns detections after NMS, most confident first
+func (yd *YoloDetector) Detect(img gocv.Mat) ([]Detection, error) {
+	return yd.DetectScaled(img, 1)
+}
+
+// DetectScaled is Detect with the blob size scaled by s, implementing InputScaler
+func (yd *YoloDetector) DetectScaled(img gocv.Mat, s float64) ([]Detection, error) {
+	var dets []Detection
+	for _, d := range yd.detectYolo(img, s) {
+		dets = append(dets, d.Detection())
+	}
+	return dets, nil
+}
+
+// DetectYolo is Detect returning Yolo specific detections with class IDs
+func (yd *YoloDetector) DetectYolo(img gocv.Mat) YoloDSlice {
+	return yd.detectYolo(img, 1)
+}
+
+// Runs the network with the blob size scaled by s
+func (yd *YoloDetector) detectYolo(img gocv.Mat, s float64) YoloDSlice {
+	// Image conversion is required to create a blob as explained in
+	// https://github.com/hybridgroup/gocv/issues/658
+	stop := yd.stage("preprocess")
+	img2 := gocv.NewMat() // A copy used to create blob and perform detection
+	defer img2.Close()
+	img.ConvertTo(&img2, gocv.MatTypeCV32F)
+	size := yd.blobSize(s)
+	blob := gocv.BlobFromImage(img2, 1.0/255, image.Pt(size, size), gocv.NewScalar(0, 0, 0, 0), true, false)
+	defer blob.Close()
+	yd.Net.SetInput(blob, "")
+	stop()
+
+	// Yolo4 has 3 detection layers, need to forward to each one separately
+	stop = yd.stage("inference")
+	var detLayers []gocv.Mat
+	for _, l := range yd.OutputLayers {
+		detLayers = append(detLayers, yd.Net.Forward(l))
+	}
+	stop()
+
+	defer yd.stage("postprocess")()
+	res := ExtractYoloPredictions(detLayers, img.Size(), yd.ClassLabels, yd.ConfThr, yd.OvrThr)
+	for _, l := range detLayers {
+		l.Close()
+	}
+	return res
+}
+
+// Returns the blob size scaled by s, a multiple of the network stride of 32
+func (yd *YoloDetector) blobSize(s float64) int {
+	if s == 0 || s == 1 {
+		return yd.BlobSize
+	}
+	size := int(math.Round(float64(yd.BlobSize)*s/32)) * 32
+	if size < 32 {
+		size = 32
+	}
+	return size
+}
+
+// Starts timing of a stage if Stats is set
+func (yd *YoloDetector) stage(name string) func() {
+	if yd.Stats == nil {
+		return func() {}
+	}
+	return yd.Stats.Start(name)
+}
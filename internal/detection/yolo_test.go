@@ -0,0 +1,96 @@
+package detection
+
+import (
+	"image"
+	"math/rand"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+// Builds a float Mat from rows, e.g. a Yolo output layer from rows of
+// [center_x, center_y, width, height, objectness, scores...]
+func floatRows(rows [][]float32) gocv.Mat {
+	m := gocv.NewMatWithSize(len(rows), len(rows[0]), gocv.MatTypeCV32F)
+	for i, row := range rows {
+		for j, v := range row {
+			m.SetFloatAt(i, j, v)
+		}
+	}
+	return m
+}
+
+func TestExtractYoloPredictions(t *testing.T) {
+	labels := []string{"person", "dog", "cat"}
+	layer := floatRows([][]float32{
+		{0.5, 0.5, 0.2, 0.4, 0.9, 0.1, 0.9, 0.0},  // Dog in the center
+		{0.51, 0.5, 0.2, 0.4, 0.8, 0.1, 0.8, 0.0}, // Same dog, less confident
+		{0.1, 0.1, 0.1, 0.1, 0.9, 0.7, 0.0, 0.0},  // Person in the corner
+		{0.8, 0.8, 0.1, 0.1, 0.3, 0.0, 0.0, 0.3},  // Cat below the threshold
+	})
+	defer layer.Close()
+
+	yd := ExtractYoloPredictions([]gocv.Mat{layer}, []int{200, 400}, labels, 0.5, 0.4)
+	if len(yd) != 2 {
+		t.Fatalf("Expected 2 detections, got %d: %v", len(yd), yd)
+	}
+	if yd[0].DetName != "dog" || yd[1].DetName != "person" {
+		t.Errorf("Expected dog and person ordered by confidence, got %v", yd)
+	}
+	if want := image.Rect(160, 60, 240, 140); yd[0].DetBBox != want {
+		t.Errorf("Expected dog bbox %v, got %v", want, yd[0].DetBBox)
+	}
+}
+
+func TestNMS(t *testing.T) {
+	yd := YoloDSlice{
+		{DetName: "a", DetConf: 0.6, DetBBox: image.Rect(0, 0, 10, 10)},
+		{DetName: "b", DetConf: 0.9, DetBBox: image.Rect(1, 1, 11, 11)},
+		{DetName: "c", DetConf: 0.7, DetBBox: image.Rect(20, 20, 30, 30)},
+		{DetName: "d", DetConf: 0.5, DetBBox: image.Rect(8, 8, 18, 18)}, // Small overlap with b
+	}
+	got := NMS(yd, 0.4)
+	var names []string
+	for _, d := range got {
+		names = append(names, d.DetName)
+	}
+	if len(names) != 3 || names[0] != "b" || names[1] != "c" || names[2] != "d" {
+		t.Errorf("Expected [b c d], got %v", names)
+	}
+}
+
+// Yolo 4 output layers for a 416x416 blob have 10647 rows in total, 80 COCO classes
+func BenchmarkExtractYoloPredictions(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	labels := make([]string, 80)
+	rows := make([][]float32, 10647)
+	for i := range rows {
+		rows[i] = make([]float32, 5+len(labels))
+		for j := 0; j < 4; j++ {
+			rows[i][j] = rng.Float32()
+		}
+		// Few confident detections, like in real output
+		rows[i][5+rng.Intn(len(labels))] = rng.Float32() * 0.55
+	}
+	layer := floatRows(rows)
+	defer layer.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ExtractYoloPredictions([]gocv.Mat{layer}, []int{720, 1280}, labels, 0.5, 0.4)
+	}
+}
+
+func BenchmarkNMS(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	yd := make(YoloDSlice, 500)
+	for i := range yd {
+		x, y := rng.Intn(1200), rng.Intn(700)
+		yd[i] = YoloDetection{DetConf: rng.Float32(), DetBBox: image.Rect(x, y, x+20+rng.Intn(80), y+20+rng.Intn(80))}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NMS(append(YoloDSlice(nil), yd...), 0.4)
+	}
+}
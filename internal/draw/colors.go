@@ -0,0 +1,70 @@
+package draw
+
+import (
+	"hash/fnv"
+	"image/color"
+	"math"
+)
+
+// Palette holds distinct colors assigned to classes, chosen to be told apart on most scenes
+var Palette = []color.RGBA{
+	{230, 25, 75, 0},   // Red
+	{60, 180, 75, 0},   // Green
+	{255, 225, 25, 0},  // Yellow
+	{0, 130, 200, 0},   // Blue
+	{245, 130, 48, 0},  // Orange
+	{145, 30, 180, 0},  // Purple
+	{70, 240, 240, 0},  // Cyan
+	{240, 50, 230, 0},  // Magenta
+	{210, 245, 60, 0},  // Lime
+	{250, 190, 212, 0}, // Pink
+	{0, 128, 128, 0},   // Teal
+	{170, 110, 40, 0},  // Brown
+}
+
+// ClassColor returns the palette color of the class with given index
+func ClassColor(class int) color.RGBA {
+	if class < 0 {
+		class = -class
+	}
+	return Palette[class%len(Palette)]
+}
+
+// LabelColor returns a palette color for the label, the same for the same label in every run.
+// It is used for detectors which give labels without class indexes
+func LabelColor(label string) color.RGBA {
+	h := fnv.New32a()
+	h.Write([]byte(label))
+	return Palette[h.Sum32()%uint32(len(Palette))]
+}
+
+// Luminance returns the relative luminance of the color from 0 (black) to 1 (white),
+// as defined by WCAG 2 for sRGB colors
+func Luminance(c color.RGBA) float64 {
+	lin := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*lin(c.R) + 0.7152*lin(c.G) + 0.0722*lin(c.B)
+}
+
+// ContrastText returns Black or White, whichever has the higher contrast ratio with the background
+func ContrastText(bg color.RGBA) color.RGBA {
+	l := Luminance(bg)
+	if (l+0.05)/0.05 > 1.05/(l+0.05) {
+		return Black
+	}
+	return White
+}
+
+// WithColor returns the style with lines and label background of the given color
+// and the text color readable on it
+func (st Style) WithColor(c color.RGBA) Style {
+	st.LineColor = c
+	st.BgColor = c
+	st.TextColor = ContrastText(c)
+	return st
+}
@@ -0,0 +1,161 @@
+// Package draw contains colors and annotation primitives shared by the examples,
+// so that all of them label images in a consistent way.
+package draw
+
+import (
+	"image"
+	"image/color"
+
+	"gocv.io/x/gocv"
+)
+
+// Colors used by the examples
+var (
+	White    = color.RGBA{255, 255, 255, 0}
+	Black    = color.RGBA{0, 0, 0, 0}
+	Green    = color.RGBA{0, 255, 0, 0}
+	DarkBlue = color.RGBA{0, 0, 127, 0}
+	Red      = color.RGBA{255, 0, 0, 0}
+)
+
+// Style holds font, color and line settings of the annotations
+type Style struct {
+	Font          gocv.HersheyFont
+	FontScale     float64
+	TextThickness int
+	LineThickness int
+	Padding       int // Space between text and the edges of its background box
+	TextColor     color.RGBA
+	BgColor       color.RGBA
+	LineColor     color.RGBA
+}
+
+// DefaultStyle is used for labeling detections
+var DefaultStyle = Style{
+	Font:          gocv.FontHersheySimplex,
+	FontScale:     0.6,
+	TextThickness: 1,
+	LineThickness: 1,
+	Padding:       3,
+	TextColor:     White,
+	BgColor:       DarkBlue,
+	LineColor:     Green,
+}
+
+// BannerStyle is used for large captions on top of video frames
+var BannerStyle = Style{
+	Font:          gocv.FontHersheySimplex,
+	FontScale:     1,
+	TextThickness: 2,
+	LineThickness: 2,
+	Padding:       9,
+	TextColor:     White,
+	BgColor:       Black,
+	LineColor:     White,
+}
+
+// TextSize returns width and height of the text in pixels, without padding
+func (st Style) TextSize(text string) image.Point {
+	return gocv.GetTextSize(text, st.Font, st.FontScale, st.TextThickness)
+}
+
+// TextWithBackground draws text on a filled background box. Position pt is the bottom-left corner
+// of the box. Returns the box rectangle
+func TextWithBackground(img *gocv.Mat, text string, pt image.Point, st Style) image.Rectangle {
+	textSize := st.TextSize(text)
+	box := image.Rect(pt.X, pt.Y-textSize.Y-2*st.Padding, pt.X+textSize.X+2*st.Padding, pt.Y)
+	gocv.Rectangle(img, box, st.BgColor, -1)
+	gocv.PutText(img, text, image.Pt(pt.X+st.Padding, pt.Y-st.Padding), st.Font, st.FontScale,
+		st.TextColor, st.TextThickness)
+	return box
+}
+
+// LabelBox draws a bounding box with the label on top of its top-left corner.
+// If there is no room above the box, the label is placed inside it
+func LabelBox(img *gocv.Mat, rect image.Rectangle, label string, st Style) {
+	gocv.Rectangle(img, rect, st.LineColor, st.LineThickness)
+	if label == "" {
+		return
+	}
+	pt := rect.Min
+	if pt.Y-st.TextSize(label).Y-2*st.Padding < 0 {
+		pt.Y += st.TextSize(label).Y + 2*st.Padding
+	}
+	TextWithBackground(img, label, pt, st)
+}
+
+// LegendEntry is a single line of a legend: a color sample followed by a text
+type LegendEntry struct {
+	Color color.RGBA
+	Text  string
+}
+
+// Legend draws a list of color samples with descriptions on a common background,
+// starting from the top-left corner pt
+func Legend(img *gocv.Mat, entries []LegendEntry, pt image.Point, st Style) {
+	if len(entries) == 0 {
+		return
+	}
+
+	lineHeight, maxWidth := 0, 0
+	for _, e := range entries {
+		size := st.TextSize(e.Text)
+		if size.Y > lineHeight {
+			lineHeight = size.Y
+		}
+		if size.X > maxWidth {
+			maxWidth = size.X
+		}
+	}
+	lineHeight += 2 * st.Padding
+	sample := lineHeight - 2*st.Padding
+
+	gocv.Rectangle(img, image.Rect(pt.X, pt.Y, pt.X+sample+maxWidth+3*st.Padding, pt.Y+lineHeight*len(entries)),
+		st.BgColor, -1)
+	for i, e := range entries {
+		top := pt.Y + i*lineHeight + st.Padding
+		gocv.Rectangle(img, image.Rect(pt.X+st.Padding, top, pt.X+st.Padding+sample, top+sample), e.Color, -1)
+		gocv.PutText(img, e.Text, image.Pt(pt.X+sample+2*st.Padding, top+sample), st.Font, st.FontScale,
+			st.TextColor, st.TextThickness)
+	}
+}
+
+// Crosshair draws a cross of the given size (in pixels, from the center to the end of each arm)
+// with a circle around the center
+func Crosshair(img *gocv.Mat, center image.Point, size int, st Style) {
+	gocv.Line(img, image.Pt(center.X-size, center.Y), image.Pt(center.X+size, center.Y), st.LineColor, st.LineThickness)
+	gocv.Line(img, image.Pt(center.X, center.Y-size), image.Pt(center.X, center.Y+size), st.LineColor, st.LineThickness)
+	gocv.Circle(img, center, size/2, st.LineColor, st.LineThickness)
+}
+
+// Outline draws a closed polygon through the points, e.g. a pattern seen in perspective
+func Outline(img *gocv.Mat, pts []image.Point, st Style) {
+	if len(pts) < 2 {
+		return
+	}
+	pv := gocv.NewPointsVectorFromPoints([][]image.Point{pts})
+	defer pv.Close()
+	gocv.Polylines(img, pv, true, st.LineColor, st.LineThickness)
+}
+
+// MessageBox creates and returns an image with plain background and specified text lines
+// Text is centered horizontally and vertically; lineHeight is the distance between lines
+// relative to the text height
+func MessageBox(lines []string, lineHeight float64, width, height int, st Style) (img gocv.Mat) {
+	img = gocv.NewMatWithSize(height, width, gocv.MatTypeCV8UC3)
+	gocv.Rectangle(&img, image.Rect(0, 0, width, height), st.BgColor, -1)
+
+	if len(lines) > 0 {
+		textHeightPixels := st.TextSize(lines[0]).Y
+		lineHeightPixels := int(float64(textHeightPixels) * lineHeight)
+		totalTextHeight := lineHeightPixels*(len(lines)-1) + textHeightPixels
+		startY := (height-totalTextHeight)/2 + textHeightPixels
+
+		for i, s := range lines {
+			lineWidthPixels := st.TextSize(s).X
+			gocv.PutText(&img, s, image.Pt((width-lineWidthPixels)/2, startY+i*lineHeightPixels),
+				st.Font, st.FontScale, st.TextColor, st.TextThickness)
+		}
+	}
+	return img
+}
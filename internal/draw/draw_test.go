@@ -0,0 +1,58 @@
+package draw
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/marchevska/gocv-examples/internal/testutil"
+	"gocv.io/x/gocv"
+)
+
+func TestTextWithBackground(t *testing.T) {
+	img := testutil.SolidImage(t, 200, 100, gocv.NewScalar(0, 255, 0, 0))
+	box := TextWithBackground(&img, "Label", image.Pt(10, 50), DefaultStyle)
+
+	if box.Min.X != 10 || box.Max.Y != 50 || box.Dx() <= 2*DefaultStyle.Padding {
+		t.Errorf("Unexpected box %v", box)
+	}
+	// Corner of the box is filled with the background color (BGR order)
+	v := img.GetVecbAt(box.Max.Y-1, box.Min.X)
+	if v[0] != DefaultStyle.BgColor.B || v[1] != DefaultStyle.BgColor.G || v[2] != DefaultStyle.BgColor.R {
+		t.Errorf("Expected background color at the box corner, got %v", v)
+	}
+}
+
+func TestMessageBox(t *testing.T) {
+	img := MessageBox([]string{"gocv", "examples"}, 2, 160, 120, DefaultStyle)
+	defer img.Close()
+	testutil.AssertGolden(t, "message_box.png", img, 0, 0)
+}
+
+func TestContrastText(t *testing.T) {
+	tests := []struct {
+		bg   color.RGBA
+		want color.RGBA
+	}{
+		{White, Black},
+		{Black, White},
+		{DarkBlue, White},
+		{Green, Black},
+		{color.RGBA{255, 225, 25, 0}, Black}, // Yellow
+		{color.RGBA{145, 30, 180, 0}, White}, // Purple
+	}
+	for _, tt := range tests {
+		if got := ContrastText(tt.bg); got != tt.want {
+			t.Errorf("ContrastText(%v) = %v, want %v", tt.bg, got, tt.want)
+		}
+	}
+}
+
+func TestLabelColor(t *testing.T) {
+	if LabelColor("person") != LabelColor("person") {
+		t.Error("Expected the same color for the same label")
+	}
+	if ClassColor(1) == ClassColor(2) || ClassColor(1) != ClassColor(1+len(Palette)) {
+		t.Error("Expected distinct colors of neighbouring classes repeating after the palette")
+	}
+}
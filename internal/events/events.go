@@ -0,0 +1,178 @@
+// Package events publishes detections and tracker events to a Kafka topic for analytics pipelines
+// and to a Redis channel for dashboards. Each event is a JSON message, e.g.
+//
+//	{"type":"detections","camera":"cam0","time":"2024-01-31T12:00:00.04Z","frame":1,"detections":[...]}
+//	{"type":"crossing","camera":"cam0","time":"2024-01-31T12:00:02.5Z","frame":63,"track":7,"direction":"in"}
+//
+// Kafka messages are keyed by the camera ID, so that the events of a camera stay in order in one
+// partition. With Redis, the latest annotated frame can also be kept as a JPEG in a key, so that
+// a dashboard can poll it, e.g. with GET gocv:cam0:frame, without touching the video pipeline
+package events
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/storage"
+	"gocv.io/x/gocv"
+)
+
+// Defaults of the options
+const (
+	DefaultTopic         = "gocv-events"
+	DefaultChannel       = "gocv-events"
+	DefaultFrameInterval = time.Second
+)
+
+// Frame keys expire after this time, so that a stopped pipeline does not leave a stale frame
+const frameTTL = time.Minute
+
+// Types of events
+const (
+	TypeDetections = "detections" // Objects found on a frame
+	TypeCrossing   = "crossing"   // A track crossed a counting line
+)
+
+// Event is a message published for a frame
+type Event struct {
+	Type       string                `json:"type"`
+	Camera     string                `json:"camera"`
+	Time       time.Time             `json:"time"`
+	Frame      int                   `json:"frame"`
+	Detections []detection.Detection `json:"detections,omitempty"`
+	Track      int                   `json:"track,omitempty"`     // ID of the track of tracker events
+	Direction  string                `json:"direction,omitempty"` // "in" or "out" of crossings
+}
+
+// Options holds the Kafka and Redis parameters given by flags
+type Options struct {
+	Brokers       config.StringList
+	Topic         string
+	Redis         string
+	Channel       string
+	FrameKey      string
+	FrameInterval time.Duration
+	Camera        string
+}
+
+// RegisterFlags adds -kafka, -kafka-topic, -redis, -redis-channel, -redis-frame-key,
+// -redis-frame-interval and -camera-id flags to fs
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	fs.Var(&o.Brokers, "kafka", "Kafka bootstrap broker host:port publishing events, repeatable; no Kafka if empty")
+	fs.StringVar(&o.Topic, "kafka-topic", DefaultTopic, "Kafka topic of the events")
+	fs.StringVar(&o.Redis, "redis", "", "Redis server host:port or redis://[:PASSWORD@]HOST[:PORT][/DB] publishing events; no Redis if empty")
+	fs.StringVar(&o.Channel, "redis-channel", DefaultChannel, "Redis channel of the events, none if empty")
+	fs.StringVar(&o.FrameKey, "redis-frame-key", "", `Redis key of the latest annotated frame as JPEG, "gocv:CAMERA:frame" if empty, "-" for none`)
+	fs.DurationVar(&o.FrameInterval, "redis-frame-interval", DefaultFrameInterval, "Shortest time between updates of the frame key")
+	fs.StringVar(&o.Camera, "camera-id", "", "Camera ID keying the events, made from the input if empty")
+}
+
+// Open creates the publisher of the options, closed by sd. It is nil without Kafka brokers and a Redis server.
+// The camera is named after the input unless the options name it
+func (o *Options) Open(sd *shutdown.Handler, input string) (*Publisher, error) {
+	if len(o.Brokers) == 0 && o.Redis == "" {
+		return nil, nil
+	}
+	camera := o.Camera
+	if camera == "" {
+		camera = storage.CameraName(input)
+	}
+	p := &Publisher{Camera: camera}
+	if len(o.Brokers) > 0 {
+		for _, b := range o.Brokers {
+			if _, _, err := net.SplitHostPort(b); err != nil {
+				return nil, fmt.Errorf("Kafka broker should be host:port: %q", b)
+			}
+		}
+		if o.Topic == "" {
+			return nil, fmt.Errorf("Kafka topic should not be empty")
+		}
+		p.producer = NewKafkaProducer(o.Brokers, o.Topic)
+		logging.Infof("Publishing events to Kafka topic %s as camera %s", o.Topic, camera)
+	}
+	if o.Redis != "" {
+		addr, password, db, err := ParseRedisURL(o.Redis)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.redis = NewRedisClient(addr, password, db)
+		p.channel, p.frameKey, p.frameInterval = o.Channel, o.FrameKey, o.FrameInterval
+		switch p.frameKey {
+		case "":
+			p.frameKey = "gocv:" + camera + ":frame"
+		case "-":
+			p.frameKey = ""
+		}
+		logging.Infof("Publishing events to Redis channel %q and frames to key %q", p.channel, p.frameKey)
+	}
+	sd.OnClose("events", p.Close)
+	return p, nil
+}
+
+// Publisher publishes events and frames of a camera in the background
+type Publisher struct {
+	Camera        string
+	producer      *KafkaProducer
+	redis         *RedisClient
+	channel       string
+	frameKey      string
+	frameInterval time.Duration
+	lastFrame     time.Time
+}
+
+// Publish sends the event with the camera of the publisher and, if it has none, the current time
+func (p *Publisher) Publish(e Event) {
+	e.Camera = p.Camera
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	value, err := json.Marshal(e)
+	if err != nil {
+		logging.Errorf("Cannot encode event: %v", err)
+		return
+	}
+	if p.producer != nil {
+		p.producer.Send(Message{Key: []byte(p.Camera), Value: value, Time: e.Time})
+	}
+	if p.redis != nil && p.channel != "" {
+		p.redis.Do([]byte("PUBLISH"), []byte(p.channel), value)
+	}
+}
+
+// Frame stores the annotated frame as JPEG in the frame key, at most once per the frame interval.
+// Frames not sent yet are replaced by newer ones
+func (p *Publisher) Frame(img gocv.Mat) {
+	if p.redis == nil || p.frameKey == "" || time.Since(p.lastFrame) < p.frameInterval {
+		return
+	}
+	p.lastFrame = time.Now()
+	buf, err := gocv.IMEncode(gocv.JPEGFileExt, img)
+	if err != nil {
+		logging.Warnf("Cannot encode frame: %v", err)
+		return
+	}
+	jpeg := append([]byte(nil), buf.GetBytes()...)
+	buf.Close()
+	p.redis.SetLatest([]byte("SET"), []byte(p.frameKey), jpeg,
+		[]byte("EX"), []byte(strconv.Itoa(int(frameTTL.Seconds()))))
+}
+
+// Close sends the queued events and stops the publisher
+func (p *Publisher) Close() error {
+	if p.producer != nil {
+		p.producer.Close()
+	}
+	if p.redis != nil {
+		p.redis.Close()
+	}
+	return nil
+}
@@ -0,0 +1,531 @@
+package events
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/logging"
+)
+
+// Kafka API keys and the versions used, supported by brokers since Kafka 1.0
+const (
+	apiProduce      = 0
+	apiMetadata     = 3
+	produceVersion  = 3
+	metadataVersion = 4
+)
+
+const (
+	kafkaClientID   = "gocv-examples"
+	kafkaQueueSize  = 1024 // Messages waiting to be sent, more are dropped
+	kafkaBatchSize  = 100  // Messages sent in one request at most
+	kafkaLinger     = 100 * time.Millisecond
+	kafkaTimeout    = 10 * time.Second
+	kafkaRetries    = 3
+	kafkaBackoff    = 500 * time.Millisecond
+	kafkaMaxMessage = 64 << 20 // Largest response accepted
+)
+
+// Message is a record to publish
+type Message struct {
+	Key   []byte
+	Value []byte
+	Time  time.Time
+}
+
+// KafkaProducer publishes messages to a Kafka topic in the background. Messages are batched for
+// a short time, partitioned by the murmur2 hash of their key like the Java client does, so that
+// messages with the same key keep their order, and written to the partition leaders with acks from
+// all in-sync replicas. Failed requests are retried after refreshing the leaders; messages that still
+// fail are logged and dropped. It speaks plain TCP, without TLS or SASL
+type KafkaProducer struct {
+	brokers []string
+	topic   string
+	queue   chan Message
+	wg      sync.WaitGroup
+
+	// Used by the sending goroutine only
+	addrs   map[int32]string // Broker addresses by node ID
+	leaders []int32          // Leader of each partition
+	conns   map[int32]*kafkaConn
+}
+
+// NewKafkaProducer creates a producer to the topic through the bootstrap brokers given as host:port
+// and starts sending
+func NewKafkaProducer(brokers []string, topic string) *KafkaProducer {
+	p := &KafkaProducer{brokers: brokers, topic: topic, queue: make(chan Message, kafkaQueueSize),
+		conns: map[int32]*kafkaConn{}}
+	p.wg.Add(1)
+	go p.run()
+	return p
+}
+
+// Send queues the message, dropping it if the queue is full
+func (p *KafkaProducer) Send(m Message) {
+	select {
+	case p.queue <- m:
+	default:
+		logging.Warnf("Kafka message dropped, %d messages are waiting", kafkaQueueSize)
+	}
+}
+
+// Close sends the queued messages and stops the producer
+func (p *KafkaProducer) Close() error {
+	close(p.queue)
+	p.wg.Wait()
+	return nil
+}
+
+// Sends queued messages in batches
+func (p *KafkaProducer) run() {
+	defer p.wg.Done()
+	defer p.closeConns()
+	for m := range p.queue {
+		batch := []Message{m}
+		linger := time.NewTimer(kafkaLinger)
+	collect:
+		for len(batch) < kafkaBatchSize {
+			select {
+			case m, ok := <-p.queue:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, m)
+			case <-linger.C:
+				break collect
+			}
+		}
+		linger.Stop()
+
+		for attempt := 0; ; attempt++ {
+			err := p.produce(batch)
+			if err == nil {
+				break
+			}
+			p.leaders = nil
+			p.closeConns()
+			if attempt == kafkaRetries {
+				logging.Errorf("Cannot publish %d messages to Kafka: %v", len(batch), err)
+				break
+			}
+			logging.Warnf("Error publishing to Kafka, retrying: %v", err)
+			time.Sleep(kafkaBackoff << attempt)
+		}
+	}
+}
+
+// Writes the messages to the leaders of their partitions
+func (p *KafkaProducer) produce(msgs []Message) error {
+	if p.leaders == nil {
+		if err := p.refresh(); err != nil {
+			return err
+		}
+	}
+	byLeader := map[int32]map[int32][]Message{}
+	for _, m := range msgs {
+		part := partition(m.Key, len(p.leaders))
+		leader := p.leaders[part]
+		if byLeader[leader] == nil {
+			byLeader[leader] = map[int32][]Message{}
+		}
+		byLeader[leader][part] = append(byLeader[leader][part], m)
+	}
+	for leader, parts := range byLeader {
+		c, err := p.conn(leader)
+		if err != nil {
+			return err
+		}
+		resp, err := c.roundTrip(apiProduce, produceVersion, produceRequest(p.topic, parts))
+		if err != nil {
+			return err
+		}
+		if err := parseProduceResponse(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the connection to the broker, connecting if needed
+func (p *KafkaProducer) conn(node int32) (*kafkaConn, error) {
+	if c := p.conns[node]; c != nil {
+		return c, nil
+	}
+	addr, ok := p.addrs[node]
+	if !ok {
+		return nil, fmt.Errorf("Unknown Kafka broker %d", node)
+	}
+	c, err := dialKafka(addr)
+	if err != nil {
+		return nil, err
+	}
+	p.conns[node] = c
+	return c, nil
+}
+
+func (p *KafkaProducer) closeConns() {
+	for node, c := range p.conns {
+		c.Close()
+		delete(p.conns, node)
+	}
+}
+
+// Reads the brokers and partition leaders of the topic from the first bootstrap broker that answers
+func (p *KafkaProducer) refresh() error {
+	var err error
+	for _, addr := range p.brokers {
+		var c *kafkaConn
+		if c, err = dialKafka(addr); err != nil {
+			continue
+		}
+		var resp []byte
+		resp, err = c.roundTrip(apiMetadata, metadataVersion, metadataRequest(p.topic))
+		c.Close()
+		if err != nil {
+			continue
+		}
+		p.addrs, p.leaders, err = parseMetadataResponse(resp, p.topic)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// partition returns the partition of the key: murmur2 of the key like the Java client,
+// or partition 0 for messages without a key
+func partition(key []byte, partitions int) int32 {
+	if key == nil {
+		return 0
+	}
+	return int32((murmur2(key) & 0x7fffffff) % uint32(partitions))
+}
+
+// murmur2 is the 32-bit MurmurHash2 with the seed of Kafka's default partitioner
+func murmur2(data []byte) uint32 {
+	const (
+		seed = 0x9747b28c
+		m    = 0x5bd1e995
+		r    = 24
+	)
+	h := uint32(seed) ^ uint32(len(data))
+	for len(data) >= 4 {
+		k := binary.LittleEndian.Uint32(data)
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+		data = data[4:]
+	}
+	switch len(data) {
+	case 3:
+		h ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(data[0])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return h
+}
+
+// kafkaConn is a connection to a broker sending one request at a time
+type kafkaConn struct {
+	conn        net.Conn
+	correlation int32
+}
+
+func dialKafka(addr string) (*kafkaConn, error) {
+	conn, err := net.DialTimeout("tcp", addr, kafkaTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot connect to Kafka broker: %v", err)
+	}
+	return &kafkaConn{conn: conn}, nil
+}
+
+// roundTrip sends the request and returns the body of the response
+func (c *kafkaConn) roundTrip(apiKey, version int16, body []byte) ([]byte, error) {
+	c.correlation++
+	var w kafkaWriter
+	w.int32(0) // Size, set below
+	w.int16(apiKey)
+	w.int16(version)
+	w.int32(c.correlation)
+	w.string(kafkaClientID)
+	w.Write(body)
+	req := w.Bytes()
+	binary.BigEndian.PutUint32(req, uint32(len(req)-4))
+
+	c.conn.SetDeadline(time.Now().Add(kafkaTimeout))
+	if _, err := c.conn.Write(req); err != nil {
+		return nil, err
+	}
+	var size [4]byte
+	if _, err := io.ReadFull(c.conn, size[:]); err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n < 4 || n > kafkaMaxMessage {
+		return nil, fmt.Errorf("Invalid Kafka response size %d", n)
+	}
+	resp := make([]byte, n)
+	if _, err := io.ReadFull(c.conn, resp); err != nil {
+		return nil, err
+	}
+	if corr := int32(binary.BigEndian.Uint32(resp)); corr != c.correlation {
+		return nil, fmt.Errorf("Kafka response %d does not match request %d", corr, c.correlation)
+	}
+	return resp[4:], nil
+}
+
+func (c *kafkaConn) Close() error {
+	return c.conn.Close()
+}
+
+// metadataRequest asks for the partitions of the topic, creating it if the broker allows
+func metadataRequest(topic string) []byte {
+	var w kafkaWriter
+	w.int32(1)
+	w.string(topic)
+	w.int8(1) // allow_auto_topic_creation
+	return w.Bytes()
+}
+
+// parseMetadataResponse returns the broker addresses and the leaders of the partitions of the topic
+func parseMetadataResponse(data []byte, topic string) (map[int32]string, []int32, error) {
+	r := kafkaReader{data: data}
+	r.int32() // throttle_time_ms
+	addrs := map[int32]string{}
+	for i := r.int32(); i > 0 && r.err == nil; i-- {
+		node := r.int32()
+		host := r.string()
+		port := r.int32()
+		r.string() // rack
+		addrs[node] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	r.string() // cluster_id
+	r.int32()  // controller_id
+	var leaders []int32
+	var topicErr int16 = -1
+	for i := r.int32(); i > 0 && r.err == nil; i-- {
+		code := r.int16()
+		name := r.string()
+		r.int8() // is_internal
+		var tl []int32
+		for j := r.int32(); j > 0 && r.err == nil; j-- {
+			pcode := r.int16()
+			index := r.int32()
+			leader := r.int32()
+			r.int32s() // replica_nodes
+			r.int32s() // isr_nodes
+			if code == 0 && pcode != 0 {
+				code = pcode
+			}
+			for int(index) >= len(tl) {
+				tl = append(tl, -1)
+			}
+			tl[index] = leader
+		}
+		if name == topic {
+			topicErr, leaders = code, tl
+		}
+	}
+	switch {
+	case r.err != nil:
+		return nil, nil, r.err
+	case topicErr == -1:
+		return nil, nil, fmt.Errorf("Kafka topic %s not found", topic)
+	case topicErr != 0:
+		return nil, nil, fmt.Errorf("Kafka topic %s: %v", topic, kafkaError(topicErr))
+	case len(leaders) == 0:
+		return nil, nil, fmt.Errorf("Kafka topic %s has no partitions", topic)
+	}
+	for _, l := range leaders {
+		if l < 0 {
+			return nil, nil, fmt.Errorf("Kafka topic %s: %v", topic, kafkaError(5))
+		}
+	}
+	return addrs, leaders, nil
+}
+
+// produceRequest writes the messages of each partition of the topic as a record batch
+func produceRequest(topic string, parts map[int32][]Message) []byte {
+	var w kafkaWriter
+	w.int16(-1) // transactional_id, null
+	w.int16(-1) // acks from all in-sync replicas
+	w.int32(int32(kafkaTimeout / time.Millisecond))
+	w.int32(1)
+	w.string(topic)
+	w.int32(int32(len(parts)))
+	for part, msgs := range parts {
+		w.int32(part)
+		batch := recordBatch(msgs)
+		w.int32(int32(len(batch)))
+		w.Write(batch)
+	}
+	return w.Bytes()
+}
+
+// parseProduceResponse returns the first error of a partition
+func parseProduceResponse(data []byte) error {
+	r := kafkaReader{data: data}
+	for i := r.int32(); i > 0 && r.err == nil; i-- {
+		r.string() // name
+		for j := r.int32(); j > 0 && r.err == nil; j-- {
+			r.int32() // index
+			code := r.int16()
+			r.int64() // base_offset
+			r.int64() // log_append_time_ms
+			if code != 0 && r.err == nil {
+				return kafkaError(code)
+			}
+		}
+	}
+	return r.err
+}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// recordBatch encodes the messages as a record batch of message format v2
+func recordBatch(msgs []Message) []byte {
+	first := msgs[0].Time.UnixNano() / int64(time.Millisecond)
+	max := first
+	var records kafkaWriter
+	for i, m := range msgs {
+		ts := m.Time.UnixNano() / int64(time.Millisecond)
+		if ts > max {
+			max = ts
+		}
+		var rec kafkaWriter
+		rec.int8(0) // attributes
+		rec.varint(ts - first)
+		rec.varint(int64(i))
+		if m.Key == nil {
+			rec.varint(-1)
+		} else {
+			rec.varint(int64(len(m.Key)))
+			rec.Write(m.Key)
+		}
+		rec.varint(int64(len(m.Value)))
+		rec.Write(m.Value)
+		rec.varint(0) // headers
+		records.varint(int64(rec.Len()))
+		records.Write(rec.Bytes())
+	}
+
+	// Fields covered by the CRC
+	var body kafkaWriter
+	body.int16(0) // attributes: no compression, create time
+	body.int32(int32(len(msgs) - 1))
+	body.int64(first)
+	body.int64(max)
+	body.int64(-1) // producer_id
+	body.int16(-1) // producer_epoch
+	body.int32(-1) // base_sequence
+	body.int32(int32(len(msgs)))
+	body.Write(records.Bytes())
+
+	var w kafkaWriter
+	w.int64(0)                             // base_offset
+	w.int32(int32(4 + 1 + 4 + body.Len())) // batch_length: the bytes after it
+	w.int32(-1)                            // partition_leader_epoch
+	w.int8(2)                              // magic
+	w.int32(int32(crc32.Checksum(body.Bytes(), castagnoli)))
+	w.Write(body.Bytes())
+	return w.Bytes()
+}
+
+// kafkaError is an error code of the Kafka protocol
+type kafkaError int16
+
+var kafkaErrors = map[kafkaError]string{
+	3:  "unknown topic or partition",
+	5:  "leader not available",
+	6:  "not leader for partition",
+	7:  "request timed out",
+	10: "message too large",
+	17: "invalid topic",
+	19: "not enough replicas",
+	29: "topic authorization failed",
+	31: "cluster authorization failed",
+}
+
+func (e kafkaError) Error() string {
+	if s, ok := kafkaErrors[e]; ok {
+		return s
+	}
+	return fmt.Sprintf("Kafka error %d", int16(e))
+}
+
+// kafkaWriter encodes protocol primitives in big-endian order
+type kafkaWriter struct {
+	bytes.Buffer
+}
+
+func (w *kafkaWriter) int8(v int8)   { w.WriteByte(byte(v)) }
+func (w *kafkaWriter) int16(v int16) { binary.Write(w, binary.BigEndian, v) }
+func (w *kafkaWriter) int32(v int32) { binary.Write(w, binary.BigEndian, v) }
+func (w *kafkaWriter) int64(v int64) { binary.Write(w, binary.BigEndian, v) }
+
+func (w *kafkaWriter) string(s string) {
+	w.int16(int16(len(s)))
+	w.WriteString(s)
+}
+
+// varint writes the zigzag varint of records
+func (w *kafkaWriter) varint(v int64) {
+	var buf [binary.MaxVarintLen64]byte
+	w.Write(buf[:binary.PutVarint(buf[:], v)])
+}
+
+// kafkaReader decodes protocol primitives, keeping the first error
+type kafkaReader struct {
+	data []byte
+	err  error
+}
+
+var errShortResponse = errors.New("Kafka response is too short")
+
+func (r *kafkaReader) next(n int) []byte {
+	if r.err != nil || n > len(r.data) {
+		r.err = errShortResponse
+		return make([]byte, n)
+	}
+	b := r.data[:n]
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *kafkaReader) int8() int8   { return int8(r.next(1)[0]) }
+func (r *kafkaReader) int16() int16 { return int16(binary.BigEndian.Uint16(r.next(2))) }
+func (r *kafkaReader) int32() int32 { return int32(binary.BigEndian.Uint32(r.next(4))) }
+func (r *kafkaReader) int64() int64 { return int64(binary.BigEndian.Uint64(r.next(8))) }
+
+// string reads a string, empty for null
+func (r *kafkaReader) string() string {
+	n := r.int16()
+	if n < 0 {
+		return ""
+	}
+	return string(r.next(int(n)))
+}
+
+func (r *kafkaReader) int32s() {
+	for n := r.int32(); n > 0 && r.err == nil; n-- {
+		r.int32()
+	}
+}
@@ -0,0 +1,225 @@
+package events
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Values of the murmur2 tests of the Kafka Java client
+func TestMurmur2(t *testing.T) {
+	tests := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	}
+	for s, want := range tests {
+		if got := int32(murmur2([]byte(s))); got != want {
+			t.Errorf("murmur2(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
+
+func TestPartition(t *testing.T) {
+	if p := partition(nil, 3); p != 0 {
+		t.Errorf("partition(nil) = %d, want 0", p)
+	}
+	for _, key := range []string{"cam0", "cam1", "10.0.0.5"} {
+		p := partition([]byte(key), 3)
+		if p < 0 || p >= 3 || p != partition([]byte(key), 3) {
+			t.Errorf("partition(%q) = %d", key, p)
+		}
+	}
+}
+
+// decodeBatch returns the keys and values of a record batch, checking its length and CRC
+func decodeBatch(t *testing.T, batch []byte) (keys, values []string) {
+	t.Helper()
+	r := kafkaReader{data: batch}
+	r.int64()
+	if n := r.int32(); int(n) != len(r.data) {
+		t.Fatalf("Batch length %d, %d bytes follow", n, len(r.data))
+	}
+	r.int32()
+	if magic := r.int8(); magic != 2 {
+		t.Fatalf("Magic %d", magic)
+	}
+	crc := uint32(r.int32())
+	if got := crc32.Checksum(r.data, castagnoli); got != crc {
+		t.Fatalf("CRC %x, want %x", crc, got)
+	}
+	r.next(2 + 4 + 8 + 8 + 8 + 2 + 4)
+	count := r.int32()
+	for i := int32(0); i < count; i++ {
+		length, n := binary.Varint(r.data)
+		rec := r.next(n + int(length))[n:]
+		rec = rec[1:]            // attributes
+		for j := 0; j < 2; j++ { // timestamp and offset deltas
+			_, n = binary.Varint(rec)
+			rec = rec[n:]
+		}
+		var fields [2]string
+		for j := range fields {
+			l, n := binary.Varint(rec)
+			rec = rec[n:]
+			if l >= 0 {
+				fields[j] = string(rec[:l])
+				rec = rec[l:]
+			}
+		}
+		keys, values = append(keys, fields[0]), append(values, fields[1])
+	}
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	return keys, values
+}
+
+func TestRecordBatch(t *testing.T) {
+	now := time.Now()
+	msgs := []Message{
+		{Key: []byte("cam0"), Value: []byte(`{"frame":1}`), Time: now},
+		{Value: []byte("no key"), Time: now.Add(time.Second)},
+	}
+	keys, values := decodeBatch(t, recordBatch(msgs))
+	if len(keys) != 2 || keys[0] != "cam0" || values[0] != `{"frame":1}` || keys[1] != "" || values[1] != "no key" {
+		t.Errorf("Decoded keys %q, values %q", keys, values)
+	}
+}
+
+// fakeBroker answers metadata requests with one partition led by itself and records produced values
+type fakeBroker struct {
+	ln     net.Listener
+	mu     sync.Mutex
+	values []string
+}
+
+func (fb *fakeBroker) serve(t *testing.T) {
+	for {
+		conn, err := fb.ln.Accept()
+		if err != nil {
+			return
+		}
+		go fb.handle(t, conn)
+	}
+}
+
+func (fb *fakeBroker) handle(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		req := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+		r := kafkaReader{data: req}
+		api, _, corr := r.int16(), r.int16(), r.int32()
+		r.string() // client_id
+
+		var w kafkaWriter
+		w.int32(0)
+		w.int32(corr)
+		switch api {
+		case apiMetadata:
+			host, port, _ := net.SplitHostPort(fb.ln.Addr().String())
+			p, _ := strconv.Atoi(port)
+			w.int32(0) // throttle
+			w.int32(1)
+			w.int32(1)
+			w.string(host)
+			w.int32(int32(p))
+			w.int16(-1) // rack
+			w.int16(-1) // cluster_id
+			w.int32(1)
+			w.int32(1)
+			w.int16(0)
+			w.string("events")
+			w.int8(0)
+			w.int32(1)
+			w.int16(0)
+			w.int32(0) // partition
+			w.int32(1) // leader
+			w.int32(0)
+			w.int32(0)
+		case apiProduce:
+			r.int16() // transactional_id
+			r.int16() // acks
+			r.int32() // timeout
+			r.int32() // topics
+			topic := r.string()
+			r.int32() // partitions
+			part := r.int32()
+			batch := r.next(int(r.int32()))
+			_, values := decodeBatch(t, batch)
+			fb.mu.Lock()
+			fb.values = append(fb.values, values...)
+			fb.mu.Unlock()
+			w.int32(1)
+			w.string(topic)
+			w.int32(1)
+			w.int32(part)
+			w.int16(0)
+			w.int64(0)
+			w.int64(-1)
+			w.int32(0) // throttle
+		}
+		resp := w.Bytes()
+		binary.BigEndian.PutUint32(resp, uint32(len(resp)-4))
+		conn.Write(resp)
+	}
+}
+
+func TestKafkaProducer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	fb := &fakeBroker{ln: ln}
+	go fb.serve(t)
+
+	p := NewKafkaProducer([]string{ln.Addr().String()}, "events")
+	for i := 0; i < 3; i++ {
+		p.Send(Message{Key: []byte("cam0"), Value: []byte(strconv.Itoa(i)), Time: time.Now()})
+	}
+	p.Close()
+
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	if len(fb.values) != 3 || fb.values[0] != "0" || fb.values[2] != "2" {
+		t.Errorf("Broker received %q", fb.values)
+	}
+}
+
+func TestParseMetadataErrors(t *testing.T) {
+	var w kafkaWriter
+	w.int32(0)
+	w.int32(0) // no brokers
+	w.int16(-1)
+	w.int32(-1)
+	w.int32(1)
+	w.int16(5)
+	w.string("events")
+	w.int8(0)
+	w.int32(0)
+	if _, _, err := parseMetadataResponse(w.Bytes(), "events"); err == nil {
+		t.Error("Expected leader not available")
+	}
+	if _, _, err := parseMetadataResponse(w.Bytes(), "other"); err == nil {
+		t.Error("Expected topic not found")
+	}
+	if _, _, err := parseMetadataResponse(w.Bytes()[:10], "events"); err != errShortResponse {
+		t.Errorf("Got %v, want %v", err, errShortResponse)
+	}
+}
@@ -0,0 +1,250 @@
+package events
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/logging"
+)
+
+const (
+	redisQueueSize = 1024 // Commands waiting to be sent, more are dropped
+	redisTimeout   = 5 * time.Second
+	redisBackoff   = 2 * time.Second // Time commands are dropped after a failed connection
+)
+
+// ParseRedisURL returns the address, password and database of a server given as host:port
+// or redis://[[USER]:PASSWORD@]HOST[:PORT][/DB]
+func ParseRedisURL(s string) (addr, password string, db int, err error) {
+	if !strings.Contains(s, "://") {
+		if _, _, err := net.SplitHostPort(s); err != nil {
+			return "", "", 0, fmt.Errorf("Redis server should be host:port or redis://: %q", s)
+		}
+		return s, "", 0, nil
+	}
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme != "redis" || u.Hostname() == "" {
+		return "", "", 0, fmt.Errorf("Redis URL should be redis://[:PASSWORD@]HOST[:PORT][/DB]: %q", s)
+	}
+	addr = u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		password, _ = u.User.Password()
+	}
+	if path := strings.Trim(u.Path, "/"); path != "" {
+		if db, err = strconv.Atoi(path); err != nil || db < 0 {
+			return "", "", 0, fmt.Errorf("Redis database should be a number: %q", path)
+		}
+	}
+	return addr, password, db, nil
+}
+
+// RedisClient sends commands to a Redis server in the background over one connection, reconnecting
+// after errors. Commands given within a few seconds of a failed connection are dropped, so that
+// a missing server does not hold up processing
+type RedisClient struct {
+	addr     string
+	password string
+	db       int
+	commands chan [][]byte
+	latest   chan [][]byte // Pending command of SetLatest
+	wg       sync.WaitGroup
+
+	// Used by the sending goroutine only
+	conn   net.Conn
+	reader *bufio.Reader
+	down   time.Time // Time of the last failed connection
+}
+
+// NewRedisClient creates a client of the server and starts sending
+func NewRedisClient(addr, password string, db int) *RedisClient {
+	rc := &RedisClient{addr: addr, password: password, db: db,
+		commands: make(chan [][]byte, redisQueueSize), latest: make(chan [][]byte, 1)}
+	rc.wg.Add(1)
+	go rc.run()
+	return rc
+}
+
+// Do queues the command, dropping it if the queue is full
+func (rc *RedisClient) Do(args ...[]byte) {
+	select {
+	case rc.commands <- args:
+	default:
+		logging.Warnf("Redis command dropped, %d commands are waiting", redisQueueSize)
+	}
+}
+
+// SetLatest queues the command in place of the previous one given to SetLatest if that was not sent yet,
+// e.g. to keep only the latest frame
+func (rc *RedisClient) SetLatest(args ...[]byte) {
+	for {
+		select {
+		case rc.latest <- args:
+			return
+		default:
+		}
+		select {
+		case <-rc.latest:
+		default:
+		}
+	}
+}
+
+// Close sends the queued commands and closes the connection
+func (rc *RedisClient) Close() error {
+	close(rc.commands)
+	rc.wg.Wait()
+	return nil
+}
+
+// Sends queued commands, the latest ones first
+func (rc *RedisClient) run() {
+	defer rc.wg.Done()
+	defer rc.disconnect()
+	for {
+		var args [][]byte
+		select {
+		case args = <-rc.latest:
+		default:
+			select {
+			case args = <-rc.latest:
+			case cmd, ok := <-rc.commands:
+				if !ok {
+					return
+				}
+				args = cmd
+			}
+		}
+		if err := rc.send(args); err != nil {
+			logging.Warnf("Redis %s: %v", args[0], err)
+		}
+	}
+}
+
+// Sends the command and reads its reply
+func (rc *RedisClient) send(args [][]byte) error {
+	if rc.conn == nil {
+		if time.Since(rc.down) < redisBackoff {
+			return nil // Dropped, the failed connection is already logged
+		}
+		if err := rc.connect(); err != nil {
+			rc.down = time.Now()
+			return err
+		}
+	}
+	rc.conn.SetDeadline(time.Now().Add(redisTimeout))
+	if _, err := rc.conn.Write(encodeCommand(args)); err != nil {
+		rc.disconnect()
+		return err
+	}
+	if err := readReply(rc.reader); err != nil {
+		if _, ok := err.(redisError); !ok {
+			rc.disconnect()
+		}
+		return err
+	}
+	return nil
+}
+
+// Connects, authenticates and selects the database
+func (rc *RedisClient) connect() error {
+	conn, err := net.DialTimeout("tcp", rc.addr, redisTimeout)
+	if err != nil {
+		return fmt.Errorf("Cannot connect to Redis: %v", err)
+	}
+	rc.conn, rc.reader = conn, bufio.NewReader(conn)
+	var setup [][][]byte
+	if rc.password != "" {
+		setup = append(setup, [][]byte{[]byte("AUTH"), []byte(rc.password)})
+	}
+	if rc.db != 0 {
+		setup = append(setup, [][]byte{[]byte("SELECT"), []byte(strconv.Itoa(rc.db))})
+	}
+	for _, args := range setup {
+		conn.SetDeadline(time.Now().Add(redisTimeout))
+		_, err := conn.Write(encodeCommand(args))
+		if err == nil {
+			err = readReply(rc.reader)
+		}
+		if err != nil {
+			rc.disconnect()
+			return fmt.Errorf("Redis %s failed: %v", args[0], err)
+		}
+	}
+	return nil
+}
+
+func (rc *RedisClient) disconnect() {
+	if rc.conn != nil {
+		rc.conn.Close()
+		rc.conn, rc.reader = nil, nil
+	}
+}
+
+// encodeCommand encodes the command as a RESP array of bulk strings
+func encodeCommand(args [][]byte) []byte {
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, a := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(a))+"\r\n"...)
+		buf = append(buf, a...)
+		buf = append(buf, "\r\n"...)
+	}
+	return buf
+}
+
+// redisError is an error reply of the server
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+// readReply reads and discards a reply, returning error replies as redisError
+func readReply(r *bufio.Reader) error {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return errors.New("Empty Redis reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return nil
+	case '-':
+		return redisError(line[1:])
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return fmt.Errorf("Invalid Redis reply %q", line)
+		}
+		if n >= 0 {
+			_, err = io.CopyN(ioutil.Discard, r, int64(n)+2)
+		}
+		return err
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return fmt.Errorf("Invalid Redis reply %q", line)
+		}
+		for i := 0; i < n; i++ {
+			if err := readReply(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("Invalid Redis reply %q", line)
+}
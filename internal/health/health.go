@@ -0,0 +1,238 @@
+// Package health serves liveness and readiness endpoints (-health) for examples running as
+// services, so that an orchestrator like Kubernetes or systemd can restart a wedged detector:
+//
+//	GET /healthz  200 while frames keep coming, 503 when none was processed for -health-stale
+//	GET /readyz   200 when the model is loaded, the input delivers frames and the outputs accept them
+//
+// Both reply with the state of each check, e.g.
+//
+//	{"status":"ok","frames":1200,"last_frame":"2024-01-31T12:00:00.04Z","frame_age":"40ms",
+//	 "checks":{"camera":{"ok":true},"model":{"ok":true},"writer":{"ok":false,"error":"broken pipe"}}}
+//
+// Frames are only expected once the input is watched with Checker.Source, so that a server waiting
+// for pushed frames stays alive without clients
+package health
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+// DefaultStale is the default time without frames after which a watched input is considered wedged
+const DefaultStale = 30 * time.Second
+
+// Names of the checks set by the wrappers of Checker
+const (
+	CheckCamera = "camera" // Reading frames from the input
+	CheckWriter = "writer" // Writing frames to the outputs
+	CheckModel  = "model"  // Loading the model, set by the example
+)
+
+// Options holds the health endpoint parameters given by flags
+type Options struct {
+	Addr  string
+	Stale time.Duration
+}
+
+// RegisterFlags adds -health and -health-stale flags to fs
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Addr, "health", "", `Address like ":8091" serving /healthz and /readyz; none if empty`)
+	fs.DurationVar(&o.Stale, "health-stale", DefaultStale, "Report the example unhealthy when no frame was processed for this time")
+}
+
+// Open starts serving the endpoints, closed by sd. The checker is nil without an address
+func (o *Options) Open(sd *shutdown.Handler) (*Checker, error) {
+	if o.Addr == "" {
+		return nil, nil
+	}
+	if o.Stale <= 0 {
+		return nil, fmt.Errorf("Health stale time should be positive, not %v", o.Stale)
+	}
+	ln, err := net.Listen("tcp", o.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot serve health endpoints: %v", err)
+	}
+	c := NewChecker(o.Stale)
+	server := &http.Server{Handler: c}
+	go server.Serve(ln)
+	logging.Infof("Serving health endpoints on %s", ln.Addr())
+	sd.OnClose("health", server.Close)
+	return c, nil
+}
+
+// Check is the state of a component
+type Check struct {
+	OK    bool      `json:"ok"`
+	Error string    `json:"error,omitempty"`
+	Since time.Time `json:"since"` // Time of the last change of OK
+}
+
+// Report is the reply of the endpoints
+type Report struct {
+	Status    string           `json:"status"` // "ok" or "unhealthy", or "not ready" for /readyz
+	Frames    int              `json:"frames"`
+	LastFrame *time.Time       `json:"last_frame,omitempty"`
+	FrameAge  string           `json:"frame_age,omitempty"`
+	Stale     bool             `json:"stale,omitempty"`
+	Checks    map[string]Check `json:"checks"`
+}
+
+// Checker keeps the state of the checks and the time of the last frame. It is an http.Handler
+// serving the endpoints and safe for concurrent use
+type Checker struct {
+	stale     time.Duration
+	mux       *http.ServeMux
+	mu        sync.Mutex
+	checks    map[string]Check
+	frames    int
+	lastFrame time.Time
+	watched   time.Time // When the input started to be watched, zero if it is not
+}
+
+// NewChecker creates a checker considering a watched input wedged after stale without frames
+func NewChecker(stale time.Duration) *Checker {
+	c := &Checker{stale: stale, mux: http.NewServeMux(), checks: make(map[string]Check)}
+	c.mux.HandleFunc("/healthz", c.handle(false))
+	c.mux.HandleFunc("/readyz", c.handle(true))
+	return c
+}
+
+// Set sets the state of the named check, ok if err is nil
+func (c *Checker) Set(name string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	check := Check{OK: err == nil, Since: time.Now()}
+	if err != nil {
+		check.Error = err.Error()
+	}
+	old, ok := c.checks[name]
+	switch {
+	case ok && old.OK == check.OK:
+		check.Since = old.Since
+	case !check.OK:
+		logging.Warnf("Health check %s fails: %v", name, err)
+	case ok:
+		logging.Infof("Health check %s recovered", name)
+	}
+	c.checks[name] = check
+}
+
+// Frame records a successfully processed frame
+func (c *Checker) Frame() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.frames++
+	c.lastFrame = time.Now()
+}
+
+// Report returns the state of the checks; ready additionally requires a frame and all checks passing
+func (c *Checker) Report(ready bool) (Report, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	r := Report{Status: "ok", Frames: c.frames, Checks: make(map[string]Check, len(c.checks))}
+	for name, check := range c.checks {
+		r.Checks[name] = check
+	}
+	if !c.lastFrame.IsZero() {
+		last := c.lastFrame
+		r.LastFrame = &last
+		r.FrameAge = now.Sub(last).Round(time.Millisecond).String()
+	}
+	if !c.watched.IsZero() {
+		since := c.lastFrame
+		if since.Before(c.watched) {
+			since = c.watched
+		}
+		r.Stale = now.Sub(since) > c.stale
+	}
+
+	ok := !r.Stale
+	if ready {
+		ok = ok && (c.watched.IsZero() || c.frames > 0)
+		for _, check := range c.checks {
+			ok = ok && check.OK
+		}
+	}
+	if !ok {
+		r.Status = "unhealthy"
+		if ready {
+			r.Status = "not ready"
+		}
+	}
+	return r, ok
+}
+
+// ServeHTTP serves the endpoints
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mux.ServeHTTP(w, r)
+}
+
+func (c *Checker) handle(ready bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, ok := c.Report(ready)
+		status := http.StatusOK
+		if !ok {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// Source wraps src to expect frames from now on and to set the camera check by the result of reading
+func (c *Checker) Source(src videoio.FrameSource) videoio.FrameSource {
+	c.mu.Lock()
+	c.watched = time.Now()
+	c.mu.Unlock()
+	return &checkedSource{FrameSource: src, c: c}
+}
+
+type checkedSource struct {
+	videoio.FrameSource
+	c *Checker
+}
+
+func (s *checkedSource) Next() (gocv.Mat, error) {
+	img, err := s.FrameSource.Next()
+	checkErr := err
+	if err == io.EOF || err == videoio.ErrStopped {
+		checkErr = nil // The input ended or the example stops
+	}
+	s.c.Set(CheckCamera, checkErr)
+	return img, err
+}
+
+// Sink wraps sink to set the writer check by the result of writing and record written frames
+func (c *Checker) Sink(sink videoio.FrameSink) videoio.FrameSink {
+	return &checkedSink{FrameSink: sink, c: c}
+}
+
+type checkedSink struct {
+	videoio.FrameSink
+	c *Checker
+}
+
+func (s *checkedSink) Write(img gocv.Mat) error {
+	err := s.FrameSink.Write(img)
+	if err == nil || err == videoio.ErrStopped {
+		s.c.Set(CheckWriter, nil)
+		s.c.Frame()
+	} else {
+		s.c.Set(CheckWriter, err)
+	}
+	return err
+}
@@ -0,0 +1,94 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+type frameSource struct {
+	err error
+}
+
+func (s *frameSource) Next() (gocv.Mat, error) { return gocv.NewMat(), s.err }
+func (s *frameSource) Close() error            { return nil }
+
+type frameSink struct {
+	err error
+}
+
+func (s *frameSink) Write(img gocv.Mat) error { return s.err }
+func (s *frameSink) Close() error             { return nil }
+
+func get(t *testing.T, url string) (int, Report) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var r Report
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, r
+}
+
+func TestEndpoints(t *testing.T) {
+	c := NewChecker(50 * time.Millisecond)
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	// Loading the model
+	c.Set(CheckModel, errors.New("Loading"))
+	if status, _ := get(t, server.URL+"/healthz"); status != http.StatusOK {
+		t.Errorf("Expected to be alive while loading, got %d", status)
+	}
+	if status, r := get(t, server.URL+"/readyz"); status != http.StatusServiceUnavailable || r.Status != "not ready" ||
+		r.Checks[CheckModel].Error != "Loading" {
+		t.Errorf("Expected not to be ready while loading, got %d %+v", status, r)
+	}
+
+	c.Set(CheckModel, nil)
+	fs, sink := &frameSource{}, &frameSink{}
+	src, out := c.Source(fs), c.Sink(sink)
+	if status, _ := get(t, server.URL+"/readyz"); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected not to be ready before the first frame, got %d", status)
+	}
+	img, _ := src.Next()
+	out.Write(img)
+	if status, r := get(t, server.URL+"/readyz"); status != http.StatusOK || r.Frames != 1 || r.LastFrame == nil ||
+		!r.Checks[CheckCamera].OK || !r.Checks[CheckWriter].OK {
+		t.Errorf("Expected to be ready, got %d %+v", status, r)
+	}
+
+	// A failing output makes the example not ready, but it is alive
+	sink.err = errors.New("Broken pipe")
+	out.Write(img)
+	if status, r := get(t, server.URL+"/readyz"); status != http.StatusServiceUnavailable || r.Checks[CheckWriter].OK {
+		t.Errorf("Expected not to be ready with a failing writer, got %d %+v", status, r)
+	}
+	if status, _ := get(t, server.URL+"/healthz"); status != http.StatusOK {
+		t.Errorf("Expected to be alive with a failing writer, got %d", status)
+	}
+
+	// No frames for the stale time
+	time.Sleep(60 * time.Millisecond)
+	if status, r := get(t, server.URL+"/healthz"); status != http.StatusServiceUnavailable || !r.Stale || r.Status != "unhealthy" {
+		t.Errorf("Expected to be unhealthy without frames, got %d %+v", status, r)
+	}
+}
+
+func TestUnwatched(t *testing.T) {
+	c := NewChecker(time.Millisecond)
+	c.Set(CheckModel, nil)
+	time.Sleep(5 * time.Millisecond)
+	if r, ok := c.Report(true); !ok || r.Stale {
+		t.Errorf("Expected a server without frames to be ready, got %+v", r)
+	}
+}
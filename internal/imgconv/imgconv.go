@@ -0,0 +1,210 @@
+// Package imgconv converts between Go images and Mats without encoding them, so that examples can
+// use pure-Go image libraries. Pixels of *image.RGBA, *image.NRGBA, *image.Gray and *image.YCbCr
+// are wrapped by Mats in place, with any stride, and converted by OpenCV in one pass; Mats are
+// converted back into the pixels of a reused image.
+//
+// Wrapping Mats share memory with the image only during a conversion, results never point into
+// Go memory. Alpha is dropped when converting to BGR
+package imgconv
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+
+	"gocv.io/x/gocv"
+)
+
+// Converter converts images to BGR Mats, reusing its buffers between calls. It is not safe for
+// concurrent use and should be closed after use
+type Converter struct {
+	buf    []byte      // Rows of an image that cannot be wrapped in place
+	rgba   *image.RGBA // Images of other types drawn as RGBA
+	cb, cr gocv.Mat    // Upsampled chroma planes
+	ycrcb  gocv.Mat
+}
+
+// NewConverter creates a converter
+func NewConverter() *Converter {
+	return &Converter{cb: gocv.NewMat(), cr: gocv.NewMat(), ycrcb: gocv.NewMat()}
+}
+
+// ToMat converts the image to a BGR Mat in dst, which keeps its memory if it has the size of the image
+func (c *Converter) ToMat(img image.Image, dst *gocv.Mat) error {
+	r := img.Bounds()
+	if r.Empty() {
+		return errors.New("Cannot convert an empty image")
+	}
+	switch m := img.(type) {
+	case *image.RGBA:
+		return c.convert(m.Pix, r, m.Stride, 4, gocv.MatTypeCV8UC4, gocv.ColorRGBAToBGR, dst)
+	case *image.NRGBA:
+		return c.convert(m.Pix, r, m.Stride, 4, gocv.MatTypeCV8UC4, gocv.ColorRGBAToBGR, dst)
+	case *image.Gray:
+		return c.convert(m.Pix, r, m.Stride, 1, gocv.MatTypeCV8U, gocv.ColorGrayToBGR, dst)
+	case *image.YCbCr:
+		return c.fromYCbCr(m, dst)
+	}
+	if c.rgba == nil || c.rgba.Rect != r {
+		c.rgba = image.NewRGBA(r)
+	}
+	draw.Draw(c.rgba, r, img, r.Min, draw.Src)
+	return c.convert(c.rgba.Pix, r, c.rgba.Stride, 4, gocv.MatTypeCV8UC4, gocv.ColorRGBAToBGR, dst)
+}
+
+// Converts the pixels wrapped as a Mat with the color conversion code
+func (c *Converter) convert(pix []byte, r image.Rectangle, stride, n int, mt gocv.MatType,
+	code gocv.ColorConversionCode, dst *gocv.Mat) error {
+	src, err := c.wrap(pix, r.Dx(), r.Dy(), stride, n, mt)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	gocv.CvtColor(src, dst, code)
+	return nil
+}
+
+// Returns a Mat of h rows of w pixels of n bytes, starting rows stride bytes apart in pix. The Mat
+// shares the memory of pix if the stride is a whole number of pixels and pix spans all rows; the
+// rows are copied to the buffer of the converter otherwise. It should be closed before pix is released
+func (c *Converter) wrap(pix []byte, w, h, stride, n int, mt gocv.MatType) (gocv.Mat, error) {
+	if stride%n == 0 && cap(pix) >= h*stride {
+		full, err := gocv.NewMatFromBytes(h, stride/n, mt, pix[:h*stride])
+		if err != nil || stride == w*n {
+			return full, err
+		}
+		// The region keeps pointing into pix, which OpenCV does not reference count
+		defer full.Close()
+		return full.Region(image.Rect(0, 0, w, h)), nil
+	}
+	size := w * n * h
+	if cap(c.buf) < size {
+		c.buf = make([]byte, size)
+	}
+	c.buf = c.buf[:size]
+	for y := 0; y < h; y++ {
+		copy(c.buf[y*w*n:(y+1)*w*n], pix[y*stride:])
+	}
+	return gocv.NewMatFromBytes(h, w, mt, c.buf)
+}
+
+// Converts the planes of the image: chroma is upsampled to the size of luma by repeating samples,
+// as image.YCbCr does, and the full range JPEG conversion of OpenCV's YCrCb matches the one of Go
+func (c *Converter) fromYCbCr(m *image.YCbCr, dst *gocv.Mat) error {
+	w, h := m.Rect.Dx(), m.Rect.Dy()
+	cr := chromaRect(m.Rect, m.SubsampleRatio)
+	// Planes are wrapped one at a time, since they may be copied to the same buffer: chroma is
+	// upsampled to Mats of the converter before luma is wrapped
+	for _, p := range []struct {
+		pix []byte
+		dst *gocv.Mat
+	}{{m.Cr, &c.cr}, {m.Cb, &c.cb}} {
+		plane, err := c.wrap(p.pix, cr.Dx(), cr.Dy(), m.CStride, 1, gocv.MatTypeCV8U)
+		if err != nil {
+			return err
+		}
+		gocv.Resize(plane, p.dst, image.Pt(w, h), 0, 0, gocv.InterpolationNearestNeighbor)
+		plane.Close()
+	}
+	y, err := c.wrap(m.Y, w, h, m.YStride, 1, gocv.MatTypeCV8U)
+	if err != nil {
+		return err
+	}
+	defer y.Close()
+	gocv.Merge([]gocv.Mat{y, c.cr, c.cb}, &c.ycrcb)
+	gocv.CvtColor(c.ycrcb, dst, gocv.ColorYCrCbToBGR)
+	return nil
+}
+
+// chromaRect returns the rectangle of the chroma samples covering r, as image.YCbCr indexes them
+func chromaRect(r image.Rectangle, ratio image.YCbCrSubsampleRatio) image.Rectangle {
+	dx, dy := 1, 1
+	switch ratio {
+	case image.YCbCrSubsampleRatio422:
+		dx = 2
+	case image.YCbCrSubsampleRatio420:
+		dx, dy = 2, 2
+	case image.YCbCrSubsampleRatio440:
+		dy = 2
+	case image.YCbCrSubsampleRatio411:
+		dx = 4
+	case image.YCbCrSubsampleRatio410:
+		dx, dy = 4, 2
+	}
+	return image.Rect(r.Min.X/dx, r.Min.Y/dy, (r.Max.X+dx-1)/dx, (r.Max.Y+dy-1)/dy)
+}
+
+// Close releases the buffers
+func (c *Converter) Close() error {
+	c.cb.Close()
+	c.cr.Close()
+	return c.ycrcb.Close()
+}
+
+// ToRGBA converts the BGR, BGRA or gray Mat to an RGBA image. The pixels are written into dst if it
+// has the size of m and no padding between rows, otherwise a new image is returned
+func ToRGBA(m gocv.Mat, dst *image.RGBA) (*image.RGBA, error) {
+	// Gray is the same in RGBA and BGRA
+	code, err := colorCode(m, gocv.ColorBGRToRGBA, gocv.ColorBGRAToRGBA, gocv.ColorGrayToBGRA)
+	if err != nil {
+		return nil, err
+	}
+	w, h := m.Cols(), m.Rows()
+	if dst == nil || dst.Rect.Dx() != w || dst.Rect.Dy() != h || dst.Stride != 4*w {
+		dst = image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+	if err := convertInto(m, dst.Pix[:h*dst.Stride], gocv.MatTypeCV8UC4, code); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// ToGray converts the BGR, BGRA or gray Mat to a gray image. The pixels are written into dst if it
+// has the size of m and no padding between rows, otherwise a new image is returned
+func ToGray(m gocv.Mat, dst *image.Gray) (*image.Gray, error) {
+	code, err := colorCode(m, gocv.ColorBGRToGray, gocv.ColorBGRAToGray, -1)
+	if err != nil {
+		return nil, err
+	}
+	w, h := m.Cols(), m.Rows()
+	if dst == nil || dst.Rect.Dx() != w || dst.Rect.Dy() != h || dst.Stride != w {
+		dst = image.NewGray(image.Rect(0, 0, w, h))
+	}
+	if err := convertInto(m, dst.Pix[:h*dst.Stride], gocv.MatTypeCV8U, code); err != nil {
+		return nil, err
+	}
+	return dst, nil
+}
+
+// Returns the conversion code of the Mat by its number of channels, -1 to copy it
+func colorCode(m gocv.Mat, bgr, bgra, gray gocv.ColorConversionCode) (gocv.ColorConversionCode, error) {
+	if m.Empty() {
+		return 0, errors.New("Cannot convert an empty Mat")
+	}
+	switch m.Type() {
+	case gocv.MatTypeCV8UC3:
+		return bgr, nil
+	case gocv.MatTypeCV8UC4:
+		return bgra, nil
+	case gocv.MatTypeCV8U:
+		return gray, nil
+	}
+	return 0, fmt.Errorf("Cannot convert a Mat of type %v to an image, only 8-bit gray, BGR and BGRA", m.Type())
+}
+
+// Converts the Mat into pix wrapped as a Mat of its size: OpenCV writes into the memory of a
+// destination of the size and type of the result. A code of -1 copies the Mat
+func convertInto(m gocv.Mat, pix []byte, mt gocv.MatType, code gocv.ColorConversionCode) error {
+	out, err := gocv.NewMatFromBytes(m.Rows(), m.Cols(), mt, pix)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if code < 0 {
+		m.CopyTo(&out)
+	} else {
+		gocv.CvtColor(m, &out, code)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package imgconv
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/marchevska/gocv-examples/internal/testutil"
+	"gocv.io/x/gocv"
+)
+
+// Fills the image with a pattern differing in each channel
+func pattern(img interface {
+	image.Image
+	Set(x, y int, c color.Color)
+}) {
+	r := img.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 7), uint8(y * 5), uint8(x*y + 40), 255})
+		}
+	}
+}
+
+// Fails the test if a pixel of the BGR Mat differs from the image by more than tol in a channel
+func assertPixels(t *testing.T, m gocv.Mat, img image.Image, tol int) {
+	t.Helper()
+	r := img.Bounds()
+	if m.Cols() != r.Dx() || m.Rows() != r.Dy() || m.Type() != gocv.MatTypeCV8UC3 {
+		t.Fatalf("Converted to %dx%d %v, want %dx%d BGR", m.Cols(), m.Rows(), m.Type(), r.Dx(), r.Dy())
+	}
+	for y := 0; y < r.Dy(); y++ {
+		for x := 0; x < r.Dx(); x++ {
+			v := m.GetVecbAt(y, x)
+			c := color.RGBAModel.Convert(img.At(r.Min.X+x, r.Min.Y+y)).(color.RGBA)
+			for i, want := range []uint8{c.B, c.G, c.R} {
+				if d := int(v[i]) - int(want); d > tol || d < -tol {
+					t.Fatalf("Pixel %d,%d is %v, want BGR %d,%d,%d", x, y, v, c.B, c.G, c.R)
+				}
+			}
+		}
+	}
+}
+
+func TestToMat(t *testing.T) {
+	rgba := image.NewRGBA(image.Rect(0, 0, 16, 12))
+	pattern(rgba)
+	gray := image.NewGray(image.Rect(0, 0, 15, 9))
+	pattern(gray)
+	ycbcr := image.NewYCbCr(image.Rect(0, 0, 16, 12), image.YCbCrSubsampleRatio420)
+	for i := range ycbcr.Y {
+		ycbcr.Y[i] = uint8(i * 3)
+	}
+	for i := range ycbcr.Cb {
+		ycbcr.Cb[i], ycbcr.Cr[i] = uint8(100+i), uint8(160-i)
+	}
+	paletted := image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Black, color.White})
+	paletted.SetColorIndex(1, 2, 1)
+
+	tests := []struct {
+		name string
+		img  image.Image
+		tol  int
+	}{
+		{"RGBA", rgba, 0},
+		{"RGBA region", rgba.SubImage(image.Rect(3, 2, 11, 12)), 0},
+		{"odd RGBA region", rgba.SubImage(image.Rect(1, 1, 6, 4)), 0},
+		{"gray", gray, 0},
+		{"gray region", gray.SubImage(image.Rect(2, 3, 9, 7)), 0},
+		{"YCbCr", ycbcr, 2},
+		{"YCbCr region", ycbcr.SubImage(image.Rect(4, 2, 12, 10)), 2},
+		{"paletted", paletted, 0},
+	}
+	c := NewConverter()
+	defer c.Close()
+	m := gocv.NewMat()
+	defer m.Close()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := c.ToMat(tt.img, &m); err != nil {
+				t.Fatal(err)
+			}
+			assertPixels(t, m, tt.img, tt.tol)
+		})
+	}
+	if err := c.ToMat(image.NewRGBA(image.Rectangle{}), &m); err == nil {
+		t.Error("Expected an error for an empty image")
+	}
+}
+
+func TestToRGBA(t *testing.T) {
+	img := testutil.LoadImage(t, "card.png")
+	rgba, err := ToRGBA(img, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPixels(t, img, rgba, 0)
+
+	// The pixels of an image of the same size are reused
+	pix := &rgba.Pix[0]
+	if rgba, err = ToRGBA(img, rgba); err != nil || &rgba.Pix[0] != pix {
+		t.Errorf("Image not reused: %v", err)
+	}
+
+	c := NewConverter()
+	defer c.Close()
+	back := gocv.NewMat()
+	defer back.Close()
+	if err := c.ToMat(rgba, &back); err != nil {
+		t.Fatal(err)
+	}
+	testutil.AssertNear(t, back, img, 0, 0)
+}
+
+func TestToGray(t *testing.T) {
+	img := testutil.LoadGray(t, "ramp.png")
+	gray, err := ToGray(img, image.NewGray(image.Rect(0, 0, 1, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := gray.Bounds(); r.Dx() != img.Cols() || r.Dy() != img.Rows() {
+		t.Fatalf("Converted to %v", r)
+	}
+	for _, p := range []image.Point{{0, 0}, {img.Cols() / 2, img.Rows() / 3}, {img.Cols() - 1, img.Rows() - 1}} {
+		if got, want := gray.GrayAt(p.X, p.Y).Y, img.GetUCharAt(p.Y, p.X); got != want {
+			t.Errorf("Pixel %v is %d, want %d", p, got, want)
+		}
+	}
+
+	f := gocv.NewMatWithSize(2, 2, gocv.MatTypeCV32F)
+	defer f.Close()
+	if _, err := ToGray(f, nil); err == nil {
+		t.Error("Expected an error for a float Mat")
+	}
+}
@@ -0,0 +1,90 @@
+package ingest
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Client pushes frames to an ingest server
+type Client struct {
+	url    string
+	client *http.Client
+}
+
+// NewClient creates a client of the server at host:port. The TLS config tells how the server
+// certificate is checked, e.g. InsecureSkipVerify for a self-signed one
+func NewClient(addr string, tlsConfig *tls.Config) *Client {
+	transport := &http.Transport{TLSClientConfig: tlsConfig, ForceAttemptHTTP2: true}
+	return &Client{url: "https://" + addr + MethodDetect, client: &http.Client{Transport: transport}}
+}
+
+// Stream is a Detect call sending frames and receiving their results
+type Stream struct {
+	body *io.PipeWriter
+	resp *http.Response
+}
+
+// Detect starts a stream. It ends when the context is cancelled, or after CloseSend once all results are received
+func (c *Client) Detect(ctx context.Context) (*Stream, error) {
+	pr, pw := io.Pipe()
+	req, err := http.NewRequest(http.MethodPost, c.url, pr)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("Te", "trailers")
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		pw.Close()
+		if ue, ok := err.(*url.Error); ok {
+			err = ue.Err
+		}
+		return nil, fmt.Errorf("Cannot start ingest stream: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		pw.Close()
+		resp.Body.Close()
+		return nil, fmt.Errorf("Ingest server returned %s", resp.Status)
+	}
+	return &Stream{body: pw, resp: resp}, nil
+}
+
+// Send sends the frame
+func (s *Stream) Send(f Frame) error {
+	return writeMessage(s.body, f.Marshal())
+}
+
+// CloseSend tells the server that no more frames are sent
+func (s *Stream) CloseSend() error {
+	return s.body.Close()
+}
+
+// Recv returns the next result, or io.EOF after the last one
+func (s *Stream) Recv() (Result, error) {
+	msg, err := readMessage(s.resp.Body)
+	if err == io.EOF {
+		s.resp.Body.Close()
+		status := s.resp.Trailer.Get("Grpc-Status")
+		if status == "" {
+			status = s.resp.Header.Get("Grpc-Status") // Responses without messages may carry the status in the headers
+		}
+		if code, _ := strconv.Atoi(status); status == "" || code != statusOK {
+			msg, _ := url.PathUnescape(s.resp.Trailer.Get("Grpc-Message") + s.resp.Header.Get("Grpc-Message"))
+			return Result{}, fmt.Errorf("Ingest stream failed with status %s: %s", status, msg)
+		}
+		return Result{}, io.EOF
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	var r Result
+	if err := r.Unmarshal(msg); err != nil {
+		return Result{}, err
+	}
+	return r, nil
+}
@@ -0,0 +1,271 @@
+// Package ingest serves a gRPC stream of frames pushed by clients, e.g. cameras on other machines,
+// and streams back detections on each frame, so that capture and inference can run apart.
+// The service is defined in ingest.proto:
+//
+//	rpc Detect(stream Frame) returns (stream Result);
+//
+// Frames carry an encoded image, e.g. JPEG, and are processed one at a time in the order they arrive,
+// so the detector need not be safe for concurrent use, unless Concurrent is set: frames of different
+// streams are then processed in parallel, e.g. by a detection.Pool on several GPUs. Results are sent
+// in the order of the frames of a stream.
+//
+// gRPC runs over HTTP/2 with TLS, using the HTTP/2 support of the standard library. Without -grpc-cert
+// and -grpc-key a self-signed certificate is made at start, which clients should be told to accept,
+// e.g. with grpcurl -insecure. Compressed messages are not supported
+package ingest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/logging"
+)
+
+// MethodDetect is the HTTP/2 path of the Detect method
+const MethodDetect = "/gocvexamples.ingest.Ingest/Detect"
+
+// Largest message accepted, the default limit of gRPC
+const maxMessageSize = 4 << 20
+
+// gRPC status codes
+const (
+	statusOK              = 0
+	statusInvalidArgument = 3
+	statusUnimplemented   = 12
+)
+
+// Options holds the address and the certificate of the server given by flags
+type Options struct {
+	Addr       string
+	Cert       string
+	Key        string
+	Concurrent bool // Set by the caller if the detector is safe for concurrent use
+}
+
+// RegisterFlags adds -grpc, -grpc-cert and -grpc-key flags to fs
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Addr, "grpc", "", "Address to serve gRPC frame ingestion on, e.g. :50051, instead of reading the input; see internal/ingest")
+	fs.StringVar(&o.Cert, "grpc-cert", "", "TLS certificate file of the gRPC server, self-signed if empty")
+	fs.StringVar(&o.Key, "grpc-key", "", "TLS key file of the gRPC server")
+}
+
+// Enabled tells whether the server is configured
+func (o *Options) Enabled() bool {
+	return o.Addr != ""
+}
+
+// DetectFunc returns the detections on the frame
+type DetectFunc func(f Frame) ([]detection.Detection, error)
+
+// Serve serves frame ingestion with the detector until the context is cancelled
+func (o *Options) Serve(ctx context.Context, detect DetectFunc) error {
+	var cert tls.Certificate
+	var err error
+	if o.Cert != "" || o.Key != "" {
+		cert, err = tls.LoadX509KeyPair(o.Cert, o.Key)
+	} else {
+		logging.Warnf("No -grpc-cert, using a self-signed certificate which clients should accept explicitly")
+		cert, err = selfSignedCert()
+	}
+	if err != nil {
+		return fmt.Errorf("Cannot load TLS certificate: %v", err)
+	}
+	ln, err := net.Listen("tcp", o.Addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:   &Server{detect: detect, Concurrent: o.Concurrent},
+		TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}, NextProtos: []string{"h2"}},
+	}
+	logging.Infof("Serving gRPC frame ingestion on %s", ln.Addr())
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ServeTLS(ln, "", "") }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		sctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		srv.Shutdown(sctx)
+		return nil
+	}
+}
+
+// Server handles Detect streams. It is an http.Handler, to be served with HTTP/2
+type Server struct {
+	// Concurrent tells that the detector is safe for concurrent use, so that streams are not
+	// serialized; frames of a stream are still processed one after another
+	Concurrent bool
+
+	detect DetectFunc
+	mu     sync.Mutex // Runs one detection at a time unless Concurrent
+}
+
+// NewServer creates a server running the detector
+func NewServer(detect DetectFunc) *Server {
+	return &Server{detect: detect}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor != 2 || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC requests only", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	if r.URL.Path != MethodDetect {
+		setStatus(w, statusUnimplemented, "unknown method "+r.URL.Path)
+		return
+	}
+	if enc := r.Header.Get("Grpc-Encoding"); enc != "" && enc != "identity" {
+		setStatus(w, statusUnimplemented, "compression is not supported")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	frames := 0
+	for {
+		msg, err := readMessage(r.Body)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			logging.Warnf("Ingest stream from %s: %v", r.RemoteAddr, err)
+			setStatus(w, statusInvalidArgument, err.Error())
+			return
+		}
+		var f Frame
+		if err := f.Unmarshal(msg); err != nil {
+			setStatus(w, statusInvalidArgument, err.Error())
+			return
+		}
+		res := Result{ID: f.ID}
+		if !s.Concurrent {
+			s.mu.Lock()
+		}
+		res.Detections, err = s.detect(f)
+		if !s.Concurrent {
+			s.mu.Unlock()
+		}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		if err := writeMessage(w, res.Marshal()); err != nil {
+			logging.Warnf("Ingest stream to %s: %v", r.RemoteAddr, err)
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		frames++
+	}
+	logging.Debugf("Ingest stream from %s ended after %d frames", r.RemoteAddr, frames)
+	setStatus(w, statusOK, "")
+}
+
+// Sets the status trailers of the response
+func setStatus(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	if msg != "" {
+		w.Header().Set("Grpc-Message", percentEncode(msg))
+	}
+}
+
+// percentEncode encodes the message for the grpc-message header: bytes outside printable ASCII and '%'
+func percentEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// readMessage reads a length-prefixed gRPC message
+func readMessage(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errors.New("Truncated gRPC message")
+		}
+		return nil, err
+	}
+	if prefix[0] != 0 {
+		return nil, errors.New("Compressed gRPC messages are not supported")
+	}
+	n := binary.BigEndian.Uint32(prefix[1:])
+	if n > maxMessageSize {
+		return nil, fmt.Errorf("gRPC message of %d bytes exceeds %d", n, maxMessageSize)
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, errors.New("Truncated gRPC message")
+	}
+	return msg, nil
+}
+
+// writeMessage writes an uncompressed length-prefixed gRPC message
+func writeMessage(w io.Writer, msg []byte) error {
+	buf := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(buf[1:], uint32(len(msg)))
+	_, err := w.Write(append(buf, msg...))
+	return err
+}
+
+// selfSignedCert returns a certificate for localhost and the host name, valid for a year
+func selfSignedCert() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	names := []string{"localhost"}
+	if host, err := os.Hostname(); err == nil {
+		names = append(names, host)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{Organization: []string{"gocv-examples"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     names,
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
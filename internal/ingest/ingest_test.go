@@ -0,0 +1,185 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"errors"
+	"image"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/detection"
+)
+
+func TestMessages(t *testing.T) {
+	f := Frame{ID: 7, Image: []byte{0xff, 0xd8, 0}, TimestampMs: 1700000000000, Camera: "cam0"}
+	var gotF Frame
+	if err := gotF.Unmarshal(f.Marshal()); err != nil || !reflect.DeepEqual(gotF, f) {
+		t.Errorf("Frame round trip = %+v, %v; want %+v", gotF, err, f)
+	}
+
+	r := Result{ID: 7, Detections: []detection.Detection{
+		{Label: "person", Confidence: 0.9, BBox: image.Rect(10, 20, 110, 220)},
+		{Label: "dog", Confidence: 0.5, BBox: image.Rect(-5, 0, 40, 30)},
+	}}
+	var gotR Result
+	if err := gotR.Unmarshal(r.Marshal()); err != nil || !reflect.DeepEqual(gotR, r) {
+		t.Errorf("Result round trip = %+v, %v; want %+v", gotR, err, r)
+	}
+
+	// Field 1 = 150, as in the protobuf encoding guide
+	if err := gotF.Unmarshal([]byte{0x08, 0x96, 0x01}); err != nil || gotF.ID != 150 {
+		t.Errorf("Unmarshal = %+v, %v", gotF, err)
+	}
+	if err := gotF.Unmarshal([]byte{0x12, 0x05, 'a'}); err == nil {
+		t.Error("Expected an error for a truncated message")
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	var buf bytes.Buffer
+	writeMessage(&buf, []byte("abc"))
+	writeMessage(&buf, nil)
+	for _, want := range []string{"abc", ""} {
+		if msg, err := readMessage(&buf); err != nil || string(msg) != want {
+			t.Errorf("readMessage = %q, %v; want %q", msg, err, want)
+		}
+	}
+	if _, err := readMessage(&buf); err != io.EOF {
+		t.Errorf("readMessage = %v, want EOF", err)
+	}
+	if _, err := readMessage(bytes.NewReader([]byte{1, 0, 0, 0, 0})); err == nil {
+		t.Error("Expected an error for a compressed message")
+	}
+	if _, err := readMessage(bytes.NewReader([]byte{0, 0, 0, 0, 9, 1})); err == nil {
+		t.Error("Expected an error for a truncated message")
+	}
+}
+
+func TestServer(t *testing.T) {
+	server := httptest.NewUnstartedServer(NewServer(func(f Frame) ([]detection.Detection, error) {
+		if len(f.Image) == 0 {
+			return nil, errors.New("empty image")
+		}
+		return []detection.Detection{{Label: f.Camera, Confidence: 1, BBox: image.Rect(0, 0, len(f.Image), 1)}}, nil
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	tlsConfig := server.Client().Transport.(*http.Transport).TLSClientConfig
+	addr := strings.TrimPrefix(server.URL, "https://")
+
+	stream, err := NewClient(addr, tlsConfig).Detect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := []Frame{{ID: 1, Image: []byte("abc"), Camera: "cam0"}, {ID: 2}}
+	for _, f := range frames {
+		if err := stream.Send(f); err != nil {
+			t.Fatal(err)
+		}
+		res, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.ID != f.ID {
+			t.Errorf("Result %d for frame %d", res.ID, f.ID)
+		}
+		switch f.ID {
+		case 1:
+			if len(res.Detections) != 1 || res.Detections[0].Label != "cam0" || res.Detections[0].BBox.Max.X != 3 {
+				t.Errorf("Detections %v", res.Detections)
+			}
+		case 2:
+			if res.Error != "empty image" {
+				t.Errorf("Error %q, want empty image", res.Error)
+			}
+		}
+	}
+	stream.CloseSend()
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("Recv after the last result = %v, want EOF", err)
+	}
+
+	// Other methods are not implemented
+	client := NewClient(addr, tlsConfig)
+	client.url = "https://" + addr + "/gocvexamples.ingest.Ingest/Other"
+	if stream, err = client.Detect(context.Background()); err == nil {
+		stream.CloseSend()
+		_, err = stream.Recv()
+	}
+	if err == nil || !strings.Contains(err.Error(), "status 12") {
+		t.Errorf("Unknown method = %v, want status 12", err)
+	}
+}
+
+func TestConcurrentServer(t *testing.T) {
+	// Each detection waits for the other stream, which only completes if they run in parallel
+	var arrived sync.WaitGroup
+	arrived.Add(2)
+	server := httptest.NewUnstartedServer(&Server{Concurrent: true, detect: func(f Frame) ([]detection.Detection, error) {
+		arrived.Done()
+		both := make(chan struct{})
+		go func() {
+			arrived.Wait()
+			close(both)
+		}()
+		select {
+		case <-both:
+			return nil, nil
+		case <-time.After(5 * time.Second):
+			return nil, errors.New("Streams were serialized")
+		}
+	}})
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	client := NewClient(strings.TrimPrefix(server.URL, "https://"), server.Client().Transport.(*http.Transport).TLSClientConfig)
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func(id uint64) {
+			stream, err := client.Detect(context.Background())
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer stream.CloseSend()
+			if err := stream.Send(Frame{ID: id}); err != nil {
+				errs <- err
+				return
+			}
+			res, err := stream.Recv()
+			if err == nil && res.Error != "" {
+				err = errors.New(res.Error)
+			}
+			errs <- err
+		}(uint64(i))
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestSelfSignedCert(t *testing.T) {
+	cert, err := selfSignedCert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := leaf.VerifyHostname("localhost"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,195 @@
+package ingest
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/marchevska/gocv-examples/internal/detection"
+)
+
+// Messages of ingest.proto, encoded by hand in the protobuf wire format to keep the module free of
+// generated code
+
+// Frame is a frame pushed by a client
+type Frame struct {
+	ID          uint64
+	Image       []byte // Encoded image, e.g. JPEG
+	TimestampMs int64
+	Camera      string
+}
+
+// Result holds the detections on a frame, or why it could not be processed
+type Result struct {
+	ID         uint64
+	Detections []detection.Detection
+	Error      string
+}
+
+// Wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+var errTruncated = errors.New("Truncated protobuf message")
+
+func appendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(b, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+func appendTag(b []byte, field, wire int) []byte {
+	return appendUvarint(b, uint64(field<<3|wire))
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	return appendUvarint(appendTag(b, field, wireVarint), v)
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = appendUvarint(appendTag(b, field, wireBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+// Marshal encodes the frame
+func (f *Frame) Marshal() []byte {
+	b := appendVarintField(nil, 1, f.ID)
+	b = appendBytesField(b, 2, f.Image)
+	b = appendVarintField(b, 3, uint64(f.TimestampMs))
+	return appendBytesField(b, 4, []byte(f.Camera))
+}
+
+// Unmarshal decodes the frame, skipping unknown fields
+func (f *Frame) Unmarshal(b []byte) error {
+	*f = Frame{}
+	return parseFields(b, func(field int, v uint64, data []byte) {
+		switch field {
+		case 1:
+			f.ID = v
+		case 2:
+			f.Image = data
+		case 3:
+			f.TimestampMs = int64(v)
+		case 4:
+			f.Camera = string(data)
+		}
+	})
+}
+
+// Marshal encodes the result
+func (r *Result) Marshal() []byte {
+	b := appendVarintField(nil, 1, r.ID)
+	for _, d := range r.Detections {
+		b = appendBytesField(b, 2, marshalDetection(d))
+	}
+	return appendBytesField(b, 3, []byte(r.Error))
+}
+
+// Unmarshal decodes the result, skipping unknown fields
+func (r *Result) Unmarshal(b []byte) error {
+	*r = Result{}
+	var err error
+	perr := parseFields(b, func(field int, v uint64, data []byte) {
+		switch field {
+		case 1:
+			r.ID = v
+		case 2:
+			d, derr := unmarshalDetection(data)
+			if derr != nil && err == nil {
+				err = derr
+			}
+			r.Detections = append(r.Detections, d)
+		case 3:
+			r.Error = string(data)
+		}
+	})
+	if perr != nil {
+		return perr
+	}
+	return err
+}
+
+func marshalDetection(d detection.Detection) []byte {
+	b := appendBytesField(nil, 1, []byte(d.Label))
+	if d.Confidence != 0 {
+		b = appendTag(b, 2, wireFixed32)
+		var buf [4]byte
+		binary.LittleEndian.PutUint32(buf[:], math.Float32bits(d.Confidence))
+		b = append(b, buf[:]...)
+	}
+	// int32 fields are sign-extended to 64 bits, as protobuf encodes negative int32 values
+	for i, v := range []int{d.BBox.Min.X, d.BBox.Min.Y, d.BBox.Max.X, d.BBox.Max.Y} {
+		b = appendVarintField(b, 3+i, uint64(int64(int32(v))))
+	}
+	return b
+}
+
+func unmarshalDetection(b []byte) (detection.Detection, error) {
+	var d detection.Detection
+	var box [4]int
+	err := parseFields(b, func(field int, v uint64, data []byte) {
+		switch {
+		case field == 1:
+			d.Label = string(data)
+		case field == 2:
+			d.Confidence = math.Float32frombits(uint32(v))
+		case field >= 3 && field <= 6:
+			box[field-3] = int(int32(v))
+		}
+	})
+	d.BBox = image.Rect(box[0], box[1], box[2], box[3])
+	return d, err
+}
+
+// parseFields calls fn with each field of the message: the value of varint and fixed fields,
+// or the data of length-delimited ones
+func parseFields(b []byte, fn func(field int, v uint64, data []byte)) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errTruncated
+		}
+		b = b[n:]
+		field, wire := int(tag>>3), int(tag&7)
+		var v uint64
+		var data []byte
+		switch wire {
+		case wireVarint:
+			if v, n = binary.Uvarint(b); n <= 0 {
+				return errTruncated
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return errTruncated
+			}
+			v, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return errTruncated
+			}
+			v, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || l > uint64(len(b)-n) {
+				return errTruncated
+			}
+			data, b = b[n:n+int(l)], b[n+int(l):]
+		default:
+			return fmt.Errorf("Unsupported protobuf wire type %d", wire)
+		}
+		fn(field, v, data)
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+// Package logging provides leveled logging for the examples, as plain text or JSON lines,
+// with a per-example prefix.
+package logging
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is a logging severity level
+type Level int
+
+// Logging levels
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+var levelNames = [...]string{"debug", "info", "warn", "error"}
+
+// Syslog priorities of the levels, prefixed to journal messages as <N>
+var levelPriorities = [...]int{7, 6, 4, 3}
+
+func (lv Level) String() string {
+	if lv < Debug || lv > Error {
+		return fmt.Sprintf("level(%d)", int(lv))
+	}
+	return levelNames[lv]
+}
+
+// Set parses the level name, so Level can be used as a flag
+func (lv *Level) Set(s string) error {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			*lv = Level(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("Unknown logging level %q, expected one of %s", s, strings.Join(levelNames[:], ", "))
+}
+
+// Logger writes messages at or above its level
+type Logger struct {
+	mu      sync.Mutex
+	out     io.Writer
+	prefix  string
+	level   Level
+	json    bool
+	journal bool
+}
+
+// New creates a logger writing text messages at Info level and above to stderr
+func New(prefix string) *Logger {
+	return &Logger{out: os.Stderr, prefix: prefix, level: Info}
+}
+
+// SetPrefix sets the name of the example or component shown with each message
+func (l *Logger) SetPrefix(prefix string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prefix = prefix
+}
+
+// SetLevel sets the minimum level of messages to write
+func (l *Logger) SetLevel(lv Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.level = lv
+}
+
+// SetJSON switches between text and JSON lines output
+func (l *Logger) SetJSON(on bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.json = on
+}
+
+// SetJournal switches text output to the format of services logging to the systemd journal:
+// no time, which the journal adds, and the level as a syslog priority prefix like <4>
+func (l *Logger) SetJournal(on bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.journal = on
+}
+
+// SetOutput sets the destination of messages
+func (l *Logger) SetOutput(w io.Writer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.out = w
+}
+
+// Child returns a logger with the settings of l and the name appended to its prefix, e.g. for one
+// of several streams. Later changes of l do not apply to the child
+func (l *Logger) Child(name string) *Logger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	prefix := name
+	if l.prefix != "" {
+		prefix = l.prefix + " " + name
+	}
+	return &Logger{out: l.out, prefix: prefix, level: l.level, json: l.json, journal: l.journal}
+}
+
+// Enabled reports whether messages of the level are written
+func (l *Logger) Enabled(lv Level) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return lv >= l.level
+}
+
+// RegisterFlags adds -log-level and -log-json flags controlling the logger to fs
+func (l *Logger) RegisterFlags(fs *flag.FlagSet) {
+	fs.Var(levelFlag{l}, "log-level", "Minimum logging level: debug, info, warn or error")
+	fs.Var(jsonFlag{l}, "log-json", "Write logs as JSON lines")
+}
+
+type jsonEntry struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Prefix  string `json:"prefix,omitempty"`
+	Message string `json:"msg"`
+}
+
+// Log writes a message with the level
+func (l *Logger) Log(lv Level, format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if lv < l.level {
+		return
+	}
+
+	now := time.Now()
+	msg := fmt.Sprintf(format, args...)
+	if l.json {
+		line, _ := json.Marshal(jsonEntry{now.Format(time.RFC3339Nano), lv.String(), l.prefix, msg})
+		l.out.Write(append(line, '\n'))
+		return
+	}
+
+	prefix := ""
+	if l.prefix != "" {
+		prefix = "[" + l.prefix + "] "
+	}
+	if l.journal && lv >= Debug && lv <= Error {
+		fmt.Fprintf(l.out, "<%d>%s%s\n", levelPriorities[lv], prefix, msg)
+		return
+	}
+	fmt.Fprintf(l.out, "%s %-5s %s%s\n", now.Format("2006/01/02 15:04:05"), strings.ToUpper(lv.String()), prefix, msg)
+}
+
+// Debugf writes a debug message
+func (l *Logger) Debugf(format string, args ...interface{}) { l.Log(Debug, format, args...) }
+
+// Infof writes an informational message
+func (l *Logger) Infof(format string, args ...interface{}) { l.Log(Info, format, args...) }
+
+// Warnf writes a warning
+func (l *Logger) Warnf(format string, args ...interface{}) { l.Log(Warn, format, args...) }
+
+// Errorf writes an error message
+func (l *Logger) Errorf(format string, args ...interface{}) { l.Log(Error, format, args...) }
+
+// Fatalf writes an error message and exits the program
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.Log(Error, format, args...)
+	os.Exit(1)
+}
+
+// Flag values bound to the logger settings
+type levelFlag struct{ l *Logger }
+
+func (f levelFlag) String() string {
+	if f.l == nil {
+		return Info.String()
+	}
+	return f.l.level.String()
+}
+
+func (f levelFlag) Set(s string) error {
+	var lv Level
+	if err := lv.Set(s); err != nil {
+		return err
+	}
+	f.l.SetLevel(lv)
+	return nil
+}
+
+type jsonFlag struct{ l *Logger }
+
+func (f jsonFlag) String() string {
+	if f.l == nil {
+		return "false"
+	}
+	return fmt.Sprint(f.l.json)
+}
+
+func (f jsonFlag) Set(s string) error {
+	on, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	f.l.SetJSON(on)
+	return nil
+}
+
+func (f jsonFlag) IsBoolFlag() bool { return true }
+
+// Default is the logger used by the examples and the shared packages
+var Default = New("")
+
+// SetPrefix sets the prefix of the default logger
+func SetPrefix(prefix string) { Default.SetPrefix(prefix) }
+
+// SetJournal switches the default logger to the format of the systemd journal
+func SetJournal(on bool) { Default.SetJournal(on) }
+
+// RegisterFlags adds flags controlling the default logger to fs
+func RegisterFlags(fs *flag.FlagSet) { Default.RegisterFlags(fs) }
+
+// Debugf writes a debug message to the default logger
+func Debugf(format string, args ...interface{}) { Default.Log(Debug, format, args...) }
+
+// Infof writes an informational message to the default logger
+func Infof(format string, args ...interface{}) { Default.Log(Info, format, args...) }
+
+// Warnf writes a warning to the default logger
+func Warnf(format string, args ...interface{}) { Default.Log(Warn, format, args...) }
+
+// Errorf writes an error message to the default logger
+func Errorf(format string, args ...interface{}) { Default.Log(Error, format, args...) }
+
+// Fatalf writes an error message to the default logger and exits the program
+func Fatalf(format string, args ...interface{}) { Default.Fatalf(format, args...) }
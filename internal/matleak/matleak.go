@@ -0,0 +1,46 @@
+//go:build matprofile
+// +build matprofile
+
+// Package matleak reports gocv Mats which were created but never closed, with the stack traces
+// of their creation. It relies on gocv's MatProfile, so it only works when the program is built
+// or run with the matprofile tag:
+//
+//	go run -tags matprofile .
+//
+// Without the tag all functions do nothing and cost nothing.
+package matleak
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"gocv.io/x/gocv"
+)
+
+// Enabled reports whether Mat tracking is compiled in
+const Enabled = true
+
+// Count returns the number of Mats which are currently not closed
+func Count() int {
+	return gocv.MatProfile.Count()
+}
+
+// WriteReport writes the number of unclosed Mats and the stack traces where they were created
+func WriteReport(w io.Writer) error {
+	n := Count()
+	if n == 0 {
+		_, err := fmt.Fprintln(w, "matleak: no unclosed Mats")
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "matleak: %d unclosed Mats, created at:\n", n); err != nil {
+		return err
+	}
+	return gocv.MatProfile.WriteTo(w, 1)
+}
+
+// Report writes the report to stderr. Call it with defer as the first statement of main,
+// so that it runs after all other cleanup
+func Report() {
+	WriteReport(os.Stderr)
+}
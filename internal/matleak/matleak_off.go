@@ -0,0 +1,22 @@
+//go:build !matprofile
+// +build !matprofile
+
+package matleak
+
+import "io"
+
+// Enabled reports whether Mat tracking is compiled in
+const Enabled = false
+
+// Count returns 0, Mats are not tracked without the matprofile tag
+func Count() int {
+	return 0
+}
+
+// WriteReport does nothing without the matprofile tag
+func WriteReport(w io.Writer) error {
+	return nil
+}
+
+// Report does nothing without the matprofile tag
+func Report() {}
@@ -0,0 +1,139 @@
+// Package metrics measures frame rate and per-stage processing time over a rolling window
+// of recent frames, and renders them over the frames.
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultWindow is the number of recent frames used for averaging
+const DefaultWindow = 30
+
+// StageStat holds timing of a single processing stage
+type StageStat struct {
+	Name string
+	Avg  time.Duration // Average over the window
+	Last time.Duration
+}
+
+// Tracer receives every stage measured with Start, e.g. to export it as a span of a trace
+type Tracer interface {
+	Stage(name string, start, end time.Time)
+}
+
+// Collector counts frames and measures named stages
+type Collector struct {
+	mu     sync.Mutex
+	window int
+	frames []time.Time // Times of recent frames, oldest first
+	stages map[string][]time.Duration
+	order  []string // Stage names in order of first use
+	tracer Tracer
+}
+
+// NewCollector creates a collector averaging over window recent frames or measurements
+func NewCollector(window int) *Collector {
+	if window < 2 {
+		window = 2
+	}
+	return &Collector{window: window, stages: map[string][]time.Duration{}}
+}
+
+// Frame marks the end of processing of a frame
+func (c *Collector) Frame() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.frames = append(c.frames, time.Now())
+	if len(c.frames) > c.window {
+		c.frames = c.frames[len(c.frames)-c.window:]
+	}
+}
+
+// FPS returns the frame rate over the window, 0 until at least 2 frames are processed
+func (c *Collector) FPS() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.frames) < 2 {
+		return 0
+	}
+	elapsed := c.frames[len(c.frames)-1].Sub(c.frames[0])
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(len(c.frames)-1) / elapsed.Seconds()
+}
+
+// Observe records the duration of the stage
+func (c *Collector) Observe(stage string, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	durations, ok := c.stages[stage]
+	if !ok {
+		c.order = append(c.order, stage)
+	}
+	durations = append(durations, d)
+	if len(durations) > c.window {
+		durations = durations[len(durations)-c.window:]
+	}
+	c.stages[stage] = durations
+}
+
+// SetTracer passes the stages measured from now on to t, nil for none
+func (c *Collector) SetTracer(t Tracer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.tracer = t
+}
+
+// Start starts measuring the stage and returns a function which stops the measurement:
+//
+//	defer c.Start("inference")()
+func (c *Collector) Start(stage string) func() {
+	start := time.Now()
+	return func() {
+		end := time.Now()
+		c.Observe(stage, end.Sub(start))
+		c.mu.Lock()
+		t := c.tracer
+		c.mu.Unlock()
+		if t != nil {
+			t.Stage(stage, start, end)
+		}
+	}
+}
+
+// Stages returns timing of all stages in order of first use
+func (c *Collector) Stages() []StageStat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := make([]StageStat, 0, len(c.order))
+	for _, name := range c.order {
+		durations := c.stages[name]
+		var total time.Duration
+		for _, d := range durations {
+			total += d
+		}
+		stats = append(stats, StageStat{
+			Name: name,
+			Avg:  total / time.Duration(len(durations)),
+			Last: durations[len(durations)-1],
+		})
+	}
+	return stats
+}
+
+// Lines returns the frame rate and stage timing as text lines
+func (c *Collector) Lines() []string {
+	lines := []string{fmt.Sprintf("FPS: %.1f", c.FPS())}
+	for _, st := range c.Stages() {
+		lines = append(lines, fmt.Sprintf("%s: %.1f ms", st.Name, float64(st.Avg)/float64(time.Millisecond)))
+	}
+	return lines
+}
+
+func (c *Collector) String() string {
+	return strings.Join(c.Lines(), ", ")
+}
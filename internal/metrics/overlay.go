@@ -0,0 +1,19 @@
+package metrics
+
+import (
+	"image"
+
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"gocv.io/x/gocv"
+)
+
+// Overlay draws the frame rate and stage timing in the bottom-left corner of the image
+func Overlay(img *gocv.Mat, c *Collector, st draw.Style) {
+	lines := c.Lines()
+	lineHeight := st.TextSize(lines[0]).Y + 2*st.Padding
+	y := img.Rows() - lineHeight*(len(lines)-1)
+	for _, line := range lines {
+		draw.TextWithBackground(img, line, image.Pt(0, y), st)
+		y += lineHeight
+	}
+}
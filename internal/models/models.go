@@ -0,0 +1,226 @@
+// Package models resolves model files used by the DNN examples. Files are looked up locally first,
+// otherwise downloaded from the registered URL on first use, verified against the registered SHA-256
+// checksum and cached under the user cache directory (~/.cache/gocv-examples on Linux). Cached files
+// are verified again on each use.
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/marchevska/gocv-examples/internal/logging"
+)
+
+// CacheEnv is the environment variable overriding the cache directory
+const CacheEnv = "GOCV_EXAMPLES_CACHE"
+
+// Model describes a downloadable model file
+type Model struct {
+	URL    string
+	SHA256 string // Expected checksum in hex, files without one are not downloaded
+}
+
+var (
+	mu       sync.Mutex
+	registry = map[string]Model{
+		"coco.names": {
+			URL:    "https://raw.githubusercontent.com/AlexeyAB/darknet/59596d7880f6504768df41d6daa586f5cb2b932f/cfg/coco.names",
+			SHA256: "634a1132eb33f8091d60f2c346ababe8b905ae08387037aed883953b7329af84",
+		},
+		"yolov4.cfg": {
+			URL:    "https://raw.githubusercontent.com/AlexeyAB/darknet/59596d7880f6504768df41d6daa586f5cb2b932f/cfg/yolov4.cfg",
+			SHA256: "a6d0f8e5c62cc8378384f75a8159b95fa2964d4162e33351b00ac82e0fc46a34",
+		},
+		"yolov4.weights": {
+			URL: "https://github.com/AlexeyAB/darknet/releases/download/darknet_yolo_v3_optimal/yolov4.weights",
+		},
+		"res10_300x300_ssd_deploy.prototxt": {
+			URL:    "https://raw.githubusercontent.com/opencv/opencv/908c30ceb65c9b79add6c07c7e84adc5722f2334/samples/dnn/face_detector/deploy.prototxt",
+			SHA256: "dcd661dc48fc9de0a341db1f666a2164ea63a67265c7f779bc12d6b3f2fa67e9",
+		},
+		"res10_300x300_ssd_iter_140000.caffemodel": {
+			URL:    "https://raw.githubusercontent.com/opencv/opencv_3rdparty/b2bfc75f6aea5b1f834ff0f0b865a7c18ff1459f/res10_300x300_ssd_iter_140000.caffemodel",
+			SHA256: "2a56a11a57a4a295956b0660b4a3d76bbdca2206c4961cea8efe7d95c7cb2f2d",
+		},
+		"object_detection_classes_coco.txt": {
+			URL:    "https://raw.githubusercontent.com/opencv/opencv/908c30ceb65c9b79add6c07c7e84adc5722f2334/samples/data/dnn/object_detection_classes_coco.txt",
+			SHA256: "cf743111a02d083f91ada4d99ccd3b937cdfc853cf0f5582ee20ca6621b4393f",
+		},
+		"mask_rcnn_inception_v2_coco_2018_01_28.pbtxt": {
+			URL: "https://raw.githubusercontent.com/opencv/opencv_extra/4.x/testdata/dnn/mask_rcnn_inception_v2_coco_2018_01_28.pbtxt",
+		},
+		"haarcascade_frontalface_default.xml": {
+			URL:    "https://raw.githubusercontent.com/opencv/opencv/908c30ceb65c9b79add6c07c7e84adc5722f2334/data/haarcascades/haarcascade_frontalface_default.xml",
+			SHA256: "0f7d4527844eb514d4a4948e822da90fbb16a34a0bbbbc6adc6498747a5aafb0",
+		},
+		"haarcascade_eye_tree_eyeglasses.xml": {
+			URL:    "https://raw.githubusercontent.com/opencv/opencv/908c30ceb65c9b79add6c07c7e84adc5722f2334/data/haarcascades/haarcascade_eye_tree_eyeglasses.xml",
+			SHA256: "e32f9c67935c33e9d1331eb14fa58554ff17835c03742663bcb97a892e936a57",
+		},
+		"colorization_deploy_v2.prototxt": {
+			URL: "https://raw.githubusercontent.com/richzhang/colorization/caffe/colorization/models/colorization_deploy_v2.prototxt",
+		},
+		"colorization_release_v2.caffemodel": {
+			URL: "http://eecs.berkeley.edu/~rich.zhang/projects/2016_colorization/files/demo_v2/colorization_release_v2.caffemodel",
+		},
+		"pts_in_hull.npy": {
+			URL: "https://raw.githubusercontent.com/richzhang/colorization/caffe/colorization/resources/pts_in_hull.npy",
+		},
+		"model-small.onnx": {
+			URL: "https://github.com/isl-org/MiDaS/releases/download/v2_1/model-small.onnx",
+		},
+		"age_deploy.prototxt": {
+			URL: "https://raw.githubusercontent.com/smahesh29/Gender-and-Age-Detection/master/age_deploy.prototxt",
+		},
+		"age_net.caffemodel": {
+			URL: "https://raw.githubusercontent.com/smahesh29/Gender-and-Age-Detection/master/age_net.caffemodel",
+		},
+		"gender_deploy.prototxt": {
+			URL: "https://raw.githubusercontent.com/smahesh29/Gender-and-Age-Detection/master/gender_deploy.prototxt",
+		},
+		"gender_net.caffemodel": {
+			URL: "https://raw.githubusercontent.com/smahesh29/Gender-and-Age-Detection/master/gender_net.caffemodel",
+		},
+		"emotion-ferplus-8.onnx": {
+			URL: "https://github.com/onnx/models/raw/main/vision/body_analysis/emotion_ferplus/model/emotion-ferplus-8.onnx",
+		},
+		"ssd_mobilenet_v2_coco_quant_postprocess.tflite": {
+			URL: "https://raw.githubusercontent.com/google-coral/test_data/master/ssd_mobilenet_v2_coco_quant_postprocess.tflite",
+		},
+		"coco_labels.txt": {
+			URL: "https://raw.githubusercontent.com/google-coral/test_data/master/coco_labels.txt",
+		},
+	}
+)
+
+// Register adds or replaces a model file in the registry
+func Register(name string, m Model) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[name] = m
+}
+
+// Lookup returns the registry entry for the model file
+func Lookup(name string) (Model, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	m, ok := registry[name]
+	return m, ok
+}
+
+// CacheDir returns the directory where downloaded models are stored
+func CacheDir() (string, error) {
+	if dir := os.Getenv(CacheEnv); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gocv-examples"), nil
+}
+
+// Resolve returns the path of the model file. If a file exists at the given path, it is used as is.
+// Otherwise the file is looked up in the cache and downloaded if it is not there yet
+func Resolve(name string) (string, error) {
+	if _, err := os.Stat(name); err == nil {
+		return name, nil
+	}
+
+	m, ok := Lookup(name)
+	if !ok {
+		return "", fmt.Errorf("Model file %s not found and has no download URL", name)
+	}
+	dir, err := CacheDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, filepath.Base(name))
+	if m.SHA256 == "" {
+		return "", fmt.Errorf("Model file %s has no checksum to verify a download, download it from %s "+
+			"to the current directory", name, m.URL)
+	}
+	// Cached files are verified again, so that a truncated or altered file is replaced
+	if _, err := os.Stat(path); err == nil {
+		err := Verify(path, m.SHA256)
+		if err == nil {
+			return path, nil
+		}
+		logging.Warnf("Cached %s is invalid, downloading it again: %v", path, err)
+	}
+
+	logging.Infof("Downloading %s from %s", name, m.URL)
+	if err := download(m, path); err != nil {
+		return "", fmt.Errorf("Cannot download %s: %v", name, err)
+	}
+	return path, nil
+}
+
+// Verify checks the file against the expected SHA256 checksum
+func Verify(path, checksum string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	return checkSum(h.Sum(nil), checksum)
+}
+
+// Downloads the model to a temporary file in the cache and moves it in place once verified,
+// so that an interrupted download does not leave a broken file
+func download(m Model, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	resp, err := http.Get(m.URL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP status %s", resp.Status)
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, h), resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := checkSum(h.Sum(nil), m.SHA256); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func checkSum(sum []byte, checksum string) error {
+	if checksum == "" {
+		return errors.New("No checksum to verify against")
+	}
+	if got := hex.EncodeToString(sum); got != strings.ToLower(checksum) {
+		return fmt.Errorf("Checksum mismatch: expected %s, got %s", checksum, got)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const content = "person\nbicycle\ncar\n"
+
+// Serves the content and counts the requests, using a fresh cache directory
+func serve(t *testing.T) (*httptest.Server, *int32) {
+	t.Helper()
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(content))
+	}))
+	t.Cleanup(srv.Close)
+
+	dir, err := ioutil.TempDir("", "models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	old, had := os.LookupEnv(CacheEnv)
+	os.Setenv(CacheEnv, dir)
+	t.Cleanup(func() {
+		if had {
+			os.Setenv(CacheEnv, old)
+		} else {
+			os.Unsetenv(CacheEnv)
+		}
+	})
+	return srv, &requests
+}
+
+func sum(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+func TestResolveDownloadsAndCaches(t *testing.T) {
+	srv, requests := serve(t)
+	Register("test-labels.txt", Model{URL: srv.URL, SHA256: sum(content)})
+
+	for i := 0; i < 2; i++ {
+		path, err := Resolve("test-labels.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, err := ioutil.ReadFile(path); err != nil || string(data) != content {
+			t.Fatalf("Resolved file has %q, %v", data, err)
+		}
+	}
+	if n := atomic.LoadInt32(requests); n != 1 {
+		t.Errorf("Downloaded %d times, want once and then a cache hit", n)
+	}
+}
+
+func TestResolveReplacesInvalidCache(t *testing.T) {
+	srv, requests := serve(t)
+	Register("test-truncated.txt", Model{URL: srv.URL, SHA256: sum(content)})
+	dir, _ := CacheDir()
+	path := filepath.Join(dir, "test-truncated.txt")
+	if err := ioutil.WriteFile(path, []byte(content[:5]), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Resolve("test-truncated.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != content {
+		t.Errorf("Truncated cache file not replaced: %q", data)
+	}
+	if n := atomic.LoadInt32(requests); n != 1 {
+		t.Errorf("Downloaded %d times, want once", n)
+	}
+}
+
+func TestResolveChecksumMismatch(t *testing.T) {
+	srv, _ := serve(t)
+	Register("test-tampered.txt", Model{URL: srv.URL, SHA256: sum("something else")})
+
+	_, err := Resolve("test-tampered.txt")
+	if err == nil || !strings.Contains(err.Error(), "Checksum mismatch") {
+		t.Fatalf("Got %v, want a checksum mismatch", err)
+	}
+	dir, _ := CacheDir()
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("Rejected download left %d files in the cache", len(files))
+	}
+}
+
+func TestResolveWithoutChecksum(t *testing.T) {
+	srv, requests := serve(t)
+	Register("test-unverified.txt", Model{URL: srv.URL})
+
+	if _, err := Resolve("test-unverified.txt"); err == nil {
+		t.Error("Model without a checksum resolved")
+	}
+	if n := atomic.LoadInt32(requests); n != 0 {
+		t.Errorf("Model without a checksum downloaded")
+	}
+}
+
+func TestRegistryChecksums(t *testing.T) {
+	for name, m := range registry {
+		if m.SHA256 == "" {
+			continue // Needs to be downloaded manually
+		}
+		if b, err := hex.DecodeString(m.SHA256); err != nil || len(b) != sha256.Size {
+			t.Errorf("Invalid SHA-256 of %s: %s", name, m.SHA256)
+		}
+		if strings.Contains(m.URL, "/master/") || strings.Contains(m.URL, "/4.x/") {
+			t.Errorf("URL of %s is not pinned: %s", name, m.URL)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package nms implements non-maximum suppression of overlapping detections: hard (greedy) NMS by IoU
+// or by the overlapped fraction of a box, soft NMS and class-aware NMS. Functions work on scored boxes and return indices of kept boxes,
+// so that detectors can keep their own detection types.
+// At the moment of writing, GoCV does not include an implementation of NMSBoxes.
+package nms
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// Box is a scored bounding box. Class is only used by class-aware NMS
+type Box struct {
+	Rect  image.Rectangle
+	Score float32
+	Class int
+}
+
+// IoU returns intersection over union of the rectangles, 0 for empty rectangles
+func IoU(a, b image.Rectangle) float64 {
+	inter := area(a.Intersect(b))
+	if inter == 0 {
+		return 0
+	}
+	return float64(inter) / float64(area(a)+area(b)-inter)
+}
+
+// Overlap returns the area of the intersection of the rectangles as a fraction of the area of a,
+// 0 for empty rectangles. Unlike IoU, it is 1 for a box inside a larger one
+func Overlap(a, b image.Rectangle) float64 {
+	inter := area(a.Intersect(b))
+	if inter == 0 {
+		return 0
+	}
+	return float64(inter) / float64(area(a))
+}
+
+func area(r image.Rectangle) int {
+	if r.Empty() {
+		return 0
+	}
+	return r.Dx() * r.Dy()
+}
+
+// byScore returns indices of the boxes ordered by descending score; equal scores keep the input order
+func byScore(boxes []Box) []int {
+	order := make([]int, len(boxes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return boxes[order[i]].Score > boxes[order[j]].Score })
+	return order
+}
+
+// Hard applies greedy NMS: boxes are taken in order of descending score and a box is dropped if
+// its IoU with an already kept box is above iouThr. Returns indices of kept boxes, most confident first
+func Hard(boxes []Box, iouThr float64) []int {
+	return greedy(boxes, IoU, iouThr, false)
+}
+
+// HardOverlap applies greedy NMS like Hard, but drops a box if more than ovrThr of its area is
+// covered by an already kept box, see Overlap. This is the rule of the Yolo detectors, which also
+// drops small boxes inside larger ones. Returns indices of kept boxes, most confident first
+func HardOverlap(boxes []Box, ovrThr float64) []int {
+	return greedy(boxes, Overlap, ovrThr, false)
+}
+
+// ClassAware applies Hard NMS to each class separately, so that overlapping objects of different
+// classes are all kept. Returns indices of kept boxes, most confident first
+func ClassAware(boxes []Box, iouThr float64) []int {
+	return greedy(boxes, IoU, iouThr, true)
+}
+
+// Keeps boxes in order of descending score unless their overlap with a kept box is above thr
+func greedy(boxes []Box, overlap func(box, kept image.Rectangle) float64, thr float64, perClass bool) []int {
+	var keep []int
+	for _, i := range byScore(boxes) {
+		suppressed := false
+		for _, k := range keep {
+			if perClass && boxes[k].Class != boxes[i].Class {
+				continue
+			}
+			if overlap(boxes[i].Rect, boxes[k].Rect) > thr {
+				suppressed = true
+				break
+			}
+		}
+		if !suppressed {
+			keep = append(keep, i)
+		}
+	}
+	return keep
+}
+
+// SoftMethod selects how soft NMS decays scores of overlapping boxes
+type SoftMethod int
+
+const (
+	// Linear multiplies the score by 1-IoU when IoU is above the threshold
+	Linear SoftMethod = iota
+	// Gaussian multiplies the score by exp(-IoU²/sigma) regardless of the threshold
+	Gaussian
+)
+
+// Soft applies soft NMS (Bodla et al., 2017): instead of dropping boxes overlapping a more confident
+// one, their scores are decayed, and boxes with the score falling below scoreThr are dropped.
+// iouThr is used by Linear and sigma by Gaussian method.
+// Returns indices of kept boxes in order of selection and their decayed scores
+func Soft(boxes []Box, method SoftMethod, iouThr, sigma float64, scoreThr float32) (keep []int, scores []float32) {
+	remaining := byScore(boxes)
+	current := make([]float32, len(boxes))
+	for i, b := range boxes {
+		current[i] = b.Score
+	}
+
+	for len(remaining) > 0 {
+		// Select the remaining box with the highest decayed score
+		best := 0
+		for j := range remaining {
+			if current[remaining[j]] > current[remaining[best]] {
+				best = j
+			}
+		}
+		m := remaining[best]
+		remaining = append(remaining[:best], remaining[best+1:]...)
+		if current[m] < scoreThr {
+			break // Others have lower scores
+		}
+		keep = append(keep, m)
+		scores = append(scores, current[m])
+
+		for _, i := range remaining {
+			iou := IoU(boxes[m].Rect, boxes[i].Rect)
+			switch method {
+			case Linear:
+				if iou > iouThr {
+					current[i] *= float32(1 - iou)
+				}
+			case Gaussian:
+				current[i] *= float32(math.Exp(-iou * iou / sigma))
+			}
+		}
+	}
+	return keep, scores
+}
@@ -0,0 +1,132 @@
+package nms
+
+import (
+	"image"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// Two classes of overlapping boxes and a separate one. Soft NMS reference outputs were computed
+// with the algorithm of the paper's implementation (github.com/bharatsingh430/soft-nms)
+var testBoxes = []Box{
+	{Rect: image.Rect(0, 0, 100, 100), Score: 0.9, Class: 0},
+	{Rect: image.Rect(10, 10, 110, 110), Score: 0.8, Class: 0},   // IoU 0.68 with 0
+	{Rect: image.Rect(50, 50, 150, 150), Score: 0.7, Class: 1},   // IoU 0.14 with 0
+	{Rect: image.Rect(200, 200, 260, 260), Score: 0.6, Class: 0}, // No overlaps
+	{Rect: image.Rect(5, 0, 105, 100), Score: 0.5, Class: 1},     // IoU 0.90 with 0
+}
+
+func TestIoU(t *testing.T) {
+	tests := []struct {
+		a, b image.Rectangle
+		want float64
+	}{
+		{image.Rect(0, 0, 10, 10), image.Rect(0, 0, 10, 10), 1},
+		{image.Rect(0, 0, 10, 10), image.Rect(5, 0, 15, 10), 1.0 / 3},
+		{image.Rect(0, 0, 10, 10), image.Rect(10, 0, 20, 10), 0},
+		{image.Rect(0, 0, 10, 10), image.Rectangle{}, 0},
+	}
+	for _, tt := range tests {
+		if got := IoU(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("IoU(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestHard(t *testing.T) {
+	if got, want := Hard(testBoxes, 0.5), []int{0, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	// High threshold only suppresses nearly identical boxes
+	if got, want := Hard(testBoxes, 0.8), []int{0, 1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := Hard(nil, 0.5); len(got) != 0 {
+		t.Errorf("Expected no boxes, got %v", got)
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	tests := []struct {
+		a, b image.Rectangle
+		want float64
+	}{
+		{image.Rect(0, 0, 10, 10), image.Rect(5, 0, 15, 10), 0.5},
+		{image.Rect(2, 2, 8, 8), image.Rect(0, 0, 10, 10), 1}, // Inside b
+		{image.Rect(0, 0, 10, 10), image.Rect(2, 2, 8, 8), 0.36},
+		{image.Rect(0, 0, 10, 10), image.Rectangle{}, 0},
+	}
+	for _, tt := range tests {
+		if got := Overlap(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Overlap(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestHardOverlap(t *testing.T) {
+	// Box 2 has IoU 0.14 with box 0, but a quarter of it is covered
+	if got, want := HardOverlap(testBoxes, 0.2), []int{0, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got, want := HardOverlap(testBoxes, 0.5), []int{0, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestHardEqualScores(t *testing.T) {
+	boxes := []Box{
+		{Rect: image.Rect(0, 0, 10, 10), Score: 0.5},
+		{Rect: image.Rect(1, 1, 11, 11), Score: 0.5},
+	}
+	if got, want := Hard(boxes, 0.5), []int{0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the first of equal boxes %v, got %v", want, got)
+	}
+}
+
+func TestClassAware(t *testing.T) {
+	// Box 4 overlaps box 0 of another class, so it is kept
+	if got, want := ClassAware(testBoxes, 0.5), []int{0, 2, 3, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestSoft(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     SoftMethod
+		scoreThr   float32
+		wantKeep   []int
+		wantScores []float32
+	}{
+		{"linear", Linear, 0.001, []int{0, 2, 3, 1, 4}, []float32{0.9, 0.7, 0.6, 0.2555, 0.0121}},
+		{"gaussian", Gaussian, 0.001, []int{0, 2, 3, 1, 4}, []float32{0.9, 0.672, 0.6, 0.2876, 0.0303}},
+		{"linear threshold", Linear, 0.3, []int{0, 2, 3}, []float32{0.9, 0.7, 0.6}},
+	}
+	for _, tt := range tests {
+		keep, scores := Soft(testBoxes, tt.method, 0.3, 0.5, tt.scoreThr)
+		if !reflect.DeepEqual(keep, tt.wantKeep) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.wantKeep, keep)
+			continue
+		}
+		for i := range scores {
+			if math.Abs(float64(scores[i]-tt.wantScores[i])) > 1e-3 {
+				t.Errorf("%s: expected scores %v, got %v", tt.name, tt.wantScores, scores)
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkHard(b *testing.B) {
+	boxes := make([]Box, 500)
+	for i := range boxes {
+		x, y := (i*37)%1200, (i*53)%700
+		boxes[i] = Box{Rect: image.Rect(x, y, x+20+i%80, y+20+i%60), Score: float32(i%100) / 100}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Hard(boxes, 0.4)
+	}
+}
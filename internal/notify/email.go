@@ -0,0 +1,202 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+)
+
+// Clips larger than this are not attached, since mail servers commonly reject messages over 10-25 MB
+const maxClipSize = 8 << 20
+
+// EmailOptions holds the SMTP server and addresses of email alerts given by flags
+type EmailOptions struct {
+	Server   string // host:port
+	User     string
+	Password string
+	From     string
+	To       config.StringList
+}
+
+// RegisterFlags adds -smtp, -smtp-user, -smtp-password, -mail-from and -mail-to flags to fs
+func (eo *EmailOptions) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&eo.Server, "smtp", "", "SMTP server host:port sending email alerts, e.g. smtp.gmail.com:587; port 465 uses TLS")
+	fs.StringVar(&eo.User, "smtp-user", "", "SMTP user name, no authentication if empty")
+	fs.StringVar(&eo.Password, "smtp-password", "", "SMTP password, better given by the environment than on the command line")
+	fs.StringVar(&eo.From, "mail-from", "", "Sender of email alerts, the SMTP user if empty")
+	fs.Var(&eo.To, "mail-to", "Recipient of email alerts, repeatable")
+}
+
+// Enabled tells whether email alerts are configured
+func (eo *EmailOptions) Enabled() bool {
+	return eo.Server != ""
+}
+
+// Notifier returns the email notifier of the options
+func (eo *EmailOptions) Notifier() (*Email, error) {
+	if _, _, err := net.SplitHostPort(eo.Server); err != nil {
+		return nil, fmt.Errorf("SMTP server should be host:port: %q", eo.Server)
+	}
+	from := eo.From
+	if from == "" {
+		from = eo.User
+	}
+	if from == "" || len(eo.To) == 0 {
+		return nil, errors.New("Email alerts need a sender and at least one recipient")
+	}
+	return &Email{Server: eo.Server, User: eo.User, Password: eo.Password, From: from, To: eo.To}, nil
+}
+
+// Email sends alerts by SMTP with the snapshot and the clip attached. The text of the alert is
+// the subject, and the body lists the detections
+type Email struct {
+	Server         string
+	User, Password string
+	From           string
+	To             []string
+}
+
+// Notify sends the alert
+func (e *Email) Notify(ctx context.Context, a Alert) error {
+	var clip []byte
+	if a.Clip != "" {
+		if fi, err := os.Stat(a.Clip); err == nil && fi.Size() > maxClipSize {
+			a.Text += fmt.Sprintf(" (clip of %d MB is too large to attach)", fi.Size()>>20)
+		} else if clip, err = ioutil.ReadFile(a.Clip); err != nil {
+			return err
+		}
+	}
+	msg, err := buildMessage(e.From, e.To, a, clip)
+	if err != nil {
+		return err
+	}
+	return e.send(ctx, msg)
+}
+
+// buildMessage returns the MIME message of the alert with the snapshot and the clip, if any
+func buildMessage(from string, to []string, a Alert, clip []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	subject := strings.SplitN(a.Text, "\n", 2)[0]
+	fmt.Fprintf(&buf, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\n"+
+		"Content-Type: multipart/mixed; boundary=%s\r\n\r\n",
+		from, strings.Join(to, ", "), mime.QEncoding.Encode("utf-8", subject), a.Time.Format(time.RFC1123Z), mw.Boundary())
+
+	part, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	qp := quotedprintable.NewWriter(part)
+	fmt.Fprintf(qp, "%s\r\n\r\nRule: %v\r\n", a.Text, a.Rule)
+	for _, d := range a.Detections {
+		fmt.Fprintf(qp, "%v\r\n", d)
+	}
+	qp.Close()
+
+	attach := func(name, contentType string, data []byte) error {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", name)},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return err
+		}
+		enc := base64.StdEncoding.EncodeToString(data)
+		for len(enc) > 76 {
+			fmt.Fprintf(part, "%s\r\n", enc[:76])
+			enc = enc[76:]
+		}
+		_, err = fmt.Fprintf(part, "%s\r\n", enc)
+		return err
+	}
+	stamp := a.Time.Format("20060102_150405")
+	if a.Snapshot != nil {
+		if err := attach("alert_"+stamp+".jpg", "image/jpeg", a.Snapshot); err != nil {
+			return nil, err
+		}
+	}
+	if clip != nil {
+		if err := attach("alert_"+stamp+filepath.Ext(a.Clip), "video/x-msvideo", clip); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Sends the message through the server, with TLS on port 465 and STARTTLS where the server offers it
+func (e *Email) send(ctx context.Context, msg []byte) error {
+	host, port, _ := net.SplitHostPort(e.Server)
+	dialer := &net.Dialer{Timeout: sendTimeout}
+	var conn net.Conn
+	var err error
+	if port == "465" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", e.Server, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", e.Server)
+	}
+	if err != nil {
+		return fmt.Errorf("Cannot connect to SMTP server: %v", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if e.User != "" {
+		if err := c.Auth(smtp.PlainAuth("", e.User, e.Password, host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %v", err)
+		}
+	}
+	if err := c.Mail(e.From); err != nil {
+		return err
+	}
+	for _, to := range e.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP server rejected %s: %v", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,69 @@
+package notify
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"testing"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/detection"
+)
+
+func TestEmailOptions(t *testing.T) {
+	eo := EmailOptions{Server: "smtp.example.com:587", User: "cam@example.com", To: []string{"me@example.com"}}
+	if e, err := eo.Notifier(); err != nil || e.From != "cam@example.com" {
+		t.Errorf("Unexpected notifier %+v, %v", e, err)
+	}
+	for _, bad := range []EmailOptions{
+		{Server: "smtp.example.com", User: "a@b", To: []string{"c@d"}},
+		{Server: "smtp.example.com:25", To: []string{"c@d"}},
+		{Server: "smtp.example.com:25", From: "a@b"},
+	} {
+		if _, err := bad.Notifier(); err == nil {
+			t.Errorf("Expected an error for %+v", bad)
+		}
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	rule, _ := ParseRule("person")
+	a := Alert{Rule: rule, Time: time.Date(2024, 5, 1, 23, 0, 0, 0, time.UTC), Text: "Person at the door",
+		Detections: []detection.Detection{{Label: "person", Confidence: 0.9}},
+		Snapshot:   bytes.Repeat([]byte{0xff}, 100), Clip: "/tmp/alert_1.avi"}
+	data, err := buildMessage("cam@example.com", []string{"a@example.com", "b@example.com"}, a, []byte("RIFF"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Header.Get("Subject") != "Person at the door" || msg.Header.Get("To") != "a@example.com, b@example.com" {
+		t.Errorf("Unexpected headers %v", msg.Header)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	var names []string
+	var sizes []int
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		body, _ := ioutil.ReadAll(p) // Quoted-printable is decoded by the reader, base64 is not
+		names = append(names, p.FileName())
+		sizes = append(sizes, len(body))
+	}
+	if len(names) != 3 || names[0] != "" || names[1] != "alert_20240501_230000.jpg" || names[2] != "alert_20240501_230000.avi" {
+		t.Errorf("Unexpected parts %q", names)
+	}
+	if len(sizes) == 3 && (sizes[1] < 136 || sizes[2] == 0) {
+		t.Errorf("Unexpected attachment sizes %v", sizes)
+	}
+}
@@ -0,0 +1,335 @@
+// Package notify sends alerts when detections on a frame match a rule, e.g. a person in a zone
+// at night. An alert carries a message made from a template, the annotated frame as a JPEG
+// snapshot and optionally a short video clip around it, and is sent to every configured notifier
+// in the background, so that slow services do not hold up processing. Each rule fires at most once
+// per its cooldown
+package notify
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"os"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/storage"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+// Defaults of the options
+const (
+	DefaultText     = `{{.Rule}}: {{.Count}} {{.Label}} at {{.Time.Format "2006-01-02 15:04:05"}}`
+	DefaultInterval = 5 * time.Minute
+)
+
+const (
+	queueSize   = 8 // Alerts waiting to be sent, more are dropped
+	sendTimeout = 30 * time.Second
+)
+
+// Alert is a fired rule with the matching detections and the frame they were found on
+type Alert struct {
+	Rule       Rule
+	Time       time.Time
+	Detections []detection.Detection
+	Text       string // Message made from the template
+	Snapshot   []byte // Annotated frame as JPEG, nil if it could not be encoded
+	Clip       string // Video file of the frames around the alert, removed once the alert is sent; empty without clips
+
+	removeClip bool // Whether the clip is removed after sending, for the last alert sharing it
+}
+
+// Label returns the label of the first detection, for templates
+func (a Alert) Label() string {
+	if len(a.Detections) == 0 {
+		return a.Rule.Label
+	}
+	return a.Detections[0].Label
+}
+
+// Count returns the number of detections, for templates
+func (a Alert) Count() int {
+	return len(a.Detections)
+}
+
+// Notifier delivers alerts, e.g. to a chat through a webhook
+type Notifier interface {
+	Notify(ctx context.Context, a Alert) error
+}
+
+// Options holds alert rules and notifiers given by flags
+type Options struct {
+	Rules    config.StringList
+	Webhooks config.StringList
+	Email    EmailOptions
+	Text     string
+	Interval time.Duration
+	Clip     time.Duration
+
+	// Uploader storing snapshots and clips of alerts, set by the example before Open; nil for none
+	Upload *storage.Uploader
+}
+
+// RegisterFlags adds -alert, -webhook, -alert-text, -alert-interval, -alert-clip and the email flags to fs
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	fs.Var(&o.Rules, "alert", `Alert rule, repeatable: LABEL [in X0:Y0:X1:Y1] [HH:MM-HH:MM], e.g. "person in 0.5:0:1:1 22:00-06:00"`)
+	fs.Var(&o.Webhooks, "webhook", "Webhook URL receiving alerts, repeatable: Slack incoming webhook, "+
+		"Telegram https://api.telegram.org/botTOKEN/sendPhoto?chat_id=ID or any URL accepting JSON")
+	fs.StringVar(&o.Text, "alert-text", DefaultText, "Template of the alert message, see notify.Alert for the fields")
+	fs.DurationVar(&o.Interval, "alert-interval", DefaultInterval, "Shortest time between alerts of a rule without its own cooldown")
+	fs.DurationVar(&o.Clip, "alert-clip", 0, "Length of the video clip around each alert, half of it before, 0 for no clips")
+	o.Email.RegisterFlags(fs)
+}
+
+// Open creates the dispatcher of the options, closed by sd. It is nil without rules.
+// Without notifiers alerts are only logged. Clips are written with the codec at the frame rate of the input
+func (o *Options) Open(sd *shutdown.Handler, codec string, fps float64) (*Dispatcher, error) {
+	if len(o.Rules) == 0 {
+		return nil, nil
+	}
+	var rules []Rule
+	for _, s := range o.Rules {
+		r, err := ParseRule(s)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	text, err := template.New("alert").Parse(o.Text)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid alert text template: %v", err)
+	}
+	var notifiers []Notifier
+	for _, url := range o.Webhooks {
+		w, err := NewWebhook(url)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, w)
+	}
+	if o.Email.Enabled() {
+		e, err := o.Email.Notifier()
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, e)
+	}
+	if o.Upload != nil {
+		notifiers = append(notifiers, Upload{Uploader: o.Upload})
+	}
+	d := NewDispatcher(rules, notifiers, text, o.Interval)
+	if o.Clip > 0 {
+		if fps <= 0 {
+			return nil, fmt.Errorf("Frame rate should be positive for alert clips: %g", fps)
+		}
+		d.RecordClips(o.Clip, codec, fps)
+	}
+	sd.OnClose("alerts", d.Close)
+	return d, nil
+}
+
+// Dispatcher checks the rules on each frame and sends alerts of the rules that fire
+type Dispatcher struct {
+	rules     []Rule
+	notifiers []Notifier
+	text      *template.Template
+	interval  time.Duration
+	limit     rateLimit
+	queue     chan Alert
+	wg        sync.WaitGroup
+
+	// Clips: frames before an alert are buffered, and its clip is recorded for postFrames more
+	codec      string
+	fps        float64
+	buffer     *videoio.FrameBuffer
+	postFrames int
+	clip       *clipRecording
+}
+
+// clipRecording is a clip being recorded with the alerts waiting for it
+type clipRecording struct {
+	sink   *videoio.VideoSink
+	path   string
+	left   int // Frames to record
+	alerts []Alert
+}
+
+// NewDispatcher creates a dispatcher and starts sending alerts. Rules without a cooldown fire at most once per interval
+func NewDispatcher(rules []Rule, notifiers []Notifier, text *template.Template, interval time.Duration) *Dispatcher {
+	d := &Dispatcher{rules: rules, notifiers: notifiers, text: text, interval: interval,
+		limit: rateLimit{last: map[int]time.Time{}}, queue: make(chan Alert, queueSize)}
+	d.wg.Add(1)
+	go d.send()
+	return d
+}
+
+// RecordClips attaches to each alert a clip of the given length at the frame rate, half of it
+// before the alert. Alerts are sent once their clip is complete. Alerts fired while a clip is
+// recorded share it
+func (d *Dispatcher) RecordClips(length time.Duration, codec string, fps float64) {
+	frames := int(length.Seconds() * fps)
+	d.codec, d.fps = codec, fps
+	d.buffer = videoio.NewPreRollBuffer(length/2, fps)
+	d.postFrames = frames - frames/2
+}
+
+// Check matches the detections found on the annotated frame against the rules and queues alerts.
+// It should be called on every frame when clips are recorded
+func (d *Dispatcher) Check(img gocv.Mat, dets []detection.Detection) {
+	now := time.Now()
+	var snapshot []byte
+	var fired []Alert
+	for i, r := range d.rules {
+		matched := r.Match(dets, image.Pt(img.Cols(), img.Rows()), now)
+		cooldown := r.Cooldown
+		if cooldown == 0 {
+			cooldown = d.interval
+		}
+		if len(matched) == 0 || !d.limit.Allow(i, now, cooldown) {
+			continue
+		}
+		if snapshot == nil {
+			if buf, err := gocv.IMEncode(gocv.JPEGFileExt, img); err != nil {
+				logging.Warnf("Cannot encode alert snapshot: %v", err)
+			} else {
+				snapshot = append([]byte(nil), buf.GetBytes()...)
+				buf.Close()
+			}
+		}
+		a := Alert{Rule: r, Time: now, Detections: matched, Snapshot: snapshot}
+		var text bytes.Buffer
+		if err := d.text.Execute(&text, a); err != nil {
+			logging.Warnf("Error in alert text template: %v", err)
+			text.Reset()
+			fmt.Fprintf(&text, "%v: %d %s", r, a.Count(), a.Label())
+		}
+		a.Text = text.String()
+		logging.Infof("Alert: %s", a.Text)
+		fired = append(fired, a)
+	}
+
+	if d.buffer == nil {
+		d.enqueue(fired)
+		return
+	}
+	if len(fired) > 0 && d.clip == nil {
+		if d.startClip(); d.clip == nil {
+			d.enqueue(fired)
+			fired = nil
+		}
+	}
+	if d.clip == nil {
+		d.buffer.Push(img)
+		return
+	}
+	d.clip.alerts = append(d.clip.alerts, fired...)
+	if err := d.clip.sink.Write(img); err != nil {
+		logging.Errorf("Error writing alert clip: %v", err)
+		d.clip.left = 0
+	}
+	if d.clip.left--; d.clip.left <= 0 {
+		d.finishClip()
+	}
+}
+
+// Starts a clip with the buffered frames
+func (d *Dispatcher) startClip() {
+	f, err := ioutil.TempFile("", "alert_*.avi")
+	if err != nil {
+		logging.Errorf("Cannot create alert clip: %v", err)
+		return
+	}
+	f.Close()
+	d.clip = &clipRecording{sink: videoio.NewVideoSink(f.Name(), d.codec, d.fps), path: f.Name(), left: d.postFrames}
+	if err := d.buffer.Drain(d.clip.sink); err != nil {
+		logging.Errorf("Error writing alert clip: %v", err)
+	}
+}
+
+// Closes the clip and queues its alerts
+func (d *Dispatcher) finishClip() {
+	c := d.clip
+	d.clip = nil
+	path := c.path
+	if err := c.sink.Close(); err != nil {
+		logging.Errorf("Error closing alert clip: %v", err)
+		os.Remove(path)
+		path = ""
+	}
+	for i := range c.alerts {
+		c.alerts[i].Clip = path
+	}
+	if path != "" {
+		// The file is removed after the last alert using it is sent
+		c.alerts[len(c.alerts)-1].removeClip = true
+	}
+	d.enqueue(c.alerts)
+}
+
+// Queues alerts to be sent, dropping them if the queue is full
+func (d *Dispatcher) enqueue(alerts []Alert) {
+	for _, a := range alerts {
+		select {
+		case d.queue <- a:
+		default:
+			logging.Warnf("Alert dropped, %d alerts are waiting to be sent", queueSize)
+			if a.removeClip {
+				os.Remove(a.Clip)
+			}
+		}
+	}
+}
+
+// Sends queued alerts to every notifier
+func (d *Dispatcher) send() {
+	defer d.wg.Done()
+	for a := range d.queue {
+		for _, n := range d.notifiers {
+			ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+			if err := n.Notify(ctx, a); err != nil {
+				logging.Errorf("Error sending alert: %v", err)
+			}
+			cancel()
+		}
+		if a.removeClip {
+			os.Remove(a.Clip)
+		}
+	}
+}
+
+// Close finishes the clip being recorded, sends the queued alerts and stops the dispatcher
+func (d *Dispatcher) Close() error {
+	if d.clip != nil {
+		d.finishClip()
+	}
+	close(d.queue)
+	d.wg.Wait()
+	if d.buffer != nil {
+		d.buffer.Close()
+	}
+	return nil
+}
+
+// rateLimit lets each rule fire once per its cooldown
+type rateLimit struct {
+	last map[int]time.Time
+}
+
+// Allow tells whether the rule may fire at the time, and if so, starts its cooldown
+func (rl *rateLimit) Allow(rule int, t time.Time, cooldown time.Duration) bool {
+	if last, ok := rl.last[rule]; ok && t.Sub(last) < cooldown {
+		return false
+	}
+	rl.last[rule] = t
+	return true
+}
@@ -0,0 +1,127 @@
+package notify
+
+import (
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/detection"
+)
+
+// Rule fires when detections of its label are found in its zone during its hours. It is written as
+//
+//	LABEL [in X0:Y0:X1:Y1] [HH:MM-HH:MM] [every DURATION]
+//
+// e.g. "person in 0.5:0:1:1 22:00-06:00 every 10m" for a person in the right half of the frame at night,
+// at most once per 10 minutes. The label "*" matches any object. The zone is given in fractions of
+// the frame width and height, and an object is in it when the center of its box is. Hours may go
+// past midnight. Without a cooldown the rule fires at most once per the interval of the dispatcher
+type Rule struct {
+	Label    string
+	Zone     [4]float64 // X0, Y0, X1, Y1 in fractions of the frame, zero for the whole frame
+	From, To int        // Minutes since midnight, equal for the whole day
+	Cooldown time.Duration
+	text     string
+}
+
+func (r Rule) String() string {
+	return r.text
+}
+
+// ParseRule parses the rule, see Rule
+func ParseRule(s string) (Rule, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return Rule{}, fmt.Errorf("Empty alert rule")
+	}
+	r := Rule{Label: fields[0], text: strings.Join(fields, " ")}
+	fields = fields[1:]
+	if len(fields) >= 2 && fields[0] == "in" {
+		parts := strings.Split(fields[1], ":")
+		if len(parts) != 4 {
+			return Rule{}, fmt.Errorf("Alert zone should be X0:Y0:X1:Y1: %q", s)
+		}
+		for i, p := range parts {
+			v, err := strconv.ParseFloat(p, 64)
+			if err != nil || v < 0 || v > 1 {
+				return Rule{}, fmt.Errorf("Alert zone coordinates should be from 0 to 1: %q", s)
+			}
+			r.Zone[i] = v
+		}
+		if r.Zone[0] >= r.Zone[2] || r.Zone[1] >= r.Zone[3] {
+			return Rule{}, fmt.Errorf("Alert zone is empty: %q", s)
+		}
+		fields = fields[2:]
+	}
+	if len(fields) >= 1 && fields[0] != "every" {
+		hours := strings.Split(fields[0], "-")
+		var err error
+		if len(hours) != 2 {
+			err = fmt.Errorf("no range")
+		} else if r.From, err = parseClock(hours[0]); err == nil {
+			r.To, err = parseClock(hours[1])
+		}
+		if err != nil {
+			return Rule{}, fmt.Errorf("Alert hours should be HH:MM-HH:MM: %q", s)
+		}
+		fields = fields[1:]
+	}
+	if len(fields) == 2 && fields[0] == "every" {
+		var err error
+		if r.Cooldown, err = time.ParseDuration(fields[1]); err != nil || r.Cooldown <= 0 {
+			return Rule{}, fmt.Errorf("Alert cooldown should be a positive duration like 10m: %q", s)
+		}
+		fields = fields[2:]
+	}
+	if len(fields) > 0 {
+		return Rule{}, fmt.Errorf("Alert rule should be LABEL [in X0:Y0:X1:Y1] [HH:MM-HH:MM] [every DURATION]: %q", s)
+	}
+	return r, nil
+}
+
+// parseClock returns the minutes since midnight of the time of day given as HH:MM
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Active tells whether the time is within the hours of the rule
+func (r Rule) Active(t time.Time) bool {
+	if r.From == r.To {
+		return true
+	}
+	m := t.Hour()*60 + t.Minute()
+	if r.From < r.To {
+		return m >= r.From && m < r.To
+	}
+	return m >= r.From || m < r.To
+}
+
+// Match returns the detections on a frame of the size matching the rule at the time
+func (r Rule) Match(dets []detection.Detection, size image.Point, t time.Time) []detection.Detection {
+	if !r.Active(t) {
+		return nil
+	}
+	var zone image.Rectangle
+	if r.Zone != [4]float64{} {
+		zone = image.Rect(int(r.Zone[0]*float64(size.X)), int(r.Zone[1]*float64(size.Y)),
+			int(r.Zone[2]*float64(size.X)), int(r.Zone[3]*float64(size.Y)))
+	}
+	var matched []detection.Detection
+	for _, d := range dets {
+		if r.Label != "*" && d.Label != r.Label {
+			continue
+		}
+		center := d.BBox.Min.Add(d.BBox.Max).Div(2)
+		if !zone.Empty() && !center.In(zone) {
+			continue
+		}
+		matched = append(matched, d)
+	}
+	return matched
+}
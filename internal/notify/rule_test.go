@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/detection"
+)
+
+func TestParseRule(t *testing.T) {
+	r, err := ParseRule(" person  in 0.5:0:1:1 22:00-06:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Label != "person" || r.Zone != [4]float64{0.5, 0, 1, 1} || r.From != 22*60 || r.To != 6*60 ||
+		r.String() != "person in 0.5:0:1:1 22:00-06:00" {
+		t.Errorf("Unexpected rule %+v", r)
+	}
+	if r, err := ParseRule("* 08:30-17:00"); err != nil || r.Zone != [4]float64{} || r.From != 8*60+30 || r.Cooldown != 0 {
+		t.Errorf("Unexpected rule %+v, %v", r, err)
+	}
+	if r, err := ParseRule("car every 10m"); err != nil || r.Cooldown != 10*time.Minute || r.From != r.To {
+		t.Errorf("Unexpected rule %+v, %v", r, err)
+	}
+	for _, s := range []string{"", "person in 0:0:1", "person in 0.5:0:0.2:1", "person 22:00", "person 25:00-06:00",
+		"person in 0:0:1:2", "person at night",
+		"person every", "person every -1m", "person every 1m 22:00-06:00"} {
+		if _, err := ParseRule(s); err == nil {
+			t.Errorf("Expected an error for %q", s)
+		}
+	}
+}
+
+func TestRuleMatch(t *testing.T) {
+	r, _ := ParseRule("person in 0.5:0:1:1 22:00-06:00")
+	dets := []detection.Detection{
+		{Label: "person", BBox: image.Rect(300, 100, 400, 300)}, // Right half
+		{Label: "person", BBox: image.Rect(0, 100, 100, 300)},   // Left half
+		{Label: "dog", BBox: image.Rect(300, 100, 400, 300)},
+	}
+	size := image.Pt(640, 480)
+	night := time.Date(2024, 1, 1, 23, 15, 0, 0, time.Local)
+	if m := r.Match(dets, size, night); len(m) != 1 || m[0].BBox.Min.X != 300 {
+		t.Errorf("Expected the person in the zone, got %v", m)
+	}
+	if m := r.Match(dets, size, night.Add(8*time.Hour)); len(m) != 0 {
+		t.Errorf("Expected no match in the morning, got %v", m)
+	}
+	any, _ := ParseRule("*")
+	if m := any.Match(dets, size, night); len(m) != 3 {
+		t.Errorf("Expected all detections, got %v", m)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	rl := rateLimit{last: map[int]time.Time{}}
+	t0 := time.Now()
+	if !rl.Allow(0, t0, time.Minute) || rl.Allow(0, t0.Add(30*time.Second), time.Minute) || !rl.Allow(1, t0, time.Minute) ||
+		!rl.Allow(0, t0.Add(time.Minute), time.Minute) {
+		t.Error("Each rule should fire once per interval")
+	}
+}
@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+
+	"github.com/marchevska/gocv-examples/internal/storage"
+)
+
+// Upload stores the snapshot and the clip of each alert in a bucket under the label of its first detection
+type Upload struct {
+	Uploader *storage.Uploader
+}
+
+// Notify uploads the snapshot and the clip. They are uploaded before Notify returns, since
+// the clip is removed once the alert is sent
+func (u Upload) Notify(ctx context.Context, a Alert) error {
+	name := "alert_" + a.Time.Format("20060102_150405")
+	if a.Snapshot != nil {
+		f, err := ioutil.TempFile("", name+"_*.jpg")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		_, err = f.Write(a.Snapshot)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		if err := u.Uploader.Put(ctx, f.Name(), u.Uploader.Key(a.Label(), a.Time, name+".jpg")); err != nil {
+			return err
+		}
+	}
+	if a.Clip != "" {
+		return u.Uploader.Put(ctx, a.Clip, u.Uploader.Key(a.Label(), a.Time, name+".avi"))
+	}
+	return nil
+}
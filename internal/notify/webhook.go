@@ -0,0 +1,110 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/detection"
+)
+
+// Kinds of webhooks, telling the format of the request
+const (
+	WebhookSlack    = "slack"
+	WebhookTelegram = "telegram"
+	WebhookJSON     = "json"
+)
+
+// Webhook posts alerts to a URL. The kind is told by the host:
+//   - Slack incoming webhooks (hooks.slack.com) get the text only, since they cannot take files
+//   - Telegram bot API (api.telegram.org) gets the snapshot with the text as its caption through
+//     sendPhoto, or the text through sendMessage; the chat is given by the chat_id parameter of the URL
+//   - other URLs get a JSON object with the text, rule, time, detections and the snapshot in base64
+type Webhook struct {
+	URL    string
+	Kind   string
+	Client *http.Client
+}
+
+// NewWebhook creates a webhook for the URL
+func NewWebhook(rawURL string) (*Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("Webhook should be an HTTP(S) URL: %q", rawURL)
+	}
+	kind := WebhookJSON
+	switch strings.ToLower(u.Hostname()) {
+	case "hooks.slack.com":
+		kind = WebhookSlack
+	case "api.telegram.org":
+		kind = WebhookTelegram
+		if u.Query().Get("chat_id") == "" {
+			return nil, fmt.Errorf("Telegram webhook needs the chat_id parameter: %s", u.Redacted())
+		}
+	}
+	return &Webhook{URL: rawURL, Kind: kind, Client: &http.Client{Timeout: sendTimeout}}, nil
+}
+
+// jsonAlert is the body of JSON webhooks
+type jsonAlert struct {
+	Text       string                `json:"text"`
+	Rule       string                `json:"rule"`
+	Time       time.Time             `json:"time"`
+	Detections []detection.Detection `json:"detections"`
+	Snapshot   []byte                `json:"snapshot,omitempty"` // JPEG, base64 encoded by encoding/json
+}
+
+// Notify posts the alert
+func (w *Webhook) Notify(ctx context.Context, a Alert) error {
+	var body bytes.Buffer
+	contentType := "application/json"
+	target := w.URL
+	switch {
+	case w.Kind == WebhookSlack:
+		json.NewEncoder(&body).Encode(map[string]string{"text": a.Text})
+	case w.Kind == WebhookTelegram && a.Snapshot != nil:
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("caption", a.Text)
+		part, err := mw.CreateFormFile("photo", "snapshot.jpg")
+		if err != nil {
+			return err
+		}
+		part.Write(a.Snapshot)
+		mw.Close()
+		contentType = mw.FormDataContentType()
+	case w.Kind == WebhookTelegram:
+		target = strings.Replace(target, "/sendPhoto", "/sendMessage", 1)
+		json.NewEncoder(&body).Encode(map[string]string{"text": a.Text})
+	default:
+		json.NewEncoder(&body).Encode(jsonAlert{Text: a.Text, Rule: a.Rule.String(), Time: a.Time,
+			Detections: a.Detections, Snapshot: a.Snapshot})
+	}
+
+	req, err := http.NewRequest(http.MethodPost, target, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := w.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		// The URL may hold a token, so it is left out
+		if ue, ok := err.(*url.Error); ok {
+			err = ue.Err
+		}
+		return fmt.Errorf("%s webhook: %v", w.Kind, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s webhook returned %s: %s", w.Kind, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewWebhook(t *testing.T) {
+	tests := map[string]string{
+		"https://hooks.slack.com/services/T/B/X":                   WebhookSlack,
+		"https://api.telegram.org/bot123:abc/sendPhoto?chat_id=42": WebhookTelegram,
+		"http://localhost:9000/alerts":                             WebhookJSON,
+	}
+	for u, kind := range tests {
+		if w, err := NewWebhook(u); err != nil || w.Kind != kind {
+			t.Errorf("NewWebhook(%q) = %v, %v; want kind %s", u, w, err, kind)
+		}
+	}
+	for _, u := range []string{"localhost:9000", "ftp://host/x", "https://api.telegram.org/bot123:abc/sendPhoto"} {
+		if _, err := NewWebhook(u); err == nil {
+			t.Errorf("Expected an error for %q", u)
+		}
+	}
+}
+
+func TestWebhookNotify(t *testing.T) {
+	var path, contentType, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		path, contentType, body = r.URL.Path, r.Header.Get("Content-Type"), string(data)
+		if path == "/missing" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	rule, _ := ParseRule("person")
+	a := Alert{Rule: rule, Text: "1 person", Snapshot: []byte{0xff, 0xd8}}
+	w, _ := NewWebhook(server.URL + "/hook")
+	if err := w.Notify(context.Background(), a); err != nil {
+		t.Fatal(err)
+	}
+	var got jsonAlert
+	if err := json.Unmarshal([]byte(body), &got); err != nil || got.Text != "1 person" || got.Rule != "person" ||
+		len(got.Snapshot) != 2 {
+		t.Errorf("Unexpected JSON alert %s, %v", body, err)
+	}
+
+	w.Kind = WebhookTelegram
+	w.URL = server.URL + "/botX/sendPhoto?chat_id=1"
+	if err := w.Notify(context.Background(), a); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(contentType, "multipart/form-data") || !strings.Contains(body, "1 person") {
+		t.Errorf("Expected the snapshot as a form, got %s: %s", contentType, body)
+	}
+	a.Snapshot = nil
+	if err := w.Notify(context.Background(), a); err != nil || path != "/botX/sendMessage" {
+		t.Errorf("Expected sendMessage without a snapshot, got %s, %v", path, err)
+	}
+
+	w.URL = server.URL + "/missing"
+	if err := w.Notify(context.Background(), a); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected an error status, got %v", err)
+	}
+}
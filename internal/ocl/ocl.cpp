@@ -0,0 +1,68 @@
+#include <cstdlib>
+#include <cstring>
+#include <opencv2/core/ocl.hpp>
+#include <opencv2/imgproc.hpp>
+#include <opencv2/video/tracking.hpp>
+#include "ocl.h"
+
+bool OCL_HaveOpenCL() {
+    return cv::ocl::haveOpenCL();
+}
+
+bool OCL_UseOpenCL() {
+    return cv::ocl::useOpenCL();
+}
+
+void OCL_SetUseOpenCL(bool use) {
+    cv::ocl::setUseOpenCL(use);
+}
+
+char* OCL_DeviceName() {
+    std::string name;
+    if (cv::ocl::useOpenCL()) {
+        name = cv::ocl::Device::getDefault().name();
+    }
+    return strdup(name.c_str());
+}
+
+bool OCL_Resize(void* src, void* dst, int width, int height, double fx, double fy, int interp) {
+    cv::Mat* s = (cv::Mat*)src;
+    cv::Mat* d = (cv::Mat*)dst;
+    if (!cv::ocl::useOpenCL()) {
+        cv::resize(*s, *d, cv::Size(width, height), fx, fy, interp);
+        return false;
+    }
+    cv::UMat out;
+    cv::resize(s->getUMat(cv::ACCESS_READ), out, cv::Size(width, height), fx, fy, interp);
+    out.copyTo(*d);
+    return true;
+}
+
+bool OCL_GaussianBlur(void* src, void* dst, int kwidth, int kheight, double sigmaX, double sigmaY, int border) {
+    cv::Mat* s = (cv::Mat*)src;
+    cv::Mat* d = (cv::Mat*)dst;
+    if (!cv::ocl::useOpenCL()) {
+        cv::GaussianBlur(*s, *d, cv::Size(kwidth, kheight), sigmaX, sigmaY, border);
+        return false;
+    }
+    cv::UMat out;
+    cv::GaussianBlur(s->getUMat(cv::ACCESS_READ), out, cv::Size(kwidth, kheight), sigmaX, sigmaY, border);
+    out.copyTo(*d);
+    return true;
+}
+
+bool OCL_CalcOpticalFlowFarneback(void* prev, void* next, void* flow, double pyrScale, int levels, int winsize,
+                                  int iterations, int polyN, double polySigma, int flags) {
+    cv::Mat* p = (cv::Mat*)prev;
+    cv::Mat* n = (cv::Mat*)next;
+    cv::Mat* f = (cv::Mat*)flow;
+    if (!cv::ocl::useOpenCL()) {
+        cv::calcOpticalFlowFarneback(*p, *n, *f, pyrScale, levels, winsize, iterations, polyN, polySigma, flags);
+        return false;
+    }
+    cv::UMat out;
+    cv::calcOpticalFlowFarneback(p->getUMat(cv::ACCESS_READ), n->getUMat(cv::ACCESS_READ), out,
+                                 pyrScale, levels, winsize, iterations, polyN, polySigma, flags);
+    out.copyTo(*f);
+    return true;
+}
@@ -0,0 +1,116 @@
+// Package ocl enables the transparent OpenCL path of OpenCV, which is not exposed by GoCV, and runs
+// heavy per-frame operations on it: resize, Gaussian blur and Farneback optical flow.
+// With -opencl, these functions copy their input to a UMat, so that OpenCV runs them on the default
+// OpenCL device, e.g. an integrated GPU, and copy the result back. Without it, or when OpenCV has
+// no OpenCL device, they run on the CPU like their gocv counterparts.
+// Copies between host and device memory cost time too, so OpenCL pays off for large frames only;
+// the device can be chosen with the OPENCV_OPENCL_DEVICE environment variable of OpenCV.
+// Report logs how many operations actually ran on OpenCL
+package ocl
+
+/*
+#cgo !windows pkg-config: opencv4
+#cgo CXXFLAGS: --std=c++11
+#include <stdlib.h>
+#include "ocl.h"
+*/
+import "C"
+
+import (
+	"flag"
+	"image"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"gocv.io/x/gocv"
+)
+
+var (
+	requested bool
+	// Operations run on OpenCL and on the CPU
+	oclOps, cpuOps int64
+)
+
+// RegisterFlags adds the -opencl flag to fs
+func RegisterFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&requested, "opencl", false, "Run resize, blur and optical flow with OpenCL when available")
+}
+
+// Available tells whether OpenCV has an OpenCL device
+func Available() bool {
+	return bool(C.OCL_HaveOpenCL())
+}
+
+// Enabled tells whether OpenCV currently uses OpenCL
+func Enabled() bool {
+	return bool(C.OCL_UseOpenCL())
+}
+
+// Device returns the name of the default OpenCL device, empty if OpenCL is not used
+func Device() string {
+	name := C.OCL_DeviceName()
+	defer C.free(unsafe.Pointer(name))
+	return C.GoString(name)
+}
+
+// Setup turns OpenCL on if -opencl was given and OpenCV has a device, and off otherwise,
+// since OpenCV may use it by default. It logs the outcome and returns whether OpenCL is used
+func Setup() bool {
+	if !requested {
+		C.OCL_SetUseOpenCL(false)
+		return false
+	}
+	if !Available() {
+		logging.Warnf("OpenCL is not available in this OpenCV build or has no device, using the CPU")
+		return false
+	}
+	C.OCL_SetUseOpenCL(true)
+	if !Enabled() {
+		logging.Warnf("OpenCL could not be enabled, using the CPU")
+		return false
+	}
+	logging.Infof("Using OpenCL on %s", Device())
+	return true
+}
+
+// Report logs how many operations ran on OpenCL, to tell whether it was actually used
+func Report() {
+	used, cpu := atomic.LoadInt64(&oclOps), atomic.LoadInt64(&cpuOps)
+	if used+cpu == 0 || !requested {
+		return
+	}
+	logging.Infof("OpenCL ran %d of %d operations", used, used+cpu)
+}
+
+// Counts an operation
+func count(onOpenCL C.bool) {
+	if onOpenCL {
+		atomic.AddInt64(&oclOps, 1)
+	} else {
+		atomic.AddInt64(&cpuOps, 1)
+	}
+}
+
+// Returns the cv::Mat pointer of the Mat
+func ptr(m *gocv.Mat) unsafe.Pointer {
+	return unsafe.Pointer(m.Ptr())
+}
+
+// Resize works as gocv.Resize
+func Resize(src gocv.Mat, dst *gocv.Mat, sz image.Point, fx, fy float64, interp gocv.InterpolationFlags) {
+	count(C.OCL_Resize(ptr(&src), ptr(dst), C.int(sz.X), C.int(sz.Y), C.double(fx), C.double(fy), C.int(interp)))
+}
+
+// GaussianBlur works as gocv.GaussianBlur
+func GaussianBlur(src gocv.Mat, dst *gocv.Mat, ksize image.Point, sigmaX, sigmaY float64, border gocv.BorderType) {
+	count(C.OCL_GaussianBlur(ptr(&src), ptr(dst), C.int(ksize.X), C.int(ksize.Y), C.double(sigmaX), C.double(sigmaY),
+		C.int(border)))
+}
+
+// CalcOpticalFlowFarneback works as gocv.CalcOpticalFlowFarneback
+func CalcOpticalFlowFarneback(prev, next gocv.Mat, flow *gocv.Mat, pyrScale float64, levels, winsize, iterations,
+	polyN int, polySigma float64, flags int) {
+	count(C.OCL_CalcOpticalFlowFarneback(ptr(&prev), ptr(&next), ptr(flow), C.double(pyrScale), C.int(levels),
+		C.int(winsize), C.int(iterations), C.int(polyN), C.double(polySigma), C.int(flags)))
+}
@@ -0,0 +1,28 @@
+#ifndef _GOCV_EXAMPLES_OCL_H_
+#define _GOCV_EXAMPLES_OCL_H_
+
+#include <stdbool.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+// Mats are the cv::Mat pointers of gocv.Mat
+
+bool OCL_HaveOpenCL();
+bool OCL_UseOpenCL();
+void OCL_SetUseOpenCL(bool use);
+// Name of the default OpenCL device, to be freed by the caller
+char* OCL_DeviceName();
+
+// Operations run on UMats if OpenCL is used, and return whether it was
+bool OCL_Resize(void* src, void* dst, int width, int height, double fx, double fy, int interp);
+bool OCL_GaussianBlur(void* src, void* dst, int kwidth, int kheight, double sigmaX, double sigmaY, int border);
+bool OCL_CalcOpticalFlowFarneback(void* prev, void* next, void* flow, double pyrScale, int levels, int winsize,
+                                  int iterations, int polyN, double polySigma, int flags);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif //_GOCV_EXAMPLES_OCL_H_
@@ -0,0 +1,34 @@
+package ocl
+
+import (
+	"image"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+func TestResizeAndBlur(t *testing.T) {
+	img := gocv.NewMatWithSize(40, 60, gocv.MatTypeCV8U)
+	defer img.Close()
+	gocv.RandU(&img, gocv.NewScalar(0, 0, 0, 0), gocv.NewScalar(255, 0, 0, 0))
+
+	// The OpenCL path, when there is a device, may differ from the CPU by rounding
+	for _, use := range []bool{false, true} {
+		requested = use
+		Setup()
+		want, got := gocv.NewMat(), gocv.NewMat()
+		gocv.Resize(img, &want, image.Pt(30, 20), 0, 0, gocv.InterpolationArea)
+		Resize(img, &got, image.Pt(30, 20), 0, 0, gocv.InterpolationArea)
+		gocv.GaussianBlur(want, &want, image.Pt(5, 5), 0, 0, gocv.BorderDefault)
+		GaussianBlur(got, &got, image.Pt(5, 5), 0, 0, gocv.BorderDefault)
+		if got.Cols() != 30 || got.Rows() != 20 {
+			t.Errorf("OpenCL %v: size %dx%d, want 30x20", use, got.Cols(), got.Rows())
+		} else if norm := gocv.NormWithMats(want, got, gocv.NormInf); norm > 1 {
+			t.Errorf("OpenCL %v: result differs from gocv by %v", use, norm)
+		}
+		want.Close()
+		got.Close()
+	}
+	requested = false
+	Setup()
+}
@@ -0,0 +1,11 @@
+//go:build cuda
+// +build cuda
+
+package probe
+
+import "gocv.io/x/gocv/cuda"
+
+// cudaDevices returns the number of CUDA devices visible to OpenCV
+func cudaDevices() (int, error) {
+	return cuda.GetCudaEnabledDeviceCount(), nil
+}
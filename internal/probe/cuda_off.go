@@ -0,0 +1,11 @@
+//go:build !cuda
+// +build !cuda
+
+package probe
+
+import "errors"
+
+// cudaDevices cannot query CUDA without the cuda tag
+func cudaDevices() (int, error) {
+	return 0, errors.New("Not checked, build with -tags cuda")
+}
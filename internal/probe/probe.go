@@ -0,0 +1,239 @@
+// Package probe checks at startup that the environment provides what an example needs:
+// OpenCV version, DNN backends, CUDA devices, video codecs and cameras. Missing pieces are
+// reported with a hint on how to fix them, rather than failing later with empty Mats.
+package probe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"gocv.io/x/gocv"
+)
+
+const installHint = "Install or upgrade OpenCV as described in https://github.com/hybridgroup/gocv#installation"
+
+// Requirements of an example which are checked by Run
+type Requirements struct {
+	MinOpenCV string   // Minimal OpenCV version, e.g. "4.4.0", empty for any
+	DNN       bool     // The example runs a DNN model, available backends are reported
+	CUDA      bool     // A CUDA device is required
+	Codecs    []string // FourCC codes of codecs used to write video files
+	Camera    int      // ID of the camera used as input, negative if there is none
+}
+
+// Result of a single check. Hint tells how to fix a failed check
+type Result struct {
+	Name   string
+	OK     bool
+	Detail string
+	Hint   string
+}
+
+// Report holds results of all checks
+type Report []Result
+
+// Run checks the environment against the requirements
+func Run(req Requirements) Report {
+	var r Report
+	r = append(r, checkOpenCV(req.MinOpenCV))
+	if req.DNN || req.CUDA {
+		r = append(r, checkCUDA(req.CUDA))
+	}
+	if req.DNN {
+		r = append(r, checkDNN())
+	}
+	for _, codec := range req.Codecs {
+		r = append(r, checkCodec(codec))
+	}
+	if req.Camera >= 0 {
+		r = append(r, checkCamera(req.Camera))
+	}
+	return r
+}
+
+// CameraID returns the camera ID if the input is one, as in videoio.OpenSource, or -1
+func CameraID(input string) int {
+	if id, err := strconv.Atoi(input); err == nil && id >= 0 {
+		return id
+	}
+	return -1
+}
+
+// Err returns an error naming the failed checks, or nil if all passed
+func (r Report) Err() error {
+	var failed []string
+	for _, res := range r {
+		if !res.OK {
+			failed = append(failed, res.Name)
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("Environment check failed: %s", strings.Join(failed, ", "))
+}
+
+// Log logs passed checks at debug level and failed checks as errors with their hints
+func (r Report) Log() {
+	for _, res := range r {
+		if res.OK {
+			logging.Debugf("Check %s: %s", res.Name, res.Detail)
+		} else {
+			logging.Errorf("Check %s failed: %s. %s", res.Name, res.Detail, res.Hint)
+		}
+	}
+}
+
+// Print writes all results as a table, with hints for failed checks
+func (r Report) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, res := range r {
+		status := "ok"
+		if !res.OK {
+			status = "FAILED"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", res.Name, status, res.Detail)
+		if !res.OK && res.Hint != "" {
+			fmt.Fprintf(tw, "\t\t%s\n", res.Hint)
+		}
+	}
+	return tw.Flush()
+}
+
+func checkOpenCV(minVersion string) Result {
+	version := gocv.OpenCVVersion()
+	res := Result{Name: "OpenCV", OK: true, Detail: fmt.Sprintf("OpenCV %s, GoCV %s", version, gocv.Version())}
+	if minVersion != "" && !versionAtLeast(version, minVersion) {
+		res.OK = false
+		res.Detail += fmt.Sprintf(", %s or newer is required", minVersion)
+		res.Hint = installHint
+	}
+	return res
+}
+
+func checkCUDA(required bool) Result {
+	res := Result{Name: "CUDA", OK: true}
+	n, err := cudaDevices()
+	switch {
+	case err != nil:
+		res.Detail = err.Error()
+		res.OK = !required
+	case n == 0:
+		res.Detail = "No CUDA devices found"
+		res.OK = !required
+	default:
+		res.Detail = fmt.Sprintf("%d CUDA devices", n)
+	}
+	if !res.OK {
+		res.Hint = "Install the NVIDIA driver and CUDA, build OpenCV with CUDA support and the example with -tags cuda"
+	}
+	return res
+}
+
+// GoCV does not list DNN backends, CUDA backend is usable if OpenCV sees a CUDA device
+func checkDNN() Result {
+	backends := []string{gocv.NetBackendOpenCV.String()}
+	if n, err := cudaDevices(); err == nil && n > 0 {
+		backends = append(backends, gocv.NetBackendCUDA.String())
+	}
+	return Result{Name: "DNN", OK: true, Detail: "Backends: " + strings.Join(backends, ", ")}
+}
+
+// Writes a tiny video file to make sure the codec can be opened
+func checkCodec(codec string) Result {
+	res := Result{Name: "codec " + codec, OK: true, Detail: "Video files can be written"}
+	fail := func(detail string) Result {
+		res.OK = false
+		res.Detail = detail
+		res.Hint = "Build OpenCV with FFmpeg or GStreamer support, or use another codec such as MJPG"
+		return res
+	}
+	dir, err := ioutil.TempDir("", "probe")
+	if err != nil {
+		return fail(err.Error())
+	}
+	defer os.RemoveAll(dir)
+	vw, err := gocv.VideoWriterFile(filepath.Join(dir, "probe.avi"), codec, 25, 64, 48, true)
+	if err != nil {
+		return fail(err.Error())
+	}
+	defer vw.Close()
+	if !vw.IsOpened() {
+		return fail("Cannot open video writer")
+	}
+	img := gocv.NewMatWithSize(48, 64, gocv.MatTypeCV8UC3)
+	defer img.Close()
+	if err := vw.Write(img); err != nil {
+		return fail(err.Error())
+	}
+	return res
+}
+
+// Opens the camera and reads one frame
+func checkCamera(id int) Result {
+	res := Result{Name: fmt.Sprintf("camera %d", id), OK: true}
+	hint := "Check that the camera is connected and not used by another program"
+	if runtime.GOOS == "linux" {
+		hint += fmt.Sprintf(", and that the user can read /dev/video%d (e.g. is in the video group)", id)
+	}
+	fail := func(err error) Result {
+		res.OK = false
+		res.Detail = err.Error()
+		res.Hint = hint
+		return res
+	}
+	vc, err := gocv.OpenVideoCapture(id)
+	if err != nil {
+		return fail(err)
+	}
+	defer vc.Close()
+	if !vc.IsOpened() {
+		return fail(errors.New("Cannot open camera"))
+	}
+	img := gocv.NewMat()
+	defer img.Close()
+	if !vc.Read(&img) || img.Empty() {
+		return fail(errors.New("Cannot read frame"))
+	}
+	res.Detail = fmt.Sprintf("%dx%d frames", img.Cols(), img.Rows())
+	return res
+}
+
+// versionAtLeast compares dotted versions like "4.5.5" or "4.6.0-dev" numerically
+func versionAtLeast(version, min string) bool {
+	v, m := versionParts(version), versionParts(min)
+	for i := range m {
+		vi := 0 // Missing parts are zeros
+		if i < len(v) {
+			vi = v[i]
+		}
+		if vi != m[i] {
+			return vi > m[i]
+		}
+	}
+	return true
+}
+
+func versionParts(version string) []int {
+	if i := strings.IndexAny(version, "-+ "); i >= 0 {
+		version = version[:i]
+	}
+	var parts []int
+	for _, s := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
@@ -0,0 +1,45 @@
+package probe
+
+import "testing"
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version, min string
+		want         bool
+	}{
+		{"4.5.5", "4.4.0", true},
+		{"4.4.0", "4.4.0", true},
+		{"4.3.0", "4.4.0", false},
+		{"4.10.0", "4.4.0", true},
+		{"4.6.0-dev", "4.6.0", true},
+		{"4.5", "4.5.1", false},
+		{"5", "4.4", true},
+	}
+	for _, tt := range tests {
+		if got := versionAtLeast(tt.version, tt.min); got != tt.want {
+			t.Errorf("versionAtLeast(%q, %q) = %v, want %v", tt.version, tt.min, got, tt.want)
+		}
+	}
+}
+
+func TestCameraID(t *testing.T) {
+	if id := CameraID("1"); id != 1 {
+		t.Errorf("Expected camera 1, got %d", id)
+	}
+	for _, input := range []string{"video.mp4", "rtsp://host/stream", "-1", ""} {
+		if id := CameraID(input); id != -1 {
+			t.Errorf("Expected no camera for %q, got %d", input, id)
+		}
+	}
+}
+
+func TestReportErr(t *testing.T) {
+	r := Report{{Name: "OpenCV", OK: true}, {Name: "camera 0"}, {Name: "codec X264"}}
+	err := r.Err()
+	if err == nil || err.Error() != "Environment check failed: camera 0, codec X264" {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if err := r[:1].Err(); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+// Package shutdown handles SIGINT and SIGTERM: the first signal cancels the context passed to
+// the processing loops so that they stop and the program exits normally, a second signal or
+// a loop not stopping in time exits immediately. Cleanup functions only run in Close, after
+// the loops have returned, since closing Mats or models under a running loop would crash it.
+package shutdown
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/logging"
+)
+
+// DefaultTimeout is the time given to the program to exit after the first signal
+const DefaultTimeout = 5 * time.Second
+
+type cleanup struct {
+	name string
+	fn   func() error
+}
+
+// Handler cancels its context on a signal and runs cleanup functions on exit
+type Handler struct {
+	ctx      context.Context
+	cancel   context.CancelFunc
+	signals  chan os.Signal
+	mu       sync.Mutex
+	cleanups []cleanup
+	once     sync.Once
+}
+
+// New installs signal handlers. After the first signal the program has timeout to exit
+// before it is terminated without running the cleanup functions
+func New(timeout time.Duration) *Handler {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := &Handler{ctx: ctx, cancel: cancel, signals: make(chan os.Signal, 2)}
+	signal.Notify(h.signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		sig, ok := <-h.signals
+		if !ok {
+			return
+		}
+		logging.Infof("Received %v, stopping", sig)
+		cancel()
+
+		select {
+		case _, ok := <-h.signals:
+			if !ok {
+				return
+			}
+			logging.Warnf("Received second signal, exiting without cleanup")
+		case <-time.After(timeout):
+			logging.Warnf("Not stopped in %v, exiting without cleanup", timeout)
+		}
+		os.Exit(1)
+	}()
+	return h
+}
+
+// Context returns the context cancelled by the first signal
+func (h *Handler) Context() context.Context {
+	return h.ctx
+}
+
+// OnClose registers a cleanup function. Cleanup functions run in reverse order of registration,
+// like deferred calls
+func (h *Handler) OnClose(name string, fn func() error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cleanups = append(h.cleanups, cleanup{name, fn})
+}
+
+// Close runs the cleanup functions once and stops handling signals
+func (h *Handler) Close() {
+	h.once.Do(func() {
+		signal.Stop(h.signals)
+		close(h.signals)
+		h.cancel()
+
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		for i := len(h.cleanups) - 1; i >= 0; i-- {
+			c := h.cleanups[i]
+			if err := c.fn(); err != nil {
+				logging.Errorf("Error closing %s: %v", c.name, err)
+			}
+		}
+		h.cleanups = nil
+	})
+}
@@ -0,0 +1,283 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/logging"
+)
+
+const (
+	batchLinger   = 500 * time.Millisecond // Wait for more files to fill a batch
+	probeInterval = 30 * time.Second       // Between attempts to reach the storage again
+	spoolExt      = ".json"
+)
+
+// Queue holds the parameters of queueing uploads
+type Queue struct {
+	Batch int     // Files uploaded at once
+	Rate  float64 // Uploads started per second at most, unlimited if 0
+	Spool string  // Directory keeping uploads while the storage is unreachable, none if empty
+}
+
+// Uploads queued files, and spooled ones every probe interval
+func (up *Uploader) run() {
+	defer up.wg.Done()
+	var probe <-chan time.Time
+	if up.spool != nil {
+		ticker := time.NewTicker(probeInterval)
+		defer ticker.Stop()
+		probe = ticker.C
+		up.flush()
+	}
+	for {
+		select {
+		case u, ok := <-up.queue:
+			if !ok {
+				return
+			}
+			batch := up.collect(u)
+			if up.offline {
+				up.save(batch)
+				continue
+			}
+			up.send(batch, up.retries)
+		case <-probe:
+			up.flush()
+		}
+	}
+}
+
+// Returns a batch of queued files starting with u, waiting a little for more
+func (up *Uploader) collect(u upload) []upload {
+	batch := []upload{u}
+	if up.batch == 1 {
+		return batch
+	}
+	linger := time.NewTimer(batchLinger)
+	defer linger.Stop()
+	for len(batch) < up.batch {
+		select {
+		case u, ok := <-up.queue:
+			if !ok {
+				return batch
+			}
+			batch = append(batch, u)
+		case <-linger.C:
+			return batch
+		}
+	}
+	return batch
+}
+
+// Uploads the files in parallel. Uploaded files are removed from the spool, and from the disk with
+// remove. Files that failed because the storage could not be reached are spooled if there is a spool,
+// switching the uploader offline. It returns whether the storage was reached
+func (up *Uploader) send(batch []upload, retries int) bool {
+	errs := make([]error, len(batch))
+	var wg sync.WaitGroup
+	for i, u := range batch {
+		wg.Add(1)
+		go func(i int, u upload) {
+			defer wg.Done()
+			if up.limiter != nil {
+				up.limiter.wait()
+			}
+			errs[i] = up.put(context.Background(), u.Path, u.Key, retries)
+		}(i, u)
+	}
+	wg.Wait()
+
+	var failed []upload
+	var unreachable error
+	for i, u := range batch {
+		err := errs[i]
+		switch {
+		case err == nil:
+			logging.Debugf("Uploaded %s to %s", u.Path, u.Key)
+			if up.remove {
+				if err := os.Remove(u.Path); err != nil {
+					logging.Warnf("Cannot remove uploaded file: %v", err)
+				}
+			}
+		case up.spool == nil || permanent(err):
+			logging.Errorf("%v", err)
+		default:
+			failed = append(failed, u)
+			unreachable = err
+			continue
+		}
+		if u.spooled != "" {
+			up.spool.remove(u)
+		}
+	}
+	if unreachable == nil {
+		return true
+	}
+	if !up.offline {
+		logging.Warnf("Storage unreachable, keeping uploads in %s until it is back: %v", up.spool.dir, unreachable)
+		up.offline = true
+	}
+	up.save(failed)
+	return false
+}
+
+// Adds the files not spooled yet to the spool, logging those that cannot be kept
+func (up *Uploader) save(batch []upload) {
+	for _, u := range batch {
+		if u.spooled != "" {
+			continue
+		}
+		if err := up.spool.add(u); err != nil {
+			logging.Errorf("%v", err)
+		}
+	}
+}
+
+// Uploads the spooled files in the order they were queued until the spool is empty, the storage
+// cannot be reached or the uploader stops. Offline, the oldest file is tried alone and once
+func (up *Uploader) flush() {
+	for {
+		select {
+		case <-up.stop:
+			return
+		default:
+		}
+		pending, err := up.spool.list()
+		if err != nil {
+			logging.Errorf("%v", err)
+			return
+		}
+		if len(pending) == 0 {
+			return
+		}
+		n, retries := up.batch, up.retries
+		if up.offline {
+			n, retries = 1, 0
+		}
+		if n > len(pending) {
+			n = len(pending)
+		}
+		if !up.send(pending[:n], retries) {
+			return
+		}
+		if up.offline {
+			logging.Infof("Storage reachable again, uploading %d kept files", len(pending)-n)
+			up.offline = false
+		}
+	}
+}
+
+// spool keeps uploads in a directory, one JSON file each, named so that they sort in the order
+// they were added
+type spool struct {
+	dir  string
+	mu   sync.Mutex
+	last int64
+}
+
+// Creates the spool directory if needed
+func openSpool(dir string) (*spool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("Cannot create upload spool: %v", err)
+	}
+	return &spool{dir: dir}, nil
+}
+
+// Adds the upload, with the absolute path of its file
+func (s *spool) add(u upload) error {
+	if abs, err := filepath.Abs(u.Path); err == nil {
+		u.Path = abs
+	}
+	data, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	id := time.Now().UnixNano()
+	if id <= s.last {
+		id = s.last + 1
+	}
+	s.last = id
+	s.mu.Unlock()
+
+	// Written under a temporary name, so that an interrupted write leaves no partial entry
+	name := filepath.Join(s.dir, fmt.Sprintf("%020d", id))
+	if err := ioutil.WriteFile(name+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("Cannot spool upload of %s: %v", u.Path, err)
+	}
+	if err := os.Rename(name+".tmp", name+spoolExt); err != nil {
+		return fmt.Errorf("Cannot spool upload of %s: %v", u.Path, err)
+	}
+	return nil
+}
+
+// Returns the spooled uploads, oldest first. Unreadable entries are logged and skipped
+func (s *spool) list() ([]upload, error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read upload spool: %v", err)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+	var uploads []upload
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), spoolExt) {
+			continue
+		}
+		var u upload
+		data, err := ioutil.ReadFile(filepath.Join(s.dir, f.Name()))
+		if err == nil {
+			err = json.Unmarshal(data, &u)
+		}
+		if err != nil || u.Path == "" || u.Key == "" {
+			logging.Warnf("Skipping invalid spooled upload %s: %v", f.Name(), err)
+			continue
+		}
+		u.spooled = f.Name()
+		uploads = append(uploads, u)
+	}
+	return uploads, nil
+}
+
+// Removes the spooled upload
+func (s *spool) remove(u upload) {
+	if err := os.Remove(filepath.Join(s.dir, u.spooled)); err != nil {
+		logging.Warnf("Cannot remove spooled upload: %v", err)
+	}
+}
+
+// limiter is a token bucket allowing a rate of events per second, in bursts up to its size
+type limiter struct {
+	rate   float64
+	burst  float64
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(rate float64, burst int) *limiter {
+	return &limiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// Waits until an event is allowed
+func (l *limiter) wait() {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
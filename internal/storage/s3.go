@@ -0,0 +1,171 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Layouts of the request time in signatures
+const (
+	amzDate  = "20060102T150405Z"
+	amzShort = "20060102"
+)
+
+// Bucket puts objects to an S3-compatible bucket with requests signed by AWS Signature Version 4.
+// Without an endpoint the bucket is on AWS and addressed by its host name, e.g.
+// https://bucket.s3.eu-west-1.amazonaws.com/key, otherwise by path, e.g. http://minio:9000/bucket/key,
+// as MinIO and most other S3-compatible servers expect
+type Bucket struct {
+	Name      string
+	Endpoint  string // Base URL of the server, empty for AWS
+	Region    string
+	AccessKey string
+	SecretKey string
+	Client    *http.Client
+}
+
+// objectURL returns the URL of the object with the key
+func (b *Bucket) objectURL(key string) string {
+	if b.Endpoint == "" {
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", b.Name, b.Region, awsEscape(key, false))
+	}
+	return strings.TrimRight(b.Endpoint, "/") + "/" + b.Name + "/" + awsEscape(key, false)
+}
+
+// PutFile uploads the file as the object with the key
+func (b *Bucket) PutFile(ctx context.Context, key, path, contentType string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, b.objectURL(key), ioutil.NopCloser(f))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	b.sign(req, hex.EncodeToString(h.Sum(nil)), time.Now())
+	resp, err := b.Client.Do(req.WithContext(ctx))
+	if err != nil {
+		if ue, ok := err.(*url.Error); ok {
+			err = ue.Err
+		}
+		return fmt.Errorf("Cannot upload %s: %v", key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return &StatusError{Code: resp.StatusCode, Status: resp.Status, Message: strings.TrimSpace(string(msg))}
+	}
+	return nil
+}
+
+// StatusError is an upload rejected by the server
+type StatusError struct {
+	Code    int
+	Status  string
+	Message string // Start of the response body, usually an XML error
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("Storage returned %s: %s", e.Status, e.Message)
+}
+
+// Temporary tells whether the upload may succeed when retried: throttling and server errors
+func (e *StatusError) Temporary() bool {
+	return e.Code == http.StatusTooManyRequests || e.Code/100 == 5
+}
+
+// sign adds the date, payload hash and authorization headers of Signature Version 4 to the request.
+// All headers set on the request, and the host, are signed
+func (b *Bucket) sign(req *http.Request, payloadHash string, t time.Time) {
+	t = t.UTC()
+	req.Header.Set("X-Amz-Date", t.Format(amzDate))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonHeaders strings.Builder
+	for _, name := range names {
+		canonHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{req.Method, awsEscape(req.URL.Path, false), canonicalQuery(req.URL),
+		canonHeaders.String(), signed, payloadHash}, "\n")
+	scope := t.Format(amzShort) + "/" + b.Region + "/s3/aws4_request"
+	sum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + t.Format(amzDate) + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := hmacSHA256([]byte("AWS4"+b.SecretKey), t.Format(amzShort))
+	for _, s := range []string{b.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		b.AccessKey, scope, signed, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalQuery returns the query parameters sorted by name and escaped as signatures require
+func canonicalQuery(u *url.URL) string {
+	var params []string
+	for name, values := range u.Query() {
+		for _, v := range values {
+			params = append(params, awsEscape(name, true)+"="+awsEscape(v, true))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// awsEscape percent-encodes everything but unreserved characters, and slashes unless slash is set
+func awsEscape(s string, slash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !slash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,273 @@
+// Package storage uploads saved snapshots and clips to an S3-compatible bucket, e.g. AWS S3 or MinIO,
+// in the background. Objects are keyed by date, camera and class:
+//
+//	PREFIX/2024-01-31/CAMERA/CLASS/motion_20240131_120000.avi
+//
+// so that a day or a camera can be listed and expired by prefix. Failed uploads are retried
+// with a growing delay; uploads that still fail are logged and the local file is kept.
+//
+// Queued files are uploaded in batches (-s3-batch) at a limited rate (-s3-rate). For cameras on
+// flaky links, -s3-spool keeps the uploads that cannot reach the storage in a directory, and they
+// are uploaded when it is reachable again, also after a restart
+package storage
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+)
+
+// Defaults of the options
+const (
+	DefaultRegion  = "us-east-1"
+	DefaultRetries = 3
+	DefaultBatch   = 4
+)
+
+const (
+	queueSize     = 64 // Files waiting to be uploaded, more are dropped
+	uploadTimeout = 5 * time.Minute
+	firstBackoff  = time.Second // Delay before the first retry, doubled for each next one
+)
+
+// Options holds the bucket and upload parameters given by flags
+type Options struct {
+	URL       string // s3://BUCKET[/PREFIX]
+	Endpoint  string
+	Region    string
+	AccessKey string
+	SecretKey string
+	Camera    string
+	Retries   int
+	Remove    bool
+	Queue     Queue
+}
+
+// RegisterFlags adds -s3, -s3-endpoint, -s3-region, -s3-access-key, -s3-secret-key, -s3-camera,
+// -s3-retries, -s3-remove, -s3-batch, -s3-rate and -s3-spool flags to fs
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.URL, "s3", "", "Bucket receiving snapshots and clips as s3://BUCKET[/PREFIX], no uploads if empty")
+	fs.StringVar(&o.Endpoint, "s3-endpoint", "", "URL of an S3-compatible server, e.g. http://localhost:9000 for MinIO, AWS if empty")
+	fs.StringVar(&o.Region, "s3-region", "", "Region of the bucket, AWS_REGION or "+DefaultRegion+" if empty")
+	fs.StringVar(&o.AccessKey, "s3-access-key", "", "Access key ID, AWS_ACCESS_KEY_ID if empty")
+	fs.StringVar(&o.SecretKey, "s3-secret-key", "", "Secret access key, AWS_SECRET_ACCESS_KEY if empty; better given by the environment")
+	fs.StringVar(&o.Camera, "s3-camera", "", "Camera name in object keys, made from the input if empty")
+	fs.IntVar(&o.Retries, "s3-retries", DefaultRetries, "Retries of a failed upload")
+	fs.BoolVar(&o.Remove, "s3-remove", false, "Remove local files once uploaded")
+	fs.IntVar(&o.Queue.Batch, "s3-batch", DefaultBatch, "Files uploaded at once")
+	fs.Float64Var(&o.Queue.Rate, "s3-rate", 0, "Uploads started per second at most, unlimited if 0")
+	fs.StringVar(&o.Queue.Spool, "s3-spool", "", "Directory keeping uploads while the storage is unreachable, uploaded when it is back; none if empty")
+}
+
+// Open creates the uploader of the options, closed by sd. It is nil without a bucket.
+// The camera is named after the input unless the options name it
+func (o *Options) Open(sd *shutdown.Handler, input string) (*Uploader, error) {
+	if o.URL == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(o.URL)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return nil, fmt.Errorf("Bucket should be s3://BUCKET[/PREFIX]: %q", o.URL)
+	}
+	if o.Endpoint != "" {
+		if e, err := url.Parse(o.Endpoint); err != nil || (e.Scheme != "http" && e.Scheme != "https") || e.Host == "" {
+			return nil, fmt.Errorf("Storage endpoint should be an HTTP(S) URL: %q", o.Endpoint)
+		}
+	}
+	if o.Retries < 0 {
+		return nil, fmt.Errorf("Upload retries should not be negative: %d", o.Retries)
+	}
+	b := &Bucket{
+		Name:      u.Host,
+		Endpoint:  o.Endpoint,
+		Region:    firstSet(o.Region, os.Getenv("AWS_REGION"), DefaultRegion),
+		AccessKey: firstSet(o.AccessKey, os.Getenv("AWS_ACCESS_KEY_ID")),
+		SecretKey: firstSet(o.SecretKey, os.Getenv("AWS_SECRET_ACCESS_KEY")),
+		Client:    &http.Client{},
+	}
+	if b.AccessKey == "" || b.SecretKey == "" {
+		return nil, errors.New("Storage needs an access key and a secret key, e.g. from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	camera := o.Camera
+	if camera == "" {
+		camera = CameraName(input)
+	}
+	up, err := NewUploader(b, strings.Trim(u.Path, "/"), keyPart(camera), o.Retries, o.Remove, o.Queue)
+	if err != nil {
+		return nil, err
+	}
+	sd.OnClose("uploads", up.Close)
+	logging.Infof("Uploading to %s as camera %s", o.URL, up.camera)
+	return up, nil
+}
+
+// Returns the first non-empty value
+func firstSet(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// CameraName returns a name of the input usable in object keys: "cam0" for camera 0,
+// the host of a stream URL, or the base name of a file without its extension
+func CameraName(input string) string {
+	if input == "" {
+		return "camera"
+	}
+	if strings.Trim(input, "0123456789") == "" {
+		return "cam" + input
+	}
+	if u, err := url.Parse(input); err == nil && u.Host != "" {
+		return keyPart(u.Hostname())
+	}
+	base := filepath.Base(input)
+	return keyPart(strings.TrimSuffix(base, filepath.Ext(base)))
+}
+
+// keyPart replaces characters other than letters, digits, '-', '_' and '.' with '_',
+// so that the part is a single path element safe in any S3 client
+func keyPart(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, s)
+	if strings.Trim(s, ".") == "" {
+		return "_"
+	}
+	return s
+}
+
+// Uploader uploads files in the background, in batches in the order they are queued
+type Uploader struct {
+	bucket  *Bucket
+	prefix  string
+	camera  string
+	retries int
+	remove  bool
+	batch   int
+	limiter *limiter // nil if unlimited
+	spool   *spool   // nil without a spool directory
+	offline bool     // The storage could not be reached by the last uploads, only used by run
+	queue   chan upload
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// upload is a queued file
+type upload struct {
+	Path    string `json:"path"`
+	Key     string `json:"key"`
+	spooled string // Name of the spooled entry, empty if not spooled
+}
+
+// NewUploader creates an uploader to the bucket and starts uploading. Objects are keyed under
+// the prefix and the camera. Failed uploads are retried the given number of times. With remove,
+// files are removed once uploaded. Files already in the spool of the queue are uploaded first
+func NewUploader(b *Bucket, prefix, camera string, retries int, remove bool, q Queue) (*Uploader, error) {
+	if q.Batch < 1 {
+		return nil, fmt.Errorf("Upload batch should be at least 1, not %d", q.Batch)
+	}
+	if q.Rate < 0 {
+		return nil, fmt.Errorf("Upload rate should not be negative: %v", q.Rate)
+	}
+	up := &Uploader{bucket: b, prefix: prefix, camera: camera, retries: retries, remove: remove,
+		batch: q.Batch, queue: make(chan upload, queueSize), stop: make(chan struct{})}
+	if q.Rate > 0 {
+		up.limiter = newLimiter(q.Rate, q.Batch)
+	}
+	if q.Spool != "" {
+		var err error
+		if up.spool, err = openSpool(q.Spool); err != nil {
+			return nil, err
+		}
+	}
+	up.wg.Add(1)
+	go up.run()
+	return up, nil
+}
+
+// Key returns the key of the file of the class saved at the time
+func (up *Uploader) Key(class string, t time.Time, file string) string {
+	return path.Join(up.prefix, t.Format("2006-01-02"), up.camera, keyPart(class), keyPart(filepath.Base(file)))
+}
+
+// Upload queues the file of the class saved at the time. If the queue is full, the file is
+// spooled, or dropped without a spool
+func (up *Uploader) Upload(file, class string, t time.Time) {
+	u := upload{Path: file, Key: up.Key(class, t, file)}
+	select {
+	case up.queue <- u:
+		return
+	default:
+	}
+	if up.spool != nil {
+		err := up.spool.add(u)
+		if err == nil {
+			return
+		}
+		logging.Errorf("%v", err)
+	}
+	logging.Warnf("Upload of %s dropped, %d files are waiting", file, queueSize)
+}
+
+// Put uploads the file as the object with the key, retrying failures that may be temporary
+func (up *Uploader) Put(ctx context.Context, file, key string) error {
+	return up.put(ctx, file, key, up.retries)
+}
+
+func (up *Uploader) put(ctx context.Context, file, key string, retries int) error {
+	contentType := mime.TypeByExtension(filepath.Ext(file))
+	backoff := firstBackoff
+	for attempt := 0; ; attempt++ {
+		actx, cancel := context.WithTimeout(ctx, uploadTimeout)
+		err := up.bucket.PutFile(actx, key, file, contentType)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt == retries || permanent(err) {
+			return fmt.Errorf("Upload of %s failed: %w", file, err)
+		}
+		logging.Warnf("Upload of %s failed, retrying in %v: %v", file, backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// permanent tells whether retrying the upload cannot help: the file is missing or the server rejected it
+func permanent(err error) bool {
+	var se *StatusError
+	return errors.Is(err, os.ErrNotExist) || (errors.As(err, &se) && !se.Temporary())
+}
+
+// Close uploads the queued files, or spools them if the storage is unreachable, and stops the
+// uploader. Spooled files are left for the next start
+func (up *Uploader) Close() error {
+	close(up.stop)
+	close(up.queue)
+	up.wg.Wait()
+	return nil
+}
@@ -0,0 +1,190 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCameraName(t *testing.T) {
+	tests := map[string]string{
+		"0":                                "cam0",
+		"rtsp://user:pw@10.0.0.5:554/live": "10.0.0.5",
+		"videos/front door.mp4":            "front_door",
+		"":                                 "camera",
+		"picam":                            "picam",
+	}
+	for input, want := range tests {
+		if got := CameraName(input); got != want {
+			t.Errorf("CameraName(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	up := &Uploader{prefix: "site1", camera: "cam0"}
+	at := time.Date(2024, 1, 31, 12, 0, 0, 0, time.Local)
+	if got, want := up.Key("person", at, "/tmp/alert_1.jpg"), "site1/2024-01-31/cam0/person/alert_1.jpg"; got != want {
+		t.Errorf("Key = %s, want %s", got, want)
+	}
+	up.prefix = ""
+	if got, want := up.Key("traffic light", at, "x.avi"), "2024-01-31/cam0/traffic_light/x.avi"; got != want {
+		t.Errorf("Key = %s, want %s", got, want)
+	}
+}
+
+func TestUploader(t *testing.T) {
+	var mu sync.Mutex
+	objects := map[string]string{}
+	failures := 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ") {
+			http.Error(w, "unsigned", http.StatusForbidden)
+			return
+		}
+		if strings.Contains(r.URL.Path, "denied") {
+			http.Error(w, "AccessDenied", http.StatusForbidden)
+			return
+		}
+		if failures > 0 {
+			failures--
+			http.Error(w, "SlowDown", http.StatusServiceUnavailable)
+			return
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		objects[r.URL.Path] = string(data)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "clip.avi")
+	if err := ioutil.WriteFile(file, []byte("frames"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b := &Bucket{Name: "cams", Endpoint: server.URL, Region: DefaultRegion, AccessKey: "k", SecretKey: "s", Client: server.Client()}
+	up, err := NewUploader(b, "", "cam0", 1, true, Queue{Batch: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2024, 1, 31, 12, 0, 0, 0, time.Local)
+	up.Upload(file, "motion", at)
+	up.Close()
+
+	if got := objects["/cams/2024-01-31/cam0/motion/clip.avi"]; got != "frames" {
+		t.Errorf("Uploaded objects %v", objects)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("Uploaded file not removed: %v", err)
+	}
+
+	// Denied uploads are not retried
+	up, _ = NewUploader(b, "", "denied", 3, true, Queue{Batch: 1})
+	start := time.Now()
+	if err := up.Put(context.Background(), filepath.Join(dir, "x"), "denied/x"); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+	ioutil.WriteFile(file, []byte("frames"), 0644)
+	if err := up.Put(context.Background(), file, "denied/clip.avi"); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Put = %v, want 403 error", err)
+	}
+	if time.Since(start) > firstBackoff {
+		t.Error("Permanent errors should not be retried")
+	}
+	up.Close()
+}
+
+func TestSpool(t *testing.T) {
+	var mu sync.Mutex
+	var objects []string
+	down := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if down {
+			http.Error(w, "ServiceUnavailable", http.StatusServiceUnavailable)
+			return
+		}
+		objects = append(objects, r.URL.Path)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	spoolDir := filepath.Join(dir, "spool")
+	b := &Bucket{Name: "cams", Endpoint: server.URL, Region: DefaultRegion, AccessKey: "k", SecretKey: "s", Client: server.Client()}
+	q := Queue{Batch: 2, Spool: spoolDir}
+	up, err := NewUploader(b, "", "cam0", 0, true, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2024, 1, 31, 12, 0, 0, 0, time.Local)
+	var files []string
+	for i := 0; i < 3; i++ {
+		file := filepath.Join(dir, fmt.Sprintf("snap%d.jpg", i))
+		ioutil.WriteFile(file, []byte("jpeg"), 0644)
+		files = append(files, file)
+		up.Upload(file, "person", at)
+	}
+	up.Close()
+
+	pending, err := up.spool.list()
+	if err != nil || len(pending) != 3 {
+		t.Fatalf("Spooled %+v, %v; want 3 uploads", pending, err)
+	}
+	for i, u := range pending {
+		if u.Path != files[i] {
+			t.Errorf("Spooled upload %d is %s, want %s", i, u.Path, files[i])
+		}
+	}
+
+	// Kept uploads are sent in order after a restart once the storage is back
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	up, _ = NewUploader(b, "", "cam0", 0, true, q)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if pending, _ = up.spool.list(); len(pending) == 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	up.Close()
+	if len(pending) != 0 {
+		t.Errorf("Spool not flushed: %+v", pending)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	// Files of a batch are uploaded in parallel
+	sort.Strings(objects)
+	if len(objects) != 3 || objects[2] != "/cams/2024-01-31/cam0/person/snap2.jpg" {
+		t.Errorf("Uploaded objects %v", objects)
+	}
+	for _, file := range files {
+		if _, err := os.Stat(file); !os.IsNotExist(err) {
+			t.Errorf("Uploaded file %s not removed: %v", file, err)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter(50, 2)
+	start := time.Now()
+	for i := 0; i < 7; i++ {
+		l.wait()
+	}
+	// 2 at once, then 5 at 20ms intervals
+	if d := time.Since(start); d < 90*time.Millisecond || d > 300*time.Millisecond {
+		t.Errorf("7 events at 50/s with bursts of 2 took %v", d)
+	}
+}
@@ -0,0 +1,208 @@
+// Package systemd runs examples as systemd services (-service): logs are written in the format of
+// the journal, readiness is notified once the model is loaded, and the watchdog is pinged from the
+// frame loop, so that systemd restarts a detector whose frames stop coming. It speaks the sd_notify
+// protocol directly, e.g. with a unit like
+//
+//	[Service]
+//	Type=notify
+//	ExecStart=/usr/local/bin/gocv-examples yolo -service -no-gui -input rtsp://camera/stream -output :8080
+//	WatchdogSec=30
+//	Restart=on-failure
+//
+// Without NOTIFY_SOCKET, when not started by systemd with Type=notify, only the logging changes
+package systemd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+// Options holds the service parameters given by flags
+type Options struct {
+	Service bool
+}
+
+// RegisterFlags adds the -service flag to fs
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&o.Service, "service", false, "Run as a systemd service: journal logging, readiness and watchdog notifications")
+}
+
+// Open switches logging to the journal format and connects to the notification socket of systemd,
+// notifying stopping and closed by sd. The notifier is nil without -service
+func (o *Options) Open(sd *shutdown.Handler) (*Notifier, error) {
+	if !o.Service {
+		return nil, nil
+	}
+	logging.SetJournal(true)
+	socket := os.Getenv("NOTIFY_SOCKET")
+	watchdog, err := watchdogTimeout(os.Getenv("WATCHDOG_USEC"), os.Getenv("WATCHDOG_PID"))
+	if err != nil {
+		return nil, err
+	}
+	// Processes started by the example, e.g. ffmpeg, should not notify in its name
+	for _, name := range []string{"NOTIFY_SOCKET", "WATCHDOG_USEC", "WATCHDOG_PID"} {
+		os.Unsetenv(name)
+	}
+	if socket == "" {
+		logging.Warnf("Not started by systemd with Type=notify, no readiness and watchdog notifications")
+		return &Notifier{}, nil
+	}
+	n, err := NewNotifier(socket, watchdog)
+	if err != nil {
+		return nil, err
+	}
+	if watchdog > 0 {
+		logging.Infof("Pinging the systemd watchdog from the frame loop, timeout %v", watchdog)
+	}
+	sd.OnClose("systemd", n.Close)
+	return n, nil
+}
+
+// Returns the watchdog timeout of WATCHDOG_USEC if it is meant for this process, 0 if there is none
+func watchdogTimeout(usec, pid string) (time.Duration, error) {
+	if usec == "" {
+		return 0, nil
+	}
+	if pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("Invalid WATCHDOG_USEC %q", usec)
+	}
+	return time.Duration(n) * time.Microsecond, nil
+}
+
+// Notifier sends notifications to systemd. It is safe for concurrent use, and does nothing when
+// created without a socket
+type Notifier struct {
+	conn     *net.UnixConn
+	watchdog time.Duration // 0 without watchdog
+	mu       sync.Mutex
+	lastPing time.Time
+}
+
+// NewNotifier connects to the notification socket, a path or an abstract name starting with "@".
+// The watchdog is pinged at half of its timeout, if not 0
+func NewNotifier(socket string, watchdog time.Duration) (*Notifier, error) {
+	addr := &net.UnixAddr{Name: socket, Net: "unixgram"}
+	if strings.HasPrefix(socket, "@") {
+		addr.Name = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, addr)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot connect to systemd: %v", err)
+	}
+	return &Notifier{conn: conn, watchdog: watchdog}, nil
+}
+
+// Notify sends the state assignments, e.g. "READY=1", in one message
+func (n *Notifier) Notify(state ...string) error {
+	if n.conn == nil {
+		return nil
+	}
+	_, err := n.conn.Write([]byte(strings.Join(state, "\n") + "\n"))
+	return err
+}
+
+// Ready tells systemd that the service has started, with a status line shown by systemctl status.
+// The watchdog is expected to be pinged from now on
+func (n *Notifier) Ready(status string) {
+	n.mu.Lock()
+	n.lastPing = time.Now()
+	n.mu.Unlock()
+	if err := n.Notify("READY=1", "STATUS="+status); err != nil {
+		logging.Warnf("Cannot notify systemd: %v", err)
+	}
+}
+
+// Status updates the status line
+func (n *Notifier) Status(status string) {
+	if err := n.Notify("STATUS=" + status); err != nil {
+		logging.Warnf("Cannot notify systemd: %v", err)
+	}
+}
+
+// Frame marks progress of the frame loop, pinging the watchdog if half of its timeout passed since
+// the last ping
+func (n *Notifier) Frame() {
+	if n.watchdog <= 0 {
+		return
+	}
+	n.mu.Lock()
+	now := time.Now()
+	if now.Sub(n.lastPing) < n.watchdog/2 {
+		n.mu.Unlock()
+		return
+	}
+	n.lastPing = now
+	n.mu.Unlock()
+	if err := n.Notify("WATCHDOG=1"); err != nil {
+		logging.Warnf("Cannot ping systemd watchdog: %v", err)
+	}
+}
+
+// KeepAlive pings the watchdog until ctx is cancelled whenever while returns true, or always if it
+// is nil, for times without frames when the example is alive, e.g. a server waiting for pushed frames
+// or processing paused by a client
+func (n *Notifier) KeepAlive(ctx context.Context, while func() bool) {
+	if n.watchdog <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(n.watchdog / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if while != nil && !while() {
+					continue
+				}
+				if err := n.Notify("WATCHDOG=1"); err != nil {
+					logging.Warnf("Cannot ping systemd watchdog: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close tells systemd that the service is stopping and closes the connection
+func (n *Notifier) Close() error {
+	if n.conn == nil {
+		return nil
+	}
+	n.Notify("STOPPING=1")
+	return n.conn.Close()
+}
+
+// Sink wraps sink to mark progress of the frame loop after each frame is written
+func (n *Notifier) Sink(sink videoio.FrameSink) videoio.FrameSink {
+	return &notifyingSink{FrameSink: sink, n: n}
+}
+
+type notifyingSink struct {
+	videoio.FrameSink
+	n *Notifier
+}
+
+func (s *notifyingSink) Write(img gocv.Mat) error {
+	err := s.FrameSink.Write(img)
+	if err == nil {
+		s.n.Frame()
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+package systemd
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// listen creates a notification socket like the one of systemd
+func listen(t *testing.T) (*net.UnixConn, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("Cannot create a datagram socket: %v", err)
+	}
+	return conn, path
+}
+
+// receive returns the next message, or "" if there is none
+func receive(conn *net.UnixConn) string {
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	n, err := conn.Read(buf)
+	if err != nil {
+		return ""
+	}
+	return string(buf[:n])
+}
+
+func TestNotifier(t *testing.T) {
+	conn, path := listen(t)
+	defer conn.Close()
+	n, err := NewNotifier(path, 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n.Ready("Detecting objects")
+	if msg := receive(conn); msg != "READY=1\nSTATUS=Detecting objects\n" {
+		t.Errorf("Unexpected ready message %q", msg)
+	}
+	n.Frame()
+	if msg := receive(conn); msg != "" {
+		t.Errorf("Expected no ping right after ready, got %q", msg)
+	}
+	time.Sleep(100 * time.Millisecond)
+	n.Frame()
+	n.Frame()
+	if msg := receive(conn); msg != "WATCHDOG=1\n" {
+		t.Errorf("Expected a watchdog ping, got %q", msg)
+	}
+	if msg := receive(conn); msg != "" {
+		t.Errorf("Expected one ping, got %q", msg)
+	}
+
+	n.Close()
+	if msg := receive(conn); msg != "STOPPING=1\n" {
+		t.Errorf("Unexpected stopping message %q", msg)
+	}
+}
+
+func TestWatchdogTimeout(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	tests := []struct {
+		usec, pid string
+		want      time.Duration
+	}{
+		{"", "", 0},
+		{"30000000", "", 30 * time.Second},
+		{"30000000", pid, 30 * time.Second},
+		{"30000000", "1", 0},
+	}
+	for _, tt := range tests {
+		if got, err := watchdogTimeout(tt.usec, tt.pid); err != nil || got != tt.want {
+			t.Errorf("watchdogTimeout(%q, %q) = %v, %v; want %v", tt.usec, tt.pid, got, err, tt.want)
+		}
+	}
+	if _, err := watchdogTimeout("soon", ""); err == nil {
+		t.Error("Expected an error for an invalid timeout")
+	}
+
+	// Without a socket nothing is sent
+	n := &Notifier{}
+	n.Ready("Idle")
+	n.Frame()
+	if err := n.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	conn, path := listen(t)
+	defer conn.Close()
+	n, err := NewNotifier(path, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	paused := make(chan bool, 1)
+	paused <- false
+	n.KeepAlive(ctx, func() bool {
+		p := <-paused
+		paused <- p
+		return p
+	})
+	if msg := receive(conn); msg != "" {
+		t.Errorf("Expected no ping while not paused, got %q", msg)
+	}
+	<-paused
+	paused <- true
+	if msg := receive(conn); msg != "WATCHDOG=1\n" {
+		t.Errorf("Expected a ping while paused, got %q", msg)
+	}
+	cancel()
+}
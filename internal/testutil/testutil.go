@@ -0,0 +1,131 @@
+// Package testutil helps tests load the shared fixtures from the testdata directory at the root
+// of the repository, compare Mats with tolerances and check results against golden images.
+//
+// Golden images are stored in testdata/golden and committed with the tests. A missing golden image
+// fails the test; run tests with -update to create new ones or regenerate them after an intended change:
+//
+//	go test ./internal/draw -update
+package testutil
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+var update = flag.Bool("update", false, "Rewrite golden images with the test results")
+
+// RepoPath returns the absolute path of a file relative to the repository root
+func RepoPath(elem ...string) string {
+	_, filename, _, _ := runtime.Caller(0)
+	root := filepath.Join(filepath.Dir(filename), "..", "..")
+	return filepath.Join(append([]string{root}, elem...)...)
+}
+
+// Path returns the absolute path of a fixture in testdata
+func Path(name string) string {
+	return RepoPath("testdata", name)
+}
+
+// LoadImage reads a color image from testdata, failing the test if it cannot be read.
+// The Mat is closed when the test finishes
+func LoadImage(t testing.TB, name string) gocv.Mat {
+	t.Helper()
+	return loadImage(t, Path(name), gocv.IMReadColor)
+}
+
+// LoadGray reads a grayscale image from testdata, failing the test if it cannot be read.
+// The Mat is closed when the test finishes
+func LoadGray(t testing.TB, name string) gocv.Mat {
+	t.Helper()
+	return loadImage(t, Path(name), gocv.IMReadGrayScale)
+}
+
+func loadImage(t testing.TB, path string, flags gocv.IMReadFlag) gocv.Mat {
+	t.Helper()
+	img := gocv.IMRead(path, flags)
+	if img.Empty() {
+		t.Fatalf("Cannot read test image %s", path)
+	}
+	t.Cleanup(func() { img.Close() })
+	return img
+}
+
+// Diff returns the maximum and the mean absolute difference between two Mats over all channels.
+// Mats must have the same size and type
+func Diff(a, b gocv.Mat) (maxDiff, meanDiff float64, err error) {
+	if a.Rows() != b.Rows() || a.Cols() != b.Cols() || a.Type() != b.Type() {
+		return 0, 0, fmt.Errorf("Mats differ: %dx%d %v vs %dx%d %v",
+			a.Cols(), a.Rows(), a.Type(), b.Cols(), b.Rows(), b.Type())
+	}
+	if a.Empty() {
+		return 0, 0, nil
+	}
+
+	diff := gocv.NewMat()
+	defer diff.Close()
+	gocv.AbsDiff(a, b, &diff)
+
+	// Treat all channels as a single channel image
+	flat := diff.Reshape(1, 0)
+	defer flat.Close()
+	_, maxVal, _, _ := gocv.MinMaxLoc(flat)
+	mean := flat.Mean()
+	return float64(maxVal), mean.Val1, nil
+}
+
+// AssertNear fails the test if any element of got differs from want by more than maxTol,
+// or the mean difference is above meanTol
+func AssertNear(t testing.TB, got, want gocv.Mat, maxTol, meanTol float64) {
+	t.Helper()
+	maxDiff, meanDiff, err := Diff(got, want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxDiff > maxTol || meanDiff > meanTol {
+		t.Errorf("Mats differ: max %.2f (tolerance %.2f), mean %.3f (tolerance %.3f)",
+			maxDiff, maxTol, meanDiff, meanTol)
+	}
+}
+
+// AssertGolden compares got with the golden image testdata/golden/name within the tolerances,
+// see AssertNear. The golden image is written instead if -update is set, and the test fails if it
+// does not exist otherwise
+func AssertGolden(t testing.TB, name string, got gocv.Mat, maxTol, meanTol float64) {
+	t.Helper()
+	path := Path(filepath.Join("golden", name))
+	if _, err := os.Stat(path); os.IsNotExist(err) && !*update {
+		t.Fatalf("Golden image %s does not exist, run the test with -update to create it", path)
+	}
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if !gocv.IMWrite(path, got) {
+			t.Fatalf("Cannot write golden image %s", path)
+		}
+		t.Logf("Written golden image %s", path)
+		return
+	}
+
+	want := gocv.IMRead(path, gocv.IMReadUnchanged)
+	defer want.Close()
+	if want.Empty() {
+		t.Fatalf("Cannot read golden image %s", path)
+	}
+	AssertNear(t, got, want, maxTol, meanTol)
+}
+
+// SolidImage returns a 3-channel image of the given size filled with the color.
+// The Mat is closed when the test finishes
+func SolidImage(t testing.TB, width, height int, c gocv.Scalar) gocv.Mat {
+	t.Helper()
+	img := gocv.NewMatWithSizeFromScalar(c, height, width, gocv.MatTypeCV8UC3)
+	t.Cleanup(func() { img.Close() })
+	return img
+}
@@ -0,0 +1,41 @@
+package threads
+
+import (
+	"io/ioutil"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// Sets the CPUs of a thread of the process, the calling one if tid is 0
+func setAffinity(tid int, cpus []int) error {
+	var mask [maxCPUs / 64]uint64
+	for _, c := range cpus {
+		mask[c/64] |= 1 << uint(c%64)
+	}
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, uintptr(tid), unsafe.Sizeof(mask),
+		uintptr(unsafe.Pointer(&mask)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Sets the CPUs of all threads of the process, which threads started later inherit
+func setProcessAffinity(cpus []int) error {
+	tasks, err := ioutil.ReadDir("/proc/self/task")
+	if err != nil {
+		return err
+	}
+	for _, t := range tasks {
+		tid, err := strconv.Atoi(t.Name())
+		if err != nil {
+			continue
+		}
+		// Threads may have exited since the directory was read
+		if err := setAffinity(tid, cpus); err != nil && err != syscall.ESRCH {
+			return err
+		}
+	}
+	return nil
+}
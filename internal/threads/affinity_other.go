@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package threads
+
+import "errors"
+
+var errNoAffinity = errors.New("CPU affinity is only supported on Linux")
+
+func setAffinity(tid int, cpus []int) error {
+	return errNoAffinity
+}
+
+func setProcessAffinity(cpus []int) error {
+	return errNoAffinity
+}
@@ -0,0 +1,14 @@
+#include <opencv2/core.hpp>
+#include "threads.h"
+
+void Threads_SetNumThreads(int n) {
+    cv::setNumThreads(n);
+}
+
+int Threads_GetNumThreads() {
+    return cv::getNumThreads();
+}
+
+int Threads_GetNumberOfCPUs() {
+    return cv::getNumberOfCPUs();
+}
@@ -0,0 +1,126 @@
+// Package threads bounds the CPU usage of the examples, for shared servers and small boards: -cv-threads
+// sets the number of threads OpenCV runs its parallel loops on, which is not exposed by GoCV, and -cpus
+// restricts the process to a list of CPUs. With -pin-workers, each worker thread, e.g. of a detection
+// pool, is pinned to one CPU of the list, so that workers do not compete for a core.
+// CPU affinity is only supported on Linux
+package threads
+
+/*
+#cgo !windows pkg-config: opencv4
+#cgo CXXFLAGS: --std=c++11
+#include "threads.h"
+*/
+import "C"
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/marchevska/gocv-examples/internal/logging"
+)
+
+// Highest CPU number that can be used plus one, as in the CPU sets of Linux
+const maxCPUs = 1024
+
+// Options holds the thread and CPU settings
+type Options struct {
+	Threads int    // Threads of OpenCV parallel loops, its default if 0
+	CPUs    string // CPUs the process runs on, e.g. 0-3,6, all if empty
+	Pin     bool   // Pin each worker thread to one of the CPUs
+
+	cpus []int // Parsed by Setup
+}
+
+// RegisterFlags adds -cv-threads, -cpus and -pin-workers flags to fs
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	fs.IntVar(&o.Threads, "cv-threads", 0, "Threads OpenCV runs parallel loops on, 1 to run them sequentially, "+
+		"0 for one per CPU")
+	fs.StringVar(&o.CPUs, "cpus", "", "CPUs to run on, e.g. 0-3,6, all if empty (Linux only)")
+	fs.BoolVar(&o.Pin, "pin-workers", false, "Pin each worker thread to one CPU of -cpus, or of all CPUs (Linux only)")
+}
+
+// Setup restricts the process to the CPUs and sets the number of OpenCV threads, logging the outcome.
+// It should be called before workers start, since threads inherit the CPUs of the thread starting them
+func (o *Options) Setup() error {
+	if o.Threads < 0 {
+		return errors.New("-cv-threads cannot be negative")
+	}
+	cpus, err := parseCPUs(o.CPUs)
+	if err != nil {
+		return err
+	}
+	if len(cpus) > 0 {
+		if err := setProcessAffinity(cpus); err != nil {
+			return fmt.Errorf("Cannot run on CPUs %s: %v", o.CPUs, err)
+		}
+	} else if o.Pin {
+		for i := 0; i < runtime.NumCPU(); i++ {
+			cpus = append(cpus, i)
+		}
+	}
+	o.cpus = cpus
+	if o.Threads > 0 {
+		C.Threads_SetNumThreads(C.int(o.Threads))
+	}
+	if o.Threads > 0 || len(cpus) > 0 {
+		logging.Infof("OpenCV runs parallel loops on %d threads, %d CPUs available", NumThreads(), NumCPUs())
+	}
+	return nil
+}
+
+// PinWorker pins the calling thread to a CPU with -pin-workers, the CPUs taken in turn by worker number.
+// The calling goroutine must be locked to its thread with runtime.LockOSThread
+func (o *Options) PinWorker(worker int) error {
+	if !o.Pin || len(o.cpus) == 0 {
+		return nil
+	}
+	cpu := o.cpus[worker%len(o.cpus)]
+	if err := setAffinity(0, []int{cpu}); err != nil {
+		return fmt.Errorf("Cannot pin worker %d to CPU %d: %v", worker, cpu, err)
+	}
+	logging.Debugf("Worker %d pinned to CPU %d", worker, cpu)
+	return nil
+}
+
+// NumThreads returns the number of threads OpenCV runs parallel loops on
+func NumThreads() int {
+	return int(C.Threads_GetNumThreads())
+}
+
+// NumCPUs returns the number of CPUs available to OpenCV
+func NumCPUs() int {
+	return int(C.Threads_GetNumberOfCPUs())
+}
+
+// Parses a list of CPUs and CPU ranges like 0-3,6, returning the CPUs sorted without duplicates
+func parseCPUs(s string) ([]int, error) {
+	seen := map[int]bool{}
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		lo, hi := f, f
+		if i := strings.Index(f, "-"); i >= 0 {
+			lo, hi = f[:i], f[i+1:]
+		}
+		first, err1 := strconv.Atoi(strings.TrimSpace(lo))
+		last, err2 := strconv.Atoi(strings.TrimSpace(hi))
+		if err1 != nil || err2 != nil || first < 0 || last < first || last >= maxCPUs {
+			return nil, fmt.Errorf("Invalid CPU or range %q", f)
+		}
+		for c := first; c <= last; c++ {
+			seen[c] = true
+		}
+	}
+	var cpus []int
+	for c := range seen {
+		cpus = append(cpus, c)
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
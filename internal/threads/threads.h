@@ -0,0 +1,16 @@
+#ifndef _GOCV_EXAMPLES_THREADS_H_
+#define _GOCV_EXAMPLES_THREADS_H_
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+void Threads_SetNumThreads(int n);
+int Threads_GetNumThreads();
+int Threads_GetNumberOfCPUs();
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif //_GOCV_EXAMPLES_THREADS_H_
@@ -0,0 +1,29 @@
+package threads
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseCPUs(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []int
+	}{
+		{"", nil},
+		{"2", []int{2}},
+		{"0-3,6", []int{0, 1, 2, 3, 6}},
+		{" 5 , 1-2, 2 ", []int{1, 2, 5}},
+	}
+	for _, tt := range tests {
+		got, err := parseCPUs(tt.s)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseCPUs(%q) = %v, %v, want %v", tt.s, got, err, tt.want)
+		}
+	}
+	for _, s := range []string{"a", "3-1", "-1", "1-", "2048"} {
+		if _, err := parseCPUs(s); err == nil {
+			t.Errorf("Expected an error for %q", s)
+		}
+	}
+}
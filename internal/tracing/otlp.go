@@ -0,0 +1,211 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/logging"
+)
+
+const (
+	otlpQueueSize = 256  // Traces waiting to be sent, more are dropped
+	otlpBatchSize = 1024 // Spans sent in one request at most
+	otlpLinger    = time.Second
+	otlpTimeout   = 10 * time.Second
+	otlpScopeName = "github.com/marchevska/gocv-examples/internal/tracing"
+)
+
+// Kind of all spans, SPAN_KIND_INTERNAL
+const spanKindInternal = 1
+
+// Exporter sends spans to an OTLP/HTTP collector in the background, in the JSON encoding
+// of ExportTraceServiceRequest. Traces are batched for a short time; failed requests are
+// logged and their spans dropped
+type Exporter struct {
+	endpoint string
+	service  string
+	client   *http.Client
+	queue    chan []Span
+	wg       sync.WaitGroup
+}
+
+// NewExporter creates an exporter to the traces URL of a collector, e.g.
+// "http://localhost:4318/v1/traces", naming the service in the resource of the spans
+func NewExporter(endpoint, service string) *Exporter {
+	e := &Exporter{endpoint: endpoint, service: service, client: &http.Client{Timeout: otlpTimeout},
+		queue: make(chan []Span, otlpQueueSize)}
+	e.wg.Add(1)
+	go e.run()
+	return e
+}
+
+// Send queues the spans of a trace, dropping them if the queue is full
+func (e *Exporter) Send(spans []Span) {
+	select {
+	case e.queue <- spans:
+	default:
+		logging.Warnf("Trace dropped, %d traces are waiting", otlpQueueSize)
+	}
+}
+
+// Close sends the queued spans and stops the exporter
+func (e *Exporter) Close() error {
+	close(e.queue)
+	e.wg.Wait()
+	return nil
+}
+
+// Sends queued traces in batches
+func (e *Exporter) run() {
+	defer e.wg.Done()
+	for spans := range e.queue {
+		batch := spans
+		linger := time.NewTimer(otlpLinger)
+	collect:
+		for len(batch) < otlpBatchSize {
+			select {
+			case spans, ok := <-e.queue:
+				if !ok {
+					break collect
+				}
+				batch = append(batch, spans...)
+			case <-linger.C:
+				break collect
+			}
+		}
+		linger.Stop()
+
+		if err := e.export(batch); err != nil {
+			logging.Errorf("Cannot export %d spans: %v", len(batch), err)
+		}
+	}
+}
+
+// Posts the spans to the collector
+func (e *Exporter) export(spans []Span) error {
+	body, err := json.Marshal(exportRequest(e.service, spans))
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Collector answered %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// JSON encoding of OTLP: IDs are hex, 64-bit integers are strings and enums are numbers
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+// Builds the request exporting the spans of the service
+func exportRequest(service string, spans []Span) otlpRequest {
+	out := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(s.TraceID[:]),
+			SpanID:            hex.EncodeToString(s.ID[:]),
+			Name:              s.Name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+			Attributes:        attributes(s.Attributes),
+		}
+		if s.ParentID != ([8]byte{}) {
+			span.ParentSpanID = hex.EncodeToString(s.ParentID[:])
+		}
+		out = append(out, span)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: attributes(map[string]interface{}{"service.name": service})},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: otlpScopeName}, Spans: out}},
+	}}}
+}
+
+// Encodes attributes sorted by key; values of other types are formatted as strings
+func attributes(m map[string]interface{}) []otlpAttribute {
+	var attrs []otlpAttribute
+	for k, v := range m {
+		var val otlpValue
+		switch v := v.(type) {
+		case string:
+			val.StringValue = &v
+		case int:
+			s := strconv.Itoa(v)
+			val.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			val.IntValue = &s
+		case float32:
+			f := float64(v)
+			val.DoubleValue = &f
+		case float64:
+			val.DoubleValue = &v
+		case bool:
+			val.BoolValue = &v
+		default:
+			s := fmt.Sprint(v)
+			val.StringValue = &s
+		}
+		attrs = append(attrs, otlpAttribute{Key: k, Value: val})
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	return attrs
+}
@@ -0,0 +1,228 @@
+// Package tracing records each processed frame as an OpenTelemetry trace and exports the traces
+// to a collector over OTLP/HTTP (-otlp), e.g. Jaeger or the OpenTelemetry Collector, to find out
+// which stage a latency spike of a deployed detector comes from. A trace has a "frame" span
+// with a child span for each stage of the processing loop:
+//
+//	frame
+//	├── capture      reading the frame from the source
+//	├── preprocess   stages measured with metrics.Collector.Start, e.g. by the detectors
+//	├── inference
+//	├── postprocess
+//	└── sink         writing the annotated frame to the outputs
+//
+// A ratio of frames is sampled (-trace-sample), while frames slower than -trace-slow are always
+// exported. Spans are sent in batches by a small built-in exporter using the JSON encoding of
+// OTLP, so no OpenTelemetry SDK is needed; spans that cannot be sent are dropped
+package tracing
+
+import (
+	"crypto/rand"
+	"flag"
+	"fmt"
+	"math"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+// Defaults of the options
+const (
+	DefaultSampleRatio = 0.1
+	DefaultSlow        = 0
+)
+
+// Path of the traces endpoint of OTLP/HTTP collectors
+const tracesPath = "/v1/traces"
+
+// Options holds the tracing parameters given by flags
+type Options struct {
+	Endpoint    string
+	Service     string
+	SampleRatio float64
+	Slow        time.Duration
+}
+
+// RegisterFlags adds -otlp, -trace-service, -trace-sample and -trace-slow flags to fs
+func (o *Options) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.Endpoint, "otlp", "", `OTLP/HTTP collector URL exporting traces of frames, e.g. "http://localhost:4318"; no tracing if empty`)
+	fs.StringVar(&o.Service, "trace-service", "", "Service name of the traces, the name of the example if empty")
+	fs.Float64Var(&o.SampleRatio, "trace-sample", DefaultSampleRatio, "Ratio of frames traced, from 0 to 1")
+	fs.DurationVar(&o.Slow, "trace-slow", DefaultSlow, "Always trace frames taking at least this time, e.g. 100ms, 0 for sampled frames only")
+}
+
+// Open creates the tracer of the options, flushed and closed by sd. It is nil without an endpoint.
+// The service is named after the example unless the options name it
+func (o *Options) Open(sd *shutdown.Handler, service string) (*Tracer, error) {
+	if o.Endpoint == "" {
+		return nil, nil
+	}
+	endpoint, err := tracesURL(o.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if o.SampleRatio < 0 || o.SampleRatio > 1 {
+		return nil, fmt.Errorf("Trace sample ratio should be from 0 to 1, not %g", o.SampleRatio)
+	}
+	if o.Service != "" {
+		service = o.Service
+	}
+	t := NewTracer(NewExporter(endpoint, service), o.SampleRatio, o.Slow)
+	logging.Infof("Exporting traces of %s to %s, sampling %g of frames", service, endpoint, o.SampleRatio)
+	sd.OnClose("tracing", t.Close)
+	return t, nil
+}
+
+// Returns the URL of the traces endpoint of the collector, adding the standard path if there is none
+func tracesURL(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("OTLP endpoint should be an http or https URL: %q", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = tracesPath
+	}
+	return u.String(), nil
+}
+
+// Span is a timed operation of a trace
+type Span struct {
+	TraceID    [16]byte
+	ID         [8]byte
+	ParentID   [8]byte // Zero for the root span
+	Name       string
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]interface{} // Values are strings, integers, floats or booleans
+}
+
+// Tracer traces frames of the processing loop, one at a time: the frame started by the source
+// returned by Source is ended by the sink returned by Sink, and stages measured in between are
+// its children. It implements metrics.Tracer to get the stages of a collector
+type Tracer struct {
+	exporter *Exporter
+	ratio    float64
+	slow     time.Duration
+	mu       sync.Mutex
+	frame    []Span // Spans of the current frame, the root first; nil between frames
+	frames   int
+}
+
+// NewTracer creates a tracer sending traces of the sampled ratio of frames and of frames taking
+// at least slow, if not 0, to the exporter
+func NewTracer(exporter *Exporter, ratio float64, slow time.Duration) *Tracer {
+	return &Tracer{exporter: exporter, ratio: ratio, slow: slow}
+}
+
+// StartFrame starts the trace of a frame, ending the current one unfinished
+func (t *Tracer) StartFrame() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	root := Span{TraceID: newID16(), ID: newID8(), Name: "frame", Start: time.Now(),
+		Attributes: map[string]interface{}{"frame": t.frames}}
+	t.frames++
+	t.frame = []Span{root}
+}
+
+// EndFrame ends the trace of the current frame and exports it if sampled or slow
+func (t *Tracer) EndFrame() {
+	t.mu.Lock()
+	spans := t.frame
+	t.frame = nil
+	t.mu.Unlock()
+	if spans == nil {
+		return
+	}
+	root := &spans[0]
+	root.End = time.Now()
+	if t.sampled(root.TraceID) || (t.slow > 0 && root.End.Sub(root.Start) >= t.slow) {
+		t.exporter.Send(spans)
+	}
+}
+
+// Decides from the trace ID, like the TraceIdRatioBased sampler of OpenTelemetry
+func (t *Tracer) sampled(id [16]byte) bool {
+	if t.ratio >= 1 {
+		return true
+	}
+	var x uint64
+	for _, b := range id[8:] {
+		x = x<<8 | uint64(b)
+	}
+	return x>>1 < uint64(t.ratio*math.MaxInt64)
+}
+
+// Stage adds a child span to the current frame, if any
+func (t *Tracer) Stage(name string, start, end time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.frame == nil {
+		return
+	}
+	root := t.frame[0]
+	t.frame = append(t.frame, Span{TraceID: root.TraceID, ID: newID8(), ParentID: root.ID, Name: name, Start: start, End: end})
+}
+
+// SetAttribute sets an attribute of the frame span of the current frame, e.g. the number of objects
+func (t *Tracer) SetAttribute(key string, value interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.frame != nil {
+		t.frame[0].Attributes[key] = value
+	}
+}
+
+// Close sends the exported spans and stops the exporter
+func (t *Tracer) Close() error {
+	return t.exporter.Close()
+}
+
+// Source wraps src to start a frame before reading it, traced as the capture stage
+func (t *Tracer) Source(src videoio.FrameSource) videoio.FrameSource {
+	return &tracedSource{FrameSource: src, t: t}
+}
+
+type tracedSource struct {
+	videoio.FrameSource
+	t *Tracer
+}
+
+func (s *tracedSource) Next() (gocv.Mat, error) {
+	s.t.StartFrame()
+	start := time.Now()
+	img, err := s.FrameSource.Next()
+	s.t.Stage("capture", start, time.Now())
+	return img, err
+}
+
+// Sink wraps sink to trace writing as the sink stage and end the frame
+func (t *Tracer) Sink(sink videoio.FrameSink) videoio.FrameSink {
+	return &tracedSink{FrameSink: sink, t: t}
+}
+
+type tracedSink struct {
+	videoio.FrameSink
+	t *Tracer
+}
+
+func (s *tracedSink) Write(img gocv.Mat) error {
+	start := time.Now()
+	err := s.FrameSink.Write(img)
+	s.t.Stage("sink", start, time.Now())
+	s.t.EndFrame()
+	return err
+}
+
+func newID16() (id [16]byte) {
+	rand.Read(id[:])
+	return id
+}
+
+func newID8() (id [8]byte) {
+	rand.Read(id[:])
+	return id
+}
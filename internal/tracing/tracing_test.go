@@ -0,0 +1,135 @@
+package tracing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"gocv.io/x/gocv"
+)
+
+type frameSource struct{}
+
+func (frameSource) Next() (gocv.Mat, error) { return gocv.NewMat(), nil }
+func (frameSource) Close() error            { return nil }
+
+type frameSink struct{}
+
+func (frameSink) Write(img gocv.Mat) error { return nil }
+func (frameSink) Close() error             { return nil }
+
+// collector records the spans posted to it
+type collector struct {
+	mu       sync.Mutex
+	requests []otlpRequest
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req otlpRequest
+	if r.URL.Path != tracesPath || json.NewDecoder(r.Body).Decode(&req) != nil {
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	c.requests = append(c.requests, req)
+	c.mu.Unlock()
+}
+
+func TestTracesURL(t *testing.T) {
+	tests := map[string]string{
+		"http://localhost:4318":            "http://localhost:4318/v1/traces",
+		"https://otel.example.com/":        "https://otel.example.com/v1/traces",
+		"http://localhost:4318/api/traces": "http://localhost:4318/api/traces",
+	}
+	for endpoint, want := range tests {
+		if got, err := tracesURL(endpoint); err != nil || got != want {
+			t.Errorf("tracesURL(%q) = %q, %v; want %q", endpoint, got, err, want)
+		}
+	}
+	for _, endpoint := range []string{"localhost:4318", "grpc://localhost:4317", ""} {
+		if _, err := tracesURL(endpoint); err == nil {
+			t.Errorf("Expected an error for %q", endpoint)
+		}
+	}
+}
+
+func TestTracer(t *testing.T) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	tracer := NewTracer(NewExporter(server.URL+tracesPath, "yolo4"), 1, 0)
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	stats.SetTracer(tracer)
+	src, sink := tracer.Source(frameSource{}), tracer.Sink(frameSink{})
+	for i := 0; i < 2; i++ {
+		img, _ := src.Next()
+		stats.Start("inference")()
+		tracer.SetAttribute("objects", 3)
+		sink.Write(img)
+	}
+	stats.Start("inference")() // Between frames, not traced
+	tracer.Close()
+
+	if len(c.requests) != 1 || len(c.requests[0].ResourceSpans) != 1 {
+		t.Fatalf("Expected one batch, got %+v", c.requests)
+	}
+	rs := c.requests[0].ResourceSpans[0]
+	if attrs := rs.Resource.Attributes; len(attrs) != 1 || attrs[0].Key != "service.name" || *attrs[0].Value.StringValue != "yolo4" {
+		t.Errorf("Unexpected resource %+v", rs.Resource)
+	}
+	spans := rs.ScopeSpans[0].Spans
+	if len(spans) != 8 {
+		t.Fatalf("Expected 2 frames of 4 spans, got %d", len(spans))
+	}
+	for i, name := range []string{"frame", "capture", "inference", "sink"} {
+		s := spans[i]
+		if s.Name != name || s.TraceID != spans[0].TraceID || len(s.TraceID) != 32 || len(s.SpanID) != 16 {
+			t.Errorf("Unexpected span %d %+v", i, s)
+		}
+		if (i == 0) != (s.ParentSpanID == "") || (i > 0 && s.ParentSpanID != spans[0].SpanID) {
+			t.Errorf("Unexpected parent of span %+v", s)
+		}
+	}
+	if spans[4].TraceID == spans[0].TraceID {
+		t.Error("Expected a trace per frame")
+	}
+	attrs := spans[0].Attributes
+	if len(attrs) != 2 || attrs[0].Key != "frame" || *attrs[0].Value.IntValue != "0" || attrs[1].Key != "objects" ||
+		*attrs[1].Value.IntValue != "3" {
+		t.Errorf("Unexpected frame attributes %+v", attrs)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	c := &collector{}
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	tracer := NewTracer(NewExporter(server.URL+tracesPath, "test"), 0, 20*time.Millisecond)
+	src, sink := tracer.Source(frameSource{}), tracer.Sink(frameSink{})
+	for _, d := range []time.Duration{0, 30 * time.Millisecond, 0} {
+		img, _ := src.Next()
+		time.Sleep(d)
+		sink.Write(img)
+	}
+	tracer.Close()
+	if len(c.requests) != 1 || len(c.requests[0].ResourceSpans[0].ScopeSpans[0].Spans) != 3 {
+		t.Errorf("Expected the slow frame only, got %+v", c.requests)
+	}
+
+	sampled := 0
+	tracer = &Tracer{ratio: 0.25}
+	for i := 0; i < 10000; i++ {
+		if tracer.sampled(newID16()) {
+			sampled++
+		}
+	}
+	if sampled < 2200 || sampled > 2800 {
+		t.Errorf("Sampled %d of 10000 traces with ratio 0.25", sampled)
+	}
+}
@@ -0,0 +1,90 @@
+package videoio
+
+import (
+	"math"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// FrameBuffer keeps copies of the most recent frames, e.g. to save the moments before an event.
+// Memory of the frames is reused once the buffer is full
+type FrameBuffer struct {
+	frames []gocv.Mat
+	times  []time.Time
+	maxAge time.Duration // 0 to keep frames until they are replaced
+	start  int           // Index of the oldest frame
+	count  int
+}
+
+// NewFrameBuffer creates a buffer of up to size frames, which should be closed after use
+func NewFrameBuffer(size int) *FrameBuffer {
+	if size < 0 {
+		size = 0
+	}
+	b := &FrameBuffer{frames: make([]gocv.Mat, size), times: make([]time.Time, size)}
+	for i := range b.frames {
+		b.frames[i] = gocv.NewMat()
+	}
+	return b
+}
+
+// NewPreRollBuffer creates a buffer of the frames pushed during the last d, for recordings that
+// start d before their event. It holds up to d at the frame rate, which bounds its memory when the
+// input is faster, e.g. width*height*3 bytes per frame for BGR frames. It should be closed after use
+func NewPreRollBuffer(d time.Duration, fps float64) *FrameBuffer {
+	b := NewFrameBuffer(int(math.Ceil(d.Seconds() * fps)))
+	b.maxAge = d
+	return b
+}
+
+// Push stores a copy of the frame, replacing the oldest one when the buffer is full
+func (b *FrameBuffer) Push(img gocv.Mat) {
+	if len(b.frames) == 0 {
+		return
+	}
+	now := time.Now()
+	i := (b.start + b.count) % len(b.frames)
+	img.CopyTo(&b.frames[i])
+	b.times[i] = now
+	if b.count < len(b.frames) {
+		b.count++
+	} else {
+		b.start = (b.start + 1) % len(b.frames)
+	}
+	b.expire(now)
+}
+
+// Forgets frames older than the maximal age
+func (b *FrameBuffer) expire(now time.Time) {
+	for b.maxAge > 0 && b.count > 0 && now.Sub(b.times[b.start]) > b.maxAge {
+		b.start = (b.start + 1) % len(b.frames)
+		b.count--
+	}
+}
+
+// Len returns the number of buffered frames
+func (b *FrameBuffer) Len() int {
+	b.expire(time.Now())
+	return b.count
+}
+
+// Drain writes the buffered frames to the sink, oldest first, and empties the buffer
+func (b *FrameBuffer) Drain(sink FrameSink) error {
+	defer func() { b.start, b.count = 0, 0 }()
+	b.expire(time.Now())
+	for i := 0; i < b.count; i++ {
+		if err := sink.Write(b.frames[(b.start+i)%len(b.frames)]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close releases the frames
+func (b *FrameBuffer) Close() error {
+	for _, f := range b.frames {
+		f.Close()
+	}
+	return nil
+}
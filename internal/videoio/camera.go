@@ -0,0 +1,133 @@
+package videoio
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"gocv.io/x/gocv"
+)
+
+// CameraSettings holds image controls of the camera set through VideoCapture.Set: V4L2 on Linux,
+// DirectShow or Media Foundation on Windows. Automatic exposure and focus hunt for the best picture
+// and change it from frame to frame, which makes feature and color detection unstable, so fixing
+// them often helps. Units and ranges depend on the camera and the backend, e.g. V4L2 exposure is
+// in 100 µs and DirectShow exposure is log2 of seconds, like -6 for 1/64 s. Unset controls keep
+// the camera's own settings
+type CameraSettings struct {
+	Exposure     cameraValue
+	Focus        cameraValue
+	Gain         cameraValue
+	WhiteBalance cameraValue // Color temperature in Kelvin
+}
+
+// RegisterFlags adds -exposure, -focus, -gain and -white-balance flags to fs
+func (cs *CameraSettings) RegisterFlags(fs *flag.FlagSet) {
+	cs.Exposure.allowAuto, cs.Focus.allowAuto, cs.WhiteBalance.allowAuto = true, true, true
+	fs.Var(&cs.Exposure, "exposure", "Camera exposure, auto or a value in the units of the camera backend")
+	fs.Var(&cs.Focus, "focus", "Camera focus, auto or a value in the units of the camera, often 0 to 255")
+	fs.Var(&cs.Gain, "gain", "Camera gain in the units of the camera")
+	fs.Var(&cs.WhiteBalance, "white-balance", "Camera white balance, auto or color temperature in Kelvin")
+}
+
+// cameraValue is a camera control given as a number or "auto"
+type cameraValue struct {
+	set, auto bool // Whether the value was given, and as "auto"
+	value     float64
+	allowAuto bool
+}
+
+func (v *cameraValue) String() string {
+	switch {
+	case v == nil || !v.set:
+		return ""
+	case v.auto:
+		return "auto"
+	}
+	return strconv.FormatFloat(v.value, 'g', -1, 64)
+}
+
+// Set parses the value, implementing flag.Value
+func (v *cameraValue) Set(s string) error {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "auto") {
+		if !v.allowAuto {
+			return fmt.Errorf("This control has no automatic mode")
+		}
+		*v = cameraValue{set: true, auto: true, allowAuto: v.allowAuto}
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Errorf("Should be a number or auto: %q", s)
+	}
+	*v = cameraValue{set: true, value: f, allowAuto: v.allowAuto}
+	return nil
+}
+
+// Values of VideoCaptureAutoExposure turning automatic exposure off and on. The V4L2 backend passes
+// the V4L2 menu values through, other backends take OpenCV's old DC1394 values
+func autoExposureValues(goos string) (manual, auto float64) {
+	if goos == "linux" {
+		return 1, 3
+	}
+	return 0.25, 0.75
+}
+
+// cameraProperty is a property to set and its name for the log
+type cameraProperty struct {
+	Name  string
+	Prop  gocv.VideoCaptureProperties
+	Value float64
+}
+
+// properties returns the capture properties to set, in order: the automatic mode of a control
+// goes before its value, since cameras ignore manual values while in the automatic mode
+func (cs *CameraSettings) properties(goos string) []cameraProperty {
+	var props []cameraProperty
+	manualExp, autoExp := autoExposureValues(goos)
+	add := func(v cameraValue, name string, autoProp gocv.VideoCaptureProperties, off, on float64, prop gocv.VideoCaptureProperties) {
+		switch {
+		case !v.set:
+		case v.auto:
+			props = append(props, cameraProperty{"auto " + name, autoProp, on})
+		default:
+			if autoProp >= 0 {
+				props = append(props, cameraProperty{"auto " + name, autoProp, off})
+			}
+			props = append(props, cameraProperty{name, prop, v.value})
+		}
+	}
+	add(cs.Exposure, "exposure", gocv.VideoCaptureAutoExposure, manualExp, autoExp, gocv.VideoCaptureExposure)
+	add(cs.Focus, "focus", gocv.VideoCaptureAutoFocus, 0, 1, gocv.VideoCaptureFocus)
+	add(cs.Gain, "gain", -1, 0, 0, gocv.VideoCaptureGain)
+	add(cs.WhiteBalance, "white balance", gocv.VideoCaptureAutoWB, 0, 1, gocv.VideoCaptureWBTemperature)
+	return props
+}
+
+// Apply sets the controls of the camera of the source, which should be opened by OpenSource and not
+// wrapped yet. Cameras ignore controls they do not have or values out of their range without an error,
+// so each value is read back and a different one is logged as a warning
+func (cs *CameraSettings) Apply(src FrameSource) {
+	props := cs.properties(runtime.GOOS)
+	if len(props) == 0 {
+		return
+	}
+	capture, ok := src.(*captureSource)
+	if !ok || !capture.live {
+		logging.Warnf("Camera settings are ignored, the input is not a camera")
+		return
+	}
+	for _, p := range props {
+		capture.capture.Set(p.Prop, p.Value)
+		if got := capture.capture.Get(p.Prop); math.Abs(got-p.Value) > 1e-3 {
+			logging.Warnf("Camera %s is %g instead of %g, it may be unsupported or out of range", p.Name, got, p.Value)
+		} else {
+			logging.Debugf("Camera %s set to %g", p.Name, p.Value)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package videoio
+
+import (
+	"flag"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+func TestCameraSettings(t *testing.T) {
+	var cs CameraSettings
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	cs.RegisterFlags(fs)
+	if err := fs.Parse([]string{"-exposure", "156", "-focus", "auto", "-gain", "10"}); err != nil {
+		t.Fatal(err)
+	}
+	if cs.Exposure.String() != "156" || cs.Focus.String() != "auto" || cs.WhiteBalance.String() != "" {
+		t.Errorf("Unexpected values %v %v %v", &cs.Exposure, &cs.Focus, &cs.WhiteBalance)
+	}
+	if err := cs.Gain.Set("auto"); err == nil {
+		t.Error("Gain should have no automatic mode")
+	}
+	if err := cs.Exposure.Set("dark"); err == nil {
+		t.Error("Expected an error for a value that is not a number")
+	}
+
+	want := []cameraProperty{
+		{"auto exposure", gocv.VideoCaptureAutoExposure, 1},
+		{"exposure", gocv.VideoCaptureExposure, 156},
+		{"auto focus", gocv.VideoCaptureAutoFocus, 1},
+		{"gain", gocv.VideoCaptureGain, 10},
+	}
+	props := cs.properties("linux")
+	if len(props) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, props)
+	}
+	for i := range want {
+		if props[i] != want[i] {
+			t.Errorf("Property %d: expected %v, got %v", i, want[i], props[i])
+		}
+	}
+	if p := cs.properties("windows"); p[0].Value != 0.25 {
+		t.Errorf("Expected DirectShow manual exposure 0.25, got %v", p[0].Value)
+	}
+}
@@ -0,0 +1,220 @@
+package videoio
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"time"
+	"unicode"
+
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"gocv.io/x/gocv"
+)
+
+// Keys handled by Controls
+const (
+	KeyPause      = ' '
+	KeyScreenshot = 's'
+	KeyRecord     = 'r'
+	KeyUp         = '+'
+	KeyDown       = '-'
+	KeyHelp       = 'h'
+	KeyQuit       = 'q'
+	KeyEsc        = 27
+)
+
+// Default file names of screenshots and recordings, %s is replaced with the time
+const (
+	DefaultScreenshotPattern = "screenshot_%s.png"
+	DefaultRecordPattern     = "record_%s.avi"
+)
+
+var helpLines = []string{
+	"Space  pause / resume",
+	"S      screenshot",
+	"R      start / stop recording",
+	"+/-    adjust threshold",
+	"H      show / hide help",
+	"Q/Esc  quit",
+}
+
+// Controls handles keys pressed in the window of an example in the same way for all examples:
+// space pauses, S saves a screenshot, R toggles recording, +/- adjust the main threshold,
+// H shows help and Q or Esc quits
+type Controls struct {
+	// Threshold is called with +1 or -1 to adjust the main threshold of the example and returns
+	// its new value as text. Nil if the example has no threshold
+	Threshold func(step int) string
+
+	// Keys are additional keys of the example, handled after the common ones
+	Keys []KeyAction
+
+	ScreenshotPattern string
+	RecordPattern     string
+	Codec             string
+	FPS               float64
+	Segment           time.Duration // Length of the segments of recordings, one file if 0
+
+	ctx       context.Context
+	paused    bool
+	help      bool
+	record    FrameSink
+	recording string // File name of the recording
+	message   string
+	messageTo time.Time
+}
+
+// KeyAction is an example specific key, shown in the help as "key  help"
+type KeyAction struct {
+	Key  int
+	Help string
+	Do   func()
+}
+
+// NewControls creates controls with default file names. Pause is interrupted when ctx is cancelled
+func NewControls(ctx context.Context, codec string, fps float64) *Controls {
+	return &Controls{
+		ScreenshotPattern: DefaultScreenshotPattern,
+		RecordPattern:     DefaultRecordPattern,
+		Codec:             codec,
+		FPS:               fps,
+		ctx:               ctx,
+		message:           "Press H for help",
+		messageTo:         time.Now().Add(5 * time.Second),
+	}
+}
+
+// Show shows the frame in the window with the help and status overlay, records it if recording
+// is on, and handles pressed keys. While paused it keeps showing the frame until resumed.
+// Returns ErrStopped when the user quits
+func (c *Controls) Show(window Display, img gocv.Mat) error {
+	if c.record != nil {
+		if err := c.record.Write(img); err != nil {
+			c.stopRecording()
+			return err
+		}
+	}
+	for {
+		shown := img
+		if c.help || c.paused || time.Now().Before(c.messageTo) {
+			shown = img.Clone()
+			c.overlay(&shown)
+		}
+		window.IMShow(shown)
+		if shown.Ptr() != img.Ptr() {
+			shown.Close()
+		}
+
+		if err := c.handleKey(window.WaitKey(1), img); err != nil {
+			return err
+		}
+		if !c.paused {
+			return nil
+		}
+		if c.ctx.Err() != nil {
+			return ErrStopped
+		}
+		time.Sleep(30 * time.Millisecond)
+	}
+}
+
+// Returns ErrStopped if the user quits
+func (c *Controls) handleKey(key int, img gocv.Mat) error {
+	switch key {
+	case KeyQuit, KeyEsc:
+		return ErrStopped
+	case KeyPause:
+		c.paused = !c.paused
+	case KeyHelp:
+		c.help = !c.help
+	case KeyScreenshot:
+		filename := fmt.Sprintf(c.ScreenshotPattern, timestamp())
+		if gocv.IMWrite(filename, img) {
+			c.notify("Saved " + filename)
+		} else {
+			c.notify("Cannot save " + filename)
+		}
+	case KeyRecord:
+		if c.record != nil {
+			c.stopRecording()
+			break
+		}
+		c.recording = fmt.Sprintf(c.RecordPattern, timestamp())
+		if c.Segment > 0 {
+			c.record = NewSegmentSink(c.recording, c.Codec, c.FPS, c.Segment)
+		} else {
+			c.record = NewVideoSink(c.recording, c.Codec, c.FPS)
+		}
+		c.notify("Recording to " + c.recording)
+	case KeyUp, '=', KeyDown, '_':
+		if c.Threshold == nil {
+			break
+		}
+		step := 1
+		if key == KeyDown || key == '_' {
+			step = -1
+		}
+		c.notify("Threshold " + c.Threshold(step))
+	default:
+		for _, ka := range c.Keys {
+			if ka.Key == key {
+				ka.Do()
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Controls) stopRecording() {
+	if err := c.record.Close(); err != nil {
+		logging.Errorf("Error finishing recording: %v", err)
+	}
+	c.notify("Recorded " + c.recording)
+	c.record = nil
+}
+
+// Shows the message on the frames for a while and logs it
+func (c *Controls) notify(msg string) {
+	logging.Infof("%s", msg)
+	c.message = msg
+	c.messageTo = time.Now().Add(2 * time.Second)
+}
+
+// Draws help, pause and status messages in the top right corner
+func (c *Controls) overlay(img *gocv.Mat) {
+	var lines []string
+	if c.paused {
+		lines = append(lines, "Paused")
+	}
+	if time.Now().Before(c.messageTo) {
+		lines = append(lines, c.message)
+	}
+	if c.help {
+		lines = append(lines, helpLines...)
+		for _, ka := range c.Keys {
+			lines = append(lines, fmt.Sprintf("%-6c %s", unicode.ToUpper(rune(ka.Key)), ka.Help))
+		}
+	}
+	st := draw.DefaultStyle
+	y := 0
+	for _, line := range lines {
+		size := st.TextSize(line)
+		y += size.Y + 2*st.Padding
+		draw.TextWithBackground(img, line, image.Pt(img.Cols()-size.X-2*st.Padding, y), st)
+	}
+}
+
+// Close finishes the recording, if any
+func (c *Controls) Close() error {
+	if c.record != nil {
+		err := c.record.Close()
+		c.record = nil
+		return err
+	}
+	return nil
+}
+
+func timestamp() string {
+	return time.Now().Format("20060102_150405")
+}
@@ -0,0 +1,181 @@
+package videoio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gocv.io/x/gocv"
+)
+
+// Editor writes frames to a video file and keeps a copy of the last written frame, which is used
+// as a starting point for transitions. Writing stops with ErrStopped once the context is cancelled
+type Editor struct {
+	VWriter   *gocv.VideoWriter
+	LastFrame *gocv.Mat // Nil until a frame is written, owned by the editor
+	FPS       float64
+	ctx       context.Context
+	last      gocv.Mat
+}
+
+// NewEditor creates an Editor writing to vWriter with the given frame rate. It should be closed
+func NewEditor(ctx context.Context, vWriter *gocv.VideoWriter, fps float64) *Editor {
+	return &Editor{VWriter: vWriter, FPS: fps, ctx: ctx, last: gocv.NewMat()}
+}
+
+// Close releases the last frame
+func (ed *Editor) Close() error {
+	ed.LastFrame = nil
+	return ed.last.Close()
+}
+
+// Copies the last written frame of a sequence to LastFrame, once the sequence is complete since
+// its source may be LastFrame itself
+func (ed *Editor) keep(img *gocv.Mat) {
+	if img.Empty() || img.Ptr() == ed.last.Ptr() {
+		return
+	}
+	img.CopyTo(&ed.last)
+	ed.LastFrame = &ed.last
+}
+
+// RepeatFrame writes the same image to the video file for delay seconds
+func (ed *Editor) RepeatFrame(img *gocv.Mat, delay float64) (err error) {
+	if !ed.VWriter.IsOpened() {
+		return errors.New("Cannot write to the file")
+	}
+	defer ed.keep(img)
+	nFrames := int(delay * ed.FPS)
+	for i := 0; i <= nFrames; i++ {
+		if ed.ctx.Err() != nil {
+			return ErrStopped
+		}
+		err = ed.VWriter.Write(*img)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// FadeImageInto writes a fading in sequence to the video file
+// Starting image img1, ending image img2, duration delay seconds
+func (ed *Editor) FadeImageInto(img1, img2 *gocv.Mat, delay float64) (err error) {
+	if delay <= 0 {
+		return fmt.Errorf("Cannot make transition of %f seconds", delay)
+	}
+	if !ed.VWriter.IsOpened() {
+		return errors.New("Cannot write to the file")
+	}
+
+	img3 := gocv.NewMat()
+	defer img3.Close()
+	defer ed.keep(&img3)
+	alpha, beta := 0.0, 1.0
+	nFrames := int(delay * ed.FPS)
+
+	for i := 0; i <= nFrames; i++ {
+		if ed.ctx.Err() != nil {
+			return ErrStopped
+		}
+		if i == nFrames-1 {
+			alpha, beta = 0, 1
+		} else {
+			beta = float64(i) / float64(nFrames-1)
+			alpha = 1 - beta
+		}
+		gocv.AddWeighted(*img1, alpha, *img2, beta, 1, &img3)
+		err = ed.VWriter.Write(img3)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Transition writes a sequence of delay seconds rendered frame by frame: render draws the frame
+// at t going from 0 to 1 into img, which is kept between calls
+func (ed *Editor) Transition(delay float64, render func(t float64, img *gocv.Mat)) error {
+	if delay <= 0 {
+		return fmt.Errorf("Cannot make transition of %f seconds", delay)
+	}
+	if !ed.VWriter.IsOpened() {
+		return errors.New("Cannot write to the file")
+	}
+
+	img := gocv.NewMat()
+	defer img.Close()
+	defer ed.keep(&img)
+	nFrames := int(delay * ed.FPS)
+	for i := 0; i <= nFrames; i++ {
+		if ed.ctx.Err() != nil {
+			return ErrStopped
+		}
+		t := 1.0
+		if nFrames > 0 {
+			t = float64(i) / float64(nFrames)
+		}
+		render(t, &img)
+		if err := ed.VWriter.Write(img); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyFrom copies frames from the video and adds intermediate frames since the original video is slow
+func (ed *Editor) CopyFrom(vr *gocv.VideoCapture, delay float64) (err error) {
+	if delay <= 0 {
+		return fmt.Errorf("Wrong duration specified: %f seconds", delay)
+	}
+	if !ed.VWriter.IsOpened() {
+		return errors.New("Cannot write to the file")
+	}
+
+	nFrames := int(delay * ed.FPS)
+	extraFrame := gocv.NewMat()
+	defer extraFrame.Close()
+	img := gocv.NewMat()
+	defer img.Close()
+	for i := 0; i < nFrames; i++ {
+		if ed.ctx.Err() != nil {
+			return ErrStopped
+		}
+		vr.Read(&img)
+		gocv.AddWeighted(*ed.LastFrame, 0.7, img, 0.3, 1, &extraFrame)
+		ed.VWriter.Write(extraFrame)
+		gocv.AddWeighted(*ed.LastFrame, 0.3, img, 0.7, 1, &extraFrame)
+		ed.VWriter.Write(extraFrame)
+		ed.VWriter.Write(img)
+		ed.keep(&img)
+	}
+	return
+}
+
+// Copy copies frames from the video as they are for delay seconds of the output, or until
+// the video ends
+func (ed *Editor) Copy(vr *gocv.VideoCapture, delay float64) error {
+	if delay <= 0 {
+		return fmt.Errorf("Wrong duration specified: %f seconds", delay)
+	}
+	if !ed.VWriter.IsOpened() {
+		return errors.New("Cannot write to the file")
+	}
+
+	nFrames := int(delay * ed.FPS)
+	img := gocv.NewMat()
+	defer img.Close()
+	defer ed.keep(&img)
+	for i := 0; i < nFrames; i++ {
+		if ed.ctx.Err() != nil {
+			return ErrStopped
+		}
+		if !vr.Read(&img) || img.Empty() {
+			return nil
+		}
+		if err := ed.VWriter.Write(img); err != nil {
+			return err
+		}
+	}
+	return nil
+}
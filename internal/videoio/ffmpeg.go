@@ -0,0 +1,159 @@
+package videoio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gocv.io/x/gocv"
+)
+
+// Lines of ffmpeg's error output kept to explain a failure
+const ffmpegLogLines = 10
+
+// IsStreamOutput reports whether OpenSink publishes the output through ffmpeg: an RTMP URL,
+// e.g. rtmp://a.rtmp.youtube.com/live2/KEY, or an HLS playlist like out/live.m3u8
+func IsStreamOutput(output string) bool {
+	lower := strings.ToLower(output)
+	return strings.HasPrefix(lower, "rtmp://") || strings.HasPrefix(lower, "rtmps://") ||
+		strings.HasSuffix(lower, ".m3u8")
+}
+
+// FFmpegSink pipes raw frames to an ffmpeg process encoding them with H.264 for an RTMP server
+// or as HLS segments next to the playlist. The process is started with the definition of the first frame.
+// Frames are timed at the given frame rate, so processing should keep up with it for a live stream.
+// RTMP gets a silent audio track, since some services, like YouTube, do not start a stream without audio
+type FFmpegSink struct {
+	output string
+	fps    float64
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	log    *tailWriter
+	width  int
+	height int
+}
+
+// NewFFmpegSink creates a sink publishing frames to the RTMP URL or HLS playlist at the frame rate
+func NewFFmpegSink(output string, fps float64) (*FFmpegSink, error) {
+	if !IsStreamOutput(output) {
+		return nil, fmt.Errorf("Not an RTMP URL or HLS playlist: %s", output)
+	}
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return nil, errors.New("ffmpeg command not found, install FFmpeg, e.g. 'apt install ffmpeg'")
+	}
+	if fps <= 0 {
+		return nil, fmt.Errorf("Frame rate should be positive: %g", fps)
+	}
+	return &FFmpegSink{output: output, fps: fps}, nil
+}
+
+// ffmpegArgs returns the arguments of ffmpeg reading BGR frames of the size from stdin
+func ffmpegArgs(output string, width, height int, fps float64) []string {
+	rate := strconv.FormatFloat(fps, 'g', -1, 64)
+	gop := strconv.Itoa(int(2*fps + 0.5)) // A keyframe every 2 s lets viewers join quickly
+	args := []string{"-hide_banner", "-loglevel", "error",
+		"-f", "rawvideo", "-pix_fmt", "bgr24", "-s", fmt.Sprintf("%dx%d", width, height), "-r", rate, "-i", "-"}
+	hls := strings.HasSuffix(strings.ToLower(output), ".m3u8")
+	if !hls {
+		args = append(args, "-f", "lavfi", "-i", "anullsrc=channel_layout=stereo:sample_rate=44100")
+	}
+	args = append(args, "-c:v", "libx264", "-preset", "veryfast", "-tune", "zerolatency",
+		"-pix_fmt", "yuv420p", "-g", gop)
+	if hls {
+		return append(args, "-f", "hls", "-hls_time", "2", "-hls_list_size", "6",
+			"-hls_flags", "delete_segments", output)
+	}
+	return append(args, "-c:a", "aac", "-b:a", "128k", "-shortest", "-f", "flv", output)
+}
+
+func (ff *FFmpegSink) start(width, height int) error {
+	if strings.HasSuffix(strings.ToLower(ff.output), ".m3u8") {
+		if err := os.MkdirAll(filepath.Dir(ff.output), 0755); err != nil {
+			return err
+		}
+	}
+	cmd := exec.Command("ffmpeg", ffmpegArgs(ff.output, width, height, ff.fps)...)
+	ff.log = &tailWriter{max: ffmpegLogLines}
+	cmd.Stderr = ff.log
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("Cannot start ffmpeg: %v", err)
+	}
+	ff.cmd, ff.stdin, ff.width, ff.height = cmd, stdin, width, height
+	return nil
+}
+
+// Write sends the frame to ffmpeg. All frames should have the definition of the first one
+func (ff *FFmpegSink) Write(img gocv.Mat) error {
+	if ff.cmd == nil {
+		if err := ff.start(img.Cols(), img.Rows()); err != nil {
+			return err
+		}
+	}
+	if img.Cols() != ff.width || img.Rows() != ff.height {
+		return fmt.Errorf("Frame %dx%d differs from the stream %dx%d", img.Cols(), img.Rows(), ff.width, ff.height)
+	}
+	if img.Type() != gocv.MatTypeCV8UC3 {
+		return errors.New("Stream frames should be BGR")
+	}
+	if _, err := ff.stdin.Write(img.ToBytes()); err != nil {
+		return fmt.Errorf("Error streaming to %s: %v: %s", ff.output, err, ff.log)
+	}
+	return nil
+}
+
+// Close ends the stream and waits for ffmpeg to finish, so the HLS playlist is complete
+func (ff *FFmpegSink) Close() error {
+	if ff.cmd == nil {
+		return nil
+	}
+	ff.stdin.Close()
+	if err := ff.cmd.Wait(); err != nil {
+		return fmt.Errorf("ffmpeg: %v: %s", err, ff.log)
+	}
+	return nil
+}
+
+// tailWriter keeps the last lines written to it
+type tailWriter struct {
+	mu      sync.Mutex
+	max     int
+	lines   []string
+	partial string
+}
+
+func (tw *tailWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	parts := strings.Split(tw.partial+string(p), "\n")
+	tw.partial = parts[len(parts)-1]
+	for _, line := range parts[:len(parts)-1] {
+		if line = strings.TrimSpace(line); line != "" {
+			tw.lines = append(tw.lines, line)
+		}
+	}
+	if len(tw.lines) > tw.max {
+		tw.lines = tw.lines[len(tw.lines)-tw.max:]
+	}
+	return len(p), nil
+}
+
+// String returns the kept lines joined with "; "
+func (tw *tailWriter) String() string {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	lines := tw.lines
+	if p := strings.TrimSpace(tw.partial); p != "" {
+		lines = append(lines[:len(lines):len(lines)], p)
+	}
+	return strings.Join(lines, "; ")
+}
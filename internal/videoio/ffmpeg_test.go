@@ -0,0 +1,41 @@
+package videoio
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStreamOutput(t *testing.T) {
+	for _, out := range []string{"rtmp://a.rtmp.youtube.com/live2/key", "RTMPS://host/app", "out/live.m3u8"} {
+		if !IsStreamOutput(out) || IsVideoOutput(out) {
+			t.Errorf("%q should be a stream output", out)
+		}
+	}
+	for _, out := range []string{"video.avi", ":8080", "out/frame_%03d.jpg"} {
+		if IsStreamOutput(out) {
+			t.Errorf("%q should not be a stream output", out)
+		}
+	}
+}
+
+func TestFFmpegArgs(t *testing.T) {
+	rtmp := strings.Join(ffmpegArgs("rtmp://host/app/key", 640, 480, 25), " ")
+	for _, want := range []string{"-s 640x480 -r 25 -i -", "anullsrc", "-g 50", "-f flv rtmp://host/app/key"} {
+		if !strings.Contains(rtmp, want) {
+			t.Errorf("RTMP arguments %q should contain %q", rtmp, want)
+		}
+	}
+	hls := strings.Join(ffmpegArgs("out/live.m3u8", 640, 480, 12.5), " ")
+	if strings.Contains(hls, "anullsrc") || !strings.Contains(hls, "-r 12.5") || !strings.HasSuffix(hls, "-f hls -hls_time 2 -hls_list_size 6 -hls_flags delete_segments out/live.m3u8") {
+		t.Errorf("Unexpected HLS arguments %q", hls)
+	}
+}
+
+func TestTailWriter(t *testing.T) {
+	tw := &tailWriter{max: 2}
+	tw.Write([]byte("one\ntwo\nthr"))
+	tw.Write([]byte("ee\nfour"))
+	if s := tw.String(); s != "two; three; four" {
+		t.Errorf("Unexpected tail %q", s)
+	}
+}
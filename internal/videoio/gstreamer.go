@@ -0,0 +1,52 @@
+package videoio
+
+import (
+	"fmt"
+	"strings"
+
+	"gocv.io/x/gocv"
+)
+
+// GStreamerPrefix starts an input given as a GStreamer pipeline, e.g.
+// "gst:nvarguscamerasrc ! video/x-raw(memory:NVMM),width=1280,height=720 ! nvvidconv ! video/x-raw,format=BGRx"
+// for the CSI camera of a Jetson board. A pipeline without an appsink gets one converting frames to BGR,
+// see gstreamerPipeline
+const GStreamerPrefix = "gst:"
+
+// Tail of pipelines without an appsink, delivering the latest frame in BGR
+const gstreamerSink = "videoconvert ! video/x-raw,format=BGR ! appsink drop=true max-buffers=1"
+
+// IsGStreamerInput checks whether the input is a GStreamer pipeline: it has GStreamerPrefix
+// or is a pipeline ending in an appsink, as passed to OpenCV directly
+func IsGStreamerInput(input string) bool {
+	return strings.HasPrefix(input, GStreamerPrefix) ||
+		strings.Contains(input, "!") && strings.Contains(input, "appsink")
+}
+
+// gstreamerPipeline returns the pipeline of the GStreamer input, adding an appsink if there is none.
+// A pipeline reading a file with filesrc is not live: it ends rather than fails when it runs out of frames
+func gstreamerPipeline(input string) (pipeline string, live bool, err error) {
+	pipeline = strings.TrimSpace(strings.TrimPrefix(input, GStreamerPrefix))
+	if pipeline == "" {
+		return "", false, fmt.Errorf("Empty GStreamer pipeline: %q", input)
+	}
+	if !strings.Contains(pipeline, "appsink") {
+		pipeline = strings.TrimSpace(strings.TrimSuffix(pipeline, "!")) + " ! " + gstreamerSink
+	}
+	return pipeline, !strings.Contains(pipeline, "filesrc"), nil
+}
+
+// NewGStreamerSource reads frames of a GStreamer pipeline, see GStreamerPrefix. It lets hardware
+// decoders and board cameras feed the examples, and needs OpenCV built with GStreamer
+func NewGStreamerSource(input string) (FrameSource, error) {
+	pipeline, live, err := gstreamerPipeline(input)
+	if err != nil {
+		return nil, err
+	}
+	capture, err := gocv.VideoCaptureFileWithAPI(pipeline, gocv.VideoCaptureGstreamer)
+	if err != nil {
+		capture.Close()
+		return nil, fmt.Errorf("Cannot open GStreamer pipeline %q, it needs OpenCV built with GStreamer: %v", pipeline, err)
+	}
+	return &captureSource{capture: capture, name: "GStreamer pipeline", live: live}, nil
+}
@@ -0,0 +1,29 @@
+package videoio
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGStreamerPipeline(t *testing.T) {
+	if !IsGStreamerInput("gst:v4l2src") || !IsGStreamerInput("v4l2src ! videoconvert ! appsink") ||
+		IsGStreamerInput("video.mp4") || IsGStreamerInput("rtsp://host/stream") {
+		t.Error("Unexpected GStreamer input detection")
+	}
+
+	p, live, err := gstreamerPipeline("gst: v4l2src device=/dev/video1 ! ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !live || p != "v4l2src device=/dev/video1 ! "+gstreamerSink {
+		t.Errorf("Unexpected pipeline %q, live %v", p, live)
+	}
+
+	in := "filesrc location=a.mp4 ! decodebin ! videoconvert ! appsink"
+	if p, live, _ := gstreamerPipeline(in); live || p != in {
+		t.Errorf("File pipeline should be kept and not live: %q, live %v", p, live)
+	}
+	if _, _, err := gstreamerPipeline("gst: "); err == nil || !strings.Contains(err.Error(), "Empty") {
+		t.Errorf("Expected an error for an empty pipeline, got %v", err)
+	}
+}
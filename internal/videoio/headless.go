@@ -0,0 +1,71 @@
+package videoio
+
+import (
+	"flag"
+	"io"
+	"os"
+	"runtime"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// Headless holds options of running an example without a display, e.g. on CI or a server:
+// no windows are created and processing stops after a number of frames or a duration
+type Headless struct {
+	NoGUI     bool
+	MaxFrames int
+	Duration  time.Duration
+}
+
+// RegisterFlags adds -no-gui, -max-frames and -duration flags to fs
+func (h *Headless) RegisterFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&h.NoGUI, "no-gui", false, "Do not open windows; also enabled automatically when there is no display")
+	fs.IntVar(&h.MaxFrames, "max-frames", 0, "Stop after processing this number of frames, 0 for no limit")
+	fs.DurationVar(&h.Duration, "duration", 0, "Stop after this time, e.g. 30s, 0 for no limit")
+}
+
+// Enabled reports whether windows should not be used: either requested by the flag, or there is no display
+func (h *Headless) Enabled() bool {
+	return h.NoGUI || !HasDisplay()
+}
+
+// Limit wraps the source to end after MaxFrames frames or Duration, if set
+func (h *Headless) Limit(src FrameSource) FrameSource {
+	if h.MaxFrames <= 0 && h.Duration <= 0 {
+		return src
+	}
+	ls := &limitedSource{FrameSource: src, maxFrames: h.MaxFrames}
+	if h.Duration > 0 {
+		ls.deadline = time.Now().Add(h.Duration)
+	}
+	return ls
+}
+
+// HasDisplay reports whether windows can be shown. On Linux and BSD it checks for an X11 or Wayland display
+func HasDisplay() bool {
+	switch runtime.GOOS {
+	case "windows", "darwin":
+		return true
+	}
+	return os.Getenv("DISPLAY") != "" || os.Getenv("WAYLAND_DISPLAY") != ""
+}
+
+// limitedSource ends the stream after a number of frames or at a deadline
+type limitedSource struct {
+	FrameSource
+	maxFrames int
+	deadline  time.Time
+	frames    int
+}
+
+func (ls *limitedSource) Next() (gocv.Mat, error) {
+	if ls.maxFrames > 0 && ls.frames >= ls.maxFrames {
+		return gocv.Mat{}, io.EOF
+	}
+	if !ls.deadline.IsZero() && time.Now().After(ls.deadline) {
+		return gocv.Mat{}, io.EOF
+	}
+	ls.frames++
+	return ls.FrameSource.Next()
+}
@@ -0,0 +1,98 @@
+package videoio
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+)
+
+// DefaultHeadlessOutput is used without a display when no outputs are given
+const DefaultHeadlessOutput = "out/frame_%05d.jpg"
+
+// Outputs holds display and output options shared by the examples: frames are shown in a window
+// with Controls, unless running headless, and written to each output given with -output
+type Outputs struct {
+	Headless
+	Paths   config.StringList
+	Codec   string        // Codec of output video files and recordings
+	FPS     float64       // Frame rate of output video files and recordings
+	Segment time.Duration // Length of the segments of video files and recordings, one file if 0
+
+	// Windows, if set, owns the window, so that frames can be written from any goroutine
+	Windows *WindowManager
+}
+
+// NewOutputs creates options writing video files with given codec and frame rate
+func NewOutputs(codec string, fps float64) *Outputs {
+	return &Outputs{Codec: codec, FPS: fps}
+}
+
+// RegisterFlags adds -output and the headless flags to fs
+func (o *Outputs) RegisterFlags(fs *flag.FlagSet) {
+	fs.Var(&o.Paths, "output", "Additional output, repeatable: video file, image file pattern like out/frame_%03d.jpg, "+
+		"address like :8080 for MJPEG stream, RTMP URL or .m3u8 playlist to publish with ffmpeg, "+
+		"webrtc::8081 for a WebRTC viewer, or shm:NAME for shared memory")
+	fs.DurationVar(&o.Segment, "segment", 0, "Split output videos and recordings into files of this length, e.g. 10m, "+
+		"so that an aborted run keeps all but the last one; 0 for one file")
+	o.Headless.RegisterFlags(fs)
+}
+
+// Codecs returns the codecs used by the outputs, to be checked at startup
+func (o *Outputs) Codecs() []string {
+	for _, output := range o.Paths {
+		if IsVideoOutput(output) {
+			return []string{o.Codec}
+		}
+	}
+	return nil
+}
+
+// Open creates the window with given title, unless headless, and sinks of the outputs. Without
+// a display and outputs, frames are saved with DefaultHeadlessOutput. All sinks are closed by sd.
+// The window is nil when headless
+func (o *Outputs) Open(sd *shutdown.Handler, title string) (*WindowSink, MultiSink, error) {
+	var window *WindowSink
+	var sink MultiSink
+	outputs := o.Paths
+	if o.Enabled() {
+		logging.Infof("Running without display")
+		if len(outputs) == 0 {
+			outputs = []string{DefaultHeadlessOutput}
+		}
+	} else {
+		if o.Windows != nil {
+			window = NewManagedWindowSink(o.Windows, title, 0, 0)
+		} else {
+			window = NewWindowSink(title, 0, 0)
+		}
+		window.Controls = NewControls(sd.Context(), o.Codec, o.FPS)
+		window.Controls.Segment = o.Segment
+		sink = append(sink, window)
+		sd.OnClose("window", window.Close)
+	}
+	for _, output := range outputs {
+		var s FrameSink
+		if o.Segment > 0 && IsVideoOutput(output) {
+			s = NewSegmentSink(output, o.Codec, o.FPS, o.Segment)
+		} else {
+			var err error
+			if s, err = OpenSink(output, o.Codec, o.FPS); err != nil {
+				return nil, nil, fmt.Errorf("Error opening output: %v", err)
+			}
+		}
+		sink = append(sink, s)
+		sd.OnClose(output, s.Close)
+	}
+	return window, sink, nil
+}
+
+// IsVideoOutput reports whether OpenSink writes the output to a video file
+func IsVideoOutput(output string) bool {
+	return output != "" && !strings.HasPrefix(output, ":") && !strings.Contains(output, "%") && !IsStreamOutput(output) &&
+		!IsShmPath(output) && !IsWebRTCOutput(output)
+}
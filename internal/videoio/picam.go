@@ -0,0 +1,118 @@
+package videoio
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"gocv.io/x/gocv"
+)
+
+// PiCameraInput is the input name of the Raspberry Pi camera module, optionally followed by the mode
+// as ":WIDTHxHEIGHT" or ":WIDTHxHEIGHT@FPS", e.g. "picam:1640x1232@30". Without a mode the width and
+// height given to OpenSource are used, or piCameraDefault
+const PiCameraInput = "picam"
+
+// Mode of the Pi camera if none is requested
+var piCameraDefault = piCameraMode{Width: 1280, Height: 720, FPS: 30}
+
+// Highest frame rates by frame size that the ISP and the conversion to BGR keep up with on a Pi 4,
+// from the smallest frames. Larger frames than the last one are limited to its rate
+var piCameraMaxFPS = []piCameraMode{
+	{Width: 640, Height: 480, FPS: 90},
+	{Width: 1280, Height: 720, FPS: 60},
+	{Width: 1920, Height: 1080, FPS: 30},
+	{Width: 4056, Height: 3040, FPS: 10},
+}
+
+// piCameraMode is the frame size and rate requested from the Pi camera
+type piCameraMode struct {
+	Width, Height, FPS int
+}
+
+func (m piCameraMode) String() string {
+	return fmt.Sprintf("%dx%d@%d", m.Width, m.Height, m.FPS)
+}
+
+// IsPiCameraInput checks whether the input is the Raspberry Pi camera
+func IsPiCameraInput(input string) bool {
+	return input == PiCameraInput || strings.HasPrefix(input, PiCameraInput+":")
+}
+
+// parsePiCameraMode returns the mode of the Pi camera input, zero for the default
+func parsePiCameraMode(input string) (piCameraMode, error) {
+	if !IsPiCameraInput(input) {
+		return piCameraMode{}, fmt.Errorf("Not a Pi camera input: %s", input)
+	}
+	mode := strings.TrimPrefix(strings.TrimPrefix(input, PiCameraInput), ":")
+	if mode == "" {
+		return piCameraMode{}, nil
+	}
+	var m piCameraMode
+	valid := false
+	switch n, _ := fmt.Sscanf(mode, "%dx%d@%d", &m.Width, &m.Height, &m.FPS); n {
+	case 2:
+		valid = fmt.Sprintf("%dx%d", m.Width, m.Height) == mode
+	case 3:
+		valid = m.String() == mode && m.FPS > 0
+	}
+	if !valid || m.Width <= 0 || m.Height <= 0 {
+		return piCameraMode{}, fmt.Errorf("Pi camera mode should be WIDTHxHEIGHT or WIDTHxHEIGHT@FPS: %q", mode)
+	}
+	return m, nil
+}
+
+// negotiate returns the mode the camera can deliver closest to the requested one: missing values
+// are the defaults, sizes are even as the ISP needs them and the frame rate is capped by the size
+func (m piCameraMode) negotiate() piCameraMode {
+	if m.Width <= 0 || m.Height <= 0 {
+		m.Width, m.Height = piCameraDefault.Width, piCameraDefault.Height
+	}
+	m.Width, m.Height = m.Width&^1, m.Height&^1
+	max := piCameraMaxFPS[len(piCameraMaxFPS)-1].FPS
+	for _, limit := range piCameraMaxFPS {
+		if m.Width <= limit.Width && m.Height <= limit.Height {
+			max = limit.FPS
+			break
+		}
+	}
+	if m.FPS <= 0 {
+		m.FPS = piCameraDefault.FPS
+	}
+	if m.FPS > max {
+		m.FPS = max
+	}
+	return m
+}
+
+// piCameraPipelines returns the GStreamer pipelines to try for the mode, best first: libcamera of
+// Raspberry Pi OS Bullseye and later, then the legacy camera stack through its V4L2 driver
+func piCameraPipelines(m piCameraMode) []string {
+	caps := fmt.Sprintf("video/x-raw,width=%d,height=%d,framerate=%d/1", m.Width, m.Height, m.FPS)
+	return []string{
+		"libcamerasrc ! " + caps + " ! " + gstreamerSink,
+		"v4l2src device=/dev/video0 ! " + caps + " ! " + gstreamerSink,
+	}
+}
+
+// NewPiCameraSource opens the Raspberry Pi camera module with the mode closest to the requested one,
+// see PiCameraInput. It needs OpenCV built with GStreamer and, on the libcamera stack, the gstreamer1.0-libcamera
+// package; the legacy stack needs the camera enabled in raspi-config. Zero values are the defaults
+func NewPiCameraSource(width, height, fps int) (FrameSource, error) {
+	m := piCameraMode{Width: width, Height: height, FPS: fps}.negotiate()
+	var errs []string
+	for _, pipeline := range piCameraPipelines(m) {
+		capture, err := gocv.VideoCaptureFileWithAPI(pipeline, gocv.VideoCaptureGstreamer)
+		if err == nil && !capture.IsOpened() {
+			err = errors.New("not opened")
+		}
+		if err == nil {
+			logging.Infof("Pi camera %v: %s", m, pipeline)
+			return &captureSource{capture: capture, name: "Pi camera", live: true}, nil
+		}
+		capture.Close()
+		errs = append(errs, fmt.Sprintf("%s: %v", strings.SplitN(pipeline, " ", 2)[0], err))
+	}
+	return nil, fmt.Errorf("Cannot open Pi camera %v, it needs OpenCV built with GStreamer: %s", m, strings.Join(errs, "; "))
+}
@@ -0,0 +1,49 @@
+package videoio
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePiCameraMode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want piCameraMode
+	}{
+		{"picam", piCameraMode{}},
+		{"picam:640x480", piCameraMode{Width: 640, Height: 480}},
+		{"picam:1640x1232@30", piCameraMode{Width: 1640, Height: 1232, FPS: 30}},
+	}
+	for _, tt := range tests {
+		if got, err := parsePiCameraMode(tt.in); err != nil || got != tt.want {
+			t.Errorf("parsePiCameraMode(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"picamera", "picam:640", "picam:640x480@", "picam:640x480@0", "picam:0x480"} {
+		if _, err := parsePiCameraMode(in); err == nil {
+			t.Errorf("parsePiCameraMode(%q) should fail", in)
+		}
+	}
+}
+
+func TestNegotiatePiCameraMode(t *testing.T) {
+	tests := []struct {
+		in, want piCameraMode
+	}{
+		{piCameraMode{}, piCameraDefault},
+		{piCameraMode{Width: 641, Height: 481, FPS: 120}, piCameraMode{Width: 640, Height: 480, FPS: 90}},
+		{piCameraMode{Width: 1920, Height: 1080, FPS: 60}, piCameraMode{Width: 1920, Height: 1080, FPS: 30}},
+		{piCameraMode{Width: 8000, Height: 6000}, piCameraMode{Width: 8000, Height: 6000, FPS: 10}},
+	}
+	for _, tt := range tests {
+		if got := tt.in.negotiate(); got != tt.want {
+			t.Errorf("%v negotiated to %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	p := piCameraPipelines(piCameraMode{Width: 640, Height: 480, FPS: 30})
+	if len(p) != 2 || !strings.HasPrefix(p[0], "libcamerasrc ! video/x-raw,width=640,height=480,framerate=30/1 !") ||
+		!strings.HasPrefix(p[1], "v4l2src") {
+		t.Errorf("Unexpected pipelines %q", p)
+	}
+}
@@ -0,0 +1,237 @@
+package videoio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"gocv.io/x/gocv"
+)
+
+// ReplayExt is the extension of replay files: frames of a capture session with their times, recorded
+// with -record and read back by OpenSource, so that a pipeline can be tested and benchmarked on
+// exactly the same input. The file is little-endian:
+//
+//	header  "GOCVRPL1", codec byte (0 PNG, 1 JPEG), 7 reserved bytes
+//	frame   int64 nanoseconds since the first frame, uint32 length, the encoded image
+//
+// Frames are appended as they come, so the file of an interrupted session replays up to its last
+// complete frame. PNG frames replay exactly as captured; JPEG frames are smaller and decoded the
+// same way on every replay
+const ReplayExt = ".gcvr"
+
+const replayMagic = "GOCVRPL1"
+
+// Codecs of replay frames
+const (
+	replayPNG  = 0
+	replayJPEG = 1
+)
+
+// Largest frame accepted from a replay file, to fail on corrupt lengths
+const replayMaxFrame = 256 << 20
+
+// IsReplayFile reports whether the input is a replay file, by its extension
+func IsReplayFile(input string) bool {
+	return strings.EqualFold(filepath.Ext(input), ReplayExt)
+}
+
+// ReplayWriter appends frames to a replay file
+type ReplayWriter struct {
+	f      *os.File
+	w      *bufio.Writer
+	ext    gocv.FileExt
+	params []int
+	start  time.Time
+	frames int
+}
+
+// NewReplayWriter creates the replay file. Frames are stored as PNG if quality is 0, which is lossless,
+// or as JPEG of the quality from 1 to 100
+func NewReplayWriter(path string, quality int) (*ReplayWriter, error) {
+	if quality < 0 || quality > 100 {
+		return nil, fmt.Errorf("Replay JPEG quality should be from 1 to 100, or 0 for PNG, not %d", quality)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	rw := &ReplayWriter{f: f, w: bufio.NewWriterSize(f, 1<<20)}
+	header := make([]byte, 16)
+	copy(header, replayMagic)
+	if quality == 0 {
+		// Fast compression, recording should not slow capture down much
+		rw.ext, rw.params = gocv.PNGFileExt, []int{gocv.IMWritePngCompression, 1}
+	} else {
+		rw.ext, rw.params = gocv.JPEGFileExt, []int{gocv.IMWriteJpegQuality, quality}
+		header[8] = replayJPEG
+	}
+	if _, err := rw.w.Write(header); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return rw, nil
+}
+
+// Write appends the frame captured at t
+func (rw *ReplayWriter) Write(img gocv.Mat, t time.Time) error {
+	if rw.frames == 0 {
+		rw.start = t
+	}
+	buf, err := gocv.IMEncodeWithParams(rw.ext, img, rw.params)
+	if err != nil {
+		return fmt.Errorf("Cannot encode replay frame: %v", err)
+	}
+	defer buf.Close()
+	data := buf.GetBytes()
+	var head [12]byte
+	binary.LittleEndian.PutUint64(head[:8], uint64(t.Sub(rw.start)))
+	binary.LittleEndian.PutUint32(head[8:], uint32(len(data)))
+	if _, err := rw.w.Write(head[:]); err != nil {
+		return err
+	}
+	if _, err := rw.w.Write(data); err != nil {
+		return err
+	}
+	rw.frames++
+	return nil
+}
+
+// Close writes the buffered frames and closes the file
+func (rw *ReplayWriter) Close() error {
+	err := rw.w.Flush()
+	if cerr := rw.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// ReplaySource reads the frames of a replay file, in order and as fast as they are processed
+type ReplaySource struct {
+	f      *os.File
+	r      *bufio.Reader
+	path   string
+	frame  int
+	offset time.Duration
+}
+
+// NewReplaySource opens the replay file
+func NewReplaySource(path string) (*ReplaySource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReaderSize(f, 1<<20)
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:8]) != replayMagic {
+		f.Close()
+		return nil, fmt.Errorf("Not a replay file: %s", path)
+	}
+	if header[8] != replayPNG && header[8] != replayJPEG {
+		f.Close()
+		return nil, fmt.Errorf("Unknown codec %d of replay file %s", header[8], path)
+	}
+	return &ReplaySource{f: f, r: r, path: path}, nil
+}
+
+// Next returns the next frame, or io.EOF after the last complete frame
+func (rs *ReplaySource) Next() (gocv.Mat, error) {
+	var head [12]byte
+	if _, err := io.ReadFull(rs.r, head[:]); err != nil {
+		return gocv.NewMat(), rs.end(err)
+	}
+	n := binary.LittleEndian.Uint32(head[8:])
+	if n == 0 || n > replayMaxFrame {
+		return gocv.NewMat(), fmt.Errorf("Corrupt frame %d of replay file %s", rs.frame, rs.path)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(rs.r, data); err != nil {
+		return gocv.NewMat(), rs.end(err)
+	}
+	img, err := gocv.IMDecode(data, gocv.IMReadColor)
+	if err != nil || img.Empty() {
+		img.Close()
+		return gocv.NewMat(), fmt.Errorf("Cannot decode frame %d of replay file %s", rs.frame, rs.path)
+	}
+	rs.frame++
+	rs.offset = time.Duration(binary.LittleEndian.Uint64(head[:8]))
+	return img, nil
+}
+
+// Returns io.EOF at the end of the file, warning about a frame cut short by an interrupted recording
+func (rs *ReplaySource) end(err error) error {
+	if err == io.ErrUnexpectedEOF {
+		logging.Warnf("Replay file %s ends in the middle of frame %d", rs.path, rs.frame)
+		return io.EOF
+	}
+	return err
+}
+
+// Offset returns the time of the last frame since the first one, as recorded
+func (rs *ReplaySource) Offset() time.Duration {
+	return rs.offset
+}
+
+// Close closes the file
+func (rs *ReplaySource) Close() error {
+	return rs.f.Close()
+}
+
+// Recording holds the parameters of recording the input to a replay file, given by flags
+type Recording struct {
+	Path    string
+	Quality int
+}
+
+// RegisterFlags adds -record and -record-quality flags to fs
+func (rec *Recording) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&rec.Path, "record", "", "Record the input frames with their times to a "+ReplayExt+" replay file, which can be given as -input later")
+	fs.IntVar(&rec.Quality, "record-quality", 0, "JPEG quality of recorded frames from 1 to 100, 0 for lossless PNG")
+}
+
+// Wrap returns src recording each frame read to the replay file, which is closed by sd,
+// or src itself if there is no file to record to
+func (rec *Recording) Wrap(sd *shutdown.Handler, src FrameSource) (FrameSource, error) {
+	if rec.Path == "" {
+		return src, nil
+	}
+	if !IsReplayFile(rec.Path) {
+		return nil, fmt.Errorf("Replay file should have the %s extension: %s", ReplayExt, rec.Path)
+	}
+	rw, err := NewReplayWriter(rec.Path, rec.Quality)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot create replay file: %v", err)
+	}
+	sd.OnClose("record", func() error {
+		logging.Infof("Recorded %d frames to %s", rw.frames, rec.Path)
+		return rw.Close()
+	})
+	logging.Infof("Recording the input to %s", rec.Path)
+	return &recordingSource{FrameSource: src, w: rw}, nil
+}
+
+// recordingSource writes the frames read from the source to a replay file
+type recordingSource struct {
+	FrameSource
+	w   *ReplayWriter
+	err error
+}
+
+func (rs *recordingSource) Next() (gocv.Mat, error) {
+	img, err := rs.FrameSource.Next()
+	if err != nil || rs.err != nil {
+		return img, err
+	}
+	if rs.err = rs.w.Write(img, time.Now()); rs.err != nil {
+		logging.Errorf("Recording stopped: %v", rs.err)
+	}
+	return img, nil
+}
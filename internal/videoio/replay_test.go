@@ -0,0 +1,95 @@
+package videoio
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// Writes frames of the colors 1 ms apart and returns the path of the file
+func writeReplay(t *testing.T, quality int, colors ...float64) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "session"+ReplayExt)
+	rw, err := NewReplayWriter(path, quality)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	for i, c := range colors {
+		img := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(c, c+1, c+2, 0), 4, 6, gocv.MatTypeCV8UC3)
+		err := rw.Write(img, start.Add(time.Duration(i)*time.Millisecond))
+		img.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReplay(t *testing.T) {
+	path := writeReplay(t, 0, 10, 20, 30)
+	src, err := OpenSource(path, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	// Lossless frames come back exactly, with their times
+	for i, c := range []uint8{10, 20, 30} {
+		img, err := src.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := img.GetVecbAt(3, 5); img.Cols() != 6 || img.Rows() != 4 || v[0] != c || v[1] != c+1 || v[2] != c+2 {
+			t.Errorf("Frame %d of %dx%d with pixel %v, want %d", i, img.Cols(), img.Rows(), v, c)
+		}
+		img.Close()
+		if off := src.(*ReplaySource).Offset(); off != time.Duration(i)*time.Millisecond {
+			t.Errorf("Frame %d at %v", i, off)
+		}
+	}
+	if _, err := src.Next(); err != io.EOF {
+		t.Errorf("Expected io.EOF after the last frame, got %v", err)
+	}
+}
+
+func TestReplayTruncated(t *testing.T) {
+	path := writeReplay(t, 90, 10, 20)
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, fi.Size()-3); err != nil {
+		t.Fatal(err)
+	}
+	src, err := NewReplaySource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	img, err := src.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	img.Close()
+	if _, err := src.Next(); err != io.EOF {
+		t.Errorf("Expected the cut frame to end the replay, got %v", err)
+	}
+
+	if _, err := NewReplayWriter(path, 101); err == nil {
+		t.Error("Expected an error for quality 101")
+	}
+	bad := filepath.Join(t.TempDir(), "bad"+ReplayExt)
+	ioutil.WriteFile(bad, []byte("GOCVSHM1"), 0644)
+	if _, err := NewReplaySource(bad); err == nil {
+		t.Error("Expected an error for a file without replay header")
+	}
+}
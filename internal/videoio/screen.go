@@ -0,0 +1,107 @@
+package videoio
+
+import (
+	"fmt"
+	"image"
+	"runtime"
+	"strings"
+
+	"gocv.io/x/gocv"
+)
+
+// ScreenInput is the input name of the screen capture, optionally followed by the region
+// as ":WIDTHxHEIGHT+X+Y", e.g. "screen:1280x720+0+0"
+const ScreenInput = "screen"
+
+// Frame rate requested from the screen capture
+const screenFPS = 25
+
+// IsScreenInput checks whether the input is the screen capture
+func IsScreenInput(input string) bool {
+	return input == ScreenInput || strings.HasPrefix(input, ScreenInput+":")
+}
+
+// parseScreenRegion returns the region of the screen capture input, empty for the whole screen
+func parseScreenRegion(input string) (image.Rectangle, error) {
+	if !IsScreenInput(input) {
+		return image.Rectangle{}, fmt.Errorf("Not a screen input: %s", input)
+	}
+	geometry := strings.TrimPrefix(strings.TrimPrefix(input, ScreenInput), ":")
+	if geometry == "" {
+		return image.Rectangle{}, nil
+	}
+	var w, h, x, y int
+	if n, err := fmt.Sscanf(geometry, "%dx%d+%d+%d", &w, &h, &x, &y); err != nil || n != 4 ||
+		w <= 0 || h <= 0 || x < 0 || y < 0 || fmt.Sprintf("%dx%d+%d+%d", w, h, x, y) != geometry {
+		return image.Rectangle{}, fmt.Errorf("Screen region should be WIDTHxHEIGHT+X+Y: %q", geometry)
+	}
+	return image.Rect(x, y, x+w, y+h), nil
+}
+
+// screenPipeline returns the GStreamer pipeline capturing the screen with the capture element of
+// the operating system. The capture is limited to the region if it is not empty and the element
+// supports it, as cropped tells; otherwise frames have the whole screen
+func screenPipeline(goos string, region image.Rectangle) (pipeline string, cropped bool, err error) {
+	var src string
+	switch goos {
+	case "linux", "freebsd", "openbsd":
+		// X11 only; coordinates of the end are inclusive
+		src = "ximagesrc use-damage=false show-pointer=true"
+		if !region.Empty() {
+			src += fmt.Sprintf(" startx=%d starty=%d endx=%d endy=%d", region.Min.X, region.Min.Y, region.Max.X-1, region.Max.Y-1)
+		}
+	case "darwin":
+		src = "avfvideosrc capture-screen=true capture-screen-cursor=true"
+	case "windows":
+		src = "gdiscreencapsrc cursor=true"
+		if !region.Empty() {
+			src += fmt.Sprintf(" x=%d y=%d width=%d height=%d", region.Min.X, region.Min.Y, region.Dx(), region.Dy())
+		}
+	default:
+		return "", false, fmt.Errorf("Screen capture is not supported on %s", goos)
+	}
+	cropped = !region.Empty() && goos != "darwin"
+	return fmt.Sprintf("%s ! video/x-raw,framerate=%d/1 ! videoconvert ! video/x-raw,format=BGR ! appsink drop=true max-buffers=1",
+		src, screenFPS), cropped, nil
+}
+
+// NewScreenSource captures the screen, or the region of it if not empty, through a GStreamer
+// pipeline, so OpenCV should be built with GStreamer and its plugins for the platform installed.
+// The region is in screen pixels, e.g. a window whose position is known
+func NewScreenSource(region image.Rectangle) (FrameSource, error) {
+	pipeline, cropped, err := screenPipeline(runtime.GOOS, region)
+	if err != nil {
+		return nil, err
+	}
+	capture, err := gocv.VideoCaptureFileWithAPI(pipeline, gocv.VideoCaptureGstreamer)
+	if err != nil {
+		capture.Close()
+		return nil, fmt.Errorf("Cannot open screen capture, it needs OpenCV built with GStreamer: %v", err)
+	}
+	src := &captureSource{capture: capture, name: "screen", live: true}
+	if cropped || region.Empty() {
+		return src, nil
+	}
+	return &cropSource{FrameSource: src, region: region}, nil
+}
+
+// cropSource crops frames of the source to the region, where the capture cannot do it
+type cropSource struct {
+	FrameSource
+	region image.Rectangle
+}
+
+func (cs *cropSource) Next() (gocv.Mat, error) {
+	img, err := cs.FrameSource.Next()
+	if err != nil {
+		return img, err
+	}
+	defer img.Close()
+	r := cs.region.Intersect(image.Rect(0, 0, img.Cols(), img.Rows()))
+	if r.Empty() {
+		return gocv.Mat{}, fmt.Errorf("Screen region %v is outside of the screen %dx%d", cs.region, img.Cols(), img.Rows())
+	}
+	crop := img.Region(r)
+	defer crop.Close()
+	return crop.Clone(), nil
+}
@@ -0,0 +1,48 @@
+package videoio
+
+import (
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestParseScreenRegion(t *testing.T) {
+	tests := []struct {
+		in   string
+		want image.Rectangle
+	}{
+		{"screen", image.Rectangle{}},
+		{"screen:", image.Rectangle{}},
+		{"screen:1280x720+100+50", image.Rect(100, 50, 1380, 770)},
+	}
+	for _, tt := range tests {
+		if got, err := parseScreenRegion(tt.in); err != nil || got != tt.want {
+			t.Errorf("parseScreenRegion(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"screens", "screen:1280x720", "screen:0x720+0+0", "screen:1280x720+0+0px"} {
+		if _, err := parseScreenRegion(in); err == nil {
+			t.Errorf("parseScreenRegion(%q) should fail", in)
+		}
+	}
+}
+
+func TestScreenPipeline(t *testing.T) {
+	p, cropped, err := screenPipeline("linux", image.Rect(100, 50, 1380, 770))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cropped || !strings.HasPrefix(p, "ximagesrc") || !strings.Contains(p, "startx=100 starty=50 endx=1379 endy=769") ||
+		!strings.HasSuffix(p, "appsink drop=true max-buffers=1") {
+		t.Errorf("Unexpected pipeline %q", p)
+	}
+	if p, _, _ := screenPipeline("windows", image.Rectangle{}); strings.Contains(p, "width=") {
+		t.Errorf("Whole screen pipeline should have no region: %q", p)
+	}
+	if _, cropped, _ := screenPipeline("darwin", image.Rect(0, 0, 640, 480)); cropped {
+		t.Error("Screen capture on macOS should be cropped by the source")
+	}
+	if _, _, err := screenPipeline("plan9", image.Rectangle{}); err == nil {
+		t.Error("Expected an error on an unsupported system")
+	}
+}
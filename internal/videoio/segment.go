@@ -0,0 +1,132 @@
+package videoio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"gocv.io/x/gocv"
+)
+
+// Marks the name of a segment being written, before the extension so that the container is kept
+const partSuffix = ".part"
+
+// SegmentSink records frames to a series of video files, starting a new one every segment length,
+// so that an aborted run loses at most the segment being written instead of the whole recording.
+// Segments are named after the path with the time they start, e.g. video_20240102_150405.avi for
+// video.avi, and are written as video_20240102_150405.part.avi until they are finalized: a file
+// without .part is complete. Segments left as .part by a crashed run are recovered under their final
+// name when the sink starts
+type SegmentSink struct {
+	path   string
+	length time.Duration
+	open   func(path string) FrameSink
+	now    func() time.Time
+
+	cur      FrameSink
+	name     string    // Final name of the current segment
+	started  time.Time // Time of the first frame of the current segment
+	segments []string
+}
+
+// NewSegmentSink creates a sink writing to segments of the video file with given codec and frame rate,
+// each of the given length
+func NewSegmentSink(path, codec string, fps float64, length time.Duration) *SegmentSink {
+	return &SegmentSink{path: path, length: length, now: time.Now,
+		open: func(path string) FrameSink { return NewVideoSink(path, codec, fps) }}
+}
+
+// Write appends the frame to the current segment, finalizing it and starting the next one when it
+// reached its length
+func (ss *SegmentSink) Write(img gocv.Mat) error {
+	now := ss.now()
+	if ss.cur != nil && now.Sub(ss.started) >= ss.length {
+		if err := ss.finish(); err != nil {
+			return err
+		}
+	}
+	if ss.cur == nil {
+		if ss.segments == nil {
+			ss.recover()
+		}
+		ss.start(now)
+	}
+	return ss.cur.Write(img)
+}
+
+// Starts a segment named after the time
+func (ss *SegmentSink) start(now time.Time) {
+	ext := filepath.Ext(ss.path)
+	base := strings.TrimSuffix(ss.path, ext) + "_" + now.Format("20060102_150405")
+	ss.name = base + ext
+	// Segments shorter than a second would have the same name
+	for i := 2; ss.exists(ss.name); i++ {
+		ss.name = fmt.Sprintf("%s_%d%s", base, i, ext)
+	}
+	ss.cur, ss.started = ss.open(partName(ss.name)), now
+	logging.Debugf("Recording segment %s", ss.name)
+}
+
+// Reports whether the segment was written by this sink or exists on disk
+func (ss *SegmentSink) exists(name string) bool {
+	for _, s := range ss.segments {
+		if s == name {
+			return true
+		}
+	}
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+// Finalizes the current segment and renames it to its final name
+func (ss *SegmentSink) finish() error {
+	cur := ss.cur
+	ss.cur = nil
+	if err := cur.Close(); err != nil {
+		return fmt.Errorf("Error finalizing segment %s: %v", ss.name, err)
+	}
+	if err := os.Rename(partName(ss.name), ss.name); err != nil {
+		return fmt.Errorf("Error finalizing segment: %v", err)
+	}
+	ss.segments = append(ss.segments, ss.name)
+	logging.Infof("Saved segment %s", ss.name)
+	return nil
+}
+
+// Renames segments of the path left unfinished by an aborted run. They are readable up to the last
+// frames written, but may lack the index of the container
+func (ss *SegmentSink) recover() {
+	ss.segments = []string{}
+	ext := filepath.Ext(ss.path)
+	parts, _ := filepath.Glob(strings.TrimSuffix(ss.path, ext) + "_*" + partSuffix + ext)
+	for _, p := range parts {
+		name := strings.TrimSuffix(p, partSuffix+ext) + ext
+		if err := os.Rename(p, name); err != nil {
+			logging.Warnf("Cannot recover segment of an aborted run: %v", err)
+			continue
+		}
+		logging.Warnf("Recovered segment %s of an aborted run, its end may be missing", name)
+	}
+}
+
+// Segments returns the names of the finalized segments, oldest first
+func (ss *SegmentSink) Segments() []string {
+	return ss.segments
+}
+
+// Close finalizes the current segment
+func (ss *SegmentSink) Close() error {
+	if ss.cur == nil {
+		return nil
+	}
+	return ss.finish()
+}
+
+// Returns the name a segment is written under until it is finalized
+func partName(name string) string {
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + partSuffix + ext
+}
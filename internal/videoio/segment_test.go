@@ -0,0 +1,95 @@
+package videoio
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/testutil"
+	"gocv.io/x/gocv"
+)
+
+// Sink appending a line per frame to a file
+type lineSink struct {
+	f *os.File
+}
+
+func (s *lineSink) Write(img gocv.Mat) error {
+	_, err := s.f.WriteString("frame\n")
+	return err
+}
+
+func (s *lineSink) Close() error { return s.f.Close() }
+
+func TestSegmentSink(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "video.avi")
+	// A segment left by an aborted run
+	aborted := filepath.Join(dir, "video_20240101_235959.part.avi")
+	if err := ioutil.WriteFile(aborted, []byte("frame\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ss := NewSegmentSink(path, "MJPG", 10, time.Minute)
+	ss.open = func(path string) FrameSink {
+		f, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &lineSink{f}
+	}
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	ss.now = func() time.Time { return now }
+	img := testutil.SolidImage(t, 8, 6, gocv.NewScalar(0, 0, 0, 0))
+
+	// Frames every 20 seconds: 3 in each minute, and 2 left in the last segment
+	for i := 0; i < 8; i++ {
+		if err := ss.Write(img); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			if _, err := os.Stat(filepath.Join(dir, "video_20240102_150405.part.avi")); err != nil {
+				t.Errorf("Segment not written under its part name: %v", err)
+			}
+		}
+		now = now.Add(20 * time.Second)
+	}
+	if err := ss.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"video_20240102_150405.avi", "video_20240102_150505.avi", "video_20240102_150605.avi"}
+	var got []string
+	for _, s := range ss.Segments() {
+		got = append(got, filepath.Base(s))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Segments %v, want %v", got, want)
+	}
+	for i, name := range want {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if frames := len(data) / len("frame\n"); err != nil || frames != 3-i/2 {
+			t.Errorf("Segment %s has %d frames: %v", name, frames, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "video_20240101_235959.avi")); err != nil {
+		t.Errorf("Aborted segment not recovered: %v", err)
+	}
+	if parts, _ := filepath.Glob(filepath.Join(dir, "*.part.avi")); len(parts) > 0 {
+		t.Errorf("Unfinished segments left: %v", parts)
+	}
+
+	// Segments starting in the same second get a number
+	now = time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	ss.segments = nil
+	if err := ss.Write(img); err != nil {
+		t.Fatal(err)
+	}
+	ss.Close()
+	if s := ss.Segments(); len(s) != 1 || filepath.Base(s[0]) != "video_20240102_150405_2.avi" {
+		t.Errorf("Segment in the same second saved as %v", s)
+	}
+}
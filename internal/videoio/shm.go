@@ -0,0 +1,279 @@
+package videoio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"gocv.io/x/gocv"
+)
+
+// ShmPrefix starts an input or output exchanging raw frames with another process through POSIX
+// shared memory, e.g. "shm:camera0" for /dev/shm/camera0, the segment of
+// multiprocessing.shared_memory.SharedMemory(name="camera0") in Python. A name with a slash is
+// taken as the path of a file to map, e.g. on a tmpfs.
+//
+// The segment holds a 64-byte header followed by the pixels of one frame, rows without padding.
+// Header fields are little-endian:
+//
+//	offset  size  field
+//	0       8     magic "GOCVSHM1"
+//	8       8     sequence, odd while a frame is written, incremented before and after writing it
+//	16      4     width
+//	20      4     height
+//	24      4     channels: 1 for gray, 3 for BGR, 4 for BGRA, 8 bits each
+//	28      4     capacity in bytes of the frame area
+//	32      8     capture time in nanoseconds since the Unix epoch
+//	40      4     closed, 1 once the writer has no more frames
+//
+// The writer creates the segment. A reader copies the frame and checks that the sequence did not
+// change meanwhile, so it never waits for the writer and the writer never waits for readers:
+// frames written faster than they are read are skipped. A Python writer, with frame a NumPy BGR
+// image of h x w x 3:
+//
+//	shm = SharedMemory(name="camera0", create=True, size=64 + h * w * 3)
+//	shm.buf[:8] = b"GOCVSHM1"
+//	struct.pack_into("<IIII", shm.buf, 16, w, h, 3, h * w * 3)
+//	seq = 0
+//	for frame in frames:
+//	    struct.pack_into("<Q", shm.buf, 8, seq + 1)
+//	    shm.buf[64:64 + frame.nbytes] = frame.tobytes()
+//	    struct.pack_into("<q", shm.buf, 32, time.time_ns())
+//	    seq += 2
+//	    struct.pack_into("<Q", shm.buf, 8, seq)
+const ShmPrefix = "shm:"
+
+// Layout of the shared memory segment, see ShmPrefix
+const (
+	shmMagic      = "GOCVSHM1"
+	shmHeaderSize = 64
+	shmOffSeq     = 8
+	shmOffWidth   = 16
+	shmOffHeight  = 20
+	shmOffChans   = 24
+	shmOffCap     = 28
+	shmOffTime    = 32
+	shmOffClosed  = 40
+)
+
+// A reader gives up if the sequence does not change for this long
+const shmStallTimeout = 10 * time.Second
+
+// Interval of polling for new frames
+const shmPollInterval = time.Millisecond
+
+// IsShmPath checks whether the input or output is a shared memory segment, see ShmPrefix
+func IsShmPath(s string) bool {
+	return strings.HasPrefix(s, ShmPrefix)
+}
+
+// shmFile returns the file of the segment given with ShmPrefix
+func shmFile(s string) (string, error) {
+	name := strings.TrimPrefix(s, ShmPrefix)
+	if name == "" {
+		return "", fmt.Errorf("Empty shared memory name: %q", s)
+	}
+	if strings.Contains(name, "/") {
+		return name, nil
+	}
+	return filepath.Join("/dev/shm", name), nil
+}
+
+// Mat type of 8-bit frames with the number of channels
+func shmMatType(channels int) (gocv.MatType, error) {
+	switch channels {
+	case 1:
+		return gocv.MatTypeCV8UC1, nil
+	case 3:
+		return gocv.MatTypeCV8UC3, nil
+	case 4:
+		return gocv.MatTypeCV8UC4, nil
+	}
+	return 0, fmt.Errorf("Unsupported number of channels %d", channels)
+}
+
+// shmSegment accesses the header and the frame of a mapped segment
+type shmSegment []byte
+
+func (m shmSegment) seq() *uint64 {
+	return (*uint64)(unsafe.Pointer(&m[shmOffSeq]))
+}
+
+func (m shmSegment) closed() *uint32 {
+	return (*uint32)(unsafe.Pointer(&m[shmOffClosed]))
+}
+
+func (m shmSegment) u32(off int) int {
+	return int(binary.LittleEndian.Uint32(m[off:]))
+}
+
+// init writes the header of a new segment. The sequence of a reused segment is kept, so that its
+// readers see the frames as new
+func (m shmSegment) init() {
+	copy(m, shmMagic)
+	binary.LittleEndian.PutUint32(m[shmOffCap:], uint32(len(m)-shmHeaderSize))
+	atomic.StoreUint32(m.closed(), 0)
+}
+
+// check verifies the magic and the capacity of the segment
+func (m shmSegment) check() error {
+	if len(m) < shmHeaderSize || string(m[:len(shmMagic)]) != shmMagic {
+		return errors.New("Not a frame segment, the magic is missing")
+	}
+	if m.u32(shmOffCap) > len(m)-shmHeaderSize {
+		return fmt.Errorf("Segment capacity %d exceeds its size %d", m.u32(shmOffCap), len(m))
+	}
+	return nil
+}
+
+// write stores the frame pixels and their format, see ShmPrefix
+func (m shmSegment) write(data []byte, width, height, channels int, t time.Time) error {
+	if len(data) > len(m)-shmHeaderSize {
+		return fmt.Errorf("Frame of %d bytes does not fit in %d bytes of shared memory", len(data), len(m)-shmHeaderSize)
+	}
+	atomic.AddUint64(m.seq(), 1)
+	binary.LittleEndian.PutUint32(m[shmOffWidth:], uint32(width))
+	binary.LittleEndian.PutUint32(m[shmOffHeight:], uint32(height))
+	binary.LittleEndian.PutUint32(m[shmOffChans:], uint32(channels))
+	binary.LittleEndian.PutUint64(m[shmOffTime:], uint64(t.UnixNano()))
+	copy(m[shmHeaderSize:], data)
+	atomic.AddUint64(m.seq(), 1)
+	return nil
+}
+
+// ShmSource reads frames from a shared memory segment written by another process, see ShmPrefix
+type ShmSource struct {
+	mem  shmSegment
+	name string
+	last uint64 // Sequence of the last frame read
+}
+
+// NewShmSource maps the segment of the input, which the writer should have created
+func NewShmSource(input string) (*ShmSource, error) {
+	path, err := shmFile(input)
+	if err != nil {
+		return nil, err
+	}
+	mem, err := mapShm(path, 0)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot open shared memory %s, start the writer first: %v", path, err)
+	}
+	if err := shmSegment(mem).check(); err != nil {
+		unmapShm(mem)
+		return nil, fmt.Errorf("Shared memory %s: %v", path, err)
+	}
+	return &ShmSource{mem: mem, name: path}, nil
+}
+
+// Next waits for a frame newer than the last one read and returns a copy of it, or io.EOF
+// once the writer is closed
+func (s *ShmSource) Next() (gocv.Mat, error) {
+	stalled := time.Now()
+	for {
+		seq := atomic.LoadUint64(s.mem.seq())
+		if seq == s.last || seq%2 == 1 {
+			if seq == s.last && atomic.LoadUint32(s.mem.closed()) != 0 {
+				return gocv.Mat{}, io.EOF
+			}
+			if time.Since(stalled) > shmStallTimeout {
+				return gocv.Mat{}, fmt.Errorf("No new frame in shared memory %s for %v", s.name, shmStallTimeout)
+			}
+			time.Sleep(shmPollInterval)
+			continue
+		}
+		img, err := s.read()
+		if err != nil {
+			return gocv.Mat{}, err
+		}
+		if atomic.LoadUint64(s.mem.seq()) != seq {
+			img.Close() // Overwritten while copying
+			continue
+		}
+		s.last = seq
+		return img, nil
+	}
+}
+
+// Copies the current frame, which may be torn if the writer overwrites it meanwhile
+func (s *ShmSource) read() (gocv.Mat, error) {
+	w, h, c := s.mem.u32(shmOffWidth), s.mem.u32(shmOffHeight), s.mem.u32(shmOffChans)
+	mt, err := shmMatType(c)
+	if err != nil {
+		return gocv.Mat{}, err
+	}
+	if n := w * h * c; w <= 0 || h <= 0 || n > s.mem.u32(shmOffCap) {
+		return gocv.Mat{}, fmt.Errorf("Invalid frame of %dx%dx%d in shared memory %s", w, h, c, s.name)
+	}
+	img := gocv.NewMatWithSize(h, w, mt)
+	dst, err := img.DataPtrUint8()
+	if err != nil {
+		img.Close()
+		return gocv.Mat{}, err
+	}
+	copy(dst, s.mem[shmHeaderSize:])
+	return img, nil
+}
+
+// Close unmaps the segment
+func (s *ShmSource) Close() error {
+	return unmapShm(s.mem)
+}
+
+// ShmSink writes frames to a shared memory segment read by other processes, see ShmPrefix.
+// The segment is created at the first frame, sized for it, and frames later should not be larger
+type ShmSink struct {
+	path string
+	mem  shmSegment
+}
+
+// NewShmSink creates a sink of the output, see ShmPrefix
+func NewShmSink(output string) (*ShmSink, error) {
+	path, err := shmFile(output)
+	if err != nil {
+		return nil, err
+	}
+	return &ShmSink{path: path}, nil
+}
+
+// Write copies the frame to the segment
+func (s *ShmSink) Write(img gocv.Mat) error {
+	if img.Empty() {
+		return nil
+	}
+	if _, err := shmMatType(img.Channels()); err != nil || img.Type()&7 != gocv.MatTypeCV8U {
+		return fmt.Errorf("Shared memory takes 8-bit frames with 1, 3 or 4 channels, got type %v", img.Type())
+	}
+	var data []byte
+	if img.IsContinuous() {
+		data, _ = img.DataPtrUint8()
+	} else {
+		data = img.ToBytes()
+	}
+	if s.mem == nil {
+		mem, err := mapShm(s.path, shmHeaderSize+len(data))
+		if err != nil {
+			return fmt.Errorf("Cannot create shared memory %s: %v", s.path, err)
+		}
+		s.mem = mem
+		s.mem.init()
+	}
+	return s.mem.write(data, img.Cols(), img.Rows(), img.Channels(), time.Now())
+}
+
+// Close marks the segment closed, so that readers end, and unmaps it. The file is left for readers
+// still opening it and replaced by the next writer
+func (s *ShmSink) Close() error {
+	if s.mem == nil {
+		return nil
+	}
+	atomic.StoreUint32(s.mem.closed(), 1)
+	err := unmapShm(s.mem)
+	s.mem = nil
+	return err
+}
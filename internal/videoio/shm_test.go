@@ -0,0 +1,76 @@
+package videoio
+
+import (
+	"io"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"gocv.io/x/gocv"
+)
+
+func TestShmFile(t *testing.T) {
+	if p, err := shmFile("shm:camera0"); err != nil || p != "/dev/shm/camera0" {
+		t.Errorf("shmFile = %q, %v", p, err)
+	}
+	if p, err := shmFile("shm:/tmp/frames"); err != nil || p != "/tmp/frames" {
+		t.Errorf("shmFile = %q, %v", p, err)
+	}
+	if _, err := shmFile("shm:"); err == nil {
+		t.Error("Expected an error for an empty name")
+	}
+	if IsVideoOutput("shm:camera0") || !IsShmPath("shm:camera0") {
+		t.Error("shm: outputs should not be video files")
+	}
+}
+
+func TestShmExchange(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("No shared memory on Windows")
+	}
+	output := ShmPrefix + filepath.Join(t.TempDir(), "frames")
+	sink, err := NewShmSink(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	img := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(10, 20, 30, 0), 2, 3, gocv.MatTypeCV8UC3)
+	defer img.Close()
+	if err := sink.Write(img); err != nil {
+		t.Fatal(err)
+	}
+	src, err := NewShmSource(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	got, err := src.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Rows() != 2 || got.Cols() != 3 || got.Type() != gocv.MatTypeCV8UC3 {
+		t.Errorf("Frame of %dx%d, type %v", got.Cols(), got.Rows(), got.Type())
+	}
+	if v := got.GetVecbAt(1, 2); v[0] != 10 || v[1] != 20 || v[2] != 30 {
+		t.Errorf("Pixel %v, want [10 20 30]", v)
+	}
+	got.Close()
+
+	// A frame read is not returned again, the reader ends when the writer is closed
+	sink.Close()
+	if _, err := src.Next(); err != io.EOF {
+		t.Errorf("Next after close = %v, want EOF", err)
+	}
+
+	large := gocv.NewMatWithSize(4, 4, gocv.MatTypeCV8UC3)
+	defer large.Close()
+	sink, _ = NewShmSink(output)
+	if err := sink.Write(img); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(large); err == nil {
+		t.Error("Expected an error for a frame larger than the segment")
+	}
+}
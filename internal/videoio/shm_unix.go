@@ -0,0 +1,42 @@
+//go:build !windows
+// +build !windows
+
+package videoio
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapShm maps the file shared. With a size, the file is created or resized to it,
+// otherwise the existing file is mapped whole
+func mapShm(path string, size int) ([]byte, error) {
+	flags := os.O_RDWR
+	if size > 0 {
+		flags |= os.O_CREATE
+	}
+	f, err := os.OpenFile(path, flags, 0666)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if size > 0 {
+		if err := f.Truncate(int64(size)); err != nil {
+			return nil, err
+		}
+	} else {
+		fi, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		size = int(fi.Size())
+		if size < shmHeaderSize {
+			return nil, syscall.EINVAL
+		}
+	}
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+}
+
+func unmapShm(mem []byte) error {
+	return syscall.Munmap(mem)
+}
@@ -0,0 +1,13 @@
+package videoio
+
+import "errors"
+
+var errNoShm = errors.New("Shared memory frames are not supported on Windows")
+
+func mapShm(path string, size int) ([]byte, error) {
+	return nil, errNoShm
+}
+
+func unmapShm(mem []byte) error {
+	return errNoShm
+}
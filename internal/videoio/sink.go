@@ -0,0 +1,294 @@
+package videoio
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gocv.io/x/gocv"
+)
+
+// ErrStopped is returned by a sink when the user asked to stop processing, e.g. pressed a key in the window
+var ErrStopped = errors.New("Stopped by user")
+
+// FrameSink consumes processed frames: shows, records or streams them
+type FrameSink interface {
+	Write(img gocv.Mat) error
+	Close() error
+}
+
+// WindowSink shows frames in a window. Without Controls any key stops processing
+type WindowSink struct {
+	Display  Display
+	Controls *Controls
+	resized  bool
+}
+
+// NewWindowSink creates a window with the given name. The window is resized to the size of the first frame
+// unless width and height are specified
+func NewWindowSink(name string, width, height int) *WindowSink {
+	window := gocv.NewWindow(name)
+	ws := &WindowSink{Display: window}
+	ws.resize(width, height)
+	return ws
+}
+
+// NewManagedWindowSink creates a window of the manager, which can be written from any goroutine.
+// The window is resized like by NewWindowSink
+func NewManagedWindowSink(m *WindowManager, name string, width, height int) *WindowSink {
+	ws := &WindowSink{Display: m.NewWindow(name)}
+	ws.resize(width, height)
+	return ws
+}
+
+func (ws *WindowSink) resize(width, height int) {
+	if width > 0 && height > 0 {
+		ws.Display.ResizeWindow(width, height)
+		ws.resized = true
+	}
+}
+
+// SetWindowTitle sets the title of the window
+func (ws *WindowSink) SetWindowTitle(title string) {
+	ws.Display.SetWindowTitle(title)
+}
+
+// CreateTrackbar adds a trackbar from 0 to max to the window
+func (ws *WindowSink) CreateTrackbar(name string, max int) Slider {
+	if w, ok := ws.Display.(*gocv.Window); ok {
+		return w.CreateTrackbar(name, max)
+	}
+	return ws.Display.(*ManagedWindow).CreateTrackbar(name, max)
+}
+
+// Write shows the frame and returns ErrStopped if the user stopped processing
+func (ws *WindowSink) Write(img gocv.Mat) error {
+	if !ws.resized {
+		ws.resize(img.Cols(), img.Rows())
+	}
+	if ws.Controls != nil {
+		return ws.Controls.Show(ws.Display, img)
+	}
+	ws.Display.IMShow(img)
+	if ws.Display.WaitKey(1) > 0 {
+		return ErrStopped
+	}
+	return nil
+}
+
+// Close finishes recording started with Controls and closes the window
+func (ws *WindowSink) Close() error {
+	if ws.Controls != nil {
+		ws.Controls.Close()
+	}
+	return ws.Display.Close()
+}
+
+// VideoSink records frames to a video file. The writer is started with the definition of the first frame
+type VideoSink struct {
+	path   string
+	codec  string
+	fps    float64
+	writer *gocv.VideoWriter
+}
+
+// NewVideoSink creates a sink writing to the video file with given codec and frame rate
+func NewVideoSink(path, codec string, fps float64) *VideoSink {
+	return &VideoSink{path: path, codec: codec, fps: fps}
+}
+
+// Write appends the frame to the video file
+func (vs *VideoSink) Write(img gocv.Mat) (err error) {
+	if vs.writer == nil {
+		vs.writer, err = gocv.VideoWriterFile(vs.path, vs.codec, vs.fps, img.Cols(), img.Rows(), true)
+		if err != nil {
+			return err
+		}
+	}
+	if !vs.writer.IsOpened() {
+		return fmt.Errorf("Cannot write to the file %s", vs.path)
+	}
+	return vs.writer.Write(img)
+}
+
+// Close finalizes the video file
+func (vs *VideoSink) Close() error {
+	if vs.writer == nil {
+		return nil
+	}
+	return vs.writer.Close()
+}
+
+// ImageSink saves each frame to a separate image file. The file name pattern should contain
+// a format verb for the frame number, e.g. "out/frame_%05d.jpg"
+type ImageSink struct {
+	pattern string
+	count   int
+}
+
+// NewImageSink creates a sink saving frames to files named by the pattern
+func NewImageSink(pattern string) (*ImageSink, error) {
+	if !strings.Contains(pattern, "%") {
+		return nil, fmt.Errorf("File name pattern should contain frame number format: %s", pattern)
+	}
+	if err := os.MkdirAll(filepath.Dir(pattern), 0755); err != nil {
+		return nil, err
+	}
+	return &ImageSink{pattern: pattern}, nil
+}
+
+// Write saves the frame to the next file
+func (is *ImageSink) Write(img gocv.Mat) error {
+	filename := fmt.Sprintf(is.pattern, is.count)
+	is.count++
+	if !gocv.IMWrite(filename, img) {
+		return fmt.Errorf("Cannot write image %s", filename)
+	}
+	return nil
+}
+
+// Close does nothing, all images are already saved
+func (is *ImageSink) Close() error {
+	return nil
+}
+
+// MJPEGSink streams frames over HTTP as a multipart JPEG stream, viewable in a browser
+type MJPEGSink struct {
+	server  *http.Server
+	mu      sync.Mutex
+	clients map[chan []byte]bool
+}
+
+const mjpegBoundary = "frame"
+
+// NewMJPEGSink starts an HTTP server on addr, e.g. ":8080", streaming frames to every client
+func NewMJPEGSink(addr string) (*MJPEGSink, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	ms := &MJPEGSink{clients: make(map[chan []byte]bool)}
+	ms.server = &http.Server{Handler: ms}
+	go ms.server.Serve(ln)
+	return ms, nil
+}
+
+// ServeHTTP sends frames to the client until it disconnects
+func (ms *MJPEGSink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+mjpegBoundary)
+	frames := make(chan []byte, 1)
+	ms.mu.Lock()
+	ms.clients[frames] = true
+	ms.mu.Unlock()
+	defer func() {
+		ms.mu.Lock()
+		delete(ms.clients, frames)
+		ms.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case jpg, ok := <-frames:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "--%s\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n", mjpegBoundary, len(jpg))
+			if _, err := w.Write(jpg); err != nil {
+				return
+			}
+			fmt.Fprint(w, "\r\n")
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+	}
+}
+
+// Write encodes the frame to JPEG and sends it to connected clients. Slow clients skip frames
+func (ms *MJPEGSink) Write(img gocv.Mat) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if len(ms.clients) == 0 {
+		return nil
+	}
+
+	buf, err := gocv.IMEncode(gocv.JPEGFileExt, img)
+	if err != nil {
+		return err
+	}
+	jpg := append([]byte(nil), buf.GetBytes()...)
+	buf.Close()
+
+	for c := range ms.clients {
+		select {
+		case c <- jpg:
+		default:
+		}
+	}
+	return nil
+}
+
+// Close stops the HTTP server
+func (ms *MJPEGSink) Close() error {
+	return ms.server.Close()
+}
+
+// MultiSink writes every frame to all of its sinks
+type MultiSink []FrameSink
+
+// Write passes the frame to every sink. ErrStopped from any sink takes precedence over other errors
+func (m MultiSink) Write(img gocv.Mat) error {
+	var firstErr error
+	for _, s := range m {
+		err := s.Write(img)
+		if err == ErrStopped || (err != nil && firstErr == nil) {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Close closes all sinks and returns the first error
+func (m MultiSink) Close() error {
+	var firstErr error
+	for _, s := range m {
+		if err := s.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// OpenSink creates a sink depending on the output: an address like ":8080" starts an MJPEG stream,
+// an RTMP URL or HLS playlist is published with ffmpeg (see FFmpegSink), "shm:NAME" shares raw frames
+// with other processes (see ShmPrefix), "webrtc:ADDR" serves a WebRTC viewer (see WebRTCPrefix),
+// a pattern with a format verb saves image files, anything else is a video file written with given codec
+// and frame rate
+func OpenSink(output, codec string, fps float64) (FrameSink, error) {
+	if output == "" {
+		return nil, errors.New("No output specified")
+	}
+	if IsVideoOutput(output) {
+		return NewVideoSink(output, codec, fps), nil
+	}
+	if strings.HasPrefix(output, ":") {
+		return NewMJPEGSink(output)
+	}
+	if IsStreamOutput(output) {
+		return NewFFmpegSink(output, fps)
+	}
+	if IsShmPath(output) {
+		return NewShmSink(output)
+	}
+	if IsWebRTCOutput(output) {
+		return NewWebRTCSink(output, fps)
+	}
+	return NewImageSink(output)
+}
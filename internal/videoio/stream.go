@@ -0,0 +1,228 @@
+package videoio
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"sync"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"gocv.io/x/gocv"
+)
+
+// Default stream settings
+const (
+	DefaultReconnect   = 2 * time.Second
+	DefaultReadTimeout = 10 * time.Second
+	maxReconnect       = 30 * time.Second // Longest delay between reconnection attempts
+)
+
+// StreamSettings holds options of reading network streams, e.g. of IP cameras: a stream which drops
+// or stops sending frames for the read timeout is reopened, retrying with growing delays from the
+// reconnect delay up to half a minute. A stride skips frames of any input
+type StreamSettings struct {
+	Reconnect time.Duration // First delay before reopening a dropped stream, no reconnection if 0
+	Timeout   time.Duration // Longest wait for a frame of a stream
+	Stride    int           // Every Stride-th frame is processed
+
+	open func(url string) (FrameSource, error)
+}
+
+// RegisterFlags adds -reconnect, -read-timeout and -stride flags to fs
+func (ss *StreamSettings) RegisterFlags(fs *flag.FlagSet) {
+	fs.DurationVar(&ss.Reconnect, "reconnect", DefaultReconnect, "Delay before reopening a stream URL that dropped, "+
+		"growing up to 30s while it fails, 0 to stop at the first dropout")
+	fs.DurationVar(&ss.Timeout, "read-timeout", DefaultReadTimeout, "Time without frames after which a stream URL "+
+		"counts as dropped")
+	fs.IntVar(&ss.Stride, "stride", 1, "Process every Nth frame of the input, skipping the others")
+}
+
+// Validate checks the settings
+func (ss *StreamSettings) Validate() error {
+	if ss.Reconnect < 0 || ss.Timeout < 0 {
+		return errors.New("-reconnect and -read-timeout cannot be negative")
+	}
+	if ss.Stride < 1 {
+		return errors.New("-stride should be at least 1")
+	}
+	return nil
+}
+
+// Wrap returns the source of the input reconnecting if it is a stream URL and skipping frames by the
+// stride, or src itself. Reconnection stops when ctx is cancelled. Closing the returned source closes
+// the current connection
+func (ss *StreamSettings) Wrap(ctx context.Context, input string, src FrameSource) FrameSource {
+	if IsStreamURL(input) && ss.Reconnect > 0 {
+		open := ss.open
+		if open == nil {
+			open = NewStreamSource
+		}
+		src = &reconnectingSource{ctx: ctx, url: input, open: open, src: src, delay: ss.Reconnect,
+			timeout: ss.Timeout, closed: make(chan struct{})}
+	}
+	if ss.Stride > 1 {
+		src = &strideSource{FrameSource: src, stride: ss.Stride}
+	}
+	return src
+}
+
+// reconnectingSource reads frames from a stream, reopening it when reading fails or times out
+type reconnectingSource struct {
+	ctx     context.Context
+	url     string
+	open    func(url string) (FrameSource, error)
+	delay   time.Duration
+	timeout time.Duration
+	closed  chan struct{}
+
+	mu      sync.Mutex
+	src     FrameSource // Current connection, nil while reconnecting
+	reading bool        // Whether a read of src is running, which then closes src if it is dropped
+}
+
+var errDropped = errors.New("Stream dropped")
+
+type readResult struct {
+	img gocv.Mat
+	err error
+}
+
+func (rs *reconnectingSource) Next() (gocv.Mat, error) {
+	for {
+		rs.mu.Lock()
+		src := rs.src
+		rs.reading = src != nil
+		rs.mu.Unlock()
+		if src == nil {
+			if err := rs.reconnect(); err != nil {
+				return gocv.Mat{}, err
+			}
+			continue
+		}
+		img, err := rs.read(src)
+		if err != errDropped {
+			return img, err
+		}
+	}
+}
+
+// Reads a frame in the background, since a stalled stream may block reading for long. If reading
+// fails, times out or the source is closed meanwhile, the connection is dropped and closed once the
+// read returns
+func (rs *reconnectingSource) read(src FrameSource) (gocv.Mat, error) {
+	res := make(chan readResult, 1)
+	go func() {
+		img, err := src.Next()
+		res <- readResult{img, err}
+	}()
+	var timeout <-chan time.Time
+	if rs.timeout > 0 {
+		t := time.NewTimer(rs.timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+	var err error
+	select {
+	case r := <-res:
+		if r.err == nil {
+			rs.mu.Lock()
+			defer rs.mu.Unlock()
+			rs.reading = false
+			select {
+			case <-rs.closed:
+				r.img.Close()
+				src.Close()
+				return gocv.Mat{}, ErrStopped
+			default:
+			}
+			return r.img, nil
+		}
+		logging.Warnf("Stream %s dropped: %v", rs.url, r.err)
+		res <- r // Lets the connection be closed below
+		err = errDropped
+	case <-timeout:
+		logging.Warnf("No frame from %s for %v", rs.url, rs.timeout)
+		err = errDropped
+	case <-rs.closed:
+		err = ErrStopped
+	}
+
+	rs.mu.Lock()
+	rs.src, rs.reading = nil, false
+	rs.mu.Unlock()
+	go func() {
+		if r := <-res; r.err == nil {
+			r.img.Close()
+		}
+		src.Close()
+	}()
+	return gocv.Mat{}, err
+}
+
+// Reopens the stream, retrying with doubling delays until it succeeds, ctx is cancelled or the source
+// is closed
+func (rs *reconnectingSource) reconnect() error {
+	for delay := rs.delay; ; delay *= 2 {
+		if delay > maxReconnect {
+			delay = maxReconnect
+		}
+		logging.Infof("Reconnecting to %s in %v", rs.url, delay)
+		select {
+		case <-time.After(delay):
+		case <-rs.ctx.Done():
+			return ErrStopped
+		case <-rs.closed:
+			return ErrStopped
+		}
+		src, err := rs.open(rs.url)
+		if err != nil {
+			logging.Warnf("Cannot reconnect to %s: %v", rs.url, err)
+			continue
+		}
+		rs.mu.Lock()
+		defer rs.mu.Unlock()
+		select {
+		case <-rs.closed:
+			src.Close()
+			return ErrStopped
+		default:
+		}
+		logging.Infof("Reconnected to %s", rs.url)
+		rs.src = src
+		return nil
+	}
+}
+
+// Close stops reconnecting and closes the current connection. It may be called while Next is waiting
+func (rs *reconnectingSource) Close() error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	select {
+	case <-rs.closed:
+		return nil
+	default:
+	}
+	close(rs.closed)
+	if rs.src != nil && !rs.reading {
+		return rs.src.Close()
+	}
+	return nil
+}
+
+// strideSource returns every stride-th frame of the source, closing the others
+type strideSource struct {
+	FrameSource
+	stride int
+}
+
+func (ss *strideSource) Next() (gocv.Mat, error) {
+	for i := 1; i < ss.stride; i++ {
+		img, err := ss.FrameSource.Next()
+		if err != nil {
+			return img, err
+		}
+		img.Close()
+	}
+	return ss.FrameSource.Next()
+}
@@ -0,0 +1,121 @@
+package videoio
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// fakeStream returns frames of size 1xN for N = 1, 2, ... up to its length, then fails or stalls
+type fakeStream struct {
+	frames int
+	stall  chan struct{} // Reading blocks until closed if set
+	n      int
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (fs *fakeStream) Next() (gocv.Mat, error) {
+	if fs.n == fs.frames {
+		if fs.stall != nil {
+			<-fs.stall
+		}
+		return gocv.Mat{}, errors.New("Cannot read frame")
+	}
+	fs.n++
+	return gocv.NewMatWithSize(1, fs.n, gocv.MatTypeCV8UC3), nil
+}
+
+func (fs *fakeStream) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.closed = true
+	return nil
+}
+
+func (fs *fakeStream) isClosed() bool {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.closed
+}
+
+func readCols(t *testing.T, src FrameSource, n int) []int {
+	t.Helper()
+	var cols []int
+	for i := 0; i < n; i++ {
+		img, err := src.Next()
+		if err != nil {
+			t.Fatalf("Frame %d: %v", i, err)
+		}
+		cols = append(cols, img.Cols())
+		img.Close()
+	}
+	return cols
+}
+
+func TestReconnectingSource(t *testing.T) {
+	first := &fakeStream{frames: 2}
+	stalled := &fakeStream{frames: 1, stall: make(chan struct{})}
+	defer close(stalled.stall)
+	var opened []*fakeStream
+	ss := StreamSettings{Reconnect: time.Millisecond, Timeout: 50 * time.Millisecond, Stride: 1,
+		open: func(url string) (FrameSource, error) {
+			// The first attempt fails, then a stream stalling after one frame and a working one open
+			if len(opened) == 0 {
+				opened = append(opened, nil)
+				return nil, errors.New("Cannot open stream")
+			}
+			next := &fakeStream{frames: 3}
+			if len(opened) == 1 {
+				next = stalled
+			}
+			opened = append(opened, next)
+			return next, nil
+		}}
+	src := ss.Wrap(context.Background(), "rtsp://camera/stream", first)
+
+	got := readCols(t, src, 6)
+	want := []int{1, 2, 1, 1, 2, 3}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Got frames %v, want %v", got, want)
+		}
+	}
+	if !first.isClosed() {
+		t.Error("Dropped stream not closed")
+	}
+	if stalled.isClosed() {
+		t.Error("Stalled stream closed while reading")
+	}
+	src.Close()
+	if !opened[2].isClosed() {
+		t.Error("Current stream not closed")
+	}
+}
+
+func TestReconnectingSourceStops(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ss := StreamSettings{Reconnect: time.Hour, Stride: 1}
+	src := ss.Wrap(ctx, "rtsp://camera/stream", &fakeStream{})
+	time.AfterFunc(10*time.Millisecond, cancel)
+	if _, err := src.Next(); err != ErrStopped {
+		t.Errorf("Got %v after cancelling, want ErrStopped", err)
+	}
+	src.Close()
+}
+
+func TestStrideSource(t *testing.T) {
+	ss := StreamSettings{Stride: 3}
+	src := ss.Wrap(context.Background(), "video.mp4", &fakeStream{frames: 7})
+	if got := readCols(t, src, 2); got[0] != 3 || got[1] != 6 {
+		t.Errorf("Got frames %v, want every third", got)
+	}
+	if _, err := src.Next(); err == nil {
+		t.Errorf("Got %v at the end of the source, want its error", err)
+	}
+}
@@ -0,0 +1,45 @@
+// Package videoio contains video capture, playback and writing helpers shared by the examples.
+package videoio
+
+import (
+	"context"
+	"io"
+
+	"gocv.io/x/gocv"
+)
+
+// Run reads frames from the source, passes each one to process, which may annotate the frame
+// in place, and writes the result to the sink.
+// The loop stops when the source has no more frames, returning nil, or when the source or the sink
+// fails. ErrStopped is returned if the user stopped processing or the context is cancelled
+func Run(ctx context.Context, src FrameSource, sink FrameSink, process func(img *gocv.Mat)) error {
+	for {
+		if ctx.Err() != nil {
+			return ErrStopped
+		}
+
+		img, err := src.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		process(&img)
+		err = sink.Write(img)
+		img.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// WaitForKey blocks until any key is pressed in the window or the context is cancelled
+func WaitForKey(ctx context.Context, window Display) {
+	for ctx.Err() == nil {
+		if window.WaitKey(1) > 0 {
+			return
+		}
+	}
+}
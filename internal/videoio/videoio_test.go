@@ -0,0 +1,308 @@
+package videoio
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/testutil"
+	"gocv.io/x/gocv"
+)
+
+func TestImageSource(t *testing.T) {
+	src, err := OpenSource(testutil.Path("*.png"), 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	// Images are read in alphabetical order
+	var widths []int
+	for {
+		img, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		widths = append(widths, img.Cols())
+		img.Close()
+	}
+	if len(widths) != 3 || widths[0] != 240 || widths[1] != 80 || widths[2] != 256 {
+		t.Errorf("Expected card, checkerboard and ramp, got widths %v", widths)
+	}
+}
+
+func TestRunWritesAllFrames(t *testing.T) {
+	src, err := NewImageSource(testutil.Path("*.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	pattern := filepath.Join(t.TempDir(), "frame_%d.png")
+	sink, err := NewImageSink(pattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := 0
+	if err := Run(context.Background(), src, sink, func(img *gocv.Mat) { n++ }); err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("Expected 3 processed frames, got %d", n)
+	}
+	want := testutil.LoadImage(t, "ramp.png")
+	got := gocv.IMRead(filepath.Join(filepath.Dir(pattern), "frame_2.png"), gocv.IMReadColor)
+	defer got.Close()
+	testutil.AssertNear(t, got, want, 0, 0)
+}
+
+func TestEditorFade(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fade.avi")
+	vw, err := gocv.VideoWriterFile(path, "MJPG", 10, 64, 48, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	black := testutil.SolidImage(t, 64, 48, gocv.NewScalar(0, 0, 0, 0))
+	white := testutil.SolidImage(t, 64, 48, gocv.NewScalar(255, 255, 255, 0))
+
+	ed := NewEditor(context.Background(), vw, 10)
+	defer ed.Close()
+	if err := ed.FadeImageInto(&black, &white, 1); err != nil {
+		t.Fatal(err)
+	}
+	vw.Close()
+	// The last frame outlives the fade, which closes its own Mats
+	if ed.LastFrame == nil || ed.LastFrame.GetUCharAt(0, 0) != 255 {
+		t.Error("Last frame of the fade is not kept")
+	}
+
+	// Fade of 1 second at 10 FPS goes from black to white in 11 frames
+	vr, err := gocv.OpenVideoCapture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer vr.Close()
+	var frames []gocv.Mat
+	for {
+		img := gocv.NewMat()
+		if !vr.Read(&img) || img.Empty() {
+			img.Close()
+			break
+		}
+		defer img.Close()
+		frames = append(frames, img)
+	}
+	if len(frames) != 11 {
+		t.Fatalf("Expected 11 frames, got %d", len(frames))
+	}
+	// MJPG is lossy, so compare with a tolerance
+	testutil.AssertNear(t, frames[0], black, 8, 2)
+	testutil.AssertNear(t, frames[len(frames)-1], white, 8, 2)
+}
+
+func TestEditorCopy(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.avi")
+	vw, err := gocv.VideoWriterFile(in, "MJPG", 10, 64, 48, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		img := testutil.SolidImage(t, 64, 48, gocv.NewScalar(float64(50*i), 0, 0, 0))
+		vw.Write(img)
+	}
+	vw.Close()
+
+	count := func(path string) int {
+		vr, err := gocv.OpenVideoCapture(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer vr.Close()
+		return int(vr.Get(gocv.VideoCaptureFrameCount))
+	}
+	for _, tt := range []struct {
+		delay float64
+		want  int
+	}{{0.3, 3}, {2, 5}} {
+		out := filepath.Join(dir, fmt.Sprintf("out_%v.avi", tt.delay))
+		ow, err := gocv.VideoWriterFile(out, "MJPG", 10, 64, 48, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		vr, err := gocv.OpenVideoCapture(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ed := NewEditor(context.Background(), ow, 10)
+		err = ed.Copy(vr, tt.delay)
+		ed.Close()
+		vr.Close()
+		ow.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := count(out); got != tt.want {
+			t.Errorf("Copy of %vs: %d frames, want %d", tt.delay, got, tt.want)
+		}
+	}
+}
+
+func TestEditorTransition(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transition.avi")
+	vw, err := gocv.VideoWriterFile(path, "MJPG", 10, 64, 48, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var steps []float64
+	ed := NewEditor(context.Background(), vw, 10)
+	defer ed.Close()
+	err = ed.Transition(0.5, func(step float64, img *gocv.Mat) {
+		steps = append(steps, step)
+		solid := testutil.SolidImage(t, 64, 48, gocv.NewScalar(255*step, 0, 0, 0))
+		solid.CopyTo(img)
+	})
+	vw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Half a second at 10 FPS renders 6 frames from 0 to 1
+	if len(steps) != 6 || steps[0] != 0 || steps[5] != 1 {
+		t.Errorf("Expected 6 steps from 0 to 1, got %v", steps)
+	}
+}
+
+// Renders a one second transition between two 720p frames per iteration
+func BenchmarkEditorFade(b *testing.B) {
+	vw, err := gocv.VideoWriterFile(filepath.Join(b.TempDir(), "fade.avi"), "MJPG", 30, 1280, 720, true)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer vw.Close()
+	img1 := testutil.SolidImage(b, 1280, 720, gocv.NewScalar(127, 0, 0, 0))
+	img2 := testutil.SolidImage(b, 1280, 720, gocv.NewScalar(255, 255, 255, 0))
+	ed := NewEditor(context.Background(), vw, 30)
+	defer ed.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := ed.FadeImageInto(&img1, &img2, 1); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestControlsKeys(t *testing.T) {
+	c := NewControls(context.Background(), "MJPG", 25)
+	thr := 5
+	c.Threshold = func(step int) string {
+		thr += step
+		return fmt.Sprint(thr)
+	}
+	img := testutil.SolidImage(t, 32, 24, gocv.NewScalar(0, 0, 0, 0))
+
+	for _, key := range []int{KeyUp, KeyUp, KeyDown, KeyPause, KeyHelp} {
+		if err := c.handleKey(key, img); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if thr != 6 || !c.paused || !c.help {
+		t.Errorf("Unexpected state: threshold %d, paused %v, help %v", thr, c.paused, c.help)
+	}
+	if err := c.handleKey(KeyEsc, img); err != ErrStopped {
+		t.Errorf("Expected ErrStopped on Esc, got %v", err)
+	}
+}
+
+// Sink remembering the first pixel of each written frame
+type pixelSink []uint8
+
+func (s *pixelSink) Write(img gocv.Mat) error {
+	*s = append(*s, img.GetUCharAt(0, 0))
+	return nil
+}
+
+func (s *pixelSink) Close() error { return nil }
+
+func TestFrameBuffer(t *testing.T) {
+	b := NewFrameBuffer(3)
+	defer b.Close()
+	for v := 1; v <= 5; v++ {
+		img := testutil.SolidImage(t, 4, 4, gocv.NewScalar(float64(v), 0, 0, 0))
+		b.Push(img)
+	}
+	if b.Len() != 3 {
+		t.Fatalf("Expected 3 buffered frames, got %d", b.Len())
+	}
+	var sink pixelSink
+	if err := b.Drain(&sink); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink) != 3 || sink[0] != 3 || sink[2] != 5 || b.Len() != 0 {
+		t.Errorf("Expected frames 3, 4, 5 and an empty buffer, got %v and %d frames", sink, b.Len())
+	}
+}
+
+func TestPreRollBuffer(t *testing.T) {
+	b := NewPreRollBuffer(50*time.Millisecond, 100)
+	defer b.Close()
+	if len(b.frames) != 5 {
+		t.Fatalf("Expected room for 5 frames, got %d", len(b.frames))
+	}
+	for v := 1; v <= 3; v++ {
+		b.Push(testutil.SolidImage(t, 4, 4, gocv.NewScalar(float64(v), 0, 0, 0)))
+	}
+	time.Sleep(60 * time.Millisecond)
+	b.Push(testutil.SolidImage(t, 4, 4, gocv.NewScalar(4, 0, 0, 0)))
+
+	// Frames older than the pre-roll are not written
+	var sink pixelSink
+	if err := b.Drain(&sink); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink) != 1 || sink[0] != 4 {
+		t.Errorf("Expected the last frame only, got %v", sink)
+	}
+}
+
+func TestWindowManager(t *testing.T) {
+	m := NewWindowManager()
+	var calls []int
+	for i := 0; i < 3; i++ {
+		m.Do(func() { calls = append(calls, i) })
+	}
+	if len(calls) != 3 || calls[2] != 2 {
+		t.Errorf("Expected calls 0, 1, 2, got %v", calls)
+	}
+
+	// Only the latest queued frame is shown
+	w := &ManagedWindow{m: m, frames: make(chan gocv.Mat, 1)}
+	for v := 1; v <= 2; v++ {
+		w.IMShow(testutil.SolidImage(t, 4, 4, gocv.NewScalar(float64(v), 0, 0, 0)))
+	}
+	img := <-w.frames
+	if img.GetUCharAt(0, 0) != 2 {
+		t.Errorf("Expected the second frame, got %d", img.GetUCharAt(0, 0))
+	}
+	img.Close()
+
+	m.keys <- KeyQuit
+	if key := w.WaitKey(1); key != KeyQuit {
+		t.Errorf("WaitKey = %d, want %d", key, KeyQuit)
+	}
+	if key := w.WaitKey(1); key != -1 {
+		t.Errorf("WaitKey = %d without a key, want -1", key)
+	}
+
+	m.Close()
+	m.Do(func() { t.Error("Call run after Close") })
+	if key := w.WaitKey(0); key != -1 {
+		t.Errorf("WaitKey = %d after Close, want -1", key)
+	}
+}
@@ -0,0 +1,269 @@
+//go:build webrtc
+// +build webrtc
+
+package videoio
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/h264reader"
+	"gocv.io/x/gocv"
+)
+
+//go:embed webrtc.html
+var webrtcPage []byte
+
+// STUN server used to find public addresses, as in the viewer page
+var webrtcICEServers = []webrtc.ICEServer{{URLs: []string{"stun:stun.l.google.com:19302"}}}
+
+// Largest SDP offer accepted
+const maxOfferSize = 64 << 10
+
+// WebRTCSink serves frames over WebRTC with github.com/pion/webrtc, see WebRTCPrefix.
+// The address serves a viewer page, which posts its SDP offer to /offer and plays the stream.
+// Frames are encoded by an ffmpeg process started with the definition of the first frame, and
+// all viewers share the encoded track. Without viewers, frames are still encoded, so that a viewer
+// joining gets the stream from the next keyframe
+type WebRTCSink struct {
+	fps    float64
+	server *http.Server
+	track  *webrtc.TrackLocalStaticSample
+
+	mu    sync.Mutex
+	peers map[*webrtc.PeerConnection]bool
+
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	log    *tailWriter
+	done   chan struct{} // Closed when the encoded stream ends
+	width  int
+	height int
+}
+
+// NewWebRTCSink starts serving the viewer page of the output at the frame rate
+func NewWebRTCSink(output string, fps float64) (*WebRTCSink, error) {
+	addr, err := webrtcAddr(output)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return nil, errors.New("ffmpeg command not found, install FFmpeg, e.g. 'apt install ffmpeg'")
+	}
+	if fps <= 0 {
+		return nil, fmt.Errorf("Frame rate should be positive: %g", fps)
+	}
+	track, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264},
+		"video", "gocv-examples")
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	ws := &WebRTCSink{fps: fps, track: track, peers: make(map[*webrtc.PeerConnection]bool)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", ws.servePage)
+	mux.HandleFunc("/offer", ws.serveOffer)
+	ws.server = &http.Server{Handler: mux}
+	go ws.server.Serve(ln)
+	logging.Infof("Serving WebRTC viewer on http://%s/", ln.Addr())
+	return ws, nil
+}
+
+func (ws *WebRTCSink) servePage(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(webrtcPage)
+}
+
+// serveOffer answers the SDP offer of a viewer with a connection receiving the track
+func (ws *WebRTCSink) serveOffer(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST an SDP offer", http.StatusMethodNotAllowed)
+		return
+	}
+	offer, err := ioutil.ReadAll(io.LimitReader(r.Body, maxOfferSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	answer, err := ws.connect(string(offer))
+	if err != nil {
+		logging.Warnf("WebRTC viewer %s: %v", r.RemoteAddr, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/sdp")
+	io.WriteString(w, answer)
+}
+
+// connect creates a peer connection for the offer and returns its answer with all ICE candidates
+func (ws *WebRTCSink) connect(offer string) (string, error) {
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{ICEServers: webrtcICEServers})
+	if err != nil {
+		return "", err
+	}
+	sender, err := pc.AddTrack(ws.track)
+	if err != nil {
+		pc.Close()
+		return "", err
+	}
+	// RTCP packets should be read for interceptors like NACK to work
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			if _, _, err := sender.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		logging.Debugf("WebRTC viewer %s", state)
+		if state == webrtc.PeerConnectionStateFailed || state == webrtc.PeerConnectionStateClosed {
+			ws.remove(pc)
+		}
+	})
+
+	if err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offer}); err != nil {
+		pc.Close()
+		return "", err
+	}
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		pc.Close()
+		return "", err
+	}
+	gathered := webrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(answer); err != nil {
+		pc.Close()
+		return "", err
+	}
+	<-gathered
+
+	ws.mu.Lock()
+	ws.peers[pc] = true
+	n := len(ws.peers)
+	ws.mu.Unlock()
+	logging.Infof("WebRTC viewer connected, %d viewers", n)
+	return pc.LocalDescription().SDP, nil
+}
+
+// Closes the connection of a viewer that left
+func (ws *WebRTCSink) remove(pc *webrtc.PeerConnection) {
+	ws.mu.Lock()
+	_, ok := ws.peers[pc]
+	delete(ws.peers, pc)
+	ws.mu.Unlock()
+	if ok {
+		pc.Close()
+	}
+}
+
+// Starts ffmpeg encoding frames of the size and sending them to the track
+func (ws *WebRTCSink) start(width, height int) error {
+	cmd := exec.Command("ffmpeg", webrtcFFmpegArgs(width, height, ws.fps)...)
+	ws.log = &tailWriter{max: ffmpegLogLines}
+	cmd.Stderr = ws.log
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("Cannot start ffmpeg: %v", err)
+	}
+	ws.cmd, ws.stdin, ws.width, ws.height = cmd, stdin, width, height
+	ws.done = make(chan struct{})
+	go ws.send(stdout)
+	return nil
+}
+
+// send writes each encoded frame to the track: the NAL units up to its slice, with the parameter
+// sets before keyframes, timed by the time between frames
+func (ws *WebRTCSink) send(stdout io.Reader) {
+	defer close(ws.done)
+	reader, err := h264reader.NewReader(stdout)
+	if err != nil {
+		logging.Errorf("WebRTC stream: %v", err)
+		return
+	}
+	var frame []byte
+	last := time.Now().Add(-time.Duration(float64(time.Second) / ws.fps))
+	for {
+		nal, err := reader.NextNAL()
+		if err != nil {
+			if err != io.EOF {
+				logging.Errorf("WebRTC stream: %v", err)
+			}
+			return
+		}
+		frame = append(frame, 0, 0, 0, 1)
+		frame = append(frame, nal.Data...)
+		if nal.UnitType != h264reader.NalUnitTypeCodedSliceNonIdr && nal.UnitType != h264reader.NalUnitTypeCodedSliceIdr {
+			continue
+		}
+		now := time.Now()
+		if err := ws.track.WriteSample(media.Sample{Data: frame, Duration: now.Sub(last)}); err != nil {
+			logging.Errorf("WebRTC stream: %v", err)
+		}
+		frame, last = nil, now
+	}
+}
+
+// Write encodes the frame for the viewers. All frames should have the definition of the first one
+func (ws *WebRTCSink) Write(img gocv.Mat) error {
+	if ws.cmd == nil {
+		if err := ws.start(img.Cols(), img.Rows()); err != nil {
+			return err
+		}
+	}
+	if img.Cols() != ws.width || img.Rows() != ws.height {
+		return fmt.Errorf("Frame %dx%d differs from the stream %dx%d", img.Cols(), img.Rows(), ws.width, ws.height)
+	}
+	if img.Type() != gocv.MatTypeCV8UC3 {
+		return errors.New("Stream frames should be BGR")
+	}
+	if _, err := ws.stdin.Write(img.ToBytes()); err != nil {
+		return fmt.Errorf("Error encoding WebRTC stream: %v: %s", err, ws.log)
+	}
+	return nil
+}
+
+// Close stops the server, the encoder and the connections of all viewers
+func (ws *WebRTCSink) Close() error {
+	err := ws.server.Close()
+	if ws.cmd != nil {
+		ws.stdin.Close()
+		<-ws.done // Reading ends before Wait closes the pipe
+		if werr := ws.cmd.Wait(); werr != nil && err == nil {
+			err = fmt.Errorf("ffmpeg: %v: %s", werr, ws.log)
+		}
+	}
+	ws.mu.Lock()
+	peers := ws.peers
+	ws.peers = map[*webrtc.PeerConnection]bool{}
+	ws.mu.Unlock()
+	for pc := range peers {
+		pc.Close()
+	}
+	return err
+}
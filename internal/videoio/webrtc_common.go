@@ -0,0 +1,42 @@
+package videoio
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// WebRTCPrefix starts an output serving frames over WebRTC, followed by the address of the viewer page,
+// e.g. "webrtc::8081". Frames are encoded to H.264 by ffmpeg and sent to each browser opening the page,
+// with much lower latency and bandwidth than MJPEG. This output needs a binary built with -tags webrtc,
+// see WebRTCSink
+const WebRTCPrefix = "webrtc:"
+
+// Keyframe interval of WebRTC streams in seconds; viewers see the first frame at the next keyframe
+const webrtcKeyframeInterval = 1
+
+// IsWebRTCOutput reports whether the output is served over WebRTC, see WebRTCPrefix
+func IsWebRTCOutput(output string) bool {
+	return strings.HasPrefix(output, WebRTCPrefix)
+}
+
+// webrtcAddr returns the address of the viewer page of the output
+func webrtcAddr(output string) (string, error) {
+	addr := strings.TrimPrefix(output, WebRTCPrefix)
+	if addr == "" {
+		return "", fmt.Errorf("WebRTC output needs an address, e.g. %s:8081", WebRTCPrefix)
+	}
+	return addr, nil
+}
+
+// webrtcFFmpegArgs returns the arguments of ffmpeg reading BGR frames of the size from stdin and writing
+// H.264 in Annex B format to stdout. Browsers decode the constrained baseline profile, without B-frames,
+// and one slice per frame lets each frame be sent as one sample
+func webrtcFFmpegArgs(width, height int, fps float64) []string {
+	rate := strconv.FormatFloat(fps, 'g', -1, 64)
+	gop := strconv.Itoa(int(webrtcKeyframeInterval*fps + 0.5))
+	return []string{"-hide_banner", "-loglevel", "error",
+		"-f", "rawvideo", "-pix_fmt", "bgr24", "-s", fmt.Sprintf("%dx%d", width, height), "-r", rate, "-i", "-",
+		"-c:v", "libx264", "-preset", "ultrafast", "-tune", "zerolatency", "-x264-params", "sliced-threads=0",
+		"-profile:v", "baseline", "-pix_fmt", "yuv420p", "-g", gop, "-bsf:v", "h264_mp4toannexb", "-f", "h264", "-"}
+}
@@ -0,0 +1,30 @@
+//go:build !webrtc
+// +build !webrtc
+
+package videoio
+
+import (
+	"errors"
+
+	"gocv.io/x/gocv"
+)
+
+var errNoWebRTC = errors.New("Built without WebRTC, build with -tags webrtc")
+
+// WebRTCSink needs github.com/pion/webrtc, which is only linked with the webrtc tag
+type WebRTCSink struct{}
+
+// NewWebRTCSink cannot serve WebRTC without the webrtc tag
+func NewWebRTCSink(output string, fps float64) (*WebRTCSink, error) {
+	return nil, errNoWebRTC
+}
+
+// Write returns an error, see NewWebRTCSink
+func (ws *WebRTCSink) Write(img gocv.Mat) error {
+	return errNoWebRTC
+}
+
+// Close does nothing
+func (ws *WebRTCSink) Close() error {
+	return nil
+}
@@ -0,0 +1,25 @@
+package videoio
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWebRTCOutput(t *testing.T) {
+	if !IsWebRTCOutput("webrtc::8081") || IsVideoOutput("webrtc::8081") || IsWebRTCOutput(":8081") {
+		t.Error("Unexpected WebRTC output detection")
+	}
+	if addr, err := webrtcAddr("webrtc:0.0.0.0:8081"); err != nil || addr != "0.0.0.0:8081" {
+		t.Errorf("webrtcAddr = %q, %v", addr, err)
+	}
+	if _, err := webrtcAddr("webrtc:"); err == nil {
+		t.Error("Expected an error without an address")
+	}
+
+	args := strings.Join(webrtcFFmpegArgs(640, 480, 25), " ")
+	for _, want := range []string{"-s 640x480 -r 25 -i -", "-profile:v baseline", "-g 25", "-f h264 -"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("Arguments %q should contain %q", args, want)
+		}
+	}
+}
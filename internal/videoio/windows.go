@@ -0,0 +1,319 @@
+package videoio
+
+import (
+	"image"
+	"runtime"
+	"sync"
+	"time"
+
+	"gocv.io/x/gocv"
+)
+
+// How long the GUI thread waits for events between handling calls
+const guiPoll = 10 * time.Millisecond
+
+// Display shows frames and reads pressed keys: a *gocv.Window used on the goroutine that created it,
+// or a ManagedWindow used on any goroutine
+type Display interface {
+	IMShow(img gocv.Mat)
+	WaitKey(delay int) int
+	ResizeWindow(width, height int)
+	SetWindowTitle(title string)
+	Close() error
+}
+
+// Slider is a trackbar of a Display: a *gocv.Trackbar, or a *Trackbar of a ManagedWindow
+type Slider interface {
+	GetPos() int
+	SetPos(pos int)
+}
+
+// WindowManager makes all HighGUI calls on one goroutine locked to its OS thread, as the GUI backends
+// require, so that windows can be used from the goroutines of a pipeline or of several streams.
+// Frames to show are queued to the GUI thread, pressed keys, trackbar positions and selected regions
+// come back on channels. This version of gocv has no mouse callbacks, so the mouse is only used to
+// select regions
+type WindowManager struct {
+	calls chan func()
+	keys  chan int
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+
+	// Only used on the GUI thread
+	windows []*ManagedWindow
+}
+
+// NewWindowManager starts the GUI thread of a manager, which should be closed after use
+func NewWindowManager() *WindowManager {
+	m := &WindowManager{calls: make(chan func()), keys: make(chan int, 16), stop: make(chan struct{}),
+		done: make(chan struct{})}
+	go m.run()
+	return m
+}
+
+// Handles calls and, while there are windows, shows queued frames and polls keys and trackbars
+func (m *WindowManager) run() {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	defer close(m.done)
+	for {
+		if len(m.windows) == 0 {
+			select {
+			case f := <-m.calls:
+				f()
+			case <-m.stop:
+				return
+			}
+			continue
+		}
+	calls:
+		for {
+			select {
+			case f := <-m.calls:
+				f()
+			case <-m.stop:
+				for _, w := range m.windows {
+					w.close()
+				}
+				return
+			default:
+				break calls
+			}
+		}
+		m.poll()
+	}
+}
+
+// Shows the latest frame of each window and handles GUI events
+func (m *WindowManager) poll() {
+	for _, w := range m.windows {
+		select {
+		case img := <-w.frames:
+			w.w.IMShow(img)
+			img.Close()
+		default:
+		}
+	}
+	if len(m.windows) == 0 {
+		return
+	}
+	if key := m.windows[0].w.WaitKey(int(guiPoll / time.Millisecond)); key >= 0 {
+		select {
+		case m.keys <- key:
+		default: // Nobody reads keys
+		}
+	}
+	for _, w := range m.windows {
+		for _, tb := range w.trackbars {
+			tb.poll()
+		}
+	}
+}
+
+// Do runs f on the GUI thread and waits until it returns. It must not be called from the GUI thread,
+// e.g. by f itself. f is not run if the manager is closed
+func (m *WindowManager) Do(f func()) {
+	done := make(chan struct{})
+	select {
+	case m.calls <- func() { f(); close(done) }:
+		<-done
+	case <-m.done:
+	}
+}
+
+// Keys returns the channel of keys pressed in any of the windows. Keys pressed while nobody reads
+// are dropped once a few are waiting
+func (m *WindowManager) Keys() <-chan int {
+	return m.keys
+}
+
+// NewWindow creates a window with the given name
+func (m *WindowManager) NewWindow(name string) *ManagedWindow {
+	w := &ManagedWindow{m: m, name: name, frames: make(chan gocv.Mat, 1)}
+	m.Do(func() {
+		w.w = gocv.NewWindow(name)
+		m.windows = append(m.windows, w)
+	})
+	return w
+}
+
+// Close closes the windows and stops the GUI thread
+func (m *WindowManager) Close() error {
+	m.once.Do(func() { close(m.stop) })
+	<-m.done
+	return nil
+}
+
+// ManagedWindow is a window of a WindowManager. Its methods may be called from any goroutine
+type ManagedWindow struct {
+	m      *WindowManager
+	name   string
+	frames chan gocv.Mat // The next frame to show
+
+	// Only used on the GUI thread
+	w         *gocv.Window
+	trackbars []*Trackbar
+}
+
+// Name returns the name of the window
+func (w *ManagedWindow) Name() string {
+	return w.name
+}
+
+// IMShow queues a copy of the frame to be shown, replacing a frame that was not shown yet
+func (w *ManagedWindow) IMShow(img gocv.Mat) {
+	select {
+	case <-w.m.done:
+		return
+	default:
+	}
+	frame := img.Clone()
+	for {
+		select {
+		case w.frames <- frame:
+			return
+		default:
+		}
+		select {
+		case old := <-w.frames:
+			old.Close()
+		default:
+		}
+	}
+}
+
+// WaitKey waits for a key pressed in any window of the manager for delay milliseconds, or forever
+// if delay is 0, and returns it, or -1 if none was pressed
+func (w *ManagedWindow) WaitKey(delay int) int {
+	var timeout <-chan time.Time
+	if delay > 0 {
+		t := time.NewTimer(time.Duration(delay) * time.Millisecond)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case key := <-w.m.keys:
+		return key
+	case <-timeout:
+	case <-w.m.done:
+	}
+	return -1
+}
+
+// ResizeWindow resizes the window
+func (w *ManagedWindow) ResizeWindow(width, height int) {
+	w.m.Do(func() { w.w.ResizeWindow(width, height) })
+}
+
+// MoveWindow moves the window to the position on the screen
+func (w *ManagedWindow) MoveWindow(x, y int) {
+	w.m.Do(func() { w.w.MoveWindow(x, y) })
+}
+
+// SetWindowTitle sets the title of the window
+func (w *ManagedWindow) SetWindowTitle(title string) {
+	w.m.Do(func() { w.w.SetWindowTitle(title) })
+}
+
+// CreateTrackbar adds a trackbar from 0 to max to the window
+func (w *ManagedWindow) CreateTrackbar(name string, max int) *Trackbar {
+	tb := &Trackbar{m: w.m, values: make(chan int, 1)}
+	w.m.Do(func() {
+		tb.bar = w.w.CreateTrackbar(name, max)
+		w.trackbars = append(w.trackbars, tb)
+	})
+	return tb
+}
+
+// SelectROI shows the image and lets the user select a region with the mouse, confirmed with
+// space or enter. The region is sent on the returned channel, empty if the selection was cancelled.
+// Other windows are not updated while selecting
+func (w *ManagedWindow) SelectROI(img gocv.Mat) <-chan image.Rectangle {
+	res := make(chan image.Rectangle, 1)
+	frame := img.Clone()
+	go func() {
+		defer frame.Close()
+		w.m.Do(func() { res <- w.w.SelectROI(frame) })
+		select {
+		case res <- image.Rectangle{}: // The manager was closed
+		default:
+		}
+	}()
+	return res
+}
+
+// Close closes the window
+func (w *ManagedWindow) Close() error {
+	w.m.Do(func() {
+		for i, mw := range w.m.windows {
+			if mw == w {
+				w.m.windows = append(w.m.windows[:i], w.m.windows[i+1:]...)
+				w.close()
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// Closes the window and the frame that was not shown, on the GUI thread
+func (w *ManagedWindow) close() {
+	select {
+	case img := <-w.frames:
+		img.Close()
+	default:
+	}
+	w.w.Close()
+}
+
+// Trackbar is a trackbar of a ManagedWindow. Its methods may be called from any goroutine
+type Trackbar struct {
+	m      *WindowManager
+	values chan int
+
+	mu  sync.Mutex
+	pos int
+
+	bar *gocv.Trackbar // Only used on the GUI thread
+}
+
+// Values returns the channel receiving the position whenever the user moves the trackbar.
+// Only the latest position is kept if it is not received in time
+func (tb *Trackbar) Values() <-chan int {
+	return tb.values
+}
+
+// GetPos returns the last known position
+func (tb *Trackbar) GetPos() int {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return tb.pos
+}
+
+// SetPos moves the trackbar to the position
+func (tb *Trackbar) SetPos(pos int) {
+	tb.m.Do(func() {
+		tb.bar.SetPos(pos)
+		tb.mu.Lock()
+		tb.pos = pos
+		tb.mu.Unlock()
+	})
+}
+
+// Reads the position on the GUI thread, sending it if it changed
+func (tb *Trackbar) poll() {
+	pos := tb.bar.GetPos()
+	tb.mu.Lock()
+	changed := pos != tb.pos
+	tb.pos = pos
+	tb.mu.Unlock()
+	if !changed {
+		return
+	}
+	select {
+	case <-tb.values:
+	default:
+	}
+	tb.values <- pos
+}
@@ -0,0 +1,215 @@
+// This example summarizes a video: it finds scene changes, picks the most representative and
+// diverse frames as keyframes and optionally assembles them into a short highlights video
+//
+// Call: gocv-examples keyframes -input video.mp4 [-max 10] [-out-dir keyframes] [-highlights highlights.avi]
+// Every -step-th frame is described by its hue-saturation histogram. A scene change is where
+// the Bhattacharyya distance of consecutive histograms exceeds -scene-thr; shots shorter than
+// -min-shot samples are merged into the previous one. The frame closest to the mean histogram
+// of each shot represents it, and up to -max of them are chosen to differ the most from each other.
+// Keyframes are saved to -out-dir as keyframe_<n>_<mm-ss>.jpg. With -highlights, -clip seconds of
+// video starting at each keyframe are joined with cross-fades of -fade seconds, see videoio.Editor
+// Parameters can also be set with KEYFRAMES_* environment variables or a config file, see internal/config
+//
+// Histograms ignore brightness, so lighting changes within a shot are not scene changes, but cuts
+// between shots of similar colors may be missed. Lower -scene-thr finds more of them
+
+package keyframes
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	sampleStep   = 5
+	sceneThr     = 0.4
+	minShot      = 3 // Samples
+	maxKeyframes = 10
+	outDir       = "keyframes"
+	clipLength   = 2 * time.Second
+	fadeLength   = 500 * time.Millisecond
+	videoCodec   = "MJPG"
+	defaultFPS   = 25 // Used when the video does not report its frame rate
+	hueBins      = 16
+	satBins      = 16
+)
+
+// Run extracts keyframes of the video given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples keyframes", flag.ExitOnError)
+	input := fs.String("input", "", "Video file")
+	step := fs.Int("step", sampleStep, "Analyze every n-th frame")
+	thr := fs.Float64("scene-thr", sceneThr, "Histogram distance of a scene change, from 0 to 1")
+	minLen := fs.Int("min-shot", minShot, "Minimal length of a shot in analyzed frames")
+	max := fs.Int("max", maxKeyframes, "Maximal number of keyframes")
+	dir := fs.String("out-dir", outDir, "Directory for keyframe images")
+	highlights := fs.String("highlights", "", "Video file to assemble highlights to")
+	clip := fs.Duration("clip", clipLength, "Length of the highlights clip of each keyframe")
+	fade := fs.Duration("fade", fadeLength, "Length of cross-fades between highlights clips")
+	logging.RegisterFlags(fs)
+	if err := config.Load(fs, args, "KEYFRAMES"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("keyframes")
+
+	if *input == "" {
+		return errors.New("Input video is required, see 'gocv-examples keyframes -h'")
+	}
+	if *step < 1 || *minLen < 1 || *max < 1 {
+		return errors.New("Step, minimal shot length and number of keyframes should be at least 1")
+	}
+	if *highlights != "" && (*clip <= 0 || *fade <= 0) {
+		return errors.New("Clip and fade lengths should be positive")
+	}
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return fmt.Errorf("Error creating output directory: %v", err)
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	var codecs []string
+	if *highlights != "" {
+		codecs = []string{videoCodec}
+	}
+	report := probe.Run(probe.Requirements{Codecs: codecs, Camera: -1})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	vr, err := gocv.OpenVideoCapture(*input)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", vr.Close)
+	fps := vr.Get(gocv.VideoCaptureFPS)
+	if fps <= 0 {
+		fps = defaultFPS
+	}
+
+	hists, frames, err := analyze(sd, vr, *step)
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	shots := splitShots(hists, *thr, *minLen)
+	candidates := make([]int, len(shots))
+	for i, s := range shots {
+		candidates[i] = representative(hists, s)
+	}
+	selected := selectDiverse(hists, candidates, *max)
+	logging.Infof("Analyzed %d frames, found %d shots, selected %d keyframes", len(hists), len(shots), len(selected))
+
+	keyframes := make([]int, len(selected))
+	img := gocv.NewMat()
+	defer img.Close()
+	for i, s := range selected {
+		n := frames[s]
+		keyframes[i] = n
+		vr.Set(gocv.VideoCapturePosFrames, float64(n))
+		if !vr.Read(&img) || img.Empty() {
+			logging.Errorf("Cannot read frame %d", n)
+			continue
+		}
+		at := time.Duration(float64(n) / fps * float64(time.Second))
+		name := filepath.Join(*dir, fmt.Sprintf("keyframe_%03d_%02d-%02d.jpg", i+1, int(at.Minutes()), int(at.Seconds())%60))
+		if !gocv.IMWrite(name, img) {
+			logging.Errorf("Cannot write %s", name)
+			continue
+		}
+		logging.Infof("Keyframe %d at %v saved to %s", i+1, at.Round(time.Second), name)
+	}
+
+	if *highlights != "" {
+		if err := assemble(sd, vr, keyframes, *highlights, fps, *clip, *fade); err != nil {
+			if err == videoio.ErrStopped {
+				return nil
+			}
+			return fmt.Errorf("Error writing highlights: %v", err)
+		}
+		logging.Infof("Highlights saved to %s", *highlights)
+	}
+	return nil
+}
+
+// Reads the video and returns the normalized histograms of every step-th frame and their numbers
+func analyze(sd *shutdown.Handler, vr *gocv.VideoCapture, step int) ([][]float32, []int, error) {
+	img, hsv, hist, mask := gocv.NewMat(), gocv.NewMat(), gocv.NewMat(), gocv.NewMat()
+	defer img.Close()
+	defer hsv.Close()
+	defer hist.Close()
+	defer mask.Close()
+	var hists [][]float32
+	var frames []int
+	for n := 0; ; n++ {
+		if sd.Context().Err() != nil {
+			return nil, nil, videoio.ErrStopped
+		}
+		if !vr.Read(&img) || img.Empty() {
+			break
+		}
+		if n%step != 0 {
+			continue
+		}
+		gocv.CvtColor(img, &hsv, gocv.ColorBGRToHSV)
+		gocv.CalcHist([]gocv.Mat{hsv}, []int{0, 1}, mask, &hist, []int{hueBins, satBins},
+			[]float64{0, 180, 0, 256}, false)
+		gocv.Normalize(hist, &hist, 1, 0, gocv.NormL1)
+		values, err := hist.DataPtrFloat32()
+		if err != nil {
+			return nil, nil, err
+		}
+		hists = append(hists, append([]float32(nil), values...))
+		frames = append(frames, n)
+	}
+	if len(hists) == 0 {
+		return nil, nil, errors.New("No frames could be read from the input")
+	}
+	return hists, frames, nil
+}
+
+// Writes clip seconds from each keyframe to the output, joined by cross-fades
+func assemble(sd *shutdown.Handler, vr *gocv.VideoCapture, keyframes []int, output string, fps float64,
+	clip, fade time.Duration) error {
+	width := int(vr.Get(gocv.VideoCaptureFrameWidth))
+	height := int(vr.Get(gocv.VideoCaptureFrameHeight))
+	vw, err := gocv.VideoWriterFile(output, videoCodec, fps, width, height, true)
+	if err != nil {
+		return err
+	}
+	defer vw.Close()
+	ed := videoio.NewEditor(sd.Context(), vw, fps)
+	defer ed.Close()
+
+	first := gocv.NewMat()
+	defer first.Close()
+	for _, n := range keyframes {
+		vr.Set(gocv.VideoCapturePosFrames, float64(n))
+		if !vr.Read(&first) || first.Empty() {
+			continue
+		}
+		if ed.LastFrame != nil {
+			if err := ed.FadeImageInto(ed.LastFrame, &first, fade.Seconds()); err != nil {
+				return err
+			}
+		}
+		if err := ed.Copy(vr, clip.Seconds()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package keyframes
+
+import (
+	"math"
+	"sort"
+)
+
+// bhattacharyya returns the Bhattacharyya distance of two histograms normalized to a sum of 1:
+// 0 for equal histograms, 1 for histograms without common bins
+func bhattacharyya(a, b []float32) float64 {
+	var bc float64
+	for i := range a {
+		bc += math.Sqrt(float64(a[i]) * float64(b[i]))
+	}
+	if bc > 1 {
+		bc = 1
+	}
+	return math.Sqrt(1 - bc)
+}
+
+// shot is a range of sampled frames [Start, End) between scene changes
+type shot struct {
+	Start, End int
+}
+
+// splitShots splits the sampled frames into shots where the histogram distance to the previous
+// frame exceeds thr. Shots shorter than minLen frames are merged into the previous shot, so that
+// flashes and fast motion do not make shots of their own
+func splitShots(hists [][]float32, thr float64, minLen int) []shot {
+	if len(hists) == 0 {
+		return nil
+	}
+	shots := []shot{{0, 1}}
+	for i := 1; i < len(hists); i++ {
+		last := &shots[len(shots)-1]
+		if bhattacharyya(hists[i-1], hists[i]) > thr && last.End-last.Start >= minLen {
+			shots = append(shots, shot{i, i + 1})
+		} else {
+			last.End = i + 1
+		}
+	}
+	return shots
+}
+
+// representative returns the frame of the shot closest to its mean histogram
+func representative(hists [][]float32, s shot) int {
+	mean := make([]float32, len(hists[s.Start]))
+	for i := s.Start; i < s.End; i++ {
+		for j, v := range hists[i] {
+			mean[j] += v / float32(s.End-s.Start)
+		}
+	}
+	best, bestDist := s.Start, math.Inf(1)
+	for i := s.Start; i < s.End; i++ {
+		if d := bhattacharyya(hists[i], mean); d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+// selectDiverse picks up to k of the candidate frames that differ the most from each other:
+// starting with the first candidate, it repeatedly adds the one farthest from those picked.
+// The result is sorted in time order
+func selectDiverse(hists [][]float32, candidates []int, k int) []int {
+	if k >= len(candidates) {
+		return append([]int(nil), candidates...)
+	}
+	if k <= 0 {
+		return nil
+	}
+	picked := []int{candidates[0]}
+	// Distance of each candidate to the nearest picked frame
+	nearest := make([]float64, len(candidates))
+	for i, c := range candidates {
+		nearest[i] = bhattacharyya(hists[c], hists[candidates[0]])
+	}
+	for len(picked) < k {
+		far := 0
+		for i := range candidates {
+			if nearest[i] > nearest[far] {
+				far = i
+			}
+		}
+		picked = append(picked, candidates[far])
+		for i, c := range candidates {
+			nearest[i] = math.Min(nearest[i], bhattacharyya(hists[c], hists[candidates[far]]))
+		}
+	}
+	sort.Ints(picked)
+	return picked
+}
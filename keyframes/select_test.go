@@ -0,0 +1,59 @@
+package keyframes
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// Histograms of 4 bins concentrated in one bin
+var (
+	red   = []float32{1, 0, 0, 0}
+	green = []float32{0, 1, 0, 0}
+	blue  = []float32{0, 0, 1, 0}
+	mixed = []float32{0.5, 0.5, 0, 0}
+)
+
+func TestBhattacharyya(t *testing.T) {
+	if d := bhattacharyya(red, red); d != 0 {
+		t.Errorf("Distance of equal histograms %v", d)
+	}
+	if d := bhattacharyya(red, green); d != 1 {
+		t.Errorf("Distance of disjoint histograms %v", d)
+	}
+	if d, want := bhattacharyya(red, mixed), math.Sqrt(1-math.Sqrt(0.5)); math.Abs(d-want) > 1e-6 {
+		t.Errorf("Distance %v, want %v", d, want)
+	}
+}
+
+func TestSplitShots(t *testing.T) {
+	hists := [][]float32{red, red, red, green, blue, blue, blue, red, red}
+	want := []shot{{0, 3}, {3, 7}, {7, 9}}
+	if got := splitShots(hists, 0.5, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("splitShots = %v, want %v", got, want)
+	}
+	want = []shot{{0, 3}, {3, 4}, {4, 7}, {7, 9}}
+	if got := splitShots(hists, 0.5, 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("splitShots without merging = %v, want %v", got, want)
+	}
+	if got := splitShots(nil, 0.5, 1); got != nil {
+		t.Errorf("splitShots of no frames = %v", got)
+	}
+}
+
+func TestRepresentative(t *testing.T) {
+	hists := [][]float32{red, mixed, green}
+	if r := representative(hists, shot{0, 3}); r != 1 {
+		t.Errorf("representative = %d, want the mixed frame 1", r)
+	}
+}
+
+func TestSelectDiverse(t *testing.T) {
+	hists := [][]float32{red, mixed, green, red, blue}
+	if got := selectDiverse(hists, []int{0, 1, 2, 3, 4}, 3); !reflect.DeepEqual(got, []int{0, 2, 4}) {
+		t.Errorf("selectDiverse = %v, want [0 2 4]", got)
+	}
+	if got := selectDiverse(hists, []int{1, 3}, 5); !reflect.DeepEqual(got, []int{1, 3}) {
+		t.Errorf("selectDiverse of few candidates = %v", got)
+	}
+}
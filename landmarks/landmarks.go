@@ -0,0 +1,273 @@
+// This example detects faces with the OpenCV DNN face detector and fits facial landmarks
+// to each face with a landmark regression network, drawing the landmark mesh
+//
+// Call: gocv-examples landmarks [-input 0] [-landmarks model.onnx] [-export landmarks.jsonl]
+// Input can be a camera ID (default 0), a video file, a stream URL or images
+// Parameters can also be set with LANDMARKS_* environment variables or a config file, see internal/config
+//
+// Face detector (ResNet-10 SSD) files are downloaded on first run and cached:
+// https://github.com/opencv/opencv/tree/4.x/samples/dnn/face_detector
+//
+// The landmark model is not downloaded, any ONNX model with the following contract can be used,
+// e.g. PFLD models trained on 300-W (68 points) or WFLW (98 points):
+// input 1x3xSxS RGB image of the face scaled to [0, 1], output 1x2N with x, y of N points relative
+// to the face crop. With 68 points the iBUG 300-W mesh is drawn, otherwise points only
+// With -export, faces and their landmarks of each frame are saved as JSON lines, see detection.FrameDetections
+
+package landmarks
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/models"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID            = "0"                                        // Default input
+	faceConfigPath   = "res10_300x300_ssd_deploy.prototxt"        // Face detector config
+	faceWeightsPath  = "res10_300x300_ssd_iter_140000.caffemodel" // Face detector weights
+	landmarksPath    = "face_landmarks.onnx"                      // Default landmark model
+	faceSize         = 300                                        // Input size of the face detector
+	landmarkSize     = 112                                        // Input size of the landmark model
+	faceConfThr      = 0.5
+	faceConfThrStep  = 0.05 // Change of the threshold by +/- keys
+	faceMargin       = 0.1  // Part of the face size added on each side of the landmark model input
+	minLandmarkCount = 5
+	landmarkRadius   = 2
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Contours of the 68-point iBUG 300-W layout, as ranges of point indexes
+var mesh68 = []struct {
+	from, to int
+	closed   bool
+}{
+	{0, 16, false},  // Jaw
+	{17, 21, false}, // Right brow
+	{22, 26, false}, // Left brow
+	{27, 30, false}, // Nose bridge
+	{31, 35, false}, // Nose base
+	{36, 41, true},  // Right eye
+	{42, 47, true},  // Left eye
+	{48, 59, true},  // Outer lips
+	{60, 67, true},  // Inner lips
+}
+
+// Run detects faces and their landmarks on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples landmarks", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	lmPath := fs.String("landmarks", landmarksPath, "ONNX landmark model, see the package comment for the expected input and output")
+	lmSize := fs.Int("landmark-size", landmarkSize, "Input size of the landmark model")
+	confThr := fs.Float64("conf-thr", faceConfThr, "Face detection confidence threshold")
+	export := fs.String("export", "", "File to export faces and landmarks to as JSON lines, one object per frame")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "LANDMARKS"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("landmarks")
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{DNN: true, Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	faces, err := LoadFaceDetector(sd, *confThr)
+	if err != nil {
+		return err
+	}
+	lmNet, err := LoadModel(sd, *lmPath)
+	if err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Facial landmarks")
+	if err != nil {
+		return err
+	}
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			faces.ConfThr += float32(step) * faceConfThrStep
+			if faces.ConfThr < faceConfThrStep {
+				faces.ConfThr = faceConfThrStep
+			} else if faces.ConfThr > 1-faceConfThrStep {
+				faces.ConfThr = 1 - faceConfThrStep
+			}
+			return fmt.Sprintf("face confidence %.2f", faces.ConfThr)
+		}
+	}
+
+	var exporter *detection.JSONWriter
+	if *export != "" {
+		if exporter, err = detection.NewJSONWriter(*export); err != nil {
+			return fmt.Errorf("Error opening export file: %v", err)
+		}
+		sd.OnClose("export", exporter.Close)
+	}
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	frame := 0
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("faces")
+		dets, err := faces.Detect(*img)
+		stop()
+		if err != nil {
+			logging.Errorf("Error detecting faces: %v", err)
+		}
+
+		stop = stats.Start("landmarks")
+		for i := range dets {
+			dets[i].Points = Landmarks(lmNet, *img, dets[i].BBox, *lmSize)
+		}
+		stop()
+		stats.Frame()
+
+		if exporter != nil {
+			if err := exporter.Write(detection.FrameDetections{Frame: frame, Detections: dets}); err != nil {
+				logging.Errorf("Error exporting landmarks: %v", err)
+			}
+		}
+		frame++
+
+		for _, d := range dets {
+			draw.LabelBox(img, d.BBox, fmt.Sprintf("%.0f%%", d.Confidence*100), draw.DefaultStyle)
+			drawLandmarks(img, d.Points, draw.DefaultStyle)
+		}
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err != nil && err != videoio.ErrStopped {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	return nil
+}
+
+// LoadFaceDetector resolves the face detector files and creates the detector with the confidence
+// threshold. The network is closed by sd
+func LoadFaceDetector(sd *shutdown.Handler, confThr float64) (*detection.SSDDetector, error) {
+	var paths []string
+	for _, name := range []string{faceConfigPath, faceWeightsPath} {
+		path, err := models.Resolve(name)
+		if err != nil {
+			return nil, fmt.Errorf("Error loading face detector: %v", err)
+		}
+		paths = append(paths, path)
+	}
+	faceNet := gocv.ReadNet(paths[1], paths[0])
+	if faceNet.Empty() {
+		return nil, errors.New("Error loading face detector")
+	}
+	sd.OnClose("face detector", faceNet.Close)
+	return &detection.SSDDetector{
+		Net:         &faceNet,
+		Size:        image.Pt(faceSize, faceSize),
+		Scale:       1,
+		Mean:        gocv.NewScalar(104, 177, 123, 0),
+		ClassLabels: []string{"background", "face"},
+		ConfThr:     float32(confThr),
+	}, nil
+}
+
+// LoadModel loads the ONNX landmark model, see the package comment for the expected contract.
+// The network is closed by sd
+func LoadModel(sd *shutdown.Handler, name string) (*gocv.Net, error) {
+	path, err := models.Resolve(name)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading landmark model, see 'gocv-examples landmarks -h': %v", err)
+	}
+	lmNet := gocv.ReadNetFromONNX(path)
+	if lmNet.Empty() {
+		return nil, errors.New("Error loading landmark model")
+	}
+	sd.OnClose("landmark model", lmNet.Close)
+	return &lmNet, nil
+}
+
+// Landmarks runs the landmark model on the face and returns the points in image coordinates.
+// size is the input size of the model
+func Landmarks(net *gocv.Net, img gocv.Mat, face image.Rectangle, size int) []image.Point {
+	box := cropBox(face, faceMargin, image.Rect(0, 0, img.Cols(), img.Rows()))
+	if box.Empty() {
+		return nil
+	}
+	crop := img.Region(box)
+	defer crop.Close()
+	blob := gocv.BlobFromImage(crop, 1.0/255, image.Pt(size, size), gocv.NewScalar(0, 0, 0, 0), true, false)
+	defer blob.Close()
+	net.SetInput(blob, "")
+	out := net.Forward("")
+	defer out.Close()
+
+	flat := out.Reshape(1, 1)
+	defer flat.Close()
+	n := flat.Cols() / 2
+	if n < minLandmarkCount {
+		return nil
+	}
+	pts := make([]image.Point, n)
+	for i := range pts {
+		pts[i] = image.Pt(box.Min.X+int(flat.GetFloatAt(0, 2*i)*float32(box.Dx())),
+			box.Min.Y+int(flat.GetFloatAt(0, 2*i+1)*float32(box.Dy())))
+	}
+	return pts
+}
+
+// cropBox returns a square around the face, enlarged by margin of its size on each side
+// and limited to bounds. Landmark models are trained on such crops
+func cropBox(face image.Rectangle, margin float64, bounds image.Rectangle) image.Rectangle {
+	side := face.Dx()
+	if face.Dy() > side {
+		side = face.Dy()
+	}
+	side += 2 * int(margin*float64(side))
+	center := face.Min.Add(face.Max).Div(2)
+	half := image.Pt(side/2, side/2)
+	return image.Rectangle{Min: center.Sub(half), Max: center.Sub(half).Add(image.Pt(side, side))}.Intersect(bounds)
+}
+
+// Draws the landmarks, connected by the mesh for the 68-point layout
+func drawLandmarks(img *gocv.Mat, pts []image.Point, st draw.Style) {
+	if len(pts) == 68 {
+		for _, c := range mesh68 {
+			part := pts[c.from : c.to+1]
+			pv := gocv.NewPointsVectorFromPoints([][]image.Point{part})
+			gocv.Polylines(img, pv, c.closed, st.LineColor, st.LineThickness)
+			pv.Close()
+		}
+	}
+	for _, p := range pts {
+		gocv.Circle(img, p, landmarkRadius, draw.Red, -1)
+	}
+}
@@ -0,0 +1,23 @@
+package landmarks
+
+import (
+	"image"
+	"testing"
+)
+
+func TestCropBox(t *testing.T) {
+	bounds := image.Rect(0, 0, 640, 480)
+	tests := []struct {
+		face, want image.Rectangle
+	}{
+		// Square around the center of the face, 10% larger on each side
+		{image.Rect(100, 100, 200, 150), image.Rect(90, 65, 210, 185)},
+		// Limited by the frame
+		{image.Rect(0, 0, 100, 100), image.Rect(0, 0, 110, 110)},
+	}
+	for _, tt := range tests {
+		if got := cropBox(tt.face, 0.1, bounds); got != tt.want {
+			t.Errorf("cropBox(%v) = %v, want %v", tt.face, got, tt.want)
+		}
+	}
+}
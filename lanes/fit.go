@@ -0,0 +1,104 @@
+package lanes
+
+import (
+	"image"
+	"math"
+)
+
+// segment is a line segment found by the Hough transform
+type segment struct {
+	a, b image.Point
+}
+
+// lane is a lane boundary as x = slope*y + offset, which keeps near vertical lines finite
+type lane struct {
+	slope, offset float64
+}
+
+// x returns the horizontal position of the boundary at row y
+func (l lane) x(y int) int {
+	return int(math.Round(l.slope*float64(y) + l.offset))
+}
+
+// split sorts segments into the left and the right boundary candidates by their direction and
+// side of the frame of given width. Segments closer to horizontal than minSlope (dy/dx) are dropped
+func split(segs []segment, width int, minSlope float64) (left, right []segment) {
+	center := width / 2
+	for _, s := range segs {
+		dx, dy := float64(s.b.X-s.a.X), float64(s.b.Y-s.a.Y)
+		if dx == 0 || math.Abs(dy/dx) < minSlope {
+			continue
+		}
+		// Image y grows downwards, so the left boundary rises to the right
+		switch slope := dy / dx; {
+		case slope < 0 && s.a.X < center && s.b.X < center:
+			left = append(left, s)
+		case slope > 0 && s.a.X > center && s.b.X > center:
+			right = append(right, s)
+		}
+	}
+	return left, right
+}
+
+// fit returns the boundary fitted to the end points of the segments by least squares,
+// weighted by segment length. ok is false if there are no segments
+func fit(segs []segment) (l lane, ok bool) {
+	var sw, sy, sx, syy, sxy float64
+	for _, s := range segs {
+		w := math.Hypot(float64(s.b.X-s.a.X), float64(s.b.Y-s.a.Y))
+		for _, p := range []image.Point{s.a, s.b} {
+			x, y := float64(p.X), float64(p.Y)
+			sw += w
+			sy += w * y
+			sx += w * x
+			syy += w * y * y
+			sxy += w * x * y
+		}
+	}
+	det := sw*syy - sy*sy
+	if sw == 0 || det == 0 {
+		return lane{}, false
+	}
+	l.slope = (sw*sxy - sy*sx) / det
+	l.offset = (sx - l.slope*sy) / sw
+	return l, true
+}
+
+// smoother averages a boundary over frames and keeps it for a few frames when it is not found
+type smoother struct {
+	alpha   float64 // Weight of the new fit, from 0 to 1
+	maxMiss int     // Frames without a fit after which the boundary is dropped
+	current lane
+	valid   bool
+	missed  int
+}
+
+// update takes the fit of the next frame and returns the smoothed boundary
+func (s *smoother) update(l lane, ok bool) (lane, bool) {
+	if !ok {
+		s.missed++
+		if s.missed > s.maxMiss {
+			s.valid = false
+		}
+		return s.current, s.valid
+	}
+	s.missed = 0
+	if !s.valid {
+		s.current, s.valid = l, true
+		return s.current, true
+	}
+	s.current.slope += s.alpha * (l.slope - s.current.slope)
+	s.current.offset += s.alpha * (l.offset - s.current.offset)
+	return s.current, true
+}
+
+// region returns the trapezoid where lanes are searched in a frame of given size: the bottom
+// edge spans the frame, the top edge is at top part of the height and topWidth part of the width
+func region(size image.Point, top, topWidth float64) []image.Point {
+	yTop := int(float64(size.Y) * top)
+	half := int(float64(size.X) * topWidth / 2)
+	center := size.X / 2
+	return []image.Point{
+		{0, size.Y - 1}, {center - half, yTop}, {center + half, yTop}, {size.X - 1, size.Y - 1},
+	}
+}
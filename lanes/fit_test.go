@@ -0,0 +1,67 @@
+package lanes
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	segs := []segment{
+		{image.Pt(100, 400), image.Pt(250, 250)}, // Left boundary
+		{image.Pt(500, 250), image.Pt(650, 400)}, // Right boundary
+		{image.Pt(100, 300), image.Pt(300, 310)}, // Nearly horizontal
+		{image.Pt(500, 400), image.Pt(600, 300)}, // Rising to the right on the right side
+		{image.Pt(380, 300), image.Pt(380, 400)}, // Vertical
+	}
+	left, right := split(segs, 800, 0.3)
+	if len(left) != 1 || left[0] != segs[0] {
+		t.Errorf("Unexpected left segments %v", left)
+	}
+	if len(right) != 1 || right[0] != segs[1] {
+		t.Errorf("Unexpected right segments %v", right)
+	}
+}
+
+func TestFit(t *testing.T) {
+	// Two collinear segments of x = -y + 500
+	l, ok := fit([]segment{
+		{image.Pt(100, 400), image.Pt(200, 300)},
+		{image.Pt(250, 250), image.Pt(300, 200)},
+	})
+	if !ok || math.Abs(l.slope+1) > 1e-9 || math.Abs(l.offset-500) > 1e-9 {
+		t.Errorf("Unexpected fit %+v, %v", l, ok)
+	}
+	if x := l.x(100); x != 400 {
+		t.Errorf("Expected x 400 at y 100, got %d", x)
+	}
+	if _, ok := fit(nil); ok {
+		t.Error("Expected no fit without segments")
+	}
+}
+
+func TestSmoother(t *testing.T) {
+	s := smoother{alpha: 0.5, maxMiss: 1}
+	if l, ok := s.update(lane{1, 100}, true); !ok || l != (lane{1, 100}) {
+		t.Errorf("First fit should be taken as is, got %+v", l)
+	}
+	if l, _ := s.update(lane{3, 200}, true); l != (lane{2, 150}) {
+		t.Errorf("Expected the average, got %+v", l)
+	}
+	if _, ok := s.update(lane{}, false); !ok {
+		t.Error("Boundary should be kept for one missed frame")
+	}
+	if _, ok := s.update(lane{}, false); ok {
+		t.Error("Boundary should be dropped after two missed frames")
+	}
+}
+
+func TestRegion(t *testing.T) {
+	got := region(image.Pt(800, 600), 0.6, 0.2)
+	want := []image.Point{{0, 599}, {320, 360}, {480, 360}, {799, 599}}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected %v, got %v", want, got)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// This example finds lane boundaries on dashcam footage and overlays them on the video
+//
+// Call: gocv-examples lanes -input drive.mp4 [-roi-top 0.6] [-canny-low 50] [-canny-high 150]
+// Input can be a video file, a stream URL, a camera ID or images
+// Parameters can also be set with LANES_* environment variables or a config file, see internal/config
+//
+// Edges are found with Canny and masked by a trapezoid in front of the car, given by -roi-top and
+// -roi-top-width as parts of the frame. Line segments found by the probabilistic Hough transform
+// are split into left and right boundary candidates by direction, a line is fitted to each side and
+// averaged over frames, so that dashed markings and short gaps do not make the boundaries jump.
+// The classic approach works on straight roads in daylight, curves and night driving need more
+// than straight lines. D shows the masked edges and the Hough segments
+
+package lanes
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	houghThr      = 40 // Votes needed for a line
+	houghThrStep  = 5  // Change of the threshold by +/- keys
+	minLineLength = 30
+	maxLineGap    = 100
+	laneThickness = 6
+	fillOpacity   = 0.3
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+var laneColor = color.RGBA{255, 64, 0, 0}
+
+// Returns the segments found by HoughLinesP
+func houghSegments(lines gocv.Mat) []segment {
+	segs := make([]segment, lines.Rows())
+	for i := range segs {
+		v := lines.GetVeciAt(i, 0)
+		segs[i] = segment{image.Pt(int(v[0]), int(v[1])), image.Pt(int(v[2]), int(v[3]))}
+	}
+	return segs
+}
+
+// Run finds lanes on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples lanes", flag.ExitOnError)
+	input := fs.String("input", "", "Video file, stream URL, camera ID, image file, directory or glob pattern of images")
+	cannyLow := fs.Float64("canny-low", 50, "Lower Canny threshold")
+	cannyHigh := fs.Float64("canny-high", 150, "Upper Canny threshold")
+	roiTop := fs.Float64("roi-top", 0.6, "Top of the search region, as part of the frame height from the top")
+	roiTopWidth := fs.Float64("roi-top-width", 0.15, "Width of the top edge of the search region, as part of the frame width")
+	minSlope := fs.Float64("min-slope", 0.5, "Minimal slope of lane segments, flatter ones are ignored")
+	smooth := fs.Float64("smooth", 0.2, "Weight of the new frame in the averaged boundaries, from 0 to 1")
+	maxMiss := fs.Int("max-miss", 10, "Frames a boundary is kept after it is lost")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "LANES"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("lanes")
+
+	if *input == "" {
+		return errors.New("Input is required, see 'gocv-examples lanes -h'")
+	}
+	if *roiTop <= 0 || *roiTop >= 1 || *roiTopWidth <= 0 || *roiTopWidth > 1 {
+		return errors.New("Search region top should be from 0 to 1 and its width from 0 to 1")
+	}
+	if *smooth <= 0 || *smooth > 1 {
+		return errors.New("Smoothing weight should be greater than 0 and at most 1")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Lane detection")
+	if err != nil {
+		return err
+	}
+	threshold, debug := houghThr, false
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			threshold += step * houghThrStep
+			if threshold < houghThrStep {
+				threshold = houghThrStep
+			}
+			return fmt.Sprintf("Hough threshold %d", threshold)
+		}
+		window.Controls.Keys = append(window.Controls.Keys, videoio.KeyAction{
+			Key: 'd', Help: "show edges and segments", Do: func() { debug = !debug },
+		})
+	}
+
+	left := &smoother{alpha: *smooth, maxMiss: *maxMiss}
+	right := &smoother{alpha: *smooth, maxMiss: *maxMiss}
+	gray, edges, mask, lines := gocv.NewMat(), gocv.NewMat(), gocv.NewMat(), gocv.NewMat()
+	defer gray.Close()
+	defer edges.Close()
+	defer mask.Close()
+	defer lines.Close()
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("edges")
+		size := image.Pt(img.Cols(), img.Rows())
+		roi := region(size, *roiTop, *roiTopWidth)
+		gocv.CvtColor(*img, &gray, gocv.ColorBGRToGray)
+		gocv.GaussianBlur(gray, &gray, image.Pt(5, 5), 0, 0, gocv.BorderDefault)
+		gocv.Canny(gray, &edges, float32(*cannyLow), float32(*cannyHigh))
+		if mask.Rows() != size.Y || mask.Cols() != size.X {
+			mask.Close()
+			mask = gocv.Zeros(size.Y, size.X, gocv.MatTypeCV8U)
+			pv := gocv.NewPointsVectorFromPoints([][]image.Point{roi})
+			gocv.FillPoly(&mask, pv, draw.White)
+			pv.Close()
+		}
+		gocv.BitwiseAnd(edges, mask, &edges)
+		stop()
+
+		stop = stats.Start("lines")
+		gocv.HoughLinesPWithParams(edges, &lines, 2, math.Pi/180, threshold, minLineLength, maxLineGap)
+		segs := houghSegments(lines)
+		leftSegs, rightSegs := split(segs, size.X, *minSlope)
+		l, lok := left.update(fit(leftSegs))
+		r, rok := right.update(fit(rightSegs))
+		stop()
+		stats.Frame()
+		logging.Debugf("Segments: %d, left %d, right %d", len(segs), len(leftSegs), len(rightSegs))
+
+		if debug {
+			gocv.CvtColor(edges, img, gocv.ColorGrayToBGR)
+			for _, s := range segs {
+				gocv.Line(img, s.a, s.b, draw.Green, 2)
+			}
+		}
+		drawLanes(img, roi[1].Y, size.Y-1, l, lok, r, rok)
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}
+
+// Draws the boundaries found between rows top and bottom, and fills the lane if both are found
+func drawLanes(img *gocv.Mat, top, bottom int, l lane, lok bool, r lane, rok bool) {
+	if lok && rok {
+		overlay := img.Clone()
+		poly := []image.Point{{l.x(bottom), bottom}, {l.x(top), top}, {r.x(top), top}, {r.x(bottom), bottom}}
+		pv := gocv.NewPointsVectorFromPoints([][]image.Point{poly})
+		gocv.FillPoly(&overlay, pv, draw.Green)
+		pv.Close()
+		gocv.AddWeighted(*img, 1-fillOpacity, overlay, fillOpacity, 0, img)
+		overlay.Close()
+	}
+	for _, b := range []struct {
+		l  lane
+		ok bool
+	}{{l, lok}, {r, rok}} {
+		if b.ok {
+			gocv.Line(img, image.Pt(b.l.x(bottom), bottom), image.Pt(b.l.x(top), top), laneColor, laneThickness)
+		}
+	}
+}
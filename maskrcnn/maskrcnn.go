@@ -0,0 +1,194 @@
+// This example finds object instances with Mask R-CNN and draws a colored mask over each one
+//
+// Call: gocv-examples mask-rcnn [-input 0] [-conf-thr 0.5] [-mask-thr 0.3] [-opacity 0.5]
+// Input can be a camera ID (default 0), a video file, a stream URL or images
+// Parameters can also be set with MASKRCNN_* environment variables or a config file, see internal/config
+//
+// The TensorFlow Mask R-CNN Inception v2 model trained on COCO is used. Its config and labels are
+// downloaded on first run, the weights are distributed as an archive:
+// http://download.tensorflow.org/models/object_detection/mask_rcnn_inception_v2_coco_2018_01_28.tar.gz
+// Extract frozen_inference_graph.pb to the current directory as mask_rcnn_inception_v2_coco_2018_01_28.pb,
+// or to the model cache, see internal/models
+//
+// Boxes are decoded like SSD output and the mask of each box is taken from the plane of its class,
+// see detection.MaskRCNNDetector. The network is slow on CPU, a few seconds per frame
+
+package maskrcnn
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/models"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID           = "0"                                            // Default input
+	classLabelsPath = "object_detection_classes_coco.txt"            // Labels by class ID
+	configPath      = "mask_rcnn_inception_v2_coco_2018_01_28.pbtxt" // Network config
+	weightsPath     = "mask_rcnn_inception_v2_coco_2018_01_28.pb"    // Network weights
+	opacity         = 0.5
+	confThrStep     = 0.05 // Change of the threshold by +/- keys
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Run finds object instances on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples mask-rcnn", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	confThr := fs.Float64("conf-thr", detection.DefaultMaskRCNNConfThr, "Detection confidence threshold")
+	maskThr := fs.Float64("mask-thr", detection.DefaultMaskThr, "Minimal mask probability of an object pixel")
+	alpha := fs.Float64("opacity", opacity, "Opacity of the masks, from 0 to 1")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "MASKRCNN"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("mask-rcnn")
+
+	if *alpha < 0 || *alpha > 1 {
+		return errors.New("Opacity should be from 0 to 1")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{DNN: true, Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	var paths []string
+	for _, name := range []string{classLabelsPath, configPath, weightsPath} {
+		path, err := models.Resolve(name)
+		if err != nil {
+			return fmt.Errorf("Error loading model, see 'gocv-examples mask-rcnn -h': %v", err)
+		}
+		paths = append(paths, path)
+	}
+	classLabels, err := detection.ReadClassLabels(paths[0])
+	if err != nil {
+		return fmt.Errorf("Error loading class labels: %v", err)
+	}
+	net := gocv.ReadNet(paths[2], paths[1])
+	if net.Empty() {
+		return errors.New("Error loading model")
+	}
+	sd.OnClose("model", net.Close)
+	detector := detection.NewMaskRCNNDetector(&net, classLabels)
+	detector.ConfThr, detector.MaskThr = float32(*confThr), float32(*maskThr)
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Mask R-CNN")
+	if err != nil {
+		return err
+	}
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			detector.ConfThr += float32(step) * confThrStep
+			if detector.ConfThr < confThrStep {
+				detector.ConfThr = confThrStep
+			}
+			return fmt.Sprintf("confidence %.2f", detector.ConfThr)
+		}
+	}
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("inference")
+		dets, err := detector.Detect(*img)
+		stop()
+		stats.Frame()
+		if err != nil {
+			logging.Errorf("Error detecting objects: %v", err)
+		}
+		logging.Infof("Detected objects: %d", len(dets))
+
+		drawMasks(img, dets, *alpha)
+		for _, d := range dets {
+			draw.LabelBox(img, d.BBox, fmt.Sprintf("%s %.0f%%", d.Label, d.Confidence*100),
+				draw.DefaultStyle.WithColor(draw.LabelColor(d.Label)))
+		}
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}
+
+// Fills the masks with the colors of their labels, blended with the image, and outlines them.
+// Instances of the same class get shifted colors to tell them apart
+func drawMasks(img *gocv.Mat, dets []detection.Detection, alpha float64) {
+	if len(dets) == 0 {
+		return
+	}
+	overlay := img.Clone()
+	defer overlay.Close()
+	seen := map[string]int{}
+	colors := make([]color.RGBA, len(dets))
+	for i, d := range dets {
+		colors[i] = draw.ClassColor(paletteIndex(d.Label) + seen[d.Label])
+		seen[d.Label]++
+		if len(d.Mask) >= 3 {
+			pv := gocv.NewPointsVectorFromPoints([][]image.Point{d.Mask})
+			gocv.FillPoly(&overlay, pv, colors[i])
+			pv.Close()
+		}
+	}
+	gocv.AddWeighted(*img, 1-alpha, overlay, alpha, 0, img)
+	for i, d := range dets {
+		if len(d.Mask) >= 3 {
+			pv := gocv.NewPointsVectorFromPoints([][]image.Point{d.Mask})
+			gocv.Polylines(img, pv, true, colors[i], 1)
+			pv.Close()
+		}
+	}
+}
+
+// Returns the palette index of the label color
+func paletteIndex(label string) int {
+	c := draw.LabelColor(label)
+	for i, p := range draw.Palette {
+		if p == c {
+			return i
+		}
+	}
+	return 0
+}
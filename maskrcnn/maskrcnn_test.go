@@ -0,0 +1,15 @@
+package maskrcnn
+
+import (
+	"testing"
+
+	"github.com/marchevska/gocv-examples/internal/draw"
+)
+
+func TestPaletteIndex(t *testing.T) {
+	for _, label := range []string{"person", "car", "dog"} {
+		if got := draw.Palette[paletteIndex(label)]; got != draw.LabelColor(label) {
+			t.Errorf("Expected the label color of %s, got %v", label, got)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package midas
+
+import "math"
+
+// normalize scales relative depth values linearly to integers from 0 to maxValue, so that
+// the smallest value gives 0 and the largest maxValue. A constant map gives zeros
+func normalize(depth []float32, maxValue float64) []uint16 {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, d := range depth {
+		min = math.Min(min, float64(d))
+		max = math.Max(max, float64(d))
+	}
+	out := make([]uint16, len(depth))
+	if max <= min {
+		return out
+	}
+	scale := maxValue / (max - min)
+	for i, d := range depth {
+		out[i] = uint16(math.Round((float64(d) - min) * scale))
+	}
+	return out
+}
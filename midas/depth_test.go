@@ -0,0 +1,19 @@
+package midas
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	got := normalize([]float32{2, 4, 3, 6}, 255)
+	if want := []uint16{0, 128, 64, 255}; !reflect.DeepEqual(got, want) {
+		t.Errorf("normalize = %v, want %v", got, want)
+	}
+	if got := normalize([]float32{-1, 1}, 65535); got[0] != 0 || got[1] != 65535 {
+		t.Errorf("Unexpected 16-bit range %v", got)
+	}
+	if got := normalize([]float32{5, 5}, 255); got[0] != 0 || got[1] != 0 {
+		t.Errorf("Constant depth should give zeros, got %v", got)
+	}
+}
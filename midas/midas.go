@@ -0,0 +1,229 @@
+// This example estimates depth from a single image or camera frame with MiDaS and shows it
+// as a color map, closer objects in warmer colors
+//
+// Call: gocv-examples depth [-input 0] [-model model-small.onnx] [-size 256] [-export-dir depth]
+// Input can be a camera ID (default 0), a video file, a stream URL or images
+// V toggles showing the frame next to the depth map
+// With -export-dir, the depth of each frame is also saved as a 16-bit PNG depth_000001.png...
+// scaled to the full range of the frame
+// Parameters can also be set with DEPTH_* environment variables or a config file, see internal/config
+//
+// The small MiDaS v2.1 model is downloaded on first run:
+// https://github.com/isl-org/MiDaS/releases/download/v2_1/model-small.onnx
+// Larger MiDaS models exported to ONNX work as well with their input size, e.g. -size 384.
+// MiDaS gives relative inverse depth: values only compare distances within a frame, larger is closer.
+// The network expects RGB normalized with the ImageNet mean and standard deviation; the standard
+// deviation differs slightly by channel, here the mean of the three is used for all of them
+
+package midas
+
+import (
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/models"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID     = "0"                // Default input
+	modelPath = "model-small.onnx" // MiDaS v2.1 small
+	netSize   = 256
+	stdDev    = 0.226 // Mean of the ImageNet standard deviations of R, G and B
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// ImageNet mean of R, G and B on the 0-255 scale
+var imageNetMean = gocv.NewScalar(123.675, 116.28, 103.53, 0)
+
+// Estimator runs a MiDaS network
+type Estimator struct {
+	Net  *gocv.Net
+	Size int // Square input size of the network
+}
+
+// Estimate returns the relative inverse depth of the image as a 32-bit float map of the image size,
+// which should be closed after use
+func (e *Estimator) Estimate(img gocv.Mat) (gocv.Mat, error) {
+	blob := gocv.BlobFromImage(img, 1/(255*stdDev), image.Pt(e.Size, e.Size), imageNetMean, true, false)
+	defer blob.Close()
+	e.Net.SetInput(blob, "")
+	out := e.Net.Forward("")
+	defer out.Close()
+	data, err := out.DataPtrFloat32()
+	if err != nil {
+		return gocv.NewMat(), err
+	}
+	if len(data) != e.Size*e.Size {
+		return gocv.NewMat(), fmt.Errorf("Unexpected output size %d, expected %dx%d", len(data), e.Size, e.Size)
+	}
+	depth := gocv.NewMatWithSize(e.Size, e.Size, gocv.MatTypeCV32F)
+	dst, _ := depth.DataPtrFloat32()
+	copy(dst, data)
+	gocv.Resize(depth, &depth, image.Pt(img.Cols(), img.Rows()), 0, 0, gocv.InterpolationCubic)
+	return depth, nil
+}
+
+// Colorize returns the depth map normalized to the color map, which should be closed after use
+func Colorize(depth gocv.Mat) gocv.Mat {
+	data, _ := depth.DataPtrFloat32()
+	values := normalize(data, 255)
+	gray := make([]byte, len(values))
+	for i, v := range values {
+		gray[i] = byte(v)
+	}
+	result := gocv.NewMat()
+	grayMat, err := gocv.NewMatFromBytes(depth.Rows(), depth.Cols(), gocv.MatTypeCV8U, gray)
+	if err != nil {
+		return result
+	}
+	defer grayMat.Close()
+	gocv.ApplyColorMap(grayMat, &result, gocv.ColormapJet)
+	return result
+}
+
+// Saves the depth map normalized to a 16-bit grayscale PNG
+func export(path string, depth gocv.Mat) error {
+	data, _ := depth.DataPtrFloat32()
+	values := normalize(data, 65535)
+	buf := make([]byte, 2*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint16(buf[2*i:], v)
+	}
+	img, err := gocv.NewMatFromBytes(depth.Rows(), depth.Cols(), gocv.MatTypeCV16U, buf)
+	if err != nil {
+		return err
+	}
+	defer img.Close()
+	if !gocv.IMWrite(path, img) {
+		return fmt.Errorf("Cannot write %s", path)
+	}
+	return nil
+}
+
+// Run estimates depth on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples depth", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	model := fs.String("model", modelPath, "MiDaS ONNX model")
+	size := fs.Int("size", netSize, "Square input size of the model")
+	exportDir := fs.String("export-dir", "", "Directory to save 16-bit depth PNGs to")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "DEPTH"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("depth")
+
+	if *size < 32 || *size%32 != 0 {
+		return errors.New("Input size should be a multiple of 32")
+	}
+	if *exportDir != "" {
+		if err := os.MkdirAll(*exportDir, 0755); err != nil {
+			return fmt.Errorf("Error creating export directory: %v", err)
+		}
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{DNN: true, Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	path, err := models.Resolve(*model)
+	if err != nil {
+		return fmt.Errorf("Error loading model, see 'gocv-examples depth -h': %v", err)
+	}
+	net := gocv.ReadNet(path, "")
+	if net.Empty() {
+		return errors.New("Error loading model")
+	}
+	sd.OnClose("model", net.Close)
+	estimator := &Estimator{Net: &net, Size: *size}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Monocular depth")
+	if err != nil {
+		return err
+	}
+	sideBySide := false
+	if window != nil {
+		window.Controls.Keys = append(window.Controls.Keys,
+			videoio.KeyAction{Key: 'v', Help: "show the frame next to the depth", Do: func() { sideBySide = !sideBySide }})
+	}
+
+	frame := 0
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		frame++
+		stop := stats.Start("inference")
+		depth, err := estimator.Estimate(*img)
+		stop()
+		defer depth.Close()
+		if err != nil {
+			logging.Errorf("Error estimating depth: %v", err)
+			return
+		}
+		if *exportDir != "" {
+			out := filepath.Join(*exportDir, fmt.Sprintf("depth_%06d.png", frame))
+			if err := export(out, depth); err != nil {
+				logging.Errorf("Error exporting depth: %v", err)
+			}
+		}
+		stats.Frame()
+
+		colored := Colorize(depth)
+		defer colored.Close()
+		if sideBySide {
+			side := gocv.NewMat()
+			gocv.Hconcat(*img, colored, &side)
+			side.CopyTo(img)
+			side.Close()
+		} else {
+			colored.CopyTo(img)
+		}
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package morph
+
+import "math"
+
+// GoCV does not wrap Subdiv2D at the moment of writing, so the triangulation is done here
+// with the Bowyer-Watson algorithm
+
+// triangulate returns the Delaunay triangulation of the points as triples of point indexes.
+// Repeated points are left out
+func triangulate(pts [][2]float64) [][3]int {
+	if len(pts) < 3 {
+		return nil
+	}
+	// Super triangle containing all points, its vertices follow the points
+	minX, minY, maxX, maxY := pts[0][0], pts[0][1], pts[0][0], pts[0][1]
+	for _, p := range pts {
+		minX, minY = math.Min(minX, p[0]), math.Min(minY, p[1])
+		maxX, maxY = math.Max(maxX, p[0]), math.Max(maxY, p[1])
+	}
+	d := math.Max(maxX-minX, maxY-minY) + 1
+	cx, cy := (minX+maxX)/2, (minY+maxY)/2
+	all := append(append([][2]float64(nil), pts...),
+		[2]float64{cx - 20*d, cy - d}, [2]float64{cx, cy + 20*d}, [2]float64{cx + 20*d, cy - d})
+	n := len(pts)
+	tris := []circumTriangle{newCircumTriangle(all, [3]int{n, n + 1, n + 2})}
+
+	seen := map[[2]float64]bool{}
+	for i, p := range pts {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+
+		// Triangles whose circumcircle contains the point are replaced by a fan from the point
+		// to the edges of the hole they leave
+		edges := map[[2]int]int{}
+		kept := tris[:0]
+		for _, t := range tris {
+			if t.contains(p) {
+				for k := 0; k < 3; k++ {
+					a, b := t.v[k], t.v[(k+1)%3]
+					if a > b {
+						a, b = b, a
+					}
+					edges[[2]int{a, b}]++
+				}
+				continue
+			}
+			kept = append(kept, t)
+		}
+		tris = kept
+		for e, count := range edges {
+			if count == 1 {
+				tris = append(tris, newCircumTriangle(all, [3]int{e[0], e[1], i}))
+			}
+		}
+	}
+
+	var out [][3]int
+	for _, t := range tris {
+		if t.v[0] < n && t.v[1] < n && t.v[2] < n {
+			out = append(out, t.v)
+		}
+	}
+	return out
+}
+
+// circumTriangle is a triangle with its circumcircle
+type circumTriangle struct {
+	v      [3]int
+	cx, cy float64
+	r2     float64 // Squared radius
+}
+
+func newCircumTriangle(pts [][2]float64, v [3]int) circumTriangle {
+	a, b, c := pts[v[0]], pts[v[1]], pts[v[2]]
+	d := 2 * (a[0]*(b[1]-c[1]) + b[0]*(c[1]-a[1]) + c[0]*(a[1]-b[1]))
+	if d == 0 {
+		// Collinear points: no circle, so the triangle is replaced by any point
+		return circumTriangle{v: v, r2: math.Inf(1)}
+	}
+	sa, sb, sc := a[0]*a[0]+a[1]*a[1], b[0]*b[0]+b[1]*b[1], c[0]*c[0]+c[1]*c[1]
+	cx := (sa*(b[1]-c[1]) + sb*(c[1]-a[1]) + sc*(a[1]-b[1])) / d
+	cy := (sa*(c[0]-b[0]) + sb*(a[0]-c[0]) + sc*(b[0]-a[0])) / d
+	return circumTriangle{v: v, cx: cx, cy: cy, r2: (a[0]-cx)*(a[0]-cx) + (a[1]-cy)*(a[1]-cy)}
+}
+
+func (t circumTriangle) contains(p [2]float64) bool {
+	dx, dy := p[0]-t.cx, p[1]-t.cy
+	return dx*dx+dy*dy < t.r2
+}
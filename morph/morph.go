@@ -0,0 +1,186 @@
+// This example morphs one face photo into another: facial landmarks of both faces are matched,
+// the images are split into triangles between the landmarks, and each frame warps both images
+// to the intermediate shape and cross-dissolves them
+//
+// Call: gocv-examples morph -from face1.jpg -to face2.jpg [-output morph.avi] [-duration 3] [-back]
+// The largest face of each photo is used. The second photo is resized to the size of the first,
+// which is the size of the video. The video holds the first photo for -hold seconds, morphs for
+// -duration seconds and holds the second photo; with -back it morphs back, so it can be looped
+// Parameters can also be set with MORPH_* environment variables or a config file, see internal/config
+//
+// Faces are detected and landmarks fitted as in the landmarks example, see its package comment
+// for the face detector and the landmark model. The corners and the middles of the image edges
+// are added to the landmarks, so that the background morphs too. Photos with faces of a similar
+// size and position, looking into the camera, give the best results
+
+package morph
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"github.com/marchevska/gocv-examples/landmarks"
+	"gocv.io/x/gocv"
+)
+
+const (
+	outputVideo   = "morph.avi"
+	landmarksPath = "face_landmarks.onnx" // Default landmark model
+	landmarkSize  = 112                   // Input size of the landmark model
+	faceConfThr   = 0.5
+	morphTime     = 3.0 // Seconds
+	holdTime      = 1.0
+	videoCodec    = "MJPG"
+	outputFPS     = 30
+)
+
+// Run morphs the faces given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples morph", flag.ExitOnError)
+	from := fs.String("from", "", "Photo of the first face")
+	to := fs.String("to", "", "Photo of the second face")
+	output := fs.String("output", outputVideo, "Output video")
+	lmPath := fs.String("landmarks", landmarksPath, "ONNX landmark model, see 'gocv-examples landmarks -h'")
+	lmSize := fs.Int("landmark-size", landmarkSize, "Input size of the landmark model")
+	confThr := fs.Float64("conf-thr", faceConfThr, "Face detection confidence threshold")
+	duration := fs.Float64("duration", morphTime, "Duration of the morph in seconds")
+	hold := fs.Float64("hold", holdTime, "Time in seconds each photo is shown before and after the morph")
+	back := fs.Bool("back", false, "Morph back to the first face at the end")
+	logging.RegisterFlags(fs)
+	if err := config.Load(fs, args, "MORPH"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("morph")
+
+	if *from == "" || *to == "" {
+		return errors.New("Both photos are required, see 'gocv-examples morph -h'")
+	}
+	if *duration <= 0 || *hold < 0 {
+		return errors.New("Duration should be positive and hold time not negative")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{DNN: true, Codecs: []string{videoCodec}, Camera: -1})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	faces, err := landmarks.LoadFaceDetector(sd, *confThr)
+	if err != nil {
+		return err
+	}
+	lmNet, err := landmarks.LoadModel(sd, *lmPath)
+	if err != nil {
+		return err
+	}
+
+	img1 := gocv.IMRead(*from, gocv.IMReadColor)
+	defer img1.Close()
+	if img1.Empty() {
+		return fmt.Errorf("Cannot read image %s", *from)
+	}
+	img2 := gocv.IMRead(*to, gocv.IMReadColor)
+	defer img2.Close()
+	if img2.Empty() {
+		return fmt.Errorf("Cannot read image %s", *to)
+	}
+	size := image.Pt(img1.Cols(), img1.Rows())
+	pts1, err := faceLandmarks(faces, lmNet, img1, *lmSize)
+	if err != nil {
+		return fmt.Errorf("Error in %s: %v", *from, err)
+	}
+	// Landmarks of the second photo are found at its own size and scaled with the photo
+	pts2, err := faceLandmarks(faces, lmNet, img2, *lmSize)
+	if err != nil {
+		return fmt.Errorf("Error in %s: %v", *to, err)
+	}
+	if len(pts1) != len(pts2) {
+		return fmt.Errorf("Landmark counts differ: %d and %d", len(pts1), len(pts2))
+	}
+	p1 := append(toFloat(pts1, 1, 1), boundaryPoints(size)...)
+	p2 := append(toFloat(pts2, float64(size.X)/float64(img2.Cols()), float64(size.Y)/float64(img2.Rows())),
+		boundaryPoints(size)...)
+	gocv.Resize(img2, &img2, size, 0, 0, gocv.InterpolationArea)
+	tris := triangulate(interpolate(p1, p2, 0.5))
+	logging.Infof("Morphing %d landmarks in %d triangles", len(pts1), len(tris))
+
+	vWriter, err := gocv.VideoWriterFile(*output, videoCodec, outputFPS, size.X, size.Y, true)
+	if err != nil {
+		return fmt.Errorf("Error opening output: %v", err)
+	}
+	sd.OnClose("video writer", vWriter.Close)
+	ed := videoio.NewEditor(sd.Context(), vWriter, outputFPS)
+	defer ed.Close()
+
+	warp1, warp2 := gocv.NewMat(), gocv.NewMat()
+	defer warp1.Close()
+	defer warp2.Close()
+	render := func(t float64, img *gocv.Mat) {
+		pts := interpolate(p1, p2, t)
+		warpImage(img1, &warp1, p1, pts, tris)
+		warpImage(img2, &warp2, p2, pts, tris)
+		gocv.AddWeighted(warp1, 1-t, warp2, t, 0, img)
+	}
+	steps := []func() error{
+		func() error { return holdImage(ed, &img1, *hold) },
+		func() error { return ed.Transition(*duration, render) },
+		func() error { return holdImage(ed, &img2, *hold) },
+	}
+	if *back {
+		steps = append(steps, func() error {
+			return ed.Transition(*duration, func(t float64, img *gocv.Mat) { render(1-t, img) })
+		})
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			if err == videoio.ErrStopped {
+				logging.Infof("Stopped, the video is incomplete")
+				return nil
+			}
+			return fmt.Errorf("Error writing video: %v", err)
+		}
+	}
+	logging.Infof("Saved %s", *output)
+	return nil
+}
+
+// Shows the image for the time in seconds, if any
+func holdImage(ed *videoio.Editor, img *gocv.Mat, seconds float64) error {
+	if seconds <= 0 {
+		return nil
+	}
+	return ed.RepeatFrame(img, seconds)
+}
+
+// Returns the landmarks of the largest face on the image
+func faceLandmarks(faces *detection.SSDDetector, net *gocv.Net, img gocv.Mat, size int) ([]image.Point, error) {
+	dets, err := faces.Detect(img)
+	if err != nil {
+		return nil, err
+	}
+	largest := -1
+	for i, d := range dets {
+		if largest < 0 || d.BBox.Dx()*d.BBox.Dy() > dets[largest].BBox.Dx()*dets[largest].BBox.Dy() {
+			largest = i
+		}
+	}
+	if largest < 0 {
+		return nil, errors.New("No face found")
+	}
+	pts := landmarks.Landmarks(net, img, dets[largest].BBox, size)
+	if len(pts) < 3 {
+		return nil, errors.New("No landmarks found")
+	}
+	return pts, nil
+}
@@ -0,0 +1,53 @@
+package morph
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestTriangulateSquare(t *testing.T) {
+	// Square with its center: 4 triangles around the center
+	pts := [][2]float64{{0, 0}, {10, 0}, {10, 10}, {0, 10}, {5, 5}}
+	tris := triangulate(pts)
+	if len(tris) != 4 {
+		t.Fatalf("Expected 4 triangles, got %v", tris)
+	}
+	for _, tr := range tris {
+		if tr[0] != 4 && tr[1] != 4 && tr[2] != 4 {
+			t.Errorf("Triangle %v does not use the center", tr)
+		}
+	}
+}
+
+func TestTriangulateDelaunay(t *testing.T) {
+	pts := boundaryPoints(image.Pt(200, 100))
+	pts = append(pts, [2]float64{50, 30}, [2]float64{120, 70}, [2]float64{90, 20}, [2]float64{160, 40}, [2]float64{50, 30})
+	tris := triangulate(pts)
+
+	// Triangulation of n points with h on the convex hull has 2n-2-h triangles; the repeated point is left out
+	if want := 2*12 - 2 - 8; len(tris) != want {
+		t.Errorf("Expected %d triangles, got %d", want, len(tris))
+	}
+	area := 0.0
+	for _, tr := range tris {
+		c := newCircumTriangle(pts, tr)
+		for i, p := range pts {
+			if i != tr[0] && i != tr[1] && i != tr[2] && c.contains(p) && p != pts[tr[0]] && p != pts[tr[1]] && p != pts[tr[2]] {
+				t.Errorf("Point %v inside the circumcircle of %v", p, tr)
+			}
+		}
+		a, b, cc := pts[tr[0]], pts[tr[1]], pts[tr[2]]
+		area += math.Abs((b[0]-a[0])*(cc[1]-a[1])-(cc[0]-a[0])*(b[1]-a[1])) / 2
+	}
+	if math.Abs(area-199*99) > 1e-6 {
+		t.Errorf("Triangles cover %v, want the whole image %v", area, 199*99)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	got := interpolate([][2]float64{{0, 0}, {10, 20}}, [][2]float64{{10, 10}, {20, 20}}, 0.25)
+	if got[0] != [2]float64{2.5, 2.5} || got[1] != [2]float64{12.5, 20} {
+		t.Errorf("Unexpected points %v", got)
+	}
+}
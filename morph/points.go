@@ -0,0 +1,28 @@
+package morph
+
+import "image"
+
+// boundaryPoints returns the corners and the middles of the edges of an image of the size,
+// added to the landmarks so that the triangulation covers the whole image
+func boundaryPoints(size image.Point) [][2]float64 {
+	w, h := float64(size.X-1), float64(size.Y-1)
+	return [][2]float64{{0, 0}, {w / 2, 0}, {w, 0}, {w, h / 2}, {w, h}, {w / 2, h}, {0, h}, {0, h / 2}}
+}
+
+// toFloat converts landmarks to float coordinates, scaled by sx and sy as their image
+func toFloat(pts []image.Point, sx, sy float64) [][2]float64 {
+	out := make([][2]float64, len(pts))
+	for i, p := range pts {
+		out[i] = [2]float64{float64(p.X) * sx, float64(p.Y) * sy}
+	}
+	return out
+}
+
+// interpolate returns the points between a and b at t from 0 to 1
+func interpolate(a, b [][2]float64, t float64) [][2]float64 {
+	out := make([][2]float64, len(a))
+	for i := range a {
+		out[i] = [2]float64{(1-t)*a[i][0] + t*b[i][0], (1-t)*a[i][1] + t*b[i][1]}
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package morph
+
+import (
+	"image"
+	"math"
+
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"gocv.io/x/gocv"
+)
+
+// warpImage warps the triangles of src with vertices from onto the same triangles with vertices to,
+// writing the result to dst of the size of src
+func warpImage(src gocv.Mat, dst *gocv.Mat, from, to [][2]float64, tris [][3]int) {
+	if dst.Cols() != src.Cols() || dst.Rows() != src.Rows() || dst.Type() != src.Type() {
+		dst.Close()
+		*dst = gocv.NewMatWithSize(src.Rows(), src.Cols(), src.Type())
+	}
+	for _, t := range tris {
+		warpTriangle(src, dst, [3][2]float64{from[t[0]], from[t[1]], from[t[2]]}, [3][2]float64{to[t[0]], to[t[1]], to[t[2]]})
+	}
+}
+
+// warpTriangle maps the triangle from of src onto the triangle to of dst with an affine transform.
+// Only the bounding boxes of the triangles are processed
+func warpTriangle(src gocv.Mat, dst *gocv.Mat, from, to [3][2]float64) {
+	srcBox := boundingBox(from).Intersect(image.Rect(0, 0, src.Cols(), src.Rows()))
+	dstBox := boundingBox(to).Intersect(image.Rect(0, 0, dst.Cols(), dst.Rows()))
+	if srcBox.Empty() || dstBox.Empty() {
+		return
+	}
+
+	srcPts, dstPts := make([]gocv.Point2f, 3), make([]gocv.Point2f, 3)
+	poly := make([]image.Point, 3)
+	for i := range from {
+		srcPts[i] = gocv.Point2f{X: float32(from[i][0]) - float32(srcBox.Min.X), Y: float32(from[i][1]) - float32(srcBox.Min.Y)}
+		dstPts[i] = gocv.Point2f{X: float32(to[i][0]) - float32(dstBox.Min.X), Y: float32(to[i][1]) - float32(dstBox.Min.Y)}
+		poly[i] = image.Pt(int(math.Round(to[i][0]))-dstBox.Min.X, int(math.Round(to[i][1]))-dstBox.Min.Y)
+	}
+	srcVec, dstVec := gocv.NewPoint2fVectorFromPoints(srcPts), gocv.NewPoint2fVectorFromPoints(dstPts)
+	defer srcVec.Close()
+	defer dstVec.Close()
+	m := gocv.GetAffineTransform2f(srcVec, dstVec)
+	defer m.Close()
+
+	patch := src.Region(srcBox)
+	defer patch.Close()
+	warped := gocv.NewMat()
+	defer warped.Close()
+	// Reflected borders keep the edges of neighbouring triangles free of black seams
+	gocv.WarpAffineWithParams(patch, &warped, m, dstBox.Size(), gocv.InterpolationLinear, gocv.BorderReflect101, draw.Black)
+
+	mask := gocv.NewMatWithSize(dstBox.Dy(), dstBox.Dx(), gocv.MatTypeCV8U)
+	defer mask.Close()
+	pv := gocv.NewPointsVectorFromPoints([][]image.Point{poly})
+	defer pv.Close()
+	gocv.FillPoly(&mask, pv, draw.White)
+	region := dst.Region(dstBox)
+	defer region.Close()
+	warped.CopyToWithMask(&region, mask)
+}
+
+// Returns the pixels covered by the bounding box of the triangle
+func boundingBox(t [3][2]float64) image.Rectangle {
+	minX, minY := math.Min(t[0][0], math.Min(t[1][0], t[2][0])), math.Min(t[0][1], math.Min(t[1][1], t[2][1]))
+	maxX, maxY := math.Max(t[0][0], math.Max(t[1][0], t[2][0])), math.Max(t[0][1], math.Max(t[1][1], t[2][1]))
+	return image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX))+1, int(math.Ceil(maxY))+1)
+}
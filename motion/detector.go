@@ -0,0 +1,89 @@
+package motion
+
+import (
+	"fmt"
+	"image"
+	"strings"
+
+	"gocv.io/x/gocv"
+)
+
+const (
+	history    = 500 // Frames used to model the background
+	shadowThr  = 200 // Mask values below are shadows or noise
+	kernelSize = 5
+)
+
+// Subtractor is implemented by both gocv background subtractors
+type Subtractor interface {
+	Apply(src gocv.Mat, dst *gocv.Mat)
+	Close() error
+}
+
+// NewSubtractor creates a background subtractor by method name: mog2 or knn
+func NewSubtractor(method string) (Subtractor, error) {
+	switch strings.ToLower(method) {
+	case "mog2":
+		mog2 := gocv.NewBackgroundSubtractorMOG2WithParams(history, 16, true)
+		return &mog2, nil
+	case "knn":
+		knn := gocv.NewBackgroundSubtractorKNNWithParams(history, 400, true)
+		return &knn, nil
+	}
+	return nil, fmt.Errorf("Unknown background subtraction method %q, available: mog2, knn", method)
+}
+
+// Detector finds moving regions on consecutive frames with background subtraction
+type Detector struct {
+	MinArea float64 // Minimal area of a moving region in pixels
+
+	bg     Subtractor
+	mask   gocv.Mat
+	kernel gocv.Mat
+}
+
+// NewDetector creates a detector with the background subtraction method, see NewSubtractor.
+// It should be closed after use
+func NewDetector(method string, minArea float64) (*Detector, error) {
+	bg, err := NewSubtractor(method)
+	if err != nil {
+		return nil, err
+	}
+	return &Detector{MinArea: minArea, bg: bg, mask: gocv.NewMat(),
+		kernel: gocv.GetStructuringElement(gocv.MorphEllipse, image.Pt(kernelSize, kernelSize))}, nil
+}
+
+// Detect updates the background model with the frame and returns bounding boxes of the moving regions
+func (d *Detector) Detect(img gocv.Mat) []image.Rectangle {
+	d.bg.Apply(img, &d.mask)
+	gocv.Threshold(d.mask, &d.mask, shadowThr, 255, gocv.ThresholdBinary)
+	gocv.MorphologyEx(d.mask, &d.mask, gocv.MorphOpen, d.kernel)
+	gocv.Dilate(d.mask, &d.mask, d.kernel)
+	return movingRegions(d.mask, d.MinArea)
+}
+
+// Mask returns the foreground mask of the last frame
+func (d *Detector) Mask() gocv.Mat {
+	return d.mask
+}
+
+// Close releases the background model and the buffers
+func (d *Detector) Close() error {
+	d.mask.Close()
+	d.kernel.Close()
+	return d.bg.Close()
+}
+
+// movingRegions returns bounding boxes of the foreground regions of the mask with at least minArea pixels
+func movingRegions(mask gocv.Mat, minArea float64) []image.Rectangle {
+	contours := gocv.FindContours(mask, gocv.RetrievalExternal, gocv.ChainApproxSimple)
+	defer contours.Close()
+	var regions []image.Rectangle
+	for i := 0; i < contours.Size(); i++ {
+		c := contours.At(i)
+		if gocv.ContourArea(c) >= minArea {
+			regions = append(regions, gocv.BoundingRect(c))
+		}
+	}
+	return regions
+}
@@ -0,0 +1,249 @@
+// This example detects motion with background subtraction and fires actions when motion lasts
+//
+// Call: gocv-examples motion [-input 0] [-method mog2|knn] [-min-area 500] [-action log] [-action clip]
+// Moving regions larger than -min-area pixels are outlined. When there is motion on -sustain
+// consecutive frames a motion event starts, and it ends after -cooldown frames without motion.
+// Actions on events, repeatable:
+//   log      - log start and end of the event (default)
+//   clip     - record annotated frames of the event to a video file in -clip-dir, starting -pre-roll
+//              before the event, since the frames of the last -pre-roll are kept in memory
+//   snapshot - save the frame that started the event to -clip-dir
+// With -s3 s3://BUCKET, saved clips and snapshots are also uploaded to S3 or MinIO (-s3-endpoint),
+// keyed by date, camera and "motion", see internal/storage
+// Parameters can also be set with MOTION_* environment variables or a config file, see internal/config
+//
+// MOG2 adapts faster to lighting changes, KNN is often better with many small moving objects.
+// Both mark shadows with a lower value of the mask, which are ignored
+
+package motion
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/storage"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID       = "0" // Default input
+	minArea     = 500
+	minAreaStep = 100 // Change of the minimal area by +/- keys
+	sustain     = 10
+	cooldown    = 25
+	preRoll     = 2 * time.Second
+	clipDir     = "motion"
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Actions on motion events
+const (
+	actionLog      = "log"
+	actionClip     = "clip"
+	actionSnapshot = "snapshot"
+)
+
+// Class of uploaded clips and snapshots in object keys
+const motionClass = "motion"
+
+// Run detects motion on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples motion", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	method := fs.String("method", "mog2", "Background subtraction method: mog2 or knn")
+	area := fs.Int("min-area", minArea, "Minimal area of a moving region in pixels")
+	sustainFrames := fs.Int("sustain", sustain, "Frames with motion to start a motion event")
+	cooldownFrames := fs.Int("cooldown", cooldown, "Frames without motion to end a motion event")
+	var actions config.StringList
+	fs.Var(&actions, "action", "Action on motion events, repeatable: log, clip or snapshot (default log)")
+	dir := fs.String("clip-dir", clipDir, "Directory for clips and snapshots")
+	pre := fs.Duration("pre-roll", preRoll, "Time recorded in clips before each event, 0 for none")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	var storageOpts storage.Options
+	storageOpts.RegisterFlags(fs)
+	if err := config.Load(fs, args, "MOTION"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("motion")
+
+	if len(actions) == 0 {
+		actions = config.StringList{actionLog}
+	}
+	enabled := map[string]bool{}
+	for _, a := range actions {
+		a = strings.ToLower(a)
+		if a != actionLog && a != actionClip && a != actionSnapshot {
+			return fmt.Errorf("Unknown action %q, available: log, clip, snapshot", a)
+		}
+		enabled[a] = true
+	}
+	if *sustainFrames < 1 || *cooldownFrames < 1 {
+		return errors.New("Sustain and cooldown should be at least 1 frame")
+	}
+	if *pre < 0 {
+		return fmt.Errorf("Pre-roll should not be negative: %v", *pre)
+	}
+	if enabled[actionClip] || enabled[actionSnapshot] {
+		if err := os.MkdirAll(*dir, 0755); err != nil {
+			return fmt.Errorf("Error creating clip directory: %v", err)
+		}
+	}
+
+	detector, err := NewDetector(*method, float64(*area))
+	if err != nil {
+		return err
+	}
+	defer detector.Close()
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	codecs := outputs.Codecs()
+	if enabled[actionClip] && len(codecs) == 0 {
+		codecs = []string{videoCodec}
+	}
+	report := probe.Run(probe.Requirements{Codecs: codecs, Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Motion detection")
+	if err != nil {
+		return err
+	}
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			detector.MinArea += float64(step * minAreaStep)
+			if detector.MinArea < minAreaStep {
+				detector.MinArea = minAreaStep
+			}
+			return fmt.Sprintf("min area %.0f px", detector.MinArea)
+		}
+	}
+
+	uploader, err := storageOpts.Open(sd, *input)
+	if err != nil {
+		return err
+	}
+
+	// Frames before an event, written at the start of its clip
+	var buffer *videoio.FrameBuffer
+	if enabled[actionClip] && *pre > 0 {
+		buffer = videoio.NewPreRollBuffer(*pre, videoFPS)
+		defer buffer.Close()
+	}
+
+	// The clip of the current event, closed and uploaded when the event ends or on exit
+	var clip *videoio.VideoSink
+	var clipPath string
+	var started time.Time
+	closeClip := func() error {
+		if clip == nil {
+			return nil
+		}
+		err := clip.Close()
+		clip = nil
+		if err == nil && uploader != nil {
+			uploader.Upload(clipPath, motionClass, started)
+		}
+		return err
+	}
+	sd.OnClose("clip", closeClip)
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	tr := Trigger{Sustain: *sustainFrames, Cooldown: *cooldownFrames}
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("subtraction")
+		regions := detector.Detect(*img)
+		stop()
+		stats.Frame()
+
+		for _, r := range regions {
+			gocv.Rectangle(img, r, draw.Red, draw.DefaultStyle.LineThickness)
+		}
+
+		now := time.Now()
+		switch tr.Update(len(regions) > 0) {
+		case Started:
+			started = now
+			name := filepath.Join(*dir, "motion_"+now.Format("20060102_150405"))
+			if enabled[actionLog] {
+				logging.Infof("Motion started: %d regions", len(regions))
+			}
+			if enabled[actionSnapshot] {
+				if !gocv.IMWrite(name+".jpg", *img) {
+					logging.Errorf("Cannot write snapshot %s.jpg", name)
+				} else if uploader != nil {
+					uploader.Upload(name+".jpg", motionClass, now)
+				}
+			}
+			if enabled[actionClip] {
+				clipPath = name + ".avi"
+				clip = videoio.NewVideoSink(clipPath, videoCodec, videoFPS)
+				if buffer != nil {
+					if err := buffer.Drain(clip); err != nil {
+						logging.Errorf("Error writing pre-roll: %v", err)
+					}
+				}
+			}
+		case Ended:
+			if enabled[actionLog] {
+				logging.Infof("Motion ended after %v", now.Sub(started).Round(time.Second))
+			}
+			if err := closeClip(); err != nil {
+				logging.Errorf("Error closing clip: %v", err)
+			}
+		}
+
+		if tr.Active() {
+			st := draw.DefaultStyle.WithColor(draw.Red)
+			draw.TextWithBackground(img, "MOTION", image.Pt(0, st.TextSize("MOTION").Y+2*st.Padding), st)
+			if clip != nil {
+				if err := clip.Write(*img); err != nil {
+					logging.Errorf("Error writing clip: %v", err)
+					clip.Close()
+					clip = nil
+				}
+			}
+		}
+		if clip == nil && buffer != nil {
+			buffer.Push(*img)
+		}
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err != nil && err != videoio.ErrStopped {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	return nil
+}
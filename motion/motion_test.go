@@ -0,0 +1,14 @@
+package motion
+
+import "testing"
+
+func TestTrigger(t *testing.T) {
+	tr := Trigger{Sustain: 3, Cooldown: 2}
+	frames := []bool{true, false, true, true, true, true, false, true, false, false, false}
+	want := []Event{NoEvent, NoEvent, NoEvent, NoEvent, Started, NoEvent, NoEvent, NoEvent, NoEvent, Ended, NoEvent}
+	for i, m := range frames {
+		if got := tr.Update(m); got != want[i] {
+			t.Errorf("Frame %d: expected event %d, got %d", i, want[i], got)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package motion
+
+// Event is a change of the motion state reported by Trigger
+type Event int
+
+// Motion events
+const (
+	NoEvent Event = iota
+	Started       // Motion lasted for the required number of frames
+	Ended         // No motion for the cooldown number of frames
+)
+
+// Trigger turns per-frame motion flags into events, ignoring short flickers of motion
+// and short pauses during an event
+type Trigger struct {
+	Sustain  int // Frames with motion to start an event
+	Cooldown int // Frames without motion to end it
+	active   bool
+	count    int // Consecutive frames against the current state
+}
+
+// Active reports whether a motion event is going on
+func (t *Trigger) Active() bool {
+	return t.active
+}
+
+// Update takes the motion flag of the next frame and returns the event it causes
+func (t *Trigger) Update(motion bool) Event {
+	if motion == t.active {
+		t.count = 0
+		return NoEvent
+	}
+	t.count++
+	if !t.active && t.count >= t.Sustain {
+		t.active, t.count = true, 0
+		return Started
+	}
+	if t.active && t.count >= t.Cooldown {
+		t.active, t.count = false, 0
+		return Ended
+	}
+	return NoEvent
+}
@@ -0,0 +1,61 @@
+//go:build gosseract
+// +build gosseract
+
+package ocr
+
+import (
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/otiai10/gosseract/v2"
+	"gocv.io/x/gocv"
+)
+
+// gosseractAvailable reports whether the binary is built with the gosseract library
+const gosseractAvailable = true
+
+// gosseractEngine recognizes text with the Tesseract library through gosseract and implements
+// detection.Detector. Each detection is a word, labelled with its text
+type gosseractEngine struct {
+	client *gosseract.Client
+}
+
+func newGosseract(lang string, psm int) (detection.Detector, error) {
+	client := gosseract.NewClient()
+	if err := client.SetLanguage(lang); err != nil {
+		client.Close()
+		return nil, err
+	}
+	if err := client.SetPageSegMode(gosseract.PageSegMode(psm)); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &gosseractEngine{client: client}, nil
+}
+
+// Detect passes the image as PNG to Tesseract and returns the recognized words
+func (ge *gosseractEngine) Detect(img gocv.Mat) ([]detection.Detection, error) {
+	buf, err := gocv.IMEncode(gocv.PNGFileExt, img)
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Close()
+	if err := ge.client.SetImageFromBytes(buf.GetBytes()); err != nil {
+		return nil, err
+	}
+	boxes, err := ge.client.GetBoundingBoxes(gosseract.RIL_WORD)
+	if err != nil {
+		return nil, err
+	}
+	var dets []detection.Detection
+	for _, b := range boxes {
+		if b.Word == "" {
+			continue
+		}
+		dets = append(dets, detection.Detection{Label: b.Word, Confidence: float32(b.Confidence / 100), BBox: b.Box})
+	}
+	return dets, nil
+}
+
+// Close releases the Tesseract client
+func (ge *gosseractEngine) Close() error {
+	return ge.client.Close()
+}
@@ -0,0 +1,18 @@
+//go:build !gosseract
+// +build !gosseract
+
+package ocr
+
+import (
+	"errors"
+
+	"github.com/marchevska/gocv-examples/internal/detection"
+)
+
+// gosseractAvailable reports whether the binary is built with the gosseract library
+const gosseractAvailable = false
+
+// newGosseract cannot use the Tesseract library without the gosseract tag
+func newGosseract(lang string, psm int) (detection.Detector, error) {
+	return nil, errors.New("Built without gosseract, build with -tags gosseract or use -engine cli")
+}
@@ -0,0 +1,239 @@
+// This example recognizes text with Tesseract OCR, printing each word with its box and confidence
+//
+// Call: gocv-examples ocr -input sign.jpg [-lang eng] [-engine auto|cli|gosseract] [-min-conf 0.6]
+// Input can be images, a video file, a camera ID or a stream URL. The whole frame is recognized,
+// or only the regions given with -region x,y,width,height, which is repeatable. With -east, text is
+// first found with the EAST detector and only the found boxes are recognized, see the text example
+// Recognized words are printed as: frame, left, top, width, height, confidence, text
+// Parameters can also be set with OCR_* environment variables or a config file, see internal/config
+//
+// Engines:
+//   cli       - runs the tesseract command, which should be installed, e.g. 'apt install tesseract-ocr'
+//   gosseract - calls the Tesseract library through github.com/otiai10/gosseract, only available in
+//               binaries built with -tags gosseract, which needs libtesseract-dev
+//   auto      - gosseract when available, otherwise cli
+
+package ocr
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	lang    = "eng"
+	psm     = 3   // Tesseract page segmentation mode: fully automatic
+	minConf = 0.6 // Words with lower confidence are not printed
+	textPad = 0.1 // Part of the text height added around EAST boxes, which are tight
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Creates the OCR engine by name
+func newEngine(name, lang string, psm int) (detection.Detector, error) {
+	switch strings.ToLower(name) {
+	case "auto":
+		if gosseractAvailable {
+			return newGosseract(lang, psm)
+		}
+		return newTesseractCLI(lang, psm)
+	case "cli":
+		return newTesseractCLI(lang, psm)
+	case "gosseract":
+		return newGosseract(lang, psm)
+	}
+	return nil, fmt.Errorf("Unknown OCR engine %q, available: auto, cli, gosseract", name)
+}
+
+// parseRegion parses a rectangle given as "x,y,width,height"
+func parseRegion(s string) (image.Rectangle, error) {
+	var x, y, w, h int
+	if n, err := fmt.Sscanf(strings.ReplaceAll(s, " ", ""), "%d,%d,%d,%d", &x, &y, &w, &h); err != nil || n != 4 {
+		return image.Rectangle{}, fmt.Errorf("Region should be x,y,width,height: %q", s)
+	}
+	if w <= 0 || h <= 0 {
+		return image.Rectangle{}, fmt.Errorf("Region should have positive size: %q", s)
+	}
+	return image.Rect(x, y, x+w, y+h), nil
+}
+
+// Enlarges the text box by pad of its height on each side
+func padText(r image.Rectangle, pad float64) image.Rectangle {
+	d := int(pad*float64(r.Dy())) + 1
+	return r.Inset(-d)
+}
+
+// Recognize runs the engine on each region of the image and returns words in image coordinates.
+// The whole image is recognized when there are no regions
+func Recognize(engine detection.Detector, img gocv.Mat, regions []image.Rectangle) ([]detection.Detection, error) {
+	bounds := image.Rect(0, 0, img.Cols(), img.Rows())
+	if len(regions) == 0 {
+		return engine.Detect(img)
+	}
+	var words []detection.Detection
+	for _, r := range regions {
+		r = r.Intersect(bounds)
+		if r.Empty() {
+			continue
+		}
+		crop := img.Region(r)
+		dets, err := engine.Detect(crop)
+		crop.Close()
+		if err != nil {
+			return words, err
+		}
+		for _, d := range dets {
+			d.BBox = d.BBox.Add(r.Min)
+			words = append(words, d)
+		}
+	}
+	return words, nil
+}
+
+// Run recognizes text on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples ocr", flag.ExitOnError)
+	input := fs.String("input", "", "Image, directory or glob pattern of images, video file, camera ID or stream URL")
+	language := fs.String("lang", lang, "Tesseract language, e.g. eng or eng+deu")
+	engineName := fs.String("engine", "auto", "OCR engine: auto, cli or gosseract")
+	pageSeg := fs.Int("psm", psm, "Tesseract page segmentation mode, e.g. 7 for a single line of text")
+	conf := fs.Float64("min-conf", minConf, "Minimal confidence of printed words, from 0 to 1")
+	var regionFlags config.StringList
+	fs.Var(&regionFlags, "region", "Region to recognize as x,y,width,height, repeatable; the whole frame by default")
+	eastModel := fs.String("east", "", "EAST model file; if set, only text boxes found by EAST are recognized")
+	eastSize := fs.Int("east-size", detection.DefaultEASTSize, "EAST input size, a multiple of 32")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "OCR"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("ocr")
+
+	if *input == "" {
+		return errors.New("Input is required, see 'gocv-examples ocr -h'")
+	}
+	var regions []image.Rectangle
+	for _, s := range regionFlags {
+		r, err := parseRegion(s)
+		if err != nil {
+			return err
+		}
+		regions = append(regions, r)
+	}
+	if *conf < 0 || *conf > 1 {
+		return errors.New("Minimal confidence should be from 0 to 1")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{DNN: *eastModel != "", Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	engine, err := newEngine(*engineName, *language, *pageSeg)
+	if err != nil {
+		return fmt.Errorf("Error starting OCR: %v", err)
+	}
+	if c, ok := engine.(io.Closer); ok {
+		sd.OnClose("ocr", c.Close)
+	}
+	var east *detection.EASTDetector
+	if *eastModel != "" {
+		if east, err = detection.LoadEAST(sd, *eastModel, *eastSize); err != nil {
+			return err
+		}
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "OCR")
+	if err != nil {
+		return err
+	}
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	frame := 0
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		frameRegions := regions
+		if east != nil {
+			stop := stats.Start("east")
+			boxes, err := east.Detect(*img)
+			stop()
+			if err != nil {
+				logging.Errorf("Error detecting text: %v", err)
+			}
+			frameRegions = nil
+			for _, b := range boxes {
+				frameRegions = append(frameRegions, padText(b.BBox, textPad))
+			}
+		}
+
+		stop := stats.Start("ocr")
+		var words []detection.Detection
+		var err error
+		if east == nil || len(frameRegions) > 0 {
+			words, err = Recognize(engine, *img, frameRegions)
+		}
+		stop()
+		stats.Frame()
+		if err != nil {
+			logging.Errorf("Error recognizing text: %v", err)
+		}
+
+		for _, r := range frameRegions {
+			gocv.Rectangle(img, r, draw.DefaultStyle.BgColor, 1)
+		}
+		for _, w := range words {
+			if w.Confidence < float32(*conf) {
+				continue
+			}
+			fmt.Printf("%d\t%d\t%d\t%d\t%d\t%.2f\t%s\n", frame, w.BBox.Min.X, w.BBox.Min.Y, w.BBox.Dx(), w.BBox.Dy(),
+				w.Confidence, w.Label)
+			draw.LabelBox(img, w.BBox, w.Label, draw.DefaultStyle)
+		}
+		frame++
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package ocr
+
+import (
+	"image"
+	"testing"
+
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"gocv.io/x/gocv"
+)
+
+const tsv = "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n" +
+	"1\t1\t0\t0\t0\t0\t0\t0\t640\t480\t-1\t\n" +
+	"4\t1\t1\t1\t1\t0\t36\t92\t582\t68\t-1\t\n" +
+	"5\t1\t1\t1\t1\t1\t36\t92\t216\t68\t96.06\tGoCV\n" +
+	"5\t1\t1\t1\t1\t2\t270\t95\t348\t65\t85.5\texamples\n" +
+	"5\t1\t1\t1\t1\t3\t620\t95\t10\t65\t95\t \n"
+
+func TestParseTSV(t *testing.T) {
+	words, err := parseTSV([]byte(tsv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(words) != 2 {
+		t.Fatalf("Expected 2 words, got %v", words)
+	}
+	if words[0].Label != "GoCV" || words[0].BBox != image.Rect(36, 92, 252, 160) || words[0].Confidence < 0.96 {
+		t.Errorf("Unexpected first word %v", words[0])
+	}
+	if words[1].Label != "examples" {
+		t.Errorf("Unexpected second word %v", words[1])
+	}
+	if _, err := parseTSV([]byte("header\nx\t1\t1\t1\t1\t1\t0\t0\t1\t1\t90\tword\n")); err == nil {
+		t.Error("Expected error for malformed output")
+	}
+}
+
+// Finds a single word at a fixed position of the image
+type fakeEngine struct{}
+
+func (fakeEngine) Detect(img gocv.Mat) ([]detection.Detection, error) {
+	return []detection.Detection{{Label: "word", Confidence: 0.9, BBox: image.Rect(1, 2, 11, 7)}}, nil
+}
+
+func TestRecognizeRegions(t *testing.T) {
+	img := gocv.NewMatWithSize(100, 200, gocv.MatTypeCV8UC3)
+	defer img.Close()
+	words, err := Recognize(fakeEngine{}, img, []image.Rectangle{image.Rect(50, 40, 90, 60), image.Rect(300, 0, 310, 10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The second region is outside of the image
+	if len(words) != 1 || words[0].BBox != image.Rect(51, 42, 61, 47) {
+		t.Errorf("Expected a word in image coordinates, got %v", words)
+	}
+}
+
+func TestParseRegion(t *testing.T) {
+	if r, err := parseRegion("10, 20, 30, 40"); err != nil || r != image.Rect(10, 20, 40, 60) {
+		t.Errorf("Unexpected region %v, %v", r, err)
+	}
+	for _, s := range []string{"1,2,3", "1,2,0,4", "a,b,c,d"} {
+		if _, err := parseRegion(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
+
+func TestPadText(t *testing.T) {
+	if got := padText(image.Rect(100, 50, 200, 70), 0.1); got != image.Rect(97, 47, 203, 73) {
+		t.Errorf("Unexpected padded box %v", got)
+	}
+}
@@ -0,0 +1,92 @@
+package ocr
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"gocv.io/x/gocv"
+)
+
+// wordLevel is the level of words in the TSV output of Tesseract
+const wordLevel = 5
+
+// tesseractCLI recognizes text by running the tesseract command and implements detection.Detector.
+// Each detection is a word, labelled with its text
+type tesseractCLI struct {
+	lang string
+	psm  int
+}
+
+func newTesseractCLI(lang string, psm int) (detection.Detector, error) {
+	if _, err := exec.LookPath("tesseract"); err != nil {
+		return nil, errors.New("tesseract command not found, install Tesseract OCR, e.g. 'apt install tesseract-ocr'")
+	}
+	return &tesseractCLI{lang: lang, psm: psm}, nil
+}
+
+// Detect passes the image as PNG to tesseract and parses its TSV output
+func (tc *tesseractCLI) Detect(img gocv.Mat) ([]detection.Detection, error) {
+	buf, err := gocv.IMEncode(gocv.PNGFileExt, img)
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Close()
+	cmd := exec.Command("tesseract", "stdin", "stdout", "-l", tc.lang, "--psm", strconv.Itoa(tc.psm), "tsv")
+	cmd.Stdin = bytes.NewReader(buf.GetBytes())
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parseTSV(out)
+}
+
+// parseTSV extracts words with their boxes and confidence from the TSV output of Tesseract:
+// level, page_num, block_num, par_num, line_num, word_num, left, top, width, height, conf, text
+func parseTSV(out []byte) ([]detection.Detection, error) {
+	var dets []detection.Detection
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	header := true
+	for scanner.Scan() {
+		if header {
+			header = false
+			continue
+		}
+		fields := strings.SplitN(scanner.Text(), "\t", 12)
+		if len(fields) < 12 {
+			continue
+		}
+		level, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("Unexpected tesseract output: %q", scanner.Text())
+		}
+		text := strings.TrimSpace(fields[11])
+		if level != wordLevel || text == "" {
+			continue
+		}
+		var v [4]int
+		for i := range v {
+			if v[i], err = strconv.Atoi(fields[6+i]); err != nil {
+				return nil, fmt.Errorf("Unexpected tesseract output: %q", scanner.Text())
+			}
+		}
+		conf, err := strconv.ParseFloat(fields[10], 32)
+		if err != nil {
+			return nil, fmt.Errorf("Unexpected tesseract output: %q", scanner.Text())
+		}
+		dets = append(dets, detection.Detection{
+			Label:      text,
+			Confidence: float32(conf / 100),
+			BBox:       image.Rect(v[0], v[1], v[0]+v[2], v[1]+v[3]),
+		})
+	}
+	return dets, scanner.Err()
+}
@@ -0,0 +1,109 @@
+// Edit video recorded with ORB detector
+//
+// On my laptop detection on live webcam input took about 80-90 ms,
+// so I inserted transitions between original frames to make the video slower
+// while keeping the frame rate, also inserted intro frames with fade in/out transtions
+//
+// Call: gocv-examples edit [-input video1.avi] [-output video_edited.avi] [-config file.yaml]
+// Parameters can also be set with EDIT_VIDEO_* environment variables
+
+package editvideo
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	inputVideo  = "video1.avi"
+	outputVideo = "video_edited.avi"
+	videoCodec  = "MJPG"
+	outputFPS   = 30
+	frameType   = gocv.MatTypeCV8UC3
+)
+
+// Text style of the intro screens
+var introStyle = draw.Style{
+	Font:          gocv.FontHersheyTriplex,
+	FontScale:     2,
+	TextThickness: 3,
+	TextColor:     draw.White,
+	BgColor:       draw.DarkBlue,
+}
+
+// Run edits the input video given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples edit", flag.ExitOnError)
+	input := fs.String("input", inputVideo, "Video recorded with ORB detector")
+	output := fs.String("output", outputVideo, "Edited video")
+	logging.RegisterFlags(fs)
+	if err := config.Load(fs, args, "EDIT_VIDEO"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("edit-video")
+
+	// Ctrl+C stops editing and finalizes the output video
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	// Check that the output codec is available
+	report := probe.Run(probe.Requirements{Codecs: []string{videoCodec}, Camera: -1})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	// Create video reader and writer
+	vReader, err := gocv.OpenVideoCapture(*input)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("video reader", vReader.Close)
+	videoWidth := int(vReader.Get(gocv.VideoCaptureFrameWidth))
+	videoHeight := int(vReader.Get(gocv.VideoCaptureFrameHeight))
+	vWriter, err := gocv.VideoWriterFile(*output, videoCodec, outputFPS, videoWidth, videoHeight, true)
+	if err != nil {
+		return fmt.Errorf("Error opening output: %v", err)
+	}
+	sd.OnClose("video writer", vWriter.Close)
+	vwm := videoio.NewEditor(sd.Context(), vWriter, outputFPS)
+	defer vwm.Close()
+
+	// Intro screens
+	blackScreen := gocv.NewMatWithSize(videoHeight, videoWidth, frameType)
+	defer blackScreen.Close()
+	lines := []string{"OpenCV ORB", "playing cards recognition", "example with gocv"}
+	introFrame := draw.MessageBox(lines, 3, videoWidth, videoHeight, introStyle)
+	defer introFrame.Close()
+	lines2 := []string{"Continue demonstration", "with closed", "face and suit signs"}
+	introFrame2 := draw.MessageBox(lines2, 3, videoWidth, videoHeight, introStyle)
+	defer introFrame2.Close()
+
+	// First frame of the video is used for transitions
+	firstFrame := gocv.NewMat()
+	defer firstFrame.Close()
+	vReader.Read(&firstFrame)
+
+	// Add intro screen with fade in-fade out effects
+	vwm.FadeImageInto(&blackScreen, &introFrame, 1.5)
+	vwm.RepeatFrame(&introFrame, 2.0)
+	vwm.FadeImageInto(&introFrame, &firstFrame, 1.2)
+
+	// Copy slowed fragment video from the original video
+	vwm.CopyFrom(vReader, 12.1)
+
+	// Second intro screen and the rest of the original video
+	vwm.FadeImageInto(vwm.LastFrame, &introFrame2, 1.5)
+	vwm.RepeatFrame(&introFrame2, 2.0)
+	vwm.FadeImageInto(&introFrame2, vwm.LastFrame, 1.5)
+	vwm.CopyFrom(vReader, 36.0)
+	return nil
+}
@@ -0,0 +1,180 @@
+// This example implements an OpenCV ORB algorithm to identify playing cards using go and gocv.
+//
+// Due to the specifics of the ORB algorithm, this method is only suitable for the face cards
+// including Jacks, Queens, Kings, and Ace of Spades (in my deck), since these are feature rich and
+// distinguishable, and not suitable for other cards.
+//
+// gocv at the moment of writing only supports default parameters for feature2d detectors
+// Call: gocv-examples orb [arguments]
+//
+
+package goorb
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const usageStr = `Playing cards detector based on ORB algorithm. Press 'Q' to exit, 'H' for help.
+Usage: gocv-examples orb [flags]
+Parameters can also be set with GO_ORB_* environment variables or a config file, see internal/config.
+Flags accepted:`
+
+// Input and output parameters
+const (
+	camID       = "0" // Default input, edit this for your camera
+	camWidth    = 1280
+	camHeight   = 720
+	videoCodec  = "MJPG"
+	videoFPS    = 25
+	winWidth    = camWidth / 2
+	winHeight   = camHeight / 2
+	imgDir      = "../real_cards/train_img"
+	outputVideo = "video.avi"
+)
+
+// Detection parameters
+const (
+	detectInterval time.Duration = 500 * time.Millisecond
+)
+
+var detectAll bool
+var faceCardPrefixes = [...]string{"Jack", "Queen", "King", "Ace of Spades"}
+
+// Limits patterns by file name to JQK and Ace of Spades depending of arguments
+func isValidName(filename string) bool {
+	if detectAll {
+		return true
+	}
+	for _, pref := range faceCardPrefixes {
+		if strings.HasPrefix(filename, pref) {
+			return true
+		}
+	}
+	return false
+}
+
+// Run detects playing cards on the input given by command line arguments
+func Run(args []string) error {
+	// Choose whether to detect all cards or face cards only, and the input
+	fs := flag.NewFlagSet("gocv-examples orb", flag.ExitOnError)
+	fs.BoolVar(&detectAll, "all", false, "Detect all cards; otherwise limit detection to face cards")
+	input := fs.String("input", camID, "Camera ID, picam, video file, stream URL, screen, image file, directory or glob pattern of images")
+	logging.RegisterFlags(fs)
+	var headless videoio.Headless
+	headless.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), usageStr)
+		fs.PrintDefaults()
+	}
+	if err := config.Load(fs, args, "GO_ORB"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	fs.Usage()
+	logging.SetPrefix("go-orb")
+
+	// Ctrl+C stops detection and finalizes the recorded video
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	// Check the camera, if used, and that the result can be recorded
+	report := probe.Run(probe.Requirements{Codecs: []string{videoCodec}, Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	// Start input first, for webcam adjust definition for better results
+	src, err := videoio.OpenSource(*input, camWidth, camHeight)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = headless.Limit(src)
+
+	// Set working dir to the package directory
+	if _, filename, _, ok := runtime.Caller(0); ok {
+		os.Chdir(path.Dir(filename))
+	}
+
+	// Initialize detector and load (card) patterns
+	orb := gocv.NewORB()
+	sd.OnClose("ORB", orb.Close)
+	opd := detection.NewORBPatternDetector(orb, imgDir, isValidName)
+	sd.OnClose("patterns", opd.Close)
+	logging.Infof("Successfully loaded: %d patterns", len(opd.Pats))
+	var detector detection.Detector = &opd
+
+	// Output window and video writer, which is started with the definition of the first frame
+	// Without a display, results are only recorded
+	sink := videoio.MultiSink{videoio.NewVideoSink(outputVideo, videoCodec, videoFPS)}
+	if headless.Enabled() {
+		logging.Infof("Running without display, recording to %s", outputVideo)
+	} else {
+		window := videoio.NewWindowSink("ORB Detector", winWidth, winHeight)
+		window.Controls = videoio.NewControls(sd.Context(), videoCodec, videoFPS)
+		window.Controls.Threshold = func(step int) string {
+			if opd.MinMatches+step > 0 {
+				opd.MinMatches += step
+			}
+			return fmt.Sprintf("minimum matches %d", opd.MinMatches)
+		}
+		sink = append(sink, window)
+	}
+	sd.OnClose("outputs", sink.Close)
+
+	detectedClass := ""
+	lastDetClass := ""
+	lastDetTime := time.Now()
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("matching")
+		dets, err := detector.Detect(*img)
+		stop()
+		stats.Frame()
+		if err != nil {
+			logging.Errorf("Error detecting cards: %v", err)
+		}
+
+		// Workaround for detection delay caused by video input
+		if len(dets) > 0 {
+			detectedClass = dets[0].Label
+			lastDetClass = dets[0].Label
+			lastDetTime = time.Now()
+			draw.Outline(img, dets[0].Quad, draw.DefaultStyle)
+		} else if time.Now().Sub(lastDetTime) < detectInterval {
+			detectedClass = lastDetClass
+		} else {
+			detectedClass = ""
+		}
+
+		if detectedClass != "" {
+			draw.TextWithBackground(img, detectedClass, image.Pt(0, 40), draw.BannerStyle)
+		}
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err != nil && err != videoio.ErrStopped {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package panorama
+
+import (
+	"image"
+	"math"
+
+	"gocv.io/x/gocv"
+)
+
+// homography is a 3x3 perspective transform in row-major order
+type homography [9]float64
+
+var identity = homography{1, 0, 0, 0, 1, 0, 0, 0, 1}
+
+// translation returns the transform moving points by dx, dy
+func translation(dx, dy float64) homography {
+	return homography{1, 0, dx, 0, 1, dy, 0, 0, 1}
+}
+
+// fromMat reads a 3x3 CV_64F Mat returned by FindHomography
+func fromMat(m gocv.Mat) homography {
+	var h homography
+	for i := range h {
+		h[i] = m.GetDoubleAt(i/3, i%3)
+	}
+	return h
+}
+
+// mat returns the transform as a 3x3 CV_64F Mat for WarpPerspective
+func (h homography) mat() gocv.Mat {
+	m := gocv.NewMatWithSize(3, 3, gocv.MatTypeCV64F)
+	for i, v := range h {
+		m.SetDoubleAt(i/3, i%3, v)
+	}
+	return m
+}
+
+// mul returns the transform applying g first and then h
+func (h homography) mul(g homography) homography {
+	var r homography
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				r[i*3+j] += h[i*3+k] * g[k*3+j]
+			}
+		}
+	}
+	return r
+}
+
+// inverse returns the inverse transform, false if h is singular
+func (h homography) inverse() (homography, bool) {
+	a, b, c, d, e, f, g, k, l := h[0], h[1], h[2], h[3], h[4], h[5], h[6], h[7], h[8]
+	det := a*(e*l-f*k) - b*(d*l-f*g) + c*(d*k-e*g)
+	if math.Abs(det) < 1e-12 {
+		return homography{}, false
+	}
+	return homography{
+		(e*l - f*k) / det, (c*k - b*l) / det, (b*f - c*e) / det,
+		(f*g - d*l) / det, (a*l - c*g) / det, (c*d - a*f) / det,
+		(d*k - e*g) / det, (b*g - a*k) / det, (a*e - b*d) / det,
+	}, true
+}
+
+// apply transforms the point
+func (h homography) apply(x, y float64) (float64, float64) {
+	w := h[6]*x + h[7]*y + h[8]
+	return (h[0]*x + h[1]*y + h[2]) / w, (h[3]*x + h[4]*y + h[5]) / w
+}
+
+// bounds returns the bounding box of an image of given size after the transform
+func (h homography) bounds(size image.Point) image.Rectangle {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, c := range [][2]float64{{0, 0}, {float64(size.X), 0}, {float64(size.X), float64(size.Y)}, {0, float64(size.Y)}} {
+		x, y := h.apply(c[0], c[1])
+		minX, minY = math.Min(minX, x), math.Min(minY, y)
+		maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+	}
+	return image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+}
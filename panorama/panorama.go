@@ -0,0 +1,201 @@
+// This example stitches overlapping photos into a panorama with ORB feature matching
+//
+// Call: gocv-examples panorama -input "photos/*.jpg" [-out panorama.jpg]
+//       gocv-examples panorama -input pan.mp4 -every 15
+// Images are stitched in the order of their file names, each one should overlap the previous one.
+// With a video, e.g. a slow camera pan, every N-th frame is used. Images are projected on the plane
+// of the middle image, so the panorama should not cover much more than 120 degrees
+// Parameters can also be set with PANORAMA_* environment variables or a config file, see internal/config
+//
+// Each image is matched to the previous one with the ORB matching helpers of the detection
+// package, and the homographies between neighbours are composed to map all images to the middle one.
+// Overlaps are not blended, later images are drawn over earlier ones
+
+package panorama
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	outPath   = "panorama.jpg"
+	maxImages = 30
+	maxSide   = 20000 // Larger panoramas are a sign of a wrong homography
+)
+
+type frame struct {
+	img   gocv.Mat
+	kp    []gocv.KeyPoint
+	descr gocv.Mat
+}
+
+// Run stitches the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples panorama", flag.ExitOnError)
+	input := fs.String("input", "", "Directory or glob pattern of images, or a video file")
+	every := fs.Int("every", 1, "Use every N-th frame of the input")
+	max := fs.Int("max-images", maxImages, "Maximal number of images to stitch")
+	out := fs.String("out", outPath, "Output image file")
+	minMatches := fs.Int("min-matches", detection.DefaultMinMatches, "Minimal number of feature matches between neighbouring images")
+	logging.RegisterFlags(fs)
+	var headless videoio.Headless
+	headless.RegisterFlags(fs)
+	if err := config.Load(fs, args, "PANORAMA"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("panorama")
+
+	if *input == "" {
+		return errors.New("Input is required, see 'gocv-examples panorama -h'")
+	}
+	if *every < 1 || *max < 2 {
+		return errors.New("Sampling step should be positive and at least 2 images are needed")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+
+	orb := gocv.NewORB()
+	defer orb.Close()
+	var frames []frame
+	defer func() {
+		for _, f := range frames {
+			f.img.Close()
+			f.descr.Close()
+		}
+	}()
+	for n := 0; len(frames) < *max && sd.Context().Err() == nil; n++ {
+		img, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Error reading input: %v", err)
+		}
+		if n%*every != 0 {
+			img.Close()
+			continue
+		}
+		gray := gocv.NewMat()
+		gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+		mask := gocv.NewMat()
+		kp, descr := orb.DetectAndCompute(gray, mask)
+		mask.Close()
+		gray.Close()
+		frames = append(frames, frame{img: img, kp: kp, descr: descr})
+	}
+	if sd.Context().Err() != nil {
+		return nil
+	}
+	if len(frames) < 2 {
+		return fmt.Errorf("At least 2 images are needed, got %d", len(frames))
+	}
+	logging.Infof("Stitching %d images", len(frames))
+
+	// Transforms between neighbours, from each image to the previous one
+	toPrev := make([]homography, len(frames))
+	bf := gocv.NewBFMatcher()
+	defer bf.Close()
+	for i := 1; i < len(frames); i++ {
+		matches := detection.RatioTest(bf.KnnMatch(frames[i-1].descr, frames[i].descr, 2), detection.DefaultDistFactor)
+		if len(matches) < *minMatches {
+			return fmt.Errorf("Image %d does not overlap the previous one: %d matches", i, len(matches))
+		}
+		h := detection.MatchHomography(frames[i].kp, frames[i-1].kp, matches)
+		if h.Empty() {
+			h.Close()
+			return fmt.Errorf("Cannot align image %d with the previous one", i)
+		}
+		toPrev[i] = fromMat(h)
+		h.Close()
+		logging.Debugf("Image %d: %d matches", i, len(matches))
+	}
+
+	toRef, err := composeToReference(toPrev, len(frames)/2)
+	if err != nil {
+		return err
+	}
+	pano, err := warpAll(frames, toRef)
+	if err != nil {
+		return err
+	}
+	defer pano.Close()
+
+	if !gocv.IMWrite(*out, pano) {
+		return fmt.Errorf("Cannot write panorama to %s", *out)
+	}
+	logging.Infof("Panorama %dx%d saved to %s", pano.Cols(), pano.Rows(), *out)
+
+	if !headless.Enabled() {
+		window := gocv.NewWindow("Panorama - press any key to exit")
+		defer window.Close()
+		window.ResizeWindow(pano.Cols(), pano.Rows())
+		window.IMShow(pano)
+		videoio.WaitForKey(sd.Context(), window)
+	}
+	return nil
+}
+
+// composeToReference chains the transforms between neighbours into transforms of each image
+// to the reference one. toPrev[i] maps image i to image i-1, toPrev[0] is not used
+func composeToReference(toPrev []homography, ref int) ([]homography, error) {
+	toRef := make([]homography, len(toPrev))
+	toRef[ref] = identity
+	for i := ref + 1; i < len(toPrev); i++ {
+		toRef[i] = toRef[i-1].mul(toPrev[i])
+	}
+	for i := ref - 1; i >= 0; i-- {
+		inv, ok := toPrev[i+1].inverse()
+		if !ok {
+			return nil, fmt.Errorf("Cannot align image %d with the next one", i)
+		}
+		toRef[i] = toRef[i+1].mul(inv)
+	}
+	return toRef, nil
+}
+
+// Projects all images on a canvas covering all of them
+func warpAll(frames []frame, toRef []homography) (gocv.Mat, error) {
+	var canvas image.Rectangle
+	for i, f := range frames {
+		canvas = canvas.Union(toRef[i].bounds(image.Pt(f.img.Cols(), f.img.Rows())))
+	}
+	if canvas.Dx() > maxSide || canvas.Dy() > maxSide {
+		return gocv.Mat{}, fmt.Errorf("Panorama is too large (%dx%d), images may be aligned wrong", canvas.Dx(), canvas.Dy())
+	}
+
+	size := canvas.Size()
+	pano := gocv.NewMatWithSize(size.Y, size.X, frames[0].img.Type())
+	pano.SetTo(gocv.NewScalar(0, 0, 0, 0))
+	shift := translation(float64(-canvas.Min.X), float64(-canvas.Min.Y))
+	warped, mask := gocv.NewMat(), gocv.NewMat()
+	defer warped.Close()
+	defer mask.Close()
+	for i, f := range frames {
+		h := shift.mul(toRef[i]).mat()
+		gocv.WarpPerspective(f.img, &warped, h, size)
+		full := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(255, 0, 0, 0), f.img.Rows(), f.img.Cols(), gocv.MatTypeCV8U)
+		gocv.WarpPerspective(full, &mask, h, size)
+		full.Close()
+		h.Close()
+		warped.CopyToWithMask(&pano, mask)
+	}
+	return pano, nil
+}
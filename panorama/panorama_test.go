@@ -0,0 +1,48 @@
+package panorama
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestHomographyInverse(t *testing.T) {
+	h := homography{1.2, 0.1, 30, -0.05, 0.9, 12, 0.0001, 0.0002, 1}
+	inv, ok := h.inverse()
+	if !ok {
+		t.Fatal("Expected an invertible transform")
+	}
+	for i, v := range h.mul(inv) {
+		if math.Abs(v-identity[i]) > 1e-9 {
+			t.Fatalf("Expected identity, got %v", h.mul(inv))
+		}
+	}
+	if _, ok := (homography{}).inverse(); ok {
+		t.Error("Expected zero transform to be singular")
+	}
+}
+
+func TestHomographyCompose(t *testing.T) {
+	// Scale by 2, then move by 10, 20
+	h := translation(10, 20).mul(homography{2, 0, 0, 0, 2, 0, 0, 0, 1})
+	if x, y := h.apply(3, 4); x != 16 || y != 28 {
+		t.Errorf("Expected (16, 28), got (%v, %v)", x, y)
+	}
+	if got := h.bounds(image.Pt(100, 50)); got != image.Rect(10, 20, 210, 120) {
+		t.Errorf("Unexpected bounds %v", got)
+	}
+}
+
+func TestComposeToReference(t *testing.T) {
+	// Each image is 100 px right of the previous one
+	toPrev := []homography{{}, translation(100, 0), translation(100, 0), translation(100, 0)}
+	toRef, err := composeToReference(toPrev, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []float64{-200, -100, 0, 100} {
+		if x, _ := toRef[i].apply(0, 0); math.Abs(x-want) > 1e-9 {
+			t.Errorf("Image %d: expected origin at %v, got %v", i, want, x)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package portrait
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/marchevska/gocv-examples/internal/ocl"
+	"gocv.io/x/gocv"
+)
+
+// personProbability returns the probability of the person class for each pixel of planar network
+// output, one plane per class. Scores of several classes go through softmax; a single plane is taken
+// as the probability itself, or as logits if it has values outside [0, 1]
+func personProbability(data []float32, classes, plane, person int) []float32 {
+	probs := make([]float32, plane)
+	if classes == 1 {
+		logits := false
+		for _, v := range data[:plane] {
+			if v < 0 || v > 1 {
+				logits = true
+				break
+			}
+		}
+		for i, v := range data[:plane] {
+			if logits {
+				v = float32(1 / (1 + math.Exp(-float64(v))))
+			}
+			probs[i] = v
+		}
+		return probs
+	}
+	for i := range probs {
+		max := data[i]
+		for c := 1; c < classes; c++ {
+			if s := data[c*plane+i]; s > max {
+				max = s
+			}
+		}
+		// Subtracting the maximum keeps exp from overflowing
+		var sum, p float64
+		for c := 0; c < classes; c++ {
+			e := math.Exp(float64(data[c*plane+i] - max))
+			sum += e
+			if c == person {
+				p = e
+			}
+		}
+		probs[i] = float32(p / sum)
+	}
+	return probs
+}
+
+// personMask returns the person probability map of the network output as a CV_32F Mat of the output
+// size, to be closed by the caller. Output can be 1xCxHxW class scores or 1x1xHxW, 1xHxW probabilities
+func personMask(out gocv.Mat, person int) (gocv.Mat, error) {
+	dims := out.Size()
+	var classes, h, w int
+	switch len(dims) {
+	case 4:
+		classes, h, w = dims[1], dims[2], dims[3]
+	case 3:
+		classes, h, w = 1, dims[1], dims[2]
+	default:
+		return gocv.Mat{}, fmt.Errorf("Unexpected output shape %v", dims)
+	}
+	if classes > 1 && (person < 0 || person >= classes) {
+		return gocv.Mat{}, fmt.Errorf("Person class %d is not among %d output classes", person, classes)
+	}
+	data, err := out.DataPtrFloat32()
+	if err != nil {
+		return gocv.Mat{}, err
+	}
+	if len(data) < classes*h*w {
+		return gocv.Mat{}, fmt.Errorf("Output has %d values, expected %d", len(data), classes*h*w)
+	}
+
+	mask := gocv.NewMatWithSize(h, w, gocv.MatTypeCV32F)
+	dst, err := mask.DataPtrFloat32()
+	if err != nil {
+		mask.Close()
+		return gocv.Mat{}, err
+	}
+	copy(dst, personProbability(data, classes, h*w, person))
+	return mask, nil
+}
+
+// Matte turns person probability maps into a soft alpha matte of the frame size and composites
+// the person over a background
+type Matte struct {
+	Smooth  float64 // Weight of the previous matte, from 0 for none to below 1, reduces flicker
+	Feather int     // Size of the matte edge blur in pixels, 0 for a hard edge
+
+	alpha, prev, alpha3, fg, bg, diff gocv.Mat
+}
+
+// NewMatte creates a matte, which should be closed after use
+func NewMatte(smooth float64, feather int) *Matte {
+	return &Matte{Smooth: smooth, Feather: feather, alpha: gocv.NewMat(), prev: gocv.NewMat(),
+		alpha3: gocv.NewMat(), fg: gocv.NewMat(), bg: gocv.NewMat(), diff: gocv.NewMat()}
+}
+
+// Update scales the probability map to the frame size and blends it with the previous matte.
+// Returns the matte, 1 on the person, valid until the next call
+func (m *Matte) Update(probs gocv.Mat, size image.Point) gocv.Mat {
+	ocl.Resize(probs, &m.alpha, size, 0, 0, gocv.InterpolationLinear)
+	if m.Feather > 0 {
+		k := m.Feather | 1
+		ocl.GaussianBlur(m.alpha, &m.alpha, image.Pt(k, k), 0, 0, gocv.BorderReflect101)
+	}
+	if m.Smooth > 0 && !m.prev.Empty() && m.prev.Cols() == size.X && m.prev.Rows() == size.Y {
+		gocv.AddWeighted(m.prev, m.Smooth, m.alpha, 1-m.Smooth, 0, &m.alpha)
+	}
+	m.alpha.CopyTo(&m.prev)
+	return m.alpha
+}
+
+// Composite blends the image over the background of the same size with the last matte
+func (m *Matte) Composite(img *gocv.Mat, background gocv.Mat) {
+	// img = background + (img - background) * alpha, in floating point
+	gocv.Merge([]gocv.Mat{m.alpha, m.alpha, m.alpha}, &m.alpha3)
+	img.ConvertTo(&m.fg, gocv.MatTypeCV32FC3)
+	background.ConvertTo(&m.bg, gocv.MatTypeCV32FC3)
+	gocv.Subtract(m.fg, m.bg, &m.diff)
+	gocv.Multiply(m.diff, m.alpha3, &m.diff)
+	gocv.Add(m.bg, m.diff, &m.fg)
+	m.fg.ConvertTo(img, gocv.MatTypeCV8UC3)
+}
+
+// Close releases the buffers
+func (m *Matte) Close() error {
+	m.prev.Close()
+	m.alpha3.Close()
+	m.fg.Close()
+	m.bg.Close()
+	m.diff.Close()
+	return m.alpha.Close()
+}
@@ -0,0 +1,28 @@
+package portrait
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPersonProbability(t *testing.T) {
+	near := func(a, b float32) bool { return math.Abs(float64(a-b)) < 1e-5 }
+
+	// 2 classes, 3 pixels: equal scores, person far ahead, background far ahead
+	probs := personProbability([]float32{1, 0, 10, 1, 10, 0}, 2, 3, 1)
+	if !near(probs[0], 0.5) || probs[1] < 0.99 || probs[2] > 0.01 {
+		t.Errorf("Softmax probabilities %v", probs)
+	}
+
+	// Single plane of probabilities is kept
+	probs = personProbability([]float32{0, 0.25, 1}, 1, 3, 0)
+	if probs[0] != 0 || probs[1] != 0.25 || probs[2] != 1 {
+		t.Errorf("Probabilities %v, want unchanged", probs)
+	}
+
+	// Single plane of logits goes through sigmoid
+	probs = personProbability([]float32{-20, 0, 20}, 1, 3, 0)
+	if probs[0] > 1e-6 || !near(probs[1], 0.5) || probs[2] < 1-1e-6 {
+		t.Errorf("Sigmoid of logits %v", probs)
+	}
+}
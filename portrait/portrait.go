@@ -0,0 +1,234 @@
+// This example separates the person from the background with a segmentation network and blurs
+// or replaces the background, the effect of video calls
+//
+// Call: gocv-examples portrait -model model.onnx [-input 0] [-background beach.jpg] [-blur 41]
+// Input can be a camera ID (default 0), a video file, a stream URL or images. Without -background the
+// background is blurred, otherwise it is replaced with an image, a video file, which is looped,
+// a camera ID or a stream URL, as in the chroma-key example
+// +/- keys change the blur strength, B switches between blur and replacement, M shows the matte
+// With -opencl, the background blur and the matte run with OpenCL when OpenCV has a device, see internal/ocl
+// Parameters can also be set with PORTRAIT_* environment variables or a config file, see internal/config
+//
+// Models are not downloaded, any network with output 1xCxHxW of class scores, or 1x1xHxW of person
+// probabilities or logits, can be used with matching preprocessing flags, e.g.
+//   DeepLab v3 ONNX (Pascal VOC, person is class 15): the defaults
+//   Selfie segmentation ONNX (one plane): -size 256x256 -scale 0.003921 -mean 0
+// Pascal VOC models segment a person in a room well but are slow on a CPU; selfie segmentation
+// models are made for webcams and run in real time at a smaller input.
+// The matte is feathered at the edges and smoothed over frames with -smooth to reduce flicker
+
+package portrait
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+
+	"github.com/marchevska/gocv-examples/chromakey"
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/models"
+	"github.com/marchevska/gocv-examples/internal/ocl"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"github.com/marchevska/gocv-examples/segmentation"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID        = "0" // Default input
+	netSize      = "513x513"
+	netScale     = 1.0 / 127.5
+	netMean      = "127.5"
+	personClass  = 15 // Pascal VOC
+	blurSize     = 41
+	blurStep     = 10 // Change of the blur size by +/- keys
+	maxBlurSize  = 201
+	blurDownsize = 4 // The background is blurred at a lower resolution, which is much faster for large kernels
+	smoothing    = 0.5
+	featherSize  = 7
+	fillColor    = "0,128,0"
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Run blurs or replaces the background on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples portrait", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	model := fs.String("model", "", "Segmentation model: ONNX, Torch, TensorFlow or Caffe file")
+	modelConfig := fs.String("model-config", "", "Model config file, for frameworks which need one")
+	sizeStr := fs.String("size", netSize, "Network input size WIDTHxHEIGHT")
+	scale := fs.Float64("scale", netScale, "Multiplier of pixel values")
+	meanStr := fs.String("mean", netMean, "Mean subtracted from B,G,R values before scaling")
+	swapRB := fs.Bool("swap-rb", true, "Pass the image as RGB")
+	person := fs.Int("person-class", personClass, "Class ID of a person in the model output with several classes")
+	bgInput := fs.String("background", "", "Background image, video file, camera ID or stream URL; the background is blurred without it")
+	colorStr := fs.String("color", fillColor, "Background color B,G,R when replacement is switched on without a background")
+	blur := fs.Int("blur", blurSize, fmt.Sprintf("Size of the background blur in pixels, up to %d", maxBlurSize))
+	smooth := fs.Float64("smooth", smoothing, "Weight of the previous frame in the matte, from 0 to below 1")
+	feather := fs.Int("feather", featherSize, "Matte edge blur in pixels, 0 for a hard edge")
+	logging.RegisterFlags(fs)
+	ocl.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "PORTRAIT"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("portrait")
+
+	if *model == "" {
+		return errors.New("Model is required, see 'gocv-examples portrait -h'")
+	}
+	size, err := segmentation.ParseSize(*sizeStr)
+	if err != nil {
+		return err
+	}
+	mean, err := segmentation.ParseMean(*meanStr)
+	if err != nil {
+		return err
+	}
+	fill, err := chromakey.ParseColor(*colorStr)
+	if err != nil {
+		return err
+	}
+	if *blur < 1 || *blur > maxBlurSize {
+		return fmt.Errorf("Blur size should be from 1 to %d", maxBlurSize)
+	}
+	if *smooth < 0 || *smooth >= 1 || *feather < 0 {
+		return errors.New("Smoothing should be from 0 to below 1 and feather not negative")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{DNN: true, Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+	ocl.Setup()
+	defer ocl.Report()
+
+	modelFile, err := models.Resolve(*model)
+	if err != nil {
+		return fmt.Errorf("Error loading model: %v", err)
+	}
+	configFile := ""
+	if *modelConfig != "" {
+		if configFile, err = models.Resolve(*modelConfig); err != nil {
+			return fmt.Errorf("Error loading model config: %v", err)
+		}
+	}
+	net := gocv.ReadNet(modelFile, configFile)
+	if net.Empty() {
+		return errors.New("Error loading model")
+	}
+	sd.OnClose("model", net.Close)
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+	bg, err := chromakey.OpenBackground(*bgInput, fill)
+	if err != nil {
+		return fmt.Errorf("Error opening background: %v", err)
+	}
+	sd.OnClose("background", bg.Close)
+
+	matte := NewMatte(*smooth, *feather)
+	sd.OnClose("matte", matte.Close)
+
+	window, sink, err := outputs.Open(sd, "Portrait background")
+	if err != nil {
+		return err
+	}
+	replace, showMatte := *bgInput != "", false
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			*blur += step * blurStep
+			if *blur < 1 {
+				*blur = 1
+			}
+			if *blur > maxBlurSize {
+				*blur = maxBlurSize
+			}
+			return fmt.Sprintf("blur %d px", *blur)
+		}
+		window.Controls.Keys = append(window.Controls.Keys,
+			videoio.KeyAction{Key: 'b', Help: "blur or replace background", Do: func() { replace = !replace }},
+			videoio.KeyAction{Key: 'm', Help: "show matte", Do: func() { showMatte = !showMatte }})
+	}
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	background := gocv.NewMat()
+	defer background.Close()
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("inference")
+		blob := gocv.BlobFromImage(*img, *scale, size, mean, *swapRB, false)
+		net.SetInput(blob, "")
+		out := net.Forward("")
+		blob.Close()
+		stop()
+
+		stop = stats.Start("composite")
+		probs, err := personMask(out, *person)
+		out.Close()
+		if err != nil {
+			logging.Errorf("Error decoding output: %v", err)
+			stop()
+			return
+		}
+		frameSize := image.Pt(img.Cols(), img.Rows())
+		alpha := matte.Update(probs, frameSize)
+		probs.Close()
+		switch {
+		case showMatte:
+			alpha.ConvertToWithParams(img, gocv.MatTypeCV8U, 255, 0)
+			gocv.CvtColor(*img, img, gocv.ColorGrayToBGR)
+		case replace:
+			matte.Composite(img, bg.Next(frameSize))
+		default:
+			blurBackground(*img, &background, *blur)
+			matte.Composite(img, background)
+		}
+		stop()
+		stats.Frame()
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}
+
+// Blurs the image into dst with a Gaussian of about size pixels, applied at a lower resolution
+func blurBackground(img gocv.Mat, dst *gocv.Mat, size int) {
+	small := gocv.NewMat()
+	defer small.Close()
+	ocl.Resize(img, &small, image.Pt(img.Cols()/blurDownsize+1, img.Rows()/blurDownsize+1), 0, 0, gocv.InterpolationArea)
+	k := (size / blurDownsize) | 1
+	ocl.GaussianBlur(small, &small, image.Pt(k, k), 0, 0, gocv.BorderReflect101)
+	ocl.Resize(small, dst, image.Pt(img.Cols(), img.Rows()), 0, 0, gocv.InterpolationLinear)
+}
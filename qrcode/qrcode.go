@@ -0,0 +1,156 @@
+// This example finds and decodes QR codes, drawing the outline and the payload of each code
+//
+// Call: gocv-examples qr [-input 0] [-open-url]
+// Input can be a camera ID (default 0), a video file, a stream URL or images
+// Each new payload is logged once. With -open-url, web links are opened in the default browser,
+// each link only once per run
+// Parameters can also be set with QR_* environment variables or a config file, see internal/config
+
+package qrcode
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"runtime"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID       = "0"           // Default input
+	undecoded   = "(undecoded)" // Label of codes which were found but not decoded
+	maxLabelLen = 60
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// isWebURL reports whether the payload is an http or https link
+func isWebURL(payload string) bool {
+	u, err := url.Parse(payload)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// Opens the link in the default browser
+func openURL(link string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", link)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", link)
+	default:
+		cmd = exec.Command("xdg-open", link)
+	}
+	return cmd.Start()
+}
+
+// Shortens long payloads for drawing
+func shorten(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-3]) + "..."
+}
+
+// Run finds QR codes on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples qr", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	open := fs.Bool("open-url", false, "Open decoded web links in the default browser")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "QR"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("qr")
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	qr := gocv.NewQRCodeDetector()
+	sd.OnClose("detector", qr.Close)
+	var detector detection.Detector = &detection.QRDetector{Detector: &qr}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "QR codes")
+	if err != nil {
+		return err
+	}
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	seen := map[string]bool{}
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("qr")
+		dets, err := detector.Detect(*img)
+		stop()
+		stats.Frame()
+		if err != nil {
+			logging.Errorf("Error detecting QR codes: %v", err)
+		}
+
+		for _, d := range dets {
+			label := d.Label
+			if label == "" {
+				label = undecoded
+			} else if !seen[label] {
+				seen[label] = true
+				logging.Infof("QR code: %s", label)
+				if *open && isWebURL(label) {
+					if err := openURL(label); err != nil {
+						logging.Errorf("Error opening %s: %v", label, err)
+					}
+				}
+			}
+			st := draw.DefaultStyle
+			if d.Label == "" {
+				st = st.WithColor(draw.Red)
+			}
+			draw.Outline(img, d.Quad, st)
+			draw.TextWithBackground(img, shorten(label, maxLabelLen), d.BBox.Min, st)
+		}
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}
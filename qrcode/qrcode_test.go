@@ -0,0 +1,31 @@
+package qrcode
+
+import "testing"
+
+func TestIsWebURL(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    bool
+	}{
+		{"https://gocv.io", true},
+		{"http://example.com/path?q=1", true},
+		{"WIFI:S:home;T:WPA;P:secret;;", false},
+		{"javascript:alert(1)", false},
+		{"file:///etc/passwd", false},
+		{"just text", false},
+	}
+	for _, tt := range tests {
+		if got := isWebURL(tt.payload); got != tt.want {
+			t.Errorf("isWebURL(%q) = %v, want %v", tt.payload, got, tt.want)
+		}
+	}
+}
+
+func TestShorten(t *testing.T) {
+	if got := shorten("short", 10); got != "short" {
+		t.Errorf("Expected unchanged text, got %q", got)
+	}
+	if got := shorten("a rather long payload", 10); got != "a rathe..." {
+		t.Errorf("Expected shortened text, got %q", got)
+	}
+}
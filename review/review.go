@@ -0,0 +1,348 @@
+// Review tool for detections exported by the examples with -export: shows each frame of the
+// input with its detections, lets the user hide classes and mark false positives, which are saved
+// to a corrections file in the same JSON lines format, see detection.FrameDetections
+//
+// Call: gocv-examples review -input video.mp4 -detections detections.jsonl [-corrections file.jsonl]
+// Input should be the one the detections were exported from: a video file, a directory or a glob
+// pattern of images. Marks saved earlier to the corrections file are loaded on start
+//
+// Keys: n/space next frame, p previous frame, Tab select next detection, x mark/unmark
+// the selected detection as false positive, 1-9 show/hide class, s save, q/Esc save and quit
+
+package review
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	correctionsPath = "corrections.jsonl"
+	keyTab          = 9
+	keyEsc          = 27
+	helpStr         = "n/p: frame  Tab: select  x: false positive  1-9: class  s: save  q: quit"
+)
+
+// Styles of detections depending on their state
+var (
+	fpStyle    = draw.DefaultStyle.WithColor(draw.Red)
+	selFPStyle = withLineWidth(fpStyle, 3)
+)
+
+func withLineWidth(st draw.Style, w int) draw.Style {
+	st.LineThickness = w
+	return st
+}
+
+// reviewer holds the state of the review
+type reviewer struct {
+	input    string
+	src      videoio.FrameSource
+	read     int      // Number of frames read from src
+	img      gocv.Mat // Current frame
+	frame    int      // Index of the current frame
+	dets     map[int][]detection.Detection
+	classes  []string
+	hidden   map[string]bool
+	selected int                  // Index of the selected detection on the current frame, -1 for none
+	falsePos map[int]map[int]bool // Detections marked as false positives, by frame and index
+}
+
+// Run starts the review of the detections given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples review", flag.ExitOnError)
+	input := fs.String("input", "", "Video file, directory or glob pattern of images the detections were exported from")
+	detPath := fs.String("detections", "", "Detections exported with -export")
+	corrPath := fs.String("corrections", correctionsPath, "File to save false positives to")
+	logging.RegisterFlags(fs)
+	if err := config.Load(fs, args, "REVIEW"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("review")
+	if *input == "" || *detPath == "" {
+		fs.Usage()
+		return errors.New("Both -input and -detections are required")
+	}
+	if probe.CameraID(*input) >= 0 || videoio.IsStreamURL(*input) {
+		return errors.New("Review needs a video file or images, live inputs cannot be stepped through")
+	}
+	if !videoio.HasDisplay() {
+		return errors.New("Review needs a display")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	frames, err := detection.ReadJSON(*detPath)
+	if err != nil {
+		return fmt.Errorf("Error loading detections: %v", err)
+	}
+	r := newReviewer(*input, frames)
+	if _, err := os.Stat(*corrPath); err == nil {
+		corrections, err := detection.ReadJSON(*corrPath)
+		if err != nil {
+			return fmt.Errorf("Error loading corrections: %v", err)
+		}
+		r.loadCorrections(corrections)
+	}
+	logging.Infof("Loaded detections of %d frames, classes: %v", len(frames), r.classes)
+
+	sd.OnClose("input", r.Close)
+	if err := r.seek(0); err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	window := gocv.NewWindow("Review " + *detPath)
+	sd.OnClose("window", window.Close)
+
+	for sd.Context().Err() == nil {
+		show := r.render()
+		window.IMShow(show)
+		show.Close()
+
+		key := window.WaitKey(50)
+		for key < 0 && sd.Context().Err() == nil {
+			key = window.WaitKey(50)
+		}
+		switch {
+		case key == 'n' || key == ' ':
+			if err := r.seek(r.frame + 1); err == io.EOF {
+				logging.Infof("Last frame")
+			} else if err != nil {
+				return fmt.Errorf("Error reading input: %v", err)
+			}
+		case key == 'p' && r.frame > 0:
+			if err := r.seek(r.frame - 1); err != nil {
+				return fmt.Errorf("Error reading input: %v", err)
+			}
+		case key == keyTab:
+			r.selectNext()
+		case key == 'x':
+			r.toggleFalsePositive()
+		case key >= '1' && key <= '9':
+			if i := key - '1'; i < len(r.classes) {
+				r.hidden[r.classes[i]] = !r.hidden[r.classes[i]]
+				if r.selected >= 0 && r.hidden[r.current()[r.selected].Label] {
+					r.selected = -1
+				}
+			}
+		case key == 's':
+			if err := r.save(*corrPath); err != nil {
+				logging.Errorf("Error saving corrections: %v", err)
+			}
+		case key == 'q' || key == keyEsc:
+			return r.save(*corrPath)
+		}
+	}
+	return r.save(*corrPath)
+}
+
+func newReviewer(input string, frames []detection.FrameDetections) *reviewer {
+	r := &reviewer{
+		input:    input,
+		dets:     map[int][]detection.Detection{},
+		hidden:   map[string]bool{},
+		selected: -1,
+		falsePos: map[int]map[int]bool{},
+	}
+	seen := map[string]bool{}
+	for _, fd := range frames {
+		r.dets[fd.Frame] = append(r.dets[fd.Frame], fd.Detections...)
+		for _, d := range fd.Detections {
+			if !seen[d.Label] {
+				seen[d.Label] = true
+				r.classes = append(r.classes, d.Label)
+			}
+		}
+	}
+	sort.Strings(r.classes)
+	return r
+}
+
+// Marks detections which are found in the corrections
+func (r *reviewer) loadCorrections(corrections []detection.FrameDetections) {
+	for _, fd := range corrections {
+		for _, c := range fd.Detections {
+			for i, d := range r.dets[fd.Frame] {
+				if d.Label == c.Label && d.BBox == c.BBox {
+					r.setFalsePositive(fd.Frame, i, true)
+				}
+			}
+		}
+	}
+}
+
+// seek makes frame n current. Going back reopens the input, so that frame numbers are the same
+// as when the detections were exported
+func (r *reviewer) seek(n int) error {
+	if r.src == nil || n < r.read {
+		if r.src != nil {
+			r.src.Close()
+			r.src = nil
+		}
+		src, err := videoio.OpenSource(r.input, 0, 0)
+		if err != nil {
+			return err
+		}
+		r.src, r.read = src, 0
+	}
+	for r.read <= n {
+		img, err := r.src.Next()
+		if err != nil {
+			return err
+		}
+		r.read++
+		if r.read <= n {
+			img.Close()
+			continue
+		}
+		r.closeImg()
+		r.img = img
+	}
+	r.frame = n
+	r.selected = -1
+	return nil
+}
+
+// Close releases the input and the current frame
+func (r *reviewer) Close() error {
+	r.closeImg()
+	if r.src != nil {
+		return r.src.Close()
+	}
+	return nil
+}
+
+func (r *reviewer) closeImg() {
+	if r.img.Ptr() != nil {
+		r.img.Close()
+	}
+}
+
+func (r *reviewer) current() []detection.Detection {
+	return r.dets[r.frame]
+}
+
+// Selects the next detection of a visible class
+func (r *reviewer) selectNext() {
+	dets := r.current()
+	for k := 1; k <= len(dets); k++ {
+		i := (r.selected + k) % len(dets)
+		if i < 0 {
+			i += len(dets)
+		}
+		if !r.hidden[dets[i].Label] {
+			r.selected = i
+			return
+		}
+	}
+	r.selected = -1
+}
+
+func (r *reviewer) toggleFalsePositive() {
+	if r.selected < 0 {
+		return
+	}
+	r.setFalsePositive(r.frame, r.selected, !r.falsePos[r.frame][r.selected])
+}
+
+func (r *reviewer) setFalsePositive(frame, i int, on bool) {
+	if r.falsePos[frame] == nil {
+		r.falsePos[frame] = map[int]bool{}
+	}
+	if on {
+		r.falsePos[frame][i] = true
+	} else {
+		delete(r.falsePos[frame], i)
+	}
+}
+
+// Returns a copy of the current frame with visible detections, the class legend and help
+func (r *reviewer) render() gocv.Mat {
+	img := r.img.Clone()
+	nFP := 0
+	for i, d := range r.current() {
+		if r.hidden[d.Label] {
+			continue
+		}
+		fp := r.falsePos[r.frame][i]
+		st := draw.DefaultStyle.WithColor(draw.LabelColor(d.Label))
+		switch {
+		case fp && i == r.selected:
+			st = selFPStyle
+		case fp:
+			st = fpStyle
+		case i == r.selected:
+			st = withLineWidth(st, 3)
+		}
+		if fp {
+			nFP++
+		}
+		draw.LabelBox(&img, d.BBox, fmt.Sprintf("%s %.0f%%", d.Label, d.Confidence*100), st)
+		draw.Outline(&img, d.Quad, st)
+	}
+
+	var legend []draw.LegendEntry
+	for i, c := range r.classes {
+		e := draw.LegendEntry{Color: draw.LabelColor(c), Text: fmt.Sprintf("%d %s", i+1, c)}
+		if r.hidden[c] {
+			e.Color, e.Text = draw.Black, e.Text+" (hidden)"
+		}
+		legend = append(legend, e)
+	}
+	draw.Legend(&img, legend, image.Pt(0, 0), draw.DefaultStyle)
+
+	status := fmt.Sprintf("Frame %d: %d detections, %d false positives", r.frame, len(r.current()), nFP)
+	h := draw.DefaultStyle.TextSize(helpStr).Y + 2*draw.DefaultStyle.Padding
+	draw.TextWithBackground(&img, status, image.Pt(0, img.Rows()-h), draw.DefaultStyle)
+	draw.TextWithBackground(&img, helpStr, image.Pt(0, img.Rows()), draw.DefaultStyle)
+	return img
+}
+
+// Saves detections marked as false positives, ordered by frame
+func (r *reviewer) save(path string) error {
+	var frames []int
+	for f, marks := range r.falsePos {
+		if len(marks) > 0 {
+			frames = append(frames, f)
+		}
+	}
+	sort.Ints(frames)
+
+	jw, err := detection.NewJSONWriter(path)
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, f := range frames {
+		fd := detection.FrameDetections{Frame: f}
+		for i, d := range r.dets[f] {
+			if r.falsePos[f][i] {
+				fd.Detections = append(fd.Detections, d)
+			}
+		}
+		n += len(fd.Detections)
+		if err := jw.Write(fd); err != nil {
+			jw.Close()
+			return err
+		}
+	}
+	if err := jw.Close(); err != nil {
+		return err
+	}
+	logging.Infof("Saved %d false positives to %s", n, path)
+	return nil
+}
@@ -0,0 +1,58 @@
+package review
+
+import (
+	"image"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/marchevska/gocv-examples/internal/detection"
+)
+
+var testFrames = []detection.FrameDetections{
+	{Frame: 0, Detections: []detection.Detection{
+		{Label: "person", Confidence: 0.9, BBox: image.Rect(0, 0, 10, 20)},
+		{Label: "dog", Confidence: 0.6, BBox: image.Rect(20, 0, 30, 10)},
+	}},
+	{Frame: 2, Detections: []detection.Detection{
+		{Label: "person", Confidence: 0.7, BBox: image.Rect(5, 5, 15, 25)},
+	}},
+}
+
+func TestCorrectionsRoundTrip(t *testing.T) {
+	r := newReviewer("unused", testFrames)
+	if !reflect.DeepEqual(r.classes, []string{"dog", "person"}) {
+		t.Fatalf("Unexpected classes %v", r.classes)
+	}
+
+	// Hidden classes are skipped by selection
+	r.hidden["person"] = true
+	r.selectNext()
+	r.toggleFalsePositive()
+	r.frame = 2
+	r.hidden["person"] = false
+	r.selectNext()
+	r.toggleFalsePositive()
+
+	path := filepath.Join(t.TempDir(), "corrections.jsonl")
+	if err := r.save(path); err != nil {
+		t.Fatal(err)
+	}
+	corrections, err := detection.ReadJSON(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []detection.FrameDetections{
+		{Frame: 0, Detections: testFrames[0].Detections[1:]},
+		{Frame: 2, Detections: testFrames[1].Detections},
+	}
+	if !reflect.DeepEqual(corrections, want) {
+		t.Fatalf("Expected %v, got %v", want, corrections)
+	}
+
+	r2 := newReviewer("unused", testFrames)
+	r2.loadCorrections(corrections)
+	if !reflect.DeepEqual(r2.falsePos, r.falsePos) {
+		t.Errorf("Expected marks %v, got %v", r.falsePos, r2.falsePos)
+	}
+}
@@ -0,0 +1,248 @@
+// This example is a motion-triggered security recorder: it watches a camera with background
+// subtraction and records a clip of each motion event, including the moments before it
+//
+// Call: gocv-examples security [-input 0] [-dir recordings] [-pre-roll 3s] [-post-roll 5s] [-max-disk 1GB]
+// The last -pre-roll of frames is kept in memory. When there is motion on -sustain consecutive frames,
+// a clip named by the start time, e.g. recordings/20240101_120000.avi, is started with the buffered
+// frames, and it ends after -post-roll without motion. Every frame gets the current time burnt in.
+// After each clip, and at start, the oldest clips in -dir are removed while the clips take more than -max-disk
+// Parameters can also be set with SECURITY_* environment variables or a config file, see internal/config
+//
+// With -s3 s3://BUCKET, each clip is also uploaded to S3 or MinIO (-s3-endpoint) once recorded, keyed by
+// date, camera and "motion", see internal/storage; with -s3-remove only clips not uploaded yet stay in -dir
+//
+// With -alert rules on the "motion" label, e.g. -alert "motion 22:00-06:00 every 15m", motion is also
+// reported by email (-smtp, -mail-to) or webhooks with a snapshot and a clip of -alert-clip, see internal/notify
+//
+// Durations are converted to frames with -fps, which should match the camera so that clips play
+// at real speed. The pre-roll buffer takes width*height*3 bytes per frame, about 200 MB for 3s of 1080p at 25 fps
+
+package security
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/notify"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/storage"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"github.com/marchevska/gocv-examples/motion"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID     = "0" // Default input
+	minArea   = 500
+	areaStep  = 100 // Change of the minimal area by +/- keys
+	sustain   = 5
+	preRoll   = 3 * time.Second
+	postRoll  = 5 * time.Second
+	recDir    = "recordings"
+	maxDisk   = "1GB"
+	timestamp = "2006-01-02 15:04:05"
+)
+
+// Label of moving regions for alert rules
+const motionLabel = "motion"
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Run records motion events on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples security", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file or stream URL")
+	method := fs.String("method", "mog2", "Background subtraction method: mog2 or knn")
+	area := fs.Int("min-area", minArea, "Minimal area of a moving region in pixels")
+	sustainFrames := fs.Int("sustain", sustain, "Frames with motion to start recording")
+	pre := fs.Duration("pre-roll", preRoll, "Time recorded before the motion started")
+	post := fs.Duration("post-roll", postRoll, "Time without motion before recording stops")
+	dir := fs.String("dir", recDir, "Directory for recorded clips")
+	maxDiskStr := fs.String("max-disk", maxDisk, "Maximal total size of the clips in -dir, e.g. 500MB or 2GB")
+	fps := fs.Float64("fps", videoFPS, "Frame rate of the input and the clips")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	var alertOpts notify.Options
+	alertOpts.RegisterFlags(fs)
+	var storageOpts storage.Options
+	storageOpts.RegisterFlags(fs)
+	if err := config.Load(fs, args, "SECURITY"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("security")
+
+	capBytes, err := parseSize(*maxDiskStr)
+	if err != nil {
+		return err
+	}
+	if *sustainFrames < 1 || *fps <= 0 || *pre < 0 || *post <= 0 {
+		return errors.New("Sustain and frame rate should be positive, pre-roll not negative and post-roll positive")
+	}
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return fmt.Errorf("Error creating recording directory: %v", err)
+	}
+	if err := applyCap(*dir, capBytes); err != nil {
+		return err
+	}
+
+	detector, err := motion.NewDetector(*method, float64(*area))
+	if err != nil {
+		return err
+	}
+	defer detector.Close()
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: []string{videoCodec}, Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Security recorder")
+	if err != nil {
+		return err
+	}
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			detector.MinArea += float64(step * areaStep)
+			if detector.MinArea < areaStep {
+				detector.MinArea = areaStep
+			}
+			return fmt.Sprintf("min area %.0f px", detector.MinArea)
+		}
+	}
+
+	uploader, err := storageOpts.Open(sd, *input)
+	if err != nil {
+		return err
+	}
+	alerts, err := alertOpts.Open(sd, videoCodec, *fps)
+	if err != nil {
+		return err
+	}
+
+	buffer := videoio.NewPreRollBuffer(*pre, *fps)
+	defer buffer.Close()
+
+	// The clip of the current event, closed and uploaded when the event ends or on exit
+	var clip *videoio.VideoSink
+	var clipPath string
+	var started time.Time
+	closeClip := func() error {
+		if clip == nil {
+			return nil
+		}
+		err := clip.Close()
+		clip = nil
+		if err == nil && uploader != nil {
+			uploader.Upload(clipPath, motionLabel, started)
+		}
+		return err
+	}
+	sd.OnClose("clip", closeClip)
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	tr := motion.Trigger{Sustain: *sustainFrames, Cooldown: int(post.Seconds() * *fps)}
+	if tr.Cooldown < 1 {
+		tr.Cooldown = 1
+	}
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("subtraction")
+		regions := detector.Detect(*img)
+		stop()
+		stats.Frame()
+
+		now := time.Now()
+		st := draw.DefaultStyle
+		text := now.Format(timestamp)
+		draw.TextWithBackground(img, text, image.Pt(0, img.Rows()-st.Padding), st)
+
+		switch tr.Update(len(regions) > 0) {
+		case motion.Started:
+			started = now
+			clipPath = filepath.Join(*dir, now.Format("20060102_150405")+clipExt)
+			logging.Infof("Motion started, recording %s", clipPath)
+			clip = videoio.NewVideoSink(clipPath, videoCodec, *fps)
+			if err := buffer.Drain(clip); err != nil {
+				logging.Errorf("Error writing pre-roll: %v", err)
+			}
+		case motion.Ended:
+			logging.Infof("Motion ended after %v", now.Sub(started).Round(time.Second))
+			if err := closeClip(); err != nil {
+				logging.Errorf("Error closing clip %s: %v", clipPath, err)
+			}
+			if err := applyCap(*dir, capBytes); err != nil {
+				logging.Errorf("%v", err)
+			}
+		}
+
+		if clip != nil {
+			if err := clip.Write(*img); err != nil {
+				logging.Errorf("Error writing clip %s: %v", clipPath, err)
+				closeClip()
+			}
+		} else {
+			buffer.Push(*img)
+		}
+
+		for _, r := range regions {
+			gocv.Rectangle(img, r, draw.Red, st.LineThickness)
+		}
+		if alerts != nil {
+			dets := make([]detection.Detection, len(regions))
+			for i, r := range regions {
+				dets[i] = detection.Detection{Label: motionLabel, Confidence: 1, BBox: r}
+			}
+			alerts.Check(*img, dets)
+		}
+		if tr.Active() {
+			rst := st.WithColor(draw.Red)
+			draw.TextWithBackground(img, "REC", image.Pt(0, rst.TextSize("REC").Y+2*rst.Padding), rst)
+		}
+		metrics.Overlay(img, stats, st)
+	})
+	if err != nil && err != videoio.ErrStopped {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	return nil
+}
+
+// Removes the oldest clips over the disk cap and logs them
+func applyCap(dir string, max int64) error {
+	removed, err := enforceCap(dir, max)
+	for _, path := range removed {
+		logging.Infof("Disk cap reached, removed %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("Error enforcing disk cap: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package security
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Extension of the recorded clips, only these files are counted and removed by the disk cap
+const clipExt = ".avi"
+
+// Size units accepted by parseSize
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1},
+}
+
+// parseSize parses a size like "500MB" or "1.5GB", units are powers of 1024. A number without a unit is bytes
+func parseSize(s string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	mult := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(str, u.suffix) {
+			str, mult = strings.TrimSpace(strings.TrimSuffix(str, u.suffix)), u.bytes
+			break
+		}
+	}
+	v, err := strconv.ParseFloat(str, 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("Size should be a positive number with an optional unit B, KB, MB, GB or TB: %q", s)
+	}
+	return int64(v * float64(mult)), nil
+}
+
+// enforceCap removes the oldest clips in dir until their total size is at most max bytes
+// and returns the paths of the removed files. Clips are named by timestamp, so the oldest sort first
+func enforceCap(dir string, max int64) ([]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var clips []os.FileInfo
+	var total int64
+	for _, f := range files {
+		if f.Mode().IsRegular() && strings.EqualFold(filepath.Ext(f.Name()), clipExt) {
+			clips = append(clips, f)
+			total += f.Size()
+		}
+	}
+	sort.Slice(clips, func(i, j int) bool { return clips[i].Name() < clips[j].Name() })
+
+	var removed []string
+	for _, c := range clips {
+		if total <= max {
+			break
+		}
+		path := filepath.Join(dir, c.Name())
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		total -= c.Size()
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
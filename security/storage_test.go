@@ -0,0 +1,68 @@
+package security
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"100", 100},
+		{"10KB", 10 << 10},
+		{"500mb", 500 << 20},
+		{"1.5GB", 3 << 29},
+		{" 2 TB ", 2 << 40},
+	}
+	for _, tt := range tests {
+		got, err := parseSize(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parseSize(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+	for _, in := range []string{"", "GB", "-1MB", "0", "10XB"} {
+		if _, err := parseSize(in); err == nil {
+			t.Errorf("parseSize(%q) should fail", in)
+		}
+	}
+}
+
+func TestEnforceCap(t *testing.T) {
+	dir, err := ioutil.TempDir("", "security")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]int{
+		"20240101_100000.avi": 100,
+		"20240101_110000.avi": 100,
+		"20240101_120000.avi": 100,
+		"notes.txt":           1000,
+	}
+	for name, size := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := enforceCap(dir, 250)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || filepath.Base(removed[0]) != "20240101_100000.avi" {
+		t.Errorf("Removed %v, want the oldest clip only", removed)
+	}
+	left, _ := ioutil.ReadDir(dir)
+	if len(left) != 3 {
+		t.Errorf("%d files left, want 3", len(left))
+	}
+
+	if removed, _ = enforceCap(dir, 1000); len(removed) != 0 {
+		t.Errorf("Removed %v under the cap", removed)
+	}
+}
@@ -0,0 +1,111 @@
+package segmentation
+
+import (
+	"fmt"
+	"image"
+	"sort"
+
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"gocv.io/x/gocv"
+)
+
+// ClassMap returns the class ID of each pixel of the network output, row by row, and the size of the map.
+// Output can be 1xCxHxW class scores, or 1x1xHxW or 1xHxW class IDs
+func ClassMap(out gocv.Mat) ([]int, image.Point, error) {
+	dims := out.Size()
+	var classes, h, w int
+	switch len(dims) {
+	case 4:
+		classes, h, w = dims[1], dims[2], dims[3]
+	case 3:
+		classes, h, w = 1, dims[1], dims[2]
+	default:
+		return nil, image.Point{}, fmt.Errorf("Unexpected output shape %v", dims)
+	}
+
+	values := out
+	if out.Type() != gocv.MatTypeCV32F {
+		values = gocv.NewMat()
+		defer values.Close()
+		out.ConvertTo(&values, gocv.MatTypeCV32F)
+	}
+	data, err := values.DataPtrFloat32()
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+	if len(data) < classes*h*w {
+		return nil, image.Point{}, fmt.Errorf("Output has %d values, expected %d", len(data), classes*h*w)
+	}
+	if classes == 1 {
+		ids := make([]int, h*w)
+		for i := range ids {
+			ids[i] = int(data[i])
+		}
+		return ids, image.Pt(w, h), nil
+	}
+	return argmax(data, classes, h*w), image.Pt(w, h), nil
+}
+
+// argmax returns the class with the highest score for each pixel of planar scores, one plane per class
+func argmax(scores []float32, classes, plane int) []int {
+	ids := make([]int, plane)
+	for i := range ids {
+		best := scores[i]
+		for c := 1; c < classes; c++ {
+			if s := scores[c*plane+i]; s > best {
+				best, ids[i] = s, c
+			}
+		}
+	}
+	return ids
+}
+
+// colorize paints each pixel with its class color as BGR bytes, and returns a mask of the colored
+// pixels and the sorted list of classes found. Pixels of the ignored class are not colored
+func colorize(ids []int, ignore int) (bgr, mask []byte, present []int) {
+	bgr = make([]byte, 3*len(ids))
+	mask = make([]byte, len(ids))
+	found := map[int]bool{}
+	for i, id := range ids {
+		if id == ignore {
+			continue
+		}
+		c := draw.ClassColor(id)
+		bgr[3*i], bgr[3*i+1], bgr[3*i+2] = c.B, c.G, c.R
+		mask[i] = 255
+		if !found[id] {
+			found[id] = true
+			present = append(present, id)
+		}
+	}
+	sort.Ints(present)
+	return bgr, mask, present
+}
+
+// Blend draws the class map over the image with given opacity and returns the classes found
+func Blend(img *gocv.Mat, ids []int, size image.Point, ignore int, alpha float64) []int {
+	bgr, mask, present := colorize(ids, ignore)
+	if len(present) == 0 {
+		return nil
+	}
+	colors, err := gocv.NewMatFromBytes(size.Y, size.X, gocv.MatTypeCV8UC3, bgr)
+	if err != nil {
+		return nil
+	}
+	defer colors.Close()
+	maskMat, err := gocv.NewMatFromBytes(size.Y, size.X, gocv.MatTypeCV8U, mask)
+	if err != nil {
+		return nil
+	}
+	defer maskMat.Close()
+
+	// Nearest neighbour keeps class borders sharp
+	imgSize := image.Pt(img.Cols(), img.Rows())
+	gocv.Resize(colors, &colors, imgSize, 0, 0, gocv.InterpolationNearestNeighbor)
+	gocv.Resize(maskMat, &maskMat, imgSize, 0, 0, gocv.InterpolationNearestNeighbor)
+	blended := gocv.NewMat()
+	defer blended.Close()
+	gocv.AddWeighted(*img, 1-alpha, colors, alpha, 0, &blended)
+	blended.CopyToWithMask(img, maskMat)
+	return present
+}
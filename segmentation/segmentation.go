@@ -0,0 +1,222 @@
+// This example runs a semantic segmentation network and blends the colored class map over the input
+//
+// Call: gocv-examples segment -model model.onnx [-input 0] [-labels classes.txt] [-opacity 0.5]
+// Input can be a camera ID (default 0), a video file, a stream URL or images
+// Parameters can also be set with SEGMENT_* environment variables or a config file, see internal/config
+//
+// Models are not downloaded, any network with output 1xCxHxW of class scores, or 1xHxW of class IDs,
+// can be used with matching preprocessing flags, e.g.
+//   DeepLab v3 ONNX (Pascal VOC, 21 classes): -size 513x513 -scale 0.007843 -mean 127.5,127.5,127.5
+//   ENet Torch model (Cityscapes, 20 classes): -model enet-model.net -size 1024x512 -scale 0.003921
+//     https://github.com/e-lab/ENet-training/releases/download/v1.cs/model-best.net
+// Class labels are read from a text file, one per line. The class given by -ignore, background
+// by default, is not colored
+
+package segmentation
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/models"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID       = "0" // Default input
+	netSize     = "513x513"
+	opacity     = 0.5
+	opacityStep = 0.1 // Change of the opacity by +/- keys
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// ParseSize parses the network input size given as "WIDTHxHEIGHT"
+func ParseSize(s string) (image.Point, error) {
+	parts := strings.Split(strings.ToLower(s), "x")
+	if len(parts) == 2 {
+		w, errW := strconv.Atoi(parts[0])
+		h, errH := strconv.Atoi(parts[1])
+		if errW == nil && errH == nil && w > 0 && h > 0 {
+			return image.Pt(w, h), nil
+		}
+	}
+	return image.Point{}, fmt.Errorf("Size should be WIDTHxHEIGHT: %q", s)
+}
+
+// ParseMean parses the mean subtracted from the input channels, given as "B,G,R" or a single value
+func ParseMean(s string) (gocv.Scalar, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 1 && len(parts) != 3 {
+		return gocv.Scalar{}, fmt.Errorf("Mean should be a value or 3 values separated by commas: %q", s)
+	}
+	var v [3]float64
+	for i := range v {
+		p := parts[0]
+		if len(parts) == 3 {
+			p = parts[i]
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return gocv.Scalar{}, fmt.Errorf("Mean should be a value or 3 values separated by commas: %q", s)
+		}
+		v[i] = f
+	}
+	return gocv.NewScalar(v[0], v[1], v[2], 0), nil
+}
+
+// Run segments the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples segment", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	model := fs.String("model", "", "Segmentation model: ONNX, Torch, TensorFlow or Caffe file")
+	modelConfig := fs.String("model-config", "", "Model config file, for frameworks which need one")
+	labelsPath := fs.String("labels", "", "Class labels file, one label per line")
+	sizeStr := fs.String("size", netSize, "Network input size WIDTHxHEIGHT")
+	scale := fs.Float64("scale", 1.0/255, "Multiplier of pixel values")
+	meanStr := fs.String("mean", "0,0,0", "Mean subtracted from B,G,R values before scaling")
+	swapRB := fs.Bool("swap-rb", true, "Pass the image as RGB")
+	ignore := fs.Int("ignore", 0, "Class ID which is not colored, -1 to color all classes")
+	alpha := fs.Float64("opacity", opacity, "Opacity of the class colors, from 0 to 1")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "SEGMENT"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("segment")
+
+	if *model == "" {
+		return errors.New("Model is required, see 'gocv-examples segment -h'")
+	}
+	size, err := ParseSize(*sizeStr)
+	if err != nil {
+		return err
+	}
+	mean, err := ParseMean(*meanStr)
+	if err != nil {
+		return err
+	}
+	if *alpha < 0 || *alpha > 1 {
+		return errors.New("Opacity should be from 0 to 1")
+	}
+	var labels []string
+	if *labelsPath != "" {
+		if labels, err = detection.ReadClassLabels(*labelsPath); err != nil {
+			return fmt.Errorf("Error loading class labels: %v", err)
+		}
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{DNN: true, Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	modelFile, err := models.Resolve(*model)
+	if err != nil {
+		return fmt.Errorf("Error loading model: %v", err)
+	}
+	configFile := ""
+	if *modelConfig != "" {
+		if configFile, err = models.Resolve(*modelConfig); err != nil {
+			return fmt.Errorf("Error loading model config: %v", err)
+		}
+	}
+	net := gocv.ReadNet(modelFile, configFile)
+	if net.Empty() {
+		return errors.New("Error loading model")
+	}
+	sd.OnClose("model", net.Close)
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Semantic segmentation")
+	if err != nil {
+		return err
+	}
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			*alpha += float64(step) * opacityStep
+			if *alpha < 0 {
+				*alpha = 0
+			}
+			if *alpha > 1 {
+				*alpha = 1
+			}
+			return fmt.Sprintf("opacity %.1f", *alpha)
+		}
+	}
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("inference")
+		blob := gocv.BlobFromImage(*img, *scale, size, mean, *swapRB, false)
+		net.SetInput(blob, "")
+		out := net.Forward("")
+		blob.Close()
+		stop()
+
+		stop = stats.Start("render")
+		classes, dims, err := ClassMap(out)
+		out.Close()
+		if err != nil {
+			logging.Errorf("Error decoding output: %v", err)
+			stop()
+			return
+		}
+		present := Blend(img, classes, dims, *ignore, *alpha)
+		stop()
+		stats.Frame()
+
+		var legend []draw.LegendEntry
+		for _, c := range present {
+			text := strconv.Itoa(c)
+			if c < len(labels) {
+				text = labels[c]
+			}
+			legend = append(legend, draw.LegendEntry{Color: draw.ClassColor(c), Text: text})
+		}
+		draw.Legend(img, legend, image.Pt(0, 0), draw.DefaultStyle)
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}
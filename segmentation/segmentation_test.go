@@ -0,0 +1,56 @@
+package segmentation
+
+import (
+	"image"
+	"testing"
+
+	"github.com/marchevska/gocv-examples/internal/draw"
+)
+
+func TestArgmax(t *testing.T) {
+	// 3 classes, 4 pixels
+	scores := []float32{
+		0.9, 0.1, 0.2, 0.3,
+		0.05, 0.8, 0.2, 0.3,
+		0.05, 0.1, 0.6, 0.4,
+	}
+	got := argmax(scores, 3, 4)
+	want := []int{0, 1, 2, 2}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestColorize(t *testing.T) {
+	bgr, mask, present := colorize([]int{0, 3, 0, 1}, 0)
+	if len(present) != 2 || present[0] != 1 || present[1] != 3 {
+		t.Errorf("Expected classes [1 3], got %v", present)
+	}
+	if mask[0] != 0 || mask[1] != 255 {
+		t.Errorf("Expected ignored class to be masked out, got %v", mask)
+	}
+	c := draw.ClassColor(3)
+	if bgr[3] != c.B || bgr[4] != c.G || bgr[5] != c.R {
+		t.Errorf("Expected BGR of class 3 color %v, got %v", c, bgr[3:6])
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	if s, err := ParseSize("1024x512"); err != nil || s != image.Pt(1024, 512) {
+		t.Errorf("Unexpected size %v, %v", s, err)
+	}
+	if _, err := ParseSize("1024"); err == nil {
+		t.Error("Expected error for size without height")
+	}
+	if m, err := ParseMean("127.5"); err != nil || m.Val1 != 127.5 || m.Val3 != 127.5 {
+		t.Errorf("Unexpected mean %v, %v", m, err)
+	}
+	if m, err := ParseMean("104, 117, 123"); err != nil || m.Val2 != 117 {
+		t.Errorf("Unexpected mean %v, %v", m, err)
+	}
+	if _, err := ParseMean("1,2"); err == nil {
+		t.Error("Expected error for 2 values")
+	}
+}
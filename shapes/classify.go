@@ -0,0 +1,53 @@
+package shapes
+
+import (
+	"image"
+	"math"
+)
+
+// Shape names
+const (
+	Triangle  = "triangle"
+	Square    = "square"
+	Rectangle = "rectangle"
+	Pentagon  = "pentagon"
+	Hexagon   = "hexagon"
+	Circle    = "circle"
+	Polygon   = "polygon"
+)
+
+const (
+	squareTol    = 0.1 // Largest difference of the sides of a square, as part of the width
+	minCircular  = 0.8 // Smallest circularity of a circle, 1 for a perfect circle
+	polygonSides = 6   // Polygons with more vertices may be circles
+)
+
+// circularity returns 4*pi*area/perimeter^2, which is 1 for a circle and smaller for other shapes
+func circularity(area, perimeter float64) float64 {
+	if perimeter == 0 {
+		return 0
+	}
+	return 4 * math.Pi * area / (perimeter * perimeter)
+}
+
+// classify names the shape by the number of vertices of its simplified contour, the area and
+// perimeter of the contour, and its bounding box
+func classify(vertices int, area, perimeter float64, box image.Rectangle) string {
+	switch {
+	case vertices == 3:
+		return Triangle
+	case vertices == 4:
+		w, h := float64(box.Dx()), float64(box.Dy())
+		if math.Abs(w-h) <= squareTol*w {
+			return Square
+		}
+		return Rectangle
+	case vertices == 5:
+		return Pentagon
+	case vertices == polygonSides:
+		return Hexagon
+	case vertices > polygonSides && circularity(area, perimeter) >= minCircular:
+		return Circle
+	}
+	return Polygon
+}
@@ -0,0 +1,38 @@
+package shapes
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	box := image.Rect(0, 0, 100, 100)
+	tests := []struct {
+		vertices        int
+		area, perimeter float64
+		box             image.Rectangle
+		want            string
+	}{
+		{3, 4330, 300, box, Triangle},
+		{4, 10000, 400, box, Square},
+		{4, 10000, 400, image.Rect(0, 0, 100, 105), Square},
+		{4, 5000, 300, image.Rect(0, 0, 100, 50), Rectangle},
+		{5, 6900, 310, box, Pentagon},
+		{6, 6500, 300, box, Hexagon},
+		{12, math.Pi * 2500, math.Pi * 100, box, Circle},
+		{10, 2000, 600, box, Polygon}, // Star
+	}
+	for _, tt := range tests {
+		if got := classify(tt.vertices, tt.area, tt.perimeter, tt.box); got != tt.want {
+			t.Errorf("classify(%d, %v, %v, %v) = %s, want %s", tt.vertices, tt.area, tt.perimeter, tt.box, got, tt.want)
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+	shapes := []shape{{name: Circle}, {name: Square}, {name: Circle}}
+	if got := summary(shapes); got != "1 square, 2 circles" {
+		t.Errorf("Unexpected summary %q", got)
+	}
+}
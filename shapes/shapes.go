@@ -0,0 +1,210 @@
+// This example finds geometric shapes on an image and names them: triangles, squares, rectangles,
+// pentagons, hexagons and circles
+//
+// Call: gocv-examples shapes -input shapes.png [-invert] [-thr 0] [-min-area 500] [-epsilon 0.04]
+// Input can be images, a video file, a camera ID or a stream URL
+// Parameters can also be set with SHAPES_* environment variables or a config file, see internal/config
+//
+// The image is thresholded, with Otsu's method unless -thr is given, and outer contours of the
+// white regions are found. Shapes should be lighter than the background, -invert handles dark shapes
+// on light paper. Each contour is simplified with the Douglas-Peucker algorithm, to a tolerance of
+// -epsilon of its perimeter, and classified by the number of vertices; contours with many vertices
+// are circles if their area is close to that of a circle of the same perimeter. M shows the
+// thresholded image, +/- change the threshold
+
+package shapes
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"sort"
+	"strings"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	minArea     = 500
+	approxEps   = 0.04 // Contour simplification tolerance, as part of its perimeter
+	thrStep     = 5    // Change of the threshold by +/- keys
+	otsuDefault = 128  // First manual threshold after Otsu's one
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// shape is a classified contour
+type shape struct {
+	name    string
+	outline []image.Point
+	box     image.Rectangle
+}
+
+// findShapes classifies the outer contours of the binary image which are at least minArea large
+func findShapes(binary gocv.Mat, minArea, eps float64) []shape {
+	contours := gocv.FindContours(binary, gocv.RetrievalExternal, gocv.ChainApproxSimple)
+	defer contours.Close()
+	var shapes []shape
+	for i := 0; i < contours.Size(); i++ {
+		c := contours.At(i)
+		area := gocv.ContourArea(c)
+		if area < minArea {
+			continue
+		}
+		perimeter := gocv.ArcLength(c, true)
+		approx := gocv.ApproxPolyDP(c, eps*perimeter, true)
+		box := gocv.BoundingRect(approx)
+		shapes = append(shapes, shape{
+			name:    classify(approx.Size(), area, perimeter, box),
+			outline: approx.ToPoints(),
+			box:     box,
+		})
+		approx.Close()
+	}
+	return shapes
+}
+
+// summary returns the number of shapes by name, like "2 circles, 1 square"
+func summary(shapes []shape) string {
+	counts := map[string]int{}
+	for _, s := range shapes {
+		counts[s.name]++
+	}
+	var parts []string
+	for name, n := range counts {
+		if n > 1 {
+			name += "s"
+		}
+		parts = append(parts, fmt.Sprintf("%d %s", n, name))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
+
+// Run finds shapes on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples shapes", flag.ExitOnError)
+	input := fs.String("input", "", "Image, directory or glob pattern of images, video file, camera ID or stream URL")
+	invert := fs.Bool("invert", false, "Find shapes darker than the background")
+	thr := fs.Int("thr", 0, "Binary threshold from 1 to 255, 0 for Otsu's method")
+	area := fs.Float64("min-area", minArea, "Smallest shape area in pixels")
+	eps := fs.Float64("epsilon", approxEps, "Contour simplification tolerance, as part of its perimeter")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "SHAPES"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("shapes")
+
+	if *input == "" {
+		return errors.New("Input is required, see 'gocv-examples shapes -h'")
+	}
+	if *thr < 0 || *thr > 255 {
+		return errors.New("Threshold should be from 0 to 255")
+	}
+	if *eps <= 0 || *eps >= 1 {
+		return errors.New("Epsilon should be greater than 0 and less than 1")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Shape detection")
+	if err != nil {
+		return err
+	}
+	showBinary := false
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			if *thr == 0 {
+				*thr = otsuDefault
+			}
+			*thr += step * thrStep
+			if *thr < 1 {
+				*thr = 1
+			}
+			if *thr > 255 {
+				*thr = 255
+			}
+			return fmt.Sprintf("threshold %d", *thr)
+		}
+		window.Controls.Keys = append(window.Controls.Keys, videoio.KeyAction{
+			Key: 'm', Help: "show thresholded image", Do: func() { showBinary = !showBinary },
+		})
+	}
+
+	binary := gocv.NewMat()
+	defer binary.Close()
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("contours")
+		gocv.CvtColor(*img, &binary, gocv.ColorBGRToGray)
+		gocv.GaussianBlur(binary, &binary, image.Pt(5, 5), 0, 0, gocv.BorderDefault)
+		typ := gocv.ThresholdBinary
+		if *invert {
+			typ = gocv.ThresholdBinaryInv
+		}
+		if *thr == 0 {
+			typ |= gocv.ThresholdOtsu
+		}
+		gocv.Threshold(binary, &binary, float32(*thr), 255, typ)
+		shapes := findShapes(binary, *area, *eps)
+		stop()
+		stats.Frame()
+		logging.Infof("Shapes: %d %s", len(shapes), summary(shapes))
+
+		if showBinary {
+			gocv.CvtColor(binary, img, gocv.ColorGrayToBGR)
+		}
+		for _, s := range shapes {
+			st := draw.DefaultStyle.WithColor(draw.LabelColor(s.name))
+			st.LineThickness = 2
+			draw.Outline(img, s.outline, st)
+			center := image.Pt((s.box.Min.X+s.box.Max.X)/2, (s.box.Min.Y+s.box.Max.Y)/2)
+			size := st.TextSize(s.name)
+			draw.TextWithBackground(img, s.name, image.Pt(center.X-size.X/2, center.Y+size.Y/2), st)
+		}
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+// This example finds Shi-Tomasi corners and tracks them across frames with the pyramidal
+// Lucas-Kanade method, drawing the trail of each point
+//
+// Call: gocv-examples sparse-flow [-input 0] [-max-points 200] [-trail 20]
+// Points are lost when they leave the frame or their neighbourhood changes too much. When fewer
+// than -reseed of -max-points are left, new corners are added away from the tracked points.
+// Press C to clear all points and detect them again
+// Parameters can also be set with SPARSEFLOW_* environment variables or a config file, see internal/config
+//
+// https://docs.opencv.org/4.x/d4/dee/tutorial_optical_flow.html
+
+package sparseflow
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID       = "0" // Default input
+	maxPoints   = 200
+	quality     = 0.01 // Minimal corner quality relative to the best corner
+	minDist     = 10   // Minimal distance between corners in pixels
+	trailLen    = 20
+	reseedRatio = 0.5
+	pointRadius = 3
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Run tracks corners on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples sparse-flow", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	max := fs.Int("max-points", maxPoints, "Maximal number of tracked points")
+	trail := fs.Int("trail", trailLen, "Length of the drawn trails in frames")
+	reseed := fs.Float64("reseed", reseedRatio, "Part of -max-points below which new points are added")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "SPARSEFLOW"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("sparse-flow")
+
+	if *max < 1 || *trail < 1 {
+		return errors.New("Number of points and trail length should be positive")
+	}
+	if *reseed < 0 || *reseed > 1 {
+		return errors.New("Reseed ratio should be in [0, 1]")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Sparse optical flow")
+	if err != nil {
+		return err
+	}
+	var tracks trails
+	if window != nil {
+		window.Controls.Keys = append(window.Controls.Keys, videoio.KeyAction{
+			Key: 'c', Help: "detect points again", Do: func() { tracks = nil },
+		})
+	}
+
+	prev, gray := gocv.NewMat(), gocv.NewMat()
+	defer prev.Close()
+	defer gray.Close()
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		gocv.CvtColor(*img, &gray, gocv.ColorBGRToGray)
+		if len(tracks) > 0 && !prev.Empty() {
+			stop := stats.Start("flow")
+			next, found := flowLK(prev, gray, tracks.heads())
+			tracks = tracks.advance(next, found, *trail)
+			stop()
+		}
+		if float64(len(tracks)) < *reseed*float64(*max) || len(tracks) == 0 {
+			stop := stats.Start("corners")
+			before := len(tracks)
+			tracks = tracks.seed(corners(gray, *max), minDist, *max)
+			stop()
+			logging.Debugf("Added %d points, tracking %d", len(tracks)-before, len(tracks))
+		}
+		gray.CopyTo(&prev)
+		stats.Frame()
+
+		for _, t := range tracks {
+			if len(t) > 1 {
+				pv := gocv.NewPointsVectorFromPoints([][]image.Point{t})
+				gocv.Polylines(img, pv, false, draw.DefaultStyle.LineColor, 1)
+				pv.Close()
+			}
+			gocv.Circle(img, t[len(t)-1], pointRadius, draw.Red, -1)
+		}
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err != nil && err != videoio.ErrStopped {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	return nil
+}
+
+// Returns the strongest corners of a grayscale image, strongest first
+func corners(gray gocv.Mat, max int) []image.Point {
+	mat := gocv.NewMat()
+	defer mat.Close()
+	gocv.GoodFeaturesToTrack(gray, &mat, max, quality, minDist)
+	pts := make([]image.Point, mat.Rows())
+	for i := range pts {
+		v := mat.GetVecfAt(i, 0)
+		pts[i] = image.Pt(int(v[0]), int(v[1]))
+	}
+	return pts
+}
+
+// Finds the points of the previous frame on the next one. Returns their positions
+// and whether each point was found
+func flowLK(prev, next gocv.Mat, pts []image.Point) ([]image.Point, []bool) {
+	prevPts := gocv.NewMatWithSize(len(pts), 1, gocv.MatTypeCV32FC2)
+	defer prevPts.Close()
+	for i, p := range pts {
+		prevPts.SetFloatAt(i, 0, float32(p.X))
+		prevPts.SetFloatAt(i, 1, float32(p.Y))
+	}
+	nextPts, status, errs := gocv.NewMat(), gocv.NewMat(), gocv.NewMat()
+	defer nextPts.Close()
+	defer status.Close()
+	defer errs.Close()
+	gocv.CalcOpticalFlowPyrLK(prev, next, prevPts, nextPts, &status, &errs)
+
+	bounds := image.Rect(0, 0, next.Cols(), next.Rows())
+	found := make([]bool, len(pts))
+	moved := make([]image.Point, len(pts))
+	for i := range pts {
+		v := nextPts.GetVecfAt(i, 0)
+		moved[i] = image.Pt(int(v[0]), int(v[1]))
+		found[i] = status.GetUCharAt(i, 0) == 1 && moved[i].In(bounds)
+	}
+	return moved, found
+}
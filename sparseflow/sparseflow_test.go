@@ -0,0 +1,36 @@
+package sparseflow
+
+import (
+	"image"
+	"testing"
+)
+
+func TestTrailsAdvance(t *testing.T) {
+	tr := trails{{image.Pt(0, 0)}, {image.Pt(10, 10)}, {image.Pt(20, 20)}}
+	tr = tr.advance([]image.Point{image.Pt(1, 0), {}, image.Pt(21, 20)}, []bool{true, false, true}, 2)
+	tr = tr.advance([]image.Point{image.Pt(2, 0), image.Pt(22, 20)}, []bool{true, true}, 2)
+	want := trails{{image.Pt(1, 0), image.Pt(2, 0)}, {image.Pt(21, 20), image.Pt(22, 20)}}
+	if len(tr) != len(want) {
+		t.Fatalf("Expected %d trails, got %d", len(want), len(tr))
+	}
+	for i := range want {
+		if len(tr[i]) != len(want[i]) || tr[i][0] != want[i][0] || tr[i][1] != want[i][1] {
+			t.Errorf("Trail %d: expected %v, got %v", i, want[i], tr[i])
+		}
+	}
+}
+
+func TestTrailsSeed(t *testing.T) {
+	tr := trails{{image.Pt(0, 0)}}
+	tr = tr.seed([]image.Point{image.Pt(3, 4), image.Pt(10, 0), image.Pt(12, 0), image.Pt(30, 0), image.Pt(40, 0)}, 6, 3)
+	got := tr.heads()
+	want := []image.Point{image.Pt(0, 0), image.Pt(10, 0), image.Pt(30, 0)}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	}
+}
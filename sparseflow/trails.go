@@ -0,0 +1,56 @@
+package sparseflow
+
+import "image"
+
+// trails holds recent positions of the tracked points, the last one is the current position
+type trails [][]image.Point
+
+// heads returns current positions of the points
+func (tr trails) heads() []image.Point {
+	pts := make([]image.Point, len(tr))
+	for i, t := range tr {
+		pts[i] = t[len(t)-1]
+	}
+	return pts
+}
+
+// advance appends the next positions of the points found on the new frame and drops lost points.
+// Trails are limited to maxLen positions
+func (tr trails) advance(next []image.Point, found []bool, maxLen int) trails {
+	var kept trails
+	for i, t := range tr {
+		if !found[i] {
+			continue
+		}
+		t = append(t, next[i])
+		if len(t) > maxLen {
+			t = t[len(t)-maxLen:]
+		}
+		kept = append(kept, t)
+	}
+	return kept
+}
+
+// seed adds new points from candidates that are at least minDist from all tracked points,
+// up to max points in total
+func (tr trails) seed(candidates []image.Point, minDist, max int) trails {
+	heads := tr.heads()
+	for _, c := range candidates {
+		if len(tr) >= max {
+			break
+		}
+		distant := true
+		for _, h := range heads {
+			d := c.Sub(h)
+			if d.X*d.X+d.Y*d.Y < minDist*minDist {
+				distant = false
+				break
+			}
+		}
+		if distant {
+			tr = append(tr, []image.Point{c})
+			heads = append(heads, c)
+		}
+	}
+	return tr
+}
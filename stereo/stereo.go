@@ -0,0 +1,297 @@
+// This example computes a disparity map of a stereo pair by block matching and shows it in color,
+// near objects in red and far ones in blue
+//
+// Call: gocv-examples stereo -left left.png -right right.png [-disparities 64] [-block 15]
+//       gocv-examples stereo -input sbs.mp4 [-disparities 64] [-block 15]
+// A pair is given as two images, or as a side-by-side video, camera or stream with the left view
+// on the left half of each frame. Views should be rectified, so that a point has the same row on
+// both; pairs from the Middlebury dataset can be used: https://vision.middlebury.edu/stereo/data/
+// Parameters can also be set with STEREO_* environment variables or a config file, see internal/config
+//
+// StereoBM and StereoSGBM are not exposed by GoCV at the moment of writing, so block matching is
+// implemented here: for each disparity the absolute differences of the left view and the shifted
+// right view are averaged over blocks with a box filter, and each pixel takes the disparity of the
+// lowest cost, refined to subpixels. Pixels without a clear minimum are black. Block size and
+// the disparity range can be changed with the trackbars: larger blocks give smoother and less detailed
+// maps, the range should cover the nearest object
+
+package stereo
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	numDisparities = 64
+	disparityStep  = 16 // Disparity range is a multiple of this
+	maxDisparities = 256
+	blockSize      = 15
+	minBlockSize   = 5
+	maxBlockSize   = 51
+	uniqueness     = 0.1 // Best cost should be lower than the second best by this part
+	invalidCost    = 255 // Cost of pixels which have no match at a disparity
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Matcher computes disparity maps by block matching
+type Matcher struct {
+	NumDisparities int
+	BlockSize      int // Odd
+	Uniqueness     float32
+}
+
+// Compute returns the disparity of each pixel of the left view in pixels, row by row, -1 for
+// pixels without a reliable match. Views should be grayscale and of the same size
+func (m *Matcher) Compute(left, right gocv.Mat) []float32 {
+	w, h := left.Cols(), left.Rows()
+	l, r := gocv.NewMat(), gocv.NewMat()
+	defer l.Close()
+	defer r.Close()
+	left.ConvertTo(&l, gocv.MatTypeCV32F)
+	right.ConvertTo(&r, gocv.MatTypeCV32F)
+	diff, cost := gocv.NewMatWithSize(h, w, gocv.MatTypeCV32F), gocv.NewMat()
+	defer diff.Close()
+	defer cost.Close()
+
+	acc := newWTA(w * h)
+	block := image.Pt(m.BlockSize, m.BlockSize)
+	for d := 0; d < m.NumDisparities && d < w; d++ {
+		// Pixel x of the left view matches pixel x-d of the right one
+		diff.SetTo(gocv.NewScalar(invalidCost, 0, 0, 0))
+		lr := l.Region(image.Rect(d, 0, w, h))
+		rr := r.Region(image.Rect(0, 0, w-d, h))
+		dr := diff.Region(image.Rect(d, 0, w, h))
+		gocv.AbsDiff(lr, rr, &dr)
+		lr.Close()
+		rr.Close()
+		dr.Close()
+		gocv.Blur(diff, &cost, block)
+		data, err := cost.DataPtrFloat32()
+		if err != nil {
+			break
+		}
+		acc.add(d, data)
+	}
+	return acc.disparities(m.Uniqueness)
+}
+
+// Colorize paints disparities with the Jet colormap scaled to the disparity range, invalid
+// pixels are black
+func Colorize(disp []float32, size image.Point, numDisparities int) gocv.Mat {
+	gray := make([]byte, len(disp))
+	valid := make([]byte, len(disp))
+	for i, d := range disp {
+		if d < 0 {
+			continue
+		}
+		v := d * 255 / float32(numDisparities)
+		if v > 255 {
+			v = 255
+		}
+		gray[i], valid[i] = byte(v), 255
+	}
+	result := gocv.NewMatWithSize(size.Y, size.X, gocv.MatTypeCV8UC3)
+	grayMat, err := gocv.NewMatFromBytes(size.Y, size.X, gocv.MatTypeCV8U, gray)
+	if err != nil {
+		return result
+	}
+	defer grayMat.Close()
+	mask, err := gocv.NewMatFromBytes(size.Y, size.X, gocv.MatTypeCV8U, valid)
+	if err != nil {
+		return result
+	}
+	defer mask.Close()
+	colored := gocv.NewMat()
+	defer colored.Close()
+	gocv.ApplyColorMap(grayMat, &colored, gocv.ColormapJet)
+	colored.CopyToWithMask(&result, mask)
+	return result
+}
+
+// pairSource returns a side-by-side frame of a static pair, repeatedly while shown in a window,
+// so that changes of the trackbars are applied
+type pairSource struct {
+	frame  gocv.Mat
+	repeat bool
+	done   bool
+}
+
+func (ps *pairSource) Next() (gocv.Mat, error) {
+	if ps.done {
+		return gocv.Mat{}, io.EOF
+	}
+	ps.done = !ps.repeat
+	return ps.frame.Clone(), nil
+}
+
+func (ps *pairSource) Close() error {
+	return ps.frame.Close()
+}
+
+// Reads the pair and joins the views side by side
+func readPair(leftPath, rightPath string) (gocv.Mat, error) {
+	left := gocv.IMRead(leftPath, gocv.IMReadColor)
+	defer left.Close()
+	right := gocv.IMRead(rightPath, gocv.IMReadColor)
+	defer right.Close()
+	if left.Empty() || right.Empty() {
+		return gocv.Mat{}, fmt.Errorf("Error reading %s or %s", leftPath, rightPath)
+	}
+	if left.Rows() != right.Rows() || left.Cols() != right.Cols() {
+		return gocv.Mat{}, errors.New("Left and right views should have the same size")
+	}
+	pair := gocv.NewMat()
+	gocv.Hconcat(left, right, &pair)
+	return pair, nil
+}
+
+// Run computes disparity maps of the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples stereo", flag.ExitOnError)
+	input := fs.String("input", "", "Side-by-side video file, camera ID, stream URL or images")
+	leftPath := fs.String("left", "", "Left view image")
+	rightPath := fs.String("right", "", "Right view image")
+	disparities := fs.Int("disparities", numDisparities, "Disparity range in pixels, a multiple of 16")
+	block := fs.Int("block", blockSize, "Block size, odd, from 5 to 51")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var cameraSettings videoio.CameraSettings
+	cameraSettings.RegisterFlags(fs)
+	if err := config.Load(fs, args, "STEREO"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("stereo")
+
+	pairMode := *leftPath != "" || *rightPath != ""
+	if pairMode == (*input != "") || pairMode && (*leftPath == "" || *rightPath == "") {
+		return errors.New("Either -left and -right or -input is required, see 'gocv-examples stereo -h'")
+	}
+	if *disparities < disparityStep || *disparities > maxDisparities || *disparities%disparityStep != 0 {
+		return fmt.Errorf("Disparity range should be a multiple of %d up to %d", disparityStep, maxDisparities)
+	}
+	if *block < minBlockSize || *block > maxBlockSize || *block%2 == 0 {
+		return fmt.Errorf("Block size should be odd, from %d to %d", minBlockSize, maxBlockSize)
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	camera := -1
+	if !pairMode {
+		camera = probe.CameraID(*input)
+	}
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: camera})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	var src videoio.FrameSource
+	if pairMode {
+		pair, err := readPair(*leftPath, *rightPath)
+		if err != nil {
+			return err
+		}
+		src = &pairSource{frame: pair, repeat: !outputs.Enabled()}
+	} else {
+		var err error
+		if src, err = videoio.OpenSource(*input, 0, 0); err != nil {
+			return fmt.Errorf("Error opening input: %v", err)
+		}
+	}
+	sd.OnClose("input", src.Close)
+	cameraSettings.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Stereo disparity")
+	if err != nil {
+		return err
+	}
+	matcher := &Matcher{NumDisparities: *disparities, BlockSize: *block, Uniqueness: uniqueness}
+	var blockBar, dispBar videoio.Slider
+	if window != nil {
+		blockBar = window.CreateTrackbar("Block size", maxBlockSize)
+		blockBar.SetPos(*block)
+		dispBar = window.CreateTrackbar("Disparities / 16", maxDisparities/disparityStep)
+		dispBar.SetPos(*disparities / disparityStep)
+	}
+
+	// Static pairs are computed again only when the parameters change
+	var last Matcher
+	depth := gocv.NewMat()
+	defer depth.Close()
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		if blockBar != nil {
+			matcher.BlockSize = blockBar.GetPos() | 1
+			if matcher.BlockSize < minBlockSize {
+				matcher.BlockSize = minBlockSize
+			}
+			matcher.NumDisparities = dispBar.GetPos() * disparityStep
+			if matcher.NumDisparities < disparityStep {
+				matcher.NumDisparities = disparityStep
+			}
+		}
+		w, h := img.Cols()/2, img.Rows()
+		rightRect := image.Rect(w, 0, 2*w, h)
+
+		if !pairMode || *matcher != last || depth.Empty() {
+			stop := stats.Start("matching")
+			left, right := img.Region(image.Rect(0, 0, w, h)), img.Region(rightRect)
+			lg, rg := gocv.NewMat(), gocv.NewMat()
+			gocv.CvtColor(left, &lg, gocv.ColorBGRToGray)
+			gocv.CvtColor(right, &rg, gocv.ColorBGRToGray)
+			left.Close()
+			right.Close()
+			disp := matcher.Compute(lg, rg)
+			lg.Close()
+			rg.Close()
+			depth.Close()
+			depth = Colorize(disp, image.Pt(w, h), matcher.NumDisparities)
+			stop()
+			stats.Frame()
+			last = *matcher
+			logging.Debugf("Disparity map with block %d, range %d", matcher.BlockSize, matcher.NumDisparities)
+		}
+
+		// Left view stays, the right one is replaced with the disparity map
+		dst := img.Region(rightRect)
+		depth.CopyTo(&dst)
+		dst.Close()
+		text := fmt.Sprintf("Block %d, disparities %d", matcher.BlockSize, matcher.NumDisparities)
+		st := draw.DefaultStyle
+		draw.TextWithBackground(img, text, image.Pt(w, st.TextSize(text).Y+2*st.Padding), st)
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package stereo
+
+import "math"
+
+// wta picks the disparity of each pixel with the lowest matching cost ("winner takes all").
+// Costs are added disparity by disparity, so that costs of all disparities are never kept in memory
+type wta struct {
+	best, second  []float32 // Lowest cost, and the lowest one of disparities not next to the best
+	before, after []float32 // Costs at the disparities next to the best, for subpixel refinement
+	disp          []int     // Disparity of the lowest cost, -1 if there is none yet
+	prev          []float32 // Costs of the previous disparity
+	pending       []bool    // The best is at the previous disparity and needs its after cost
+}
+
+func newWTA(n int) *wta {
+	w := &wta{
+		best: make([]float32, n), second: make([]float32, n),
+		before: make([]float32, n), after: make([]float32, n),
+		disp: make([]int, n), prev: make([]float32, n), pending: make([]bool, n),
+	}
+	inf := float32(math.Inf(1))
+	for i := range w.disp {
+		w.best[i], w.second[i], w.before[i], w.after[i], w.prev[i] = inf, inf, inf, inf, inf
+		w.disp[i] = -1
+	}
+	return w
+}
+
+// add takes the costs of all pixels at disparity d; disparities should be added in increasing order
+func (w *wta) add(d int, cost []float32) {
+	for i, c := range cost {
+		if w.pending[i] {
+			w.after[i], w.pending[i] = c, false
+		}
+		switch {
+		case c < w.best[i]:
+			if w.disp[i] >= 0 && d-w.disp[i] > 1 && w.best[i] < w.second[i] {
+				w.second[i] = w.best[i]
+			}
+			w.best[i], w.disp[i] = c, d
+			w.before[i], w.after[i] = w.prev[i], float32(math.Inf(1))
+			w.pending[i] = true
+		case d-w.disp[i] > 1 && c < w.second[i]:
+			w.second[i] = c
+		}
+		w.prev[i] = c
+	}
+}
+
+// disparities returns the subpixel disparity of each pixel, or -1 if the best cost is not lower
+// than the second best by the uniqueness ratio, e.g. 0.1 for 10%
+func (w *wta) disparities(uniqueness float32) []float32 {
+	out := make([]float32, len(w.disp))
+	for i, d := range w.disp {
+		if d < 0 || w.best[i]*(1+uniqueness) > w.second[i] {
+			out[i] = -1
+			continue
+		}
+		out[i] = float32(d)
+		b, a := w.before[i], w.after[i]
+		if denom := b + a - 2*w.best[i]; !math.IsInf(float64(b), 0) && !math.IsInf(float64(a), 0) && denom > 0 {
+			out[i] += (b - a) / (2 * denom)
+		}
+	}
+	return out
+}
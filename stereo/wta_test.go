@@ -0,0 +1,43 @@
+package stereo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWTA(t *testing.T) {
+	// Costs of 3 pixels at disparities 0 to 5: a clear minimum at 3 with a symmetric neighbourhood,
+	// a minimum at 1 leaning towards 2, and two equal minima far apart
+	costs := [][]float32{
+		{9, 5, 9},
+		{8, 1, 9},
+		{4, 2, 9},
+		{1, 9, 1},
+		{4, 9, 9},
+		{9, 9, 1},
+	}
+	w := newWTA(3)
+	for d, c := range costs {
+		w.add(d, c)
+	}
+	got := w.disparities(0.1)
+	if got[0] != 3 {
+		t.Errorf("Expected disparity 3, got %v", got[0])
+	}
+	// Parabola through (0, 5), (1, 1), (2, 2): 1 + (5-2)/(2*(5+2-2)) = 1.3
+	if math.Abs(float64(got[1]-1.3)) > 1e-6 {
+		t.Errorf("Expected subpixel disparity 1.3, got %v", got[1])
+	}
+	if got[2] != -1 {
+		t.Errorf("Ambiguous pixel should be invalid, got %v", got[2])
+	}
+}
+
+func TestWTANoCosts(t *testing.T) {
+	w := newWTA(1)
+	inf := float32(math.Inf(1))
+	w.add(0, []float32{inf})
+	if got := w.disparities(0); got[0] != -1 {
+		t.Errorf("Pixel without finite costs should be invalid, got %v", got[0])
+	}
+}
@@ -0,0 +1,159 @@
+package sudoku
+
+import (
+	"fmt"
+	"image"
+	"path/filepath"
+
+	"github.com/marchevska/gocv-examples/docscan"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"gocv.io/x/gocv"
+)
+
+const (
+	cellSize     = 50   // Side of a cell of the warped grid in pixels
+	minGridArea  = 0.1  // Smallest grid, as part of the image area
+	approxEps    = 0.02 // Contour simplification tolerance, as part of its perimeter
+	cellMargin   = 7    // Pixels ignored at each edge of a cell, where grid lines are
+	minDigitSize = 0.3  // Smallest digit height, as part of the cell side
+	blockSize    = 11   // Neighbourhood of adaptive thresholding
+	threshC      = 2
+)
+
+// Size digits are compared at
+var templateSize = image.Pt(20, 30)
+
+// findGrid returns the corners of the largest quadrilateral contour of the image, ordered
+// clockwise from top-left, and false if there is none
+func findGrid(gray gocv.Mat) ([4]image.Point, bool) {
+	bin := gocv.NewMat()
+	defer bin.Close()
+	gocv.GaussianBlur(gray, &bin, image.Pt(5, 5), 0, 0, gocv.BorderDefault)
+	gocv.AdaptiveThreshold(bin, &bin, 255, gocv.AdaptiveThresholdGaussian, gocv.ThresholdBinaryInv, blockSize, threshC)
+
+	contours := gocv.FindContours(bin, gocv.RetrievalExternal, gocv.ChainApproxSimple)
+	defer contours.Close()
+	best := minGridArea * float64(gray.Rows()*gray.Cols())
+	var corners []image.Point
+	for i := 0; i < contours.Size(); i++ {
+		c := contours.At(i)
+		if gocv.ContourArea(c) <= best {
+			continue
+		}
+		approx := gocv.ApproxPolyDP(c, approxEps*gocv.ArcLength(c, true), true)
+		if approx.Size() == 4 {
+			best, corners = gocv.ContourArea(approx), approx.ToPoints()
+		}
+		approx.Close()
+	}
+	if corners == nil {
+		return [4]image.Point{}, false
+	}
+	return docscan.OrderCorners(corners), true
+}
+
+// gridTransform returns the perspective transform from the grid corners to the warped square
+// grid, or back when inverse is set
+func gridTransform(corners [4]image.Point, inverse bool) gocv.Mat {
+	side := 9 * cellSize
+	src := gocv.NewPointVectorFromPoints(corners[:])
+	defer src.Close()
+	dst := gocv.NewPointVectorFromPoints([]image.Point{{0, 0}, {side, 0}, {side, side}, {0, side}})
+	defer dst.Close()
+	if inverse {
+		return gocv.GetPerspectiveTransform(dst, src)
+	}
+	return gocv.GetPerspectiveTransform(src, dst)
+}
+
+// Recognizer reads digits by matching them to one template per digit
+type Recognizer struct {
+	templates [10]gocv.Mat // Index is the digit, 0 is unused
+}
+
+// NewRecognizer creates templates of the digits rendered with the font. If dir is given, digit
+// images 1.png to 9.png are loaded from it instead, e.g. crops of the puzzles to be read.
+// It should be closed after use
+func NewRecognizer(dir string, font gocv.HersheyFont) (*Recognizer, error) {
+	r := &Recognizer{}
+	for d := 1; d <= 9; d++ {
+		var img gocv.Mat
+		if dir != "" {
+			path := filepath.Join(dir, fmt.Sprintf("%d.png", d))
+			img = gocv.IMRead(path, gocv.IMReadGrayScale)
+			if img.Empty() {
+				img.Close()
+				r.Close()
+				return nil, fmt.Errorf("Cannot read digit template %s", path)
+			}
+			gocv.Threshold(img, &img, 0, 255, gocv.ThresholdBinaryInv|gocv.ThresholdOtsu)
+		} else {
+			img = gocv.NewMatWithSize(cellSize, cellSize, gocv.MatTypeCV8U)
+			gocv.PutText(&img, fmt.Sprint(d), image.Pt(cellSize/4, cellSize*3/4), font, 1.2, draw.White, 3)
+		}
+		r.templates[d] = normalizeDigit(img, image.Rect(0, 0, img.Cols(), img.Rows()))
+		img.Close()
+		if r.templates[d].Empty() {
+			r.Close()
+			return nil, fmt.Errorf("No digit found in the template of %d", d)
+		}
+	}
+	return r, nil
+}
+
+// Read recognizes the digits of the warped binary grid, white digits on black
+func (r *Recognizer) Read(bin gocv.Mat) grid {
+	var g grid
+	result := gocv.NewMat()
+	defer result.Close()
+	mask := gocv.NewMat()
+	defer mask.Close()
+	for i := range g {
+		cell := image.Rect(i%9*cellSize, i/9*cellSize, (i%9+1)*cellSize, (i/9+1)*cellSize).Inset(cellMargin)
+		digit := normalizeDigit(bin, cell)
+		if digit.Empty() {
+			digit.Close()
+			continue
+		}
+		best := float32(0)
+		for d := 1; d <= 9; d++ {
+			gocv.MatchTemplate(digit, r.templates[d], &result, gocv.TmCcoeffNormed, mask)
+			if _, score, _, _ := gocv.MinMaxLoc(result); score > best {
+				best, g[i] = score, d
+			}
+		}
+		digit.Close()
+	}
+	return g
+}
+
+// Close releases the templates
+func (r *Recognizer) Close() error {
+	for _, t := range r.templates {
+		t.Close()
+	}
+	return nil
+}
+
+// normalizeDigit crops the largest blob of the region of the binary image and resizes it to
+// templateSize. The result is empty when there is no blob large enough for a digit
+func normalizeDigit(bin gocv.Mat, region image.Rectangle) gocv.Mat {
+	crop := bin.Region(region)
+	defer crop.Close()
+	contours := gocv.FindContours(crop, gocv.RetrievalExternal, gocv.ChainApproxSimple)
+	defer contours.Close()
+	var box image.Rectangle
+	for i := 0; i < contours.Size(); i++ {
+		if r := gocv.BoundingRect(contours.At(i)); r.Dx()*r.Dy() > box.Dx()*box.Dy() {
+			box = r
+		}
+	}
+	out := gocv.NewMat()
+	if float64(box.Dy()) < minDigitSize*float64(region.Dy()) {
+		return out
+	}
+	digit := crop.Region(box)
+	defer digit.Close()
+	gocv.Resize(digit, &out, templateSize, 0, 0, gocv.InterpolationArea)
+	return out
+}
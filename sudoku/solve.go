@@ -0,0 +1,106 @@
+package sudoku
+
+import (
+	"errors"
+	"strings"
+)
+
+// grid holds the digits of a puzzle row by row, 0 for an empty cell
+type grid [81]int
+
+// String returns the grid as 9 lines of digits, with dots for empty cells
+func (g grid) String() string {
+	var b strings.Builder
+	for i, d := range g {
+		if d == 0 {
+			b.WriteByte('.')
+		} else {
+			b.WriteByte(byte('0' + d))
+		}
+		if i%9 == 8 && i < 80 {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// Errors of solve
+var (
+	errConflict   = errors.New("Digits conflict, the grid was probably misread")
+	errNoSolution = errors.New("Puzzle has no solution")
+	errTooHard    = errors.New("Puzzle needs too many guesses, the grid was probably misread")
+)
+
+// canPlace reports whether digit d can be written to cell i without repeating in its row,
+// column or box
+func (g *grid) canPlace(i, d int) bool {
+	row, col := i/9, i%9
+	box := (row/3)*27 + (col/3)*3
+	for k := 0; k < 9; k++ {
+		if g[row*9+k] == d || g[k*9+col] == d || g[box+(k/3)*9+k%3] == d {
+			return false
+		}
+	}
+	return true
+}
+
+// solve fills the empty cells by backtracking, always trying the cell with the fewest candidates.
+// It gives up after maxSteps guesses, since a misread grid may take very long to be proven unsolvable
+func solve(g grid, maxSteps int) (grid, error) {
+	for i, d := range g {
+		if d == 0 {
+			continue
+		}
+		g[i] = 0
+		ok := g.canPlace(i, d)
+		g[i] = d
+		if !ok {
+			return g, errConflict
+		}
+	}
+
+	steps := 0
+	var fill func() bool
+	fill = func() bool {
+		best, bestCount := -1, 10
+		var bestDigits []int
+		for i, d := range g {
+			if d != 0 {
+				continue
+			}
+			var digits []int
+			for c := 1; c <= 9; c++ {
+				if g.canPlace(i, c) {
+					digits = append(digits, c)
+				}
+			}
+			if len(digits) < bestCount {
+				best, bestCount, bestDigits = i, len(digits), digits
+				if bestCount == 0 {
+					return false
+				}
+			}
+		}
+		if best < 0 {
+			return true
+		}
+		for _, c := range bestDigits {
+			if steps++; steps > maxSteps {
+				return false
+			}
+			g[best] = c
+			if fill() {
+				return true
+			}
+		}
+		g[best] = 0
+		return false
+	}
+	if fill() {
+		return g, nil
+	}
+	if steps > maxSteps {
+		return g, errTooHard
+	}
+	return g, errNoSolution
+}
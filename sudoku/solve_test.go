@@ -0,0 +1,74 @@
+package sudoku
+
+import (
+	"strings"
+	"testing"
+)
+
+func parseGrid(s string) grid {
+	var g grid
+	s = strings.Join(strings.Fields(s), "")
+	for i, c := range s {
+		if c >= '1' && c <= '9' {
+			g[i] = int(c - '0')
+		}
+	}
+	return g
+}
+
+const puzzle = `
+53..7....
+6..195...
+.98....6.
+8...6...3
+4..8.3..1
+7...2...6
+.6....28.
+...419..5
+....8..79`
+
+const solution = `
+534678912
+672195348
+198342567
+859761423
+426853791
+713924856
+961537284
+287419635
+345286179`
+
+func TestSolve(t *testing.T) {
+	got, err := solve(parseGrid(puzzle), 100000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := parseGrid(solution); got != want {
+		t.Errorf("Solution:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestSolveErrors(t *testing.T) {
+	g := parseGrid(puzzle)
+	g[2] = 5 // Second 5 in the first row
+	if _, err := solve(g, 100000); err != errConflict {
+		t.Errorf("Conflicting grid: %v", err)
+	}
+
+	// No digit fits the last cell of the first row, although no digits conflict
+	g = parseGrid("12345678.........9")
+	if _, err := solve(g, 100000); err != errNoSolution {
+		t.Errorf("Unsolvable grid: %v", err)
+	}
+
+	if _, err := solve(grid{}, 5); err != errTooHard {
+		t.Errorf("Empty grid with 5 steps: %v", err)
+	}
+}
+
+func TestGridString(t *testing.T) {
+	s := parseGrid(puzzle).String()
+	if lines := strings.Split(s, "\n"); len(lines) != 9 || lines[0] != "53..7...." {
+		t.Errorf("String() = %q", s)
+	}
+}
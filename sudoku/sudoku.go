@@ -0,0 +1,218 @@
+// This example solves Sudoku puzzles seen by the camera or in photos: it finds the grid, reads
+// the digits, solves the puzzle and writes the missing digits back onto the image
+//
+// Call: gocv-examples sudoku [-input 0] [-templates digits] [-font simplex]
+// Input can be a camera ID (default 0), a video file, a stream URL or images
+// The grid is the largest quadrilateral of the image; it is warped to a square and each of the
+// 81 cells is thresholded. The largest blob of a cell is its digit, recognized by template matching.
+// The puzzle is solved by backtracking and the solution is drawn in the perspective of the grid.
+// Read digits are logged once per new puzzle, press D to show them instead of the solution
+// Parameters can also be set with SUDOKU_* environment variables or a config file, see internal/config
+//
+// Templates are rendered with a Hershey font, which matches most printed puzzles well enough. For
+// other fonts save crops of the digits 1 to 9 as 1.png to 9.png, dark on light, to -templates.
+// A misread digit usually makes the puzzle unsolvable, which is shown instead of a wrong solution
+
+package sudoku
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"strings"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID    = "0" // Default input
+	maxSteps = 200000
+	minGiven = 17 // No Sudoku with fewer clues has a unique solution
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Fonts for the digit templates by name
+var fonts = map[string]gocv.HersheyFont{
+	"simplex": gocv.FontHersheySimplex,
+	"duplex":  gocv.FontHersheyDuplex,
+	"complex": gocv.FontHersheyComplex,
+	"triplex": gocv.FontHersheyTriplex,
+}
+
+// Style of the solved digits
+var digitStyle = draw.Style{
+	Font:          gocv.FontHersheySimplex,
+	FontScale:     1.2,
+	TextThickness: 2,
+	TextColor:     draw.Green,
+}
+
+// Run solves the Sudoku puzzles on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples sudoku", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	templates := fs.String("templates", "", "Directory with digit images 1.png to 9.png, instead of rendered templates")
+	fontName := fs.String("font", "simplex", "Font of rendered templates: simplex, duplex, complex or triplex")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "SUDOKU"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("sudoku")
+
+	font, ok := fonts[strings.ToLower(*fontName)]
+	if !ok {
+		return fmt.Errorf("Unknown font %q, available: simplex, duplex, complex, triplex", *fontName)
+	}
+	recognizer, err := NewRecognizer(*templates, font)
+	if err != nil {
+		return err
+	}
+	defer recognizer.Close()
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Sudoku solver")
+	if err != nil {
+		return err
+	}
+	showRead := false
+	if window != nil {
+		window.Controls.Keys = append(window.Controls.Keys,
+			videoio.KeyAction{Key: 'd', Help: "show read digits", Do: func() { showRead = !showRead }})
+	}
+
+	gray, warped := gocv.NewMat(), gocv.NewMat()
+	defer gray.Close()
+	defer warped.Close()
+	// The last puzzle and its solution, so that the same puzzle on consecutive frames is solved once
+	var last, solution grid
+	var solveErr error
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		st := draw.DefaultStyle
+		status := func(text string) {
+			draw.TextWithBackground(img, text, image.Pt(0, st.TextSize(text).Y+2*st.Padding), st)
+			metrics.Overlay(img, stats, st)
+		}
+
+		stop := stats.Start("grid")
+		gocv.CvtColor(*img, &gray, gocv.ColorBGRToGray)
+		corners, found := findGrid(gray)
+		stop()
+		stats.Frame()
+		if !found {
+			status("No grid found")
+			return
+		}
+		draw.Outline(img, corners[:], st)
+
+		stop = stats.Start("digits")
+		m := gridTransform(corners, false)
+		gocv.WarpPerspective(gray, &warped, m, image.Pt(9*cellSize, 9*cellSize))
+		m.Close()
+		gocv.AdaptiveThreshold(warped, &warped, 255, gocv.AdaptiveThresholdGaussian, gocv.ThresholdBinaryInv,
+			blockSize, threshC)
+		given := recognizer.Read(warped)
+		stop()
+
+		if given != last {
+			last = given
+			stop = stats.Start("solve")
+			solution, solveErr = solve(given, maxSteps)
+			stop()
+			if count(given) < minGiven {
+				solveErr = fmt.Errorf("Only %d digits read", count(given))
+			}
+			logging.Infof("Read puzzle:\n%v", given)
+			if solveErr != nil {
+				logging.Warnf("%v", solveErr)
+			} else {
+				logging.Debugf("Solution:\n%v", solution)
+			}
+		}
+
+		switch {
+		case showRead:
+			drawDigits(img, corners, given, grid{}, st.WithColor(draw.Red))
+			status(fmt.Sprintf("%d digits read", count(given)))
+		case solveErr != nil:
+			status(solveErr.Error())
+		default:
+			drawDigits(img, corners, solution, given, digitStyle)
+			status("Solved")
+		}
+	})
+	if err != nil && err != videoio.ErrStopped {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	return nil
+}
+
+// count returns the number of filled cells
+func count(g grid) int {
+	n := 0
+	for _, d := range g {
+		if d != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// Draws digits of g in the cells that are empty in skip, in the perspective of the grid
+func drawDigits(img *gocv.Mat, corners [4]image.Point, g, skip grid, st draw.Style) {
+	side := 9 * cellSize
+	overlay := gocv.NewMatWithSize(side, side, gocv.MatTypeCV8UC3)
+	defer overlay.Close()
+	for i, d := range g {
+		if d == 0 || skip[i] != 0 {
+			continue
+		}
+		text := fmt.Sprint(d)
+		size := gocv.GetTextSize(text, st.Font, st.FontScale, st.TextThickness)
+		org := image.Pt(i%9*cellSize+(cellSize-size.X)/2, i/9*cellSize+(cellSize+size.Y)/2)
+		gocv.PutText(&overlay, text, org, st.Font, st.FontScale, st.TextColor, st.TextThickness)
+	}
+
+	m := gridTransform(corners, true)
+	defer m.Close()
+	warped := gocv.NewMat()
+	defer warped.Close()
+	gocv.WarpPerspective(overlay, &warped, m, image.Pt(img.Cols(), img.Rows()))
+	mask := gocv.NewMat()
+	defer mask.Close()
+	gocv.CvtColor(warped, &mask, gocv.ColorBGRToGray)
+	gocv.Threshold(mask, &mask, 0, 255, gocv.ThresholdBinary)
+	warped.CopyToWithMask(img, mask)
+}
@@ -0,0 +1,232 @@
+// This example upscales images 2-4 times with a super-resolution network and compares
+// the result with bicubic interpolation
+//
+// Call: gocv-examples superres -input "photos/*.jpg" -model FSRCNN_x2.pb [-out-dir superres] [-compare]
+// All images matching the input, or all images of a directory, are processed. Results are saved
+// to -out-dir as <name>_x<scale>.png; with -compare also <name>_compare.png with bicubic on the left
+// Parameters can also be set with SUPERRES_* environment variables or a config file, see internal/config
+//
+// Models of the OpenCV dnn_superres module are used, the algorithm and the scale are taken from
+// the file name, e.g. EDSR_x4.pb or FSRCNN_x3.pb:
+// https://github.com/Saafke/EDSR_Tensorflow/tree/master/models (EDSR, best quality, slow)
+// https://github.com/Saafke/FSRCNN_Tensorflow/tree/master/models (FSRCNN, fast)
+// https://github.com/fannymonori/TF-ESPCN/tree/master/export (ESPCN, fast)
+// dnn_superres is not exposed by GoCV at the moment of writing, so its pre- and postprocessing
+// is done here: EDSR works on BGR with the mean of its training set subtracted, FSRCNN and ESPCN
+// work on the luma channel only, and chroma is upscaled with bicubic interpolation
+
+package superres
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/models"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const outDir = "superres"
+
+// Mean BGR values of the EDSR training set (DIV2K)
+var edsrMean = [3]float32{103.1545, 111.5639, 114.35629}
+
+// Algorithms by the prefix of the model file name
+var algorithms = map[string]bool{"edsr": true, "fsrcnn": true, "espcn": true}
+
+// parseModelName returns the algorithm and the scale from a model file name like EDSR_x4.pb
+func parseModelName(path string) (algo string, scale int, err error) {
+	name := strings.ToLower(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	parts := strings.Split(name, "_x")
+	if len(parts) == 2 && algorithms[parts[0]] {
+		if scale, err = strconv.Atoi(parts[1]); err == nil && scale >= 2 && scale <= 4 {
+			return parts[0], scale, nil
+		}
+	}
+	return "", 0, fmt.Errorf("Model file name should be like EDSR_x4.pb or FSRCNN_x2.pb, with scale 2-4: %s", path)
+}
+
+// Upscaler runs a super-resolution network
+type Upscaler struct {
+	Net   *gocv.Net
+	Algo  string // edsr, fsrcnn or espcn
+	Scale int
+}
+
+// Upscale returns the image enlarged Scale times
+func (u *Upscaler) Upscale(img gocv.Mat) gocv.Mat {
+	if u.Algo == "edsr" {
+		return u.upscaleBGR(img)
+	}
+	return u.upscaleLuma(img)
+}
+
+// EDSR takes and returns BGR with the mean subtracted
+func (u *Upscaler) upscaleBGR(img gocv.Mat) gocv.Mat {
+	blob := gocv.BlobFromImage(img, 1.0, image.Pt(img.Cols(), img.Rows()),
+		gocv.NewScalar(float64(edsrMean[0]), float64(edsrMean[1]), float64(edsrMean[2]), 0), false, false)
+	defer blob.Close()
+	u.Net.SetInput(blob, "")
+	out := u.Net.Forward("")
+	defer out.Close()
+
+	channels := make([]gocv.Mat, 3)
+	for c := range channels {
+		channels[c] = gocv.GetBlobChannel(out, 0, c)
+		channels[c].AddFloat(edsrMean[c])
+		defer channels[c].Close()
+	}
+	merged := gocv.NewMat()
+	defer merged.Close()
+	gocv.Merge(channels, &merged)
+	result := gocv.NewMat()
+	merged.ConvertTo(&result, gocv.MatTypeCV8UC3)
+	return result
+}
+
+// FSRCNN and ESPCN take and return luma scaled to [0, 1]
+func (u *Upscaler) upscaleLuma(img gocv.Mat) gocv.Mat {
+	ycrcb := gocv.NewMat()
+	defer ycrcb.Close()
+	gocv.CvtColor(img, &ycrcb, gocv.ColorBGRToYCrCb)
+	planes := gocv.Split(ycrcb)
+	defer func() {
+		for _, p := range planes {
+			p.Close()
+		}
+	}()
+
+	blob := gocv.BlobFromImage(planes[0], 1.0/255, image.Pt(img.Cols(), img.Rows()), gocv.NewScalar(0, 0, 0, 0), false, false)
+	defer blob.Close()
+	u.Net.SetInput(blob, "")
+	out := u.Net.Forward("")
+	defer out.Close()
+	luma := gocv.GetBlobChannel(out, 0, 0)
+	defer luma.Close()
+
+	size := image.Pt(img.Cols()*u.Scale, img.Rows()*u.Scale)
+	luma.ConvertToWithParams(&planes[0], gocv.MatTypeCV8U, 255, 0)
+	gocv.Resize(planes[1], &planes[1], size, 0, 0, gocv.InterpolationCubic)
+	gocv.Resize(planes[2], &planes[2], size, 0, 0, gocv.InterpolationCubic)
+	gocv.Merge(planes, &ycrcb)
+	result := gocv.NewMat()
+	gocv.CvtColor(ycrcb, &result, gocv.ColorYCrCbToBGR)
+	return result
+}
+
+// Run upscales the images given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples superres", flag.ExitOnError)
+	input := fs.String("input", "", "Image file, directory or glob pattern of images")
+	model := fs.String("model", "FSRCNN_x2.pb", "Super-resolution model, named like EDSR_x4.pb")
+	dir := fs.String("out-dir", outDir, "Output directory")
+	compare := fs.Bool("compare", false, "Also save the bicubic and the network results side by side")
+	logging.RegisterFlags(fs)
+	if err := config.Load(fs, args, "SUPERRES"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("superres")
+
+	if *input == "" {
+		return errors.New("Input is required, see 'gocv-examples superres -h'")
+	}
+	algo, scale, err := parseModelName(*model)
+	if err != nil {
+		return err
+	}
+	files, err := videoio.ImageFiles(*input)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		return fmt.Errorf("Error creating output directory: %v", err)
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{DNN: true, Camera: -1})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	path, err := models.Resolve(*model)
+	if err != nil {
+		return fmt.Errorf("Error loading model, see 'gocv-examples superres -h': %v", err)
+	}
+	net := gocv.ReadNet(path, "")
+	if net.Empty() {
+		return errors.New("Error loading model")
+	}
+	sd.OnClose("model", net.Close)
+	up := &Upscaler{Net: &net, Algo: algo, Scale: scale}
+
+	done := 0
+	for _, file := range files {
+		if sd.Context().Err() != nil {
+			break
+		}
+		img := gocv.IMRead(file, gocv.IMReadColor)
+		if img.Empty() {
+			img.Close()
+			continue
+		}
+		if err := process(up, img, file, *dir, *compare); err != nil {
+			logging.Errorf("Error processing %s: %v", file, err)
+		} else {
+			done++
+		}
+		img.Close()
+	}
+	logging.Infof("Upscaled %d images to %s", done, *dir)
+	return nil
+}
+
+// Upscales the image and saves the result, and the comparison if needed
+func process(up *Upscaler, img gocv.Mat, file, dir string, compare bool) error {
+	start := time.Now()
+	result := up.Upscale(img)
+	defer result.Close()
+	logging.Infof("%s: %dx%d to %dx%d in %v", filepath.Base(file), img.Cols(), img.Rows(),
+		result.Cols(), result.Rows(), time.Since(start).Round(time.Millisecond))
+
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	out := filepath.Join(dir, fmt.Sprintf("%s_x%d.png", name, up.Scale))
+	if !gocv.IMWrite(out, result) {
+		return fmt.Errorf("Cannot write %s", out)
+	}
+	if !compare {
+		return nil
+	}
+
+	bicubic := gocv.NewMat()
+	defer bicubic.Close()
+	gocv.Resize(img, &bicubic, image.Pt(result.Cols(), result.Rows()), 0, 0, gocv.InterpolationCubic)
+	st := draw.DefaultStyle
+	labelPt := image.Pt(0, st.TextSize("B").Y+2*st.Padding)
+	draw.TextWithBackground(&bicubic, "Bicubic", labelPt, st)
+	labelled := result.Clone()
+	defer labelled.Close()
+	draw.TextWithBackground(&labelled, strings.ToUpper(up.Algo), labelPt, st)
+	side := gocv.NewMat()
+	defer side.Close()
+	gocv.Hconcat(bicubic, labelled, &side)
+	out = filepath.Join(dir, name+"_compare.png")
+	if !gocv.IMWrite(out, side) {
+		return fmt.Errorf("Cannot write %s", out)
+	}
+	return nil
+}
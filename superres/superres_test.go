@@ -0,0 +1,26 @@
+package superres
+
+import "testing"
+
+func TestParseModelName(t *testing.T) {
+	tests := []struct {
+		path  string
+		algo  string
+		scale int
+	}{
+		{"EDSR_x4.pb", "edsr", 4},
+		{"/models/FSRCNN_x3.pb", "fsrcnn", 3},
+		{"ESPCN_x2.pb", "espcn", 2},
+	}
+	for _, tt := range tests {
+		algo, scale, err := parseModelName(tt.path)
+		if err != nil || algo != tt.algo || scale != tt.scale {
+			t.Errorf("parseModelName(%q) = %q, %d, %v", tt.path, algo, scale, err)
+		}
+	}
+	for _, path := range []string{"model.pb", "EDSR_x8.pb", "LapSRN_x2.pb"} {
+		if _, _, err := parseModelName(path); err == nil {
+			t.Errorf("Expected error for %q", path)
+		}
+	}
+}
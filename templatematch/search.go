@@ -0,0 +1,164 @@
+package templatematch
+
+import (
+	"fmt"
+	"image"
+	"sort"
+	"strings"
+
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/nms"
+	"gocv.io/x/gocv"
+)
+
+// method is a matching method of MatchTemplate
+type method struct {
+	name   string
+	mode   gocv.TemplateMatchMode
+	normed bool // Scores are from 0 to 1 (-1 to 1 for ccoeff-normed) and can be thresholded
+	lower  bool // Lower values are better matches
+}
+
+// Methods in the order of OpenCV
+var methods = []method{
+	{"sqdiff", gocv.TmSqdiff, false, true},
+	{"sqdiff-normed", gocv.TmSqdiffNormed, true, true},
+	{"ccorr", gocv.TmCcorr, false, false},
+	{"ccorr-normed", gocv.TmCcorrNormed, true, false},
+	{"ccoeff", gocv.TmCcoeff, false, false},
+	{"ccoeff-normed", gocv.TmCcoeffNormed, true, false},
+}
+
+func methodNames() string {
+	var names []string
+	for _, m := range methods {
+		names = append(names, m.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func findMethod(name string) (method, error) {
+	for _, m := range methods {
+		if m.name == strings.ToLower(name) {
+			return m, nil
+		}
+	}
+	return method{}, fmt.Errorf("Unknown method %q, available: %s", name, methodNames())
+}
+
+// scales returns n scales evenly spaced from min to max
+func scales(min, max float64, n int) []float64 {
+	if n <= 1 || min == max {
+		return []float64{min}
+	}
+	s := make([]float64, n)
+	for i := range s {
+		s[i] = min + (max-min)*float64(i)/float64(n-1)
+	}
+	return s
+}
+
+// score converts a value of the method to a score where higher is better. Values of methods which
+// are not normalized grow with the template area, so they are divided by it to compare scales
+func (m method) score(v float32, area int) float32 {
+	if !m.normed {
+		v /= float32(area)
+	}
+	if m.lower {
+		if m.normed {
+			return 1 - v
+		}
+		return -v
+	}
+	return v
+}
+
+// peaks returns positions of the local maxima of the scores, row by row, which are at least thr.
+// A position is a maximum if no neighbour in the 3x3 window has a higher score
+func peaks(scores []float32, cols, rows int, thr float32) []image.Point {
+	var pts []image.Point
+	for y := 0; y < rows; y++ {
+		for x := 0; x < cols; x++ {
+			s := scores[y*cols+x]
+			if s < thr {
+				continue
+			}
+			max := true
+			for dy := -1; dy <= 1 && max; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx >= 0 && ny >= 0 && nx < cols && ny < rows && scores[ny*cols+nx] > s {
+						max = false
+						break
+					}
+				}
+			}
+			if max {
+				pts = append(pts, image.Pt(x, y))
+			}
+		}
+	}
+	return pts
+}
+
+// search finds the template on the image at each scale. With a normalized method all matches
+// with score at least thr are returned after NMS, otherwise only the best match. Scores of
+// sqdiff-normed are inverted, so that higher is better for all methods
+func search(img, templ gocv.Mat, m method, scaleList []float64, thr float32, ovrThr float64) []detection.Detection {
+	var boxes []nms.Box
+	var best nms.Box
+	found := false
+	scaled, result := gocv.NewMat(), gocv.NewMat()
+	defer scaled.Close()
+	defer result.Close()
+	for _, s := range scaleList {
+		size := image.Pt(int(float64(templ.Cols())*s), int(float64(templ.Rows())*s))
+		if size.X < 1 || size.Y < 1 || size.X > img.Cols() || size.Y > img.Rows() {
+			continue
+		}
+		interp := gocv.InterpolationLinear
+		if s < 1 {
+			interp = gocv.InterpolationArea
+		}
+		gocv.Resize(templ, &scaled, size, 0, 0, interp)
+		noMask := gocv.NewMat()
+		gocv.MatchTemplate(img, scaled, &result, m.mode, noMask)
+		noMask.Close()
+		data, err := result.DataPtrFloat32()
+		if err != nil {
+			continue
+		}
+		scores := make([]float32, len(data))
+		for i, v := range data {
+			scores[i] = m.score(v, size.X*size.Y)
+		}
+
+		if !m.normed {
+			_, _, _, loc := gocv.MinMaxLoc(result)
+			if m.lower {
+				_, _, loc, _ = gocv.MinMaxLoc(result)
+			}
+			b := nms.Box{Rect: image.Rectangle{loc, loc.Add(size)}, Score: scores[loc.Y*result.Cols()+loc.X]}
+			if !found || b.Score > best.Score {
+				best, found = b, true
+			}
+			continue
+		}
+		for _, p := range peaks(scores, result.Cols(), result.Rows(), thr) {
+			boxes = append(boxes, nms.Box{Rect: image.Rectangle{p, p.Add(size)}, Score: scores[p.Y*result.Cols()+p.X]})
+		}
+	}
+
+	if !m.normed {
+		if !found {
+			return nil
+		}
+		boxes = []nms.Box{best}
+	}
+	var dets []detection.Detection
+	for _, i := range nms.Hard(boxes, ovrThr) {
+		dets = append(dets, detection.Detection{Label: "template", Confidence: boxes[i].Score, BBox: boxes[i].Rect})
+	}
+	sort.SliceStable(dets, func(i, j int) bool { return dets[i].Confidence > dets[j].Confidence })
+	return dets
+}
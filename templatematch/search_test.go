@@ -0,0 +1,51 @@
+package templatematch
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func TestScales(t *testing.T) {
+	got := scales(0.5, 1.5, 5)
+	want := []float64{0.5, 0.75, 1, 1.25, 1.5}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 1e-9 {
+			t.Fatalf("Expected %v, got %v", want, got)
+		}
+	}
+	if got := scales(1, 2, 1); len(got) != 1 || got[0] != 1 {
+		t.Errorf("Single scale should be the minimum, got %v", got)
+	}
+}
+
+func TestScore(t *testing.T) {
+	sqdiffNormed, _ := findMethod("sqdiff-normed")
+	if s := sqdiffNormed.score(0.25, 100); s != 0.75 {
+		t.Errorf("Expected inverted normed score 0.75, got %v", s)
+	}
+	ccorr, _ := findMethod("ccorr")
+	if s := ccorr.score(500, 100); s != 5 {
+		t.Errorf("Expected score divided by area, got %v", s)
+	}
+	sqdiff, _ := findMethod("SQDIFF")
+	if s := sqdiff.score(500, 100); s != -5 {
+		t.Errorf("Expected negated score divided by area, got %v", s)
+	}
+	if _, err := findMethod("fast"); err == nil {
+		t.Error("Expected error for unknown method")
+	}
+}
+
+func TestPeaks(t *testing.T) {
+	scores := []float32{
+		0.1, 0.2, 0.1, 0.0, 0.0,
+		0.2, 0.9, 0.3, 0.0, 0.85,
+		0.1, 0.3, 0.2, 0.0, 0.5,
+	}
+	got := peaks(scores, 5, 3, 0.8)
+	want := []image.Point{{1, 1}, {4, 1}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Expected peaks %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,164 @@
+// This example finds a template image at an unknown scale with MatchTemplate over a range of
+// template sizes, and compares the matching methods of OpenCV
+//
+// Call: gocv-examples template -input scene.jpg -template logo.png [-method ccoeff-normed] [-thr 0.8] [-compare]
+// Input can be images, a video file, a camera ID or a stream URL
+// Parameters can also be set with TEMPLATE_* environment variables or a config file, see internal/config
+//
+// The template is resized to -scales sizes from -min-scale to -max-scale and matched on the grayscale
+// frame at each of them. With normalized methods all matches scoring at least -thr are drawn after NMS,
+// other methods give values growing with the template size and brightness, so only their best match
+// is drawn. With -compare every method runs on each frame and its best match and time are shown.
+// Template matching does not handle rotation or perspective, see the orb example for that
+
+package templatematch
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	defaultMethod = "ccoeff-normed"
+	matchThr      = 0.8
+	matchThrStep  = 0.02 // Change of the threshold by +/- keys
+	ovrThr        = 0.3  // Overlapping threshold for NMS
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Run finds the template on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples template", flag.ExitOnError)
+	input := fs.String("input", "", "Image, directory or glob pattern of images, video file, camera ID or stream URL")
+	templPath := fs.String("template", "", "Template image")
+	methodName := fs.String("method", defaultMethod, "Matching method: "+methodNames())
+	thr := fs.Float64("thr", matchThr, "Minimal score of a match, for normalized methods")
+	minScale := fs.Float64("min-scale", 0.5, "Smallest template scale")
+	maxScale := fs.Float64("max-scale", 1.5, "Largest template scale")
+	numScales := fs.Int("scales", 11, "Number of scales to search")
+	compare := fs.Bool("compare", false, "Run all methods and show the best match of each")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "TEMPLATE"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("template")
+
+	if *input == "" || *templPath == "" {
+		return errors.New("Input and template are required, see 'gocv-examples template -h'")
+	}
+	m, err := findMethod(*methodName)
+	if err != nil {
+		return err
+	}
+	if *minScale <= 0 || *maxScale < *minScale || *numScales < 1 {
+		return errors.New("Scales should be positive, max scale at least min scale and number of scales at least 1")
+	}
+	scaleList := scales(*minScale, *maxScale, *numScales)
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	templ := gocv.IMRead(*templPath, gocv.IMReadGrayScale)
+	if templ.Empty() {
+		return fmt.Errorf("Error reading template %s", *templPath)
+	}
+	defer templ.Close()
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "Template matching")
+	if err != nil {
+		return err
+	}
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			*thr += float64(step) * matchThrStep
+			if *thr < matchThrStep {
+				*thr = matchThrStep
+			}
+			return fmt.Sprintf("score %.2f", *thr)
+		}
+	}
+
+	gray := gocv.NewMat()
+	defer gray.Close()
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		gocv.CvtColor(*img, &gray, gocv.ColorBGRToGray)
+		if *compare {
+			var legend []draw.LegendEntry
+			for i, cm := range methods {
+				start := time.Now()
+				dets := search(gray, templ, cm, scaleList, float32(*thr), ovrThr)
+				elapsed := time.Since(start).Round(time.Millisecond)
+				text := fmt.Sprintf("%s: no match, %v", cm.name, elapsed)
+				if len(dets) > 0 {
+					text = fmt.Sprintf("%s: %.3g at %v, %v", cm.name, dets[0].Confidence, dets[0].BBox.Min, elapsed)
+					gocv.Rectangle(img, dets[0].BBox, draw.ClassColor(i), 2)
+				}
+				logging.Infof("%s", text)
+				legend = append(legend, draw.LegendEntry{Color: draw.ClassColor(i), Text: text})
+			}
+			stats.Frame()
+			draw.Legend(img, legend, image.Pt(0, 0), draw.DefaultStyle)
+			metrics.Overlay(img, stats, draw.DefaultStyle)
+			return
+		}
+
+		stop := stats.Start("match")
+		dets := search(gray, templ, m, scaleList, float32(*thr), ovrThr)
+		stop()
+		stats.Frame()
+		logging.Infof("Matches: %d", len(dets))
+		for _, d := range dets {
+			scale := float64(d.BBox.Dx()) / float64(templ.Cols())
+			draw.LabelBox(img, d.BBox, fmt.Sprintf("%.2f x%.2f", d.Confidence, scale), draw.DefaultStyle)
+		}
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+//go:build ignore
+// +build ignore
+
+// Generates the test fixtures in this directory: go run gen.go
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"os"
+)
+
+func main() {
+	// Checkerboard of 10 pixel squares
+	checker := image.NewGray(image.Rect(0, 0, 80, 60))
+	for y := 0; y < 60; y++ {
+		for x := 0; x < 80; x++ {
+			if (x/10+y/10)%2 == 0 {
+				checker.SetGray(x, y, color.Gray{255})
+			}
+		}
+	}
+	save("checkerboard.png", checker)
+
+	// Horizontal color ramp: blue increases to the right, red decreases
+	ramp := image.NewRGBA(image.Rect(0, 0, 256, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 256; x++ {
+			ramp.Set(x, y, color.RGBA{uint8(255 - x), 0, uint8(x), 255})
+		}
+	}
+	save("ramp.png", ramp)
+
+	// Playing card downscaled by half from the ORB example patterns
+	f, err := os.Open("../orb/real_cards/train_img/King of Hearts.png")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	src, err := png.Decode(f)
+	if err != nil {
+		log.Fatal(err)
+	}
+	b := src.Bounds()
+	card := image.NewRGBA(image.Rect(0, 0, b.Dx()/2, b.Dy()/2))
+	for y := 0; y < b.Dy()/2; y++ {
+		for x := 0; x < b.Dx()/2; x++ {
+			card.Set(x, y, src.At(b.Min.X+2*x, b.Min.Y+2*y))
+		}
+	}
+	save("card.png", card)
+}
+
+func save(name string, img image.Image) {
+	f, err := os.Create(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		log.Fatal(err)
+	}
+}
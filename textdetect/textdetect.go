@@ -0,0 +1,132 @@
+// This example finds text in natural images with the EAST scene text detector, drawing rotated text boxes
+//
+// Call: gocv-examples text -input sign.jpg [-model frozen_east_text_detection.pb] [-size 320]
+// Input can be images, a video file, a camera ID or a stream URL
+// Parameters can also be set with TEXT_* environment variables or a config file, see internal/config
+//
+// The model is not downloaded automatically, it is distributed as an archive:
+// https://www.dropbox.com/s/r2ingd0l3zt8hxs/frozen_east_text_detection.tar.gz
+// Extract frozen_east_text_detection.pb to the current directory or the model cache, see internal/models
+//
+// Score and geometry maps are decoded in Go, see detection.DecodeEAST, and overlapping boxes are
+// suppressed by their bounding boxes with the shared NMS. Text found here can be recognized with
+// 'gocv-examples ocr -east frozen_east_text_detection.pb'
+//
+// EAST: An Efficient and Accurate Scene Text Detector, https://arxiv.org/abs/1704.03155
+
+package textdetect
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	modelPath   = "frozen_east_text_detection.pb"
+	confThrStep = 0.05 // Change of the threshold by +/- keys
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Run finds text on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples text", flag.ExitOnError)
+	input := fs.String("input", "", "Image, directory or glob pattern of images, video file, camera ID or stream URL")
+	model := fs.String("model", modelPath, "EAST model file")
+	size := fs.Int("size", detection.DefaultEASTSize, "Network input size, a multiple of 32; larger finds smaller text")
+	confThr := fs.Float64("conf-thr", detection.DefaultEASTConfThr, "Text confidence threshold")
+	ovrThr := fs.Float64("nms-thr", detection.DefaultEASTOvrThr, "Overlapping threshold for NMS")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "TEXT"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("text")
+
+	if *input == "" {
+		return errors.New("Input is required, see 'gocv-examples text -h'")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{DNN: true, Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	east, err := detection.LoadEAST(sd, *model, *size)
+	if err != nil {
+		return err
+	}
+	east.ConfThr, east.OvrThr = float32(*confThr), *ovrThr
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "EAST text detection")
+	if err != nil {
+		return err
+	}
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			east.ConfThr += float32(step) * confThrStep
+			if east.ConfThr < confThrStep {
+				east.ConfThr = confThrStep
+			}
+			return fmt.Sprintf("confidence %.2f", east.ConfThr)
+		}
+	}
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		stop := stats.Start("east")
+		dets, err := east.Detect(*img)
+		stop()
+		stats.Frame()
+		if err != nil {
+			logging.Errorf("Error detecting text: %v", err)
+		}
+		logging.Debugf("Text boxes: %d", len(dets))
+		for _, d := range dets {
+			draw.Outline(img, d.Quad, draw.DefaultStyle)
+		}
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+// This example detects objects with a quantized TensorFlow Lite SSD model, for ARM boards like
+// the Raspberry Pi where Yolo 4 with the DNN module of OpenCV is too slow or its weights too large
+//
+// Call: gocv-examples tflite [-input 0] [-model file.tflite] [-labels file.txt] [-threads 4] [-conf 0.5]
+// Input can be a camera ID (default 0), a video file, a stream URL, "picam" or images
+// Parameters can also be set with TFLITE_* environment variables or a config file, see internal/config
+//
+// TensorFlow Lite is linked through github.com/mattn/go-tflite, which needs the TensorFlow Lite C library,
+// so this example is only available in binaries built with -tags tflite
+//
+// The SSD MobileNet v2 COCO model quantized to 8 bits (about 6 MB) and its labels are downloaded on first run:
+// https://github.com/google-coral/test_data/blob/master/ssd_mobilenet_v2_coco_quant_postprocess.tflite
+// https://github.com/google-coral/test_data/blob/master/coco_labels.txt
+// Other SSD models ending with the TFLite detection postprocess op work as well, quantized or not
+//
+// On a board shared with other services, -cpus 2-3 keeps the example on some cores and -cv-threads 1
+// keeps OpenCV from spreading frame conversions over all of them, see internal/threads
+
+package tflite
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/models"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/threads"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID       = "0" // Default input
+	modelPath   = "ssd_mobilenet_v2_coco_quant_postprocess.tflite"
+	labelsPath  = "coco_labels.txt"
+	numThreads  = 4    // Inference threads, the cores of a Raspberry Pi 4
+	confThr     = 0.5  // Detection confidence threshold
+	confThrStep = 0.05 // Change of the threshold by +/- keys
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Run detects objects on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples tflite", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, picam, image file, directory or glob pattern of images")
+	model := fs.String("model", modelPath, "TFLite SSD model with the detection postprocess op")
+	labels := fs.String("labels", labelsPath, "Class labels of the model, one 'ID label' per line")
+	tfThreads := fs.Int("threads", numThreads, "Number of inference threads")
+	conf := fs.Float64("conf", confThr, "Detection confidence threshold")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	var threadOpts threads.Options
+	threadOpts.RegisterFlags(fs)
+	if err := config.Load(fs, args, "TFLITE"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("tflite")
+
+	if !detection.TFLiteAvailable {
+		return errors.New("This example needs TensorFlow Lite, build with -tags tflite, see 'gocv-examples tflite -h'")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+	if err := threadOpts.Setup(); err != nil {
+		return err
+	}
+
+	// Initialize model
+	labelFile, err := models.Resolve(*labels)
+	if err != nil {
+		return fmt.Errorf("Error loading class labels: %v", err)
+	}
+	classLabels, err := detection.ReadIndexedLabels(labelFile)
+	if err != nil {
+		return fmt.Errorf("Error loading class labels: %v", err)
+	}
+	path, err := models.Resolve(*model)
+	if err != nil {
+		return fmt.Errorf("Error loading model, see 'gocv-examples tflite -h': %v", err)
+	}
+	detector, err := detection.NewTFLiteDetector(path, classLabels, *tfThreads)
+	if err != nil {
+		return fmt.Errorf("Error loading model: %v", err)
+	}
+	sd.OnClose("model", detector.Close)
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	detector.ConfThr, detector.Stats = float32(*conf), stats
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, "TensorFlow Lite")
+	if err != nil {
+		return err
+	}
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			detector.ConfThr += float32(step) * confThrStep
+			if detector.ConfThr < confThrStep {
+				detector.ConfThr = confThrStep
+			}
+			if detector.ConfThr > 1-confThrStep {
+				detector.ConfThr = 1 - confThrStep
+			}
+			return fmt.Sprintf("confidence %.2f", detector.ConfThr)
+		}
+	}
+
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		dets, err := detector.Detect(*img)
+		if err != nil {
+			logging.Errorf("Error detecting objects: %v", err)
+		}
+		stats.Frame()
+		logging.Debugf("Detected objects: %d, %v", len(dets), stats)
+		for _, d := range dets {
+			draw.LabelBox(img, d.BBox, fmt.Sprintf("%s %.2f", d.Label, d.Confidence),
+				draw.DefaultStyle.WithColor(draw.LabelColor(d.Label)))
+		}
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}
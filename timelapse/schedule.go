@@ -0,0 +1,71 @@
+package timelapse
+
+import "time"
+
+// schedule decides when to capture frames: at start and then every interval, until duration
+// has passed since start, or without end if duration is 0
+type schedule struct {
+	start    time.Time
+	interval time.Duration
+	duration time.Duration
+	slot     int // Number of the next capture on the interval grid
+	captured int
+}
+
+// due reports whether a frame should be captured at time now. Captures missed by a slow
+// input are skipped rather than made up for
+func (s *schedule) due(now time.Time) bool {
+	if s.done(now) || now.Before(s.next()) {
+		return false
+	}
+	s.slot = int(now.Sub(s.start)/s.interval) + 1
+	s.captured++
+	return true
+}
+
+// next returns the time of the next capture
+func (s *schedule) next() time.Time {
+	return s.start.Add(time.Duration(s.slot) * s.interval)
+}
+
+// done reports whether the capture duration has passed
+func (s *schedule) done(now time.Time) bool {
+	return s.duration > 0 && now.Sub(s.start) >= s.duration
+}
+
+// Limits of the brightness correction
+const (
+	minGain = 0.5
+	maxGain = 2.0
+)
+
+// deflicker evens out the brightness of consecutive frames: each frame is scaled towards the mean
+// brightness of the recent frames, so that auto exposure jumps and passing clouds do not flicker
+// while slow changes like sunset are kept
+type deflicker struct {
+	window  int
+	history []float64
+}
+
+// gain returns the multiplier of the frame with the mean brightness
+func (d *deflicker) gain(mean float64) float64 {
+	d.history = append(d.history, mean)
+	if len(d.history) > d.window {
+		d.history = d.history[len(d.history)-d.window:]
+	}
+	if mean <= 0 {
+		return 1
+	}
+	var sum float64
+	for _, m := range d.history {
+		sum += m
+	}
+	g := sum / float64(len(d.history)) / mean
+	if g < minGain {
+		return minGain
+	}
+	if g > maxGain {
+		return maxGain
+	}
+	return g
+}
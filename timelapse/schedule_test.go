@@ -0,0 +1,48 @@
+package timelapse
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	start := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := &schedule{start: start, interval: 10 * time.Second, duration: 35 * time.Second}
+	var got []int
+	for sec := 0; sec <= 40; sec++ {
+		if s.due(start.Add(time.Duration(sec) * time.Second)) {
+			got = append(got, sec)
+		}
+	}
+	if len(got) != 4 || got[0] != 0 || got[1] != 10 || got[3] != 30 {
+		t.Errorf("Captured at %v, want 0, 10, 20, 30", got)
+	}
+	if !s.done(start.Add(35*time.Second)) || s.done(start.Add(34*time.Second)) {
+		t.Error("Capture should end after the duration")
+	}
+
+	// A late frame is captured once, and the schedule keeps its grid
+	s = &schedule{start: start, interval: 10 * time.Second}
+	s.due(start)
+	if !s.due(start.Add(25*time.Second)) || s.due(start.Add(26*time.Second)) || s.next() != start.Add(30*time.Second) ||
+		s.captured != 2 {
+		t.Errorf("Unexpected schedule after a late frame, next %v", s.next())
+	}
+}
+
+func TestDeflicker(t *testing.T) {
+	d := &deflicker{window: 3}
+	if g := d.gain(100); g != 1 {
+		t.Errorf("First frame gain %v, want 1", g)
+	}
+	d.gain(100)
+	// A sudden bright frame is darkened towards the recent mean
+	if g := d.gain(160); math.Abs(g-120.0/160) > 1e-9 {
+		t.Errorf("Bright frame gain %v, want %v", g, 120.0/160)
+	}
+	// Gain is limited
+	if g := d.gain(1); g != maxGain {
+		t.Errorf("Dark frame gain %v, want %v", g, maxGain)
+	}
+}
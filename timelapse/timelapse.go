@@ -0,0 +1,172 @@
+// This example captures a camera frame at regular intervals and assembles the frames
+// into a timelapse video
+//
+// Call: gocv-examples timelapse [-input 0] [-interval 10s] [-duration 2h] [-out timelapse.avi] [-deflicker]
+// Input is usually a camera ID (default 0) or a stream URL. A frame is captured at start and then
+// every -interval until -duration has passed or -max-frames are captured, or until Esc or Ctrl+C;
+// the video is finalized in any case. At -fps 25 and one frame per 10 seconds, an hour gives 14 seconds of video
+// The live view shows the number of captured frames and the time to the next one
+// With -frames-dir the captured frames are also saved as images, e.g. to assemble them again later
+// With -segment 1h, a new video file is started every hour of capture, so that a crash or power loss
+// during a long capture keeps the finished files, see videoio.SegmentSink
+// Parameters can also be set with TIMELAPSE_* environment variables or a config file, see internal/config
+//
+// Deflicker scales the brightness of each frame to the mean brightness of the last -deflicker-window
+// frames. It removes flicker from auto exposure and passing clouds and keeps slow changes like sunset
+
+package timelapse
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID           = "0" // Default input
+	interval        = 10 * time.Second
+	outPath         = "timelapse.avi"
+	videoCodec      = "MJPG"
+	videoFPS        = 25
+	deflickerFrames = 10
+	keyEsc          = 27
+)
+
+// Returns the mean brightness of the frame
+func brightness(img gocv.Mat) float64 {
+	gray := gocv.NewMat()
+	defer gray.Close()
+	gocv.CvtColor(img, &gray, gocv.ColorBGRToGray)
+	return gray.Mean().Val1
+}
+
+// Run captures a timelapse of the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples timelapse", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID or stream URL")
+	every := fs.Duration("interval", interval, "Time between captured frames")
+	out := fs.String("out", outPath, "Output video file")
+	codec := fs.String("codec", videoCodec, "FourCC code of the output video codec")
+	fps := fs.Float64("fps", videoFPS, "Frame rate of the output video")
+	deflick := fs.Bool("deflicker", false, "Even out brightness changes between frames")
+	window := fs.Int("deflicker-window", deflickerFrames, "Number of recent frames to even out brightness over")
+	framesDir := fs.String("frames-dir", "", "Directory to also save the captured frames to")
+	segment := fs.Duration("segment", 0, "Start a new output video file after this time, e.g. 1h, so that an "+
+		"aborted capture keeps all but the last file; 0 for one file")
+	var headless videoio.Headless
+	headless.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	logging.RegisterFlags(fs)
+	if err := config.Load(fs, args, "TIMELAPSE"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("timelapse")
+
+	if *every <= 0 || *fps <= 0 || *window < 1 {
+		return errors.New("Interval, frame rate and deflicker window should be positive")
+	}
+	if len(*codec) != 4 {
+		return fmt.Errorf("Codec should be a FourCC code like MJPG: %q", *codec)
+	}
+	if *framesDir != "" {
+		if err := os.MkdirAll(*framesDir, 0755); err != nil {
+			return fmt.Errorf("Error creating frames directory: %v", err)
+		}
+	}
+	if headless.Duration > 0 {
+		frames := int(headless.Duration / *every) + 1
+		logging.Infof("Capturing %d frames, %.1f s of video", frames, float64(frames) / *fps)
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: []string{*codec}, Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	var sink videoio.FrameSink = videoio.NewVideoSink(*out, *codec, *fps)
+	if *segment > 0 {
+		sink = videoio.NewSegmentSink(*out, *codec, *fps, *segment)
+	}
+	sd.OnClose("output", sink.Close)
+
+	var view *gocv.Window
+	if !headless.Enabled() {
+		view = gocv.NewWindow("Timelapse - press Esc to finish")
+		defer view.Close()
+	}
+
+	sched := &schedule{start: time.Now(), interval: *every, duration: headless.Duration}
+	flicker := &deflicker{window: *window}
+	for sd.Context().Err() == nil && !sched.done(time.Now()) {
+		if headless.MaxFrames > 0 && sched.captured >= headless.MaxFrames {
+			break
+		}
+		img, err := src.Next()
+		if err != nil {
+			logging.Errorf("Error reading input: %v", err)
+			break
+		}
+		if sched.due(time.Now()) {
+			if err := capture(img, sink, flicker, *deflick, *framesDir, sched.captured); err != nil {
+				img.Close()
+				return fmt.Errorf("Error writing output: %v", err)
+			}
+			logging.Infof("Captured frame %d", sched.captured)
+		}
+		if view != nil {
+			text := fmt.Sprintf("Captured %d, next in %v", sched.captured, time.Until(sched.next()).Round(time.Second))
+			st := draw.DefaultStyle
+			draw.TextWithBackground(&img, text, image.Pt(0, st.TextSize(text).Y+2*st.Padding), st)
+			view.IMShow(img)
+			if key := view.WaitKey(1); key == keyEsc || key == 'q' {
+				img.Close()
+				break
+			}
+		}
+		img.Close()
+	}
+	logging.Infof("Saved %d frames to %s", sched.captured, *out)
+	return nil
+}
+
+// Corrects the brightness of the captured frame if needed and writes it to the video and the frames
+// directory
+func capture(img gocv.Mat, sink videoio.FrameSink, flicker *deflicker, deflick bool, framesDir string, n int) error {
+	frame := img.Clone()
+	defer frame.Close()
+	if deflick {
+		gain := flicker.gain(brightness(frame))
+		frame.ConvertToWithParams(&frame, gocv.MatTypeCV8UC3, float32(gain), 0)
+		logging.Debugf("Brightness gain %.2f", gain)
+	}
+	if framesDir != "" {
+		path := filepath.Join(framesDir, fmt.Sprintf("frame_%06d.jpg", n))
+		if !gocv.IMWrite(path, frame) {
+			logging.Errorf("Cannot write %s", path)
+		}
+	}
+	return sink.Write(frame)
+}
@@ -0,0 +1,202 @@
+// This example follows a single object selected by the user with one of the OpenCV trackers
+//
+// Call: gocv-examples track [-input 0] [-tracker csrt|kcf|mil] [-roi x,y,w,h]
+// Draw a rectangle around the object with the mouse and press Enter or Space to start tracking,
+// C cancels the selection. Press I to select another object, e.g. after the track is lost.
+// Without a display the object should be given with -roi
+// Parameters can also be set with TRACK_* environment variables or a config file, see internal/config
+//
+// CSRT is the most accurate and slowest, KCF is faster, MIL is available without opencv_contrib.
+// MOSSE is not exposed by GoCV at the moment of writing
+
+package tracking
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+	"gocv.io/x/gocv/contrib"
+)
+
+const (
+	camID          = "0" // Default input
+	defaultTracker = "csrt"
+	windowTitle    = "Tracking"
+	videoCodec     = "MJPG"
+	videoFPS       = 25
+)
+
+// Trackers by name. A tracker can only be initialized once, so a new one is created for each object
+var trackers = map[string]func() gocv.Tracker{
+	"csrt": contrib.NewTrackerCSRT,
+	"kcf":  contrib.NewTrackerKCF,
+	"mil":  gocv.NewTrackerMIL,
+}
+
+func trackerNames() string {
+	var names []string
+	for name := range trackers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// parseROI parses a rectangle given as "x,y,width,height"
+func parseROI(s string) (image.Rectangle, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return image.Rectangle{}, fmt.Errorf("ROI should be x,y,width,height: %q", s)
+	}
+	var v [4]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return image.Rectangle{}, fmt.Errorf("ROI should be x,y,width,height: %q", s)
+		}
+		v[i] = n
+	}
+	if v[2] <= 0 || v[3] <= 0 {
+		return image.Rectangle{}, fmt.Errorf("ROI should have positive size: %q", s)
+	}
+	return image.Rect(v[0], v[1], v[0]+v[2], v[1]+v[3]), nil
+}
+
+// Run tracks an object on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples track", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file, stream URL, image file, directory or glob pattern of images")
+	name := fs.String("tracker", defaultTracker, "Tracking algorithm: "+trackerNames())
+	roiStr := fs.String("roi", "", "Initial object rectangle x,y,width,height; selected with the mouse if not given")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "TRACK"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("track")
+
+	newTracker, ok := trackers[strings.ToLower(*name)]
+	if !ok {
+		return fmt.Errorf("Unknown tracker %q, available: %s", *name, trackerNames())
+	}
+	var roi image.Rectangle
+	if *roiStr != "" {
+		var err error
+		if roi, err = parseROI(*roiStr); err != nil {
+			return err
+		}
+	} else if outputs.Enabled() {
+		return errors.New("Without a display the object should be given with -roi")
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, windowTitle)
+	if err != nil {
+		return err
+	}
+	reselect := roi.Empty()
+	if window != nil {
+		window.Controls.Keys = append(window.Controls.Keys, videoio.KeyAction{
+			Key: 'i', Help: "select object", Do: func() { reselect = true },
+		})
+	}
+
+	var tracker gocv.Tracker
+	sd.OnClose("tracker", func() error {
+		if tracker != nil {
+			return tracker.Close()
+		}
+		return nil
+	})
+	// Starts tracking of a new object
+	start := func(img gocv.Mat, rect image.Rectangle) {
+		if tracker != nil {
+			tracker.Close()
+		}
+		tracker = newTracker()
+		if !tracker.Init(img, rect) {
+			logging.Errorf("Cannot start tracking %v", rect)
+			tracker.Close()
+			tracker = nil
+			return
+		}
+		logging.Infof("Tracking %v with %s", rect, *name)
+	}
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	lost := false
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		switch {
+		case reselect:
+			reselect = false
+			rect := gocv.SelectROI(windowTitle, *img)
+			if rect.Empty() {
+				logging.Infof("No object selected, press I to select")
+				return
+			}
+			start(*img, rect)
+			return
+		case tracker == nil && !roi.Empty():
+			start(*img, roi)
+			roi = image.Rectangle{}
+			return
+		case tracker == nil:
+			return
+		}
+
+		stop := stats.Start("tracking")
+		rect, ok := tracker.Update(*img)
+		stop()
+		stats.Frame()
+		if ok {
+			if lost {
+				logging.Infof("Track found at %v", rect)
+			}
+			draw.LabelBox(img, rect, *name, draw.DefaultStyle)
+		} else {
+			if !lost {
+				logging.Warnf("Track lost")
+			}
+			st := draw.DefaultStyle.WithColor(draw.Red)
+			draw.TextWithBackground(img, "Track lost - press I to select the object", image.Pt(0, st.TextSize("T").Y+2*st.Padding), st)
+		}
+		lost = !ok
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err != nil && err != videoio.ErrStopped {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	return nil
+}
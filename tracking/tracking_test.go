@@ -0,0 +1,21 @@
+package tracking
+
+import (
+	"image"
+	"testing"
+)
+
+func TestParseROI(t *testing.T) {
+	got, err := parseROI("10, 20,30,40")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := image.Rect(10, 20, 40, 60); got != want {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	for _, s := range []string{"", "1,2,3", "1,2,x,4", "1,2,0,4"} {
+		if _, err := parseROI(s); err == nil {
+			t.Errorf("Expected error for %q", s)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package whiteboard
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// pen turns positions of the pen tip found on frames into stroke segments. The position is
+// smoothed against jitter of the color mask, a tip lost for a few frames lifts the pen, and
+// a jump longer than MaxJump starts a new stroke rather than drawing a line across the board
+type pen struct {
+	Smooth    float64 // Weight of the previous position, from 0 for none to below 1
+	MaxJump   float64 // Pixels
+	MaxMissed int     // Frames without the tip before the pen is lifted
+
+	pos    [2]float64
+	down   bool
+	missed int
+}
+
+// Update takes the tip position of the frame, if found, and returns the segment to draw, if any
+func (p *pen) Update(tip image.Point, found bool) (from, to image.Point, draw bool) {
+	if !found {
+		if p.missed++; p.missed > p.MaxMissed {
+			p.down = false
+		}
+		return
+	}
+	p.missed = 0
+	cur := [2]float64{float64(tip.X), float64(tip.Y)}
+	if !p.down || math.Hypot(cur[0]-p.pos[0], cur[1]-p.pos[1]) > p.MaxJump {
+		p.pos, p.down = cur, true
+		return
+	}
+	prev := p.pos
+	p.pos = [2]float64{p.Smooth*prev[0] + (1-p.Smooth)*cur[0], p.Smooth*prev[1] + (1-p.Smooth)*cur[1]}
+	return round(prev), round(p.pos), true
+}
+
+// Lift ends the current stroke
+func (p *pen) Lift() {
+	p.down = false
+}
+
+func round(p [2]float64) image.Point {
+	return image.Pt(int(math.Round(p[0])), int(math.Round(p[1])))
+}
+
+// ink is a pen color
+type ink struct {
+	Name  string
+	Color color.RGBA
+}
+
+// Pen colors, switched in turn
+var inks = []ink{
+	{"red", color.RGBA{220, 30, 30, 0}},
+	{"blue", color.RGBA{30, 80, 230, 0}},
+	{"green", color.RGBA{20, 170, 60, 0}},
+	{"yellow", color.RGBA{240, 210, 0, 0}},
+	{"purple", color.RGBA{150, 50, 200, 0}},
+}
@@ -0,0 +1,34 @@
+package whiteboard
+
+import (
+	"image"
+	"testing"
+)
+
+func TestPen(t *testing.T) {
+	p := pen{Smooth: 0.5, MaxJump: 50, MaxMissed: 2}
+	type step struct {
+		tip      image.Point
+		found    bool
+		draw     bool
+		from, to image.Point
+	}
+	steps := []step{
+		{image.Pt(0, 0), true, false, image.Point{}, image.Point{}},     // Pen down
+		{image.Pt(20, 0), true, true, image.Pt(0, 0), image.Pt(10, 0)},  // Smoothed halfway
+		{image.Point{}, false, false, image.Point{}, image.Point{}},     // Lost for a frame
+		{image.Pt(30, 0), true, true, image.Pt(10, 0), image.Pt(20, 0)}, // Stroke goes on
+		{image.Pt(200, 0), true, false, image.Point{}, image.Point{}},   // Jump starts a new stroke
+		{image.Pt(200, 10), true, true, image.Pt(200, 0), image.Pt(200, 5)},
+		{image.Point{}, false, false, image.Point{}, image.Point{}},
+		{image.Point{}, false, false, image.Point{}, image.Point{}},
+		{image.Point{}, false, false, image.Point{}, image.Point{}}, // Lifted
+		{image.Pt(200, 10), true, false, image.Point{}, image.Point{}},
+	}
+	for i, s := range steps {
+		from, to, draw := p.Update(s.tip, s.found)
+		if draw != s.draw || draw && (from != s.from || to != s.to) {
+			t.Errorf("Step %d: %v %v-%v, want %v %v-%v", i, draw, from, to, s.draw, s.from, s.to)
+		}
+	}
+}
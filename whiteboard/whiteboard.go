@@ -0,0 +1,242 @@
+// This example turns the webcam into a whiteboard: the tip of a brightly colored pen is tracked
+// by its color and its movements draw strokes on a canvas laid over the camera image
+//
+// Call: gocv-examples whiteboard [-input 0] [-hsv hmin,smin,vmin,hmax,smax,vmax] [-thickness 5] [-dir .]
+// Draw a rectangle inside the pen tip with the mouse and press Enter or Space to sample its color,
+// press I to sample again. C switches the ink color, E the eraser, X clears the board, L lifts
+// the pen for the next move, W saves the drawing to -dir as a PNG on a white background and M shows
+// the mask of the pen color. +/- change the stroke thickness
+// Parameters can also be set with WHITEBOARD_* environment variables or a config file, see internal/config
+//
+// The pen is found as in the color-track example, see its package comment for the HSV range. A cap or
+// a piece of tape in a color not found elsewhere in the picture works best. The image is mirrored
+// by default, so that the pen moves on the screen as in a mirror. Strokes are smoothed, and a jump
+// of the tip across the board starts a new stroke instead of drawing a line
+
+package whiteboard
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"path/filepath"
+	"time"
+
+	"github.com/marchevska/gocv-examples/colortrack"
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	camID         = "0" // Default input
+	windowTitle   = "Whiteboard"
+	minArea       = 80 // Smallest pen tip blob in pixels
+	thickness     = 5
+	maxThickness  = 40
+	eraserScale   = 6 // Eraser size relative to the stroke thickness
+	smoothing     = 0.4
+	maxJump       = 120 // Pixels between frames
+	maxMissed     = 3   // Frames
+	hueTol        = 10  // Tolerances of the sampled range
+	satTol        = 60
+	valTol        = 60
+	exportPattern = "whiteboard_20060102_150405.png"
+)
+
+// Output parameters
+const (
+	videoCodec = "MJPG"
+	videoFPS   = 25
+)
+
+// Run draws on the input given by command line arguments
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples whiteboard", flag.ExitOnError)
+	input := fs.String("input", camID, "Camera ID, video file or stream URL")
+	rangeStr := fs.String("hsv", "", "HSV range of the pen tip hmin,smin,vmin,hmax,smax,vmax; sampled from the pen if not given")
+	area := fs.Float64("min-area", minArea, "Smallest pen tip blob in pixels")
+	width := fs.Int("thickness", thickness, fmt.Sprintf("Stroke thickness in pixels, up to %d", maxThickness))
+	mirror := fs.Bool("mirror", true, "Mirror the image horizontally")
+	dir := fs.String("dir", ".", "Directory of the saved drawings")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var camera videoio.CameraSettings
+	camera.RegisterFlags(fs)
+	if err := config.Load(fs, args, "WHITEBOARD"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	logging.SetPrefix("whiteboard")
+
+	var r colortrack.HSVRange
+	if *rangeStr != "" {
+		var err error
+		if r, err = colortrack.ParseRange(*rangeStr); err != nil {
+			return err
+		}
+	} else if outputs.Enabled() {
+		return errors.New("Without a display the pen color should be given with -hsv")
+	}
+	if *width < 1 || *width > maxThickness {
+		return fmt.Errorf("Thickness should be from 1 to %d", maxThickness)
+	}
+
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	report := probe.Run(probe.Requirements{Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	sd.OnClose("input", src.Close)
+	camera.Apply(src)
+	src = outputs.Limit(src)
+
+	window, sink, err := outputs.Open(sd, windowTitle)
+	if err != nil {
+		return err
+	}
+	canvas := gocv.NewMat()
+	defer canvas.Close()
+	tip := pen{Smooth: smoothing, MaxJump: maxJump, MaxMissed: maxMissed}
+	reselect, showMask, erasing, save := *rangeStr == "", false, false, false
+	current := 0
+	if window != nil {
+		window.Controls.Threshold = func(step int) string {
+			*width += step
+			if *width < 1 {
+				*width = 1
+			}
+			if *width > maxThickness {
+				*width = maxThickness
+			}
+			return fmt.Sprintf("thickness %d px", *width)
+		}
+		window.Controls.Keys = append(window.Controls.Keys,
+			videoio.KeyAction{Key: 'i', Help: "sample the pen color", Do: func() { reselect = true }},
+			videoio.KeyAction{Key: 'c', Help: "next ink color", Do: func() {
+				current, erasing = (current+1)%len(inks), false
+				logging.Infof("Ink %s", inks[current].Name)
+			}},
+			videoio.KeyAction{Key: 'e', Help: "eraser", Do: func() { erasing = !erasing }},
+			videoio.KeyAction{Key: 'x', Help: "clear the board", Do: func() { canvas.SetTo(gocv.NewScalar(0, 0, 0, 0)) }},
+			videoio.KeyAction{Key: 'l', Help: "lift the pen", Do: tip.Lift},
+			videoio.KeyAction{Key: 'w', Help: "save the drawing", Do: func() { save = true }},
+			videoio.KeyAction{Key: 'm', Help: "show mask", Do: func() { showMask = !showMask }},
+		)
+	}
+
+	hsv, mask, inked := gocv.NewMat(), gocv.NewMat(), gocv.NewMat()
+	defer hsv.Close()
+	defer mask.Close()
+	defer inked.Close()
+	kernel := gocv.GetStructuringElement(gocv.MorphEllipse, image.Pt(5, 5))
+	defer kernel.Close()
+
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	err = videoio.Run(sd.Context(), src, sink, func(img *gocv.Mat) {
+		if *mirror {
+			gocv.Flip(*img, img, 1)
+		}
+		if canvas.Cols() != img.Cols() || canvas.Rows() != img.Rows() {
+			canvas.Close()
+			canvas = gocv.NewMatWithSize(img.Rows(), img.Cols(), gocv.MatTypeCV8UC3)
+		}
+		gocv.CvtColor(*img, &hsv, gocv.ColorBGRToHSV)
+		if reselect {
+			reselect = false
+			rect := gocv.SelectROI(windowTitle, *img)
+			if rect.Empty() {
+				logging.Infof("No pen selected, press I to sample its color")
+				return
+			}
+			r = colortrack.RangeFromSamples(colortrack.SamplePixels(hsv, rect), hueTol, satTol, valTol)
+			tip.Lift()
+			logging.Infof("Pen HSV range %v", r)
+			return
+		}
+
+		stop := stats.Start("pen")
+		colortrack.Threshold(hsv, r, &mask)
+		gocv.MorphologyEx(mask, &mask, gocv.MorphOpen, kernel)
+		gocv.MorphologyEx(mask, &mask, gocv.MorphClose, kernel)
+		blob := colortrack.LargestBlob(mask, *area)
+		var center image.Point
+		if blob != nil {
+			center = colortrack.Centroid(blob)
+		}
+		if from, to, ok := tip.Update(center, blob != nil); ok {
+			if erasing {
+				gocv.Line(&canvas, from, to, draw.Black, *width*eraserScale)
+			} else {
+				gocv.Line(&canvas, from, to, inks[current].Color, *width)
+			}
+		}
+		stop()
+		stats.Frame()
+
+		if save {
+			save = false
+			saveDrawing(canvas, filepath.Join(*dir, time.Now().Format(exportPattern)))
+		}
+
+		if showMask {
+			gocv.CvtColor(mask, img, gocv.ColorGrayToBGR)
+		}
+		// Ink is wherever the canvas is not black
+		gocv.CvtColor(canvas, &inked, gocv.ColorBGRToGray)
+		gocv.Threshold(inked, &inked, 0, 255, gocv.ThresholdBinary)
+		canvas.CopyToWithMask(img, inked)
+
+		st := draw.DefaultStyle
+		text := "ink " + inks[current].Name
+		if erasing {
+			text = "eraser"
+			st = st.WithColor(draw.White)
+		} else {
+			st = st.WithColor(inks[current].Color)
+		}
+		if blob != nil {
+			size := *width
+			if erasing {
+				size *= eraserScale
+			}
+			gocv.Circle(img, center, size/2+2, st.LineColor, 1)
+		}
+		draw.TextWithBackground(img, text, image.Pt(0, st.TextSize(text).Y+2*st.Padding), st)
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+	})
+	if err != nil && err != videoio.ErrStopped {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+	return nil
+}
+
+// Saves the strokes of the canvas on a white background
+func saveDrawing(canvas gocv.Mat, path string) {
+	board := gocv.NewMatWithSizeFromScalar(gocv.NewScalar(255, 255, 255, 0), canvas.Rows(), canvas.Cols(), gocv.MatTypeCV8UC3)
+	defer board.Close()
+	inked := gocv.NewMat()
+	defer inked.Close()
+	gocv.CvtColor(canvas, &inked, gocv.ColorBGRToGray)
+	gocv.Threshold(inked, &inked, 0, 255, gocv.ThresholdBinary)
+	canvas.CopyToWithMask(&board, inked)
+	if !gocv.IMWrite(path, board) {
+		logging.Errorf("Cannot write %s", path)
+		return
+	}
+	logging.Infof("Drawing saved to %s", path)
+}
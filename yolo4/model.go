@@ -0,0 +1,120 @@
+package yolo4
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/models"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"gocv.io/x/gocv"
+)
+
+// ModelOptions selects the Yolo model files and the detection thresholds, so that the example runs
+// with other Darknet models, e.g. yolov4-tiny or one trained on custom classes
+type ModelOptions struct {
+	Labels   string  // Class labels, one per line
+	Config   string  // Darknet config
+	Weights  string  // Darknet weights
+	BlobSize int     // Size of the square network input, a multiple of 32
+	ConfThr  float64 // Detection confidence threshold
+	OvrThr   float64 // Overlapping threshold for NMS
+}
+
+// DefaultModelOptions returns the options of Yolo 4 trained on COCO, downloaded on first use
+func DefaultModelOptions() ModelOptions {
+	return ModelOptions{
+		Labels:   classLabelsPath,
+		Config:   yoloConfigPath,
+		Weights:  yoloWeightsPath,
+		BlobSize: detection.DefaultYoloBlobSize,
+		ConfThr:  detection.DefaultYoloConfThr,
+		OvrThr:   detection.DefaultYoloOvrThr,
+	}
+}
+
+// RegisterFlags adds -labels, -cfg, -weights, -blob-size, -conf and -nms flags to fs, defaulting to the
+// current options
+func (mo *ModelOptions) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&mo.Labels, "labels", mo.Labels, "File with the class labels of the model, one per line; "+
+		"also used by the onnxruntime backend")
+	fs.StringVar(&mo.Config, "cfg", mo.Config, "Darknet config of the model")
+	fs.StringVar(&mo.Weights, "weights", mo.Weights, "Darknet weights of the model")
+	fs.IntVar(&mo.BlobSize, "blob-size", mo.BlobSize, "Width and height of the network input, a multiple of 32; "+
+		"smaller is faster, larger finds smaller objects")
+	fs.Float64Var(&mo.ConfThr, "conf", mo.ConfThr, "Confidence threshold of detections, between 0 and 1")
+	fs.Float64Var(&mo.OvrThr, "nms", mo.OvrThr, "Overlap threshold of non-maximum suppression, between 0 and 1")
+}
+
+// Validate checks the options
+func (mo *ModelOptions) Validate() error {
+	if mo.Labels == "" || mo.Config == "" || mo.Weights == "" {
+		return errors.New("-labels, -cfg and -weights cannot be empty")
+	}
+	if mo.BlobSize < 32 || mo.BlobSize%32 != 0 {
+		return fmt.Errorf("-blob-size should be a positive multiple of 32, got %d", mo.BlobSize)
+	}
+	if mo.ConfThr <= 0 || mo.ConfThr >= 1 {
+		return fmt.Errorf("-conf should be between 0 and 1, got %g", mo.ConfThr)
+	}
+	if mo.OvrThr <= 0 || mo.OvrThr >= 1 {
+		return fmt.Errorf("-nms should be between 0 and 1, got %g", mo.OvrThr)
+	}
+	return nil
+}
+
+// Resolves the class labels of the model
+func (mo *ModelOptions) classLabels() ([]string, error) {
+	path, err := models.Resolve(mo.Labels)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading model: %v", err)
+	}
+	labels, err := detection.ReadClassLabels(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading class labels: %v", err)
+	}
+	return labels, nil
+}
+
+// Resolves the model files, returns the class labels and the paths of the config and the weights
+func (mo *ModelOptions) files() (classLabels []string, cfg, weights string, err error) {
+	if classLabels, err = mo.classLabels(); err != nil {
+		return nil, "", "", err
+	}
+	if cfg, err = models.Resolve(mo.Config); err != nil {
+		return nil, "", "", fmt.Errorf("Error loading model: %v", err)
+	}
+	if weights, err = models.Resolve(mo.Weights); err != nil {
+		return nil, "", "", fmt.Errorf("Error loading model: %v", err)
+	}
+	return classLabels, cfg, weights, nil
+}
+
+// Sets the input size and the thresholds of the detector
+func (mo *ModelOptions) configure(yd *detection.YoloDetector) {
+	yd.BlobSize, yd.ConfThr, yd.OvrThr = mo.BlobSize, float32(mo.ConfThr), mo.OvrThr
+}
+
+// Load resolves the model files and creates a detector with the options. The network is closed by sd
+func (mo *ModelOptions) Load(sd *shutdown.Handler) (*detection.YoloDetector, error) {
+	classLabels, cfg, weights, err := mo.files()
+	if err != nil {
+		return nil, err
+	}
+	yoloModel := gocv.ReadNet(weights, cfg)
+	if yoloModel.Empty() {
+		return nil, fmt.Errorf("Error loading model %s", mo.Weights)
+	}
+	sd.OnClose("model", yoloModel.Close)
+	detector := detection.NewYoloDetector(&yoloModel, classLabels)
+	mo.configure(detector)
+	return detector, nil
+}
+
+// LoadYolo resolves the Yolo 4 model files and creates a detector with default settings.
+// The network is closed by sd
+func LoadYolo(sd *shutdown.Handler) (*detection.YoloDetector, error) {
+	opts := DefaultModelOptions()
+	return opts.Load(sd)
+}
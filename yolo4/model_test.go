@@ -0,0 +1,55 @@
+package yolo4
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+)
+
+func TestModelOptionsFlags(t *testing.T) {
+	opts := DefaultModelOptions()
+	if err := opts.Validate(); err != nil {
+		t.Fatalf("Default options invalid: %v", err)
+	}
+	fs := flag.NewFlagSet("yolo", flag.ContinueOnError)
+	opts.RegisterFlags(fs)
+	err := fs.Parse([]string{"-cfg", "yolov4-tiny.cfg", "-weights", "yolov4-tiny.weights", "-blob-size", "320",
+		"-conf", "0.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultModelOptions()
+	want.Config, want.Weights, want.BlobSize, want.ConfThr = "yolov4-tiny.cfg", "yolov4-tiny.weights", 320, 0.3
+	if opts != want {
+		t.Errorf("Got %+v, want %+v", opts, want)
+	}
+}
+
+func TestModelOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*ModelOptions)
+	}{
+		{"no weights", func(mo *ModelOptions) { mo.Weights = "" }},
+		{"blob size not a multiple of 32", func(mo *ModelOptions) { mo.BlobSize = 400 }},
+		{"zero blob size", func(mo *ModelOptions) { mo.BlobSize = 0 }},
+		{"confidence above 1", func(mo *ModelOptions) { mo.ConfThr = 1.5 }},
+		{"zero NMS threshold", func(mo *ModelOptions) { mo.OvrThr = 0 }},
+	}
+	for _, tt := range tests {
+		opts := DefaultModelOptions()
+		tt.modify(&opts)
+		if err := opts.Validate(); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+
+	// Invalid values are reported by flag parsing too
+	fs := flag.NewFlagSet("yolo", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	opts := DefaultModelOptions()
+	opts.RegisterFlags(fs)
+	if err := fs.Parse([]string{"-conf", "high"}); err == nil {
+		t.Error("No error parsing -conf high")
+	}
+}
@@ -0,0 +1,224 @@
+package yolo4
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+// Size of the cell of a stream in the tiled view of several sources
+const (
+	tileWidth  = 640
+	tileHeight = 360
+)
+
+// stream is one of several sources, processed on its own goroutine
+type stream struct {
+	name   string
+	src    videoio.FrameSource
+	log    *logging.Logger
+	export *detection.JSONWriter
+
+	mu     sync.Mutex
+	latest gocv.Mat // Last annotated frame, empty until the first one
+	done   bool
+}
+
+// Write keeps a copy of the annotated frame for the tiled view, implementing videoio.FrameSink
+func (s *stream) Write(img gocv.Mat) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	img.CopyTo(&s.latest)
+	return nil
+}
+
+// Close releases the last frame
+func (s *stream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.latest.Close()
+}
+
+// Detects objects on the frames of the stream until it ends or ctx is cancelled
+func (s *stream) run(ctx context.Context, detector detection.Detector, stats *metrics.Collector) error {
+	frame := 0
+	err := videoio.Run(ctx, s.src, s, func(img *gocv.Mat) {
+		dets, err := detector.Detect(*img)
+		if err != nil {
+			s.log.Errorf("Error detecting objects: %v", err)
+		}
+		stats.Frame()
+		if s.export != nil {
+			if err := s.export.Write(detection.FrameDetections{Frame: frame, Detections: dets}); err != nil {
+				s.log.Errorf("Error exporting detections: %v", err)
+			}
+		}
+		frame++
+
+		s.log.Infof("Detected objects: %d", len(dets))
+		for _, d := range dets {
+			s.log.Infof("%v", d)
+			draw.LabelBox(img, d.BBox, d.Label, draw.DefaultStyle.WithColor(draw.LabelColor(d.Label)))
+		}
+	})
+	s.mu.Lock()
+	s.done = true
+	s.mu.Unlock()
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	return err
+}
+
+// runStreams detects objects on several inputs concurrently with the detector, which must be safe for
+// concurrent use, and shows the annotated frames tiled in one view, also written to the outputs.
+// Camera controls are set by camera, and stream URLs are reopened when they drop, as set by streamOpts.
+// Each stream logs its detections with its name, to a file of its own in logDir if set, and exports
+// them to the export path with the name of the stream appended. It returns when all streams ended or
+// the user quits
+func runStreams(sd *shutdown.Handler, inputs []string, streamOpts *videoio.StreamSettings, camera *videoio.CameraSettings,
+	detector detection.Detector, outputs *videoio.Outputs, stats *metrics.Collector, export, logDir string) error {
+	if logDir != "" {
+		if err := os.MkdirAll(logDir, 0755); err != nil {
+			return fmt.Errorf("Error creating stream log directory: %v", err)
+		}
+	}
+	var streams []*stream
+	for i, input := range inputs {
+		name := fmt.Sprintf("cam%d", i)
+		s := &stream{name: name, latest: gocv.NewMat(), log: logging.Default.Child(name)}
+		sd.OnClose(s.name+" frame", s.Close)
+		src, err := videoio.OpenSource(input, 0, 0)
+		if err != nil {
+			return fmt.Errorf("Error opening input %s: %v", input, err)
+		}
+		camera.Apply(src)
+		src = streamOpts.Wrap(sd.Context(), input, src)
+		sd.OnClose(s.name, src.Close)
+		s.src = outputs.Limit(src)
+		if logDir != "" {
+			f, err := os.Create(filepath.Join(logDir, s.name+".log"))
+			if err != nil {
+				return fmt.Errorf("Error creating stream log: %v", err)
+			}
+			sd.OnClose(s.name+" log", f.Close)
+			s.log.SetOutput(f)
+		}
+		if export != "" {
+			if s.export, err = detection.NewJSONWriter(streamPath(export, s.name)); err != nil {
+				return fmt.Errorf("Error opening export file: %v", err)
+			}
+			sd.OnClose(s.name+" export", s.export.Close)
+		}
+		logging.Infof("Detecting objects on %s as %s", input, s.name)
+		streams = append(streams, s)
+	}
+
+	// The goroutine showing the mosaic is not locked to a thread, so HighGUI calls go through a manager
+	if !outputs.Enabled() {
+		outputs.Windows = videoio.NewWindowManager()
+		sd.OnClose("window manager", outputs.Windows.Close)
+	}
+	_, sink, err := outputs.Open(sd, fmt.Sprintf("Yolo 4 - %d cameras", len(streams)))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(sd.Context())
+	defer cancel()
+	var wg sync.WaitGroup
+	for _, s := range streams {
+		wg.Add(1)
+		go func(s *stream) {
+			defer wg.Done()
+			if err := s.run(ctx, detector, stats); err != nil {
+				s.log.Errorf("Error processing input: %v", err)
+			}
+			s.log.Infof("Stream ended")
+		}(s)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Show the latest frames at the output frame rate until all streams ended
+	ticker := time.NewTicker(time.Second / videoFPS)
+	defer ticker.Stop()
+	mosaic := gocv.NewMat()
+	defer mosaic.Close()
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-ticker.C:
+		}
+		tile(streams, &mosaic)
+		metrics.Overlay(&mosaic, stats, draw.DefaultStyle)
+		if err := sink.Write(mosaic); err != nil {
+			cancel()
+			<-done
+			if err == videoio.ErrStopped {
+				return nil
+			}
+			return fmt.Errorf("Error writing output: %v", err)
+		}
+	}
+}
+
+// Draws the latest frame of each stream into its cell of the most square grid, scaled to fit and
+// labelled with the name of the stream. Cells of streams without frames stay black
+func tile(streams []*stream, dst *gocv.Mat) {
+	cols := int(math.Ceil(math.Sqrt(float64(len(streams)))))
+	rows := (len(streams) + cols - 1) / cols
+	if dst.Cols() != cols*tileWidth || dst.Rows() != rows*tileHeight {
+		dst.Close()
+		*dst = gocv.NewMatWithSize(rows*tileHeight, cols*tileWidth, gocv.MatTypeCV8UC3)
+	}
+	dst.SetTo(gocv.NewScalar(0, 0, 0, 0))
+	st := draw.DefaultStyle
+	for i, s := range streams {
+		cell := image.Rect(0, 0, tileWidth, tileHeight).Add(image.Pt(i%cols*tileWidth, i/cols*tileHeight))
+		s.mu.Lock()
+		label := s.name
+		if s.done {
+			label += " - ended"
+		}
+		if !s.latest.Empty() {
+			region := dst.Region(fit(s.latest.Cols(), s.latest.Rows(), cell))
+			gocv.Resize(s.latest, &region, image.Pt(region.Cols(), region.Rows()), 0, 0, gocv.InterpolationArea)
+			region.Close()
+		}
+		s.mu.Unlock()
+		draw.TextWithBackground(dst, label, cell.Min.Add(image.Pt(0, st.TextSize(label).Y+2*st.Padding)), st)
+	}
+}
+
+// Returns the largest rectangle with the aspect ratio of a w x h frame centered in the cell
+func fit(w, h int, cell image.Rectangle) image.Rectangle {
+	scale := math.Min(float64(cell.Dx())/float64(w), float64(cell.Dy())/float64(h))
+	size := image.Pt(int(float64(w)*scale), int(float64(h)*scale))
+	min := cell.Min.Add(cell.Size().Sub(size).Div(2))
+	return image.Rectangle{Min: min, Max: min.Add(size)}
+}
+
+// Returns the path with the name of a stream inserted before the extension, e.g. detections_cam0.jsonl
+func streamPath(path, name string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "_" + name + ext
+}
@@ -0,0 +1,59 @@
+package yolo4
+
+import (
+	"image"
+	"testing"
+
+	"github.com/marchevska/gocv-examples/internal/testutil"
+	"gocv.io/x/gocv"
+)
+
+func TestFit(t *testing.T) {
+	cell := image.Rect(640, 360, 1280, 720)
+	tests := []struct {
+		w, h int
+		want image.Rectangle
+	}{
+		{1920, 1080, cell},                           // Same aspect ratio
+		{640, 480, image.Rect(720, 360, 1200, 720)},  // Narrower, bars on the sides
+		{1280, 360, image.Rect(640, 450, 1280, 630)}, // Wider, bars above and below
+	}
+	for _, tt := range tests {
+		if got := fit(tt.w, tt.h, cell); got != tt.want {
+			t.Errorf("fit(%d, %d) = %v, want %v", tt.w, tt.h, got, tt.want)
+		}
+	}
+}
+
+func TestTile(t *testing.T) {
+	var streams []*stream
+	for i := 0; i < 3; i++ {
+		s := &stream{name: "cam", latest: gocv.NewMat()}
+		defer s.Close()
+		streams = append(streams, s)
+	}
+	white := testutil.SolidImage(t, 64, 36, gocv.NewScalar(255, 255, 255, 0))
+	if err := streams[1].Write(white); err != nil {
+		t.Fatal(err)
+	}
+
+	mosaic := gocv.NewMat()
+	defer mosaic.Close()
+	tile(streams, &mosaic)
+	if mosaic.Cols() != 2*tileWidth || mosaic.Rows() != 2*tileHeight {
+		t.Fatalf("Tiled to %dx%d, want a 2x2 grid", mosaic.Cols(), mosaic.Rows())
+	}
+	// The center of the second cell shows the frame, the other cells are black
+	if v := mosaic.GetVecbAt(tileHeight/2, tileWidth*3/2); v[0] != 255 {
+		t.Errorf("Frame of the second stream not drawn: %v", v)
+	}
+	if v := mosaic.GetVecbAt(tileHeight*3/2, tileWidth/2); v[0] != 0 {
+		t.Errorf("Cell of a stream without frames not black: %v", v)
+	}
+}
+
+func TestStreamPath(t *testing.T) {
+	if got := streamPath("out/detections.jsonl", "cam1"); got != "out/detections_cam1.jsonl" {
+		t.Errorf("Got %s", got)
+	}
+}
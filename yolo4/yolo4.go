@@ -0,0 +1,575 @@
+// This example shows how to use Yolo4 with GoCV
+// with default settings, to classify objects on images or video
+//
+// Call: gocv-examples yolo [-input path] [-output path]... [-config file.yaml]
+// Input can be images, a video file, a camera ID, a stream URL, a GStreamer pipeline or the screen,
+// see videoio.OpenSource. Annotated frames are shown in a window and written to each -output, a video
+// file, an image file name pattern or an address to stream MJPEG. Run with -h for the other flags:
+// detection on every n-th frame, several concurrent -source inputs, the ONNX Runtime backend, alerts,
+// event publishing, remote control, tracing, health checks and running as a systemd service.
+// Parameters can also be set with YOLO4_* environment variables or a config file, see internal/config
+//
+// For more information on Darknet and Yolo 4 please visit
+// https://github.com/AlexeyAB/darknet
+//
+// Model files are downloaded on first run and cached, unless they are found in the current directory.
+// Other Darknet models are given by -cfg, -weights and -labels, see ModelOptions.
+//
+// C++ example used as reference
+// https://github.com/opencv/opencv/blob/8c25a8eb7b10fb50cda323ee6bec68aa1a9ce43c/samples/dnn/object_detection.cpp#L192-L221
+//
+
+package yolo4
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marchevska/gocv-examples/internal/config"
+	"github.com/marchevska/gocv-examples/internal/control"
+	"github.com/marchevska/gocv-examples/internal/detection"
+	"github.com/marchevska/gocv-examples/internal/draw"
+	"github.com/marchevska/gocv-examples/internal/events"
+	"github.com/marchevska/gocv-examples/internal/health"
+	"github.com/marchevska/gocv-examples/internal/ingest"
+	"github.com/marchevska/gocv-examples/internal/logging"
+	"github.com/marchevska/gocv-examples/internal/metrics"
+	"github.com/marchevska/gocv-examples/internal/models"
+	"github.com/marchevska/gocv-examples/internal/notify"
+	"github.com/marchevska/gocv-examples/internal/probe"
+	"github.com/marchevska/gocv-examples/internal/shutdown"
+	"github.com/marchevska/gocv-examples/internal/storage"
+	"github.com/marchevska/gocv-examples/internal/systemd"
+	"github.com/marchevska/gocv-examples/internal/threads"
+	"github.com/marchevska/gocv-examples/internal/tracing"
+	"github.com/marchevska/gocv-examples/internal/videoio"
+	"gocv.io/x/gocv"
+)
+
+const (
+	imgPath         = "img/person.jpg" // Default input for detection
+	classLabelsPath = "coco.names"     // Labels list
+	yoloConfigPath  = "yolov4.cfg"     // Config file
+	yoloWeightsPath = "yolov4.weights" // Model weights
+	confThrStep     = 0.05             // Change of the threshold by +/- keys
+	onnxModelPath   = "yolov5s.onnx"   // Model of the ONNX Runtime backend, not downloaded
+)
+
+const usageStr = `Detects objects with Yolo 4 on images, videos, cameras and streams. Press 'Q' to exit, 'H' for help.
+Usage: gocv-examples yolo [-input path] [-output path]... [flags]
+Other Darknet models are given by -cfg, -weights and -labels, e.g.
+  gocv-examples yolo -cfg yolov4-tiny.cfg -weights yolov4-tiny.weights -blob-size 320 -conf 0.3 -input video.mp4
+Parameters can also be set with YOLO4_* environment variables or a config file, see internal/config.
+Flags accepted:`
+
+// Inference backends
+const (
+	backendOpenCV = "opencv"
+	backendOnnx   = "onnxruntime"
+)
+
+// MinOpenCV is the first OpenCV version supporting Yolo 4 layers
+const MinOpenCV = "4.4.0"
+
+// Output parameters
+const (
+	videoCodec = "MJPG" // Codec and frame rate of output video files
+	videoFPS   = 25
+)
+
+func clamp(v, min, max float32) float32 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// loadYoloPool loads a Yolo 4 network for each of n workers and spreads detections across them.
+// With devices, the networks use the CUDA backend on the devices in turn. The networks are closed by sd,
+// which logs how many frames each worker processed
+func loadYoloPool(sd *shutdown.Handler, model *ModelOptions, n int, devices []int, stats *metrics.Collector,
+	threadOpts *threads.Options) (*detection.Pool, error) {
+	classLabels, cfg, weights, err := model.files()
+	if err != nil {
+		return nil, err
+	}
+	pool, err := detection.NewPool(n, func(i int) (detection.Detector, func() error, error) {
+		if err := threadOpts.PinWorker(i); err != nil {
+			return nil, nil, err
+		}
+		if len(devices) > 0 {
+			if err := detection.SetCUDADevice(devices[i%len(devices)]); err != nil {
+				return nil, nil, err
+			}
+		}
+		net := gocv.ReadNet(weights, cfg)
+		if net.Empty() {
+			if len(devices) > 0 {
+				return nil, nil, fmt.Errorf("Error loading model on CUDA device %d", devices[i%len(devices)])
+			}
+			return nil, nil, fmt.Errorf("Error loading model %s", model.Weights)
+		}
+		if len(devices) > 0 {
+			net.SetPreferableBackend(gocv.NetBackendCUDA)
+			net.SetPreferableTarget(gocv.NetTargetCUDA)
+		}
+		detector := detection.NewYoloDetector(&net, classLabels)
+		model.configure(detector)
+		detector.Stats = stats
+		return detector, net.Close, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sd.OnClose("model", func() error {
+		if n == len(devices) {
+			logging.Infof("Frames by CUDA device %v: %v", devices, pool.Frames())
+		} else {
+			logging.Infof("Frames by network: %v", pool.Frames())
+		}
+		return pool.Close()
+	})
+	return pool, nil
+}
+
+// Parses a comma-separated list of CUDA device IDs
+func parseDevices(s string) ([]int, error) {
+	var devices []int
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		id, err := strconv.Atoi(f)
+		if err != nil || id < 0 {
+			return nil, fmt.Errorf("Invalid CUDA device %q", f)
+		}
+		devices = append(devices, id)
+	}
+	return devices, nil
+}
+
+// loadOnnxYolo creates a detector running the ONNX model with ONNX Runtime, labelled with the classes
+// and using the thresholds of the model options. The session is closed by sd
+func loadOnnxYolo(sd *shutdown.Handler, model *ModelOptions, modelPath, libPath string) (*detection.OnnxYoloDetector, error) {
+	classLabels, err := model.classLabels()
+	if err != nil {
+		return nil, err
+	}
+	if modelPath, err = models.Resolve(modelPath); err != nil {
+		return nil, fmt.Errorf("Error loading model: %v", err)
+	}
+	detector, err := detection.NewOnnxYoloDetector(modelPath, classLabels, libPath)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading model: %v", err)
+	}
+	sd.OnClose("model", detector.Close)
+	detector.ConfThr, detector.OvrThr = float32(model.ConfThr), model.OvrThr
+	return detector, nil
+}
+
+// Run detects objects on the input given by command line arguments, see the package comment
+func Run(args []string) error {
+	fs := flag.NewFlagSet("gocv-examples yolo", flag.ExitOnError)
+	input := fs.String("input", imgPath, "Image, directory or glob pattern of images, video file, camera ID, stream URL, gst:pipeline, picam, screen or .gcvr replay file")
+	model := DefaultModelOptions()
+	model.RegisterFlags(fs)
+	backend := fs.String("backend", backendOpenCV, "Inference backend: opencv runs Yolo 4 with OpenCV DNN, "+
+		"onnxruntime runs -onnx-model with ONNX Runtime")
+	onnxModel := fs.String("onnx-model", onnxModelPath, "YOLOv5 or YOLOv8 ONNX model of the onnxruntime backend")
+	onnxLib := fs.String("onnx-lib", "", "Path of the ONNX Runtime shared library, found by the system loader if empty")
+	gpus := fs.String("gpus", "", "CUDA device IDs to load the model on, e.g. 0,1; in -grpc mode concurrent streams "+
+		"are spread across them")
+	camera := fs.Int("camera", -1, "Camera ID to detect objects on live, the same as -input ID")
+	detectEvery := fs.Int("detect-every", 1, "Detect objects on every Nth frame of the input, showing and writing "+
+		"the last detections on the frames in between, e.g. to annotate a long video faster")
+	var sources config.StringList
+	fs.Var(&sources, "source", "Input processed concurrently with the other -source inputs and shown tiled, "+
+		"repeatable, e.g. -source 0 -source rtsp://camera/stream; any kind of -input")
+	streamNets := fs.Bool("stream-nets", false, "With several -source inputs, load a network for each instead of "+
+		"sharing one, which takes turns")
+	streamLogs := fs.String("stream-logs", "", "Directory to log the detections of each -source input to, "+
+		"one file each, instead of the common log")
+	adaptiveFPS := fs.Float64("adaptive-fps", 0, "Frame rate to keep up with by downscaling the inference input "+
+		"while detection is slower, 0 to always detect at full resolution")
+	adaptiveMin := fs.Float64("adaptive-min-scale", detection.DefaultAdaptiveMinScale, "Smallest scale of the inference "+
+		"input with -adaptive-fps")
+	logging.RegisterFlags(fs)
+	outputs := videoio.NewOutputs(videoCodec, videoFPS)
+	outputs.RegisterFlags(fs)
+	var cameraSettings videoio.CameraSettings
+	cameraSettings.RegisterFlags(fs)
+	var recording videoio.Recording
+	recording.RegisterFlags(fs)
+	var streamOpts videoio.StreamSettings
+	streamOpts.RegisterFlags(fs)
+	export := fs.String("export", "", "File to export detections to as JSON lines, one object per frame, "+
+		"which can be checked with 'gocv-examples review'")
+	var alertOpts notify.Options
+	alertOpts.RegisterFlags(fs)
+	var storageOpts storage.Options
+	storageOpts.RegisterFlags(fs)
+	var eventOpts events.Options
+	eventOpts.RegisterFlags(fs)
+	var ingestOpts ingest.Options
+	ingestOpts.RegisterFlags(fs)
+	var controlOpts control.Options
+	controlOpts.RegisterFlags(fs)
+	var traceOpts tracing.Options
+	traceOpts.RegisterFlags(fs)
+	var healthOpts health.Options
+	healthOpts.RegisterFlags(fs)
+	var serviceOpts systemd.Options
+	serviceOpts.RegisterFlags(fs)
+	var threadOpts threads.Options
+	threadOpts.RegisterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), usageStr)
+		fs.PrintDefaults()
+	}
+	if err := config.Load(fs, args, "YOLO4"); err != nil {
+		return fmt.Errorf("Error loading parameters: %v", err)
+	}
+	if err := model.Validate(); err != nil {
+		fs.Usage()
+		return err
+	}
+	logging.SetPrefix("yolo4")
+
+	// Ctrl+C stops processing and closes outputs properly
+	sd := shutdown.New(shutdown.DefaultTimeout)
+	defer sd.Close()
+
+	// Services log to the journal and have no display
+	notifier, err := serviceOpts.Open(sd)
+	if err != nil {
+		return err
+	}
+	if notifier != nil {
+		outputs.NoGUI = true
+	}
+
+	// Check the environment before loading the model
+	useOnnx := false
+	switch *backend {
+	case backendOpenCV:
+	case backendOnnx:
+		if !detection.OnnxAvailable {
+			return fmt.Errorf("Backend %s is not available, build with -tags onnxruntime", *backend)
+		}
+		useOnnx = true
+	default:
+		return fmt.Errorf("Unknown backend %q, available: %s, %s", *backend, backendOpenCV, backendOnnx)
+	}
+	devices, err := parseDevices(*gpus)
+	if err != nil {
+		return err
+	}
+	if len(devices) > 0 && useOnnx {
+		return fmt.Errorf("-gpus is only supported by the %s backend", backendOpenCV)
+	}
+	if *camera >= 0 {
+		*input = strconv.Itoa(*camera)
+	}
+	if *detectEvery < 1 {
+		return errors.New("-detect-every should be at least 1")
+	}
+	if *adaptiveFPS < 0 {
+		return errors.New("-adaptive-fps cannot be negative")
+	}
+	if *adaptiveFPS > 0 && (*adaptiveMin <= 0 || *adaptiveMin > 1) {
+		return fmt.Errorf("-adaptive-min-scale should be above 0 and at most 1, got %g", *adaptiveMin)
+	}
+	if err := streamOpts.Validate(); err != nil {
+		return err
+	}
+	// A single -source is the same as -input
+	if len(sources) == 1 {
+		*input = sources[0]
+	}
+	multi := len(sources) > 1
+	if multi && ingestOpts.Enabled() {
+		return errors.New("-source inputs cannot be combined with -grpc, which takes frames from clients")
+	}
+	if multi && *streamNets && useOnnx {
+		return fmt.Errorf("-stream-nets is only supported by the %s backend", backendOpenCV)
+	}
+	report := probe.Run(probe.Requirements{MinOpenCV: MinOpenCV, DNN: !useOnnx, CUDA: len(devices) > 0,
+		Codecs: outputs.Codecs(), Camera: probe.CameraID(*input)})
+	report.Log()
+	if err := report.Err(); err != nil {
+		return err
+	}
+	if err := threadOpts.Setup(); err != nil {
+		return err
+	}
+
+	// Report liveness while the model loads, and readiness once it is loaded and frames come
+	checker, err := healthOpts.Open(sd)
+	if err != nil {
+		return err
+	}
+	if checker != nil {
+		checker.Set(health.CheckModel, errors.New("Loading"))
+	}
+
+	// Initialize model; the thresholds of a single network are adjusted in the window and by the control API
+	var detector detection.Detector
+	var threshold *float32
+	var nmsThreshold *float64
+	stats := metrics.NewCollector(metrics.DefaultWindow)
+	concurrent := len(devices) > 0 || (multi && *streamNets)
+	if concurrent {
+		n := len(devices)
+		if multi && *streamNets {
+			n = len(sources)
+		}
+		pool, err := loadYoloPool(sd, &model, n, devices, stats, &threadOpts)
+		if err != nil {
+			return err
+		}
+		detector = pool
+		ingestOpts.Concurrent = true
+	} else if useOnnx {
+		od, err := loadOnnxYolo(sd, &model, *onnxModel, *onnxLib)
+		if err != nil {
+			return err
+		}
+		od.Stats = stats
+		detector, threshold, nmsThreshold = od, &od.ConfThr, &od.OvrThr
+	} else {
+		yd, err := model.Load(sd)
+		if err != nil {
+			return err
+		}
+		yd.Stats = stats
+		detector, threshold, nmsThreshold = yd, &yd.ConfThr, &yd.OvrThr
+	}
+	logging.Infof("Using the %s backend", *backend)
+	var adaptive *detection.Adaptive
+	if *adaptiveFPS > 0 {
+		budget := time.Duration(float64(time.Second) / *adaptiveFPS)
+		if adaptive, err = detection.NewAdaptive(detector, budget, *adaptiveMin); err != nil {
+			return err
+		}
+		detector = adaptive
+	}
+	if checker != nil {
+		checker.Set(health.CheckModel, nil)
+	}
+
+	// Process several inputs at once, sharing a single network
+	if multi {
+		if !concurrent {
+			detector = detection.NewSerialized(detector)
+		}
+		if notifier != nil {
+			notifier.Ready(fmt.Sprintf("Detecting objects on %d inputs", len(sources)))
+			notifier.KeepAlive(sd.Context(), nil)
+		}
+		return runStreams(sd, sources, &streamOpts, &cameraSettings, detector, outputs, stats, *export, *streamLogs)
+	}
+
+	// Serve detection of pushed frames instead of reading the input
+	if ingestOpts.Enabled() {
+		if notifier != nil {
+			notifier.Ready("Serving frame ingestion on " + ingestOpts.Addr)
+			notifier.KeepAlive(sd.Context(), nil)
+		}
+		return ingestOpts.Serve(sd.Context(), func(f ingest.Frame) ([]detection.Detection, error) {
+			img, err := gocv.IMDecode(f.Image, gocv.IMReadColor)
+			if err != nil {
+				return nil, err
+			}
+			defer img.Close()
+			if img.Empty() {
+				return nil, errors.New("Cannot decode image")
+			}
+			yd, err := detector.Detect(img)
+			stats.Frame()
+			if err == nil && checker != nil {
+				checker.Frame()
+			}
+			logging.Debugf("Frame %d of %s: %d objects, %v", f.ID, f.Camera, len(yd), stats)
+			return yd, err
+		})
+	}
+
+	src, err := videoio.OpenSource(*input, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Error opening input: %v", err)
+	}
+	cameraSettings.Apply(src)
+	// Stream URLs are reopened when they drop, closing the wrapper closes the current connection
+	src = streamOpts.Wrap(sd.Context(), *input, src)
+	sd.OnClose("input", src.Close)
+	if src, err = recording.Wrap(sd, src); err != nil {
+		return err
+	}
+	src = outputs.Limit(src)
+	if checker != nil {
+		src = checker.Source(src)
+	}
+
+	// Trace each frame from reading it to writing it, with the stages measured by stats
+	tracer, err := traceOpts.Open(sd, "yolo4")
+	if err != nil {
+		return err
+	}
+	if tracer != nil {
+		stats.SetTracer(tracer)
+		src = tracer.Source(src)
+	}
+
+	// Show frames in the window and write them to the outputs
+	window, sink, err := outputs.Open(sd, "Yolo 4")
+	if err != nil {
+		return err
+	}
+	if window != nil && threshold != nil {
+		window.Controls.Threshold = func(step int) string {
+			*threshold = clamp(*threshold+float32(step)*confThrStep, confThrStep, 1-confThrStep)
+			return fmt.Sprintf("confidence %.2f", *threshold)
+		}
+	}
+
+	// Apply changes of the control API between frames and take its snapshots of annotated frames
+	ctl, err := controlOpts.Open(sd)
+	if err != nil {
+		return err
+	}
+	if ctl != nil {
+		if threshold != nil {
+			ctl.AddThreshold32("confidence", threshold, confThrStep, 1-confThrStep)
+			ctl.AddThreshold("nms", confThrStep, 1-confThrStep,
+				func() float64 { return *nmsThreshold }, func(v float64) { *nmsThreshold = v })
+		}
+		src = ctl.Source(src)
+		sink = append(sink, ctl)
+	}
+	var out videoio.FrameSink = sink
+	if tracer != nil {
+		out = tracer.Sink(sink)
+	}
+	if checker != nil {
+		out = checker.Sink(out)
+	}
+	if notifier != nil {
+		out = notifier.Sink(out)
+		if ctl != nil {
+			notifier.KeepAlive(sd.Context(), ctl.Paused)
+		}
+	}
+
+	var exporter *detection.JSONWriter
+	if *export != "" {
+		if exporter, err = detection.NewJSONWriter(*export); err != nil {
+			return fmt.Errorf("Error opening export file: %v", err)
+		}
+		sd.OnClose("export", exporter.Close)
+	}
+	publisher, err := eventOpts.Open(sd, *input)
+	if err != nil {
+		return err
+	}
+	if alertOpts.Upload, err = storageOpts.Open(sd, *input); err != nil {
+		return err
+	}
+	alerts, err := alertOpts.Open(sd, videoCodec, videoFPS)
+	if err != nil {
+		return err
+	}
+	if notifier != nil {
+		notifier.Ready("Detecting objects on " + *input)
+	}
+
+	// Detect objects on every -detect-every frame and show frames with predictions and timing;
+	// frames in between show the detections of the last detected frame
+	frame := 0
+	scale := 1.0
+	var yd []detection.Detection
+	var latency time.Duration
+
+	// Every frame is annotated, checked for alerts and published with the latest detections
+	annotate := func(img *gocv.Mat) {
+		for _, d := range yd {
+			draw.LabelBox(img, d.BBox, d.Label, draw.DefaultStyle.WithColor(draw.LabelColor(d.Label)))
+		}
+		if alerts != nil {
+			alerts.Check(*img, yd)
+		}
+		metrics.Overlay(img, stats, draw.DefaultStyle)
+		if publisher != nil {
+			publisher.Frame(*img)
+		}
+	}
+	err = videoio.Run(sd.Context(), src, out, func(img *gocv.Mat) {
+		detect := frame%*detectEvery == 0
+		frame++
+		stats.Frame()
+		if !detect {
+			annotate(img)
+			return
+		}
+
+		var err error
+		start := time.Now()
+		yd, err = detector.Detect(*img)
+		latency = time.Since(start)
+		if err != nil {
+			logging.Errorf("Error detecting objects: %v", err)
+		}
+		if adaptive != nil && adaptive.Scale() != scale {
+			scale = adaptive.Scale()
+			logging.Infof("Detecting at %.0f%% of full resolution", scale*100)
+		}
+		if ctl != nil {
+			yd = ctl.Filter(yd)
+		}
+		if tracer != nil {
+			tracer.SetAttribute("objects", len(yd))
+		}
+		logging.Debugf("%v", stats)
+		if exporter != nil {
+			if err := exporter.Write(detection.FrameDetections{Frame: frame - 1, Detections: yd}); err != nil {
+				logging.Errorf("Error exporting detections: %v", err)
+			}
+		}
+		if publisher != nil && len(yd) > 0 {
+			publisher.Publish(events.Event{Type: events.TypeDetections, Frame: frame - 1, Detections: yd})
+		}
+
+		logging.Infof("Detected objects: %d in %v", len(yd), latency.Round(time.Millisecond/10))
+		for _, d := range yd {
+			logging.Infof("%v", d)
+		}
+
+		if window != nil {
+			ms := float64(latency) / float64(time.Millisecond)
+			title := fmt.Sprintf("No objects detected in %.0f ms - Press H for help", ms)
+			if len(yd) > 0 {
+				title = fmt.Sprintf("Detected %d objects in %.0f ms - Press H for help", len(yd), ms)
+			}
+			window.SetWindowTitle(title)
+		}
+		annotate(img)
+	})
+	if err == videoio.ErrStopped {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Error processing input: %v", err)
+	}
+
+	// Keep the last frame on the screen
+	if window != nil {
+		videoio.WaitForKey(sd.Context(), window.Display)
+	}
+	return nil
+}